@@ -19,6 +19,7 @@ import (
 	"github.com/user/hermod/internal/service"
 	"github.com/user/hermod/internal/storage"
 	"github.com/user/hermod/internal/version"
+	"github.com/user/hermod/internal/watch"
 
 	_ "github.com/user/hermod/internal/engine/registry/nodes"
 	_ "github.com/user/hermod/pkg/comm/transformer/advanced"
@@ -106,6 +107,10 @@ func runApp(svcCtx context.Context, o *Options) int {
 		store = initPrimaryStorage(svcCtx, dbType, dbConn, logger)
 		logStore = initLogStorage(svcCtx, logType, logConn, logger)
 	}
+	if store != nil && o.mode != "worker" {
+		// Workers watching this process learn of every write made through it.
+		store = watch.NewStorage(store, watch.NewFeed(watch.DefaultBacklog))
+	}
 
 	reg, cfg := setupRegistry(store, logStore, logger, o)
 	ctx, cancel := setupSignalHandler(svcCtx, logger, reg, store, logStore)
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/user/hermod"
@@ -15,7 +16,9 @@ import (
 	"github.com/user/hermod/internal/config"
 	"github.com/user/hermod/internal/engine/registry"
 	"github.com/user/hermod/internal/engine/worker"
+	"github.com/user/hermod/internal/leader"
 	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/pkg/infra/state"
 )
 
 func runServer(ctx context.Context, o *Options, reg *registry.Registry, store, logStore storage.Storage, cfg *config.Config, wrk *worker.Worker, logger hermod.Logger, configured, userSetup bool) error {
@@ -33,8 +36,11 @@ func startAPI(ctx context.Context, o *Options, reg *registry.Registry, store, lo
 		server.SetWorker(wrk)
 	}
 
-	stopAutoscaler := startAutoscaler(o, store, configured, userSetup)
-	defer stopAutoscaler()
+	elector, stopElection := startLeaderElection(ctx, o, reg, store, configured, userSetup, logger)
+	defer stopElection()
+	if elector != nil {
+		server.SetElector(elector)
+	}
 
 	httpServer := &http.Server{Addr: fmt.Sprintf(":%d", o.port), Handler: server.Routes()}
 	fatal := startServersAsync(
@@ -88,17 +94,41 @@ func startServersAsync(serveHTTP, serveGRPC func() error) <-chan error {
 	return fatal
 }
 
-func startAutoscaler(o *Options, store storage.Storage, configured, userSetup bool) func() {
-	if (o.mode == "api" || o.mode == "standalone") && configured && userSetup && !o.disableAutoscaler && store != nil {
+// startLeaderElection campaigns for the platform lease so that, of several API
+// replicas, only one runs the autoscaler and the registry's maintenance loops.
+// The lease lives in etcd when that is the configured state store, and in the
+// primary database otherwise. The returned stop function stops the leader's
+// jobs and releases the lease so another replica can take over at once.
+func startLeaderElection(ctx context.Context, o *Options, reg *registry.Registry, store storage.Storage, configured, userSetup bool, logger hermod.Logger) (*leader.Elector, func()) {
+	if !configured || !userSetup || store == nil {
+		return nil, func() {}
+	}
+	var leases leader.LeaseStore = store
+	if etcd, ok := reg.StateStore().(*state.EtcdStateStore); ok {
+		leases = etcd
+	}
+	host, _ := os.Hostname()
+	e := leader.NewElector(leases, leader.DefaultLeaseName, fmt.Sprintf("%s-%d", host, os.Getpid()))
+	e.SetLogger(logger)
+	reg.SetElector(e)
+
+	if !o.disableAutoscaler {
 		manager := &autoscaler.KubernetesWorkerManager{
 			Namespace: "hermod", Deployment: "hermod-worker", Storage: store,
 		}
-		as := autoscaler.NewAutoscaler(store, manager)
-		as.Start()
-		fmt.Println("Autoscaler service started")
-		return as.Stop
+		e.Register("autoscaler", autoscaler.NewAutoscaler(store, manager).Run)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx)
+	}()
+	fmt.Printf("Leader election started as %s\n", e.ID())
+	return e, func() {
+		cancel()
+		<-done
 	}
-	return func() {}
 }
 
 func runWorkerOnly(ctx context.Context, logger hermod.Logger, configured, userSetup bool) {
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	dlqWorkflow    string
+	dlqSink        string
+	dlqClass       string
+	dlqStatus      string
+	dlqSince       string
+	dlqListLimit   int
+	dlqReplayLimit int
+	dlqPayload     string
+	dlqPayloadFile string
+	dlqData        string
+	dlqTargetNode  string
+	dlqTargetSink  string
+	dlqRate        float64
+	dlqAll         bool
+)
+
+func init() {
+	rootCmd.AddCommand(dlqCmd)
+	dlqCmd.AddCommand(dlqListCmd)
+	dlqCmd.AddCommand(dlqShowCmd)
+	dlqCmd.AddCommand(dlqEditCmd)
+	dlqCmd.AddCommand(dlqReplayCmd)
+	dlqCmd.AddCommand(dlqPurgeCmd)
+
+	for _, c := range []*cobra.Command{dlqListCmd, dlqReplayCmd, dlqPurgeCmd} {
+		c.Flags().StringVar(&dlqWorkflow, "workflow", "", "Only dead letters of this workflow")
+		c.Flags().StringVar(&dlqSink, "sink", "", "Only dead letters rejected by this sink")
+		c.Flags().StringVar(&dlqClass, "class", "", "Only this error class (sink_write, permanent, validation, safe_mode, unroutable)")
+		c.Flags().StringVar(&dlqSince, "since", "", "Only dead letters newer than this duration, e.g. 1h")
+	}
+	dlqListCmd.Flags().StringVar(&dlqStatus, "status", "", "Only this status (pending, replayed)")
+	dlqListCmd.Flags().IntVar(&dlqListLimit, "limit", 50, "Maximum number of dead letters to list")
+	dlqPurgeCmd.Flags().StringVar(&dlqStatus, "status", "", "Only this status (pending, replayed)")
+	dlqPurgeCmd.Flags().BoolVar(&dlqAll, "all", false, "Purge every dead letter when no filter is given")
+
+	dlqEditCmd.Flags().StringVar(&dlqPayload, "payload", "", "Replacement payload")
+	dlqEditCmd.Flags().StringVar(&dlqPayloadFile, "payload-file", "", "Read the replacement payload from a file")
+	dlqEditCmd.Flags().StringVar(&dlqData, "data", "", "Replacement data as a JSON object")
+
+	dlqReplayCmd.Flags().StringVar(&dlqTargetNode, "to-node", "", "Re-enter the workflow at this node")
+	dlqReplayCmd.Flags().StringVar(&dlqTargetSink, "to-sink", "", "Write straight to this sink")
+	dlqReplayCmd.Flags().BoolVar(&dlqAll, "all", false, "Replay every pending dead letter matching the filters")
+	dlqReplayCmd.Flags().Float64Var(&dlqRate, "rate", 0, "Maximum replays per second with --all")
+	dlqReplayCmd.Flags().IntVar(&dlqReplayLimit, "limit", 100, "Maximum number of dead letters to replay with --all")
+}
+
+var dlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Inspect, edit, replay and purge dead letters",
+}
+
+type deadLetter struct {
+	ID              string            `json:"id"`
+	WorkflowID      string            `json:"workflow_id"`
+	SinkID          string            `json:"sink_id"`
+	MessageID       string            `json:"message_id"`
+	Operation       string            `json:"operation"`
+	Table           string            `json:"table"`
+	ErrorClass      string            `json:"error_class"`
+	Error           string            `json:"error"`
+	Payload         []byte            `json:"payload"`
+	Metadata        map[string]string `json:"metadata"`
+	Data            map[string]any    `json:"data"`
+	Status          string            `json:"status"`
+	ReplayCount     int               `json:"replay_count"`
+	LastReplayError string            `json:"last_replay_error"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+var dlqListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List dead letters",
+	Run: func(cmd *cobra.Command, args []string) {
+		q := url.Values{}
+		for k, v := range dlqSelector() {
+			q.Set(k, fmt.Sprint(v))
+		}
+		q.Set("limit", strconv.Itoa(dlqListLimit))
+
+		var page struct {
+			Data  []deadLetter `json:"data"`
+			Total int          `json:"total"`
+		}
+		if err := dlqCall(http.MethodGet, "/api/dlq?"+q.Encode(), nil, &page); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tWORKFLOW\tSINK\tCLASS\tSTATUS\tREPLAYS\tCREATED\tERROR")
+		for _, dl := range page.Data {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", dl.ID, dl.WorkflowID, dl.SinkID, dl.ErrorClass,
+				dl.Status, dl.ReplayCount, dl.CreatedAt.Local().Format(time.DateTime), truncate(dl.Error, 60))
+		}
+		tw.Flush()
+		fmt.Printf("\n%d of %d dead letters\n", len(page.Data), page.Total)
+	},
+}
+
+var dlqShowCmd = &cobra.Command{
+	Use:   "show [id]",
+	Short: "Show a dead letter with its payload and error",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var dl deadLetter
+		if err := dlqCall(http.MethodGet, "/api/dlq/"+args[0], nil, &dl); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("ID:        %s\n", dl.ID)
+		fmt.Printf("Workflow:  %s\n", dl.WorkflowID)
+		fmt.Printf("Sink:      %s\n", dl.SinkID)
+		fmt.Printf("Message:   %s (%s %s)\n", dl.MessageID, dl.Operation, dl.Table)
+		fmt.Printf("Class:     %s\n", dl.ErrorClass)
+		fmt.Printf("Error:     %s\n", dl.Error)
+		fmt.Printf("Status:    %s (%d replays)\n", dl.Status, dl.ReplayCount)
+		if dl.LastReplayError != "" {
+			fmt.Printf("Last replay error: %s\n", dl.LastReplayError)
+		}
+		fmt.Printf("Created:   %s\n", dl.CreatedAt.Local().Format(time.RFC1123))
+		if len(dl.Payload) > 0 {
+			fmt.Printf("\nPayload:\n%s\n", dl.Payload)
+		}
+		if len(dl.Data) > 0 {
+			data, _ := json.MarshalIndent(dl.Data, "", "  ")
+			fmt.Printf("\nData:\n%s\n", data)
+		}
+	},
+}
+
+var dlqEditCmd = &cobra.Command{
+	Use:   "edit [id]",
+	Short: "Replace the payload or data of a dead letter before replaying it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		body := map[string]any{}
+		switch {
+		case dlqPayloadFile != "":
+			payload, err := os.ReadFile(dlqPayloadFile)
+			if err != nil {
+				fmt.Printf("Error reading file: %v\n", err)
+				return
+			}
+			body["payload"] = string(payload)
+		case cmd.Flags().Changed("payload"):
+			body["payload"] = dlqPayload
+		}
+		if dlqData != "" {
+			var data map[string]any
+			if err := json.Unmarshal([]byte(dlqData), &data); err != nil {
+				fmt.Printf("Invalid --data: %v\n", err)
+				return
+			}
+			body["data"] = data
+		}
+		if len(body) == 0 {
+			fmt.Println("Nothing to edit: pass --payload, --payload-file or --data")
+			return
+		}
+		if err := dlqCall(http.MethodPut, "/api/dlq/"+args[0], body, nil); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Dead letter %s updated\n", args[0])
+	},
+}
+
+var dlqReplayCmd = &cobra.Command{
+	Use:   "replay [id]",
+	Short: "Replay a dead letter, or with --all every one matching the filters",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 {
+			var dl deadLetter
+			target := map[string]string{"node_id": dlqTargetNode, "sink_id": dlqTargetSink}
+			if err := dlqCall(http.MethodPost, "/api/dlq/"+args[0]+"/replay", target, &dl); err != nil {
+				fmt.Printf("❌ Replay failed: %v\n", err)
+				return
+			}
+			fmt.Printf("✅ Dead letter %s replayed\n", dl.ID)
+			return
+		}
+		if !dlqAll {
+			fmt.Println("Pass a dead letter ID, or --all to replay every one matching the filters")
+			return
+		}
+
+		body := dlqSelector()
+		body["node_id"] = dlqTargetNode
+		body["target_sink_id"] = dlqTargetSink
+		body["rate"] = dlqRate
+		body["limit"] = dlqReplayLimit
+		var res struct {
+			Queued int `json:"queued"`
+		}
+		if err := dlqCall(http.MethodPost, "/api/dlq/replay", body, &res); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Replaying %d dead letters in the background; `hermodctl dlq list --status pending` shows the ones still failing\n", res.Queued)
+	},
+}
+
+var dlqPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete dead letters matching the filters",
+	Run: func(cmd *cobra.Command, args []string) {
+		body := dlqSelector()
+		body["all"] = dlqAll
+		var res struct {
+			Purged int `json:"purged"`
+		}
+		if err := dlqCall(http.MethodPost, "/api/dlq/purge", body, &res); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("🗑  Purged %d dead letters\n", res.Purged)
+	},
+}
+
+// dlqSelector collects the filter flags that are set.
+func dlqSelector() map[string]any {
+	sel := map[string]any{}
+	for k, v := range map[string]string{"workflow_id": dlqWorkflow, "sink_id": dlqSink, "error_class": dlqClass, "status": dlqStatus} {
+		if v != "" {
+			sel[k] = v
+		}
+	}
+	if dlqSince != "" {
+		if d, err := time.ParseDuration(dlqSince); err == nil {
+			sel["since"] = time.Now().Add(-d).UTC().Format(time.RFC3339)
+		}
+	}
+	return sel
+}
+
+// dlqCall sends a JSON request to the API and decodes the response into out
+// when it is non-nil.
+func dlqCall(method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	req, err := http.NewRequest(method, viper.GetString("url")+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := viper.GetString("key"); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
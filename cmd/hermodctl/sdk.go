@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/user/hermod/internal/api/sdkgen"
+)
+
+var (
+	sdkLanguage  string
+	sdkOutput    string
+	sdkSpecFile  string
+	sdkGoPackage string
+)
+
+func init() {
+	rootCmd.AddCommand(sdkCmd)
+	sdkCmd.AddCommand(sdkGenerateCmd)
+	sdkCmd.AddCommand(sdkSpecCmd)
+
+	sdkGenerateCmd.Flags().StringVarP(&sdkLanguage, "lang", "l", "go", "Target language ("+strings.Join(sdkgen.Languages, ", ")+")")
+	sdkGenerateCmd.Flags().StringVarP(&sdkOutput, "out", "o", "", "Output file (default: conventional file name for the language)")
+	sdkGenerateCmd.Flags().StringVar(&sdkSpecFile, "spec", "", "Read the OpenAPI document from a file instead of the server")
+	sdkGenerateCmd.Flags().StringVar(&sdkGoPackage, "go-package", "hermod", "Package name for generated Go clients")
+	sdkSpecCmd.Flags().StringVarP(&sdkOutput, "out", "o", "", "Output file (default: stdout)")
+}
+
+var sdkCmd = &cobra.Command{
+	Use:   "sdk",
+	Short: "Generate typed API clients from the server's OpenAPI document",
+}
+
+var sdkSpecCmd = &cobra.Command{
+	Use:   "spec",
+	Short: "Download the OpenAPI document",
+	Run: func(cmd *cobra.Command, args []string) {
+		spec, err := fetchOpenAPISpec()
+		if err != nil {
+			fmt.Printf("Error fetching OpenAPI document: %v\n", err)
+			return
+		}
+		if sdkOutput == "" {
+			fmt.Println(string(spec))
+			return
+		}
+		if err := os.WriteFile(sdkOutput, spec, 0o644); err != nil {
+			fmt.Printf("Error writing file: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ OpenAPI document written to %s\n", sdkOutput)
+	},
+}
+
+var sdkGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a typed client SDK",
+	Run: func(cmd *cobra.Command, args []string) {
+		var spec []byte
+		var err error
+		if sdkSpecFile != "" {
+			spec, err = os.ReadFile(sdkSpecFile)
+		} else {
+			spec, err = fetchOpenAPISpec()
+		}
+		if err != nil {
+			fmt.Printf("Error loading OpenAPI document: %v\n", err)
+			return
+		}
+
+		gen := sdkgen.NewGenerator()
+		gen.GoPackage = sdkGoPackage
+		code, err := gen.Generate(context.Background(), sdkLanguage, spec)
+		if err != nil {
+			fmt.Printf("Error generating SDK: %v\n", err)
+			return
+		}
+
+		out := sdkOutput
+		if out == "" {
+			out = sdkgen.FileName(sdkLanguage)
+		}
+		if err := os.WriteFile(out, code, 0o644); err != nil {
+			fmt.Printf("Error writing file: %v\n", err)
+			return
+		}
+		fmt.Printf("✅ %s SDK written to %s\n", sdkLanguage, out)
+	},
+}
+
+func fetchOpenAPISpec() ([]byte, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, _ := http.NewRequest(http.MethodGet, viper.GetString("url")+"/api/openapi.json", nil)
+	if key := viper.GetString("key"); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,107 @@
+package hermod
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrorClass tells the engine how to react to a failed sink write.
+type ErrorClass int
+
+const (
+	// ErrorTransient failures may succeed on retry: timeouts, dropped
+	// connections, deadlocks. Unclassified errors are treated as transient.
+	ErrorTransient ErrorClass = iota
+	// ErrorThrottled failures mean the destination is shedding load. They are
+	// retried no sooner than the hinted delay and do not use up retries.
+	ErrorThrottled
+	// ErrorPermanent failures are caused by the message itself, such as a
+	// constraint violation or a malformed document, and fail the same way
+	// however often they are retried.
+	ErrorPermanent
+	// ErrorFatalConfig failures are caused by the sink's configuration, such
+	// as bad credentials or a missing table, and fail for every message.
+	ErrorFatalConfig
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorThrottled:
+		return "throttled"
+	case ErrorPermanent:
+		return "permanent"
+	case ErrorFatalConfig:
+		return "fatal_config"
+	default:
+		return "transient"
+	}
+}
+
+// ClassifiedError is an error annotated with how it should be handled.
+type ClassifiedError struct {
+	Class ErrorClass
+	// RetryAfter is the destination's hint for throttled errors; zero means
+	// none was given.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ClassifiedError) Error() string { return e.Err.Error() }
+func (e *ClassifiedError) Unwrap() error { return e.Err }
+
+// Classify annotates err with class. It returns nil for a nil err.
+func Classify(err error, class ErrorClass) error {
+	if err == nil {
+		return nil
+	}
+	return &ClassifiedError{Class: class, Err: err}
+}
+
+// Throttled annotates err as throttled with the destination's retry hint.
+func Throttled(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &ClassifiedError{Class: ErrorThrottled, RetryAfter: retryAfter, Err: err}
+}
+
+// ErrFenced reports that a write or checkpoint carried a fencing token older
+// than one already seen for the workflow: another worker has taken over its
+// lease. It is classified as ErrorFatalConfig so the engine stops at once.
+var ErrFenced = errors.New("stale fencing token")
+
+// ErrorClassifier is implemented by sinks that recognise their client's
+// errors. It is consulted for errors the sink did not already annotate.
+type ErrorClassifier interface {
+	ClassifyError(err error) (ErrorClass, time.Duration)
+}
+
+// ClassifyError reports how err should be handled. An error annotated with
+// Classify or Throttled keeps its class; otherwise c decides when non-nil.
+// Anything else, including cancellation, is transient.
+func ClassifyError(err error, c ErrorClassifier) (ErrorClass, time.Duration) {
+	if err == nil {
+		return ErrorTransient, 0
+	}
+	var ce *ClassifiedError
+	if errors.As(err, &ce) {
+		return ce.Class, ce.RetryAfter
+	}
+	if errors.Is(err, ErrFenced) {
+		return ErrorFatalConfig, 0
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTransient, 0
+	}
+	if c != nil {
+		return c.ClassifyError(err)
+	}
+	return ErrorTransient, 0
+}
+
+// IsFatalConfig reports whether err was classified as a configuration error.
+func IsFatalConfig(err error) bool {
+	class, _ := ClassifyError(err, nil)
+	return class == ErrorFatalConfig
+}
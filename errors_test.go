@@ -0,0 +1,47 @@
+package hermod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fixedClassifier ErrorClass
+
+func (c fixedClassifier) ClassifyError(error) (ErrorClass, time.Duration) {
+	return ErrorClass(c), 0
+}
+
+func TestClassifyError(t *testing.T) {
+	base := errors.New("boom")
+	tests := []struct {
+		name      string
+		err       error
+		c         ErrorClassifier
+		want      ErrorClass
+		wantAfter time.Duration
+	}{
+		{"unclassified is transient", base, nil, ErrorTransient, 0},
+		{"annotation survives wrapping", fmt.Errorf("write: %w", Classify(base, ErrorPermanent)), nil, ErrorPermanent, 0},
+		{"throttle hint is kept", Throttled(base, 2*time.Second), nil, ErrorThrottled, 2 * time.Second},
+		{"annotation wins over the classifier", Classify(base, ErrorFatalConfig), fixedClassifier(ErrorPermanent), ErrorFatalConfig, 0},
+		{"classifier decides the rest", base, fixedClassifier(ErrorPermanent), ErrorPermanent, 0},
+		{"cancellation is never permanent", fmt.Errorf("write: %w", context.Canceled), fixedClassifier(ErrorPermanent), ErrorTransient, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, after := ClassifyError(tt.err, tt.c)
+			if got != tt.want || after != tt.wantAfter {
+				t.Fatalf("ClassifyError() = %s, %v; want %s, %v", got, after, tt.want, tt.wantAfter)
+			}
+		})
+	}
+	if Classify(nil, ErrorPermanent) != nil || Throttled(nil, time.Second) != nil {
+		t.Fatal("annotating a nil error must return nil")
+	}
+	if !IsFatalConfig(fmt.Errorf("stop: %w", Classify(base, ErrorFatalConfig))) {
+		t.Fatal("expected IsFatalConfig to see through wrapping")
+	}
+}
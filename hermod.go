@@ -133,6 +133,23 @@ type StreamLivenessReporter interface {
 	StreamSilenceThreshold() time.Duration
 }
 
+// BufferAcker is an optional interface for sources whose producers are told
+// about a message before its sinks have it. The engine calls Buffered once a
+// message read from the source has been written to the workflow's buffer; Ack
+// still follows once every sink has accepted the message.
+type BufferAcker interface {
+	Buffered(ctx context.Context, msg Message)
+}
+
+// Nacker is an optional interface for sources whose producers wait for the
+// outcome of every message. The engine calls Nack when a message read from
+// the source will not reach its sinks: when it fails validation, routing or
+// a sink write, in which case no Ack follows, or when it is dead-lettered,
+// in which case Nack precedes the Ack that lets the source move on.
+type Nacker interface {
+	Nack(ctx context.Context, msg Message, err error)
+}
+
 // ColumnDiscoverer defines an optional interface for discovering columns of a table.
 type ColumnDiscoverer interface {
 	DiscoverColumns(ctx context.Context, table string) ([]ColumnInfo, error)
@@ -209,6 +226,32 @@ type BatchSink interface {
 	WriteBatch(ctx context.Context, msgs []Message) error
 }
 
+// BatchResultSink is an optional interface for batch sinks whose destination
+// reports an outcome per item, such as a bulk API. WriteBatchResults returns
+// one error per message, nil for those written, so the engine can acknowledge
+// the successes and retry or dead-letter only the failures; a nil slice means
+// every message was written. The second
+// return value is for failures of the request as a whole, when no outcome is
+// known for any message.
+type BatchResultSink interface {
+	BatchSink
+	WriteBatchResults(ctx context.Context, msgs []Message) ([]error, error)
+}
+
+// BatchError reduces the outcome of WriteBatchResults to the single error
+// WriteBatch returns: the request's error, or else the first message's.
+func BatchError(errs []error, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, e := range errs {
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
 // IdempotencyReporter is an optional interface for sinks to report whether the
 // last successful Write/WriteBatch resulted in a deduplicated (skipped) write
 // and/or a payload conflict. Engines can use this to emit standardized metrics.
@@ -240,6 +283,44 @@ type TwoPhaseCommit interface {
 	RollbackPrepared(ctx context.Context, txID string) error
 }
 
+// FencingTokenKey is the source-state key under which a checkpoint records
+// the fencing token of the lease it was taken under.
+const FencingTokenKey = "_hermod_fencing_token"
+
+// FencedSink is an optional interface for sinks that can tell writes from
+// the current lease holder of a workflow apart from those of a worker that
+// has lost its lease. Tokens only ever increase for a scope. The sink stamps
+// the token into the transaction IDs it hands out and, where the destination
+// can record it, fails writes with ErrFenced once it has seen a higher token
+// for the same scope.
+type FencedSink interface {
+	Sink
+	SetFencingToken(scope string, token int64)
+}
+
+// CheckpointCommitter is an optional interface for sinks that stage writes
+// durably and publish them in bulk, such as table formats. The engine calls
+// CommitCheckpoint during each checkpoint, once every in-flight message has
+// been written, and fails the checkpoint when it returns an error.
+type CheckpointCommitter interface {
+	Sink
+	CommitCheckpoint(ctx context.Context) error
+}
+
+// SchemaLookup returns the type ("avro", "json" or "protobuf") and content
+// of the latest version of a subject in the schema registry.
+type SchemaLookup func(ctx context.Context, subject string) (schemaType, content string, err error)
+
+// SchemaAwareSink is an optional interface for sinks that derive the schema
+// of what they write from upstream metadata, such as columnar file formats.
+// Before a workflow starts, the registry hands them the workflow's sources,
+// to discover the columns of the tables messages come from, and a lookup
+// into the schema registry.
+type SchemaAwareSink interface {
+	Sink
+	SetSchemaSources(columns ColumnDiscoverer, schemas SchemaLookup)
+}
+
 // Formatter defines the interface for formatting messages before they are written to a sink.
 type Formatter interface {
 	Format(msg Message) ([]byte, error)
@@ -265,6 +346,38 @@ type StateStore interface {
 	Delete(ctx context.Context, key string) error
 }
 
+// KeyedStateStore is implemented by state stores that can update keys
+// atomically, so that workers and shards sharing the store never lose
+// updates. CompareAndSwap and Increment keep a key's expiry when ttl is
+// zero, and keys written without a ttl do not expire.
+type KeyedStateStore interface {
+	StateStore
+	// CompareAndSwap sets key to value if it holds old, where a nil old means
+	// the key does not exist, and reports whether it did.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error)
+	// Increment adds delta to the decimal number at key, which is 0 when
+	// the key does not exist, and returns the sum.
+	Increment(ctx context.Context, key string, delta float64, ttl time.Duration) (float64, error)
+	// SetWithTTL sets key to value, expiring it after ttl.
+	SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Scan returns up to limit keys starting with prefix and their values,
+	// or every such key when limit is 0.
+	Scan(ctx context.Context, prefix string, limit int) (map[string][]byte, error)
+	// GetMany returns the values of the keys that exist.
+	GetMany(ctx context.Context, keys []string) (map[string][]byte, error)
+	// SetMany sets every key of entries in one write.
+	SetMany(ctx context.Context, entries map[string][]byte) error
+}
+
+// RateLimitStore is implemented by state stores that can meter a rate limit
+// atomically, so every worker sharing the store draws from one budget.
+type RateLimitStore interface {
+	// Reserve takes one unit from the bucket at key, which refills at rate
+	// units per second and holds at most burst units. If the bucket is empty
+	// nothing is taken and Reserve returns how long until a unit is available.
+	Reserve(ctx context.Context, key string, rate float64, burst int) (time.Duration, error)
+}
+
 // TraceStep represents a single step in a message's journey.
 type TraceStep struct {
 	NodeID    string         `json:"node_id"`
@@ -281,6 +394,15 @@ type TraceRecorder interface {
 	RecordStep(ctx context.Context, workflowID, messageID string, step TraceStep)
 }
 
+// DeadLetterRecorder persists dead-lettered messages so they can be inspected
+// and replayed. The engine calls it after stamping the message with the
+// _hermod_failed_sink, _hermod_last_error and _hermod_error_class metadata.
+// Without a dead letter sink the recorder keeps the only copy, so a message
+// it fails to record is not acknowledged.
+type DeadLetterRecorder interface {
+	RecordDeadLetter(ctx context.Context, workflowID string, msg Message) error
+}
+
 // OutboxItem represents a message persisted for reliable delivery.
 type OutboxItem struct {
 	ID         string            `json:"id"`
@@ -20,12 +20,23 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/user/hermod/internal/ai"
+	"github.com/user/hermod/internal/api/openapi"
 	"github.com/user/hermod/internal/config"
 	"github.com/user/hermod/internal/engine/registry"
+	"github.com/user/hermod/internal/leader"
 	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/watch"
 	"github.com/user/hermod/pkg/infra/filestorage"
 )
 
+// Router is the subset of *http.ServeMux the transport packages register their
+// routes on. Accepting it instead of the concrete mux lets the API server wrap
+// registration (to build the OpenAPI document) without the handlers knowing.
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
 type WorkerUpdater interface {
 	SetStorage(s storage.Storage)
 	// RequestShutdown asks an in-process worker whose GUID matches id to begin
@@ -42,6 +53,14 @@ type Handler struct {
 	Config      *config.Config
 	ConfigPath  string
 	FileStorage filestorage.Storage
+	// OpenAPI documents every route registered on the API mux.
+	OpenAPI *openapi.Handler
+	// Watch pushes workflow and resource changes and worker commands to
+	// workers watching /api/workers/{id}/watch.
+	Watch *watch.Feed
+	// Elector decides which API replica runs the singleton background
+	// services. Nil when this process does not take part in an election.
+	Elector *leader.Elector
 
 	// StoreMu guards concurrent reads/writes to storage during hot-swap.
 	StoreMu sync.RWMutex
@@ -186,6 +205,7 @@ func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 			path == "/api/auth/2fa/setup/pending" ||
 			path == "/api/auth/2fa/verify/pending" ||
 			path == "/api/config/status" || path == "/api/version" ||
+			path == "/api/openapi.json" ||
 			strings.HasPrefix(path, "/api/webhooks/") ||
 			strings.HasPrefix(path, "/api/forms/") ||
 			strings.HasPrefix(path, "/forms/") ||
@@ -891,6 +911,7 @@ var allowedUploadExtensions = map[string]struct{}{
 	".crt":     {},
 	".key":     {},
 	".sql":     {},
+	".proto":   {},
 	".gz":      {},
 	".zip":     {},
 }
@@ -0,0 +1,329 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/leader"
+	"github.com/user/hermod/internal/storage"
+)
+
+// routeSpec describes the typed shape of one registered pattern. Request and
+// Response are sample values whose Go types are reflected into schemas; a
+// Paginated Response is the element type of the {data, total} envelope.
+type routeSpec struct {
+	ID        string
+	Summary   string
+	Path      string // overrides the pattern path, e.g. for subtree patterns
+	Request   any
+	Response  any
+	Status    int
+	Paginated bool
+	Public    bool
+	Query     []string
+}
+
+func (s routeSpec) statusOr(def int) int {
+	if s.Status != 0 {
+		return s.Status
+	}
+	return def
+}
+
+// StatusResponse is the {"status": "..."} acknowledgement most mutating
+// endpoints answer with.
+type StatusResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// DispatchResponse is returned by endpoints that hand a message to a workflow.
+type DispatchResponse struct {
+	Status string `json:"status"`
+	ID     string `json:"id"`
+}
+
+// LoginRequest is the body of POST /api/login.
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse is returned by POST /api/login. When two-factor
+// authentication is required only the pending fields are set.
+type LoginResponse struct {
+	Token                   string `json:"token,omitempty"`
+	TwoFactorRequired       bool   `json:"two_factor_required,omitempty"`
+	TwoFactorEnrollRequired bool   `json:"two_factor_enroll_required,omitempty"`
+	UserID                  string `json:"user_id,omitempty"`
+	PendingToken            string `json:"pending_token,omitempty"`
+}
+
+// ConfigStatus is returned by GET /api/config/status.
+type ConfigStatus struct {
+	Configured bool `json:"configured"`
+	UserSetup  bool `json:"user_setup"`
+}
+
+// VersionResponse is returned by GET /api/version.
+type VersionResponse struct {
+	Version string `json:"version"`
+}
+
+// SchemaRegistration is the body of POST /api/schemas.
+type SchemaRegistration struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// SchemaRegistered is returned by POST /api/schemas.
+type SchemaRegistered struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	Status  string `json:"status"`
+}
+
+// SchemaCompatibilityCheck is the body of POST
+// /api/schemas/{name}/compatibility. A version checks that version alone.
+type SchemaCompatibilityCheck struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Version int    `json:"version,omitempty"`
+}
+
+// SchemaCompatibilityResult is returned by POST
+// /api/schemas/{name}/compatibility.
+type SchemaCompatibilityResult struct {
+	Compatible bool     `json:"compatible"`
+	Level      string   `json:"level"`
+	Messages   []string `json:"messages"`
+}
+
+// SchemaCompatibilityLevel is the body of PUT
+// /api/schemas/{name}/compatibility-level. An empty level follows the global
+// level again.
+type SchemaCompatibilityLevel struct {
+	Level string `json:"level"`
+}
+
+// SchemaCompatibilityLevelStatus is returned by the compatibility-level
+// endpoints. Explicit is false when the subject follows the global level.
+type SchemaCompatibilityLevelStatus struct {
+	Name     string `json:"name"`
+	Level    string `json:"level"`
+	Explicit bool   `json:"explicit"`
+}
+
+// ApprovalDecision is the body of the approve and reject endpoints.
+type ApprovalDecision struct {
+	Notes    string         `json:"notes,omitempty"`
+	FormData map[string]any `json:"form_data,omitempty"`
+}
+
+// DeadLetterUpdate is the body of PUT /api/dlq/{id}. Omitted fields are left
+// unchanged.
+type DeadLetterUpdate struct {
+	Payload  *string           `json:"payload,omitempty"`
+	Data     map[string]any    `json:"data,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// DeadLetterReplay is the body of POST /api/dlq/{id}/replay. Empty, the dead
+// letter goes back to the sink that rejected it.
+type DeadLetterReplay struct {
+	NodeID string `json:"node_id,omitempty"`
+	SinkID string `json:"sink_id,omitempty"`
+}
+
+// DeadLetterSelection selects the dead letters a bulk replay or purge acts on.
+type DeadLetterSelection struct {
+	WorkflowID string `json:"workflow_id,omitempty"`
+	SinkID     string `json:"sink_id,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+	Status     string `json:"status,omitempty"`
+	Search     string `json:"search,omitempty"`
+	Since      string `json:"since,omitempty"`
+	Until      string `json:"until,omitempty"`
+}
+
+// DeadLetterBulkReplay is the body of POST /api/dlq/replay.
+type DeadLetterBulkReplay struct {
+	DeadLetterSelection
+	NodeID       string  `json:"node_id,omitempty"`
+	TargetSinkID string  `json:"target_sink_id,omitempty"`
+	Rate         float64 `json:"rate,omitempty"`
+	Limit        int     `json:"limit,omitempty"`
+}
+
+// DeadLetterReplayStarted is returned by POST /api/dlq/replay. The replay
+// runs in the background and records each outcome on its dead letter.
+type DeadLetterReplayStarted struct {
+	Status string `json:"status"`
+	Queued int    `json:"queued"`
+}
+
+// DeadLetterPurge is the body of POST /api/dlq/purge.
+type DeadLetterPurge struct {
+	DeadLetterSelection
+	All bool `json:"all,omitempty"`
+}
+
+// DeadLetterPurgeResult is returned by POST /api/dlq/purge.
+type DeadLetterPurgeResult struct {
+	Purged int `json:"purged"`
+}
+
+// WorkflowStatusUpdate is the body of PATCH /api/workflows/{id}/status.
+type WorkflowStatusUpdate struct {
+	Status string `json:"status"`
+}
+
+// PluginRef is the body of the marketplace install/uninstall endpoints.
+type PluginRef struct {
+	ID string `json:"id"`
+}
+
+// SDKRequest is the body of POST /api/sdk.
+type SDKRequest struct {
+	Language string `json:"language"`
+}
+
+// catalog maps registered patterns to their typed shape. Patterns missing
+// here still appear in the document with untyped bodies; adding an entry is
+// how an endpoint gets first-class types in the generated SDKs.
+var catalog = map[string]routeSpec{
+	// Auth and users
+	"POST /api/login":           {ID: "login", Summary: "Authenticate and obtain a session token", Request: LoginRequest{}, Response: LoginResponse{}, Public: true},
+	"POST /api/forgot-password": {ID: "forgotPassword", Summary: "Request a password reset", Response: StatusResponse{}, Public: true},
+	"GET /api/me":               {ID: "getMe", Summary: "Current user", Response: storage.User{}},
+	"PUT /api/me":               {ID: "updateMe", Summary: "Update the current user", Request: storage.User{}, Response: storage.User{}},
+	"GET /api/users":            {ID: "listUsers", Summary: "List users", Response: storage.User{}, Paginated: true},
+	"GET /api/users/{id}":       {ID: "getUser", Summary: "Get a user", Response: storage.User{}},
+	"POST /api/users":           {ID: "createUser", Summary: "Create a user", Request: storage.User{}, Response: storage.User{}, Status: http.StatusCreated},
+	"PUT /api/users/{id}":       {ID: "updateUser", Summary: "Update a user", Request: storage.User{}, Response: storage.User{}},
+	"DELETE /api/users/{id}":    {ID: "deleteUser", Summary: "Delete a user", Status: http.StatusNoContent},
+	"GET /api/vhosts":           {ID: "listVHosts", Summary: "List virtual hosts", Response: storage.VHost{}, Paginated: true},
+	"GET /api/vhosts/{id}":      {ID: "getVHost", Summary: "Get a virtual host", Response: storage.VHost{}},
+	"POST /api/vhosts":          {ID: "createVHost", Summary: "Create a virtual host", Request: storage.VHost{}, Response: storage.VHost{}, Status: http.StatusCreated},
+	"PUT /api/vhosts/{id}":      {ID: "updateVHost", Summary: "Update a virtual host", Request: storage.VHost{}, Response: storage.VHost{}},
+	"DELETE /api/vhosts/{id}":   {ID: "deleteVHost", Summary: "Delete a virtual host", Status: http.StatusNoContent},
+
+	// Sources
+	"GET /api/sources":                {ID: "listSources", Summary: "List sources", Response: storage.Source{}, Paginated: true},
+	"GET /api/sources/{id}":           {ID: "getSource", Summary: "Get a source", Response: storage.Source{}},
+	"POST /api/sources":               {ID: "createSource", Summary: "Create a source", Request: storage.Source{}, Response: storage.Source{}, Status: http.StatusCreated},
+	"PUT /api/sources/{id}":           {ID: "updateSource", Summary: "Update a source", Request: storage.Source{}, Response: storage.Source{}},
+	"DELETE /api/sources/{id}":        {ID: "deleteSource", Summary: "Delete a source", Status: http.StatusNoContent},
+	"POST /api/sources/test":          {ID: "testSource", Summary: "Test source connectivity", Request: storage.Source{}, Response: StatusResponse{}},
+	"POST /api/sources/{id}/snapshot": {ID: "triggerSourceSnapshot", Summary: "Trigger a snapshot", Response: StatusResponse{}},
+	"POST /api/sources/discover/columns": {
+		ID: "discoverSourceColumns", Summary: "Discover table columns", Request: storage.Source{}, Response: []hermod.ColumnInfo{},
+	},
+	"POST /api/sources/discover/databases": {ID: "discoverSourceDatabases", Summary: "Discover databases", Request: storage.Source{}, Response: []string{}},
+	"POST /api/sources/discover/tables":    {ID: "discoverSourceTables", Summary: "Discover tables", Request: storage.Source{}, Response: []string{}},
+
+	// Sinks
+	"GET /api/sinks":         {ID: "listSinks", Summary: "List sinks", Response: storage.Sink{}, Paginated: true},
+	"GET /api/sinks/{id}":    {ID: "getSink", Summary: "Get a sink", Response: storage.Sink{}},
+	"POST /api/sinks":        {ID: "createSink", Summary: "Create a sink", Request: storage.Sink{}, Response: storage.Sink{}, Status: http.StatusCreated},
+	"PUT /api/sinks/{id}":    {ID: "updateSink", Summary: "Update a sink", Request: storage.Sink{}, Response: storage.Sink{}},
+	"DELETE /api/sinks/{id}": {ID: "deleteSink", Summary: "Delete a sink", Status: http.StatusNoContent},
+	"POST /api/sinks/test":   {ID: "testSink", Summary: "Test sink connectivity", Request: storage.Sink{}, Response: StatusResponse{}},
+	"POST /api/sinks/discover/columns": {
+		ID: "discoverSinkColumns", Summary: "Discover table columns", Request: storage.Sink{}, Response: []hermod.ColumnInfo{},
+	},
+	"POST /api/sinks/discover/databases": {ID: "discoverSinkDatabases", Summary: "Discover databases", Request: storage.Sink{}, Response: []string{}},
+	"POST /api/sinks/discover/tables":    {ID: "discoverSinkTables", Summary: "Discover tables", Request: storage.Sink{}, Response: []string{}},
+
+	// Workflows
+	"GET /api/workflows":               {ID: "listWorkflows", Summary: "List workflows", Response: storage.Workflow{}, Paginated: true, Query: []string{"workspace_id", "worker_id", "active"}},
+	"GET /api/workflows/{id}":          {ID: "getWorkflow", Summary: "Get a workflow", Response: storage.Workflow{}},
+	"POST /api/workflows":              {ID: "createWorkflow", Summary: "Create a workflow", Request: storage.Workflow{}, Response: storage.Workflow{}, Status: http.StatusCreated},
+	"PUT /api/workflows/{id}":          {ID: "updateWorkflow", Summary: "Update a workflow", Request: storage.Workflow{}, Response: storage.Workflow{}},
+	"DELETE /api/workflows/{id}":       {ID: "deleteWorkflow", Summary: "Delete a workflow", Status: http.StatusNoContent},
+	"POST /api/workflows/{id}/toggle":  {ID: "toggleWorkflow", Summary: "Start or stop a workflow", Response: storage.Workflow{}},
+	"PATCH /api/workflows/{id}/status": {ID: "updateWorkflowStatus", Summary: "Set a workflow's status", Request: WorkflowStatusUpdate{}},
+	"POST /api/workflows/{id}/lease":   {ID: "acquireWorkflowLease", Summary: "Acquire a workflow lease for a worker", Request: storage.WorkflowLease{}, Response: storage.WorkflowLease{}},
+	"PUT /api/workflows/{id}/lease":    {ID: "renewWorkflowLease", Summary: "Renew a workflow lease", Request: storage.WorkflowLease{}, Response: storage.WorkflowLease{}},
+	"DELETE /api/workflows/{id}/lease": {ID: "releaseWorkflowLease", Summary: "Release a workflow lease", Request: storage.WorkflowLease{}, Response: storage.WorkflowLease{}},
+	"GET /api/workflows/{id}/health":   {ID: "getWorkflowHealth", Summary: "Workflow health", Response: storage.WorkflowHealth{}},
+	"GET /api/workflows/{id}/versions": {ID: "listWorkflowVersions", Summary: "List workflow versions", Response: []storage.WorkflowVersion{}},
+	"POST /api/workflows/{id}/rebuild": {ID: "rebuildWorkflow", Summary: "Rebuild a workflow from its source", Response: StatusResponse{}},
+	"GET /api/workflows/{id}/traces":   {ID: "listMessageTraces", Summary: "List message traces", Response: []storage.MessageTrace{}, Query: []string{"limit", "offset"}},
+	"GET /api/workflows/{id}/traces/":  {ID: "getMessageTrace", Summary: "Get one message trace", Path: "/api/workflows/{id}/traces/{message_id}", Response: storage.MessageTrace{}},
+	"GET /api/workflows/{id}/versions/{version}": {
+		ID: "getWorkflowVersion", Summary: "Get a workflow version", Response: storage.WorkflowVersion{},
+	},
+	"POST /api/workflows/{id}/rollback/{version}": {
+		ID: "rollbackWorkflow", Summary: "Roll a workflow back to a version", Response: storage.Workflow{},
+	},
+	"GET /api/workspaces":         {ID: "listWorkspaces", Summary: "List workspaces", Response: []storage.Workspace{}},
+	"POST /api/workspaces":        {ID: "createWorkspace", Summary: "Create a workspace", Request: storage.Workspace{}, Response: storage.Workspace{}, Status: http.StatusCreated},
+	"DELETE /api/workspaces/{id}": {ID: "deleteWorkspace", Summary: "Delete a workspace", Status: http.StatusNoContent},
+
+	// Workers
+	"GET /api/workers":                  {ID: "listWorkers", Summary: "List workers", Response: storage.Worker{}, Paginated: true},
+	"GET /api/workers/{id}":             {ID: "getWorker", Summary: "Get a worker", Response: storage.Worker{}},
+	"POST /api/workers":                 {ID: "createWorker", Summary: "Register a worker", Request: storage.Worker{}, Response: storage.Worker{}, Status: http.StatusCreated},
+	"PUT /api/workers/{id}":             {ID: "updateWorker", Summary: "Update a worker", Request: storage.Worker{}, Response: storage.Worker{}},
+	"DELETE /api/workers/{id}":          {ID: "deleteWorker", Summary: "Delete a worker", Status: http.StatusNoContent},
+	"GET /api/workers/recommend":        {ID: "recommendWorker", Summary: "Least loaded worker", Response: storage.Worker{}},
+	"POST /api/workers/{id}/heartbeat":  {ID: "workerHeartbeat", Summary: "Report worker load", Status: http.StatusNoContent},
+	"POST /api/workers/{id}/shutdown":   {ID: "shutdownWorker", Summary: "Request a graceful worker shutdown", Status: http.StatusAccepted},
+	"POST /api/workers/{id}/start":      {ID: "startWorker", Summary: "Start a local worker process", Status: http.StatusAccepted},
+	"GET /api/dashboard/stats":          {ID: "getDashboardStats", Summary: "Dashboard statistics", Response: storage.DashboardStats{}, Query: []string{"vhost"}},
+	"GET /api/infra/lineage":            {ID: "getLineage", Summary: "Source-to-sink lineage", Response: []storage.LineageEdge{}},
+	"GET /api/config/status":            {ID: "getConfigStatus", Summary: "Setup status", Response: ConfigStatus{}, Public: true},
+	"GET /api/config/leader":            {ID: "getLeaderStatus", Summary: "Leader election status", Response: leader.Status{}},
+	"GET /api/version":                  {ID: "getVersion", Summary: "Server version", Response: VersionResponse{}, Public: true},
+	"GET /api/openapi.json":             {ID: "getOpenAPIDocument", Summary: "This document", Public: true},
+	"POST /api/sdk":                     {ID: "generateSDK", Summary: "Generate a typed client SDK", Request: SDKRequest{}},
+	"POST /api/webhooks/{path...}":      {ID: "publishWebhook", Summary: "Publish to a webhook source", Request: map[string]any{}, Response: DispatchResponse{}, Public: true},
+	"GET /api/webhooks/requests":        {ID: "listWebhookRequests", Summary: "List recorded webhook requests", Response: storage.WebhookRequest{}, Paginated: true},
+	"GET /api/marketplace/plugins":      {ID: "listPlugins", Summary: "List marketplace plugins", Response: []storage.Plugin{}},
+	"GET /api/marketplace/plugins/{id}": {ID: "getPlugin", Summary: "Get a marketplace plugin", Response: storage.Plugin{}},
+	"POST /api/marketplace/install":     {ID: "installPlugin", Summary: "Install a plugin", Request: PluginRef{}, Response: StatusResponse{}},
+	"POST /api/marketplace/uninstall":   {ID: "uninstallPlugin", Summary: "Uninstall a plugin", Request: PluginRef{}, Response: StatusResponse{}},
+
+	// Logs and audit
+	"GET /api/logs":        {ID: "listLogs", Summary: "List logs", Response: storage.Log{}, Paginated: true, Query: []string{"workflow_id", "source_id", "sink_id", "level", "action"}},
+	"POST /api/logs":       {ID: "createLog", Summary: "Write a log entry", Request: storage.Log{}, Status: http.StatusCreated},
+	"POST /api/logs/batch": {ID: "createLogs", Summary: "Write a batch of log entries", Request: []storage.Log{}, Status: http.StatusCreated},
+	"/api/audit-logs":      {ID: "listAuditLogs", Summary: "List audit logs", Response: storage.AuditLog{}, Paginated: true, Query: []string{"user_id", "entity_type", "entity_id", "action"}},
+
+	// Schemas
+	"GET /api/schemas":                {ID: "listSchemas", Summary: "List registered schemas", Response: []storage.Schema{}},
+	"POST /api/schemas":               {ID: "registerSchema", Summary: "Register a schema version", Request: SchemaRegistration{}, Response: SchemaRegistered{}},
+	"GET /api/schemas/{name}":         {ID: "getLatestSchema", Summary: "Latest version of a schema", Response: storage.Schema{}},
+	"GET /api/schemas/{name}/history": {ID: "getSchemaHistory", Summary: "All versions of a schema", Response: []storage.Schema{}},
+	"POST /api/schemas/{name}/compatibility": {
+		ID: "testSchemaCompatibility", Summary: "Check a schema against a subject's compatibility level", Request: SchemaCompatibilityCheck{}, Response: SchemaCompatibilityResult{},
+	},
+	"GET /api/schemas/{name}/compatibility-level": {
+		ID: "getSchemaCompatibilityLevel", Summary: "Compatibility level of a subject", Response: SchemaCompatibilityLevelStatus{},
+	},
+	"PUT /api/schemas/{name}/compatibility-level": {
+		ID: "setSchemaCompatibilityLevel", Summary: "Set the compatibility level of a subject", Request: SchemaCompatibilityLevel{}, Response: SchemaCompatibilityLevelStatus{},
+	},
+	"GET /api/schema-registry/":    {ID: "confluentRegistryGet", Summary: "Confluent Schema Registry API (read)", Path: "/api/schema-registry/{path}"},
+	"POST /api/schema-registry/":   {ID: "confluentRegistryPost", Summary: "Confluent Schema Registry API (register, look up, test)", Path: "/api/schema-registry/{path}"},
+	"PUT /api/schema-registry/":    {ID: "confluentRegistryPut", Summary: "Confluent Schema Registry API (set compatibility)", Path: "/api/schema-registry/{path}"},
+	"DELETE /api/schema-registry/": {ID: "confluentRegistryDelete", Summary: "Confluent Schema Registry API (clear compatibility)", Path: "/api/schema-registry/{path}"},
+
+	// Approvals
+	"GET /api/approvals":               {ID: "listApprovals", Summary: "List approvals", Response: storage.Approval{}, Paginated: true, Query: []string{"workflow_id", "status"}},
+	"GET /api/approvals/{id}":          {ID: "getApproval", Summary: "Get an approval", Response: storage.Approval{}},
+	"POST /api/approvals/{id}/approve": {ID: "approveApproval", Summary: "Approve a pending message", Request: ApprovalDecision{}, Response: StatusResponse{}},
+	"POST /api/approvals/{id}/reject":  {ID: "rejectApproval", Summary: "Reject a pending message", Request: ApprovalDecision{}, Response: StatusResponse{}},
+
+	// Dead letters
+	"GET /api/dlq":              {ID: "listDeadLetters", Summary: "List dead letters", Response: storage.DeadLetter{}, Paginated: true, Query: []string{"workflow_id", "sink_id", "error_class", "status", "since", "until"}},
+	"POST /api/dlq":             {ID: "createDeadLetter", Summary: "Record a dead letter", Request: storage.DeadLetter{}, Response: storage.DeadLetter{}, Status: http.StatusCreated},
+	"GET /api/dlq/{id}":         {ID: "getDeadLetter", Summary: "Get a dead letter", Response: storage.DeadLetter{}},
+	"PUT /api/dlq/{id}":         {ID: "updateDeadLetter", Summary: "Edit a dead letter before replay", Request: DeadLetterUpdate{}, Response: storage.DeadLetter{}},
+	"DELETE /api/dlq/{id}":      {ID: "deleteDeadLetter", Summary: "Delete a dead letter", Status: http.StatusNoContent},
+	"POST /api/dlq/{id}/replay": {ID: "replayDeadLetter", Summary: "Replay a dead letter", Request: DeadLetterReplay{}, Response: storage.DeadLetter{}},
+	"POST /api/dlq/replay":      {ID: "replayDeadLetters", Summary: "Replay matching dead letters in the background", Request: DeadLetterBulkReplay{}, Response: DeadLetterReplayStarted{}, Status: http.StatusAccepted},
+	"POST /api/dlq/purge":       {ID: "purgeDeadLetters", Summary: "Purge matching dead letters", Request: DeadLetterPurge{}, Response: DeadLetterPurgeResult{}},
+}
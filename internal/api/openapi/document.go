@@ -0,0 +1,357 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Version is the OpenAPI version the generated documents declare.
+const Version = "3.1.0"
+
+// Document is an OpenAPI 3.1 document.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security,omitzero"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+}
+
+// Info carries the document metadata.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is a base URL the API is reachable at.
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Tag groups operations, one per transport package.
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+// SecurityRequirement names the security schemes an operation accepts.
+type SecurityRequirement map[string][]string
+
+// Operation describes one method on one path.
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitzero"`
+	// Paginated marks list operations that accept the CommonFilter query
+	// parameters and answer with a {data, total} envelope.
+	Paginated bool `json:"x-hermod-paginated,omitempty"`
+	// Public marks operations reachable without credentials.
+	Public bool `json:"x-hermod-public,omitempty"`
+}
+
+// Parameter is a path or query parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is a JSON request body.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a single status-code response.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType wraps the schema for one content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable schemas and security schemes.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how a client authenticates.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// ErrorSchemaName is the component every non-2xx JSON response refers to.
+const ErrorSchemaName = "ErrorResponse"
+
+const jsonContentType = "application/json"
+
+// Build assembles the document for routes, taking request and response
+// shapes from the catalog. Routes absent from the catalog are still listed,
+// with untyped bodies, so the document never silently drops an endpoint.
+func Build(routes []Route, info Info) *Document {
+	g := newSchemaGenerator()
+	g.components[ErrorSchemaName] = &Schema{
+		Type:        "object",
+		Description: "Error envelope returned by handlers that answer with JSON errors.",
+		Properties:  map[string]*Schema{"error": {Type: "string"}},
+		Required:    []string{"error"},
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: g.components,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {
+					Type: "http", Scheme: "bearer", BearerFormat: "JWT",
+					Description: "Session token returned by POST /api/login.",
+				},
+				"sessionCookie": {
+					Type: "apiKey", In: "cookie", Name: "hermod_session",
+					Description: "Session cookie set by POST /api/login.",
+				},
+				"workerToken": {
+					Type: "apiKey", In: "header", Name: "X-Worker-Token",
+					Description: "Worker token for worker-to-platform calls.",
+				},
+			},
+		},
+		Security: []SecurityRequirement{
+			{"bearerAuth": {}},
+			{"sessionCookie": {}},
+			{"workerToken": {}},
+		},
+	}
+
+	seen := make(map[string]bool)
+	tags := make(map[string]bool)
+	usedIDs := make(map[string]int)
+	for _, rt := range normalize(routes) {
+		spec, hasSpec := catalog[rt.Pattern]
+		path := rt.Path
+		if spec.Path != "" {
+			path = spec.Path
+		}
+		method := strings.ToLower(rt.Method)
+		if method == "" {
+			method = "get"
+		}
+		key := method + " " + path
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		op := &Operation{
+			OperationID: spec.ID,
+			Summary:     spec.Summary,
+			Tags:        []string{tagFor(path)},
+			Responses:   make(map[string]*Response),
+			Paginated:   spec.Paginated,
+			Public:      spec.Public,
+		}
+		if op.OperationID == "" {
+			op.OperationID = deriveOperationID(method, path)
+		}
+		if n := usedIDs[op.OperationID]; n > 0 {
+			usedIDs[op.OperationID] = n + 1
+			op.OperationID = fmt.Sprintf("%s%d", op.OperationID, n+1)
+		} else {
+			usedIDs[op.OperationID] = 1
+		}
+		if spec.Public {
+			op.Security = []SecurityRequirement{}
+		}
+		tags[op.Tags[0]] = true
+
+		for _, name := range PathParams(path) {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+		if spec.Paginated {
+			op.Parameters = append(op.Parameters, paginationParams()...)
+		}
+		for _, q := range spec.Query {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: q, In: "query", Schema: &Schema{Type: "string"},
+			})
+		}
+
+		if spec.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{jsonContentType: {Schema: g.schemaOf(spec.Request)}},
+			}
+		} else if !hasSpec && (method == "post" || method == "put" || method == "patch") {
+			op.RequestBody = &RequestBody{
+				Content: map[string]MediaType{jsonContentType: {Schema: &Schema{}}},
+			}
+		}
+
+		status := "200"
+		if spec.Status != 0 {
+			status = fmt.Sprint(spec.Status)
+		}
+		success := &Response{Description: http.StatusText(spec.statusOr(http.StatusOK))}
+		switch {
+		case spec.Paginated:
+			success.Content = map[string]MediaType{jsonContentType: {Schema: listEnvelope(g, spec.Response)}}
+		case spec.Response != nil:
+			success.Content = map[string]MediaType{jsonContentType: {Schema: g.schemaOf(spec.Response)}}
+		case !hasSpec:
+			success.Content = map[string]MediaType{jsonContentType: {Schema: &Schema{}}}
+		}
+		op.Responses[status] = success
+		op.Responses["default"] = &Response{
+			Description: "Error",
+			Content: map[string]MediaType{jsonContentType: {
+				Schema: &Schema{Ref: "#/components/schemas/" + ErrorSchemaName},
+			}},
+		}
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[method] = op
+	}
+
+	for name := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: name})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	return doc
+}
+
+// normalize drops subtree duplicates: "GET /api/vhosts/" exists only so a
+// trailing slash does not 404, and documents the same operation as
+// "GET /api/vhosts". Subtree patterns the catalog maps to a concrete path are
+// kept.
+func normalize(routes []Route) []Route {
+	exact := make(map[string]bool, len(routes))
+	for _, rt := range routes {
+		if !rt.Subtree {
+			exact[rt.Method+" "+rt.Path] = true
+		}
+	}
+	out := make([]Route, 0, len(routes))
+	for _, rt := range routes {
+		if rt.Subtree {
+			if _, mapped := catalog[rt.Pattern]; !mapped && exact[rt.Method+" "+strings.TrimSuffix(rt.Path, "/")] {
+				continue
+			}
+		}
+		out = append(out, rt)
+	}
+	return out
+}
+
+// listEnvelope is the {data, total} wrapper list handlers answer with.
+func listEnvelope(g *schemaGenerator, item any) *Schema {
+	itemSchema := g.schemaOf(item)
+	if itemSchema == nil {
+		itemSchema = &Schema{}
+	}
+	name := itemSchema.RefName()
+	env := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"data":  {Type: "array", Items: itemSchema},
+			"total": {Type: "integer", Format: "int64"},
+		},
+		Required: []string{"data", "total"},
+	}
+	if name == "" {
+		return env
+	}
+	listName := name + "List"
+	if _, ok := g.components[listName]; !ok {
+		g.components[listName] = env
+	}
+	return &Schema{Ref: "#/components/schemas/" + listName}
+}
+
+// paginationParams are the CommonFilter query parameters parsed by
+// handlers.ParseCommonFilter.
+func paginationParams() []Parameter {
+	return []Parameter{
+		{Name: "page", In: "query", Description: "1-based page number", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "limit", In: "query", Description: "Page size (default 100)", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "search", In: "query", Description: "Free-text filter", Schema: &Schema{Type: "string"}},
+		{Name: "vhost", In: "query", Description: "Restrict to one virtual host", Schema: &Schema{Type: "string"}},
+	}
+}
+
+// tagFor groups a path by its first segment after /api.
+func tagFor(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) > 1 && parts[0] == "api" {
+		return parts[1]
+	}
+	return parts[0]
+}
+
+// deriveOperationID builds a camel-case identifier from method and path, e.g.
+// "post /api/sinks/discover/databases" -> "postSinksDiscoverDatabases" and
+// "get /api/sinks/{id}" -> "getSinksById".
+func deriveOperationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(method)
+	for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+		if seg == "" || seg == "api" {
+			continue
+		}
+		if strings.HasPrefix(seg, "{") {
+			b.WriteString("By")
+			seg = strings.Trim(seg, "{}")
+		}
+		b.WriteString(pascal(seg))
+	}
+	return b.String()
+}
+
+// pascal converts "dead_letter-sink" to "DeadLetterSink".
+func pascal(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Handler serves the document for the routes a Recorder saw. The document is
+// built on first use: routes are all registered by the time the server starts
+// answering requests, and building earlier would miss the later ones.
+type Handler struct {
+	rec  *Recorder
+	info Info
+
+	once sync.Once
+	doc  *Document
+	raw  []byte
+	err  error
+}
+
+// NewHandler returns a Handler documenting the routes registered on rec.
+func NewHandler(rec *Recorder, info Info) *Handler {
+	return &Handler{rec: rec, info: info}
+}
+
+// Document returns the (cached) OpenAPI document.
+func (h *Handler) Document() *Document {
+	h.build()
+	return h.doc
+}
+
+func (h *Handler) build() {
+	h.once.Do(func() {
+		h.doc = Build(h.rec.Routes(), h.info)
+		h.raw, h.err = json.Marshal(h.doc)
+	})
+}
+
+// ServeHTTP writes the document as JSON.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.build()
+	if h.err != nil {
+		http.Error(w, h.err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(h.raw)
+}
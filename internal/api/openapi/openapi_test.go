@@ -0,0 +1,149 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func noop(http.ResponseWriter, *http.Request) {}
+
+func newTestRecorder() *Recorder {
+	rec := NewRecorder(http.NewServeMux())
+	rec.HandleFunc("GET /api/sinks", noop)
+	rec.HandleFunc("GET /api/sinks/{id}", noop)
+	rec.Handle("POST /api/sinks", http.HandlerFunc(noop))
+	rec.HandleFunc("GET /api/vhosts", noop)
+	rec.HandleFunc("GET /api/vhosts/", noop)
+	rec.HandleFunc("GET /api/workflows/{id}/traces/", noop)
+	rec.HandleFunc("POST /api/login", noop)
+	rec.HandleFunc("POST /api/forms/{path...}", noop)
+	rec.HandleFunc("/api/audit-logs", noop)
+	return rec
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    Route
+	}{
+		{"GET /api/sinks/{id}", Route{Method: "GET", Path: "/api/sinks/{id}"}},
+		{"/api/audit-logs", Route{Path: "/api/audit-logs"}},
+		{"POST /api/forms/{path...}", Route{Method: "POST", Path: "/api/forms/{path}"}},
+		{"GET /api/vhosts/", Route{Method: "GET", Path: "/api/vhosts/", Subtree: true}},
+	}
+	for _, tc := range tests {
+		got := ParsePattern(tc.pattern)
+		tc.want.Pattern = tc.pattern
+		if got != tc.want {
+			t.Errorf("ParsePattern(%q) = %+v, want %+v", tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestBuildDocumentsEveryRoute(t *testing.T) {
+	doc := Build(newTestRecorder().Routes(), Info{Title: "Hermod API", Version: "test"})
+
+	if doc.OpenAPI != Version {
+		t.Fatalf("openapi = %q, want %q", doc.OpenAPI, Version)
+	}
+	for path, method := range map[string]string{
+		"/api/sinks":        "get",
+		"/api/sinks/{id}":   "get",
+		"/api/login":        "post",
+		"/api/forms/{path}": "post",
+		"/api/audit-logs":   "get",
+		"/api/workflows/{id}/traces/{message_id}": "get",
+	} {
+		if doc.Paths[path][method] == nil {
+			t.Errorf("missing %s %s", method, path)
+		}
+	}
+	if _, ok := doc.Paths["/api/vhosts/"]; ok {
+		t.Error("trailing-slash duplicate of /api/vhosts should be folded")
+	}
+
+	// Uncatalogued routes are still documented, with untyped bodies.
+	forms := doc.Paths["/api/forms/{path}"]["post"]
+	if forms.RequestBody == nil || len(forms.Parameters) != 1 || forms.Parameters[0].Name != "path" {
+		t.Errorf("uncatalogued operation not described: %+v", forms)
+	}
+}
+
+func TestBuildListEnvelopeAndSchemas(t *testing.T) {
+	doc := Build(newTestRecorder().Routes(), Info{Title: "Hermod API", Version: "test"})
+
+	list := doc.Paths["/api/sinks"]["get"]
+	if list.OperationID != "listSinks" || !list.Paginated {
+		t.Fatalf("listSinks = %+v", list)
+	}
+	var names []string
+	for _, p := range list.Parameters {
+		names = append(names, p.Name)
+	}
+	if len(names) != 4 || names[0] != "page" || names[1] != "limit" {
+		t.Errorf("pagination params = %v", names)
+	}
+	ref := list.Responses["200"].Content["application/json"].Schema.RefName()
+	if ref != "SinkList" {
+		t.Fatalf("list response ref = %q, want SinkList", ref)
+	}
+	env := doc.Components.Schemas["SinkList"]
+	if env.Properties["data"].Items.RefName() != "Sink" {
+		t.Errorf("SinkList.data items = %+v", env.Properties["data"].Items)
+	}
+
+	sink := doc.Components.Schemas["Sink"]
+	if sink == nil {
+		t.Fatal("Sink component missing")
+	}
+	if sink.Properties["config"].TypeName() != "object" || sink.Properties["config"].AdditionalProperties.TypeName() != "string" {
+		t.Errorf("Sink.config = %+v, want map of strings", sink.Properties["config"])
+	}
+	required := map[string]bool{}
+	for _, r := range sink.Required {
+		required[r] = true
+	}
+	if !required["id"] || required["status"] {
+		t.Errorf("Sink.required = %v: id must be required, status (omitempty) must not", sink.Required)
+	}
+
+	create := doc.Paths["/api/sinks"]["post"]
+	if create.Responses["201"] == nil {
+		t.Errorf("createSink responses = %v, want 201", create.Responses)
+	}
+}
+
+func TestBuildPublicOperationsOverrideSecurity(t *testing.T) {
+	doc := Build(newTestRecorder().Routes(), Info{Title: "Hermod API", Version: "test"})
+	login := doc.Paths["/api/login"]["post"]
+	if !login.Public || login.Security == nil || len(login.Security) != 0 {
+		t.Errorf("login should be public with an empty security list, got %+v", login.Security)
+	}
+	raw, err := json.Marshal(login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	_ = json.Unmarshal(raw, &decoded)
+	if sec, ok := decoded["security"]; !ok || len(sec.([]any)) != 0 {
+		t.Errorf("public operation must serialize security: [], got %s", raw)
+	}
+}
+
+func TestHandlerServesJSON(t *testing.T) {
+	h := NewHandler(newTestRecorder(), Info{Title: "Hermod API", Version: "test"})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d", rr.Code)
+	}
+	var doc Document
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc.Paths["/api/sinks/{id}"]["get"].OperationID != "getSink" {
+		t.Errorf("round-tripped document lost operations")
+	}
+}
@@ -0,0 +1,97 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Route is a single pattern registered on the API mux, split into the pieces
+// the document builder needs.
+type Route struct {
+	// Pattern is the exact string passed to Handle/HandleFunc.
+	Pattern string
+	// Method is the HTTP method, or empty when the pattern matches any method.
+	Method string
+	// Path is the pattern path in OpenAPI form: wildcards like {path...} are
+	// reduced to {path}.
+	Path string
+	// Subtree is true for patterns ending in "/" which match every path below.
+	Subtree bool
+}
+
+// Recorder wraps a ServeMux and remembers every pattern registered through it.
+// It satisfies handlers.Router, so the transport packages register on it
+// exactly as they would on the bare mux.
+type Recorder struct {
+	mux *http.ServeMux
+
+	mu     sync.Mutex
+	routes []Route
+}
+
+// NewRecorder returns a Recorder forwarding registrations to mux.
+func NewRecorder(mux *http.ServeMux) *Recorder {
+	return &Recorder{mux: mux}
+}
+
+// Handle registers handler on the underlying mux and records the pattern.
+func (r *Recorder) Handle(pattern string, handler http.Handler) {
+	r.mux.Handle(pattern, handler)
+	r.record(pattern)
+}
+
+// HandleFunc registers handler on the underlying mux and records the pattern.
+func (r *Recorder) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	r.mux.HandleFunc(pattern, handler)
+	r.record(pattern)
+}
+
+// Routes returns the recorded routes in registration order.
+func (r *Recorder) Routes() []Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Route, len(r.routes))
+	copy(out, r.routes)
+	return out
+}
+
+func (r *Recorder) record(pattern string) {
+	r.mu.Lock()
+	r.routes = append(r.routes, ParsePattern(pattern))
+	r.mu.Unlock()
+}
+
+// ParsePattern splits a ServeMux pattern ("GET /api/sinks/{id}") into a Route.
+// Host-qualified patterns are not used by the API and keep the host in Path.
+func ParsePattern(pattern string) Route {
+	rt := Route{Pattern: pattern}
+	path := strings.TrimSpace(pattern)
+	if method, rest, ok := strings.Cut(path, " "); ok {
+		rt.Method = strings.ToUpper(method)
+		path = strings.TrimSpace(rest)
+	}
+	rt.Subtree = strings.HasSuffix(path, "/") && path != "/"
+	rt.Path = strings.ReplaceAll(path, "...}", "}")
+	return rt
+}
+
+// PathParams returns the wildcard names in path, in order.
+func PathParams(path string) []string {
+	var names []string
+	for {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			return names
+		}
+		end := strings.Index(path[start:], "}")
+		if end < 0 {
+			return names
+		}
+		name := strings.TrimSuffix(path[start+1:start+end], "...")
+		if name != "$" {
+			names = append(names, name)
+		}
+		path = path[start+end+1:]
+	}
+}
@@ -2,27 +2,383 @@ package sdkgen
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/user/hermod/internal/api/openapi"
 )
 
+// Languages lists the SDK languages Generate accepts, in the order they were
+// added.
+var Languages = []string{"go", "python", "typescript"}
+
 // DefaultGenerator implements Generator for multiple languages.
-type DefaultGenerator struct{}
+type DefaultGenerator struct {
+	// GoPackage is the package clause of generated Go clients. Defaults to
+	// "hermod".
+	GoPackage string
+}
 
 // NewGenerator creates a new DefaultGenerator.
 func NewGenerator() *DefaultGenerator {
-	return &DefaultGenerator{}
-}
-
-// Generate produces a stub SDK for the requested language.
-func (g *DefaultGenerator) Generate(_ context.Context, language string, _ any) ([]byte, error) {
-	switch language {
-	case "go":
-		return []byte("// Hermod SDK for Go\npackage hermod\n\ntype Client struct {}\n"), nil
-	case "python":
-		return []byte("# Hermod SDK for Python\nclass HermodClient:\n    pass\n"), nil
-	case "java":
-		return []byte("// Hermod SDK for Java\npublic class HermodClient {}\n"), nil
+	return &DefaultGenerator{GoPackage: "hermod"}
+}
+
+// Generate produces a typed client for language from the OpenAPI document.
+func (g *DefaultGenerator) Generate(_ context.Context, language string, schema any) ([]byte, error) {
+	doc, err := loadDocument(schema)
+	if err != nil {
+		return nil, err
+	}
+	a := buildAPI(doc)
+	switch strings.ToLower(language) {
+	case "go", "golang":
+		pkg := g.GoPackage
+		if pkg == "" {
+			pkg = "hermod"
+		}
+		return generateGo(a, pkg)
+	case "python", "py":
+		return generatePython(a), nil
+	case "typescript", "ts":
+		return generateTypeScript(a), nil
 	default:
 		return nil, fmt.Errorf("unsupported language: %s", language)
 	}
 }
+
+// FileName is the conventional file name for a generated client.
+func FileName(language string) string {
+	switch strings.ToLower(language) {
+	case "go", "golang":
+		return "hermod_client.go"
+	case "python", "py":
+		return "hermod_client.py"
+	case "typescript", "ts":
+		return "hermod-client.ts"
+	default:
+		return "hermod_client.txt"
+	}
+}
+
+func loadDocument(schema any) (*openapi.Document, error) {
+	switch s := schema.(type) {
+	case *openapi.Document:
+		if s == nil {
+			return nil, errors.New("sdkgen: nil OpenAPI document")
+		}
+		return s, nil
+	case openapi.Document:
+		return &s, nil
+	case []byte:
+		return decodeDocument(s)
+	case json.RawMessage:
+		return decodeDocument(s)
+	case string:
+		return decodeDocument([]byte(s))
+	case nil:
+		return nil, errors.New("sdkgen: an OpenAPI document is required")
+	default:
+		return nil, fmt.Errorf("sdkgen: unsupported schema type %T", schema)
+	}
+}
+
+func decodeDocument(raw []byte) (*openapi.Document, error) {
+	var doc openapi.Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("sdkgen: invalid OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("sdkgen: unsupported OpenAPI version %q", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+// api is the language-neutral view of a document the emitters work from.
+type api struct {
+	Title   string
+	Version string
+	Models  []model
+	Ops     []operation
+}
+
+type model struct {
+	Name        string
+	Description string
+	Fields      []field
+	// Map is set for object schemas without declared properties.
+	Map *openapi.Schema
+}
+
+type field struct {
+	JSONName string
+	Schema   *openapi.Schema
+	Required bool
+}
+
+type operation struct {
+	ID          string
+	Method      string
+	Path        string
+	Summary     string
+	PathParams  []string
+	QueryParams []string
+	Paginated   bool
+	Public      bool
+	Body        *openapi.Schema
+	Result      *openapi.Schema
+	// Item is the element schema of a paginated envelope.
+	Item *openapi.Schema
+}
+
+func buildAPI(doc *openapi.Document) *api {
+	a := &api{Title: doc.Info.Title, Version: doc.Info.Version}
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := doc.Components.Schemas[name]
+		m := model{Name: name, Description: s.Description}
+		if len(s.Properties) == 0 {
+			m.Map = s.AdditionalProperties
+			if m.Map == nil {
+				m.Map = &openapi.Schema{}
+			}
+		}
+		required := make(map[string]bool, len(s.Required))
+		for _, r := range s.Required {
+			required[r] = true
+		}
+		props := make([]string, 0, len(s.Properties))
+		for p := range s.Properties {
+			props = append(props, p)
+		}
+		sort.Strings(props)
+		for _, p := range props {
+			m.Fields = append(m.Fields, field{JSONName: p, Schema: s.Properties[p], Required: required[p]})
+		}
+		a.Models = append(a.Models, m)
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	methodOrder := []string{"get", "post", "put", "patch", "delete"}
+	for _, p := range paths {
+		item := doc.Paths[p]
+		for _, m := range methodOrder {
+			op, ok := item[m]
+			if !ok {
+				continue
+			}
+			o := operation{
+				ID:        op.OperationID,
+				Method:    strings.ToUpper(m),
+				Path:      p,
+				Summary:   op.Summary,
+				Paginated: op.Paginated,
+				Public:    op.Public,
+			}
+			for _, prm := range op.Parameters {
+				switch prm.In {
+				case "path":
+					o.PathParams = append(o.PathParams, prm.Name)
+				case "query":
+					if op.Paginated && isPaginationParam(prm.Name) {
+						continue
+					}
+					o.QueryParams = append(o.QueryParams, prm.Name)
+				}
+			}
+			if op.RequestBody != nil {
+				if mt, ok := op.RequestBody.Content["application/json"]; ok {
+					o.Body = mt.Schema
+					if o.Body == nil {
+						o.Body = &openapi.Schema{}
+					}
+				}
+			}
+			o.Result = successSchema(op)
+			if o.Paginated && o.Result != nil {
+				o.Item = envelopeItem(doc, o.Result)
+			}
+			a.Ops = append(a.Ops, o)
+		}
+	}
+	return a
+}
+
+func isPaginationParam(name string) bool {
+	switch name {
+	case "page", "limit", "search", "vhost":
+		return true
+	}
+	return false
+}
+
+// successSchema returns the JSON schema of the lowest 2xx response, or nil
+// when that response has no body.
+func successSchema(op *openapi.Operation) *openapi.Schema {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if mt, ok := op.Responses[code].Content["application/json"]; ok {
+			if mt.Schema == nil {
+				return &openapi.Schema{}
+			}
+			return mt.Schema
+		}
+		return nil
+	}
+	return nil
+}
+
+func envelopeItem(doc *openapi.Document, s *openapi.Schema) *openapi.Schema {
+	if name := s.RefName(); name != "" {
+		s = doc.Components.Schemas[name]
+	}
+	if s == nil {
+		return &openapi.Schema{}
+	}
+	if data, ok := s.Properties["data"]; ok && data.Items != nil {
+		return data.Items
+	}
+	return &openapi.Schema{}
+}
+
+// words splits an identifier such as "listSinks", "dead_letter_sink_id" or
+// "X-Worker-Token" into lower-case words.
+func words(s string) []string {
+	var out []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, strings.ToLower(string(cur)))
+			cur = cur[:0]
+		}
+	}
+	rs := []rune(s)
+	for i, r := range rs {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r):
+			// Split "listSinks" before S, and "VHostID" before the H of a
+			// new word but not inside a run of capitals.
+			if len(cur) > 0 && (unicode.IsLower(cur[len(cur)-1]) || unicode.IsDigit(cur[len(cur)-1]) ||
+				(i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(cur[len(cur)-1]))) {
+				flush()
+			}
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	return out
+}
+
+var goInitialisms = map[string]string{
+	"id": "ID", "url": "URL", "api": "API", "http": "HTTP", "json": "JSON", "sql": "SQL",
+	"dlq": "DLQ", "pii": "PII", "cpu": "CPU", "ip": "IP", "sdk": "SDK", "ai": "AI",
+	"oidc": "OIDC", "uri": "URI", "uuid": "UUID", "2fa": "2FA", "ttl": "TTL",
+}
+
+// goName renders s as an exported Go identifier.
+func goName(s string) string {
+	var b strings.Builder
+	for _, w := range words(s) {
+		if ini, ok := goInitialisms[w]; ok {
+			b.WriteString(ini)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	out := b.String()
+	if out == "" {
+		return "X"
+	}
+	if unicode.IsDigit(rune(out[0])) {
+		out = "X" + out
+	}
+	return out
+}
+
+// goParam renders s as an unexported Go identifier.
+func goParam(s string) string {
+	ws := words(s)
+	if len(ws) == 0 {
+		return "p"
+	}
+	var b strings.Builder
+	b.WriteString(ws[0])
+	for _, w := range ws[1:] {
+		if ini, ok := goInitialisms[w]; ok {
+			b.WriteString(ini)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	out := b.String()
+	switch out {
+	case "type", "func", "range", "map", "var", "go", "select", "default", "package", "import", "interface",
+		// Locals and receivers used by the generated method bodies.
+		"c", "o", "ctx", "path", "body", "opts", "query", "out", "page", "err":
+		out += "_"
+	}
+	return out
+}
+
+// snake renders s in snake_case.
+func snake(s string) string {
+	return strings.Join(words(s), "_")
+}
+
+// camel renders s in lowerCamelCase.
+func camel(s string) string {
+	ws := words(s)
+	if len(ws) == 0 {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(ws[0])
+	for _, w := range ws[1:] {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+// splitPath splits "/api/sinks/{id}/test" into its literal segments
+// ["/api/sinks/", "/test"] and parameter names ["id"], in order.
+func splitPath(path string) (literals []string, params []string) {
+	rest := path
+	for {
+		i := strings.Index(rest, "{")
+		if i < 0 {
+			literals = append(literals, rest)
+			return literals, params
+		}
+		j := strings.Index(rest[i:], "}")
+		if j < 0 {
+			literals = append(literals, rest)
+			return literals, params
+		}
+		literals = append(literals, rest[:i])
+		params = append(params, rest[i+1:i+j])
+		rest = rest[i+j+1:]
+	}
+}
@@ -0,0 +1,121 @@
+package sdkgen
+
+import (
+	"context"
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/user/hermod/internal/api/openapi"
+)
+
+func testDocument(t *testing.T) *openapi.Document {
+	t.Helper()
+	rec := openapi.NewRecorder(http.NewServeMux())
+	noop := func(http.ResponseWriter, *http.Request) {}
+	for _, p := range []string{
+		"GET /api/sinks",
+		"GET /api/sinks/{id}",
+		"POST /api/sinks",
+		"DELETE /api/sinks/{id}",
+		"POST /api/login",
+		"POST /api/forms/{path...}",
+	} {
+		rec.HandleFunc(p, noop)
+	}
+	return openapi.Build(rec.Routes(), openapi.Info{Title: "Hermod API", Version: "test"})
+}
+
+func TestGenerateGo(t *testing.T) {
+	code, err := NewGenerator().Generate(context.Background(), "go", testDocument(t))
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "client.go", code, 0); err != nil {
+		t.Fatalf("generated Go does not parse: %v\n%s", err, code)
+	}
+	src := string(code)
+	for _, want := range []string{
+		"package hermod",
+		"type Sink struct",
+		"func (c *Client) ListSinks(ctx context.Context, opts *ListOptions) (*SinkList, error)",
+		"func (c *Client) ListSinksAll(ctx context.Context, opts *ListOptions) iter.Seq2[Sink, error]",
+		"func (c *Client) GetSink(ctx context.Context, id string) (*Sink, error)",
+		"func (c *Client) DeleteSink(ctx context.Context, id string) error",
+		"c.token = out.Token",
+		// A {path} parameter must not collide with the generated local.
+		"PostFormsByPath(ctx context.Context, path_ string, body json.RawMessage)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated Go missing %q", want)
+		}
+	}
+}
+
+func TestGeneratePythonAndTypeScript(t *testing.T) {
+	doc := testDocument(t)
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		lang string
+		want []string
+	}{
+		{"python", []string{
+			"class Sink(_Model):",
+			"def list_sinks(self, page: int = 1, limit: int = 100",
+			"def iter_sinks(self, **filters) -> Iterator[\"Sink\"]:",
+			"def get_sink(self, id: str) -> \"Sink\":",
+			"f\"/api/sinks/{urllib.parse.quote(id, safe='')}\"",
+			"self.token = result.token",
+		}},
+		{"typescript", []string{
+			"export interface Sink {",
+			"async listSinks(opts: ListOptions = {}): Promise<SinkList>",
+			"listSinksAll(opts: ListOptions = {}): AsyncGenerator<Sink>",
+			"async getSink(id: string): Promise<Sink>",
+			"`/api/sinks/${encodeURIComponent(id)}`",
+		}},
+	}
+	for _, tc := range tests {
+		// Generators accept the raw JSON document as served by the API.
+		code, err := NewGenerator().Generate(context.Background(), tc.lang, raw)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.lang, err)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(string(code), want) {
+				t.Errorf("%s output missing %q", tc.lang, want)
+			}
+		}
+	}
+}
+
+func TestGenerateUnsupportedLanguage(t *testing.T) {
+	if _, err := NewGenerator().Generate(context.Background(), "cobol", testDocument(t)); err == nil {
+		t.Fatal("expected an error for an unsupported language")
+	}
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct{ in, goN, py, ts string }{
+		{"getSinkById", "GetSinkByID", "get_sink_by_id", "getSinkById"},
+		{"workflow_id", "WorkflowID", "workflow_id", "workflowId"},
+		{"listAPIKeys", "ListAPIKeys", "list_api_keys", "listApiKeys"},
+	}
+	for _, tc := range tests {
+		if got := goName(tc.in); got != tc.goN {
+			t.Errorf("goName(%q) = %q, want %q", tc.in, got, tc.goN)
+		}
+		if got := snake(tc.in); got != tc.py {
+			t.Errorf("snake(%q) = %q, want %q", tc.in, got, tc.py)
+		}
+		if got := camel(tc.in); got != tc.ts {
+			t.Errorf("camel(%q) = %q, want %q", tc.in, got, tc.ts)
+		}
+	}
+}
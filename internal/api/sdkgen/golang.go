@@ -0,0 +1,404 @@
+package sdkgen
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/user/hermod/internal/api/openapi"
+)
+
+func generateGo(a *api, pkg string) ([]byte, error) {
+	var b strings.Builder
+	w := func(format string, args ...any) { fmt.Fprintf(&b, format, args...) }
+
+	w("// Code generated by hermod sdkgen from the %s OpenAPI document (version %s). DO NOT EDIT.\n\n", a.Title, a.Version)
+	w("// Package %s is a typed client for the Hermod REST API.\n", pkg)
+	w("package %s\n\n", pkg)
+	w(`import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned for every non-2xx response.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("hermod: %%d %%s: %%s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is an APIError with status 404.
+func IsNotFound(err error) bool { return hasStatus(err, http.StatusNotFound) }
+
+// IsUnauthorized reports whether err is an APIError with status 401.
+func IsUnauthorized(err error) bool { return hasStatus(err, http.StatusUnauthorized) }
+
+// IsForbidden reports whether err is an APIError with status 403.
+func IsForbidden(err error) bool { return hasStatus(err, http.StatusForbidden) }
+
+// IsConflict reports whether err is an APIError with status 409.
+func IsConflict(err error) bool { return hasStatus(err, http.StatusConflict) }
+
+func hasStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// Client calls the Hermod API.
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	token       string
+	workerToken string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces the default http.Client.
+func WithHTTPClient(hc *http.Client) Option { return func(c *Client) { c.httpClient = hc } }
+
+// WithToken authenticates with a session token (as returned by Login).
+func WithToken(token string) Option { return func(c *Client) { c.token = token } }
+
+// WithWorkerToken authenticates as a worker via the X-Worker-Token header.
+func WithWorkerToken(token string) Option { return func(c *Client) { c.workerToken = token } }
+
+// NewClient returns a Client for the server at baseURL.
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetToken replaces the session token used for subsequent calls.
+func (c *Client) SetToken(token string) { c.token = token }
+
+// ListOptions are the pagination and filter parameters list endpoints accept.
+type ListOptions struct {
+	Page   int
+	Limit  int
+	Search string
+	VHost  string
+	// Filters carries endpoint-specific query parameters such as workflow_id.
+	Filters map[string]string
+}
+
+func (o *ListOptions) values() url.Values {
+	v := url.Values{}
+	if o == nil {
+		return v
+	}
+	if o.Page > 0 {
+		v.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Search != "" {
+		v.Set("search", o.Search)
+	}
+	if o.VHost != "" {
+		v.Set("vhost", o.VHost)
+	}
+	for k, val := range o.Filters {
+		v.Set(k, val)
+	}
+	return v
+}
+
+// paginate walks every page of a list endpoint.
+func paginate[T any](ctx context.Context, opts *ListOptions, fetch func(context.Context, *ListOptions) ([]T, int64, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		page := ListOptions{Page: 1, Limit: 100}
+		if opts != nil {
+			page = *opts
+			if page.Page < 1 {
+				page.Page = 1
+			}
+			if page.Limit < 1 {
+				page.Limit = 100
+			}
+		}
+		var seen int64
+		for {
+			items, total, err := fetch(ctx, &page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			seen += int64(len(items))
+			if len(items) == 0 || seen >= total {
+				return
+			}
+			page.Page++
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var rdr io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("hermod: encode request: %%w", err)
+		}
+		rdr = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rdr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.workerToken != "" {
+		req.Header.Set("X-Worker-Token", c.workerToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: data, Message: strings.TrimSpace(string(data))}
+		var env struct {
+			Error string ` + "`json:\"error\"`" + `
+		}
+		if json.Unmarshal(data, &env) == nil && env.Error != "" {
+			apiErr.Message = env.Error
+		}
+		return apiErr
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("hermod: decode response: %%w", err)
+	}
+	return nil
+}
+
+`)
+
+	// Models
+	for _, m := range a.Models {
+		w("// %s is the %s schema.\n", goName(m.Name), m.Name)
+		if m.Description != "" {
+			w("//\n// %s\n", m.Description)
+		}
+		if m.Map != nil {
+			w("type %s map[string]%s\n\n", goName(m.Name), goType(m.Map))
+			continue
+		}
+		w("type %s struct {\n", goName(m.Name))
+		for _, f := range m.Fields {
+			tag := f.JSONName
+			if !f.Required {
+				tag += ",omitempty"
+			}
+			w("\t%s %s `json:%q`\n", goName(f.JSONName), goType(f.Schema), tag)
+		}
+		w("}\n\n")
+	}
+
+	// Operations
+	for _, op := range a.Ops {
+		name := goName(op.ID)
+		var params []string
+		params = append(params, "ctx context.Context")
+		for _, p := range op.PathParams {
+			params = append(params, goParam(p)+" string")
+		}
+		if op.Body != nil {
+			params = append(params, "body "+goType(op.Body))
+		}
+		if op.Paginated {
+			params = append(params, "opts *ListOptions")
+		} else if len(op.QueryParams) > 0 {
+			params = append(params, "query url.Values")
+		}
+
+		resultType := ""
+		if op.Result != nil {
+			resultType = goType(op.Result)
+			if !strings.HasPrefix(resultType, "[]") && !strings.HasPrefix(resultType, "map[") && resultType != "json.RawMessage" {
+				resultType = "*" + resultType
+			}
+		}
+
+		if op.Summary != "" {
+			w("// %s calls %s %s: %s.\n", name, op.Method, op.Path, lowerFirst(strings.TrimSuffix(op.Summary, ".")))
+		} else {
+			w("// %s calls %s %s.\n", name, op.Method, op.Path)
+		}
+		if resultType != "" {
+			w("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(params, ", "), resultType)
+		} else {
+			w("func (c *Client) %s(%s) error {\n", name, strings.Join(params, ", "))
+		}
+		w("\tpath := %s\n", goPathExpr(op.Path))
+		queryExpr := "nil"
+		if op.Paginated {
+			queryExpr = "opts.values()"
+		} else if len(op.QueryParams) > 0 {
+			queryExpr = "query"
+		}
+		bodyExpr := "nil"
+		if op.Body != nil {
+			bodyExpr = "body"
+		}
+		if resultType != "" {
+			elem := strings.TrimPrefix(resultType, "*")
+			if strings.HasPrefix(resultType, "*") {
+				w("\tout := new(%s)\n", elem)
+				w("\tif err := c.do(ctx, %q, path, %s, %s, out); err != nil {\n\t\treturn nil, err\n\t}\n", op.Method, queryExpr, bodyExpr)
+			} else {
+				w("\tvar out %s\n", elem)
+				w("\tif err := c.do(ctx, %q, path, %s, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", op.Method, queryExpr, bodyExpr)
+			}
+			if op.ID == "login" {
+				w("\tif out.Token != \"\" {\n\t\tc.token = out.Token\n\t}\n")
+			}
+			w("\treturn out, nil\n}\n\n")
+		} else {
+			w("\treturn c.do(ctx, %q, path, %s, %s, nil)\n}\n\n", op.Method, queryExpr, bodyExpr)
+		}
+
+		if op.Paginated && op.Item != nil {
+			item := goType(op.Item)
+			var args, fwd []string
+			for _, p := range op.PathParams {
+				args = append(args, goParam(p)+" string")
+				fwd = append(fwd, goParam(p))
+			}
+			args = append([]string{"ctx context.Context"}, args...)
+			args = append(args, "opts *ListOptions")
+			fwd = append([]string{"ctx"}, fwd...)
+			fwd = append(fwd, "o")
+			w("// %sAll iterates over every page of %s.\n", name, name)
+			w("func (c *Client) %sAll(%s) iter.Seq2[%s, error] {\n", name, strings.Join(args, ", "), item)
+			w("\treturn paginate(ctx, opts, func(ctx context.Context, o *ListOptions) ([]%s, int64, error) {\n", item)
+			w("\t\tpage, err := c.%s(%s)\n", name, strings.Join(fwd, ", "))
+			w("\t\tif err != nil {\n\t\t\treturn nil, 0, err\n\t\t}\n")
+			w("\t\treturn page.Data, page.Total, nil\n\t})\n}\n\n")
+		}
+	}
+
+	src := []byte(b.String())
+	formatted, err := format.Source(src)
+	if err != nil {
+		return src, fmt.Errorf("sdkgen: generated Go does not parse: %w", err)
+	}
+	return formatted, nil
+}
+
+// goPathExpr renders an expression building the request path, escaping each
+// parameter.
+func goPathExpr(path string) string {
+	lits, params := splitPath(path)
+	if len(params) == 0 {
+		return strconv.Quote(path)
+	}
+	var parts []string
+	for i, lit := range lits {
+		if lit != "" {
+			parts = append(parts, strconv.Quote(lit))
+		}
+		if i < len(params) {
+			parts = append(parts, "url.PathEscape("+goParam(params[i])+")")
+		}
+	}
+	return strings.Join(parts, " + ")
+}
+
+// goType maps a schema to a Go type. Optional scalars stay values (with
+// omitempty) except where the schema admits null, which becomes a pointer.
+func goType(s *openapi.Schema) string {
+	if s == nil {
+		return "json.RawMessage"
+	}
+	if name := s.RefName(); name != "" {
+		return goName(name)
+	}
+	var t string
+	switch s.TypeName() {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			t = "time.Time"
+		case "byte":
+			return "[]byte"
+		default:
+			t = "string"
+		}
+	case "integer":
+		if s.Format == "int32" {
+			t = "int"
+		} else {
+			t = "int64"
+		}
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "array":
+		return "[]" + goType(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + goType(s.AdditionalProperties)
+		}
+		return "map[string]any"
+	default:
+		return "json.RawMessage"
+	}
+	if s.Nullable() {
+		return "*" + t
+	}
+	return t
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -4,6 +4,8 @@ import "context"
 
 // Generator defines the interface for generating client SDKs.
 type Generator interface {
-	// Generate produces the source code for a specific language.
+	// Generate produces the source code for a specific language. schema is the
+	// OpenAPI description of the API, as an *openapi.Document or its JSON
+	// encoding.
 	Generate(ctx context.Context, language string, schema any) ([]byte, error)
 }
@@ -0,0 +1,371 @@
+package sdkgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/user/hermod/internal/api/openapi"
+)
+
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true,
+	"await": true, "break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true,
+	"else": true, "except": true, "finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true, "not": true, "or": true,
+	"pass": true, "raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+func pyIdent(s string) string {
+	id := snake(s)
+	if id == "" {
+		id = "value"
+	}
+	if id[0] >= '0' && id[0] <= '9' {
+		id = "_" + id
+	}
+	if pythonKeywords[id] {
+		id += "_"
+	}
+	return id
+}
+
+func generatePython(a *api) []byte {
+	var b strings.Builder
+	w := func(format string, args ...any) { fmt.Fprintf(&b, format, args...) }
+
+	w("# Code generated by hermod sdkgen from the %s OpenAPI document (version %s). DO NOT EDIT.\n", a.Title, a.Version)
+	w(`"""Typed client for the Hermod REST API (standard library only)."""
+
+from __future__ import annotations
+
+import json
+import urllib.error
+import urllib.parse
+import urllib.request
+from dataclasses import dataclass, fields
+from typing import Any, Dict, Iterator, List, Optional
+
+
+class HermodAPIError(Exception):
+    """Raised for every non-2xx response."""
+
+    def __init__(self, status_code: int, message: str, body: bytes = b""):
+        super().__init__(f"hermod: {status_code}: {message}")
+        self.status_code = status_code
+        self.message = message
+        self.body = body
+
+    @property
+    def not_found(self) -> bool:
+        return self.status_code == 404
+
+    @property
+    def unauthorized(self) -> bool:
+        return self.status_code == 401
+
+
+def _to_json(value: Any) -> Any:
+    if hasattr(value, "to_dict"):
+        return value.to_dict()
+    if isinstance(value, list):
+        return [_to_json(v) for v in value]
+    if isinstance(value, dict):
+        return {k: _to_json(v) for k, v in value.items()}
+    return value
+
+
+class _Model:
+    _json_names: Dict[str, str] = {}
+    _nested: Dict[str, Any] = {}
+
+    @classmethod
+    def from_dict(cls, data: Optional[Dict[str, Any]]):
+        if data is None:
+            return None
+        kwargs = {}
+        for f in fields(cls):
+            key = cls._json_names.get(f.name, f.name)
+            if key not in data:
+                continue
+            value = data[key]
+            nested = cls._nested.get(f.name)
+            if nested is not None and value is not None:
+                kind, model = nested
+                if kind == "list":
+                    value = [model.from_dict(v) for v in value]
+                elif kind == "map":
+                    value = {k: model.from_dict(v) for k, v in value.items()}
+                else:
+                    value = model.from_dict(value)
+            kwargs[f.name] = value
+        return cls(**kwargs)
+
+    def to_dict(self) -> Dict[str, Any]:
+        out: Dict[str, Any] = {}
+        for f in fields(self):
+            value = getattr(self, f.name)
+            if value is None:
+                continue
+            out[self._json_names.get(f.name, f.name)] = _to_json(value)
+        return out
+
+
+`)
+
+	for _, m := range a.Models {
+		cls := goName(m.Name)
+		if m.Map != nil {
+			w("%s = Dict[str, %s]\n\n\n", cls, pyType(m.Map))
+			continue
+		}
+		w("@dataclass\nclass %s(_Model):\n", cls)
+		desc := m.Description
+		if desc == "" {
+			desc = "The " + m.Name + " schema."
+		}
+		w("    %q\n\n", desc)
+		if len(m.Fields) == 0 {
+			w("    pass\n\n\n")
+			continue
+		}
+		var renamed []string
+		for _, f := range m.Fields {
+			id := pyIdent(f.JSONName)
+			if id != f.JSONName {
+				renamed = append(renamed, fmt.Sprintf("%q: %q", id, f.JSONName))
+			}
+			w("    %s: Optional[%s] = None\n", id, pyType(f.Schema))
+		}
+		w("\n")
+		if len(renamed) > 0 {
+			w("%s._json_names = {%s}\n", cls, strings.Join(renamed, ", "))
+		}
+		w("\n\n")
+	}
+
+	// Wire nested model references once every class is defined, since
+	// models refer to each other in both directions.
+	for _, m := range a.Models {
+		if m.Map != nil {
+			continue
+		}
+		var parts []string
+		for _, f := range m.Fields {
+			if kind, ref := pyNested(f.Schema); ref != "" {
+				parts = append(parts, fmt.Sprintf("%q: (%q, %s)", pyIdent(f.JSONName), kind, goName(ref)))
+			}
+		}
+		if len(parts) > 0 {
+			w("%s._nested = {%s}\n", goName(m.Name), strings.Join(parts, ", "))
+		}
+	}
+	w("\n\n")
+
+	w(`class HermodClient:
+    """Calls the Hermod API. Authenticate with a session token (see login)
+    or a worker token."""
+
+    def __init__(self, base_url: str, token: Optional[str] = None,
+                 worker_token: Optional[str] = None, timeout: float = 30.0):
+        self.base_url = base_url.rstrip("/")
+        self.token = token
+        self.worker_token = worker_token
+        self.timeout = timeout
+
+    def _request(self, method: str, path: str, query: Optional[Dict[str, Any]] = None,
+                 body: Any = None) -> Any:
+        url = self.base_url + path
+        if query:
+            clean = {k: v for k, v in query.items() if v is not None and v != ""}
+            if clean:
+                url += "?" + urllib.parse.urlencode(clean)
+        data = None
+        headers = {"Accept": "application/json"}
+        if body is not None:
+            data = json.dumps(_to_json(body)).encode("utf-8")
+            headers["Content-Type"] = "application/json"
+        if self.token:
+            headers["Authorization"] = "Bearer " + self.token
+        if self.worker_token:
+            headers["X-Worker-Token"] = self.worker_token
+        req = urllib.request.Request(url, data=data, headers=headers, method=method)
+        try:
+            with urllib.request.urlopen(req, timeout=self.timeout) as resp:
+                raw = resp.read()
+        except urllib.error.HTTPError as err:
+            raw = err.read()
+            message = raw.decode("utf-8", "replace").strip()
+            try:
+                message = json.loads(raw).get("error") or message
+            except (ValueError, AttributeError):
+                pass
+            raise HermodAPIError(err.code, message, raw) from None
+        if not raw.strip():
+            return None
+        return json.loads(raw)
+
+    def _paginate(self, fetch, page: int = 1, limit: int = 100, **filters) -> Iterator[Any]:
+        seen = 0
+        while True:
+            result = fetch(page=page, limit=limit, **filters)
+            items = result.data or []
+            for item in items:
+                yield item
+            seen += len(items)
+            if not items or seen >= (result.total or 0):
+                return
+            page += 1
+
+`)
+
+	for _, op := range a.Ops {
+		name := pyIdent(op.ID)
+		args := []string{"self"}
+		for _, p := range op.PathParams {
+			args = append(args, pyIdent(p)+": str")
+		}
+		if op.Body != nil {
+			args = append(args, "body: "+pyType(op.Body))
+		}
+		queryParams := op.QueryParams
+		if op.Paginated {
+			args = append(args, "page: int = 1", "limit: int = 100", "search: str = \"\"", "vhost: str = \"\"")
+		}
+		for _, q := range queryParams {
+			args = append(args, pyIdent(q)+": Optional[str] = None")
+		}
+		ret := "None"
+		if op.Result != nil {
+			ret = pyType(op.Result)
+		}
+		w("    def %s(%s) -> %s:\n", name, strings.Join(args, ", "), ret)
+		doc := op.Method + " " + op.Path
+		if op.Summary != "" {
+			doc = op.Summary + " (" + doc + ")"
+		}
+		w("        %s\n", strconv.Quote(doc))
+		w("        path = %s\n", pyPathExpr(op.Path))
+		query := "None"
+		var qparts []string
+		if op.Paginated {
+			qparts = append(qparts, `"page": page`, `"limit": limit`, `"search": search`, `"vhost": vhost`)
+		}
+		for _, q := range queryParams {
+			qparts = append(qparts, fmt.Sprintf("%q: %s", q, pyIdent(q)))
+		}
+		if len(qparts) > 0 {
+			query = "{" + strings.Join(qparts, ", ") + "}"
+		}
+		bodyExpr := "None"
+		if op.Body != nil {
+			bodyExpr = "body"
+		}
+		w("        data = self._request(%q, path, %s, %s)\n", op.Method, query, bodyExpr)
+		if op.Result == nil {
+			w("        return None\n\n")
+			continue
+		}
+		w("        result = %s\n", pyDecode(op.Result, "data"))
+		if op.ID == "login" {
+			w("        if result is not None and result.token:\n            self.token = result.token\n")
+		}
+		w("        return result\n\n")
+
+		if op.Paginated {
+			item := "Any"
+			if op.Item != nil {
+				item = pyType(op.Item)
+			}
+			var iargs, fwd []string
+			iargs = append(iargs, "self")
+			for _, p := range op.PathParams {
+				iargs = append(iargs, pyIdent(p)+": str")
+				fwd = append(fwd, pyIdent(p))
+			}
+			iargs = append(iargs, "**filters")
+			w("    def iter_%s(%s) -> Iterator[%s]:\n", strings.TrimPrefix(name, "list_"), strings.Join(iargs, ", "), item)
+			w("        %s\n", strconv.Quote("Iterate over every page of "+name+"."))
+			if len(fwd) > 0 {
+				w("        return self._paginate(lambda **kw: self.%s(%s, **kw), **filters)\n\n", name, strings.Join(fwd, ", "))
+			} else {
+				w("        return self._paginate(self.%s, **filters)\n\n", name)
+			}
+		}
+	}
+	return []byte(b.String())
+}
+
+func pyType(s *openapi.Schema) string {
+	if s == nil {
+		return "Any"
+	}
+	if name := s.RefName(); name != "" {
+		return "\"" + goName(name) + "\""
+	}
+	switch s.TypeName() {
+	case "string":
+		return "str"
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "List[" + pyType(s.Items) + "]"
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "Dict[str, " + pyType(s.AdditionalProperties) + "]"
+		}
+		return "Dict[str, Any]"
+	}
+	return "Any"
+}
+
+// pyNested reports whether a field holds a model (directly, in a list or in a
+// map) so from_dict can build typed instances.
+func pyNested(s *openapi.Schema) (kind, ref string) {
+	if s == nil {
+		return "", ""
+	}
+	if name := s.RefName(); name != "" {
+		return "one", name
+	}
+	if s.TypeName() == "array" && s.Items != nil && s.Items.RefName() != "" {
+		return "list", s.Items.RefName()
+	}
+	if s.TypeName() == "object" && s.AdditionalProperties != nil && s.AdditionalProperties.RefName() != "" {
+		return "map", s.AdditionalProperties.RefName()
+	}
+	return "", ""
+}
+
+func pyDecode(s *openapi.Schema, expr string) string {
+	switch kind, ref := pyNested(s); kind {
+	case "one":
+		return goName(ref) + ".from_dict(" + expr + ")"
+	case "list":
+		return "[" + goName(ref) + ".from_dict(v) for v in (" + expr + " or [])]"
+	case "map":
+		return "{k: " + goName(ref) + ".from_dict(v) for k, v in (" + expr + " or {}).items()}"
+	}
+	return expr
+}
+
+func pyPathExpr(path string) string {
+	lits, params := splitPath(path)
+	if len(params) == 0 {
+		return strconv.Quote(path)
+	}
+	var b strings.Builder
+	b.WriteString("\"")
+	for i, lit := range lits {
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(lit, "{", "{{"), "}", "}}"))
+		if i < len(params) {
+			b.WriteString("{urllib.parse.quote(" + pyIdent(params[i]) + ", safe='')}")
+		}
+	}
+	b.WriteString("\"")
+	return "f" + b.String()
+}
@@ -0,0 +1,274 @@
+package sdkgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/user/hermod/internal/api/openapi"
+)
+
+var tsIdentRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func generateTypeScript(a *api) []byte {
+	var b strings.Builder
+	w := func(format string, args ...any) { fmt.Fprintf(&b, format, args...) }
+
+	w("// Code generated by hermod sdkgen from the %s OpenAPI document (version %s). DO NOT EDIT.\n\n", a.Title, a.Version)
+	w(`/** Thrown for every non-2xx response. */
+export class HermodApiError extends Error {
+  constructor(
+    public readonly status: number,
+    message: string,
+    public readonly body: string,
+  ) {
+    super(` + "`hermod: ${status}: ${message}`" + `);
+    this.name = 'HermodApiError';
+  }
+
+  get notFound(): boolean {
+    return this.status === 404;
+  }
+
+  get unauthorized(): boolean {
+    return this.status === 401;
+  }
+}
+
+/** Pagination and filter parameters list endpoints accept. */
+export interface ListOptions {
+  page?: number;
+  limit?: number;
+  search?: string;
+  vhost?: string;
+  /** Endpoint-specific query parameters such as workflow_id. */
+  filters?: Record<string, string>;
+}
+
+export interface ClientOptions {
+  /** Session token, as returned by login(). */
+  token?: string;
+  /** Worker token sent as X-Worker-Token. */
+  workerToken?: string;
+  /** Custom fetch implementation (defaults to the global fetch). */
+  fetch?: typeof fetch;
+}
+
+`)
+
+	for _, m := range a.Models {
+		name := goName(m.Name)
+		desc := m.Description
+		if desc == "" {
+			desc = "The " + m.Name + " schema."
+		}
+		w("/** %s */\n", desc)
+		if m.Map != nil {
+			w("export type %s = Record<string, %s>;\n\n", name, tsType(m.Map))
+			continue
+		}
+		w("export interface %s {\n", name)
+		for _, f := range m.Fields {
+			key := f.JSONName
+			if !tsIdentRe.MatchString(key) {
+				key = strconv.Quote(key)
+			}
+			opt := "?"
+			if f.Required {
+				opt = ""
+			}
+			w("  %s%s: %s;\n", key, opt, tsType(f.Schema))
+		}
+		w("}\n\n")
+	}
+
+	w(`export class HermodClient {
+  private readonly baseURL: string;
+  private token?: string;
+  private readonly workerToken?: string;
+  private readonly fetchImpl: typeof fetch;
+
+  constructor(baseURL: string, options: ClientOptions = {}) {
+    this.baseURL = baseURL.replace(/\/+$/, '');
+    this.token = options.token;
+    this.workerToken = options.workerToken;
+    this.fetchImpl = options.fetch ?? fetch;
+  }
+
+  /** Replaces the session token used for subsequent calls. */
+  setToken(token: string | undefined): void {
+    this.token = token;
+  }
+
+  private async request<T>(method: string, path: string, query?: Record<string, unknown>, body?: unknown): Promise<T> {
+    let url = this.baseURL + path;
+    if (query) {
+      const params = new URLSearchParams();
+      for (const [k, v] of Object.entries(query)) {
+        if (v !== undefined && v !== null && v !== '') params.set(k, String(v));
+      }
+      const qs = params.toString();
+      if (qs) url += '?' + qs;
+    }
+    const headers: Record<string, string> = { Accept: 'application/json' };
+    if (body !== undefined) headers['Content-Type'] = 'application/json';
+    if (this.token) headers['Authorization'] = ` + "`Bearer ${this.token}`" + `;
+    if (this.workerToken) headers['X-Worker-Token'] = this.workerToken;
+    const resp = await this.fetchImpl(url, {
+      method,
+      headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const text = await resp.text();
+    if (!resp.ok) {
+      let message = text.trim();
+      try {
+        const parsed = JSON.parse(text);
+        if (parsed && typeof parsed.error === 'string') message = parsed.error;
+      } catch {
+        // Plain-text error body.
+      }
+      throw new HermodApiError(resp.status, message, text);
+    }
+    return (text.trim() ? JSON.parse(text) : undefined) as T;
+  }
+
+  private static listQuery(opts: ListOptions = {}): Record<string, unknown> {
+    return { page: opts.page, limit: opts.limit, search: opts.search, vhost: opts.vhost, ...(opts.filters ?? {}) };
+  }
+
+  private async *paginate<T>(opts: ListOptions, fetchPage: (o: ListOptions) => Promise<{ data: T[]; total: number }>): AsyncGenerator<T> {
+    const o: ListOptions = { ...opts, page: opts.page ?? 1, limit: opts.limit ?? 100 };
+    let seen = 0;
+    for (;;) {
+      const page = await fetchPage(o);
+      const items = page.data ?? [];
+      for (const item of items) yield item;
+      seen += items.length;
+      if (items.length === 0 || seen >= page.total) return;
+      o.page = (o.page ?? 1) + 1;
+    }
+  }
+
+`)
+
+	for _, op := range a.Ops {
+		name := camel(op.ID)
+		var params []string
+		for _, p := range op.PathParams {
+			params = append(params, camel(p)+": string")
+		}
+		if op.Body != nil {
+			params = append(params, "body: "+tsType(op.Body))
+		}
+		query := "undefined"
+		if op.Paginated {
+			params = append(params, "opts: ListOptions = {}")
+			query = "HermodClient.listQuery(opts)"
+		} else if len(op.QueryParams) > 0 {
+			var fields []string
+			for _, q := range op.QueryParams {
+				fields = append(fields, tsKey(q)+"?: string")
+			}
+			params = append(params, "query: { "+strings.Join(fields, "; ")+" } = {}")
+			query = "query"
+		}
+		ret := "void"
+		if op.Result != nil {
+			ret = tsType(op.Result)
+		}
+		doc := op.Method + " " + op.Path
+		if op.Summary != "" {
+			doc = op.Summary + " (" + doc + ")"
+		}
+		w("  /** %s */\n", doc)
+		w("  async %s(%s): Promise<%s> {\n", name, strings.Join(params, ", "), ret)
+		bodyExpr := "undefined"
+		if op.Body != nil {
+			bodyExpr = "body"
+		}
+		if op.ID == "login" && op.Result != nil {
+			w("    const result = await this.request<%s>('%s', %s, %s, %s);\n", ret, op.Method, tsPathExpr(op.Path), query, bodyExpr)
+			w("    if (result?.token) this.token = result.token;\n    return result;\n  }\n\n")
+		} else {
+			w("    return this.request<%s>('%s', %s, %s, %s);\n  }\n\n", ret, op.Method, tsPathExpr(op.Path), query, bodyExpr)
+		}
+
+		if op.Paginated && op.Item != nil {
+			item := tsType(op.Item)
+			var iparams, fwd []string
+			for _, p := range op.PathParams {
+				iparams = append(iparams, camel(p)+": string")
+				fwd = append(fwd, camel(p))
+			}
+			iparams = append(iparams, "opts: ListOptions = {}")
+			fwd = append(fwd, "o")
+			w("  /** Iterates over every page of %s. */\n", name)
+			w("  %sAll(%s): AsyncGenerator<%s> {\n", name, strings.Join(iparams, ", "), item)
+			w("    return this.paginate<%s>(opts, (o) => this.%s(%s));\n  }\n\n", item, name, strings.Join(fwd, ", "))
+		}
+	}
+	w("}\n")
+	return []byte(b.String())
+}
+
+func tsKey(s string) string {
+	if tsIdentRe.MatchString(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+func tsType(s *openapi.Schema) string {
+	if s == nil {
+		return "unknown"
+	}
+	if name := s.RefName(); name != "" {
+		return goName(name)
+	}
+	var t string
+	switch s.TypeName() {
+	case "string":
+		t = "string"
+	case "integer", "number":
+		t = "number"
+	case "boolean":
+		t = "boolean"
+	case "array":
+		inner := tsType(s.Items)
+		if strings.ContainsAny(inner, " |") {
+			inner = "(" + inner + ")"
+		}
+		t = inner + "[]"
+	case "object":
+		if s.AdditionalProperties != nil {
+			t = "Record<string, " + tsType(s.AdditionalProperties) + ">"
+		} else {
+			t = "Record<string, unknown>"
+		}
+	default:
+		return "unknown"
+	}
+	if s.Nullable() {
+		return t + " | null"
+	}
+	return t
+}
+
+func tsPathExpr(path string) string {
+	lits, params := splitPath(path)
+	if len(params) == 0 {
+		return "'" + path + "'"
+	}
+	var b strings.Builder
+	b.WriteString("`")
+	for i, lit := range lits {
+		b.WriteString(lit)
+		if i < len(params) {
+			b.WriteString("${encodeURIComponent(" + camel(params[i]) + ")}")
+		}
+	}
+	b.WriteString("`")
+	return b.String()
+}
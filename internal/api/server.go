@@ -13,18 +13,22 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/user/hermod/internal/ai"
 	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/api/openapi"
 	approvalhttp "github.com/user/hermod/internal/approval/transport/http"
 	authhttp "github.com/user/hermod/internal/auth/transport/http"
 	"github.com/user/hermod/internal/config"
 	dashboardhttp "github.com/user/hermod/internal/dashboard/transport/http"
+	deadletterhttp "github.com/user/hermod/internal/deadletter/transport/http"
 	"github.com/user/hermod/internal/engine/registry"
 	fileshttp "github.com/user/hermod/internal/files/transport/http"
 	formshttp "github.com/user/hermod/internal/forms/transport/http"
 	infrahttp "github.com/user/hermod/internal/infra/transport/http"
+	"github.com/user/hermod/internal/leader"
 	logshttp "github.com/user/hermod/internal/logs/transport/http"
 	marketplacehttp "github.com/user/hermod/internal/marketplace/transport/http"
 	schemahttp "github.com/user/hermod/internal/schema/transport/http"
@@ -32,6 +36,8 @@ import (
 	sourcehttp "github.com/user/hermod/internal/source/transport/http"
 	ssehttp "github.com/user/hermod/internal/sse/transport/http"
 	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/version"
+	"github.com/user/hermod/internal/watch"
 	webhookshttp "github.com/user/hermod/internal/webhooks/transport/http"
 	workerhttp "github.com/user/hermod/internal/worker/transport/http"
 	workflowhttp "github.com/user/hermod/internal/workflow/transport/http"
@@ -80,6 +86,13 @@ func NewServer(registry *registry.Registry, store storage.Storage, cfg *config.C
 		ConfigPath:    configPath,
 		RateLimitQuit: make(chan struct{}),
 	}
+	// Writes only reach watching workers when they go through the feed's
+	// storage; see watch.NewStorage.
+	if ws, ok := store.(*watch.Storage); ok {
+		s.Handler.Watch = ws.Feed()
+	} else {
+		s.Handler.Watch = watch.NewFeed(watch.DefaultBacklog)
+	}
 	// Initialize file storage from config; fallback to local uploads dir
 	if cfg != nil {
 		if fstorage, err := filestorage.NewStorage(context.Background(), cfg.FileStorage); err == nil {
@@ -107,14 +120,28 @@ func (s *Server) SetWorker(w handlers.WorkerUpdater) {
 	s.Handler.Worker = w
 }
 
+// SetElector sets the elector whose leader is reported by /api/config/status.
+func (s *Server) SetElector(e *leader.Elector) {
+	s.Handler.Elector = e
+}
+
 func (s *Server) Routes() http.Handler {
 	mux := http.NewServeMux()
+	// Every API route is registered through the recorder so the OpenAPI
+	// document (and the SDKs generated from it) cannot fall behind the mux.
+	router := openapi.NewRecorder(mux)
+	s.Handler.OpenAPI = openapi.NewHandler(router, openapi.Info{
+		Title:       "Hermod API",
+		Version:     version.Version,
+		Description: "REST API of the Hermod data platform.",
+	})
 
 	infraH := infrahttp.NewInfraHandler(s.Handler)
 	workflowH := workflowhttp.NewWorkflowHandler(s.Handler)
 	sourceH := sourcehttp.NewSourceHandler(s.Handler)
 	sinkH := sinkhttp.NewSinkHandler(s.Handler)
 	approvalH := approvalhttp.NewApprovalHandler(s.Handler)
+	deadLetterH := deadletterhttp.NewDeadLetterHandler(s.Handler)
 	authH := authhttp.NewAuthHandler(s.Handler)
 	schemaH := schemahttp.NewSchemaHandler(s.Handler)
 	marketplaceH := marketplacehttp.NewMarketplaceHandler(s.Handler)
@@ -131,7 +158,7 @@ func (s *Server) Routes() http.Handler {
 	mux.HandleFunc("GET /healthz", infraH.HandleLiveness)
 	mux.HandleFunc("GET /livez", infraH.HandleLiveness)
 	mux.HandleFunc("GET /readyz", infraH.HandleReadiness)
-	mux.HandleFunc("GET /api/version", infraH.HandleVersion)
+	router.HandleFunc("GET /api/version", infraH.HandleVersion)
 
 	// Optional pprof endpoints guarded by env var
 	if os.Getenv("HERMOD_PPROF") == "true" {
@@ -142,24 +169,26 @@ func (s *Server) Routes() http.Handler {
 		mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
 	}
 
-	workflowH.RegisterWorkflowRoutes(mux)
-	sourceH.RegisterSourceRoutes(mux)
-	sinkH.RegisterSinkRoutes(mux)
-	approvalH.RegisterApprovalRoutes(mux)
-	authH.RegisterAuthRoutes(mux)
-	infraH.RegisterInfrastructureRoutes(mux)
-	schemaH.RegisterSchemaRoutes(mux)
-	marketplaceH.RegisterMarketplaceRoutes(mux)
-	logsH.RegisterLogRoutes(mux)
-	dashboardH.RegisterDashboardRoutes(mux)
-	sseH.RegisterSSERoutes(mux)
-	wsH.RegisterWSRoutes(mux)
-	formsH.RegisterFormRoutes(mux)
-	filesH.RegisterFileRoutes(mux)
-	webhooksH.RegisterWebhookRoutes(mux)
-	workerH.RegisterWorkerRoutes(mux)
+	workflowH.RegisterWorkflowRoutes(router)
+	sourceH.RegisterSourceRoutes(router)
+	sinkH.RegisterSinkRoutes(router)
+	approvalH.RegisterApprovalRoutes(router)
+	deadLetterH.RegisterDeadLetterRoutes(router)
+	authH.RegisterAuthRoutes(router)
+	infraH.RegisterInfrastructureRoutes(router)
+	schemaH.RegisterSchemaRoutes(router)
+	marketplaceH.RegisterMarketplaceRoutes(router)
+	logsH.RegisterLogRoutes(router)
+	dashboardH.RegisterDashboardRoutes(router)
+	sseH.RegisterSSERoutes(router)
+	wsH.RegisterWSRoutes(router)
+	formsH.RegisterFormRoutes(router)
+	filesH.RegisterFileRoutes(router)
+	webhooksH.RegisterWebhookRoutes(router)
+	workerH.RegisterWorkerRoutes(router)
 
-	mux.HandleFunc("POST /api/graphql/{path...}", webhooksH.HandleGraphQL)
+	router.HandleFunc("POST /api/graphql/{path...}", webhooksH.HandleGraphQL)
+	router.Handle("GET /api/openapi.json", s.Handler.OpenAPI)
 	mux.Handle("/metrics", promhttp.Handler())
 
 	// Static files
@@ -304,8 +333,23 @@ func (s *Server) StartGRPC(addr string) error {
 	return s.GrpcServer.Serve(lis)
 }
 
+// grpcStopGrace bounds the wait for in-flight RPCs on shutdown. A publish
+// stream stays open for as long as its client likes, so GracefulStop alone
+// can wait forever.
+const grpcStopGrace = 5 * time.Second
+
 func (s *Server) Stop() {
-	if s.GrpcServer != nil {
+	if s.GrpcServer == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
 		s.GrpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(grpcStopGrace):
+		s.GrpcServer.Stop()
 	}
 }
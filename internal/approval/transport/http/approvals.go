@@ -10,7 +10,7 @@ import (
 	"github.com/user/hermod/internal/storage"
 )
 
-func (h *ApprovalHandler) RegisterApprovalRoutes(mux *http.ServeMux) {
+func (h *ApprovalHandler) RegisterApprovalRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/approvals", h.ListApprovals)
 	mux.HandleFunc("GET /api/approvals/{id}", h.GetApproval)
 	mux.Handle("POST /api/approvals/{id}/approve", h.EditorOnly(h.ApproveApproval))
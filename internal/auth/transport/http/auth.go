@@ -25,7 +25,7 @@ import (
 	"golang.org/x/oauth2"
 )
 
-func (h *AuthHandler) RegisterAuthRoutes(mux *http.ServeMux) {
+func (h *AuthHandler) RegisterAuthRoutes(mux handlers.Router) {
 	mux.HandleFunc("POST /api/login", h.Login)
 	mux.HandleFunc("POST /api/auth/2fa/login", h.Login2FA)
 	mux.HandleFunc("POST /api/auth/2fa/setup", h.Setup2FA)
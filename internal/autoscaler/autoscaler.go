@@ -16,7 +16,7 @@ type Autoscaler struct {
 	logger  hermod.Logger
 
 	interval time.Duration
-	stop     chan struct{}
+	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 }
 
@@ -26,26 +26,29 @@ func NewAutoscaler(s storage.Storage, m WorkerManager) *Autoscaler {
 		storage:  s,
 		logger:   telemetry.NewDefaultLogger(),
 		interval: 30 * time.Second,
-		stop:     make(chan struct{}),
 	}
 }
 
 func (a *Autoscaler) Start() {
-	a.wg.Go(a.run)
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	a.wg.Go(func() { a.Run(ctx) })
 }
 
 func (a *Autoscaler) Stop() {
-	close(a.stop)
+	a.cancel()
 	a.wg.Wait()
 }
 
-func (a *Autoscaler) run() {
+// Run checks on every interval until ctx is done. Start runs it in the
+// background; a leader elector runs it only on the elected replica.
+func (a *Autoscaler) Run(ctx context.Context) {
 	ticker := time.NewTicker(a.interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-a.stop:
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
 			a.check()
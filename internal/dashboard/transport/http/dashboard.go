@@ -3,9 +3,11 @@ package http
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/user/hermod/internal/api/handlers"
 )
 
-func (h *DashboardHandler) RegisterDashboardRoutes(mux *http.ServeMux) {
+func (h *DashboardHandler) RegisterDashboardRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/dashboard/stats", h.GetDashboardStats)
 }
 
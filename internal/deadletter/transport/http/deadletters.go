@@ -0,0 +1,381 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/storage"
+)
+
+const (
+	defaultBulkReplayLimit = 100
+	maxBulkReplayLimit     = 1000
+	// bulkReplayTimeout bounds a bulk replay running in the background.
+	bulkReplayTimeout = time.Hour
+)
+
+func (h *DeadLetterHandler) RegisterDeadLetterRoutes(mux handlers.Router) {
+	mux.HandleFunc("GET /api/dlq", h.ListDeadLetters)
+	mux.Handle("POST /api/dlq", h.EditorOnly(h.CreateDeadLetter))
+	mux.HandleFunc("GET /api/dlq/{id}", h.GetDeadLetter)
+	mux.Handle("PUT /api/dlq/{id}", h.EditorOnly(h.UpdateDeadLetter))
+	mux.Handle("DELETE /api/dlq/{id}", h.EditorOnly(h.DeleteDeadLetter))
+	mux.Handle("POST /api/dlq/{id}/replay", h.EditorOnly(h.ReplayDeadLetter))
+	mux.Handle("POST /api/dlq/replay", h.EditorOnly(h.ReplayDeadLetters))
+	mux.Handle("POST /api/dlq/purge", h.EditorOnly(h.PurgeDeadLetters))
+}
+
+// deadLetterSelector selects dead letters for a bulk replay or purge.
+type deadLetterSelector struct {
+	WorkflowID string    `json:"workflow_id,omitempty"`
+	SinkID     string    `json:"sink_id,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Search     string    `json:"search,omitempty"`
+	Since      time.Time `json:"since,omitzero"`
+	Until      time.Time `json:"until,omitzero"`
+}
+
+func (s deadLetterSelector) empty() bool {
+	return s == deadLetterSelector{}
+}
+
+func (s deadLetterSelector) filter() storage.DeadLetterFilter {
+	return storage.DeadLetterFilter{
+		CommonFilter: storage.CommonFilter{Search: s.Search, Since: s.Since, Until: s.Until},
+		WorkflowID:   s.WorkflowID,
+		SinkID:       s.SinkID,
+		ErrorClass:   s.ErrorClass,
+		Status:       s.Status,
+	}
+}
+
+// deadLetterUpdate edits a dead letter before it is replayed. Omitted fields
+// are left unchanged.
+type deadLetterUpdate struct {
+	Payload  *string           `json:"payload,omitempty"`
+	Data     map[string]any    `json:"data,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// replayTarget says where a replayed dead letter goes: into the workflow at
+// NodeID, or straight to SinkID. Empty, it goes back to the sink that
+// rejected it.
+type replayTarget struct {
+	NodeID string `json:"node_id,omitempty"`
+	SinkID string `json:"sink_id,omitempty"`
+}
+
+// bulkReplayRequest replays every dead letter matching the selector, at most
+// Limit of them and no faster than Rate per second when set. SinkID selects
+// by the failed sink; TargetSinkID, like replayTarget.SinkID, is where they go.
+type bulkReplayRequest struct {
+	deadLetterSelector
+	NodeID       string  `json:"node_id,omitempty"`
+	TargetSinkID string  `json:"target_sink_id,omitempty"`
+	Rate         float64 `json:"rate,omitempty"`
+	Limit        int     `json:"limit,omitempty"`
+}
+
+// purgeRequest deletes every dead letter matching the selector. An empty
+// selector is refused unless All is set.
+type purgeRequest struct {
+	deadLetterSelector
+	All bool `json:"all,omitempty"`
+}
+
+// replayStarted answers a bulk replay, which runs in the background.
+type replayStarted struct {
+	Status string `json:"status"`
+	Queued int    `json:"queued"`
+}
+
+func (h *DeadLetterHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := storage.DeadLetterFilter{
+		CommonFilter: h.ParseCommonFilter(r),
+		WorkflowID:   q.Get("workflow_id"),
+		SinkID:       q.Get("sink_id"),
+		ErrorClass:   q.Get("error_class"),
+		Status:       q.Get("status"),
+	}
+	for _, b := range []struct {
+		key string
+		dst *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(b.key); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				h.JsonError(w, "Invalid "+b.key+": expected RFC3339", http.StatusBadRequest)
+				return
+			}
+			*b.dst = t
+		}
+	}
+
+	dls, total, err := h.Storage.ListDeadLetters(r.Context(), f)
+	if err != nil {
+		h.JsonError(w, "Failed to list dead letters: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"data":  dls,
+		"total": total,
+	})
+}
+
+// CreateDeadLetter stores a dead letter recorded elsewhere, such as by a
+// remote worker, which has no database of its own.
+func (h *DeadLetterHandler) CreateDeadLetter(w http.ResponseWriter, r *http.Request) {
+	var dl storage.DeadLetter
+	if err := json.NewDecoder(r.Body).Decode(&dl); err != nil {
+		h.JsonError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dl.WorkflowID == "" {
+		h.JsonError(w, "workflow_id is required", http.StatusBadRequest)
+		return
+	}
+	if dl.ID == "" {
+		dl.ID = uuid.New().String()
+	}
+	if dl.Status == "" {
+		dl.Status = "pending"
+	}
+	if dl.CreatedAt.IsZero() {
+		dl.CreatedAt = time.Now()
+	}
+	if err := h.Storage.CreateDeadLetter(r.Context(), dl); err != nil {
+		h.JsonError(w, "Failed to create dead letter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(dl)
+}
+
+func (h *DeadLetterHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	dl, ok := h.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dl)
+}
+
+func (h *DeadLetterHandler) UpdateDeadLetter(w http.ResponseWriter, r *http.Request) {
+	dl, ok := h.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	var body deadLetterUpdate
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.JsonError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Payload != nil {
+		dl.Payload = []byte(*body.Payload)
+	}
+	if body.Data != nil {
+		dl.Data = body.Data
+	}
+	if body.Metadata != nil {
+		dl.Metadata = body.Metadata
+	}
+	if err := h.Storage.UpdateDeadLetter(r.Context(), dl); err != nil {
+		h.JsonError(w, "Failed to update dead letter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.RecordAuditLog(r, "INFO", "Edited dead letter "+dl.ID, "UPDATE", dl.WorkflowID, "", dl.SinkID, map[string]string{"message_id": dl.MessageID})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dl)
+}
+
+func (h *DeadLetterHandler) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	dl, ok := h.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Storage.DeleteDeadLetter(r.Context(), dl.ID); err != nil {
+		h.JsonError(w, "Failed to delete dead letter: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.RecordAuditLog(r, "INFO", "Deleted dead letter "+dl.ID, "DELETE", dl.WorkflowID, "", dl.SinkID, map[string]string{"message_id": dl.MessageID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeadLetterHandler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	rp := h.openReplayer()
+	if rp == nil {
+		h.JsonError(w, "Replay is not available on this node", http.StatusServiceUnavailable)
+		return
+	}
+	defer rp.Close()
+	dl, ok := h.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	var target replayTarget
+	_ = json.NewDecoder(r.Body).Decode(&target)
+
+	dl, err := h.replay(r.Context(), rp, dl, target)
+	if err != nil {
+		h.JsonError(w, "Replay failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	h.RecordAuditLog(r, "INFO", "Replayed dead letter "+dl.ID, "REPLAY", dl.WorkflowID, "", dl.SinkID, target)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dl)
+}
+
+// ReplayDeadLetters starts replaying the matching dead letters in the
+// background and answers with how many it queued. Each outcome is recorded
+// on its dead letter, as a single replay records it.
+func (h *DeadLetterHandler) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	rp := h.openReplayer()
+	if rp == nil {
+		h.JsonError(w, "Replay is not available on this node", http.StatusServiceUnavailable)
+		return
+	}
+	var body bulkReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		rp.Close()
+		h.JsonError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Rate < 0 {
+		rp.Close()
+		h.JsonError(w, "rate must not be negative", http.StatusBadRequest)
+		return
+	}
+	if body.Limit <= 0 {
+		body.Limit = defaultBulkReplayLimit
+	}
+	body.Limit = min(body.Limit, maxBulkReplayLimit)
+	if body.Status == "" {
+		// Replaying again what already went through is opt-in.
+		body.Status = "pending"
+	}
+
+	f := body.filter()
+	f.Page, f.Limit = 1, body.Limit
+	dls, _, err := h.Storage.ListDeadLetters(r.Context(), f)
+	if err != nil {
+		rp.Close()
+		h.JsonError(w, "Failed to list dead letters: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The replay outlives the request, but keeps its user for the audit log.
+	bg := r.WithContext(context.WithoutCancel(r.Context()))
+	h.bulk.Go(func() {
+		defer rp.Close()
+		ctx, cancel := context.WithTimeout(bg.Context(), bulkReplayTimeout)
+		defer cancel()
+		replayed, failed := h.replayAll(ctx, rp, dls, body)
+		msg := fmt.Sprintf("Replayed %d dead letters (%d failed)", replayed, failed)
+		if left := len(dls) - replayed - failed; left > 0 {
+			msg += fmt.Sprintf(", %d not attempted before the replay timed out", left)
+		}
+		h.RecordAuditLog(bg, "INFO", msg, "REPLAY", body.WorkflowID, "", body.SinkID, body)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(replayStarted{Status: "replay started", Queued: len(dls)})
+}
+
+// replayAll replays dls in order, no faster than body.Rate per second, until
+// ctx ends.
+func (h *DeadLetterHandler) replayAll(ctx context.Context, rp replayer, dls []storage.DeadLetter, body bulkReplayRequest) (replayed, failed int) {
+	var tick <-chan time.Time
+	if body.Rate > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / body.Rate))
+		defer t.Stop()
+		tick = t.C
+	}
+
+	target := replayTarget{NodeID: body.NodeID, SinkID: body.TargetSinkID}
+	for i, dl := range dls {
+		if tick != nil && i > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return replayed, failed
+			}
+		}
+		if ctx.Err() != nil {
+			return replayed, failed
+		}
+		if _, err := h.replay(ctx, rp, dl, target); err != nil {
+			failed++
+			continue
+		}
+		replayed++
+	}
+	return replayed, failed
+}
+
+func (h *DeadLetterHandler) PurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var body purgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.JsonError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.empty() && !body.All {
+		h.JsonError(w, "Refusing to purge every dead letter without \"all\": true", http.StatusBadRequest)
+		return
+	}
+	n, err := h.Storage.PurgeDeadLetters(r.Context(), body.filter())
+	if err != nil {
+		h.JsonError(w, "Failed to purge dead letters: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.RecordAuditLog(r, "WARNING", fmt.Sprintf("Purged %d dead letters", n), "DELETE", body.WorkflowID, "", body.SinkID, body)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"purged": n})
+}
+
+func (h *DeadLetterHandler) loadDeadLetter(w http.ResponseWriter, r *http.Request) (storage.DeadLetter, bool) {
+	dl, err := h.Storage.GetDeadLetter(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.JsonError(w, "Dead letter not found", http.StatusNotFound)
+		} else {
+			h.JsonError(w, "Failed to get dead letter: "+err.Error(), http.StatusInternalServerError)
+		}
+		return dl, false
+	}
+	return dl, true
+}
+
+// replay re-delivers dl and records the outcome on it, so a failed replay
+// stays pending with its error and a successful one is marked replayed.
+func (h *DeadLetterHandler) replay(ctx context.Context, rp replayer, dl storage.DeadLetter, target replayTarget) (storage.DeadLetter, error) {
+	replayErr := rp.ReplayDeadLetter(ctx, dl, target.NodeID, target.SinkID)
+	dl.ReplayCount++
+	if replayErr != nil {
+		dl.LastReplayError = replayErr.Error()
+	} else {
+		now := time.Now()
+		dl.Status = "replayed"
+		dl.LastReplayError = ""
+		dl.ReplayedAt = &now
+	}
+	if err := h.Storage.UpdateDeadLetter(context.WithoutCancel(ctx), dl); err != nil && replayErr == nil {
+		return dl, fmt.Errorf("replayed but failed to record it: %w", err)
+	}
+	return dl, replayErr
+}
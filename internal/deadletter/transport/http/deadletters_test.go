@@ -0,0 +1,230 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/testutil"
+)
+
+// deadLetterStore keeps dead letters in memory.
+type deadLetterStore struct {
+	testutil.BaseMockStorage
+	mu  sync.Mutex
+	dls map[string]storage.DeadLetter
+}
+
+func (s *deadLetterStore) ListDeadLetters(_ context.Context, f storage.DeadLetterFilter) ([]storage.DeadLetter, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []storage.DeadLetter
+	for _, dl := range s.dls {
+		if (f.WorkflowID == "" || dl.WorkflowID == f.WorkflowID) && (f.Status == "" || dl.Status == f.Status) {
+			out = append(out, dl)
+		}
+	}
+	return out, len(out), nil
+}
+
+func (s *deadLetterStore) CreateDeadLetter(_ context.Context, dl storage.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dls[dl.ID] = dl
+	return nil
+}
+
+func (s *deadLetterStore) GetDeadLetter(_ context.Context, id string) (storage.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dl, ok := s.dls[id]
+	if !ok {
+		return dl, storage.ErrNotFound
+	}
+	return dl, nil
+}
+
+func (s *deadLetterStore) UpdateDeadLetter(_ context.Context, dl storage.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dls[dl.ID] = dl
+	return nil
+}
+
+func (s *deadLetterStore) PurgeDeadLetters(_ context.Context, f storage.DeadLetterFilter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, dl := range s.dls {
+		if f.WorkflowID == "" || dl.WorkflowID == f.WorkflowID {
+			delete(s.dls, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// fakeReplayer fails replays of the listed dead letters.
+type fakeReplayer struct {
+	mu      sync.Mutex
+	fail    map[string]bool
+	targets []replayTarget
+	opened  int
+	closed  int
+}
+
+func (f *fakeReplayer) ReplayDeadLetter(_ context.Context, dl storage.DeadLetter, nodeID, sinkID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.targets = append(f.targets, replayTarget{NodeID: nodeID, SinkID: sinkID})
+	if f.fail[dl.ID] {
+		return errors.New("sink still down")
+	}
+	return nil
+}
+
+func (f *fakeReplayer) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed++
+}
+
+func newDeadLetterTestHandler(dls ...storage.DeadLetter) (*DeadLetterHandler, *deadLetterStore, *fakeReplayer) {
+	store := &deadLetterStore{dls: make(map[string]storage.DeadLetter)}
+	for _, dl := range dls {
+		store.dls[dl.ID] = dl
+	}
+	rp := &fakeReplayer{fail: make(map[string]bool)}
+	h := NewDeadLetterHandler(&handlers.Handler{Storage: store, LogStorage: store})
+	h.newReplayer = func() replayer {
+		rp.mu.Lock()
+		defer rp.mu.Unlock()
+		rp.opened++
+		return rp
+	}
+	return h, store, rp
+}
+
+func TestReplayDeadLetterRecordsOutcome(t *testing.T) {
+	h, store, rp := newDeadLetterTestHandler(
+		storage.DeadLetter{ID: "ok", WorkflowID: "wf", Status: "pending"},
+		storage.DeadLetter{ID: "bad", WorkflowID: "wf", Status: "pending"},
+	)
+	rp.fail["bad"] = true
+
+	for _, id := range []string{"ok", "bad"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/dlq/"+id+"/replay", strings.NewReader(`{"node_id":"n2"}`))
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		h.ReplayDeadLetter(rec, req)
+		if want := map[string]int{"ok": http.StatusOK, "bad": http.StatusBadGateway}[id]; rec.Code != want {
+			t.Fatalf("%s: expected %d, got %d: %s", id, want, rec.Code, rec.Body)
+		}
+	}
+
+	if ok := store.dls["ok"]; ok.Status != "replayed" || ok.ReplayCount != 1 || ok.ReplayedAt == nil {
+		t.Fatalf("expected the successful replay to be recorded, got %+v", ok)
+	}
+	if bad := store.dls["bad"]; bad.Status != "pending" || bad.ReplayCount != 1 || bad.LastReplayError != "sink still down" {
+		t.Fatalf("expected the failed replay to stay pending with its error, got %+v", bad)
+	}
+	if rp.targets[0].NodeID != "n2" {
+		t.Fatalf("expected the replay to target node n2, got %+v", rp.targets[0])
+	}
+}
+
+func TestBulkReplaySkipsReplayed(t *testing.T) {
+	h, store, rp := newDeadLetterTestHandler(
+		storage.DeadLetter{ID: "a", WorkflowID: "wf", Status: "pending"},
+		storage.DeadLetter{ID: "b", WorkflowID: "wf", Status: "replayed"},
+		storage.DeadLetter{ID: "c", WorkflowID: "wf", Status: "pending"},
+	)
+	rp.fail["c"] = true
+
+	rec := httptest.NewRecorder()
+	h.ReplayDeadLetters(rec, httptest.NewRequest(http.MethodPost, "/api/dlq/replay", strings.NewReader(`{"workflow_id":"wf","rate":1000}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body)
+	}
+	var res replayStarted
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Queued != 2 {
+		t.Fatalf("expected the two pending dead letters queued, got %+v", res)
+	}
+
+	h.bulk.Wait()
+	if a := store.dls["a"]; a.Status != "replayed" {
+		t.Fatalf("expected a replayed, got %+v", a)
+	}
+	if c := store.dls["c"]; c.Status != "pending" || c.LastReplayError != "sink still down" {
+		t.Fatalf("expected c to stay pending with its error, got %+v", c)
+	}
+	if b := store.dls["b"]; b.ReplayCount != 0 {
+		t.Fatalf("expected the replayed dead letter skipped, got %+v", b)
+	}
+	if rp.opened != 1 || rp.closed != 1 {
+		t.Fatalf("expected one replayer for the whole bulk replay, opened %d and closed %d", rp.opened, rp.closed)
+	}
+}
+
+func TestPurgeRequiresSelectorOrAll(t *testing.T) {
+	h, store, _ := newDeadLetterTestHandler(
+		storage.DeadLetter{ID: "a", WorkflowID: "wf1"},
+		storage.DeadLetter{ID: "b", WorkflowID: "wf2"},
+	)
+
+	rec := httptest.NewRecorder()
+	h.PurgeDeadLetters(rec, httptest.NewRequest(http.MethodPost, "/api/dlq/purge", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusBadRequest || len(store.dls) != 2 {
+		t.Fatalf("expected an unfiltered purge to be refused, got %d with %d left", rec.Code, len(store.dls))
+	}
+
+	rec = httptest.NewRecorder()
+	h.PurgeDeadLetters(rec, httptest.NewRequest(http.MethodPost, "/api/dlq/purge", strings.NewReader(`{"workflow_id":"wf1"}`)))
+	if rec.Code != http.StatusOK || len(store.dls) != 1 {
+		t.Fatalf("expected wf1's dead letter purged, got %d with %d left", rec.Code, len(store.dls))
+	}
+}
+
+func TestUpdateDeadLetterEditsPayload(t *testing.T) {
+	h, store, _ := newDeadLetterTestHandler(storage.DeadLetter{ID: "a", Payload: []byte(`{"id":1}`), Data: map[string]any{"id": 1}})
+
+	req := httptest.NewRequest(http.MethodPut, "/api/dlq/a", strings.NewReader(`{"payload":"{\"id\":2}"}`))
+	req.SetPathValue("id", "a")
+	rec := httptest.NewRecorder()
+	h.UpdateDeadLetter(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if dl := store.dls["a"]; string(dl.Payload) != `{"id":2}` || dl.Data["id"] != 1 {
+		t.Fatalf("expected only the payload to change, got %+v", dl)
+	}
+}
+
+func TestCreateDeadLetterStoresIt(t *testing.T) {
+	h, store, _ := newDeadLetterTestHandler()
+
+	rec := httptest.NewRecorder()
+	h.CreateDeadLetter(rec, httptest.NewRequest(http.MethodPost, "/api/dlq", strings.NewReader(`{"id":"a","workflow_id":"wf","sink_id":"s1"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	if dl, ok := store.dls["a"]; !ok || dl.SinkID != "s1" || dl.Status != "pending" {
+		t.Fatalf("expected the dead letter stored as pending, got %+v", store.dls)
+	}
+
+	rec = httptest.NewRecorder()
+	h.CreateDeadLetter(rec, httptest.NewRequest(http.MethodPost, "/api/dlq", strings.NewReader(`{"id":"b"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a dead letter without a workflow to be refused, got %d", rec.Code)
+	}
+}
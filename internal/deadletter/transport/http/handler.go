@@ -0,0 +1,40 @@
+package http
+
+import (
+	"context"
+	"sync"
+
+	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/storage"
+)
+
+// replayer re-delivers dead letters until it is closed; the registry's
+// DeadLetterReplay implements it. A bulk replay shares one, so the sinks it
+// writes to are opened once.
+type replayer interface {
+	ReplayDeadLetter(ctx context.Context, dl storage.DeadLetter, nodeID, sinkID string) error
+	Close()
+}
+
+type DeadLetterHandler struct {
+	*handlers.Handler
+	// newReplayer overrides the registry, for tests.
+	newReplayer func() replayer
+	// bulk tracks the bulk replays running in the background.
+	bulk sync.WaitGroup
+}
+
+func NewDeadLetterHandler(h *handlers.Handler) *DeadLetterHandler {
+	return &DeadLetterHandler{Handler: h}
+}
+
+// openReplayer returns a replayer, or nil when this node cannot replay.
+func (h *DeadLetterHandler) openReplayer() replayer {
+	if h.newReplayer != nil {
+		return h.newReplayer()
+	}
+	if h.Registry != nil {
+		return h.Registry.NewDeadLetterReplay()
+	}
+	return nil
+}
@@ -2,11 +2,14 @@ package interfaces
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/user/hermod"
 	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/pkg/infra/evaluator"
 )
 
 type RegistryStorage interface {
@@ -46,6 +49,10 @@ type RegistryStorage interface {
 	CreateSuspendedMessage(ctx context.Context, m storage.SuspendedMessage) error
 	ListSuspendedMessages(ctx context.Context, workflowID string, before time.Time) ([]storage.SuspendedMessage, error)
 	DeleteSuspendedMessage(ctx context.Context, id string) error
+	ListEventWaits(ctx context.Context, workflowID, nodeID, key string) ([]storage.SuspendedMessage, error)
+	ResolveEventWait(ctx context.Context, m storage.SuspendedMessage) (bool, error)
+
+	CreateDeadLetter(ctx context.Context, dl storage.DeadLetter) error
 
 	GetDashboardStats(ctx context.Context, vhost string) (storage.DashboardStats, error)
 }
@@ -63,10 +70,33 @@ type NodeContext interface {
 	GetSink(workflowID, nodeID string) (hermod.Sink, bool)
 }
 
+// EventKey evaluates the correlation key expression of a wait_for_event node
+// against msg. It is empty when the message has no key.
+func EventKey(msg hermod.Message, expr string) string {
+	switch v := evaluator.EvaluateField(msg, expr).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 type NodeExecutor interface {
 	Execute(ctx context.Context, nctx NodeContext, workflowID string, node *storage.WorkflowNode, msg hermod.Message) ([]hermod.Message, string, error)
 }
 
+// NodeTicker is implemented by node executors that emit on time rather than
+// only on records, such as a window closing once its sources go idle. The
+// registry ticks every such node of the workflows it runs and routes what Tick
+// returns as if Execute had returned it.
+type NodeTicker interface {
+	Tick(ctx context.Context, nctx NodeContext, workflowID string, node *storage.WorkflowNode) ([]hermod.Message, string, error)
+}
+
 var (
 	executorsMu sync.RWMutex
 	executors   = make(map[string]NodeExecutor)
@@ -117,7 +117,7 @@ func ownershipCases() []ownershipCase {
 		{
 			name:        "stateful",
 			nodeType:    "stateful",
-			config:      map[string]any{"stateKey": "counter"},
+			config:      map[string]any{"operation": "count", "outputField": "counter"},
 			data:        map[string]any{"k": "v"},
 			wantAtLeast: 1,
 		},
@@ -16,6 +16,9 @@ func init() {
 // ConditionNode handles boolean branching.
 type ConditionNode struct{}
 
+// Config keys: conditions (JSON), expression, a condition such as
+// "amount > 10000", or field, operator and value.
+//
 // Execute evaluates conditions and returns the branch name ("true" or "false").
 func (n *ConditionNode) Execute(ctx context.Context, nctx interfaces.NodeContext, workflowID string, node *storage.WorkflowNode, msg hermod.Message) ([]hermod.Message, string, error) {
 	conditions := n.parseConditions(node)
@@ -32,6 +35,10 @@ func (n *ConditionNode) parseConditions(node *storage.WorkflowNode) []map[string
 		_ = json.Unmarshal([]byte(conditionsStr), &conditions)
 	}
 
+	if expr, _ := node.Config["expression"].(string); expr != "" {
+		conditions = append(conditions, map[string]any{"expression": expr})
+	}
+
 	if len(conditions) == 0 {
 		field, _ := node.Config["field"].(string)
 		op, _ := node.Config["operator"].(string)
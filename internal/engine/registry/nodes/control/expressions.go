@@ -0,0 +1,64 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/pkg/comm/transformer"
+	"github.com/user/hermod/pkg/infra/evaluator"
+)
+
+// CheckExpressions reports the first expression in a node's configuration
+// that does not compile, so that a workflow is rejected when it is saved
+// rather than when a message reaches the node.
+func CheckExpressions(node *storage.WorkflowNode) error {
+	switch node.Type {
+	case "condition":
+		return evaluator.CheckConditions((&ConditionNode{}).parseConditions(node))
+	case "router":
+		rulesStr, _ := node.Config["rules"].(string)
+		var rules []map[string]any
+		_ = json.Unmarshal([]byte(rulesStr), &rules)
+		for _, rule := range rules {
+			if err := evaluator.CheckConditions((&RouterNode{}).parseRuleConditions(rule)); err != nil {
+				return fmt.Errorf("rule %v: %w", rule["label"], err)
+			}
+		}
+	case "switch":
+		casesStr, _ := node.Config["cases"].(string)
+		var cases []map[string]any
+		_ = json.Unmarshal([]byte(casesStr), &cases)
+		for _, c := range cases {
+			if err := evaluator.CheckConditions((&SwitchNode{}).parseCaseConditions(c)); err != nil {
+				return fmt.Errorf("case %v: %w", c["label"], err)
+			}
+		}
+	case "transformation":
+		transType, _ := node.Config["transType"].(string)
+		if transType != "pipeline" {
+			return checkTransformer(transType, node.Config)
+		}
+		stepsStr, _ := node.Config["steps"].(string)
+		var steps []map[string]any
+		_ = json.Unmarshal([]byte(stepsStr), &steps)
+		for i, step := range steps {
+			st, _ := step["transType"].(string)
+			if err := checkTransformer(st, step); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkTransformer(transType string, config map[string]any) error {
+	t, ok := transformer.Get(transType)
+	if !ok {
+		return nil
+	}
+	if et, ok := t.(transformer.ExpressionTransformer); ok {
+		return et.CheckExpressions(config)
+	}
+	return nil
+}
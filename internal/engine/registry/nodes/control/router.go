@@ -45,6 +45,10 @@ func (n *RouterNode) parseRuleConditions(rule map[string]any) []map[string]any {
 		}
 	}
 
+	if expr, _ := rule["expression"].(string); expr != "" {
+		ruleConditions = append(ruleConditions, map[string]any{"expression": expr})
+	}
+
 	if len(ruleConditions) == 0 {
 		field, _ := rule["field"].(string)
 		op, _ := rule["operator"].(string)
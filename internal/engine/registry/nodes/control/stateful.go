@@ -1,9 +1,18 @@
 package control
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/user/hermod"
 	"github.com/user/hermod/internal/engine/registry/interfaces"
@@ -15,59 +24,345 @@ func init() {
 	interfaces.RegisterNodeExecutor("stateful", &StatefulNode{})
 }
 
-// StatefulNode handles stateful operations like counting or summing.
+// ErrNoStateStore is returned when node state is inspected without a state
+// store.
+var ErrNoStateStore = errors.New("no state store configured")
+
+// Key infixes separating a node's per-group values and the members of its
+// distinct sets from its ungrouped value.
+const (
+	groupInfix    = ":g:"
+	distinctInfix = ":d:"
+)
+
+// StatefulNode keeps a running count, sum, minimum, maximum, last value or
+// distinct count, optionally per group of messages. Updates are atomic when
+// the state store implements hermod.KeyedStateStore, so workers sharing the
+// store never lose them.
+//
+// Config keys: operation, field, outputField, groupBy (comma-separated
+// fields or expressions) and ttl, after which a group's state expires unless
+// it is updated.
 type StatefulNode struct{}
 
-// Execute performs the stateful operation and updates message data.
+// atomicState is the part of hermod.KeyedStateStore the node uses.
+type atomicState interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error)
+	Increment(ctx context.Context, key string, delta float64, ttl time.Duration) (float64, error)
+}
+
+// Execute updates the state of the message's group and sets the result on
+// the message.
 func (n *StatefulNode) Execute(ctx context.Context, nctx interfaces.NodeContext, workflowID string, node *storage.WorkflowNode, msg hermod.Message) ([]hermod.Message, string, error) {
 	op, _ := node.Config["operation"].(string)
+	if op == "" {
+		return nil, "", fmt.Errorf("stateful node %s: operation is required", node.ID)
+	}
 	field, _ := node.Config["field"].(string)
 	outputField, _ := node.Config["outputField"].(string)
 	if outputField == "" {
 		outputField = field + "_" + op
 	}
+	var ttl time.Duration
+	if s, _ := node.Config["ttl"].(string); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, "", fmt.Errorf("stateful node %s: invalid ttl %q: %w", node.ID, s, err)
+		}
+		ttl = d
+	}
 
-	currentVal := n.getCurrentValue(ctx, nctx, workflowID, node)
+	group := groupKey(msg, node.Config["groupBy"])
+	key := StateKey(workflowID, node.ID)
+	if group != "" {
+		key += groupInfix + group
+	}
+	st := n.state(nctx)
 
+	var result any
+	var err error
 	switch op {
 	case "count":
-		currentVal++
+		result, err = st.Increment(ctx, key, 1, ttl)
 	case "sum":
-		val := evaluator.GetMsgValByPath(msg, field)
-		if v, ok := evaluator.ToFloat64(val); ok {
-			currentVal += v
-		}
+		delta, _ := evaluator.ToFloat64(evaluator.GetMsgValByPath(msg, field))
+		result, err = st.Increment(ctx, key, delta, ttl)
+	case "min", "max":
+		result, err = extreme(ctx, st, key, op == "max", evaluator.GetMsgValByPath(msg, field), ttl)
+	case "last":
+		result, err = last(ctx, st, key, evaluator.GetMsgValByPath(msg, field), ttl)
+	case "distinct":
+		member := StateKey(workflowID, node.ID) + distinctInfix + group + ":" + valueHash(evaluator.GetMsgValByPath(msg, field))
+		result, err = distinct(ctx, st, key, member, ttl)
+	default:
+		return nil, "", fmt.Errorf("stateful node %s: unsupported operation %q", node.ID, op)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("stateful node %s: %w", node.ID, err)
 	}
-
-	n.saveValue(ctx, nctx, workflowID, node, currentVal)
 
 	modifiedMsg := msg.Clone()
-	modifiedMsg.SetData(outputField, currentVal)
+	modifiedMsg.SetData(outputField, result)
 	return []hermod.Message{modifiedMsg}, "", nil
 }
 
-func (n *StatefulNode) getCurrentValue(ctx context.Context, nctx interfaces.NodeContext, workflowID string, node *storage.WorkflowNode) float64 {
-	key := workflowID + ":" + node.ID
-	if store := nctx.StateStore(); store != nil {
-		if valBytes, err := store.Get(ctx, "node:"+key); err == nil && valBytes != nil {
-			v, _ := strconv.ParseFloat(string(valBytes), 64)
-			return v
+// state returns the node's view of the state store. Stores that cannot
+// update keys atomically, and the registry's node state when there is no
+// store, are updated under a lock, which only guards this process.
+//
+// Node state is checkpointed with the workflow under keys prefixed with its
+// ID, so it is kept there under the key without the "node:" prefix, as
+// numbers and decoded JSON rather than bytes.
+func (n *StatefulNode) state(nctx interfaces.NodeContext) atomicState {
+	store := nctx.StateStore()
+	if keyed, ok := store.(hermod.KeyedStateStore); ok {
+		return keyed
+	}
+	if store != nil {
+		return &lockedState{
+			get: func(ctx context.Context, key string) ([]byte, error) { return store.Get(ctx, key) },
+			set: func(ctx context.Context, key string, val []byte) error { return store.Set(ctx, key, val) },
+		}
+	}
+	return &lockedState{
+		get: func(_ context.Context, key string) ([]byte, error) {
+			v, ok := nctx.GetNodeState(strings.TrimPrefix(key, "node:"))
+			if !ok || v == nil {
+				return nil, nil
+			}
+			if f, ok := v.(float64); ok {
+				return []byte(strconv.FormatFloat(f, 'f', -1, 64)), nil
+			}
+			return json.Marshal(v)
+		},
+		set: func(_ context.Context, key string, val []byte) error {
+			nctx.SetNodeState(strings.TrimPrefix(key, "node:"), decodeState(val))
+			return nil
+		},
+	}
+}
+
+// StateKey is the key of a stateful node's ungrouped value. Grouped values
+// are kept under it, so it is also the prefix of all of the node's state.
+func StateKey(workflowID, nodeID string) string {
+	return "node:" + workflowID + ":" + nodeID
+}
+
+// groupKey joins the values of the groupBy expressions with "|". It is empty
+// for ungrouped nodes.
+func groupKey(msg hermod.Message, groupBy any) string {
+	var exprs []string
+	switch g := groupBy.(type) {
+	case string:
+		exprs = strings.Split(g, ",")
+	case []any:
+		for _, e := range g {
+			if s, ok := e.(string); ok {
+				exprs = append(exprs, s)
+			}
+		}
+	}
+	var parts []string
+	for _, expr := range exprs {
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		}
+		parts = append(parts, formatState(evaluator.EvaluateField(msg, expr)))
+	}
+	return strings.Join(parts, "|")
+}
+
+// extreme keeps the smallest, or largest, number seen. Values that are not
+// numbers leave it as it is.
+func extreme(ctx context.Context, st atomicState, key string, isMax bool, val any, ttl time.Duration) (any, error) {
+	v, isNum := evaluator.ToFloat64(val)
+	for {
+		cur, err := st.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if cur != nil {
+			c, err := strconv.ParseFloat(string(cur), 64)
+			if err != nil {
+				return nil, fmt.Errorf("state key %q does not hold a number", key)
+			}
+			if !isNum || (isMax && v <= c) || (!isMax && v >= c) {
+				// Touch the key so that its ttl runs from the last update.
+				if ttl > 0 {
+					if _, err := st.CompareAndSwap(ctx, key, cur, cur, ttl); err != nil {
+						return nil, err
+					}
+				}
+				return c, nil
+			}
+		} else if !isNum {
+			return nil, nil
+		}
+		ok, err := st.CompareAndSwap(ctx, key, cur, []byte(strconv.FormatFloat(v, 'f', -1, 64)), ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return v, nil
 		}
 	}
+}
 
-	if state, ok := nctx.GetNodeState(key); ok {
-		if v, ok := state.(float64); ok {
-			return v
+// last keeps the latest value, as JSON.
+func last(ctx context.Context, st atomicState, key string, val any, ttl time.Duration) (any, error) {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		cur, err := st.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := st.CompareAndSwap(ctx, key, cur, b, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return val, nil
 		}
 	}
-	return 0
 }
 
-func (n *StatefulNode) saveValue(ctx context.Context, nctx interfaces.NodeContext, workflowID string, node *storage.WorkflowNode, val float64) {
-	key := workflowID + ":" + node.ID
-	if store := nctx.StateStore(); store != nil {
-		_ = store.Set(ctx, "node:"+key, []byte(fmt.Sprintf("%f", val)))
-	} else {
-		nctx.SetNodeState(key, val)
+// distinct counts the values seen. Each value is recorded as a member key,
+// and the count is only incremented by the worker that creates it.
+func distinct(ctx context.Context, st atomicState, key, member string, ttl time.Duration) (any, error) {
+	created, err := st.CompareAndSwap(ctx, member, nil, []byte("1"), ttl)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return st.Increment(ctx, key, 1, ttl)
+	}
+	if ttl > 0 {
+		if _, err := st.CompareAndSwap(ctx, member, []byte("1"), []byte("1"), ttl); err != nil {
+			return nil, err
+		}
+	}
+	return st.Increment(ctx, key, 0, ttl)
+}
+
+// valueHash identifies a distinct value in its member key.
+func valueHash(v any) string {
+	sum := sha1.Sum([]byte(formatState(v)))
+	return hex.EncodeToString(sum[:])
+}
+
+func formatState(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case map[string]any, []any:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// lockedState updates a store that has no atomic operations under a lock.
+type lockedState struct {
+	get func(ctx context.Context, key string) ([]byte, error)
+	set func(ctx context.Context, key string, val []byte) error
+}
+
+var lockedStateMu sync.Mutex
+
+func (s *lockedState) Get(ctx context.Context, key string) ([]byte, error) {
+	lockedStateMu.Lock()
+	defer lockedStateMu.Unlock()
+	return s.get(ctx, key)
+}
+
+func (s *lockedState) CompareAndSwap(ctx context.Context, key string, old, value []byte, _ time.Duration) (bool, error) {
+	lockedStateMu.Lock()
+	defer lockedStateMu.Unlock()
+	cur, err := s.get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if (cur == nil) != (old == nil) || !bytes.Equal(cur, old) {
+		return false, nil
+	}
+	return true, s.set(ctx, key, value)
+}
+
+func (s *lockedState) Increment(ctx context.Context, key string, delta float64, _ time.Duration) (float64, error) {
+	lockedStateMu.Lock()
+	defer lockedStateMu.Unlock()
+	cur, err := s.get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	var sum float64
+	if cur != nil {
+		if sum, err = strconv.ParseFloat(string(cur), 64); err != nil {
+			return 0, fmt.Errorf("state key %q does not hold a number", key)
+		}
+	}
+	sum += delta
+	return sum, s.set(ctx, key, []byte(strconv.FormatFloat(sum, 'f', -1, 64)))
+}
+
+// StateEntry is the value of one group of a stateful node.
+type StateEntry struct {
+	Group string `json:"group"`
+	Value any    `json:"value"`
+}
+
+// InspectState returns the state of a stateful node, its ungrouped value
+// first and then up to limit groups in order. Stores that cannot scan keys
+// only return the ungrouped value.
+func InspectState(ctx context.Context, store hermod.StateStore, workflowID, nodeID string, limit int) ([]StateEntry, error) {
+	if store == nil {
+		return nil, ErrNoStateStore
+	}
+	key := StateKey(workflowID, nodeID)
+	var entries []StateEntry
+	val, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if val != nil {
+		entries = append(entries, StateEntry{Value: decodeState(val)})
+	}
+
+	keyed, ok := store.(hermod.KeyedStateStore)
+	if !ok {
+		return entries, nil
+	}
+	groups, err := keyed.Scan(ctx, key+groupInfix, limit)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(groups))
+	for k := range groups {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		entries = append(entries, StateEntry{Group: strings.TrimPrefix(k, key+groupInfix), Value: decodeState(groups[k])})
+	}
+	return entries, nil
+}
+
+// decodeState returns a stored value as the number or JSON it holds.
+func decodeState(b []byte) any {
+	if f, err := strconv.ParseFloat(string(b), 64); err == nil {
+		return f
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err == nil {
+		return v
 	}
+	return string(b)
 }
@@ -0,0 +1,131 @@
+package control
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/storage"
+	msgpkg "github.com/user/hermod/pkg/comm/message"
+	"github.com/user/hermod/pkg/infra/state"
+)
+
+// storeCtx is a stubCtx with a state store.
+type storeCtx struct {
+	stubCtx
+	store hermod.StateStore
+}
+
+func (s *storeCtx) StateStore() hermod.StateStore { return s.store }
+
+func runStateful(t *testing.T, nctx *storeCtx, config map[string]any, data map[string]any) any {
+	t.Helper()
+	m := msgpkg.AcquireMessage()
+	defer msgpkg.ReleaseMessage(m)
+	m.SetID("m1")
+	for k, v := range data {
+		m.SetData(k, v)
+	}
+	node := &storage.WorkflowNode{ID: "n1", Type: "stateful", Config: config}
+	msgs, _, err := (&StatefulNode{}).Execute(context.Background(), nctx, "wf1", node, m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, _ := config["outputField"].(string)
+	return msgs[0].Data()[out]
+}
+
+func TestStateful_GroupedOperations(t *testing.T) {
+	nctx := &storeCtx{store: state.NewMemoryStore()}
+	rows := []map[string]any{
+		{"customer": "a", "amount": 10.0, "sku": "x"},
+		{"customer": "b", "amount": 5.0, "sku": "x"},
+		{"customer": "a", "amount": 3.0, "sku": "y"},
+		{"customer": "a", "amount": 7.0, "sku": "x"},
+	}
+	ops := map[string]any{"sum": 20.0, "count": 3.0, "min": 3.0, "max": 10.0, "last": 7.0, "distinct": 2.0}
+	for op, want := range ops {
+		var got any
+		for _, row := range rows {
+			field := "amount"
+			if op == "distinct" {
+				field = "sku"
+			}
+			got = runStateful(t, nctx, map[string]any{"operation": op, "field": field, "outputField": op, "groupBy": "customer"}, row)
+		}
+		if got != want {
+			t.Errorf("%s: got %v, want %v", op, got, want)
+		}
+		// Every operation starts from empty state.
+		nctx.store = state.NewMemoryStore()
+	}
+}
+
+func TestStateful_ConcurrentCounts(t *testing.T) {
+	for name, nctx := range map[string]*storeCtx{
+		"keyed store": {store: state.NewMemoryStore()},
+		"no store":    {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					runStateful(t, nctx, map[string]any{"operation": "count", "outputField": "n"}, nil)
+				}()
+			}
+			wg.Wait()
+			if got := runStateful(t, nctx, map[string]any{"operation": "count", "outputField": "n"}, nil); got != 51.0 {
+				t.Fatalf("lost increments: got %v", got)
+			}
+		})
+	}
+}
+
+func TestInspectState(t *testing.T) {
+	nctx := &storeCtx{store: state.NewMemoryStore()}
+	cfg := map[string]any{"operation": "sum", "field": "amount", "outputField": "total", "groupBy": "region, tier"}
+	runStateful(t, nctx, cfg, map[string]any{"region": "eu", "tier": "gold", "amount": 2.0})
+	runStateful(t, nctx, cfg, map[string]any{"region": "us", "tier": "gold", "amount": 4.0})
+	runStateful(t, nctx, cfg, map[string]any{"region": "eu", "tier": "gold", "amount": 1.0})
+
+	entries, err := InspectState(context.Background(), nctx.store, "wf1", "n1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0] != (StateEntry{Group: "eu|gold", Value: 3.0}) || entries[1] != (StateEntry{Group: "us|gold", Value: 4.0}) {
+		t.Fatalf("got %+v", entries)
+	}
+	if _, err := InspectState(context.Background(), nil, "wf1", "n1", 0); err != ErrNoStateStore {
+		t.Fatalf("got %v", err)
+	}
+}
+
+// Without a state store, values are kept in the node state the workflow
+// checkpoints, under the keys and as the numbers earlier versions used.
+func TestStateful_NodeStateWithoutStore(t *testing.T) {
+	nctx := &storeCtx{}
+	nctx.SetNodeState("wf1:n1", 41.0)
+	if got := runStateful(t, nctx, map[string]any{"operation": "count", "outputField": "n"}, nil); got != 42.0 {
+		t.Fatalf("expected the stored count to be resumed, got %v", got)
+	}
+	if v, _ := nctx.GetNodeState("wf1:n1"); v != 42.0 {
+		t.Fatalf("expected the count stored as a number under wf1:n1, got %#v", v)
+	}
+
+	runStateful(t, nctx, map[string]any{"operation": "last", "field": "name", "outputField": "last", "groupBy": "region"}, map[string]any{"region": "eu", "name": "ada"})
+	if v, _ := nctx.GetNodeState("wf1:n1" + groupInfix + "eu"); v != "ada" {
+		t.Fatalf("expected the last value stored as decoded JSON, got %#v", v)
+	}
+}
+
+func TestStateful_RequiresOperation(t *testing.T) {
+	m := msgpkg.AcquireMessage()
+	defer msgpkg.ReleaseMessage(m)
+	node := &storage.WorkflowNode{ID: "n1", Type: "stateful", Config: map[string]any{"outputField": "n"}}
+	if _, _, err := (&StatefulNode{}).Execute(context.Background(), &storeCtx{}, "wf1", node, m); err == nil {
+		t.Fatal("expected a node without an operation to be rejected")
+	}
+}
@@ -63,5 +63,8 @@ func (n *SwitchNode) parseCaseConditions(c map[string]any) []map[string]any {
 			}
 		}
 	}
+	if expr, _ := c["expression"].(string); expr != "" {
+		caseConditions = append(caseConditions, map[string]any{"expression": expr})
+	}
 	return caseConditions
 }
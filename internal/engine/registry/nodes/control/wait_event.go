@@ -0,0 +1,74 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/engine/registry/interfaces"
+	"github.com/user/hermod/internal/storage"
+)
+
+func init() {
+	interfaces.RegisterNodeExecutor("wait_for_event", &WaitForEventNode{})
+}
+
+// DefaultEventTimeout is how long a wait_for_event node waits without a
+// configured timeout.
+const DefaultEventTimeout = 24 * time.Hour
+
+// WaitForEventNode suspends a message until an event with the same
+// correlation key arrives on any workflow, for saga-style flows. The message
+// resumes on the "matched" branch with the event merged in, or on the
+// "timeout" branch once the timeout passes.
+//
+// Config keys: correlationKey, the expression giving the key of the waiting
+// message; eventKey, the expression giving the key of events, correlationKey
+// by default; eventConditions, the conditions (as JSON) an event must meet;
+// timeout; and mergeField, the field the event's data is set on. Without
+// mergeField the event's fields are merged into the message, whose own
+// fields win.
+type WaitForEventNode struct{}
+
+// Execute persists the message as suspended until its event arrives.
+func (n *WaitForEventNode) Execute(ctx context.Context, nctx interfaces.NodeContext, workflowID string, node *storage.WorkflowNode, msg hermod.Message) ([]hermod.Message, string, error) {
+	expr, _ := node.Config["correlationKey"].(string)
+	if expr == "" {
+		return nil, "", fmt.Errorf("wait_for_event node %s needs a correlation key", node.ID)
+	}
+	key := interfaces.EventKey(msg, expr)
+	if key == "" {
+		return nil, "", fmt.Errorf("message %s has no correlation key %q", msg.ID(), expr)
+	}
+	timeout := DefaultEventTimeout
+	if s, _ := node.Config["timeout"].(string); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, "", fmt.Errorf("wait_for_event node %s: invalid timeout %q: %w", node.ID, s, err)
+		}
+		timeout = d
+	}
+	store := nctx.Storage()
+	if store == nil {
+		return nil, "", fmt.Errorf("wait_for_event node %s needs storage to suspend messages", node.ID)
+	}
+
+	now := time.Now()
+	sm := storage.SuspendedMessage{
+		ID:         msg.ID(),
+		WorkflowID: workflowID,
+		NodeID:     node.ID,
+		Payload:    msg.Payload(),
+		Metadata:   msg.Metadata(),
+		Data:       msg.Data(),
+		ResumeAt:   now.Add(timeout),
+		CreatedAt:  now,
+		WaitKey:    key,
+	}
+	if err := store.CreateSuspendedMessage(ctx, sm); err != nil {
+		return nil, "", fmt.Errorf("failed to suspend message %s: %w", msg.ID(), err)
+	}
+	nctx.BroadcastLog(workflowID, "INFO", fmt.Sprintf("Message waiting for event %q for up to %v", key, timeout), msg.ID())
+	return nil, "suspended", nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/user/hermod"
 	"github.com/user/hermod/internal/engine/registry/interfaces"
 	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/pkg/comm/transformer"
 )
 
 func init() {
@@ -39,6 +40,11 @@ func (n *TransformationNode) Execute(ctx context.Context, nctx interfaces.NodeCo
 	if res == nil {
 		return nil, "", nil
 	}
+	if s, ok := transformer.Get(transType); ok {
+		if splitter, ok := s.(transformer.Splitter); ok {
+			return splitter.Split(res, node.Config), "", nil
+		}
+	}
 	return []hermod.Message{res}, "", nil
 }
 
@@ -0,0 +1,459 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/engine/registry/interfaces"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/pkg/comm/message"
+	"github.com/user/hermod/pkg/infra/evaluator"
+)
+
+func init() {
+	interfaces.RegisterNodeExecutor("window", &WindowExecutor{})
+}
+
+// WindowExecutor aggregates messages into event-time windows. Unlike the
+// aggregate transformer, which buckets on processing time, it reads each
+// record's timestamp from the message, so replayed or backfilled data lands
+// in the windows it belongs to.
+//
+// Progress is tracked with a watermark per upstream source: the latest event
+// time seen from it minus maxOutOfOrderness. The node's watermark is the
+// slowest source's, and a window emits once the watermark passes its end.
+// Records arriving after that but within allowedLateness update the window
+// and re-emit it as an update; records later still are routed to lateRoute,
+// or dropped when none is set. With idleTimeout set, event time advances with
+// the wall clock once every source has been idle that long, and the registry's
+// periodic Tick closes the windows it passes without waiting for a record.
+//
+// Window state is checkpointed to the state store so it survives restarts:
+// whenever windows fire or close, and otherwise at most once per
+// checkpointInterval (10s by default). Records buffered since the last
+// checkpoint are lost if the process dies.
+type WindowExecutor struct {
+	mu     sync.Mutex
+	states map[string]*windowHandle
+	now    func() time.Time
+}
+
+type windowHandle struct {
+	mu           sync.Mutex
+	loaded       bool
+	state        *windowState
+	dirty        bool
+	checkpointed time.Time
+}
+
+// windowConfig is the parsed configuration of a window node.
+type windowConfig struct {
+	timestamp  string
+	windowType string
+	size       time.Duration
+	slide      time.Duration
+	gap        time.Duration
+	groupBy    string
+	groupAs    string
+	aggs       []windowAgg
+	outOfOrder time.Duration
+	lateness   time.Duration
+	idle       time.Duration
+	checkpoint time.Duration
+	lateRoute  string
+}
+
+// windowBranch is the branch emitted windows take, so edges labelled with the
+// late route never receive them.
+const windowBranch = "window"
+
+const defaultWindowCheckpointInterval = 10 * time.Second
+
+func parseWindowConfig(cfg map[string]any) (*windowConfig, error) {
+	c := &windowConfig{}
+	c.timestamp, _ = cfg["timestamp"].(string)
+	if c.timestamp == "" {
+		return nil, errors.New("window node requires timestamp")
+	}
+	c.windowType, _ = cfg["windowType"].(string)
+	if c.windowType == "" {
+		c.windowType = "tumbling"
+	}
+	c.groupBy, _ = cfg["groupBy"].(string)
+	c.groupAs, _ = cfg["groupAs"].(string)
+	if c.groupAs == "" {
+		c.groupAs = "group"
+	}
+	c.lateRoute, _ = cfg["lateRoute"].(string)
+
+	var err error
+	for _, d := range []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"window", &c.size},
+		{"slide", &c.slide},
+		{"gap", &c.gap},
+		{"maxOutOfOrderness", &c.outOfOrder},
+		{"allowedLateness", &c.lateness},
+		{"idleTimeout", &c.idle},
+		{"checkpointInterval", &c.checkpoint},
+	} {
+		if *d.dst, err = configDuration(cfg, d.key); err != nil {
+			return nil, err
+		}
+	}
+	if c.checkpoint <= 0 {
+		c.checkpoint = defaultWindowCheckpointInterval
+	}
+
+	switch c.windowType {
+	case "tumbling":
+		if c.size <= 0 {
+			return nil, errors.New("tumbling window requires window")
+		}
+	case "sliding":
+		if c.size <= 0 || c.slide <= 0 {
+			return nil, errors.New("sliding window requires window and slide")
+		}
+		if c.slide > c.size {
+			return nil, errors.New("sliding window slide must not exceed window")
+		}
+	case "session":
+		if c.gap <= 0 {
+			return nil, errors.New("session window requires gap")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported window type: %s", c.windowType)
+	}
+
+	if c.aggs, err = parseWindowAggs(cfg["aggregations"]); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// configDuration reads a duration given as a Go duration string or as a
+// number of seconds.
+func configDuration(cfg map[string]any, key string) (time.Duration, error) {
+	switch v := cfg[key].(type) {
+	case nil:
+		return 0, nil
+	case string:
+		if v == "" {
+			return 0, nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		return d, nil
+	default:
+		if f, ok := evaluator.ToFloat64(v); ok {
+			return time.Duration(f * float64(time.Second)), nil
+		}
+		return 0, fmt.Errorf("invalid %s: %v", key, v)
+	}
+}
+
+func (e *WindowExecutor) Execute(ctx context.Context, nctx interfaces.NodeContext, workflowID string, node *storage.WorkflowNode, msg hermod.Message) ([]hermod.Message, string, error) {
+	cfg, err := parseWindowConfig(node.Config)
+	if err != nil {
+		return nil, "error", err
+	}
+	ts, err := parseEventTime(evaluator.EvaluateField(msg, cfg.timestamp))
+	if err != nil {
+		return nil, "error", fmt.Errorf("window node %s: %w", node.ID, err)
+	}
+
+	group := ""
+	if cfg.groupBy != "" {
+		group = fmt.Sprintf("%v", evaluator.EvaluateField(msg, cfg.groupBy))
+	}
+	source := msg.Metadata()["_source_node_id"]
+	now := e.clock()
+
+	h := e.handle(workflowID, node.ID)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st := e.load(ctx, nctx, workflowID, node.ID, h)
+	st.Table, st.Schema = msg.Table(), msg.Schema()
+	h.dirty = true
+
+	before, started := st.watermark(cfg.outOfOrder, cfg.idle, now)
+	clock := st.Sources[source]
+	if clock == nil {
+		clock = &sourceClock{MaxEventTime: ts}
+		st.Sources[source] = clock
+	}
+	clock.MaxEventTime = max(clock.MaxEventTime, ts)
+	clock.LastSeen = now.UnixNano()
+
+	accepted := false
+	for _, w := range e.assign(cfg, group, ts) {
+		if started && w.End+int64(cfg.lateness) <= before {
+			continue
+		}
+		w = e.place(cfg, st, w)
+		if len(w.Aggs) != len(cfg.aggs) {
+			// The aggregations were reconfigured since this window was checkpointed.
+			w.Aggs = make([]aggValue, len(cfg.aggs))
+		}
+		for i, a := range cfg.aggs {
+			var raw any
+			if a.Field != "" {
+				raw = evaluator.EvaluateField(msg, a.Field)
+			}
+			w.Aggs[i].add(a, raw)
+		}
+		w.Records++
+		if w.Fired {
+			w.Dirty = true
+		}
+		accepted = true
+	}
+
+	if !accepted {
+		e.checkpoint(ctx, nctx, workflowID, node.ID, cfg, h, now, false)
+		if cfg.lateRoute == "" {
+			nctx.BroadcastLog(workflowID, "DEBUG", fmt.Sprintf("Window node %s dropped late record at %s", node.ID, time.Unix(0, ts).UTC().Format(time.RFC3339Nano)), msg.ID())
+			return nil, "late", nil
+		}
+		return []hermod.Message{msg}, cfg.lateRoute, nil
+	}
+
+	out, closed := e.advance(cfg, st, workflowID, node.ID, now)
+	e.checkpoint(ctx, nctx, workflowID, node.ID, cfg, h, now, closed)
+
+	if len(out) == 0 {
+		return nil, "buffered", nil
+	}
+	return out, windowBranch, nil
+}
+
+// Tick fires the windows the watermark has passed since the last record,
+// which only an idle timeout makes happen, and checkpoints state buffered
+// since the last checkpoint once the interval is up.
+func (e *WindowExecutor) Tick(ctx context.Context, nctx interfaces.NodeContext, workflowID string, node *storage.WorkflowNode) ([]hermod.Message, string, error) {
+	cfg, err := parseWindowConfig(node.Config)
+	if err != nil {
+		return nil, "error", err
+	}
+	now := e.clock()
+
+	h := e.handle(workflowID, node.ID)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st := e.load(ctx, nctx, workflowID, node.ID, h)
+	out, closed := e.advance(cfg, st, workflowID, node.ID, now)
+	if closed {
+		h.dirty = true
+	}
+	e.checkpoint(ctx, nctx, workflowID, node.ID, cfg, h, now, closed)
+
+	if len(out) == 0 {
+		return nil, "", nil
+	}
+	return out, windowBranch, nil
+}
+
+// advance emits the windows the watermark has passed that have not fired or
+// changed since, and drops those past their allowed lateness. It reports
+// whether any window fired or was dropped.
+func (e *WindowExecutor) advance(cfg *windowConfig, st *windowState, workflowID, nodeID string, now time.Time) ([]hermod.Message, bool) {
+	watermark, started := st.watermark(cfg.outOfOrder, cfg.idle, now)
+	if !started {
+		return nil, false
+	}
+	var ready []*window
+	closed := false
+	for k, w := range st.Windows {
+		if w.End <= watermark && (!w.Fired || w.Dirty) {
+			ready = append(ready, w)
+		}
+		if w.End+int64(cfg.lateness) <= watermark {
+			delete(st.Windows, k)
+			closed = true
+		}
+	}
+
+	out := make([]hermod.Message, 0, len(ready))
+	for _, w := range sortedWindows(ready) {
+		out = append(out, e.emit(cfg, st, workflowID, nodeID, w))
+		w.Fired = true
+		w.Dirty = false
+	}
+	return out, closed || len(out) > 0
+}
+
+// assign returns the windows, not yet placed in the state, that a record at ts
+// belongs to.
+func (e *WindowExecutor) assign(cfg *windowConfig, group string, ts int64) []*window {
+	newWindow := func(start, end int64) *window {
+		return &window{Group: group, Start: start, End: end, Aggs: make([]aggValue, len(cfg.aggs))}
+	}
+	switch cfg.windowType {
+	case "sliding":
+		size, slide := int64(cfg.size), int64(cfg.slide)
+		var ws []*window
+		for start := floorDiv(ts, slide) * slide; start > ts-size; start -= slide {
+			ws = append(ws, newWindow(start, start+size))
+		}
+		return ws
+	case "session":
+		return []*window{newWindow(ts, ts+int64(cfg.gap))}
+	default:
+		size := int64(cfg.size)
+		start := floorDiv(ts, size) * size
+		return []*window{newWindow(start, start+size)}
+	}
+}
+
+// place returns the stored window w should accumulate into, adding w when
+// there is none. A session window absorbs every window of its group it
+// overlaps.
+func (e *WindowExecutor) place(cfg *windowConfig, st *windowState, w *window) *window {
+	if cfg.windowType != "session" {
+		if existing, ok := st.Windows[w.key()]; ok {
+			return existing
+		}
+		st.Windows[w.key()] = w
+		return w
+	}
+
+	var overlapping []*window
+	for k, o := range st.Windows {
+		if o.Group == w.Group && w.Start < o.End && o.Start < w.End {
+			overlapping = append(overlapping, o)
+			delete(st.Windows, k)
+		}
+	}
+	if len(overlapping) == 0 {
+		st.Windows[w.key()] = w
+		return w
+	}
+	merged := overlapping[0]
+	for _, o := range overlapping[1:] {
+		merged.merge(o)
+	}
+	merged.Start = min(merged.Start, w.Start)
+	merged.End = max(merged.End, w.End)
+	st.Windows[merged.key()] = merged
+	return merged
+}
+
+func (e *WindowExecutor) emit(cfg *windowConfig, st *windowState, workflowID, nodeID string, w *window) hermod.Message {
+	out := message.AcquireMessage()
+	out.SetID(fmt.Sprintf("%s:%s:%d", nodeID, w.Group, w.Start))
+	out.SetTable(st.Table)
+	out.SetSchema(st.Schema)
+	out.SetOperation(hermod.OpCreate)
+	if w.Fired {
+		out.SetOperation(hermod.OpUpdate)
+		out.SetMetadata("_window_update", "true")
+	}
+	out.SetMetadata("_hermod_workflow_id", workflowID)
+	out.SetData("window_start", time.Unix(0, w.Start).UTC().Format(time.RFC3339Nano))
+	out.SetData("window_end", time.Unix(0, w.End).UTC().Format(time.RFC3339Nano))
+	out.SetData("record_count", w.Records)
+	if cfg.groupBy != "" {
+		out.SetData(cfg.groupAs, w.Group)
+	}
+	for i, a := range cfg.aggs {
+		out.SetData(a.name(), w.Aggs[i].result(a))
+	}
+	return out
+}
+
+func (e *WindowExecutor) handle(workflowID, nodeID string) *windowHandle {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.states == nil {
+		e.states = make(map[string]*windowHandle)
+	}
+	key := workflowID + ":" + nodeID
+	h, ok := e.states[key]
+	if !ok {
+		h = &windowHandle{}
+		e.states[key] = h
+	}
+	return h
+}
+
+func (e *WindowExecutor) clock() time.Time {
+	if e.now != nil {
+		return e.now()
+	}
+	return time.Now()
+}
+
+// load returns the state of h, reading it from the state store the first
+// time. The caller holds h.mu.
+func (e *WindowExecutor) load(ctx context.Context, nctx interfaces.NodeContext, workflowID, nodeID string, h *windowHandle) *windowState {
+	if !h.loaded {
+		h.state = loadWindowState(ctx, nctx.StateStore(), windowStateKey(workflowID, nodeID))
+		h.loaded = true
+		h.checkpointed = e.clock()
+	}
+	return h.state
+}
+
+// checkpoint saves the state of h if it changed, right away when force is set
+// and otherwise once the checkpoint interval has passed since the last save.
+// The caller holds h.mu.
+func (e *WindowExecutor) checkpoint(ctx context.Context, nctx interfaces.NodeContext, workflowID, nodeID string, cfg *windowConfig, h *windowHandle, now time.Time, force bool) {
+	store := nctx.StateStore()
+	if store == nil || !h.dirty || (!force && now.Sub(h.checkpointed) < cfg.checkpoint) {
+		return
+	}
+	data, err := json.Marshal(h.state)
+	if err == nil {
+		err = store.Set(ctx, windowStateKey(workflowID, nodeID), data)
+	}
+	if err != nil {
+		nctx.BroadcastLog(workflowID, "WARN", fmt.Sprintf("Failed to checkpoint window state: %v", err), "")
+		return
+	}
+	h.dirty = false
+	h.checkpointed = now
+}
+
+func windowStateKey(workflowID, nodeID string) string {
+	return fmt.Sprintf("window:%s:%s", workflowID, nodeID)
+}
+
+func loadWindowState(ctx context.Context, store hermod.StateStore, key string) *windowState {
+	st := newWindowState()
+	if store == nil {
+		return st
+	}
+	data, err := store.Get(ctx, key)
+	if err != nil || len(data) == 0 {
+		return st
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return newWindowState()
+	}
+	if st.Sources == nil {
+		st.Sources = make(map[string]*sourceClock)
+	}
+	if st.Windows == nil {
+		st.Windows = make(map[string]*window)
+	}
+	return st
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,323 @@
+package flow
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/user/hermod/pkg/infra/evaluator"
+	"github.com/user/hermod/pkg/infra/sketch"
+)
+
+// windowAgg is one aggregation computed for every window.
+type windowAgg struct {
+	Field      string  `json:"field"`
+	Type       string  `json:"type"` // count, sum, avg, min, max, distinct, percentile
+	Percentile float64 `json:"percentile"`
+	As         string  `json:"as"`
+}
+
+func (a windowAgg) name() string {
+	if a.As != "" {
+		return a.As
+	}
+	switch {
+	case a.Field == "":
+		return a.Type
+	case a.Type == "percentile":
+		return fmt.Sprintf("p%g_%s", a.Percentile*100, a.Field)
+	default:
+		return a.Type + "_" + a.Field
+	}
+}
+
+// parseWindowAggs reads the "aggregations" config, given either as a list or
+// as its JSON encoding. Without it the window counts records.
+func parseWindowAggs(raw any) ([]windowAgg, error) {
+	var aggs []windowAgg
+	switch v := raw.(type) {
+	case nil:
+	case string:
+		if strings.TrimSpace(v) != "" {
+			if err := json.Unmarshal([]byte(v), &aggs); err != nil {
+				return nil, fmt.Errorf("invalid aggregations: %w", err)
+			}
+		}
+	default:
+		data, _ := json.Marshal(v)
+		if err := json.Unmarshal(data, &aggs); err != nil {
+			return nil, fmt.Errorf("invalid aggregations: %w", err)
+		}
+	}
+	if len(aggs) == 0 {
+		aggs = []windowAgg{{Type: "count"}}
+	}
+	for i, a := range aggs {
+		switch a.Type {
+		case "count":
+		case "sum", "avg", "min", "max", "distinct":
+			if a.Field == "" {
+				return nil, fmt.Errorf("aggregation %d (%s) requires a field", i, a.Type)
+			}
+		case "percentile":
+			if a.Field == "" {
+				return nil, fmt.Errorf("aggregation %d (percentile) requires a field", i)
+			}
+			if a.Percentile > 1 && a.Percentile <= 100 {
+				aggs[i].Percentile = a.Percentile / 100
+			}
+			if aggs[i].Percentile <= 0 || aggs[i].Percentile > 1 {
+				return nil, fmt.Errorf("aggregation %d (percentile) requires a percentile between 0 and 1", i)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported aggregation type: %s", a.Type)
+		}
+	}
+	return aggs, nil
+}
+
+// aggValue is the running state of one aggregation in one window. Only the
+// fields its type needs are set, which keeps checkpoints small.
+type aggValue struct {
+	Count  float64         `json:"n,omitempty"`
+	Sum    float64         `json:"sum,omitempty"`
+	Min    float64         `json:"min,omitempty"`
+	Max    float64         `json:"max,omitempty"`
+	HLL    *sketch.HLL     `json:"hll,omitempty"`
+	Digest *sketch.TDigest `json:"digest,omitempty"`
+}
+
+func (v *aggValue) add(a windowAgg, raw any) {
+	switch a.Type {
+	case "count":
+		if a.Field == "" || raw != nil {
+			v.Count++
+		}
+		return
+	case "distinct":
+		if raw == nil {
+			return
+		}
+		if v.HLL == nil {
+			v.HLL = sketch.NewHLL()
+		}
+		v.HLL.Add(fmt.Sprintf("%v", raw))
+		return
+	}
+
+	x, ok := evaluator.ToFloat64(raw)
+	if !ok {
+		return
+	}
+	if a.Type == "percentile" {
+		if v.Digest == nil {
+			v.Digest = sketch.NewTDigest()
+		}
+		v.Digest.Add(x)
+		return
+	}
+	if v.Count == 0 || x < v.Min {
+		v.Min = x
+	}
+	if v.Count == 0 || x > v.Max {
+		v.Max = x
+	}
+	v.Count++
+	v.Sum += x
+}
+
+func (v *aggValue) merge(o aggValue) {
+	if o.HLL != nil {
+		if v.HLL == nil {
+			v.HLL = sketch.NewHLL()
+		}
+		v.HLL.Merge(o.HLL)
+	}
+	if o.Digest != nil {
+		if v.Digest == nil {
+			v.Digest = sketch.NewTDigest()
+		}
+		v.Digest.Merge(o.Digest)
+	}
+	if o.Count > 0 {
+		if v.Count == 0 || o.Min < v.Min {
+			v.Min = o.Min
+		}
+		if v.Count == 0 || o.Max > v.Max {
+			v.Max = o.Max
+		}
+		v.Count += o.Count
+		v.Sum += o.Sum
+	}
+}
+
+func (v *aggValue) result(a windowAgg) any {
+	switch a.Type {
+	case "count":
+		return int64(v.Count)
+	case "sum":
+		return v.Sum
+	case "avg":
+		if v.Count == 0 {
+			return nil
+		}
+		return v.Sum / v.Count
+	case "min", "max":
+		if v.Count == 0 {
+			return nil
+		}
+		if a.Type == "min" {
+			return v.Min
+		}
+		return v.Max
+	case "distinct":
+		if v.HLL == nil {
+			return uint64(0)
+		}
+		return v.HLL.Count()
+	case "percentile":
+		if v.Digest == nil {
+			return nil
+		}
+		if q := v.Digest.Quantile(a.Percentile); !math.IsNaN(q) {
+			return q
+		}
+	}
+	return nil
+}
+
+// window is one open window of one group. Start and End are Unix
+// nanoseconds; a session window's End is its last event plus the gap.
+type window struct {
+	Group   string     `json:"group"`
+	Start   int64      `json:"start"`
+	End     int64      `json:"end"`
+	Records int64      `json:"records"`
+	Fired   bool       `json:"fired,omitempty"`
+	Dirty   bool       `json:"dirty,omitempty"`
+	Aggs    []aggValue `json:"aggs"`
+}
+
+func (w *window) key() string {
+	return fmt.Sprintf("%s\x00%d", w.Group, w.Start)
+}
+
+func (w *window) merge(o *window) {
+	w.Start = min(w.Start, o.Start)
+	w.End = max(w.End, o.End)
+	w.Records += o.Records
+	w.Fired = w.Fired || o.Fired
+	w.Dirty = true
+	for i := range w.Aggs {
+		if i < len(o.Aggs) {
+			w.Aggs[i].merge(o.Aggs[i])
+		}
+	}
+}
+
+// sourceClock tracks the event-time progress of one upstream source.
+type sourceClock struct {
+	MaxEventTime int64 `json:"max_event_time"`
+	LastSeen     int64 `json:"last_seen"`
+}
+
+// windowState is everything a window node checkpoints: the clock of every
+// source it has seen, the windows that are still open or within their allowed
+// lateness, and the table and schema of the latest record, which windows
+// fired by a tick are emitted with.
+type windowState struct {
+	Sources map[string]*sourceClock `json:"sources"`
+	Windows map[string]*window      `json:"windows"`
+	Table   string                  `json:"table,omitempty"`
+	Schema  string                  `json:"schema,omitempty"`
+}
+
+func newWindowState() *windowState {
+	return &windowState{
+		Sources: make(map[string]*sourceClock),
+		Windows: make(map[string]*window),
+	}
+}
+
+// watermark is the event time up to which every active source is believed
+// complete: the slowest source's latest event time minus the allowed
+// out-of-orderness. Sources idle for longer than idle (when set) stop holding
+// it back. It returns false until any source has been seen.
+func (s *windowState) watermark(outOfOrder, idle time.Duration, now time.Time) (int64, bool) {
+	var wm int64
+	found := false
+	for _, c := range s.Sources {
+		if idle > 0 && now.UnixNano()-c.LastSeen > int64(idle) {
+			continue
+		}
+		if !found || c.MaxEventTime < wm {
+			wm = c.MaxEventTime
+			found = true
+		}
+	}
+	if !found {
+		// Every source is idle: event time is taken to have moved on with the
+		// wall clock since the most advanced one's last record, so its open
+		// windows close without waiting for another record.
+		for _, c := range s.Sources {
+			if t := c.MaxEventTime + now.UnixNano() - c.LastSeen; !found || t > wm {
+				wm = t
+				found = true
+			}
+		}
+	}
+	return wm - int64(outOfOrder), found
+}
+
+// sortedWindows returns the windows ordered by end, then group, so emissions
+// are deterministic.
+func sortedWindows(ws []*window) []*window {
+	sort.Slice(ws, func(i, j int) bool {
+		if ws[i].End != ws[j].End {
+			return ws[i].End < ws[j].End
+		}
+		return ws[i].Group < ws[j].Group
+	})
+	return ws
+}
+
+// parseEventTime converts a timestamp value to Unix nanoseconds. Numbers are
+// Unix epoch values whose unit (s, ms, µs or ns) is inferred from magnitude.
+func parseEventTime(v any) (int64, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t.UnixNano(), nil
+	case *time.Time:
+		if t != nil {
+			return t.UnixNano(), nil
+		}
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05", "2006-01-02"} {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts.UnixNano(), nil
+			}
+		}
+		if f, ok := evaluator.ToFloat64(t); ok {
+			return parseEventTime(f)
+		}
+		return 0, fmt.Errorf("unrecognized timestamp %q", t)
+	default:
+		if f, ok := evaluator.ToFloat64(v); ok {
+			abs := math.Abs(f)
+			switch {
+			case abs >= 1e17:
+				return int64(f), nil
+			case abs >= 1e14:
+				return int64(f * 1e3), nil
+			case abs >= 1e11:
+				return int64(f * 1e6), nil
+			default:
+				return int64(f * 1e9), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("missing or unsupported timestamp %v", v)
+}
@@ -0,0 +1,298 @@
+package flow
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/engine/registry/interfaces"
+	"github.com/user/hermod/internal/storage"
+	msgpkg "github.com/user/hermod/pkg/comm/message"
+)
+
+// memStore is an in-memory hermod.StateStore.
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (s *memStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key], nil
+}
+
+func (s *memStore) Set(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		s.data = make(map[string][]byte)
+	}
+	s.data[key] = value
+	return nil
+}
+
+func (s *memStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+// windowCtx is a minimal NodeContext backed by a memStore.
+type windowCtx struct{ store *memStore }
+
+func (c *windowCtx) BroadcastLiveMessage(workflowID, nodeID string, msg hermod.Message, isError bool, errMsg string) {
+}
+func (c *windowCtx) BroadcastLog(workflowID, level, msg, msgID string) {}
+func (c *windowCtx) ApplyTransformation(ctx context.Context, msg hermod.Message, transType string, config map[string]any) (hermod.Message, error) {
+	return msg, nil
+}
+func (c *windowCtx) ContextWithPipelineSnapshot(ctx context.Context) context.Context { return ctx }
+func (c *windowCtx) EvaluateConditions(msg hermod.Message, conditions []map[string]any) bool {
+	return true
+}
+func (c *windowCtx) Storage() interfaces.RegistryStorage                   { return nil }
+func (c *windowCtx) StateStore() hermod.StateStore                         { return c.store }
+func (c *windowCtx) GetNodeState(key string) (any, bool)                   { return nil, false }
+func (c *windowCtx) SetNodeState(key string, val any)                      {}
+func (c *windowCtx) GetSink(workflowID, nodeID string) (hermod.Sink, bool) { return nil, false }
+
+var windowEpoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func windowMsg(source string, at time.Duration, data map[string]any) hermod.Message {
+	m := msgpkg.AcquireMessage()
+	m.SetID(source + at.String())
+	m.SetTable("orders")
+	m.SetMetadata("_source_node_id", source)
+	m.SetData("ts", windowEpoch.Add(at).Format(time.RFC3339Nano))
+	for k, v := range data {
+		m.SetData(k, v)
+	}
+	return m
+}
+
+func newWindowNode(cfg map[string]any) *storage.WorkflowNode {
+	base := map[string]any{"timestamp": "ts", "window": "1m"}
+	for k, v := range cfg {
+		base[k] = v
+	}
+	return &storage.WorkflowNode{ID: "w1", Type: "window", Config: base}
+}
+
+func TestWindow_TumblingEmitsOnWatermark(t *testing.T) {
+	e := &WindowExecutor{}
+	nctx := &windowCtx{store: &memStore{}}
+	node := newWindowNode(map[string]any{"aggregations": `[{"type":"count"},{"field":"amount","type":"sum"}]`})
+
+	for _, at := range []time.Duration{10 * time.Second, 50 * time.Second, 30 * time.Second} {
+		out, branch, err := e.Execute(context.Background(), nctx, "wf", node, windowMsg("s", at, map[string]any{"amount": 2}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(out) != 0 || branch != "buffered" {
+			t.Fatalf("expected buffering before the window closes, got %d msgs on %q", len(out), branch)
+		}
+	}
+
+	out, branch, err := e.Execute(context.Background(), nctx, "wf", node, windowMsg("s", 61*time.Second, map[string]any{"amount": 5}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if branch != windowBranch || len(out) != 1 {
+		t.Fatalf("expected one window on %q, got %d on %q", windowBranch, len(out), branch)
+	}
+	d := out[0].Data()
+	if d["count"] != int64(3) || d["sum_amount"] != 6.0 || d["record_count"] != int64(3) {
+		t.Fatalf("unexpected aggregates: %v", d)
+	}
+	if d["window_start"] != windowEpoch.Format(time.RFC3339Nano) {
+		t.Fatalf("unexpected window_start %v", d["window_start"])
+	}
+	if out[0].Operation() != hermod.OpCreate {
+		t.Fatalf("expected create, got %s", out[0].Operation())
+	}
+}
+
+func TestWindow_LateRecordsRouteOrUpdate(t *testing.T) {
+	e := &WindowExecutor{}
+	nctx := &windowCtx{store: &memStore{}}
+	node := newWindowNode(map[string]any{"allowedLateness": "30s", "lateRoute": "late"})
+	ctx := context.Background()
+
+	e.Execute(ctx, nctx, "wf", node, windowMsg("s", 10*time.Second, nil))
+	if out, _, _ := e.Execute(ctx, nctx, "wf", node, windowMsg("s", 70*time.Second, nil)); len(out) != 1 {
+		t.Fatalf("expected first window to fire, got %d", len(out))
+	}
+
+	// Within allowed lateness: the window is updated and re-emitted.
+	out, branch, _ := e.Execute(ctx, nctx, "wf", node, windowMsg("s", 20*time.Second, nil))
+	if branch != windowBranch || len(out) != 1 {
+		t.Fatalf("expected an update on %q, got %d on %q", windowBranch, len(out), branch)
+	}
+	if out[0].Operation() != hermod.OpUpdate || out[0].Data()["count"] != int64(2) {
+		t.Fatalf("expected update with count 2, got %s %v", out[0].Operation(), out[0].Data())
+	}
+
+	// Past allowed lateness: the record goes to the late route unchanged.
+	e.Execute(ctx, nctx, "wf", node, windowMsg("s", 100*time.Second, nil))
+	late := windowMsg("s", 5*time.Second, nil)
+	out, branch, _ = e.Execute(ctx, nctx, "wf", node, late)
+	if branch != "late" || len(out) != 1 || out[0] != late {
+		t.Fatalf("expected the record on the late route, got %d on %q", len(out), branch)
+	}
+}
+
+func TestWindow_SlowestSourceHoldsWatermark(t *testing.T) {
+	e := &WindowExecutor{}
+	nctx := &windowCtx{store: &memStore{}}
+	node := newWindowNode(nil)
+	ctx := context.Background()
+
+	e.Execute(ctx, nctx, "wf", node, windowMsg("a", 10*time.Second, nil))
+	e.Execute(ctx, nctx, "wf", node, windowMsg("b", 20*time.Second, nil))
+	if out, _, _ := e.Execute(ctx, nctx, "wf", node, windowMsg("a", 90*time.Second, nil)); len(out) != 0 {
+		t.Fatalf("source b has not passed the window end, expected no emission, got %d", len(out))
+	}
+	if out, _, _ := e.Execute(ctx, nctx, "wf", node, windowMsg("b", 65*time.Second, nil)); len(out) != 1 {
+		t.Fatalf("expected the window to fire once every source passed it, got %d", len(out))
+	}
+}
+
+func TestWindow_DistinctAndPercentile(t *testing.T) {
+	e := &WindowExecutor{}
+	nctx := &windowCtx{store: &memStore{}}
+	node := newWindowNode(map[string]any{
+		"groupBy": "region",
+		"aggregations": []any{
+			map[string]any{"field": "user", "type": "distinct", "as": "users"},
+			map[string]any{"field": "latency", "type": "percentile", "percentile": 90, "as": "p90"},
+		},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		e.Execute(ctx, nctx, "wf", node, windowMsg("s", time.Duration(i)*100*time.Millisecond, map[string]any{
+			"region":  "eu",
+			"user":    i % 10,
+			"latency": float64(i + 1),
+		}))
+	}
+	out, _, _ := e.Execute(ctx, nctx, "wf", node, windowMsg("s", 2*time.Minute, map[string]any{"region": "eu"}))
+	if len(out) != 1 {
+		t.Fatalf("expected one window, got %d", len(out))
+	}
+	d := out[0].Data()
+	if d["group"] != "eu" || d["users"] != uint64(10) {
+		t.Fatalf("unexpected group or distinct count: %v", d)
+	}
+	if p90 := d["p90"].(float64); math.Abs(p90-90) > 2 {
+		t.Fatalf("expected p90 near 90, got %v", p90)
+	}
+}
+
+func TestWindow_SessionMerges(t *testing.T) {
+	e := &WindowExecutor{}
+	nctx := &windowCtx{store: &memStore{}}
+	node := newWindowNode(map[string]any{"windowType": "session", "gap": "10s"})
+	ctx := context.Background()
+
+	e.Execute(ctx, nctx, "wf", node, windowMsg("s", 0, nil))
+	e.Execute(ctx, nctx, "wf", node, windowMsg("s", 8*time.Second, nil))
+	e.Execute(ctx, nctx, "wf", node, windowMsg("s", 4*time.Second, nil))
+	out, _, _ := e.Execute(ctx, nctx, "wf", node, windowMsg("s", 40*time.Second, nil))
+	if len(out) != 1 {
+		t.Fatalf("expected one session, got %d", len(out))
+	}
+	d := out[0].Data()
+	if d["count"] != int64(3) || d["window_end"] != windowEpoch.Add(18*time.Second).Format(time.RFC3339Nano) {
+		t.Fatalf("unexpected session: %v", d)
+	}
+}
+
+func TestWindow_StateSurvivesRestart(t *testing.T) {
+	store := &memStore{}
+	node := newWindowNode(map[string]any{"aggregations": `[{"field":"user","type":"distinct"}]`})
+	ctx := context.Background()
+	now := time.Now()
+
+	first := &WindowExecutor{now: func() time.Time { return now }}
+	first.Execute(ctx, &windowCtx{store: store}, "wf", node, windowMsg("s", 10*time.Second, map[string]any{"user": "a"}))
+	first.Execute(ctx, &windowCtx{store: store}, "wf", node, windowMsg("s", 20*time.Second, map[string]any{"user": "b"}))
+	if len(store.data[windowStateKey("wf", "w1")]) != 0 {
+		t.Fatal("expected buffered records not to be checkpointed before the interval")
+	}
+	now = now.Add(defaultWindowCheckpointInterval)
+	if out, _, err := first.Tick(ctx, &windowCtx{store: store}, "wf", node); err != nil || len(out) != 0 {
+		t.Fatalf("expected the tick to emit nothing, got %d, %v", len(out), err)
+	}
+	if len(store.data[windowStateKey("wf", "w1")]) == 0 {
+		t.Fatal("expected window state to be checkpointed once the interval passed")
+	}
+
+	second := &WindowExecutor{}
+	out, _, err := second.Execute(ctx, &windowCtx{store: store}, "wf", node, windowMsg("s", 61*time.Second, map[string]any{"user": "c"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out) != 1 || out[0].Data()["distinct_user"] != uint64(2) {
+		t.Fatalf("expected the restored window to fire with 2 distinct users, got %v", out)
+	}
+}
+
+func TestWindow_TickClosesIdleWindows(t *testing.T) {
+	store := &memStore{}
+	nctx := &windowCtx{store: store}
+	node := newWindowNode(map[string]any{"idleTimeout": "30s"})
+	ctx := context.Background()
+	now := time.Now()
+	e := &WindowExecutor{now: func() time.Time { return now }}
+
+	e.Execute(ctx, nctx, "wf", node, windowMsg("s", 10*time.Second, nil))
+	now = now.Add(20 * time.Second)
+	if out, _, _ := e.Tick(ctx, nctx, "wf", node); len(out) != 0 {
+		t.Fatalf("expected no emission before the source is idle, got %d", len(out))
+	}
+
+	// Idle for 60s since the record at 10s: event time has reached 70s.
+	now = now.Add(40 * time.Second)
+	out, branch, err := e.Tick(ctx, nctx, "wf", node)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if branch != windowBranch || len(out) != 1 {
+		t.Fatalf("expected the idle window to fire on %q, got %d on %q", windowBranch, len(out), branch)
+	}
+	if out[0].Table() != "orders" || out[0].Data()["count"] != int64(1) {
+		t.Fatalf("unexpected window: %s %v", out[0].Table(), out[0].Data())
+	}
+	if len(store.data[windowStateKey("wf", "w1")]) == 0 {
+		t.Fatal("expected the fired window to be checkpointed right away")
+	}
+	if out, _, _ := e.Tick(ctx, nctx, "wf", node); len(out) != 0 {
+		t.Fatalf("expected the window to fire once, got %d more", len(out))
+	}
+}
+
+func TestParseEventTime(t *testing.T) {
+	want := windowEpoch.UnixNano()
+	for _, v := range []any{
+		windowEpoch,
+		windowEpoch.Format(time.RFC3339),
+		float64(windowEpoch.Unix()),
+		windowEpoch.UnixMilli(),
+		windowEpoch.UnixMicro(),
+		windowEpoch.UnixNano(),
+	} {
+		got, err := parseEventTime(v)
+		if err != nil || got != want {
+			t.Fatalf("parseEventTime(%v) = %d, %v; want %d", v, got, err, want)
+		}
+	}
+	if _, err := parseEventTime(nil); err == nil {
+		t.Fatal("expected an error for a missing timestamp")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/user/hermod/internal/engine/registry/interfaces"
 	"github.com/user/hermod/internal/factory"
 	"github.com/user/hermod/internal/governance"
+	"github.com/user/hermod/internal/leader"
 	"github.com/user/hermod/internal/mesh"
 	"github.com/user/hermod/internal/notification"
 	"github.com/user/hermod/internal/optimizer"
@@ -79,7 +80,14 @@ type DebuggerEvent struct {
 	NodeID     string         `json:"node_id"`
 	MsgID      string         `json:"msg_id"`
 	Data       map[string]any `json:"data"`
-	State      string         `json:"state"` // "paused", "resumed", "aborted"
+	State      string         `json:"state"` // "paused", "resumed", "stepped", "skipped", "dropped", "timeout" or "breakpoints"
+	// BreakpointID and Hits identify the breakpoint a message paused at.
+	BreakpointID string `json:"breakpoint_id,omitempty"`
+	Hits         int    `json:"hits,omitempty"`
+	// Watches holds the value of every watch expression for a paused message.
+	Watches     map[string]any `json:"watches,omitempty"`
+	Breakpoints []Breakpoint   `json:"breakpoints,omitempty"`
+	Error       string         `json:"error,omitempty"`
 }
 
 type PIIStats struct {
@@ -109,8 +117,11 @@ type Registry struct {
 	debuggerSubs        map[string]map[chan DebuggerEvent]bool
 	statusSubsMu        sync.RWMutex
 	debuggerSubsMu      sync.RWMutex
-	debugChans          map[string]chan string
-	debugChansMu        sync.Mutex
+	debugPauses         map[string]*debugPause
+	breakpoints         map[string]map[string]*Breakpoint
+	debugWatches        map[string][]*evaluator.Expression
+	debugSteps          map[string]time.Time
+	debugMu             sync.Mutex
 	lastDashboardUpdate time.Time
 	startTime           time.Time
 
@@ -149,6 +160,12 @@ type Registry struct {
 	reconciling   map[string]struct{}
 	reconcilingMu sync.Mutex
 
+	// eventWaitNodes holds the wait_for_event nodes of each active
+	// workflow, which every incoming message is matched against.
+	eventWaitNodes map[string][]*storage.WorkflowNode
+	eventWaitsMu   sync.Mutex
+	hasEventWaits  atomic.Int32
+
 	sf singleflight.Group
 
 	// backgroundTasks bounds concurrent background work (tracing, PII discovery)
@@ -158,6 +175,10 @@ type Registry struct {
 	hasLiveSubs   atomic.Int32
 	hasStatusSubs atomic.Int32
 
+	// stopSingletons cancels the replica-wide maintenance loops NewRegistry
+	// starts, once SetElector hands them to the elected leader instead.
+	stopSingletons context.CancelFunc
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
@@ -263,7 +284,10 @@ func NewRegistry(s storage.Storage, ls ...storage.Storage) *Registry {
 		liveMsgSubs:         make(map[chan LiveMessage]bool),
 		workflowLiveMsgSubs: make(map[string]map[chan LiveMessage]bool),
 		debuggerSubs:        make(map[string]map[chan DebuggerEvent]bool),
-		debugChans:          make(map[string]chan string),
+		debugPauses:         make(map[string]*debugPause),
+		breakpoints:         make(map[string]map[string]*Breakpoint),
+		debugWatches:        make(map[string][]*evaluator.Expression),
+		debugSteps:          make(map[string]time.Time),
 		notificationService: ns,
 		nodeStates:          make(map[string]any),
 		lookupCache:         make(map[string]lookupCacheEntry),
@@ -280,6 +304,7 @@ func NewRegistry(s storage.Storage, ls ...storage.Storage) *Registry {
 		sourceCache:         make(map[string]storage.Source),
 		sinkCache:           make(map[string]storage.Sink),
 		reconciling:         make(map[string]struct{}),
+		eventWaitNodes:      make(map[string][]*storage.WorkflowNode),
 		sf:                  singleflight.Group{},
 		backgroundTasks:     make(chan struct{}, 1000), // Max 1000 concurrent background tasks
 		ctx:                 ctx,
@@ -309,9 +334,12 @@ func NewRegistry(s storage.Storage, ls ...storage.Storage) *Registry {
 
 	// Start background maintenance routines
 	go reg.runIdleMonitor()
-	go reg.runRetentionPurge()
+	singletonCtx, stopSingletons := context.WithCancel(ctx)
+	reg.stopSingletons = stopSingletons
+	go reg.runRetentionPurge(singletonCtx)
+	go reg.optimizer.Start(singletonCtx)
+	// Every replica resumes the suspended messages of the engines it runs.
 	go reg.startReconciliationLoop(ctx)
-	go reg.optimizer.Start(reg.ctx)
 	go reg.runStatusFlusher()
 	return reg
 }
@@ -381,7 +409,7 @@ func (r *Registry) flushStatsToStorage() {
 	}
 }
 
-func (r *Registry) runRetentionPurge() {
+func (r *Registry) runRetentionPurge(ctx context.Context) {
 	defer func() {
 		if p := recover(); p != nil {
 			r.logger.Error("Registry: retention purge panicked", "panic", p)
@@ -395,7 +423,7 @@ func (r *Registry) runRetentionPurge() {
 
 	for {
 		select {
-		case <-r.ctx.Done():
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
 			r.purgeRetention()
@@ -605,6 +633,25 @@ func (r *Registry) SetStateStore(ss hermod.StateStore) {
 	r.stateStore = ss
 }
 
+// SetElector moves the retention purge and optimizer loops under e, so that
+// of several replicas only the leader runs them. The jobs also stop when the
+// registry is closed. Suspended-message reconciliation stays on every
+// replica, since each can only resume the workflows it runs.
+func (r *Registry) SetElector(e *leader.Elector) {
+	r.stopSingletons()
+	singleton := func(run func(ctx context.Context)) func(ctx context.Context) {
+		return func(ctx context.Context) {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			stop := context.AfterFunc(r.ctx, cancel)
+			defer stop()
+			run(ctx)
+		}
+	}
+	e.Register("retention-purge", singleton(r.runRetentionPurge))
+	e.Register("optimizer", singleton(r.optimizer.Start))
+}
+
 func getConfigString(config map[string]any, key string) string {
 	if val, ok := config[key]; ok {
 		if s, ok := val.(string); ok {
@@ -1556,9 +1603,13 @@ func (r *Registry) ValidateWorkflow(ctx context.Context, wf storage.Workflow) er
 				return fmt.Errorf("sink node %s is not configured", r.getNodeName(node))
 			}
 			if r.storage != nil {
-				if _, err := r.GetSinkConfig(ctx, node.RefID); err != nil {
+				snk, err := r.GetSinkConfig(ctx, node.RefID)
+				if err != nil {
 					return fmt.Errorf("sink node %s refers to missing sink %s: %w", r.getNodeName(node), node.RefID, err)
 				}
+				if err := ValidateSchemaChangePolicy(snk.Type, snk.Config); err != nil {
+					return fmt.Errorf("sink node %s: %w", r.getNodeName(node), err)
+				}
 			}
 		}
 	}
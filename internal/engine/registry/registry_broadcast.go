@@ -180,29 +180,23 @@ func (r *Registry) SubscribeDebugger(workflowID string) chan DebuggerEvent {
 	return ch
 }
 
+// UnsubscribeDebugger detaches a debugger client. Messages paused in the
+// workflow continue once its last client has gone.
 func (r *Registry) UnsubscribeDebugger(workflowID string, ch chan DebuggerEvent) {
 	r.debuggerSubsMu.Lock()
-	defer r.debuggerSubsMu.Unlock()
+	last := false
 	if r.debuggerSubs[workflowID] != nil {
 		delete(r.debuggerSubs[workflowID], ch)
 		if len(r.debuggerSubs[workflowID]) == 0 {
 			delete(r.debuggerSubs, workflowID)
+			last = true
 		}
 	}
 	close(ch)
-}
-
-func (r *Registry) DebuggerCommand(workflowID, msgID, action string) {
-	key := workflowID + ":" + msgID
-	r.debugChansMu.Lock()
-	ch, ok := r.debugChans[key]
-	r.debugChansMu.Unlock()
+	r.debuggerSubsMu.Unlock()
 
-	if ok {
-		select {
-		case ch <- action:
-		default:
-		}
+	if last {
+		r.releaseDebugPauses(workflowID)
 	}
 }
 
@@ -477,46 +471,6 @@ func (r *Registry) IsDebuggerAttached(workflowID string) bool {
 	return len(r.debuggerSubs[workflowID]) > 0
 }
 
-func (r *Registry) PauseForDebugger(workflowID, nodeID string, msg hermod.Message) {
-	if msg == nil {
-		return
-	}
-
-	ev := DebuggerEvent{
-		WorkflowID: workflowID,
-		NodeID:     nodeID,
-		MsgID:      msg.ID(),
-		Data:       r.getConsistentData(msg),
-		State:      "paused",
-	}
-	r.broadcastDebuggerEvent(ev)
-
-	key := workflowID + ":" + msg.ID()
-	ch := make(chan string, 1)
-
-	r.debugChansMu.Lock()
-	r.debugChans[key] = ch
-	r.debugChansMu.Unlock()
-
-	defer func() {
-		r.debugChansMu.Lock()
-		delete(r.debugChans, key)
-		r.debugChansMu.Unlock()
-	}()
-
-	select {
-	case action := <-ch:
-		r.broadcastDebuggerEvent(DebuggerEvent{
-			WorkflowID: workflowID,
-			NodeID:     nodeID,
-			MsgID:      msg.ID(),
-			State:      action,
-		})
-	case <-time.After(5 * time.Minute):
-		// Timeout
-	}
-}
-
 func (r *Registry) RecordStep(ctx context.Context, workflowID, messageID string, step hermod.TraceStep) {
 	r.mu.RLock()
 	ls := r.logStorage
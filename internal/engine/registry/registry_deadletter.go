@@ -0,0 +1,198 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/factory"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/pkg/comm/message"
+)
+
+// deadLetterStamps are the metadata keys the engine sets when it diverts a
+// message. They describe the failure rather than the message, so they are
+// stored as columns and stripped again on replay.
+var deadLetterStamps = []string{"_hermod_failed_sink", "_hermod_last_error", "_hermod_error_class", "_hermod_failed_at"}
+
+// RecordDeadLetter implements hermod.DeadLetterRecorder. It keeps a copy of
+// every message the engine diverts so it can be inspected, edited and
+// replayed from the API, whether or not the workflow has a dead letter sink.
+func (r *Registry) RecordDeadLetter(ctx context.Context, workflowID string, msg hermod.Message) error {
+	if r.storage == nil || msg == nil {
+		return errors.New("registry storage not available")
+	}
+	md := maps.Clone(msg.Metadata())
+	dl := storage.DeadLetter{
+		ID:         uuid.New().String(),
+		WorkflowID: workflowID,
+		SinkID:     md["_hermod_failed_sink"],
+		MessageID:  msg.ID(),
+		Operation:  string(msg.Operation()),
+		Table:      msg.Table(),
+		ErrorClass: md["_hermod_error_class"],
+		Error:      md["_hermod_last_error"],
+		Payload:    msg.Payload(),
+		Data:       maps.Clone(msg.Data()),
+		Status:     "pending",
+		CreatedAt:  time.Now(),
+	}
+	if len(dl.Payload) == 0 {
+		dl.Payload = msg.After()
+	}
+	for _, k := range deadLetterStamps {
+		delete(md, k)
+	}
+	dl.Metadata = md
+	return r.storage.CreateDeadLetter(ctx, dl)
+}
+
+// ReplayDeadLetter re-delivers a dead letter. With a sinkID the message is
+// written straight to that sink; with a nodeID it re-enters the workflow at
+// that node and continues downstream. With neither it goes back to the sink
+// that rejected it. The returned error is the target's, so callers can tell
+// whether the replay succeeded.
+func (r *Registry) ReplayDeadLetter(ctx context.Context, dl storage.DeadLetter, nodeID, sinkID string) error {
+	p := r.NewDeadLetterReplay()
+	defer p.Close()
+	return p.ReplayDeadLetter(ctx, dl, nodeID, sinkID)
+}
+
+// DeadLetterReplay re-delivers dead letters as Registry.ReplayDeadLetter
+// does, but opens each sink once and keeps it open until Close, so a bulk
+// replay does not connect to the target for every letter. It is not safe for
+// concurrent use.
+type DeadLetterReplay struct {
+	r     *Registry
+	sinks map[string]hermod.Sink
+}
+
+// NewDeadLetterReplay starts a replay. The caller closes it.
+func (r *Registry) NewDeadLetterReplay() *DeadLetterReplay {
+	return &DeadLetterReplay{r: r, sinks: make(map[string]hermod.Sink)}
+}
+
+// ReplayDeadLetter re-delivers one dead letter; see Registry.ReplayDeadLetter.
+func (p *DeadLetterReplay) ReplayDeadLetter(ctx context.Context, dl storage.DeadLetter, nodeID, sinkID string) error {
+	r := p.r
+	if nodeID == "" && sinkID == "" {
+		sinkID = dl.SinkID
+	}
+	if nodeID == "" && sinkID == "" {
+		return errors.New("dead letter has no failed sink; specify a node or sink to replay to")
+	}
+
+	m := deadLetterMessage(dl)
+	defer message.ReleaseMessage(m)
+
+	if sinkID != "" {
+		snk, err := p.sink(ctx, sinkID)
+		if err != nil {
+			return err
+		}
+		return snk.Write(ctx, m)
+	}
+
+	if r.storage == nil {
+		return errors.New("registry storage not available")
+	}
+	wf, err := r.storage.GetWorkflow(ctx, dl.WorkflowID)
+	if err != nil {
+		return err
+	}
+	nodeMap, adj := replayTopology(wf)
+	node := nodeMap[nodeID]
+	if node == nil {
+		return fmt.Errorf("node %s not found in workflow %s", nodeID, wf.ID)
+	}
+	if node.Type == "sink" {
+		snk, err := p.sink(ctx, node.RefID)
+		if err != nil {
+			return err
+		}
+		return snk.Write(ctx, m)
+	}
+
+	processed, branch, err := r.RunWorkflowNode(wf.ID, node, m)
+	defer func() {
+		for _, pm := range processed {
+			if pm != m {
+				pm.Release()
+			}
+		}
+	}()
+	if err != nil {
+		return fmt.Errorf("node %s: %w", r.getNodeName(*node), err)
+	}
+	if len(processed) == 0 {
+		return nil
+	}
+
+	var sinks []hermod.Sink
+	sinkNodeToIndex := make(map[string]int)
+	for _, n := range wf.Nodes {
+		if n.Type != "sink" {
+			continue
+		}
+		snk, err := p.sink(ctx, n.RefID)
+		if err != nil {
+			return err
+		}
+		sinkNodeToIndex[n.ID] = len(sinks)
+		sinks = append(sinks, snk)
+	}
+	var errs []error
+	for _, pm := range processed {
+		errs = append(errs, r.resumeFromNode(wf.ID, node.ID, pm, wf, nodeMap, adj, sinks, sinkNodeToIndex, branch))
+	}
+	return errors.Join(errs...)
+}
+
+// sink returns the sink with sinkID, opening it on first use.
+func (p *DeadLetterReplay) sink(ctx context.Context, sinkID string) (hermod.Sink, error) {
+	if snk, ok := p.sinks[sinkID]; ok {
+		return snk, nil
+	}
+	dbSnk, err := p.r.GetSinkConfig(ctx, sinkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sink %s: %w", sinkID, err)
+	}
+	snk, err := p.r.createSinkInternal(ctx, factory.SinkConfig{ID: dbSnk.ID, Type: dbSnk.Type, Config: dbSnk.Config})
+	if err != nil {
+		return nil, err
+	}
+	p.sinks[sinkID] = snk
+	return snk, nil
+}
+
+// Close closes the sinks the replay opened.
+func (p *DeadLetterReplay) Close() {
+	for _, snk := range p.sinks {
+		_ = snk.Close()
+	}
+	clear(p.sinks)
+}
+
+// deadLetterMessage rebuilds the message a dead letter was recorded from,
+// including any edits made since.
+func deadLetterMessage(dl storage.DeadLetter) hermod.Message {
+	m := message.AcquireMessage()
+	m.SetID(dl.MessageID)
+	m.SetTable(dl.Table)
+	if dl.Operation != "" {
+		m.SetOperation(hermod.Operation(dl.Operation))
+	}
+	m.SetAfter(dl.Payload)
+	for k, v := range dl.Metadata {
+		m.SetMetadata(k, v)
+	}
+	for k, v := range dl.Data {
+		m.SetData(k, v)
+	}
+	m.SetMetadata("_hermod_replayed_from", dl.ID)
+	return m
+}
@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/factory"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/testutil"
+	"github.com/user/hermod/pkg/comm/message"
+)
+
+type deadLetterStorage struct {
+	testutil.BaseMockStorage
+	recorded []storage.DeadLetter
+	wf       storage.Workflow
+}
+
+func (s *deadLetterStorage) CreateDeadLetter(_ context.Context, dl storage.DeadLetter) error {
+	s.recorded = append(s.recorded, dl)
+	return nil
+}
+
+func (s *deadLetterStorage) GetWorkflow(_ context.Context, id string) (storage.Workflow, error) {
+	if id != s.wf.ID {
+		return storage.Workflow{}, storage.ErrNotFound
+	}
+	return s.wf, nil
+}
+
+func (s *deadLetterStorage) GetSink(_ context.Context, id string) (storage.Sink, error) {
+	return storage.Sink{ID: id, Type: "failing"}, nil
+}
+
+// failingSink rejects every write.
+type failingSink struct{ writes int }
+
+func (s *failingSink) Write(context.Context, hermod.Message) error {
+	s.writes++
+	return errors.New("sink still down")
+}
+func (s *failingSink) Ping(context.Context) error { return nil }
+func (s *failingSink) Close() error               { return nil }
+
+func TestRecordDeadLetterKeepsFailure(t *testing.T) {
+	ms := &deadLetterStorage{}
+	r := NewRegistry(ms)
+
+	msg := message.AcquireMessage()
+	msg.SetID("m1")
+	msg.SetTable("orders")
+	msg.SetOperation(hermod.OpUpdate)
+	msg.SetAfter([]byte(`{"id":1}`))
+	msg.SetData("id", 1)
+	msg.SetMetadata("tenant", "acme")
+	msg.SetMetadata("_hermod_failed_sink", "sink-1")
+	msg.SetMetadata("_hermod_last_error", "duplicate key")
+	msg.SetMetadata("_hermod_error_class", "sink_write")
+
+	r.RecordDeadLetter(t.Context(), "wf-1", msg)
+
+	if len(ms.recorded) != 1 {
+		t.Fatalf("expected one dead letter, got %d", len(ms.recorded))
+	}
+	dl := ms.recorded[0]
+	if dl.WorkflowID != "wf-1" || dl.SinkID != "sink-1" || dl.Error != "duplicate key" || dl.ErrorClass != "sink_write" {
+		t.Fatalf("unexpected failure fields: %+v", dl)
+	}
+	if dl.Status != "pending" || dl.Operation != string(hermod.OpUpdate) || string(dl.Payload) != `{"id":1}` {
+		t.Fatalf("unexpected message fields: %+v", dl)
+	}
+	if _, ok := dl.Metadata["_hermod_last_error"]; ok || dl.Metadata["tenant"] != "acme" {
+		t.Fatalf("expected failure stamps stripped and user metadata kept, got %v", dl.Metadata)
+	}
+}
+
+func TestReplayDeadLetterNeedsTarget(t *testing.T) {
+	ms := &deadLetterStorage{wf: storage.Workflow{ID: "wf-1", Nodes: []storage.WorkflowNode{{ID: "n1", Type: "transformation"}}}}
+	r := NewRegistry(ms)
+
+	if err := r.ReplayDeadLetter(t.Context(), storage.DeadLetter{WorkflowID: "wf-1"}, "", ""); err == nil {
+		t.Fatal("expected an error when the dead letter has no sink and no target is given")
+	}
+	err := r.ReplayDeadLetter(t.Context(), storage.DeadLetter{WorkflowID: "wf-1"}, "missing", "")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected an unknown node to be reported, got %v", err)
+	}
+}
+
+// A replay through the workflow fails when the sink downstream rejects the
+// message, and a bulk replay opens that sink once.
+func TestReplayDeadLetterReportsSinkFailure(t *testing.T) {
+	ms := &deadLetterStorage{wf: storage.Workflow{
+		ID: "wf-1",
+		Nodes: []storage.WorkflowNode{
+			{ID: "n1", Type: "transformation", Config: map[string]any{"transType": "set", "field": "a", "value": 1}},
+			{ID: "n2", Type: "sink", RefID: "sink-1"},
+		},
+		Edges: []storage.WorkflowEdge{{ID: "e1", SourceID: "n1", TargetID: "n2"}},
+	}}
+	r := NewRegistry(ms)
+	snk := &failingSink{}
+	opened := 0
+	r.SetFactories(nil, func(factory.SinkConfig) (hermod.Sink, error) {
+		opened++
+		return snk, nil
+	})
+
+	p := r.NewDeadLetterReplay()
+	defer p.Close()
+	for _, id := range []string{"dl-1", "dl-2"} {
+		err := p.ReplayDeadLetter(t.Context(), storage.DeadLetter{ID: id, WorkflowID: "wf-1", Payload: []byte(`{}`)}, "n1", "")
+		if err == nil || !strings.Contains(err.Error(), "sink still down") {
+			t.Fatalf("%s: expected the sink's error, got %v", id, err)
+		}
+	}
+	if snk.writes != 2 || opened != 1 {
+		t.Fatalf("expected 2 writes through one sink, got %d writes and %d sinks opened", snk.writes, opened)
+	}
+}
@@ -0,0 +1,393 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/engine/registry/traversal"
+	"github.com/user/hermod/pkg/infra/evaluator"
+)
+
+// Breakpoints expire so that a forgotten debug session cannot stall
+// production traffic.
+const (
+	// DefaultBreakpointTTL is how long a breakpoint lives without a ttl.
+	DefaultBreakpointTTL = 15 * time.Minute
+	// MaxBreakpointTTL caps the ttl of a breakpoint.
+	MaxBreakpointTTL = time.Hour
+	// debugPauseTimeout is how long a message stays paused without a command
+	// before it continues on its own.
+	debugPauseTimeout = 5 * time.Minute
+)
+
+// Debugger actions. The empty action, "resume" and "continue" all continue
+// the paused message.
+const (
+	DebugContinue   = "continue"
+	DebugStep       = "step"
+	DebugSkip       = "skip"
+	DebugDrop       = "drop"
+	DebugEdit       = "edit"
+	DebugSetBreak   = "set_breakpoint"
+	DebugClearBreak = "remove_breakpoint"
+	DebugSetWatches = "set_watches"
+)
+
+// Breakpoint pauses messages reaching a node. A message pauses when it meets
+// Condition, if any, once the breakpoint has been hit HitCount times.
+type Breakpoint struct {
+	ID     string `json:"id"`
+	NodeID string `json:"node_id"`
+	// Condition is an expression such as "amount > 10000".
+	Condition string    `json:"condition,omitempty"`
+	HitCount  int       `json:"hit_count,omitempty"`
+	Hits      int       `json:"hits"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	condition *evaluator.Expression
+}
+
+// DebugCommand is sent by a debugger client. Data and Metadata are applied to
+// the paused message MsgID before the action; a nil data value deletes the
+// field.
+type DebugCommand struct {
+	Action       string            `json:"action"`
+	MsgID        string            `json:"msg_id,omitempty"`
+	Data         map[string]any    `json:"data,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Breakpoint   *Breakpoint       `json:"breakpoint,omitempty"`
+	BreakpointID string            `json:"breakpoint_id,omitempty"`
+	// TTL is how long a new breakpoint lives, DefaultBreakpointTTL if empty.
+	TTL     string   `json:"ttl,omitempty"`
+	Watches []string `json:"watches,omitempty"`
+}
+
+// debugPause is a message paused at a node.
+type debugPause struct {
+	nodeID   string
+	msg      hermod.Message
+	commands chan DebugCommand
+}
+
+// SetBreakpoint adds a breakpoint to a workflow, or replaces the one with the
+// same ID.
+func (r *Registry) SetBreakpoint(workflowID string, bp Breakpoint, ttl time.Duration) (Breakpoint, error) {
+	if bp.NodeID == "" {
+		return Breakpoint{}, errors.New("breakpoint needs a node")
+	}
+	var condition *evaluator.Expression
+	if strings.TrimSpace(bp.Condition) != "" {
+		c, err := evaluator.CompileCondition(bp.Condition)
+		if err != nil {
+			return Breakpoint{}, err
+		}
+		condition = c
+	}
+	if ttl <= 0 {
+		ttl = DefaultBreakpointTTL
+	}
+	if bp.ID == "" {
+		bp.ID = uuid.NewString()
+	}
+	bp.Hits = 0
+	bp.ExpiresAt = time.Now().Add(min(ttl, MaxBreakpointTTL))
+	bp.condition = condition
+
+	r.debugMu.Lock()
+	if r.breakpoints[workflowID] == nil {
+		r.breakpoints[workflowID] = make(map[string]*Breakpoint)
+	}
+	r.breakpoints[workflowID][bp.ID] = &bp
+	r.debugMu.Unlock()
+
+	r.broadcastBreakpoints(workflowID)
+	return bp, nil
+}
+
+// RemoveBreakpoint deletes a breakpoint of a workflow.
+func (r *Registry) RemoveBreakpoint(workflowID, id string) {
+	r.debugMu.Lock()
+	delete(r.breakpoints[workflowID], id)
+	if len(r.breakpoints[workflowID]) == 0 {
+		delete(r.breakpoints, workflowID)
+	}
+	r.debugMu.Unlock()
+
+	r.broadcastBreakpoints(workflowID)
+}
+
+// Breakpoints returns the live breakpoints of a workflow.
+func (r *Registry) Breakpoints(workflowID string) []Breakpoint {
+	r.debugMu.Lock()
+	defer r.debugMu.Unlock()
+	return r.liveBreakpoints(workflowID, time.Now())
+}
+
+// liveBreakpoints drops the expired breakpoints of a workflow and returns the
+// others. debugMu must be held.
+func (r *Registry) liveBreakpoints(workflowID string, now time.Time) []Breakpoint {
+	var live []Breakpoint
+	for id, bp := range r.breakpoints[workflowID] {
+		if now.After(bp.ExpiresAt) {
+			delete(r.breakpoints[workflowID], id)
+			continue
+		}
+		live = append(live, *bp)
+	}
+	if len(r.breakpoints[workflowID]) == 0 {
+		delete(r.breakpoints, workflowID)
+	}
+	return live
+}
+
+// SetWatches replaces the watch expressions evaluated for every message
+// paused in a workflow.
+func (r *Registry) SetWatches(workflowID string, exprs []string) error {
+	watches := make([]*evaluator.Expression, 0, len(exprs))
+	for _, src := range exprs {
+		x, err := evaluator.Compile(src)
+		if err != nil {
+			return err
+		}
+		watches = append(watches, x)
+	}
+
+	r.debugMu.Lock()
+	defer r.debugMu.Unlock()
+	if len(watches) == 0 {
+		delete(r.debugWatches, workflowID)
+		return nil
+	}
+	r.debugWatches[workflowID] = watches
+	return nil
+}
+
+// DebuggerCommand applies a command from a debugger client of a workflow.
+func (r *Registry) DebuggerCommand(workflowID string, cmd DebugCommand) error {
+	switch cmd.Action {
+	case DebugSetBreak:
+		if cmd.Breakpoint == nil {
+			return errors.New("set_breakpoint needs a breakpoint")
+		}
+		var ttl time.Duration
+		if cmd.TTL != "" {
+			d, err := time.ParseDuration(cmd.TTL)
+			if err != nil {
+				return fmt.Errorf("invalid breakpoint ttl %q: %w", cmd.TTL, err)
+			}
+			ttl = d
+		}
+		_, err := r.SetBreakpoint(workflowID, *cmd.Breakpoint, ttl)
+		return err
+	case DebugClearBreak:
+		r.RemoveBreakpoint(workflowID, cmd.BreakpointID)
+		return nil
+	case DebugSetWatches:
+		return r.SetWatches(workflowID, cmd.Watches)
+	}
+
+	r.debugMu.Lock()
+	p, ok := r.debugPauses[workflowID+":"+cmd.MsgID]
+	r.debugMu.Unlock()
+	if !ok {
+		return fmt.Errorf("message %s is not paused", cmd.MsgID)
+	}
+	select {
+	case p.commands <- cmd:
+	default:
+		return fmt.Errorf("message %s is busy", cmd.MsgID)
+	}
+	return nil
+}
+
+// PauseForDebugger pauses msg at a node when a breakpoint of the workflow
+// matches it, or when it is being stepped, and returns the debugger's action:
+// traversal.DebugContinue, traversal.DebugSkip or traversal.DebugDrop.
+func (r *Registry) PauseForDebugger(workflowID, nodeID string, msg hermod.Message) string {
+	if msg == nil {
+		return traversal.DebugContinue
+	}
+	bp, ok := r.debugBreak(workflowID, nodeID, msg)
+	if !ok {
+		return traversal.DebugContinue
+	}
+
+	key := workflowID + ":" + msg.ID()
+	p := &debugPause{nodeID: nodeID, msg: msg, commands: make(chan DebugCommand, 1)}
+	r.debugMu.Lock()
+	if _, busy := r.debugPauses[key]; busy {
+		// A copy of the message is already paused on another branch.
+		r.debugMu.Unlock()
+		return traversal.DebugContinue
+	}
+	r.debugPauses[key] = p
+	r.debugMu.Unlock()
+	defer func() {
+		r.debugMu.Lock()
+		delete(r.debugPauses, key)
+		r.debugMu.Unlock()
+	}()
+
+	r.broadcastDebuggerEvent(r.pausedEvent(workflowID, p, bp))
+
+	timeout := time.NewTimer(debugPauseTimeout)
+	defer timeout.Stop()
+	for {
+		var cmd DebugCommand
+		select {
+		case cmd = <-p.commands:
+		case <-timeout.C:
+			r.broadcastDebuggerEvent(DebuggerEvent{WorkflowID: workflowID, NodeID: nodeID, MsgID: msg.ID(), State: "timeout"})
+			return traversal.DebugContinue
+		case <-r.ctx.Done():
+			return traversal.DebugContinue
+		}
+
+		applyDebugEdits(msg, cmd)
+		state, action := "resumed", traversal.DebugContinue
+		switch cmd.Action {
+		case DebugEdit:
+			r.broadcastDebuggerEvent(r.pausedEvent(workflowID, p, bp))
+			continue
+		case DebugStep:
+			r.stepDebugger(key)
+			state = "stepped"
+		case DebugSkip:
+			state, action = "skipped", traversal.DebugSkip
+		case DebugDrop, "abort":
+			state, action = "dropped", traversal.DebugDrop
+		}
+		r.broadcastDebuggerEvent(DebuggerEvent{WorkflowID: workflowID, NodeID: nodeID, MsgID: msg.ID(), State: state})
+		return action
+	}
+}
+
+// debugBreak reports whether msg pauses at a node, and at which breakpoint.
+// Stepped messages pause at the next node they reach without one.
+func (r *Registry) debugBreak(workflowID, nodeID string, msg hermod.Message) (*Breakpoint, bool) {
+	now := time.Now()
+	key := workflowID + ":" + msg.ID()
+	r.debugMu.Lock()
+	defer r.debugMu.Unlock()
+
+	if until, ok := r.debugSteps[key]; ok {
+		delete(r.debugSteps, key)
+		if now.Before(until) {
+			return nil, true
+		}
+	}
+	for id, bp := range r.breakpoints[workflowID] {
+		if now.After(bp.ExpiresAt) {
+			delete(r.breakpoints[workflowID], id)
+			continue
+		}
+		if bp.NodeID != nodeID {
+			continue
+		}
+		if bp.condition != nil {
+			if ok, err := bp.condition.EvalBool(msg); err != nil || !ok {
+				continue
+			}
+		}
+		bp.Hits++
+		if bp.Hits >= bp.HitCount {
+			hit := *bp
+			return &hit, true
+		}
+	}
+	return nil, false
+}
+
+// pausedEvent describes a paused message with the workflow's watch
+// expressions evaluated against it.
+func (r *Registry) pausedEvent(workflowID string, p *debugPause, bp *Breakpoint) DebuggerEvent {
+	r.debugMu.Lock()
+	exprs := r.debugWatches[workflowID]
+	r.debugMu.Unlock()
+
+	ev := DebuggerEvent{
+		WorkflowID: workflowID,
+		NodeID:     p.nodeID,
+		MsgID:      p.msg.ID(),
+		Data:       r.getConsistentData(p.msg),
+		State:      "paused",
+	}
+	if bp != nil {
+		ev.BreakpointID = bp.ID
+		ev.Hits = bp.Hits
+	}
+	if len(exprs) > 0 {
+		ev.Watches = make(map[string]any, len(exprs))
+		for _, x := range exprs {
+			v, err := x.Eval(p.msg)
+			if err != nil {
+				v = err.Error()
+			}
+			ev.Watches[x.String()] = v
+		}
+	}
+	return ev
+}
+
+func (r *Registry) broadcastBreakpoints(workflowID string) {
+	r.broadcastDebuggerEvent(DebuggerEvent{
+		WorkflowID:  workflowID,
+		State:       "breakpoints",
+		Breakpoints: r.Breakpoints(workflowID),
+	})
+}
+
+// stepDebugger pauses the message with key at the next node it reaches.
+func (r *Registry) stepDebugger(key string) {
+	now := time.Now()
+	r.debugMu.Lock()
+	defer r.debugMu.Unlock()
+	for k, until := range r.debugSteps {
+		if now.After(until) {
+			delete(r.debugSteps, k)
+		}
+	}
+	r.debugSteps[key] = now.Add(debugPauseTimeout)
+}
+
+// releaseDebugPauses continues every message paused in a workflow, once its
+// last debugger client has gone.
+func (r *Registry) releaseDebugPauses(workflowID string) {
+	r.debugMu.Lock()
+	defer r.debugMu.Unlock()
+	for key, p := range r.debugPauses {
+		if strings.HasPrefix(key, workflowID+":") {
+			select {
+			case p.commands <- DebugCommand{Action: DebugContinue}:
+			default:
+			}
+		}
+	}
+	for key := range r.debugSteps {
+		if strings.HasPrefix(key, workflowID+":") {
+			delete(r.debugSteps, key)
+		}
+	}
+}
+
+// applyDebugEdits sets the data and metadata of a command on msg.
+func applyDebugEdits(msg hermod.Message, cmd DebugCommand) {
+	for k, v := range cmd.Data {
+		if v == nil {
+			delete(msg.DataRef(), k)
+			continue
+		}
+		msg.SetData(k, v)
+	}
+	for k, v := range cmd.Metadata {
+		if v == "" {
+			delete(msg.MetadataRef(), k)
+			continue
+		}
+		msg.SetMetadata(k, v)
+	}
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/engine/registry/traversal"
+	"github.com/user/hermod/internal/testutil"
+	"github.com/user/hermod/pkg/comm/message"
+)
+
+func TestBreakpointConditionCompiles(t *testing.T) {
+	reg := NewRegistry(&testutil.BaseMockStorage{})
+	defer reg.Close()
+	if _, err := reg.SetBreakpoint("wf", Breakpoint{NodeID: "n1", Condition: "amount >"}, 0); err == nil {
+		t.Error("an invalid condition was accepted")
+	}
+	if _, err := reg.SetBreakpoint("wf", Breakpoint{NodeID: "n1", Condition: `amount >= 10000 && currency == "EUR" && note contains "a=b"`}, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDebuggerBreakpoints(t *testing.T) {
+	reg := NewRegistry(&testutil.BaseMockStorage{})
+	defer reg.Close()
+	const wf = "wf-debug"
+	events := reg.SubscribeDebugger(wf)
+
+	if _, err := reg.SetBreakpoint(wf, Breakpoint{ID: "big", NodeID: "n1", Condition: "amount > 10000", HitCount: 2}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.SetWatches(wf, []string{"amount", "upper(source.currency)", "amount / 1000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := func(id string, amount float64) hermod.Message {
+		m := message.AcquireMessage()
+		m.SetID(id)
+		m.SetData("amount", amount)
+		m.SetData("currency", "eur")
+		return m
+	}
+	// pause runs PauseForDebugger in the background, as a traversal would.
+	pause := func(m hermod.Message) chan string {
+		out := make(chan string, 1)
+		go func() { out <- reg.PauseForDebugger(wf, "n1", m) }()
+		return out
+	}
+	next := func(state string) DebuggerEvent {
+		for {
+			select {
+			case ev := <-events:
+				if ev.State == state {
+					return ev
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("no %s event", state)
+			}
+		}
+	}
+
+	small, first := msg("small", 5), msg("first", 20000)
+	for _, m := range []hermod.Message{small, first} {
+		if got := reg.PauseForDebugger(wf, "n1", m); got != traversal.DebugContinue {
+			t.Fatalf("message %s paused before the hit count", m.ID())
+		}
+	}
+	if got := reg.PauseForDebugger(wf, "n2", msg("other", 20000)); got != traversal.DebugContinue {
+		t.Fatal("message paused at a node without breakpoints")
+	}
+
+	// The second hit pauses; the message is edited, then skips the node.
+	second := msg("second", 30000)
+	done := pause(second)
+	ev := next("paused")
+	if ev.MsgID != "second" || ev.BreakpointID != "big" || ev.Hits != 2 || ev.Watches["amount"] != 30000.0 || ev.Watches["upper(source.currency)"] != "EUR" || ev.Watches["amount / 1000"] != 30.0 {
+		t.Fatalf("unexpected paused event: %+v", ev)
+	}
+	if err := reg.DebuggerCommand(wf, DebugCommand{Action: DebugEdit, MsgID: "second", Data: map[string]any{"amount": 1.0, "currency": nil}}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next("paused"); ev.Watches["amount"] != 1.0 {
+		t.Fatalf("edit not shown: %+v", ev)
+	}
+	if err := reg.DebuggerCommand(wf, DebugCommand{Action: DebugSkip, MsgID: "second", Metadata: map[string]string{"reviewed": "yes"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-done; got != traversal.DebugSkip {
+		t.Fatalf("got action %q, want skip", got)
+	}
+	if _, ok := second.Data()["currency"]; ok || second.Metadata()["reviewed"] != "yes" {
+		t.Fatalf("edits not applied: %v %v", second.Data(), second.Metadata())
+	}
+
+	// Stepping pauses the message again at the next node.
+	third := msg("third", 40000)
+	done = pause(third)
+	next("paused")
+	_ = reg.DebuggerCommand(wf, DebugCommand{Action: DebugStep, MsgID: "third"})
+	if got := <-done; got != traversal.DebugContinue {
+		t.Fatalf("got action %q, want continue", got)
+	}
+	go func() { done <- reg.PauseForDebugger(wf, "n2", third) }()
+	if ev := next("paused"); ev.NodeID != "n2" || ev.BreakpointID != "" {
+		t.Fatalf("step did not pause at the next node: %+v", ev)
+	}
+	_ = reg.DebuggerCommand(wf, DebugCommand{Action: DebugDrop, MsgID: "third"})
+	if got := <-done; got != traversal.DebugDrop {
+		t.Fatalf("got action %q, want drop", got)
+	}
+
+	// Expired breakpoints never pause.
+	reg.debugMu.Lock()
+	reg.breakpoints[wf]["big"].ExpiresAt = time.Now().Add(-time.Second)
+	reg.debugMu.Unlock()
+	if got := reg.PauseForDebugger(wf, "n1", msg("late", 50000)); got != traversal.DebugContinue {
+		t.Fatal("an expired breakpoint paused a message")
+	}
+	if len(reg.Breakpoints(wf)) != 0 {
+		t.Fatal("an expired breakpoint was kept")
+	}
+
+	// The last client leaving releases paused messages.
+	if _, err := reg.SetBreakpoint(wf, Breakpoint{NodeID: "n1"}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	done = pause(msg("left", 1))
+	next("paused")
+	reg.UnsubscribeDebugger(wf, events)
+	select {
+	case got := <-done:
+		if got != traversal.DebugContinue {
+			t.Fatalf("got action %q, want continue", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("paused message not released when the debugger left")
+	}
+}
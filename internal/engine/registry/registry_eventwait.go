@@ -0,0 +1,182 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/engine/registry/interfaces"
+	"github.com/user/hermod/internal/storage"
+)
+
+// Metadata set on messages resumed by wait_for_event nodes.
+const (
+	// eventWaitOutcomeMetadata is "matched" or "timeout".
+	eventWaitOutcomeMetadata = "_hermod_wait_outcome"
+	// eventWaitEventMetadata is the ID of the event that matched.
+	eventWaitEventMetadata = "_hermod_wait_event_id"
+)
+
+// waitForEventNodes returns the wait_for_event nodes of wf.
+func waitForEventNodes(wf storage.Workflow) []*storage.WorkflowNode {
+	var nodes []*storage.WorkflowNode
+	for i := range wf.Nodes {
+		if wf.Nodes[i].Type == "wait_for_event" {
+			nodes = append(nodes, &wf.Nodes[i])
+		}
+	}
+	return nodes
+}
+
+// loadEventWaits indexes the wait_for_event nodes of a starting workflow, so
+// events are matched against its waits before the next refresh.
+func (r *Registry) loadEventWaits(id string, wf storage.Workflow) {
+	nodes := waitForEventNodes(wf)
+	if len(nodes) == 0 {
+		return
+	}
+	r.eventWaitsMu.Lock()
+	defer r.eventWaitsMu.Unlock()
+	r.eventWaitNodes[id] = nodes
+	r.hasEventWaits.Store(int32(len(r.eventWaitNodes)))
+}
+
+// dropEventWaits forgets the wait_for_event nodes of a stopped workflow until
+// a refresh finds it running on another worker. Its messages stay suspended
+// in storage.
+func (r *Registry) dropEventWaits(id string) {
+	r.eventWaitsMu.Lock()
+	defer r.eventWaitsMu.Unlock()
+	delete(r.eventWaitNodes, id)
+	r.hasEventWaits.Store(int32(len(r.eventWaitNodes)))
+}
+
+// refreshEventWaits indexes the wait_for_event nodes of every active
+// workflow, wherever it runs, so the events this worker receives also match
+// messages suspended by workflows running on other workers.
+func (r *Registry) refreshEventWaits(ctx context.Context) {
+	active := true
+	wfs, _, err := r.storage.ListWorkflows(ctx, storage.CommonFilter{Active: &active})
+	if err != nil {
+		r.logger.Warn("Registry: failed to list workflows waiting for events", "error", err)
+		return
+	}
+	nodes := make(map[string][]*storage.WorkflowNode)
+	for _, wf := range wfs {
+		if n := waitForEventNodes(wf); len(n) > 0 {
+			nodes[wf.ID] = n
+		}
+	}
+	r.mu.RLock()
+	for id, ae := range r.engines {
+		if n := waitForEventNodes(ae.workflow); len(n) > 0 {
+			nodes[id] = n
+		}
+	}
+	r.mu.RUnlock()
+
+	r.eventWaitsMu.Lock()
+	defer r.eventWaitsMu.Unlock()
+	r.eventWaitNodes = nodes
+	r.hasEventWaits.Store(int32(len(nodes)))
+}
+
+// matchEventWaits resolves, as "matched", the waits of the messages waiting
+// for an event msg is. It runs for every message entering any workflow. The
+// worker running the waiting workflow resumes them on its next
+// reconciliation.
+func (r *Registry) matchEventWaits(ctx context.Context, msg hermod.Message) {
+	if r.hasEventWaits.Load() == 0 || r.storage == nil {
+		return
+	}
+	type waitNode struct {
+		workflowID string
+		node       *storage.WorkflowNode
+	}
+	var candidates []waitNode
+	r.eventWaitsMu.Lock()
+	for workflowID, nodes := range r.eventWaitNodes {
+		for _, node := range nodes {
+			candidates = append(candidates, waitNode{workflowID, node})
+		}
+	}
+	r.eventWaitsMu.Unlock()
+
+	for _, c := range candidates {
+		expr, _ := c.node.Config["eventKey"].(string)
+		if expr == "" {
+			expr, _ = c.node.Config["correlationKey"].(string)
+		}
+		if expr == "" {
+			continue
+		}
+		key := interfaces.EventKey(msg, expr)
+		if key == "" || !r.evaluateConditions(msg, eventConditions(c.node)) {
+			continue
+		}
+		waits, err := r.storage.ListEventWaits(ctx, c.workflowID, c.node.ID, key)
+		if err != nil {
+			r.logger.Warn("Registry: failed to list messages waiting for an event", "workflow_id", c.workflowID, "node_id", c.node.ID, "error", err)
+			continue
+		}
+		for _, sm := range waits {
+			r.resolveEventWait(ctx, sm, c.node, msg)
+		}
+	}
+}
+
+// eventConditions are the conditions, stored as JSON, events of a
+// wait_for_event node must meet.
+func eventConditions(node *storage.WorkflowNode) []map[string]any {
+	var conditions []map[string]any
+	switch c := node.Config["eventConditions"].(type) {
+	case string:
+		if c != "" {
+			_ = json.Unmarshal([]byte(c), &conditions)
+		}
+	case []any:
+		for _, item := range c {
+			if m, ok := item.(map[string]any); ok {
+				conditions = append(conditions, m)
+			}
+		}
+	}
+	return conditions
+}
+
+// resolveEventWait merges event into a waiting message and makes it due at
+// once. Storage lets only one worker resolve a wait, so a message is never
+// matched by two events, or matched after it timed out.
+func (r *Registry) resolveEventWait(ctx context.Context, sm storage.SuspendedMessage, node *storage.WorkflowNode, event hermod.Message) {
+	data := maps.Clone(sm.Data)
+	if data == nil {
+		data = make(map[string]any)
+	}
+	if field, _ := node.Config["mergeField"].(string); field != "" {
+		data[field] = maps.Clone(event.Data())
+	} else {
+		for k, v := range event.Data() {
+			if _, exists := data[k]; !exists {
+				data[k] = v
+			}
+		}
+	}
+	metadata := maps.Clone(sm.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata[eventWaitOutcomeMetadata] = "matched"
+	metadata[eventWaitEventMetadata] = event.ID()
+	sm.Data, sm.Metadata, sm.ResumeAt = data, metadata, time.Now()
+
+	ok, err := r.storage.ResolveEventWait(ctx, sm)
+	if err != nil {
+		r.logger.Error("Registry: failed to resolve a message waiting for an event", "workflow_id", sm.WorkflowID, "message_id", sm.ID, "error", err)
+		return
+	}
+	if ok {
+		r.BroadcastLog(sm.WorkflowID, "INFO", "Event "+event.ID()+" matched message waiting at node "+sm.NodeID, sm.ID)
+	}
+}
@@ -0,0 +1,240 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/testutil"
+	"github.com/user/hermod/pkg/comm/message"
+)
+
+// suspendStorage keeps suspended messages in memory. It can be shared by
+// several registries, standing in for the database of a cluster.
+type suspendStorage struct {
+	testutil.BaseMockStorage
+	mu        sync.Mutex
+	suspended map[string]storage.SuspendedMessage
+	workflows []storage.Workflow
+}
+
+func (s *suspendStorage) ListWorkflows(ctx context.Context, filter storage.CommonFilter) ([]storage.Workflow, int, error) {
+	return s.workflows, len(s.workflows), nil
+}
+
+func (s *suspendStorage) CreateSuspendedMessage(ctx context.Context, m storage.SuspendedMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.suspended[m.ID] = m
+	return nil
+}
+
+func (s *suspendStorage) ListSuspendedMessages(ctx context.Context, workflowID string, before time.Time) ([]storage.SuspendedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []storage.SuspendedMessage
+	for _, m := range s.suspended {
+		if !m.ResumeAt.After(before) && (workflowID == "" || m.WorkflowID == workflowID) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *suspendStorage) DeleteSuspendedMessage(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.suspended, id)
+	return nil
+}
+
+func (s *suspendStorage) ListEventWaits(ctx context.Context, workflowID, nodeID, key string) ([]storage.SuspendedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []storage.SuspendedMessage
+	for _, m := range s.suspended {
+		if m.WorkflowID == workflowID && m.NodeID == nodeID && m.WaitKey == key {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *suspendStorage) ResolveEventWait(ctx context.Context, m storage.SuspendedMessage) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.suspended[m.ID]
+	if !ok || old.WaitKey == "" {
+		return false, nil
+	}
+	old.Metadata, old.Data, old.ResumeAt, old.WaitKey = m.Metadata, m.Data, m.ResumeAt, ""
+	s.suspended[m.ID] = old
+	return true, nil
+}
+
+func (s *suspendStorage) CreateLogs(ctx context.Context, logs []storage.Log) error { return nil }
+
+func eventWaitWorkflow() storage.Workflow {
+	return storage.Workflow{
+		ID: "wf-saga",
+		Nodes: []storage.WorkflowNode{
+			{ID: "wait", Type: "wait_for_event", Config: map[string]any{
+				"correlationKey":  "order_id",
+				"eventConditions": `[{"field":"type","operator":"=","value":"payment.completed"}]`,
+				"timeout":         "1h",
+			}},
+			{ID: "paid", Type: "sink"},
+			{ID: "compensate", Type: "sink"},
+		},
+		Edges: []storage.WorkflowEdge{
+			{SourceID: "wait", TargetID: "paid", SourceHandle: "matched"},
+			{SourceID: "wait", TargetID: "compensate", SourceHandle: "timeout"},
+		},
+	}
+}
+
+// runEventWaitWorkflow runs wf on reg with in-memory sinks for its
+// "matched" and "timeout" branches.
+func runEventWaitWorkflow(reg *Registry, wf storage.Workflow) (paid, compensate *pipeSink) {
+	paid, compensate = &pipeSink{name: "paid"}, &pipeSink{name: "compensate"}
+	nodeMap, adj := replayTopology(wf)
+	reg.mu.Lock()
+	reg.engines[wf.ID] = &activeEngine{
+		workflow:        wf,
+		nodeMap:         nodeMap,
+		adj:             adj,
+		sinks:           []hermod.Sink{paid, compensate},
+		sinkNodeToIndex: map[string]int{"paid": 0, "compensate": 1},
+	}
+	reg.mu.Unlock()
+	reg.loadEventWaits(wf.ID, wf)
+	return paid, compensate
+}
+
+func suspendOrder(t *testing.T, reg *Registry, wf storage.Workflow, id string, orderID float64) {
+	t.Helper()
+	m := message.AcquireMessage()
+	defer message.ReleaseMessage(m)
+	m.SetID(id)
+	m.SetData("order_id", orderID)
+	m.SetData("status", "pending")
+	node := &wf.Nodes[0]
+	msgs, branch, err := reg.RunWorkflowNode(wf.ID, node, m)
+	if err != nil || len(msgs) != 0 || branch != "suspended" {
+		t.Fatalf("order %s was not suspended: %v, %q, %v", id, msgs, branch, err)
+	}
+}
+
+func sendEvent(reg *Registry, typ string, orderID float64) {
+	m := message.AcquireMessage()
+	defer message.ReleaseMessage(m)
+	m.SetID("evt-" + typ)
+	m.SetData("type", typ)
+	m.SetData("order_id", orderID)
+	m.SetData("status", "paid")
+	m.SetData("amount", 12.5)
+	reg.matchEventWaits(context.Background(), m)
+}
+
+func TestWaitForEvent(t *testing.T) {
+	store := &suspendStorage{suspended: make(map[string]storage.SuspendedMessage)}
+	reg := NewRegistry(store)
+	defer reg.Close()
+
+	wf := eventWaitWorkflow()
+	paid, compensate := runEventWaitWorkflow(reg, wf)
+
+	suspendOrder(t, reg, wf, "o1", 1)
+	suspendOrder(t, reg, wf, "o2", 2)
+	sendEvent(reg, "payment.completed", 3)
+	sendEvent(reg, "payment.failed", 1)
+	reg.reconcileSuspendedMessages(context.Background())
+	if paid.count() != 0 {
+		t.Fatalf("unmatched events resumed %d messages", paid.count())
+	}
+
+	sendEvent(reg, "payment.completed", 1)
+	reg.reconcileSuspendedMessages(context.Background())
+	got := paid.received()
+	if len(got) != 1 {
+		t.Fatalf("got %d matched messages, want 1", len(got))
+	}
+	data := got[0]
+	meta := got[0]["metadata"].(map[string]string)
+	if data["status"] != "pending" || data["amount"] != 12.5 || meta[eventWaitOutcomeMetadata] != "matched" || meta[eventWaitEventMetadata] != "evt-payment.completed" {
+		t.Fatalf("unexpected merge: %v %v", data, meta)
+	}
+	if _, ok := store.suspended["o1"]; ok {
+		t.Fatal("matched message is still suspended")
+	}
+
+	// The wait survives a restart and then times out.
+	reg.dropEventWaits(wf.ID)
+	store.mu.Lock()
+	sm := store.suspended["o2"]
+	sm.ResumeAt = time.Now().Add(-time.Second)
+	store.suspended["o2"] = sm
+	store.mu.Unlock()
+	reg.loadEventWaits(wf.ID, wf)
+	reg.reconcileSuspendedMessages(context.Background())
+
+	got = compensate.received()
+	if len(got) != 1 || got[0]["metadata"].(map[string]string)[eventWaitOutcomeMetadata] != "timeout" {
+		t.Fatalf("timeout not routed: %v", got)
+	}
+	sendEvent(reg, "payment.completed", 2)
+	reg.reconcileSuspendedMessages(context.Background())
+	if paid.count() != 1 {
+		t.Fatal("an event resumed a message that timed out")
+	}
+}
+
+// An event arriving on a worker that does not run the waiting workflow still
+// resumes the message, on the worker that does.
+func TestWaitForEventAcrossWorkers(t *testing.T) {
+	wf := eventWaitWorkflow()
+	wf.Active = true
+	store := &suspendStorage{suspended: make(map[string]storage.SuspendedMessage), workflows: []storage.Workflow{wf}}
+	owner, other := NewRegistry(store), NewRegistry(store)
+	defer owner.Close()
+	defer other.Close()
+
+	paid, compensate := runEventWaitWorkflow(owner, wf)
+	suspendOrder(t, owner, wf, "o1", 1)
+	suspendOrder(t, owner, wf, "o2", 2)
+
+	// The other worker learns the workflow's waits from storage.
+	other.reconcileSuspendedMessages(context.Background())
+	sendEvent(other, "payment.completed", 1)
+	if paid.count() != 0 {
+		t.Fatal("message resumed before its owner reconciled")
+	}
+	owner.reconcileSuspendedMessages(context.Background())
+	got := paid.received()
+	if len(got) != 1 || got[0]["amount"] != 12.5 || got[0]["metadata"].(map[string]string)[eventWaitOutcomeMetadata] != "matched" {
+		t.Fatalf("event on another worker did not resume the message: %v", got)
+	}
+
+	// A timeout resolved by the owner wins over a later event elsewhere.
+	store.mu.Lock()
+	sm := store.suspended["o2"]
+	sm.ResumeAt = time.Now().Add(-time.Second)
+	store.suspended["o2"] = sm
+	store.mu.Unlock()
+	other.reconcileSuspendedMessages(context.Background())
+	if compensate.count() != 0 {
+		t.Fatal("a worker not running the workflow resumed its message")
+	}
+	owner.reconcileSuspendedMessages(context.Background())
+	sendEvent(other, "payment.completed", 2)
+	owner.reconcileSuspendedMessages(context.Background())
+	if compensate.count() != 1 || paid.count() != 1 {
+		t.Fatalf("got %d timed out and %d matched messages, want 1 and 1", compensate.count(), paid.count())
+	}
+	if len(store.suspended) != 0 {
+		t.Fatalf("messages still suspended: %v", store.suspended)
+	}
+}
@@ -2,8 +2,12 @@ package registry
 
 import (
 	"context"
+	"errors"
+	"maps"
 	"time"
 
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/engine/registry/interfaces"
 	"github.com/user/hermod/internal/storage"
 	"github.com/user/hermod/pkg/comm/message"
 )
@@ -21,6 +25,7 @@ func (r *Registry) startReconciliationLoop(ctx context.Context) {
 		select {
 		case <-ticker.C:
 			r.reconcileSuspendedMessages(ctx)
+			r.tickNodes(ctx)
 		case <-ctx.Done():
 			return
 		}
@@ -31,6 +36,7 @@ func (r *Registry) reconcileSuspendedMessages(ctx context.Context) {
 	if r.storage == nil {
 		return
 	}
+	r.refreshEventWaits(ctx)
 	msgs, err := r.storage.ListSuspendedMessages(ctx, "", time.Now())
 	if err != nil {
 		return
@@ -41,6 +47,53 @@ func (r *Registry) reconcileSuspendedMessages(ctx context.Context) {
 	}
 }
 
+// tickNodes ticks every node of the running workflows whose executor acts on
+// time, and routes what it emits downstream the way the traversal routes its
+// results: on edges labelled with the branch and on unlabelled ones.
+func (r *Registry) tickNodes(ctx context.Context) {
+	r.mu.RLock()
+	engines := make(map[string]*activeEngine, len(r.engines))
+	maps.Copy(engines, r.engines)
+	r.mu.RUnlock()
+
+	ctx = context.WithValue(ctx, hermod.RegistryKey, r)
+	for workflowID, ae := range engines {
+		if !ae.isWorkflow {
+			continue
+		}
+		for nodeID, node := range ae.nodeMap {
+			executor, ok := interfaces.GetNodeExecutor(node.Type)
+			if !ok {
+				continue
+			}
+			ticker, ok := executor.(interfaces.NodeTicker)
+			if !ok {
+				continue
+			}
+			msgs, branch, err := ticker.Tick(ctx, r, workflowID, node)
+			if err != nil {
+				r.logger.Error("Registry: node tick failed", "workflow_id", workflowID, "node_id", nodeID, "error", err)
+				continue
+			}
+			for _, m := range msgs {
+				var errs []error
+				for _, targetID := range ae.adj[nodeID] {
+					if label := ae.edgeLabels[nodeID+":"+targetID]; branch != "" && label != "" && label != branch {
+						continue
+					}
+					if tn := ae.nodeMap[targetID]; tn != nil {
+						errs = append(errs, r.runWorkflowNodeFromReplay(workflowID, tn, m, nodeID, ae.workflow, ae.nodeMap, ae.adj, ae.sinks, ae.sinkNodeToIndex))
+					}
+				}
+				if err := errors.Join(errs...); err != nil {
+					r.logger.Error("Registry: failed to deliver a message emitted by a node tick", "workflow_id", workflowID, "node_id", nodeID, "message_id", m.ID(), "error", err)
+				}
+				message.ReleaseMessage(m)
+			}
+		}
+	}
+}
+
 // claimSuspendedMessage marks a suspended message as in-flight. It returns false
 // if the message is already being resumed by another reconciliation pass.
 func (r *Registry) claimSuspendedMessage(id string) bool {
@@ -76,6 +129,48 @@ func (r *Registry) resumeSuspendedMessage(ctx context.Context, sm storage.Suspen
 	}
 	defer r.releaseSuspendedMessage(sm.ID)
 
+	// A message still waiting for an event times out, unless an event
+	// resolved the wait first on any worker. A resolved wait resumes on the
+	// branch of its outcome.
+	branch := ""
+	if node := ae.nodeMap[sm.NodeID]; node != nil && node.Type == "wait_for_event" {
+		if sm.WaitKey != "" {
+			sm.Metadata = maps.Clone(sm.Metadata)
+			if sm.Metadata == nil {
+				sm.Metadata = make(map[string]string)
+			}
+			sm.Metadata[eventWaitOutcomeMetadata] = "timeout"
+			ok, err := r.storage.ResolveEventWait(ctx, sm)
+			if err != nil {
+				r.logger.Error("Registry: failed to time out a message waiting for an event", "workflow_id", sm.WorkflowID, "message_id", sm.ID, "error", err)
+			}
+			if !ok {
+				return
+			}
+			r.BroadcastLog(sm.WorkflowID, "INFO", "Message waiting at node "+sm.NodeID+" timed out", sm.ID)
+		}
+		branch = sm.Metadata[eventWaitOutcomeMetadata]
+	}
+
+	m := suspendedMessage(sm)
+	if branch == "" {
+		r.BroadcastLog(sm.WorkflowID, "INFO", "Resuming suspended message at node "+sm.NodeID, m.ID())
+	}
+
+	// AE has the needed maps
+	defer message.ReleaseMessage(m)
+	if err := r.resumeFromNode(sm.WorkflowID, sm.NodeID, m, ae.workflow, ae.nodeMap, ae.adj, ae.sinks, ae.sinkNodeToIndex, branch); err != nil {
+		r.logger.Error("Registry: failed to resume suspended message", "workflow_id", sm.WorkflowID, "message_id", sm.ID, "error", err)
+		// Kept, so the next tick resumes it again.
+		return
+	}
+	if r.storage != nil {
+		_ = r.storage.DeleteSuspendedMessage(ctx, sm.ID)
+	}
+}
+
+// suspendedMessage rebuilds a suspended message. The caller releases it.
+func suspendedMessage(sm storage.SuspendedMessage) hermod.Message {
 	m := message.AcquireMessage()
 	m.SetID(sm.ID)
 	m.SetAfter(sm.Payload)
@@ -85,13 +180,5 @@ func (r *Registry) resumeSuspendedMessage(ctx context.Context, sm storage.Suspen
 	for k, v := range sm.Data {
 		m.SetData(k, v)
 	}
-
-	r.BroadcastLog(sm.WorkflowID, "INFO", "Resuming suspended message at node "+sm.NodeID, m.ID())
-
-	// AE has the needed maps
-	r.resumeFromNode(sm.WorkflowID, sm.NodeID, m, ae.workflow, ae.nodeMap, ae.adj, ae.sinks, ae.sinkNodeToIndex, "")
-	if r.storage != nil {
-		_ = r.storage.DeleteSuspendedMessage(ctx, sm.ID)
-	}
-	message.ReleaseMessage(m)
+	return m
 }
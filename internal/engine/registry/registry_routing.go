@@ -200,6 +200,36 @@ func (m *multiSource) Ack(ctx context.Context, msg hermod.Message) error {
 	return nil
 }
 
+// Buffered passes the buffer acknowledgement to the source the message came
+// from, found the same way Ack finds it.
+func (m *multiSource) Buffered(ctx context.Context, msg hermod.Message) {
+	nodeID := msg.Metadata()["_source_node_id"]
+	for _, s := range m.sources {
+		if s.nodeID != nodeID {
+			continue
+		}
+		if ba, ok := s.source.(hermod.BufferAcker); ok {
+			ba.Buffered(ctx, msg)
+		}
+		return
+	}
+}
+
+// Nack passes the negative acknowledgement to the source the message came
+// from, found the same way Ack finds it.
+func (m *multiSource) Nack(ctx context.Context, msg hermod.Message, err error) {
+	nodeID := msg.Metadata()["_source_node_id"]
+	for _, s := range m.sources {
+		if s.nodeID != nodeID {
+			continue
+		}
+		if n, ok := s.source.(hermod.Nacker); ok {
+			n.Nack(ctx, msg, err)
+		}
+		return
+	}
+}
+
 func (m *multiSource) GetState() map[string]string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -251,6 +281,23 @@ func (m *multiSource) Ping(ctx context.Context) error {
 	return nil
 }
 
+// DiscoverColumns describes table with the first source of the workflow
+// that knows it.
+func (m *multiSource) DiscoverColumns(ctx context.Context, table string) ([]hermod.ColumnInfo, error) {
+	err := errors.New("no source supports column discovery")
+	for _, s := range m.sources {
+		d, ok := s.source.(hermod.ColumnDiscoverer)
+		if !ok {
+			continue
+		}
+		var cols []hermod.ColumnInfo
+		if cols, err = d.DiscoverColumns(ctx, table); err == nil {
+			return cols, nil
+		}
+	}
+	return nil, err
+}
+
 func (m *multiSource) Close() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -404,6 +451,20 @@ func (s *statefulSource) StreamSilenceThreshold() time.Duration {
 	return 0
 }
 
+// Buffered forwards the engine's buffer acknowledgement to the wrapped source.
+func (s *statefulSource) Buffered(ctx context.Context, msg hermod.Message) {
+	if ba, ok := s.Source.(hermod.BufferAcker); ok {
+		ba.Buffered(ctx, msg)
+	}
+}
+
+// Nack forwards the engine's negative acknowledgement to the wrapped source.
+func (s *statefulSource) Nack(ctx context.Context, msg hermod.Message, err error) {
+	if n, ok := s.Source.(hermod.Nacker); ok {
+		n.Nack(ctx, msg, err)
+	}
+}
+
 // --- Workflow Node Execution ---
 
 func (r *Registry) RunWorkflowNode(workflowID string, node *storage.WorkflowNode, msg hermod.Message) ([]hermod.Message, string, error) {
@@ -455,12 +516,26 @@ func setValByPath(data map[string]any, path string, val any) {
 	evaluator.SetValByPath(data, path, val)
 }
 
+// evaluateConditions reports whether msg meets conditions. A condition
+// expression that fails is logged and not met.
 func (r *Registry) evaluateConditions(msg hermod.Message, conditions []map[string]any) bool {
-	return evaluator.EvaluateConditions(msg, conditions)
+	ok, err := evaluator.MatchConditions(msg, conditions)
+	if err != nil {
+		evaluator.LogError(r.logger, err)
+		return false
+	}
+	return ok
 }
 
+// evaluateAdvancedExpression evaluates expr against msg. An expression that
+// fails is logged and yields nil.
 func (r *Registry) evaluateAdvancedExpression(msg hermod.Message, expr any) any {
-	return r.evaluator.EvaluateAdvancedExpression(msg, expr)
+	v, err := r.evaluator.EvaluateValue(msg, expr)
+	if err != nil {
+		evaluator.LogError(r.logger, err)
+		return nil
+	}
+	return v
 }
 
 func findNodeByID(nodes []storage.WorkflowNode, id string) *storage.WorkflowNode {
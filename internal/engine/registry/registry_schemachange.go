@@ -0,0 +1,406 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/factory"
+	"github.com/user/hermod/pkg/comm/message"
+	pkgengine "github.com/user/hermod/pkg/engine"
+	"github.com/user/hermod/pkg/infra/schema"
+)
+
+// Schema change policies, set per sink with the "schema_change_policy" config
+// key. They decide which part of a source schema change reaches the sink.
+const (
+	// SchemaChangeAuto applies every change to the sink.
+	SchemaChangeAuto = "auto"
+	// SchemaChangeAdditive applies added columns and leaves dropped and
+	// retyped columns alone.
+	SchemaChangeAdditive = "additive"
+	// SchemaChangePause stops the workflow until an operator restarts it,
+	// which approves the change and applies it in full.
+	SchemaChangePause = "pause"
+	// SchemaChangeNotify applies nothing and notifies. It is the default.
+	SchemaChangeNotify = "notify"
+)
+
+// schemaChangePolicyKey is the sink config key holding its policy.
+const schemaChangePolicyKey = "schema_change_policy"
+
+// schemaChangeSinkTypes are the sink types that can evolve their tables, and
+// so the only ones an applying policy does anything for.
+var schemaChangeSinkTypes = map[string]bool{
+	"postgres": true,
+	"yugabyte": true,
+	"mysql":    true,
+	"mariadb":  true,
+	"mssql":    true,
+}
+
+// ValidateSchemaChangePolicy rejects a sink config whose schema change policy
+// applies changes to a sink type that cannot apply them, which would leave
+// its table behind the source without a word.
+func ValidateSchemaChangePolicy(sinkType string, config map[string]string) error {
+	switch p := strings.ToLower(strings.TrimSpace(config[schemaChangePolicyKey])); p {
+	case SchemaChangeAuto, SchemaChangeAdditive:
+		if !schemaChangeSinkTypes[sinkType] {
+			return fmt.Errorf("%s sinks cannot apply schema changes, so %s %q has no effect; use %q or %q", sinkType, schemaChangePolicyKey, p, SchemaChangeNotify, SchemaChangePause)
+		}
+	}
+	return nil
+}
+
+// pendingSchemaChangesNode is the node state ID under which a workflow keeps
+// the changes it paused for, by registry subject.
+const pendingSchemaChangesNode = "_schema_changes"
+
+// schemaStateNode returns the node state ID under which a workflow keeps the
+// schema state of a sink.
+func schemaStateNode(sinkID string) string {
+	return "_schema_state:" + sinkID
+}
+
+// RecordSchemaState implements hermod.SchemaStateRecorder.
+func (r *Registry) RecordSchemaState(ctx context.Context, workflowID, sinkID string, state []byte) error {
+	if !json.Valid(state) {
+		return fmt.Errorf("schema state of sink %s is not JSON", sinkID)
+	}
+	node := schemaStateNode(sinkID)
+	r.SetNodeState(workflowID+":"+node, json.RawMessage(state))
+	return r.UpdateNodeState(ctx, workflowID, node, json.RawMessage(state))
+}
+
+// restoreSchemaStates hands the sinks of a workflow the schema states their
+// predecessors recorded. A sink whose state cannot be restored starts from its
+// configuration, which is logged.
+func (r *Registry) restoreSchemaStates(workflowID string, sinks []hermod.Sink, snkConfigs []factory.SinkConfig) {
+	for i, snk := range sinks {
+		ss, ok := snk.(hermod.SchemaStateSink)
+		if !ok || i >= len(snkConfigs) {
+			continue
+		}
+		state, ok := r.GetNodeState(workflowID + ":" + schemaStateNode(snkConfigs[i].ID))
+		if !ok || state == nil {
+			continue
+		}
+		// State loaded from storage is decoded JSON, not the bytes recorded.
+		b, err := json.Marshal(state)
+		if err == nil {
+			err = ss.RestoreSchemaState(b)
+		}
+		if err != nil {
+			r.logger.Warn("Registry: failed to restore sink schema state", "workflow_id", workflowID, "sink_id", snkConfigs[i].ID, "error", err)
+		}
+	}
+}
+
+// errSchemaChangePaused rejects the messages read after a workflow paused for
+// a schema change, so none is acknowledged before the operator approves it.
+var errSchemaChangePaused = errors.New("workflow paused for a schema change")
+
+// schemaChangePolicies returns the policy of each sink, by sink index.
+func schemaChangePolicies(snkConfigs []factory.SinkConfig) []string {
+	policies := make([]string, len(snkConfigs))
+	for i, cfg := range snkConfigs {
+		switch p := strings.ToLower(strings.TrimSpace(cfg.Config[schemaChangePolicyKey])); p {
+		case SchemaChangeAuto, SchemaChangeAdditive, SchemaChangePause:
+			policies[i] = p
+		default:
+			policies[i] = SchemaChangeNotify
+		}
+	}
+	return policies
+}
+
+// schemaChangeSubject returns the registry subject of a table of a source.
+func schemaChangeSubject(sourceID string, change hermod.SchemaChange) string {
+	return "cdc." + sourceID + "." + change.QualifiedTable()
+}
+
+// schemaChangeRouter routes the schema change messages of one workflow.
+type schemaChangeRouter struct {
+	r        *Registry
+	id       string
+	policies []string
+	// sinkTables holds the configured table of each sink, by sink index, and
+	// sourceTables the tables each source captures, by source ID.
+	sinkTables   []string
+	sourceTables map[string][]string
+	// paused is set once the workflow stopped for a schema change.
+	paused atomic.Bool
+}
+
+// newSchemaChangeRouter returns the schema change router of a workflow with
+// the given sources and sinks.
+func newSchemaChangeRouter(r *Registry, id string, srcConfigs []factory.SourceConfig, snkConfigs []factory.SinkConfig) *schemaChangeRouter {
+	sc := &schemaChangeRouter{
+		r:            r,
+		id:           id,
+		policies:     schemaChangePolicies(snkConfigs),
+		sinkTables:   make([]string, len(snkConfigs)),
+		sourceTables: make(map[string][]string, len(srcConfigs)),
+	}
+	for i, cfg := range snkConfigs {
+		sc.sinkTables[i] = strings.TrimSpace(cfg.Config["table"])
+	}
+	for _, cfg := range srcConfigs {
+		for _, t := range strings.Split(cfg.Config["tables"], ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				sc.sourceTables[cfg.ID] = append(sc.sourceTables[cfg.ID], t)
+			}
+		}
+	}
+	return sc
+}
+
+// feeds reports whether sink i writes the rows of the table change is about.
+// A sink without a table of its own writes each source table to one of the
+// same name. A sink with one is fed by the source table of that name, or by
+// the only table its source captures; the changes of other tables must not
+// touch its table.
+func (sc *schemaChangeRouter) feeds(i int, sourceID string, change hermod.SchemaChange) bool {
+	if i >= len(sc.sinkTables) || sc.sinkTables[i] == "" {
+		return true
+	}
+	if namesTable(sc.sinkTables[i], change) {
+		return true
+	}
+	tables := sc.sourceTables[sourceID]
+	return len(tables) == 1 && namesTable(tables[0], change)
+}
+
+// namesTable reports whether name, qualified or not, names the table of
+// change.
+func namesTable(name string, change hermod.SchemaChange) bool {
+	if strings.Contains(name, ".") {
+		return strings.EqualFold(name, change.QualifiedTable())
+	}
+	return strings.EqualFold(name, change.Table)
+}
+
+// route registers the table version a schema change message announces and
+// routes the message to each of the given sinks, carrying the part of the
+// change the sink's policy lets through. Sinks the changed table does not
+// feed get none of it. Every sink gets the message, even when nothing of the
+// change is let through, so that it is acknowledged in order with the rows
+// around it.
+func (sc *schemaChangeRouter) route(ctx context.Context, msg hermod.Message, sourceID string, sinks []int) ([]pkgengine.RoutedMessage, error) {
+	change, ok := hermod.SchemaChangeOf(msg)
+	if !ok {
+		return nil, fmt.Errorf("message %s carries no schema change", msg.ID())
+	}
+	change.Subject = schemaChangeSubject(sourceID, change)
+	sc.register(ctx, &change)
+	approved := sc.approved(ctx, &change)
+
+	// A change with unknown previous columns is the first sight of the table:
+	// there is nothing to apply.
+	if change.Previous == nil {
+		change.Previous = change.Columns
+	}
+
+	var withheld []string
+	pause := false
+	routed := make([]pkgengine.RoutedMessage, 0, len(sinks))
+	for _, i := range sinks {
+		permitted := change
+		policy := SchemaChangeNotify
+		if i < len(sc.policies) {
+			policy = sc.policies[i]
+		}
+		switch {
+		case !sc.feeds(i, sourceID, change):
+			permitted.Columns = change.Previous
+			routed = append(routed, pkgengine.RoutedMessage{SinkIndex: i, Message: sc.routedMessage(msg, permitted)})
+			continue
+		case policy == SchemaChangeAuto, policy == SchemaChangePause && approved:
+		case policy == SchemaChangeAdditive:
+			permitted = change.Additive()
+		default:
+			permitted.Columns = change.Previous
+			pause = pause || (policy == SchemaChangePause && !change.Empty())
+		}
+		if !change.Empty() && !slices.Equal(permitted.Columns, change.Columns) {
+			withheld = append(withheld, fmt.Sprintf("sink %d (%s)", i, policy))
+		}
+		routed = append(routed, pkgengine.RoutedMessage{SinkIndex: i, Message: sc.routedMessage(msg, permitted)})
+	}
+
+	if len(withheld) > 0 {
+		sc.notify(ctx, change, withheld, pause)
+	}
+	if pause {
+		for _, rm := range routed {
+			rm.Message.Release()
+		}
+		sc.pause(ctx, change)
+		return nil, errSchemaChangePaused
+	}
+	return routed, nil
+}
+
+// routedMessage returns a copy of the schema change message msg carrying
+// change instead.
+func (sc *schemaChangeRouter) routedMessage(msg hermod.Message, change hermod.SchemaChange) hermod.Message {
+	out := message.AcquireSchemaChange(msg.ID(), change)
+	for k, v := range msg.Metadata() {
+		if k != hermod.SchemaChangeKey {
+			out.SetMetadata(k, v)
+		}
+	}
+	hermod.SetSchemaChange(out, change)
+	return out
+}
+
+// register records the columns of change as a version of its subject and
+// fills in the previous columns from the latest version when the source did
+// not know them. A table whose columns match the latest version is not
+// registered again.
+func (sc *schemaChangeRouter) register(ctx context.Context, change *hermod.SchemaChange) {
+	sr := sc.r.schemaRegistry
+	if sr == nil {
+		return
+	}
+	if latest, err := sr.GetLatestSchema(ctx, change.Subject); err == nil {
+		if cols, err := schema.SchemaColumns(latest.Content); err == nil {
+			if slices.Equal(cols, change.Columns) {
+				change.Version = latest.Version
+				return
+			}
+			if change.Previous == nil {
+				change.Previous = cols
+			}
+		}
+	}
+	content, err := schema.ColumnsSchema(change.Schema, change.Table, change.Columns)
+	if err == nil {
+		change.Version, err = sr.Register(ctx, change.Subject, schema.Avro, content)
+	}
+	if err != nil {
+		sc.r.broadcastLog(sc.id, "WARN", fmt.Sprintf("Failed to register schema of %s: %v", change.QualifiedTable(), err))
+	}
+}
+
+// approved reports whether change is the one the workflow paused for, which
+// restarting the workflow approves, and forgets it if so.
+func (sc *schemaChangeRouter) approved(ctx context.Context, change *hermod.SchemaChange) bool {
+	pending := sc.pending()
+	p, ok := pending[change.Subject]
+	if !ok || !slices.Equal(p.Columns, change.Columns) {
+		return false
+	}
+	// A source that restarted no longer knows the previous columns, and the
+	// registry already holds the new ones, so the change is restored from
+	// the pending record.
+	change.Previous = p.Previous
+	delete(pending, change.Subject)
+	sc.savePending(ctx, pending)
+	return true
+}
+
+// pause records change as pending approval and stops the workflow.
+func (sc *schemaChangeRouter) pause(ctx context.Context, change hermod.SchemaChange) {
+	if !sc.paused.CompareAndSwap(false, true) {
+		return
+	}
+	pending := sc.pending()
+	pending[change.Subject] = change
+	sc.savePending(ctx, pending)
+
+	status := "Paused: schema change of " + change.QualifiedTable()
+	sc.r.broadcastLog(sc.id, "WARN", status+". Restart the workflow to apply it.")
+	// The router runs inside the engine, which StopEngine waits for.
+	go func() {
+		stopCtx := context.Background()
+		_ = sc.r.StopEngine(stopCtx, sc.id)
+		if sc.r.storage != nil {
+			_ = sc.r.storage.UpdateWorkflowStatus(stopCtx, sc.id, status)
+		}
+	}()
+}
+
+// pending returns the changes the workflow paused for, by subject.
+func (sc *schemaChangeRouter) pending() map[string]hermod.SchemaChange {
+	pending := make(map[string]hermod.SchemaChange)
+	if state, ok := sc.r.GetNodeState(sc.id + ":" + pendingSchemaChangesNode); ok && state != nil {
+		// State loaded from storage is decoded JSON, not the map stored.
+		if b, err := json.Marshal(state); err == nil {
+			_ = json.Unmarshal(b, &pending)
+		}
+	}
+	return pending
+}
+
+func (sc *schemaChangeRouter) savePending(ctx context.Context, pending map[string]hermod.SchemaChange) {
+	sc.r.SetNodeState(sc.id+":"+pendingSchemaChangesNode, pending)
+	if err := sc.r.UpdateNodeState(ctx, sc.id, pendingSchemaChangesNode, pending); err != nil {
+		sc.r.logger.Warn("Registry: failed to persist pending schema changes", "workflow_id", sc.id, "error", err)
+	}
+}
+
+// notify reports a change that some sinks did not get in full.
+func (sc *schemaChangeRouter) notify(ctx context.Context, change hermod.SchemaChange, withheld []string, pause bool) {
+	var parts []string
+	for _, d := range []struct {
+		label string
+		cols  []hermod.ColumnInfo
+	}{{"added", change.Added()}, {"dropped", change.Dropped()}, {"retyped", change.Retyped()}} {
+		if len(d.cols) == 0 {
+			continue
+		}
+		names := make([]string, len(d.cols))
+		for i, c := range d.cols {
+			names[i] = c.Name
+		}
+		parts = append(parts, d.label+" "+strings.Join(names, ", "))
+	}
+	text := fmt.Sprintf("Schema of %s changed (%s, version %d) and was not applied in full to %s",
+		change.QualifiedTable(), strings.Join(parts, "; "), change.Version, strings.Join(withheld, ", "))
+	if pause {
+		text += "; the workflow is paused until it is restarted"
+	}
+	sc.r.broadcastLog(sc.id, "WARN", text)
+
+	if sc.r.notificationService == nil || sc.r.storage == nil {
+		return
+	}
+	wf, err := sc.r.storage.GetWorkflow(ctx, sc.id)
+	if err != nil {
+		return
+	}
+	sc.r.notificationService.Notify(ctx, "Schema Change", fmt.Sprintf("Workflow '%s' (ID: %s): %s", wf.Name, wf.ID, text), wf)
+}
+
+// reachableSinks returns, for each entry node, the indexes of the sinks
+// reachable from it, in order.
+func reachableSinks(adj map[string][]string, entryIDs []string, sinkNodeToIndex map[string]int) map[string][]int {
+	out := make(map[string][]int, len(entryIDs))
+	for _, entry := range entryIDs {
+		var sinks []int
+		visited := map[string]bool{entry: true}
+		queue := []string{entry}
+		for len(queue) > 0 {
+			curr := queue[0]
+			queue = queue[1:]
+			if i, ok := sinkNodeToIndex[curr]; ok {
+				sinks = append(sinks, i)
+			}
+			for _, next := range adj[curr] {
+				if !visited[next] {
+					visited[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+		slices.Sort(sinks)
+		out[entry] = sinks
+	}
+	return out
+}
@@ -0,0 +1,277 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/factory"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/testutil"
+	"github.com/user/hermod/pkg/comm/message"
+	pkgengine "github.com/user/hermod/pkg/engine"
+)
+
+// schemaStorage keeps schemas and node states in memory.
+type schemaStorage struct {
+	testutil.BaseMockStorage
+	mu      sync.Mutex
+	schemas map[string][]storage.Schema
+	states  map[string]any
+}
+
+func (s *schemaStorage) CreateSchema(ctx context.Context, sc storage.Schema) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schemas[sc.Name] = append(s.schemas[sc.Name], sc)
+	return nil
+}
+
+func (s *schemaStorage) GetLatestSchema(ctx context.Context, name string) (storage.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v := s.schemas[name]; len(v) > 0 {
+		return v[len(v)-1], nil
+	}
+	return storage.Schema{}, storage.ErrNotFound
+}
+
+func (s *schemaStorage) UpdateNodeState(ctx context.Context, workflowID, nodeID string, state any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[workflowID+":"+nodeID] = state
+	return nil
+}
+
+func (s *schemaStorage) CreateLogs(ctx context.Context, logs []storage.Log) error { return nil }
+
+func schemaChangeMessage(previous, columns []hermod.ColumnInfo) hermod.Message {
+	return message.AcquireSchemaChange("ddl", hermod.SchemaChange{Schema: "public", Table: "users", Previous: previous, Columns: columns})
+}
+
+// routedChanges returns the change each sink got, by sink index.
+func routedChanges(t *testing.T, routed []pkgengine.RoutedMessage) map[int]hermod.SchemaChange {
+	t.Helper()
+	out := make(map[int]hermod.SchemaChange)
+	for _, rm := range routed {
+		c, ok := hermod.SchemaChangeOf(rm.Message)
+		if !ok {
+			t.Fatalf("sink %d got a message without a schema change", rm.SinkIndex)
+		}
+		out[rm.SinkIndex] = c
+		rm.Message.Release()
+	}
+	return out
+}
+
+func TestSchemaChangePolicies(t *testing.T) {
+	policies := schemaChangePolicies([]factory.SinkConfig{
+		{Config: hermod.StringMap{"schema_change_policy": "auto"}},
+		{Config: hermod.StringMap{"schema_change_policy": "Additive"}},
+		{Config: hermod.StringMap{}},
+		{Config: hermod.StringMap{"schema_change_policy": "bogus"}},
+	})
+	if want := []string{SchemaChangeAuto, SchemaChangeAdditive, SchemaChangeNotify, SchemaChangeNotify}; !slices.Equal(policies, want) {
+		t.Fatalf("policies = %v, want %v", policies, want)
+	}
+}
+
+func TestValidateSchemaChangePolicy(t *testing.T) {
+	tests := []struct {
+		sinkType, policy string
+		ok               bool
+	}{
+		{"postgres", "auto", true},
+		{"mysql", "Additive", true},
+		{"mssql", "auto", true},
+		{"kafka", "auto", false},
+		{"sqlite", "additive", false},
+		{"kafka", "pause", true},
+		{"kafka", "", true},
+	}
+	for _, tc := range tests {
+		err := ValidateSchemaChangePolicy(tc.sinkType, map[string]string{"schema_change_policy": tc.policy})
+		if (err == nil) != tc.ok {
+			t.Errorf("%s sink with policy %q: err = %v", tc.sinkType, tc.policy, err)
+		}
+	}
+}
+
+func TestSchemaChangeRouting(t *testing.T) {
+	store := &schemaStorage{schemas: make(map[string][]storage.Schema), states: make(map[string]any)}
+	reg := NewRegistry(store)
+	defer reg.Close()
+	sc := &schemaChangeRouter{r: reg, id: "wf", policies: []string{SchemaChangeAuto, SchemaChangeAdditive, SchemaChangeNotify}}
+	ctx := t.Context()
+
+	v1 := []hermod.ColumnInfo{{Name: "id", Type: "int", IsPK: true}, {Name: "age", Type: "int", IsNullable: true}}
+	v2 := []hermod.ColumnInfo{{Name: "id", Type: "int", IsPK: true}, {Name: "email", Type: "text", IsNullable: true}}
+
+	// The first sight of a table registers it and changes nothing.
+	routed, err := sc.route(ctx, schemaChangeMessage(nil, v1), "src", []int{0, 1, 2})
+	if err != nil {
+		t.Fatalf("route failed: %v", err)
+	}
+	for i, c := range routedChanges(t, routed) {
+		if !c.Empty() || c.Version != 1 || c.Subject != "cdc.src.public.users" {
+			t.Errorf("sink %d got %+v for the baseline", i, c)
+		}
+	}
+
+	// A source that lost track of the previous columns gets them from the
+	// registry.
+	routed, err = sc.route(ctx, schemaChangeMessage(nil, v2), "src", []int{0, 1, 2})
+	if err != nil {
+		t.Fatalf("route failed: %v", err)
+	}
+	got := routedChanges(t, routed)
+	if c := got[0]; !slices.Equal(c.Columns, v2) || len(c.Dropped()) != 1 || c.Version != 2 {
+		t.Errorf("auto sink got %+v", c)
+	}
+	if c := got[1]; len(c.Added()) != 1 || len(c.Dropped()) != 0 {
+		t.Errorf("additive sink got %+v", c)
+	}
+	if c := got[2]; !c.Empty() {
+		t.Errorf("notify sink got %+v", c)
+	}
+
+	// Unchanged columns are not registered again.
+	routed, _ = sc.route(ctx, schemaChangeMessage(v2, v2), "src", []int{0})
+	routedChanges(t, routed)
+	if n := len(store.schemas["cdc.src.public.users"]); n != 2 {
+		t.Errorf("registered %d versions, want 2", n)
+	}
+}
+
+// A sink writing to a table of its own only gets the changes of the source
+// table feeding it, never those of the other tables its source captures.
+func TestSchemaChangeOnlyReachesFedTables(t *testing.T) {
+	store := &schemaStorage{schemas: make(map[string][]storage.Schema), states: make(map[string]any)}
+	reg := NewRegistry(store)
+	defer reg.Close()
+	sc := newSchemaChangeRouter(reg, "wf",
+		[]factory.SourceConfig{
+			{ID: "multi", Config: hermod.StringMap{"tables": "public.users, public.orders"}},
+			{ID: "single", Config: hermod.StringMap{"tables": "public.users"}},
+		},
+		[]factory.SinkConfig{
+			{Config: hermod.StringMap{"schema_change_policy": "auto", "table": "orders"}},
+			{Config: hermod.StringMap{"schema_change_policy": "auto", "table": "Users"}},
+			{Config: hermod.StringMap{"schema_change_policy": "auto"}},
+			{Config: hermod.StringMap{"schema_change_policy": "auto", "table": "users_copy"}},
+		})
+	ctx := t.Context()
+
+	v1 := []hermod.ColumnInfo{{Name: "id", Type: "int", IsPK: true}, {Name: "status", Type: "text", IsNullable: true}}
+	v2 := []hermod.ColumnInfo{{Name: "id", Type: "int", IsPK: true}}
+
+	routed, err := sc.route(ctx, schemaChangeMessage(v1, v2), "multi", []int{0, 1, 2, 3})
+	if err != nil {
+		t.Fatalf("route failed: %v", err)
+	}
+	got := routedChanges(t, routed)
+	if len(got) != 4 {
+		t.Fatalf("every sink must get the message, got %d", len(got))
+	}
+	for i, wantDrop := range []bool{false, true, true, false} {
+		if dropped := len(got[i].Dropped()) == 1; dropped != wantDrop {
+			t.Errorf("sink %d got %+v", i, got[i])
+		}
+	}
+
+	// A source capturing a single table feeds a sink table of any name.
+	routed, err = sc.route(ctx, schemaChangeMessage(v1, v2), "single", []int{3})
+	if err != nil {
+		t.Fatalf("route failed: %v", err)
+	}
+	if c := routedChanges(t, routed)[3]; len(c.Dropped()) != 1 {
+		t.Errorf("sink fed by the only table of its source got %+v", c)
+	}
+}
+
+func TestSchemaChangePause(t *testing.T) {
+	store := &schemaStorage{schemas: make(map[string][]storage.Schema), states: make(map[string]any)}
+	reg := NewRegistry(store)
+	defer reg.Close()
+	policies := []string{SchemaChangePause, SchemaChangeAuto}
+	ctx := t.Context()
+
+	v1 := []hermod.ColumnInfo{{Name: "id", Type: "int", IsPK: true}}
+	v2 := []hermod.ColumnInfo{{Name: "id", Type: "bigint", IsPK: true}}
+
+	sc := &schemaChangeRouter{r: reg, id: "wf", policies: policies}
+	routed, err := sc.route(ctx, schemaChangeMessage(nil, v1), "src", []int{0, 1})
+	if err != nil {
+		t.Fatalf("baseline must not pause: %v", err)
+	}
+	routedChanges(t, routed)
+
+	if _, err := sc.route(ctx, schemaChangeMessage(v1, v2), "src", []int{0, 1}); !errors.Is(err, errSchemaChangePaused) {
+		t.Fatalf("expected the workflow to pause, got %v", err)
+	}
+	if !sc.paused.Load() {
+		t.Fatal("router not marked paused")
+	}
+	if _, ok := store.states["wf:"+pendingSchemaChangesNode]; !ok {
+		t.Fatal("pending change not persisted")
+	}
+
+	// After a restart the source replays the change without its previous
+	// columns; it is approved and applied in full.
+	sc = &schemaChangeRouter{r: reg, id: "wf", policies: policies}
+	routed, err = sc.route(ctx, schemaChangeMessage(nil, v2), "src", []int{0, 1})
+	if err != nil {
+		t.Fatalf("approved change must not pause: %v", err)
+	}
+	for i, c := range routedChanges(t, routed) {
+		if len(c.Retyped()) != 1 {
+			t.Errorf("sink %d got %+v after approval", i, c)
+		}
+	}
+	if len(sc.pending()) != 0 {
+		t.Error("approved change still pending")
+	}
+}
+
+// stateSink keeps its schema state in memory.
+type stateSink struct {
+	mockSink
+	state []byte
+}
+
+func (s *stateSink) SchemaState() ([]byte, error)          { return s.state, nil }
+func (s *stateSink) RestoreSchemaState(state []byte) error { s.state = state; return nil }
+
+// The schema state a sink recorded reaches the sink of the next run, also
+// when it comes back from storage as decoded JSON.
+func TestSchemaStateSurvivesRestart(t *testing.T) {
+	store := &schemaStorage{schemas: make(map[string][]storage.Schema), states: make(map[string]any)}
+	reg := NewRegistry(store)
+	defer reg.Close()
+	ctx := t.Context()
+
+	state := []byte(`{"mappings":[{"source_field":"email"}]}`)
+	if err := reg.RecordSchemaState(ctx, "wf", "snk", state); err != nil {
+		t.Fatalf("RecordSchemaState: %v", err)
+	}
+	if _, ok := store.states["wf:"+schemaStateNode("snk")]; !ok {
+		t.Fatal("schema state not persisted")
+	}
+	if err := reg.RecordSchemaState(ctx, "wf", "snk", []byte("{")); err == nil {
+		t.Fatal("expected invalid state to be refused")
+	}
+
+	var decoded any
+	_ = json.Unmarshal(state, &decoded)
+	reg.SetNodeState("wf:"+schemaStateNode("snk"), decoded)
+	next := &stateSink{}
+	reg.restoreSchemaStates("wf", []hermod.Sink{&mockSink{}, next}, []factory.SinkConfig{{ID: "other"}, {ID: "snk"}})
+	if !json.Valid(next.state) || !strings.Contains(string(next.state), `"email"`) {
+		t.Fatalf("restored state = %s", next.state)
+	}
+}
@@ -148,6 +148,9 @@ func (r *Registry) discoverWorkflowSinks(ctx context.Context, wf storage.Workflo
 				ms.Close()
 				return nil, nil, nil, err
 			}
+			if sa, ok := snk.(hermod.SchemaAwareSink); ok {
+				sa.SetSchemaSources(ms, r.lookupSchema)
+			}
 			sinkNodeToIndex[node.ID] = len(sinks)
 			sinks = append(sinks, snk)
 			snkConfigs = append(snkConfigs, snkCfg)
@@ -168,6 +171,16 @@ func (r *Registry) discoverWorkflowSinks(ctx context.Context, wf storage.Workflo
 	return sinks, snkConfigs, sinkNodeToIndex, nil
 }
 
+// lookupSchema reads the latest version of a subject from the schema
+// registry.
+func (r *Registry) lookupSchema(ctx context.Context, subject string) (string, string, error) {
+	sc, err := r.schemaRegistry.GetLatestSchema(ctx, subject)
+	if err != nil {
+		return "", "", fmt.Errorf("schema %s: %w", subject, err)
+	}
+	return sc.Type, sc.Content, nil
+}
+
 // defaultRingBufferCap is the default in-memory ring buffer capacity. It favors
 // a small footprint suitable for the lightweight, low-memory target and can be
 // raised via HERMOD_BUFFER_RING_CAP for high-throughput deployments.
@@ -262,6 +275,11 @@ func parseSinkEngineConfig(cfg factory.SinkConfig) config.SinkConfig {
 			psc.RetryInterval = d
 		}
 	}
+	if val, ok := cfg.Config["throttle_timeout"]; ok && val != "" {
+		if d, err := parseDuration(val); err == nil {
+			psc.ThrottleTimeout = d
+		}
+	}
 	if val, ok := cfg.Config["batch_size"]; ok && val != "" {
 		if n, err := strconv.Atoi(val); err == nil {
 			psc.BatchSize = n
@@ -374,6 +392,7 @@ func (r *Registry) StartWorkflow(id string, wf storage.Workflow) error {
 	if err != nil {
 		return err
 	}
+	r.restoreSchemaStates(id, sinks, snkConfigs)
 
 	// 3. Create buffer
 	buf := createWorkflowBuffer()
@@ -463,6 +482,10 @@ func (r *Registry) StartWorkflow(id string, wf storage.Workflow) error {
 	}
 
 	eng.SetTraceRecorder(r)
+	if r.storage != nil {
+		eng.SetDeadLetterRecorder(r)
+		eng.SetSchemaStateRecorder(r)
+	}
 
 	adj := make(map[string][]string)
 	for _, edge := range wf.Edges {
@@ -478,7 +501,7 @@ func (r *Registry) StartWorkflow(id string, wf storage.Workflow) error {
 	}
 
 	// Set Workflow Router
-	r.setupWorkflowRouter(eng, id, sourceNodes, nodeMap, adj, nodeIndex, edgeLabels, edgeBreakpoints, inDegree, sinkNodeToIndex)
+	r.setupWorkflowRouter(eng, id, sourceNodes, nodeMap, adj, nodeIndex, edgeLabels, edgeBreakpoints, inDegree, sinkNodeToIndex, newSchemaChangeRouter(r, id, srcConfigs, snkConfigs))
 
 	// Per-source configuration
 	sourceEngineCfg := config.SourceConfig{}
@@ -507,6 +530,9 @@ func (r *Registry) StartWorkflow(id string, wf storage.Workflow) error {
 	eng.SetIDs(id, "multi", sinkIDs)
 	eng.SetSinkTypes(sinkTypes)
 	eng.SetSinkConfigs(pkgSnkConfigs)
+	if wf.LeaseToken > 0 {
+		eng.SetFencingToken(wf.LeaseToken)
+	}
 
 	// Schema validation
 	if wf.Schema != "" && wf.SchemaType != "" {
@@ -538,6 +564,7 @@ func (r *Registry) StartWorkflow(id string, wf storage.Workflow) error {
 		inDegree:        inDegree,
 		sinkNodeToIndex: sinkNodeToIndex,
 	}
+	r.loadEventWaits(id, wf)
 
 	if r.optimizer != nil {
 		r.optimizer.Register(id, eng)
@@ -559,6 +586,7 @@ func (r *Registry) setupWorkflowRouter(
 	edgeBreakpoints map[string]bool,
 	inDegree map[string]int,
 	sinkNodeToIndex map[string]int,
+	schemaChanges *schemaChangeRouter,
 ) {
 	// A message enters at exactly one source, so a node fed by several *source*
 	// nodes must not treat its siblings' edges as co-requisites — it would never
@@ -573,7 +601,17 @@ func (r *Registry) setupWorkflowRouter(
 	}
 	inDegreeByEntry := traversal.ReachableInDegreeByEntry(adj, entryIDs)
 
+	// Schema changes bypass the DAG: they go to every sink the source reaches,
+	// and the router decides which of those the changed table feeds.
+	sinksByEntry := reachableSinks(adj, entryIDs, sinkNodeToIndex)
+
 	eng.SetRouter(func(ctx context.Context, msg hermod.Message) ([]pkgengine.RoutedMessage, error) {
+		// Nothing read after a schema change paused the workflow may be
+		// acknowledged: it is read again once the workflow restarts.
+		if schemaChanges.paused.Load() {
+			return nil, errSchemaChangePaused
+		}
+
 		// Stamp the workflow id onto every message as it enters the workflow.
 		// Downstream trace recording (doApplyTransformation) and PII discovery
 		// stats (recordPIIDiscoveries) read "_hermod_workflow_id" from message
@@ -590,6 +628,17 @@ func (r *Registry) setupWorkflowRouter(
 		// trace always shows "message received" even before any transform runs.
 		r.recordSourceIngestTrace(ctx, id, sourceNodeID, msg)
 
+		if msg.Operation() == hermod.OpSchemaChange {
+			var sourceID string
+			if n, ok := nodeMap[sourceNodeID]; ok {
+				sourceID = n.RefID
+			}
+			return schemaChanges.route(ctx, msg, sourceID, sinksByEntry[sourceNodeID])
+		}
+
+		// Resume messages of any workflow that were waiting for this one.
+		r.matchEventWaits(ctx, msg)
+
 		effectiveInDegree := inDegree
 		if reachable, ok := inDegreeByEntry[sourceNodeID]; ok {
 			effectiveInDegree = reachable
@@ -711,6 +760,12 @@ func (r *Registry) setupWorkflowCallbacks(eng *pkgengine.Engine, id string, wf s
 					if len(perSourceState) == 0 {
 						continue
 					}
+					if token, ok := sourceState[hermod.FencingTokenKey]; ok {
+						if err := r.checkFencingToken(ctx, node.RefID, token); err != nil {
+							return err
+						}
+						perSourceState[hermod.FencingTokenKey] = token
+					}
 					if err := r.storage.UpdateSourceState(ctx, node.RefID, perSourceState); err != nil {
 						r.broadcastLog(id, "ERROR", fmt.Sprintf("Failed to persist source state: %v", err))
 					} else if r.logger != nil {
@@ -743,6 +798,21 @@ func (r *Registry) setupWorkflowCallbacks(eng *pkgengine.Engine, id string, wf s
 	return dbLogger
 }
 
+// checkFencingToken refuses a checkpoint whose fencing token is older than the
+// one the source's stored state was last checkpointed with.
+func (r *Registry) checkFencingToken(ctx context.Context, sourceID, token string) error {
+	src, err := r.storage.GetSource(ctx, sourceID)
+	if err != nil {
+		return nil
+	}
+	stored, _ := strconv.ParseInt(src.State[hermod.FencingTokenKey], 10, 64)
+	ours, _ := strconv.ParseInt(token, 10, 64)
+	if stored > ours {
+		return fmt.Errorf("%w: source %s was checkpointed under token %d, this engine holds %d", hermod.ErrFenced, sourceID, stored, ours)
+	}
+	return nil
+}
+
 // runWorkflowEngine runs the engine in a goroutine and handles cleanup on completion.
 func (r *Registry) runWorkflowEngine(eng *pkgengine.Engine, ctx context.Context, cancel context.CancelFunc, done chan struct{}, id string, wf storage.Workflow, ms *multiSource, sinks []hermod.Sink) {
 	defer func() {
@@ -798,10 +868,23 @@ func (r *Registry) runWorkflowEngine(eng *pkgengine.Engine, ctx context.Context,
 		r.broadcastLog(id, "INFO", "Workflow stopped naturally")
 	}
 
+	// A fenced-off engine lost its lease to another worker, which owns the
+	// workflow's stored state now.
+	if errors.Is(err, hermod.ErrFenced) {
+		r.logger.Warn("Workflow fenced off by a newer lease holder, leaving its state alone", "workflow_id", id, "error", err)
+		return
+	}
+
 	if r.storage != nil {
 		dbCtx := context.Background()
 		if workflow, errGet := r.storage.GetWorkflow(dbCtx, id); errGet == nil {
-			if err != nil {
+			if err != nil && hermod.IsFatalConfig(err) {
+				// Restarting cannot fix a configuration error, so stop
+				// reconciliation from looping on it until the sink is fixed.
+				workflow.Status = "Error: " + err.Error()
+				workflow.Active = false
+				r.logger.Error("Workflow stopped on a sink configuration error, deactivating", "workflow_id", id, "error", err)
+			} else if err != nil {
 				workflow.Status = "Error: " + err.Error()
 				// Keep Active = true so reconciliation restarts it
 				r.logger.Error("Workflow failed, keeping active for reconciliation", "workflow_id", id, "error", err)
@@ -935,6 +1018,7 @@ func (r *Registry) stopEngine(ctx context.Context, id string, updateStorage bool
 	r.mu.Lock()
 	delete(r.engines, id)
 	r.mu.Unlock()
+	r.dropEventWaits(id)
 
 	return nil
 }
@@ -1032,7 +1116,10 @@ func (r *Registry) RebuildWorkflow(ctx context.Context, workflowID string, fromO
 				for _, targetID := range adj[node.ID] {
 					targetNode := nodeMap[targetID]
 					if targetNode != nil {
-						r.runWorkflowNodeFromReplay(workflowID, targetNode, msg, eventStoreNode.ID, wf, nodeMap, adj, sinks, sinkNodeToIndex)
+						if err := r.runWorkflowNodeFromReplay(workflowID, targetNode, msg, eventStoreNode.ID, wf, nodeMap, adj, sinks, sinkNodeToIndex); err != nil {
+							msg.Release()
+							return fmt.Errorf("rebuild stopped at message %s: %w", msg.ID(), err)
+						}
 					}
 				}
 			}
@@ -1042,9 +1129,9 @@ func (r *Registry) RebuildWorkflow(ctx context.Context, workflowID string, fromO
 	return nil
 }
 
-func (r *Registry) runWorkflowNodeFromReplay(workflowID string, node *storage.WorkflowNode, msg hermod.Message, skipNodeID string, wf storage.Workflow, nodeMap map[string]*storage.WorkflowNode, adj map[string][]string, sinks []hermod.Sink, sinkNodeToIndex map[string]int) {
+func (r *Registry) runWorkflowNodeFromReplay(workflowID string, node *storage.WorkflowNode, msg hermod.Message, skipNodeID string, wf storage.Workflow, nodeMap map[string]*storage.WorkflowNode, adj map[string][]string, sinks []hermod.Sink, sinkNodeToIndex map[string]int) error {
 	if node.ID == skipNodeID {
-		return
+		return nil
 	}
 
 	// Clone message to avoid side effects between branches
@@ -1062,18 +1149,18 @@ func (r *Registry) runWorkflowNodeFromReplay(workflowID string, node *storage.Wo
 
 	if err != nil {
 		r.broadcastLog(workflowID, "error", fmt.Sprintf("Node %s error: %v", r.getNodeName(*node), err))
-		return
-	}
-
-	if len(processedMsgs) == 0 {
-		return
+		return fmt.Errorf("node %s: %w", r.getNodeName(*node), err)
 	}
 
+	var errs []error
 	for _, processedMsg := range processedMsgs {
 		if node.Type == "sink" {
 			idx, ok := sinkNodeToIndex[node.ID]
 			if ok && idx < len(sinks) {
-				sinks[idx].Write(context.Background(), processedMsg)
+				if err := sinks[idx].Write(context.Background(), processedMsg); err != nil {
+					r.broadcastLog(workflowID, "error", fmt.Sprintf("Sink %s error: %v", r.getNodeName(*node), err))
+					errs = append(errs, fmt.Errorf("sink %s: %w", r.getNodeName(*node), err))
+				}
 			}
 			continue
 		}
@@ -1098,14 +1185,16 @@ func (r *Registry) runWorkflowNodeFromReplay(workflowID string, node *storage.Wo
 		for _, targetID := range targets {
 			targetNode := nodeMap[targetID]
 			if targetNode != nil {
-				r.runWorkflowNodeFromReplay(workflowID, targetNode, processedMsg, skipNodeID, wf, nodeMap, adj, sinks, sinkNodeToIndex)
+				errs = append(errs, r.runWorkflowNodeFromReplay(workflowID, targetNode, processedMsg, skipNodeID, wf, nodeMap, adj, sinks, sinkNodeToIndex))
 			}
 		}
 	}
+	return errors.Join(errs...)
 }
 
 // resumeFromNode continues traversal starting after startNodeID, forcing a specific branch label if provided.
-func (r *Registry) resumeFromNode(workflowID, startNodeID string, msg hermod.Message, wf storage.Workflow, nodeMap map[string]*storage.WorkflowNode, adj map[string][]string, sinks []hermod.Sink, sinkNodeToIndex map[string]int, branch string) {
+// It returns the errors of every node and sink write downstream, so callers can tell whether the message was delivered.
+func (r *Registry) resumeFromNode(workflowID, startNodeID string, msg hermod.Message, wf storage.Workflow, nodeMap map[string]*storage.WorkflowNode, adj map[string][]string, sinks []hermod.Sink, sinkNodeToIndex map[string]int, branch string) error {
 	var targets []string
 	if branch != "" {
 		for _, edge := range wf.Edges {
@@ -1120,24 +1209,18 @@ func (r *Registry) resumeFromNode(workflowID, startNodeID string, msg hermod.Mes
 	} else {
 		targets = adj[startNodeID]
 	}
+	var errs []error
 	for _, targetID := range targets {
 		if tn := nodeMap[targetID]; tn != nil {
-			r.runWorkflowNodeFromReplay(workflowID, tn, msg, startNodeID, wf, nodeMap, adj, sinks, sinkNodeToIndex)
+			errs = append(errs, r.runWorkflowNodeFromReplay(workflowID, tn, msg, startNodeID, wf, nodeMap, adj, sinks, sinkNodeToIndex))
 		}
 	}
+	return errors.Join(errs...)
 }
 
-// ResumeApproval resumes a halted workflow at an approval node with the specified decision branch ("approved" or "rejected").
-func (r *Registry) ResumeApproval(ctx context.Context, app storage.Approval, branch string) error {
-	if r.storage == nil {
-		return errors.New("registry storage not available")
-	}
-	wf, err := r.storage.GetWorkflow(ctx, app.WorkflowID)
-	if err != nil {
-		return err
-	}
-
-	// Build adjacency and node map
+// replayTopology maps a workflow's nodes by ID and its edges by source, as the
+// replay helpers expect.
+func replayTopology(wf storage.Workflow) (map[string]*storage.WorkflowNode, map[string][]string) {
 	nodeMap := make(map[string]*storage.WorkflowNode)
 	adj := make(map[string][]string)
 	for i := range wf.Nodes {
@@ -1146,8 +1229,12 @@ func (r *Registry) ResumeApproval(ctx context.Context, app storage.Approval, bra
 	for _, e := range wf.Edges {
 		adj[e.SourceID] = append(adj[e.SourceID], e.TargetID)
 	}
+	return nodeMap, adj
+}
 
-	// Build sinks and index mapping
+// openReplaySinks opens a fresh instance of every sink in the workflow and
+// indexes them by sink node ID. The caller closes them.
+func (r *Registry) openReplaySinks(ctx context.Context, wf storage.Workflow) ([]hermod.Sink, map[string]int, error) {
 	var sinks []hermod.Sink
 	sinkNodeToIndex := make(map[string]int)
 	for i := range wf.Nodes {
@@ -1158,7 +1245,7 @@ func (r *Registry) ResumeApproval(ctx context.Context, app storage.Approval, bra
 				for _, s := range sinks {
 					_ = s.Close()
 				}
-				return fmt.Errorf("failed to get sink %s: %w", n.RefID, e)
+				return nil, nil, fmt.Errorf("failed to get sink %s: %w", n.RefID, e)
 			}
 			snkCfg := factory.SinkConfig{ID: dbSnk.ID, Type: dbSnk.Type, Config: dbSnk.Config}
 			s, e := r.createSinkInternal(ctx, snkCfg)
@@ -1166,12 +1253,30 @@ func (r *Registry) ResumeApproval(ctx context.Context, app storage.Approval, bra
 				for _, s2 := range sinks {
 					_ = s2.Close()
 				}
-				return e
+				return nil, nil, e
 			}
 			sinkNodeToIndex[n.ID] = len(sinks)
 			sinks = append(sinks, s)
 		}
 	}
+	return sinks, sinkNodeToIndex, nil
+}
+
+// ResumeApproval resumes a halted workflow at an approval node with the specified decision branch ("approved" or "rejected").
+func (r *Registry) ResumeApproval(ctx context.Context, app storage.Approval, branch string) error {
+	if r.storage == nil {
+		return errors.New("registry storage not available")
+	}
+	wf, err := r.storage.GetWorkflow(ctx, app.WorkflowID)
+	if err != nil {
+		return err
+	}
+
+	nodeMap, adj := replayTopology(wf)
+	sinks, sinkNodeToIndex, err := r.openReplaySinks(ctx, wf)
+	if err != nil {
+		return err
+	}
 	defer func() {
 		for _, s := range sinks {
 			_ = s.Close()
@@ -1197,9 +1302,8 @@ func (r *Registry) ResumeApproval(ctx context.Context, app storage.Approval, bra
 	}
 
 	// Continue traversal from the approval node with forced branch
-	r.resumeFromNode(app.WorkflowID, app.NodeID, m, wf, nodeMap, adj, sinks, sinkNodeToIndex, branch)
-	message.ReleaseMessage(m)
-	return nil
+	defer message.ReleaseMessage(m)
+	return r.resumeFromNode(app.WorkflowID, app.NodeID, m, wf, nodeMap, adj, sinks, sinkNodeToIndex, branch)
 }
 
 // --- Test Workflow ---
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
 	commsource "github.com/user/hermod/pkg/comm/source"
 	enginesource "github.com/user/hermod/pkg/engine/source"
 )
@@ -18,6 +19,7 @@ type fullSource struct {
 	threshold time.Duration
 	ready     error
 	logger    hermod.Logger
+	buffered  []string
 }
 
 func (s *fullSource) Read(ctx context.Context) (hermod.Message, error) {
@@ -34,6 +36,9 @@ func (s *fullSource) LastStreamActivity() time.Time          { return s.lastSeen
 func (s *fullSource) StreamSilenceThreshold() time.Duration  { return s.threshold }
 func (s *fullSource) IsReady(context.Context) error          { return s.ready }
 func (s *fullSource) SetLogger(l hermod.Logger)              { s.logger = l }
+func (s *fullSource) Buffered(_ context.Context, m hermod.Message) {
+	s.buffered = append(s.buffered, m.ID())
+}
 
 // A source is never handed to the engine bare. It is wrapped for metrics, for
 // state persistence, for multiplexing across a workflow's source nodes, and
@@ -133,6 +138,21 @@ func TestSourceWrappersForwardOptionalInterfaces(t *testing.T) {
 					t.Errorf("StreamSilenceThreshold = %v, want %v", got, wantThreshold)
 				}
 			})
+
+			t.Run("BufferAcker", func(t *testing.T) {
+				ba, ok := wrapped.(hermod.BufferAcker)
+				if !ok {
+					t.Fatal("wrapper hides hermod.BufferAcker: a streaming producer never hears that its message was buffered")
+				}
+				m := message.AcquireMessage()
+				defer message.ReleaseMessage(m)
+				m.SetID("m-1")
+				m.SetMetadata("_source_node_id", "n1")
+				ba.Buffered(context.Background(), m)
+				if len(inner.buffered) != 1 || inner.buffered[0] != "m-1" {
+					t.Errorf("Buffered reached the source as %v, want [m-1]", inner.buffered)
+				}
+			})
 		})
 	}
 }
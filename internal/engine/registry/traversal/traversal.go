@@ -16,11 +16,19 @@ import (
 type Registry interface {
 	RunWorkflowNode(workflowID string, node *storage.WorkflowNode, msg hermod.Message) ([]hermod.Message, string, error)
 	IsDebuggerAttached(workflowID string) bool
-	PauseForDebugger(workflowID string, nodeID string, msg hermod.Message)
+	PauseForDebugger(workflowID string, nodeID string, msg hermod.Message) string
 	BroadcastLog(workflowID, level, message, details string)
 	Logger() hermod.Logger
 }
 
+// Outcomes of PauseForDebugger: run the node, pass the message on without
+// running it, or drop the message.
+const (
+	DebugContinue = ""
+	DebugSkip     = "skip"
+	DebugDrop     = "drop"
+)
+
 type WorkflowTraversal struct {
 	Registry        Registry
 	Eng             *pkgengine.Engine
@@ -209,7 +217,19 @@ func (t *WorkflowTraversal) runNode(ctx context.Context, node *storage.WorkflowN
 	}
 
 	if t.Registry.IsDebuggerAttached(t.WorkflowID) {
-		t.Registry.PauseForDebugger(t.WorkflowID, node.ID, msg)
+		switch t.Registry.PauseForDebugger(t.WorkflowID, node.ID, msg) {
+		case DebugDrop:
+			t.Registry.BroadcastLog(t.WorkflowID, "INFO", fmt.Sprintf("Debugger dropped message at node %s", node.ID), msg.ID())
+			return nil, "", nil
+		case DebugSkip:
+			// A skipped sink writes nothing; any other node passes the
+			// message on unchanged.
+			if node.Type == "sink" {
+				return nil, "", nil
+			}
+			msg.Retain()
+			return []hermod.Message{msg}, "", nil
+		}
 	}
 
 	start := time.Now()
@@ -233,6 +253,10 @@ func (t *WorkflowTraversal) handleResults(ctx context.Context, node *storage.Wor
 	}
 
 	targets := t.Adj[node.ID]
+	if len(msgs) > 1 && len(targets) > 0 {
+		t.fanOut(ctx, node, msgs[1:], branch)
+		msgs = msgs[:1]
+	}
 	for _, targetID := range targets {
 		taken := true
 		if branch != "" {
@@ -264,6 +288,26 @@ func (t *WorkflowTraversal) handleResults(ctx context.Context, node *storage.Wor
 	}
 }
 
+// fanOut continues each message after the first that a node emitted (a
+// foreach item, a text chunk, several closed windows) in a traversal of its
+// own, rooted at that node. A node's slot holds one message per traversal, so
+// passing them all through resolveEdge would keep only the first and count
+// the rest as extra arrivals, firing downstream joins early. Sink deliveries
+// from the nested traversals are collected into this one.
+func (t *WorkflowTraversal) fanOut(ctx context.Context, node *storage.WorkflowNode, msgs []hermod.Message, branch string) {
+	inDegree := ReachableInDegree(t.Adj, node.ID)
+	for _, msg := range msgs {
+		sub := Acquire(t.Registry, t.Eng, t.WorkflowID, t.NodeMap, t.Adj, t.NodeIndex, t.EdgeLabels, t.EdgeBreakpoints, inDegree, t.SinkNodeToIndex)
+		sub.handleResults(ctx, node, []hermod.Message{msg}, branch, nil)
+		sub.Wg.Wait()
+
+		t.RoutedMu.Lock()
+		t.Routed = append(t.Routed, sub.Routed...)
+		t.RoutedMu.Unlock()
+		Release(sub)
+	}
+}
+
 func (t *WorkflowTraversal) pruneBranch(ctx context.Context, targetID string) {
 	idx := t.NodeIndex[targetID]
 	newCount := atomic.AddInt32(&t.ResolvedCount[idx], 1)
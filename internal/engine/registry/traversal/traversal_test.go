@@ -26,8 +26,10 @@ func (m *mockRegistry) RunWorkflowNode(workflowID string, node *storage.Workflow
 	}
 	return []hermod.Message{msg}, "", nil
 }
-func (m *mockRegistry) IsDebuggerAttached(workflowID string) bool                             { return false }
-func (m *mockRegistry) PauseForDebugger(workflowID string, nodeID string, msg hermod.Message) {}
+func (m *mockRegistry) IsDebuggerAttached(workflowID string) bool { return false }
+func (m *mockRegistry) PauseForDebugger(workflowID string, nodeID string, msg hermod.Message) string {
+	return traversal.DebugContinue
+}
 func (m *mockRegistry) BroadcastLog(workflowID, level, msg, details string) {
 	m.Logs = append(m.Logs, msg)
 }
@@ -100,3 +102,53 @@ func TestWorkflowTraversal_ConditionalJoinReached(t *testing.T) {
 		t.Errorf("Join node J should have fired even if one branch was skipped")
 	}
 }
+
+func TestWorkflowTraversal_FanOutDeliversEveryMessage(t *testing.T) {
+	reg := &mockRegistry{
+		RunWorkflowNodeFn: func(workflowID string, node *storage.WorkflowNode, msg hermod.Message) ([]hermod.Message, string, error) {
+			if node.Type == "foreach" {
+				out := make([]hermod.Message, 3)
+				for i := range out {
+					out[i] = msg.Clone()
+				}
+				return out, "", nil
+			}
+			msg.Retain()
+			return []hermod.Message{msg}, "", nil
+		},
+	}
+	eng := pkgengine.NewEngine(nil, nil, nil)
+
+	nodeMap := map[string]*storage.WorkflowNode{
+		"S": {ID: "S", Type: "source"},
+		"F": {ID: "F", Type: "foreach"},
+		"A": {ID: "A", Type: "passthrough"},
+		"K": {ID: "K", Type: "sink"},
+	}
+	adj := map[string][]string{
+		"S": {"F"},
+		"F": {"A"},
+		"A": {"K"},
+	}
+	nodeIndex := map[string]int{"S": 0, "F": 1, "A": 2, "K": 3}
+	sinkNodeToIndex := map[string]int{"K": 0}
+
+	msg := message.AcquireMessage()
+	msg.SetID("m1")
+
+	tr := traversal.Acquire(reg, eng, "wf-fanout", nodeMap, adj, nodeIndex, nil, nil, traversal.ReachableInDegree(adj, "S"), sinkNodeToIndex)
+	msg.Retain()
+	tr.CurrentMessages[nodeIndex["S"]] = msg
+
+	tr.Traverse(t.Context(), "S")
+
+	if got := len(tr.Routed); got != 3 {
+		t.Errorf("expected all 3 fanned-out messages to reach the sink, got %d", got)
+	}
+	for _, rm := range tr.Routed {
+		rm.Message.Release()
+	}
+
+	traversal.Release(tr)
+	msg.Release()
+}
@@ -195,6 +195,9 @@ func (a *apiStorage) GetSchema(ctx context.Context, name string, version int) (s
 func (a *apiStorage) GetLatestSchema(ctx context.Context, name string) (storage.Schema, error) {
 	return storage.Schema{}, storage.ErrNotFound
 }
+func (a *apiStorage) ListSchemasByID(ctx context.Context, schemaID int) ([]storage.Schema, error) {
+	return nil, nil
+}
 func (a *apiStorage) CreateSchema(ctx context.Context, schema storage.Schema) error { return nil }
 
 // --- Message tracing ---
@@ -272,6 +275,38 @@ func (a *apiStorage) ListSuspendedMessages(ctx context.Context, workflowID strin
 	return nil, nil
 }
 func (a *apiStorage) DeleteSuspendedMessage(ctx context.Context, id string) error { return nil }
+func (a *apiStorage) ListEventWaits(ctx context.Context, workflowID, nodeID, key string) ([]storage.SuspendedMessage, error) {
+	return nil, nil
+}
+func (a *apiStorage) ResolveEventWait(ctx context.Context, m storage.SuspendedMessage) (bool, error) {
+	return false, nil
+}
+
+// --- Named leases ---
+
+// A remote worker never leads the platform's singleton services.
+func (a *apiStorage) AcquireLease(ctx context.Context, name, ownerID string, ttlSeconds int) (bool, error) {
+	return false, nil
+}
+func (a *apiStorage) ReleaseLease(ctx context.Context, name, ownerID string) error { return nil }
+func (a *apiStorage) GetLease(ctx context.Context, name string) (storage.Lease, error) {
+	return storage.Lease{}, storage.ErrNotFound
+}
+
+// --- Dead letters ---
+
+// CreateDeadLetter is forwarded to the platform by the embedded client.
+func (a *apiStorage) ListDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) ([]storage.DeadLetter, int, error) {
+	return nil, 0, nil
+}
+func (a *apiStorage) GetDeadLetter(ctx context.Context, id string) (storage.DeadLetter, error) {
+	return storage.DeadLetter{}, storage.ErrNotFound
+}
+func (a *apiStorage) UpdateDeadLetter(ctx context.Context, dl storage.DeadLetter) error { return nil }
+func (a *apiStorage) DeleteDeadLetter(ctx context.Context, id string) error             { return nil }
+func (a *apiStorage) PurgeDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) (int, error) {
+	return 0, nil
+}
 func (a *apiStorage) GetDashboardStats(ctx context.Context, vhost string) (storage.DashboardStats, error) {
 	return storage.DashboardStats{}, nil
 }
@@ -1,7 +1,10 @@
 package worker
 
 import (
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/user/hermod/internal/storage"
@@ -65,3 +68,32 @@ func TestAPIStorage_SafeDefaults(t *testing.T) {
 		}
 	})
 }
+
+// A remote worker has no dead letter store, so dead letters go to the
+// platform, and a platform that cannot keep them fails the write.
+func TestAPIStorage_CreateDeadLetterForwardsToPlatform(t *testing.T) {
+	var got storage.DeadLetter
+	status := http.StatusCreated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/dlq" || r.Header.Get("X-Worker-Token") != "token" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	s := NewAPIStorage(NewWorkerAPIClient(srv.URL, "token"))
+	if err := s.CreateDeadLetter(t.Context(), storage.DeadLetter{ID: "dl1", WorkflowID: "wf1"}); err != nil {
+		t.Fatalf("CreateDeadLetter() = %v; want nil", err)
+	}
+	if got.ID != "dl1" || got.WorkflowID != "wf1" {
+		t.Fatalf("expected the dead letter sent to the platform, got %+v", got)
+	}
+
+	// A platform that predates the route.
+	status = http.StatusNotFound
+	if err := s.CreateDeadLetter(t.Context(), storage.DeadLetter{ID: "dl2", WorkflowID: "wf1"}); err == nil {
+		t.Fatal("expected an error when the platform cannot store the dead letter")
+	}
+}
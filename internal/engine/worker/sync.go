@@ -13,6 +13,8 @@ import (
 )
 
 func (w *Worker) sync(ctx context.Context, initial bool) {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
 	workerID := w.getWorkerMetricID()
 	defer w.trackSyncMetrics(time.Now(), workerID)
 
@@ -66,6 +68,7 @@ func (w *Worker) sync(ctx context.Context, initial bool) {
 	}
 
 	srcMap, snkMap := w.loadResourceMaps(ctx)
+	w.setView(wfMap, srcMap, snkMap)
 	w.updateActiveWorkflowMetrics(workflows, workerID, initial)
 
 	sctx := SyncContext{SourceMap: srcMap, SinkMap: snkMap, WorkerID: workerID}
@@ -270,7 +273,15 @@ func (w *Worker) hasConfigChanged(wf storage.Workflow, sctx SyncContext) bool {
 }
 
 func (w *Worker) startWorkflow(ctx context.Context, wf storage.Workflow, workerID string) {
-	w.logger.Info("Worker: starting workflow", "workflow_id", wf.ID)
+	if w.workerGUID != "" {
+		token, ok := w.leaseToken(ctx, wf.ID)
+		if !ok {
+			w.logger.Warn("Worker: lease taken over before start, not starting", "workflow_id", wf.ID)
+			return
+		}
+		wf.LeaseToken = token
+	}
+	w.logger.Info("Worker: starting workflow", "workflow_id", wf.ID, "fencing_token", wf.LeaseToken)
 	err := w.registry.StartWorkflow(wf.ID, wf)
 	if err != nil {
 		w.logger.Error("Worker: failed to start", "workflow_id", wf.ID, "error", err)
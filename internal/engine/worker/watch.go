@@ -0,0 +1,309 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/watch"
+)
+
+// Watcher is implemented by worker storages that can push changes instead of
+// being polled for them. fn is called for every event meant for workerID until
+// ctx is done or the stream drops; since resumes after the last version seen.
+type Watcher interface {
+	Watch(ctx context.Context, workerID, since string, fn func(watch.Event) error) error
+}
+
+// ErrWatchUnsupported is returned by Watch when the platform has no watch
+// endpoint. The worker keeps polling.
+var ErrWatchUnsupported = errors.New("platform does not support watch")
+
+const (
+	watchMinBackoff = time.Second
+	watchMaxBackoff = 30 * time.Second
+	// watchResyncEvery is how many sync intervals a watching worker goes
+	// between full resyncs. Watching only sees writes made through the API
+	// process it is connected to, so the occasional resync picks up the rest.
+	watchResyncEvery = 30
+)
+
+// workflowView is the worker's copy of the workflows, sources and sinks it
+// syncs. A full sync replaces it; watch events patch it.
+type workflowView struct {
+	workflows map[string]storage.Workflow
+	sources   map[string]storage.Source
+	sinks     map[string]storage.Sink
+}
+
+func (w *Worker) setView(workflows map[string]storage.Workflow, sources map[string]storage.Source, sinks map[string]storage.Sink) {
+	w.viewMu.Lock()
+	defer w.viewMu.Unlock()
+	w.view = workflowView{workflows: workflows, sources: sources, sinks: sinks}
+}
+
+// viewSnapshot returns the workflows of the view and a sync context over its
+// sources and sinks.
+func (w *Worker) viewSnapshot() ([]storage.Workflow, SyncContext) {
+	w.viewMu.Lock()
+	defer w.viewMu.Unlock()
+	workflows := make([]storage.Workflow, 0, len(w.view.workflows))
+	for _, wf := range w.view.workflows {
+		workflows = append(workflows, wf)
+	}
+	srcMap := make(map[string]storage.Source, len(w.view.sources))
+	for id, src := range w.view.sources {
+		srcMap[id] = src
+	}
+	snkMap := make(map[string]storage.Sink, len(w.view.sinks))
+	for id, snk := range w.view.sinks {
+		snkMap[id] = snk
+	}
+	return workflows, SyncContext{SourceMap: srcMap, SinkMap: snkMap, WorkerID: w.getWorkerMetricID()}
+}
+
+// IsWatching reports whether the worker is receiving pushed changes rather
+// than polling for them.
+func (w *Worker) IsWatching() bool {
+	return w.watching.Load()
+}
+
+// watchLoop keeps a watch stream open while ctx lasts, reconnecting with
+// backoff and resuming from the last version seen. The worker polls while the
+// stream is down.
+func (w *Worker) watchLoop(ctx context.Context, wt Watcher) {
+	backoff := watchMinBackoff
+	since := ""
+	for {
+		err := wt.Watch(ctx, w.workerGUID, since, func(ev watch.Event) error {
+			if ev.Version != "" {
+				since = ev.Version
+			}
+			backoff = watchMinBackoff
+			w.applyEvent(ctx, ev)
+			return nil
+		})
+		w.watching.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrWatchUnsupported) {
+			w.logger.Info("Worker: platform does not support watch, polling for changes")
+			return
+		}
+		w.logger.Warn("Worker: watch stream dropped, polling until it reconnects", "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+	}
+}
+
+// applyEvent applies one pushed change or command.
+func (w *Worker) applyEvent(ctx context.Context, ev watch.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Error("Worker: applying watch event panicked", "kind", ev.Kind, "id", ev.ID, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	switch ev.Kind {
+	case watch.KindConnected:
+		w.watching.Store(true)
+	case watch.KindResync:
+		// Changes published while listing follow on the stream and are
+		// applied after it.
+		w.sync(ctx, false)
+		w.watching.Store(true)
+	case watch.KindWorkflow:
+		w.applyWorkflowEvent(ctx, ev)
+	case watch.KindSource, watch.KindSink:
+		w.applyResourceEvent(ctx, ev)
+	case watch.KindDrain:
+		w.RequestShutdown(ev.Target)
+		if w.draining.Load() {
+			select {
+			case w.wake <- struct{}{}:
+			default:
+			}
+		}
+	case watch.KindRebuild:
+		if !w.registry.IsEngineRunning(ev.ID) {
+			return
+		}
+		w.logger.Info("Worker: rebuilding workflow on platform request", "workflow_id", ev.ID, "from_offset", ev.FromOffset)
+		go func() {
+			rebuildCtx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
+			defer cancel()
+			if err := w.registry.RebuildWorkflow(rebuildCtx, ev.ID, ev.FromOffset); err != nil {
+				w.logger.Error("Worker: rebuild failed", "workflow_id", ev.ID, "error", err)
+			}
+		}()
+	}
+}
+
+func (w *Worker) applyWorkflowEvent(ctx context.Context, ev watch.Event) {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+
+	if ev.Action == watch.ActionDelete {
+		w.viewMu.Lock()
+		delete(w.view.workflows, ev.ID)
+		w.viewMu.Unlock()
+		if w.registry.IsEngineRunning(ev.ID) {
+			w.logger.Info("Worker: workflow deleted, stopping", "workflow_id", ev.ID)
+			w.stopWorkflow(ctx, ev.ID)
+		}
+		return
+	}
+	if ev.Workflow == nil {
+		return
+	}
+
+	w.viewMu.Lock()
+	if w.view.workflows == nil {
+		w.view.workflows = make(map[string]storage.Workflow)
+	}
+	w.view.workflows[ev.Workflow.ID] = *ev.Workflow
+	w.viewMu.Unlock()
+
+	_, sctx := w.viewSnapshot()
+	w.SyncWorkflow(ctx, *ev.Workflow, sctx)
+}
+
+// applyResourceEvent records a source or sink change and restarts the running
+// workflows whose config it changed.
+func (w *Worker) applyResourceEvent(ctx context.Context, ev watch.Event) {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+
+	w.viewMu.Lock()
+	if w.view.sources == nil {
+		w.view.sources = make(map[string]storage.Source)
+	}
+	if w.view.sinks == nil {
+		w.view.sinks = make(map[string]storage.Sink)
+	}
+	switch {
+	case ev.Action == watch.ActionDelete && ev.Kind == watch.KindSource:
+		delete(w.view.sources, ev.ID)
+	case ev.Action == watch.ActionDelete:
+		delete(w.view.sinks, ev.ID)
+	case ev.Source != nil:
+		w.view.sources[ev.Source.ID] = *ev.Source
+	case ev.Sink != nil:
+		w.view.sinks[ev.Sink.ID] = *ev.Sink
+	}
+	w.viewMu.Unlock()
+
+	if ev.Action == watch.ActionDelete {
+		return
+	}
+	workflows, sctx := w.viewSnapshot()
+	running := workflows[:0]
+	for _, wf := range workflows {
+		if w.registry.IsEngineRunning(wf.ID) {
+			running = append(running, wf)
+		}
+	}
+	w.syncAllWorkflows(ctx, running, sctx)
+}
+
+// reconcileView does the periodic work of a sync without listing storage:
+// it starts assigned workflows that are not running (picking up leases freed
+// by a failed peer), hands off workflows the shard assignment moved away and
+// reports the health of the rest.
+func (w *Worker) reconcileView(ctx context.Context) {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+
+	workflows, sctx := w.viewSnapshot()
+	w.updateActiveWorkflowMetrics(workflows, sctx.WorkerID, false)
+
+	var pending []storage.Workflow
+	for _, wf := range workflows {
+		running := w.registry.IsEngineRunning(wf.ID)
+		switch {
+		case running && !w.isWorkflowAssigned(wf):
+			w.handleUnassignedWorkflow(ctx, wf)
+		case running:
+			w.reportWorkflowHealth(ctx, wf.ID)
+		case wf.Active:
+			pending = append(pending, wf)
+		}
+	}
+	w.syncAllWorkflows(ctx, pending, sctx)
+}
+
+// Watch streams changes from the platform's watch endpoint as server-sent
+// events. It returns ErrWatchUnsupported when the platform has no such
+// endpoint.
+func (c *WorkerAPIClient) Watch(ctx context.Context, workerID, since string, fn func(watch.Event) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/api/workers/"+workerID+"/watch", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if since != "" {
+		req.Header.Set("Last-Event-ID", since)
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Worker-Token", c.Token)
+	}
+
+	// The stream stays open indefinitely, so it cannot share the client's
+	// request timeout.
+	client := &http.Client{Transport: c.HTTPClient.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return ErrWatchUnsupported
+	default:
+		return fmt.Errorf("API error: %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var ev watch.Event
+			if err := json.Unmarshal([]byte(data.String()), &ev); err != nil {
+				return fmt.Errorf("decode watch event: %w", err)
+			}
+			data.Reset()
+			if err := fn(ev); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("watch stream closed")
+}
@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/engine/registry"
+	"github.com/user/hermod/internal/factory"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/watch"
+)
+
+// watchingStorage is a failoverStorage whose changes are pushed from a feed.
+type watchingStorage struct {
+	*failoverStorage
+	feed *watch.Feed
+}
+
+func (s *watchingStorage) Watch(ctx context.Context, workerID, since string, fn func(watch.Event) error) error {
+	return s.feed.Stream(ctx, workerID, since, fn)
+}
+
+func newWatchedWorker(t *testing.T) (*Worker, *registry.Registry, *watchingStorage) {
+	t.Helper()
+	// Start samples the host's real load; admission control is not under test.
+	origCPU, origMem := admissionCPUThreshold, admissionMemThreshold
+	t.Cleanup(func() { admissionCPUThreshold, admissionMemThreshold = origCPU, origMem })
+	admissionCPUThreshold, admissionMemThreshold = 2.0, 2.0
+
+	store := &watchingStorage{
+		failoverStorage: &failoverStorage{
+			workers:   make(map[string]storage.Worker),
+			workflows: make(map[string]storage.Workflow),
+			leases:    make(map[string]string),
+		},
+		feed: watch.NewFeed(16),
+	}
+	reg := registry.NewRegistry(store)
+	reg.SetFactories(
+		func(cfg factory.SourceConfig) (hermod.Source, error) { return &mockSource{}, nil },
+		func(cfg factory.SinkConfig) (hermod.Sink, error) { return &mockSink{}, nil },
+	)
+	w := NewWorker(store, reg)
+	w.SetWorkerConfig(0, 1, "worker-1", "token")
+	// Far longer than the test: anything that happens was pushed.
+	w.SetSyncInterval(time.Hour)
+	return w, reg, store
+}
+
+func watchedWorkflow(id string) storage.Workflow {
+	return storage.Workflow{
+		ID:     id,
+		Active: true,
+		Nodes: []storage.WorkflowNode{
+			{ID: "n1", Type: "source", RefID: "s1"},
+			{ID: "n2", Type: "sink", RefID: "snk1"},
+		},
+		Edges: []storage.WorkflowEdge{{ID: "e1", SourceID: "n1", TargetID: "n2"}},
+	}
+}
+
+func TestWatchedWorkerAppliesPushedChanges(t *testing.T) {
+	w, reg, store := newWatchedWorker(t)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- w.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if !waitCond(5*time.Second, w.IsWatching) {
+		t.Fatal("worker never started watching")
+	}
+
+	wf := watchedWorkflow("wf-pushed")
+	store.mu.Lock()
+	store.workflows[wf.ID] = wf
+	store.mu.Unlock()
+	store.feed.Publish(watch.Event{Kind: watch.KindWorkflow, Action: watch.ActionUpsert, ID: wf.ID, Workflow: &wf})
+
+	if !waitCond(5*time.Second, func() bool { return reg.IsEngineRunning(wf.ID) }) {
+		t.Fatal("pushed workflow was not started")
+	}
+
+	store.mu.Lock()
+	delete(store.workflows, wf.ID)
+	store.mu.Unlock()
+	store.feed.Publish(watch.Event{Kind: watch.KindWorkflow, Action: watch.ActionDelete, ID: wf.ID})
+
+	if !waitCond(5*time.Second, func() bool { return !reg.IsEngineRunning(wf.ID) }) {
+		t.Fatal("deleted workflow was not stopped")
+	}
+}
+
+func TestWatchedWorkerDrainsOnPushedCommand(t *testing.T) {
+	w, _, store := newWatchedWorker(t)
+	done := make(chan error, 1)
+	go func() { done <- w.Start(t.Context()) }()
+
+	if !waitCond(5*time.Second, w.IsWatching) {
+		t.Fatal("worker never started watching")
+	}
+
+	// A drain for another worker is ignored.
+	store.feed.Publish(watch.Event{Kind: watch.KindDrain, Target: "worker-2"})
+	store.feed.Publish(watch.Event{Kind: watch.KindDrain, Target: "worker-1"})
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected a clean drain, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not drain")
+	}
+	if !w.IsDraining() {
+		t.Fatal("expected worker to be draining")
+	}
+}
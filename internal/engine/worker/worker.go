@@ -42,6 +42,11 @@ type Worker struct {
 	draining          atomic.Bool
 	healthChecking    atomic.Bool
 	shutdownFunc      context.CancelFunc
+	syncMu            sync.Mutex // serializes syncs with applied watch events
+	viewMu            sync.Mutex
+	view              workflowView
+	watching          atomic.Bool
+	wake              chan struct{}
 }
 
 // NewWorker creates a new worker.
@@ -62,6 +67,7 @@ func NewWorker(storage WorkerStorage, registry *registry.Registry) *Worker {
 		workerGUID:      "",
 		leaseTTLSeconds: 30,
 		renewCancel:     make(map[string]context.CancelFunc),
+		wake:            make(chan struct{}, 1),
 	}
 }
 
@@ -202,6 +208,19 @@ func (w *Worker) Start(ctx context.Context) (err error) {
 
 	defer w.cleanup(ctx)
 
+	// A storage that can push changes replaces polling while its stream is
+	// up. The stream is closed before cleanup runs.
+	if wt, ok := w.storage.(Watcher); ok {
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		var watchers sync.WaitGroup
+		watchers.Go(func() { w.watchLoop(watchCtx, wt) })
+		defer func() {
+			stopWatch()
+			watchers.Wait()
+		}()
+	}
+	ticks := 0
+
 	// A single panic in a sync cycle is absorbed so a transient fault does not
 	// take the worker down. A persistent one is not: without a limit the loop
 	// re-panics every tick forever, flooding logs and making no progress while
@@ -213,7 +232,11 @@ func (w *Worker) Start(ctx context.Context) (err error) {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-w.wake:
+			w.logger.Info("Worker: graceful shutdown requested by platform; draining and handing off workflows")
+			return nil
 		case <-ticker.C:
+			ticks++
 			shouldExit := false
 			panicked := false
 			var lastPanic any
@@ -225,11 +248,17 @@ func (w *Worker) Start(ctx context.Context) (err error) {
 						w.logger.Error("Worker: sync/health loop panicked", "panic", r, "stack", string(debug.Stack()))
 					}
 				}()
-				if w.pollShutdownRequest(ctx) {
+				// A watching worker is told about a drain over the stream.
+				watching := w.watching.Load()
+				if (watching && w.draining.Load()) || (!watching && w.pollShutdownRequest(ctx)) {
 					w.logger.Info("Worker: graceful shutdown requested by platform; draining and handing off workflows")
 					shouldExit = true
 				} else {
-					w.sync(ctx, false)
+					if watching && ticks%watchResyncEvery != 0 {
+						w.reconcileView(ctx)
+					} else {
+						w.sync(ctx, false)
+					}
 					w.checkHealth(ctx)
 				}
 			}()
@@ -452,11 +481,43 @@ func (w *Worker) renewLeaseOnce(ctx context.Context, workflowID string) leaseRen
 		w.logger.Warn("Worker: lease re-acquire errored, will retry", "workflow_id", workflowID, "error", aerr)
 		return leaseTransientError
 	}
-	if acquired {
-		w.logger.Info("Worker: lease re-acquired in place", "workflow_id", workflowID)
-		return leaseHeld
+	if !acquired {
+		return leaseLost
+	}
+	// A lease that lapsed and was held by another worker in between comes
+	// back with a newer token. The engine still writes with the old one and
+	// would be fenced off, so restart it under the new lease instead.
+	if cur, ok := w.runningConfig(workflowID); ok && cur.LeaseToken > 0 {
+		if token, held := w.leaseToken(ctx, workflowID); !held || token != cur.LeaseToken {
+			w.logger.Warn("Worker: lease changed hands while lapsed", "workflow_id", workflowID, "fencing_token", cur.LeaseToken, "current_token", token)
+			return leaseLost
+		}
+	}
+	w.logger.Info("Worker: lease re-acquired in place", "workflow_id", workflowID)
+	return leaseHeld
+}
+
+func (w *Worker) runningConfig(workflowID string) (storage.Workflow, bool) {
+	if w.registry == nil {
+		return storage.Workflow{}, false
+	}
+	return w.registry.GetWorkflowConfig(workflowID)
+}
+
+// leaseToken reads back the fencing token of the lease this worker holds on
+// a workflow. ok is false when the lease belongs to another worker. When the
+// workflow cannot be read the token is zero and the engine runs unfenced; the
+// renewal loop still stops it if the lease is lost.
+func (w *Worker) leaseToken(ctx context.Context, workflowID string) (token int64, ok bool) {
+	wf, err := w.storage.GetWorkflow(ctx, workflowID)
+	if err != nil {
+		w.logger.Warn("Worker: could not read lease fencing token", "workflow_id", workflowID, "error", err)
+		return 0, true
+	}
+	if wf.OwnerID != "" && wf.OwnerID != w.workerGUID {
+		return 0, false
 	}
-	return leaseLost
+	return wf.LeaseToken, true
 }
 
 // stopEngineForLostLease stops the engine and tears down the renewal loop when
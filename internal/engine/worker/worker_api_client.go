@@ -415,26 +415,64 @@ func (c *WorkerAPIClient) CreateLogs(ctx context.Context, logs []storage.Log) er
 	}
 }
 
-// Lease APIs for the platform-backed client. The platform does not yet expose
-// dedicated lease endpoints; for an API-backed remote worker, assignment is
-// already enforced by matching wf.WorkerID against the worker's GUID (see
-// isWorkflowAssigned). In that model there is no separate lease backend that
-// can be "lost", so acquire/renew report the lease as owned. Returning false
-// here would make leaseRenewalLoop interpret every tick as "lease lost" and
-// repeatedly tear down healthy engines (closing CDC sources), which is the
-// worst possible default. Treating "no lease backend" as "always owned" keeps
-// engines stable while remaining safe because real ownership is enforced by
-// the explicit worker assignment.
+// CreateDeadLetter stores a dead letter on the platform. A remote worker has
+// no dead letter store of its own, and the engine acknowledges a message once
+// its dead letter is recorded, so any failure, including a platform that
+// predates the route, is returned rather than dropping the message.
+func (c *WorkerAPIClient) CreateDeadLetter(ctx context.Context, dl storage.DeadLetter) error {
+	resp, err := c.doRequest(ctx, http.MethodPost, "/api/dlq", dl)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API error recording dead letter: %s", resp.Status)
+	}
+	return nil
+}
+
+// AcquireWorkflowLease takes the workflow's lease through the platform. The
+// fencing token of a lease the worker holds is read back with GetWorkflow.
 func (c *WorkerAPIClient) AcquireWorkflowLease(ctx context.Context, workflowID, ownerID string, ttlSeconds int) (bool, error) {
-	return true, nil
+	lease, err := c.leaseRequest(ctx, http.MethodPost, workflowID, ownerID, ttlSeconds)
+	return lease.Held, err
 }
 
 func (c *WorkerAPIClient) RenewWorkflowLease(ctx context.Context, workflowID, ownerID string, ttlSeconds int) (bool, error) {
-	return true, nil
+	lease, err := c.leaseRequest(ctx, http.MethodPut, workflowID, ownerID, ttlSeconds)
+	return lease.Held, err
 }
 
 func (c *WorkerAPIClient) ReleaseWorkflowLease(ctx context.Context, workflowID, ownerID string) error {
-	return nil
+	_, err := c.leaseRequest(ctx, http.MethodDelete, workflowID, ownerID, 0)
+	return err
+}
+
+// leaseRequest calls one of the platform's lease endpoints. A platform that
+// predates them answers 404; the lease is then reported as held, as it was
+// before the endpoints existed, because reporting it lost would make the
+// renewal loop tear down every healthy engine on each tick. Ownership on such
+// a platform rests on the explicit worker assignment alone.
+func (c *WorkerAPIClient) leaseRequest(ctx context.Context, method, workflowID, ownerID string, ttlSeconds int) (storage.WorkflowLease, error) {
+	req := storage.WorkflowLease{OwnerID: ownerID, TTLSeconds: ttlSeconds}
+	resp, err := c.doRequest(ctx, method, fmt.Sprintf("/api/workflows/%s/lease", workflowID), req)
+	if err != nil {
+		return storage.WorkflowLease{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var lease storage.WorkflowLease
+		err = json.NewDecoder(resp.Body).Decode(&lease)
+		return lease, err
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return storage.WorkflowLease{OwnerID: ownerID, Held: true}, nil
+	default:
+		return storage.WorkflowLease{}, fmt.Errorf("API error: %s", resp.Status)
+	}
 }
 
 func (c *WorkerAPIClient) doRequest(ctx context.Context, method, path string, body any) (*http.Response, error) {
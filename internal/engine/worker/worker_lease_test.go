@@ -3,9 +3,18 @@ package worker
 import (
 	"context"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/storage/pebble"
 	"github.com/user/hermod/internal/testutil"
+	workflowhttp "github.com/user/hermod/internal/workflow/transport/http"
 )
 
 // leaseMockStorage lets each test control the lease method behavior.
@@ -49,8 +58,74 @@ func TestRenewLeaseOnce(t *testing.T) {
 	}
 }
 
-func TestWorkerAPIClient_LeaseAlwaysOwned(t *testing.T) {
-	c := NewWorkerAPIClient("http://localhost:0", "token")
+// TestWorkerAPIClient_LeasePartition runs two API-mode workers against the
+// platform's lease endpoints. Worker A is cut off from the platform until its
+// lease expires and B takes over; A must then find the lease gone and B must
+// hold it under a higher fencing token.
+func TestWorkerAPIClient_LeasePartition(t *testing.T) {
+	st, err := pebble.NewPebbleStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewPebbleStorage: %v", err)
+	}
+	defer st.(io.Closer).Close()
+	ctx := t.Context()
+	if err := st.CreateWorkflow(ctx, storage.Workflow{ID: "wf1", Name: "wf1"}); err != nil {
+		t.Fatalf("CreateWorkflow: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	workflowhttp.NewWorkflowHandler(&handlers.Handler{Storage: st}).RegisterWorkflowRoutes(mux)
+	var partitioned atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if partitioned.Load() && r.Header.Get("X-Worker-Token") == "a" {
+			http.Error(w, "unreachable", http.StatusBadGateway)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	a, b := NewWorkerAPIClient(srv.URL, "a"), NewWorkerAPIClient(srv.URL, "b")
+
+	lease, err := a.leaseRequest(ctx, http.MethodPost, "wf1", "worker-a", 1)
+	if err != nil || !lease.Held || lease.Token != 1 {
+		t.Fatalf("A acquire = (%+v, %v); want held with token 1", lease, err)
+	}
+	if ok, err := b.AcquireWorkflowLease(ctx, "wf1", "worker-b", 1); err != nil || ok {
+		t.Fatalf("B acquired a live lease: (%v, %v)", ok, err)
+	}
+
+	partitioned.Store(true)
+	if _, err := a.RenewWorkflowLease(ctx, "wf1", "worker-a", 1); err == nil {
+		t.Fatal("expected A's renewal to fail while partitioned")
+	}
+	time.Sleep(1100 * time.Millisecond)
+	lease, err = b.leaseRequest(ctx, http.MethodPost, "wf1", "worker-b", 30)
+	if err != nil || !lease.Held || lease.Token != 2 {
+		t.Fatalf("B takeover = (%+v, %v); want held with token 2", lease, err)
+	}
+
+	partitioned.Store(false)
+	if ok, err := a.RenewWorkflowLease(ctx, "wf1", "worker-a", 1); err != nil || ok {
+		t.Errorf("A renew after takeover = (%v, %v); want (false, nil)", ok, err)
+	}
+	if ok, err := a.AcquireWorkflowLease(ctx, "wf1", "worker-a", 1); err != nil || ok {
+		t.Errorf("A acquire after takeover = (%v, %v); want (false, nil)", ok, err)
+	}
+	if err := a.ReleaseWorkflowLease(ctx, "wf1", "worker-a"); err != nil {
+		t.Errorf("A release after takeover: %v", err)
+	}
+	lease, err = b.leaseRequest(ctx, http.MethodPut, "wf1", "worker-b", 30)
+	if err != nil || !lease.Held || lease.Token != 2 {
+		t.Errorf("B renew = (%+v, %v); want held with token 2", lease, err)
+	}
+}
+
+// TestWorkerAPIClient_LeaseOlderPlatform checks that a platform without the
+// lease endpoints leaves ownership to the worker assignment.
+func TestWorkerAPIClient_LeaseOlderPlatform(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	c := NewWorkerAPIClient(srv.URL, "token")
 	ctx := t.Context()
 
 	if ok, err := c.AcquireWorkflowLease(ctx, "wf1", "owner", 30); err != nil || !ok {
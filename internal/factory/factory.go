@@ -3,9 +3,11 @@ package factory
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +15,9 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awscredentials "github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/gsoultan/gsmail"
 	gsmailSmtp "github.com/gsoultan/gsmail/smtp"
 	"github.com/user/hermod"
@@ -30,7 +35,9 @@ import (
 	"github.com/user/hermod/pkg/comm/sink/file"
 	sinkftp "github.com/user/hermod/pkg/comm/sink/ftp"
 	sinkgooglesheets "github.com/user/hermod/pkg/comm/sink/googlesheets"
+	sinkgrpc "github.com/user/hermod/pkg/comm/sink/grpc"
 	sinkhttp "github.com/user/hermod/pkg/comm/sink/http"
+	"github.com/user/hermod/pkg/comm/sink/iceberg"
 	"github.com/user/hermod/pkg/comm/sink/instagram"
 	sinkkafka "github.com/user/hermod/pkg/comm/sink/kafka"
 	"github.com/user/hermod/pkg/comm/sink/kinesis"
@@ -493,13 +500,51 @@ func createSourceBase(cfg SourceConfig) (hermod.Source, error) {
 			}
 		}
 		interval, _ := time.ParseDuration(cfg.Config["poll_interval"])
-		src = sourcehttp.NewHTTPSource(
+		httpSrc := sourcehttp.NewHTTPSource(
 			cfg.Config["url"],
 			cfg.Config["method"],
 			headers,
 			interval,
 			cfg.Config["data_path"],
 		)
+		if strategy := cfg.Config["pagination"]; strategy != "" && strategy != "none" {
+			pageSize, _ := strconv.Atoi(cfg.Config["page_size"])
+			startPage, _ := strconv.Atoi(cfg.Config["start_page"])
+			maxPages, _ := strconv.Atoi(cfg.Config["max_pages"])
+			httpSrc.SetPagination(sourcehttp.Pagination{
+				Strategy:   strategy,
+				Param:      cfg.Config["pagination_param"],
+				Path:       cfg.Config["pagination_path"],
+				LimitParam: cfg.Config["limit_param"],
+				PageSize:   pageSize,
+				StartPage:  startPage,
+				MaxPages:   maxPages,
+			})
+		}
+		if field := cfg.Config["watermark_field"]; field != "" {
+			httpSrc.SetIncremental(sourcehttp.Incremental{
+				Field:   field,
+				Param:   cfg.Config["watermark_param"],
+				Initial: cfg.Config["initial_watermark"],
+			})
+		}
+		if key := cfg.Config["dedup_key"]; key != "" {
+			window, _ := strconv.Atoi(cfg.Config["dedup_window"])
+			httpSrc.SetDedupKey(key, window)
+		}
+		if grant, ok := strings.CutPrefix(cfg.Config["auth_type"], "oauth2_"); ok {
+			if err := httpSrc.SetOAuth2(sourcehttp.OAuth2{
+				Grant:        grant,
+				TokenURL:     cfg.Config["token_url"],
+				ClientID:     cfg.Config["client_id"],
+				ClientSecret: cfg.Config["client_secret"],
+				RefreshToken: cfg.Config["refresh_token"],
+				Scopes:       splitList(cfg.Config["scopes"]),
+			}); err != nil {
+				return nil, err
+			}
+		}
+		src = httpSrc
 	case "googlesheets":
 		pollInterval, _ := time.ParseDuration(cfg.Config["poll_interval"])
 		src = sourcegooglesheets.NewGoogleSheetsSource(
@@ -673,7 +718,32 @@ func createSinkBase(cfg SinkConfig) (hermod.Sink, error) {
 	case "rabbitmq_queue":
 		return sinkrabbitmq.NewRabbitMQQueueSink(BuildConnectionString(cfg.Config, cfg.Type), cfg.Config["queue_name"], fmttr)
 	case "redis":
-		return sinkredis.NewRedisSink(cfg.Config["addr"], cfg.Config["password"], cfg.Config["stream"], fmttr)
+		sink, _ := sinkredis.NewRedisSink(cfg.Config["addr"], cfg.Config["password"], cfg.Config["stream"], fmttr)
+		var fields map[string]string
+		for _, pair := range splitList(cfg.Config["fields"]) {
+			field, column, ok := strings.Cut(pair, ":")
+			if !ok {
+				column = field
+			}
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[strings.TrimSpace(field)] = strings.TrimSpace(column)
+		}
+		err := sink.SetCacheMode(sinkredis.CacheConfig{
+			Mode:          sinkredis.Mode(cfg.Config["mode"]),
+			Key:           cfg.Config["key"],
+			Fields:        fields,
+			Path:          cfg.Config["json_path"],
+			Member:        cfg.Config["member"],
+			Score:         cfg.Config["score"],
+			TTL:           cfg.Config["ttl"],
+			VersionColumn: cfg.Config["version_column"],
+		})
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
 	case "file":
 		return file.NewFileSink(cfg.Config["filename"], fmttr)
 	case "kafka":
@@ -815,15 +885,7 @@ func createSinkBase(cfg SinkConfig) (hermod.Sink, error) {
 			Table:       cfg.Config["table"],
 		}), nil
 	case "elasticsearch":
-		addresses := strings.Split(cfg.Config["addresses"], ",")
-		return elasticsearch.NewElasticsearchSink(
-			addresses,
-			cfg.Config["username"],
-			cfg.Config["password"],
-			cfg.Config["api_key"],
-			cfg.Config["index"],
-			fmttr,
-		)
+		return buildElasticsearchSink(cfg, fmttr)
 	case "pulsar":
 		return pulsar.NewPulsarSink(cfg.Config["url"], cfg.Config["topic"], cfg.Config["token"], fmttr)
 	case "kinesis":
@@ -842,18 +904,9 @@ func createSinkBase(cfg SinkConfig) (hermod.Sink, error) {
 			cfg.Config["content_type"],
 		)
 	case "s3-parquet":
-		parallelizer, _ := strconv.ParseInt(cfg.Config["parallelizer"], 10, 64)
-		return s3parquet.NewS3ParquetSink(
-			context.Background(),
-			cfg.Config["region"],
-			cfg.Config["bucket"],
-			cfg.Config["key_prefix"],
-			cfg.Config["access_key"],
-			cfg.Config["secret_key"],
-			cfg.Config["endpoint"],
-			cfg.Config["schema"],
-			parallelizer,
-		)
+		return buildS3ParquetSink(cfg)
+	case "iceberg":
+		return buildIcebergSink(cfg)
 	case "ftp":
 		// Defaults
 		port, _ := strconv.Atoi(cfg.Config["port"])
@@ -905,7 +958,74 @@ func createSinkBase(cfg SinkConfig) (hermod.Sink, error) {
 				sink.SetCompressor(comp)
 			}
 		}
+		sink.SetMethod(cfg.Config["method"])
+		if m := cfg.Config["method_map"]; m != "" {
+			methods := make(map[hermod.Operation]string)
+			for _, pair := range strings.Split(m, ",") {
+				kv := strings.SplitN(pair, ":", 2)
+				if len(kv) == 2 {
+					methods[hermod.Operation(strings.TrimSpace(kv[0]))] = strings.ToUpper(strings.TrimSpace(kv[1]))
+				}
+			}
+			sink.SetOperationMethods(methods)
+		}
+		sink.SetBodyTemplate(cfg.Config["body_template"])
+		sink.SetResponseField(cfg.Config["response_field"])
+		if codes := splitList(cfg.Config["success_codes"]); len(codes) > 0 {
+			parsed := make([]int, 0, len(codes))
+			for _, c := range codes {
+				n, err := strconv.Atoi(c)
+				if err != nil {
+					return nil, fmt.Errorf("invalid http sink success code %q", c)
+				}
+				parsed = append(parsed, n)
+			}
+			sink.SetSuccessCodes(parsed)
+		}
+		auth, err := buildHTTPSinkAuth(cfg.Config)
+		if err != nil {
+			return nil, err
+		}
+		sink.SetAuth(auth)
 		return sink, nil
+	case "grpc":
+		gc := sinkgrpc.Config{
+			Target:          cfg.Config["target"],
+			ProtoFiles:      splitList(cfg.Config["proto_files"]),
+			ImportPaths:     splitList(cfg.Config["import_paths"]),
+			Method:          cfg.Config["method"],
+			RequestTemplate: cfg.Config["request_template"],
+		}
+		if m := cfg.Config["metadata"]; m != "" {
+			gc.Metadata = make(map[string]string)
+			for _, pair := range strings.Split(m, ",") {
+				kv := strings.SplitN(pair, ":", 2)
+				if len(kv) == 2 {
+					gc.Metadata[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+				}
+			}
+		}
+		if t := cfg.Config["timeout"]; t != "" {
+			d, err := time.ParseDuration(t)
+			if err != nil {
+				return nil, fmt.Errorf("invalid grpc sink timeout %q: %w", t, err)
+			}
+			gc.Timeout = d
+		}
+		if cfg.Config["tls"] == "true" {
+			tlsCfg, err := sinkgrpc.TLSConfig(
+				cfg.Config["ca_cert_pem"],
+				cfg.Config["client_cert_pem"],
+				cfg.Config["client_key_pem"],
+				cfg.Config["server_name"],
+				strings.EqualFold(cfg.Config["insecure_skip_verify"], "true"),
+			)
+			if err != nil {
+				return nil, err
+			}
+			gc.TLS = tlsCfg
+		}
+		return sinkgrpc.NewGrpcSink(gc)
 	case "websocket":
 		headers := make(map[string]string)
 		if h, ok := cfg.Config["headers"]; ok && h != "" {
@@ -1304,3 +1424,189 @@ func buildWSTLSConfig(m map[string]string) (*tls.Config, string) {
 	}
 	return cfg, pin
 }
+
+// splitList splits a comma-separated config value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if t := strings.TrimSpace(p); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// buildHTTPSinkAuth builds the authenticator selected by auth_type, or nil
+// when requests go out unauthenticated.
+func buildHTTPSinkAuth(m map[string]string) (sinkhttp.Authenticator, error) {
+	switch m["auth_type"] {
+	case "", "none":
+		return nil, nil
+	case "basic":
+		return sinkhttp.BasicAuth{Username: m["username"], Password: m["password"]}, nil
+	case "bearer":
+		return sinkhttp.BearerAuth{Token: m["token"]}, nil
+	case "oauth2_client_credentials":
+		if m["token_url"] == "" {
+			return nil, fmt.Errorf("http sink: token_url is required for oauth2")
+		}
+		return sinkhttp.NewOAuth2Auth(m["token_url"], m["client_id"], m["client_secret"], splitList(m["scopes"])), nil
+	case "aws_sigv4":
+		if m["aws_region"] == "" || m["aws_service"] == "" {
+			return nil, fmt.Errorf("http sink: aws_region and aws_service are required for aws_sigv4")
+		}
+		var creds aws.CredentialsProvider
+		if m["aws_access_key_id"] != "" {
+			creds = awscredentials.NewStaticCredentialsProvider(m["aws_access_key_id"], m["aws_secret_access_key"], m["aws_session_token"])
+		} else {
+			awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(m["aws_region"]))
+			if err != nil {
+				return nil, fmt.Errorf("http sink: failed to load aws config: %w", err)
+			}
+			creds = awsCfg.Credentials
+		}
+		return sinkhttp.NewSigV4Auth(m["aws_region"], m["aws_service"], creds), nil
+	case "hmac":
+		if m["hmac_secret"] == "" {
+			return nil, fmt.Errorf("http sink: hmac_secret is required for hmac")
+		}
+		return sinkhttp.HMACAuth{Secret: m["hmac_secret"], Header: m["hmac_header"], TimestampHeader: m["hmac_timestamp_header"]}, nil
+	}
+	return nil, fmt.Errorf("http sink: unsupported auth_type %q", m["auth_type"])
+}
+
+// buildElasticsearchSink creates an Elasticsearch sink and sets how it
+// writes CDC messages.
+func buildElasticsearchSink(cfg SinkConfig, fmttr hermod.Formatter) (hermod.Sink, error) {
+	m := cfg.Config
+	sink, err := elasticsearch.NewElasticsearchSink(strings.Split(m["addresses"], ","), m["username"], m["password"], m["api_key"], m["index"], fmttr)
+	if err != nil {
+		return nil, err
+	}
+	opts := elasticsearch.WriteOptions{
+		Action:       elasticsearch.Action(m["action"]),
+		IDColumns:    splitList(m["id_columns"]),
+		VersionField: m["version_field"],
+		Script:       m["script"],
+		Pipeline:     m["pipeline"],
+		Routing:      m["routing"],
+	}
+	if name := m["index_template"]; name != "" {
+		shards, _ := strconv.Atoi(m["template_shards"])
+		replicas, _ := strconv.Atoi(m["template_replicas"])
+		opts.Template = &elasticsearch.IndexTemplate{
+			Name:            name,
+			Patterns:        splitList(m["index_patterns"]),
+			DataStream:      m["data_stream"] == "true",
+			LifecyclePolicy: m["ilm_policy"],
+			RolloverAlias:   m["rollover_alias"],
+			Shards:          shards,
+			Replicas:        replicas,
+		}
+		if mappings := m["template_mappings"]; mappings != "" {
+			if !json.Valid([]byte(mappings)) {
+				return nil, fmt.Errorf("elasticsearch template_mappings is not valid JSON")
+			}
+			opts.Template.Mappings = json.RawMessage(mappings)
+		}
+	}
+	if err := sink.SetWriteOptions(opts); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// sinkSpoolDir returns the spool_dir of a sink, or a directory of its own
+// under the Hermod config directory, which survives restarts.
+func sinkSpoolDir(cfg SinkConfig, kind string) string {
+	if dir := cfg.Config["spool_dir"]; dir != "" {
+		return dir
+	}
+	return config.GetConfigPath(filepath.Join("spool", kind, cfg.ID))
+}
+
+// buildS3ParquetSink creates an S3 Parquet sink spooling under spool_dir,
+// where rows wait until they are uploaded.
+func buildS3ParquetSink(cfg SinkConfig) (hermod.Sink, error) {
+	parallelizer, _ := strconv.ParseInt(cfg.Config["parallelizer"], 10, 64)
+	targetSize, _ := strconv.ParseInt(cfg.Config["target_file_size"], 10, 64)
+	partSize, _ := strconv.ParseInt(cfg.Config["part_size"], 10, 64)
+	maxAge, _ := time.ParseDuration(cfg.Config["max_file_age"])
+	return s3parquet.NewS3ParquetSink(context.Background(), s3parquet.Config{
+		Region:         cfg.Config["region"],
+		Bucket:         cfg.Config["bucket"],
+		KeyPrefix:      cfg.Config["key_prefix"],
+		AccessKey:      cfg.Config["access_key"],
+		SecretKey:      cfg.Config["secret_key"],
+		Endpoint:       cfg.Config["endpoint"],
+		Schema:         cfg.Config["schema"],
+		Parallelizer:   parallelizer,
+		SchemaSubject:  cfg.Config["schema_subject"],
+		PartitionBy:    splitList(cfg.Config["partition_by"]),
+		TargetFileSize: targetSize,
+		MaxFileAge:     maxAge,
+		PartSize:       partSize,
+		SpoolDir:       sinkSpoolDir(cfg, "s3-parquet"),
+	})
+}
+
+// buildIcebergSink creates an Iceberg sink and the catalog it commits through.
+func buildIcebergSink(cfg SinkConfig) (hermod.Sink, error) {
+	ctx := context.Background()
+	warehouse := cfg.Config["warehouse"]
+	s3Opts := iceberg.S3Options{
+		Region:    cfg.Config["region"],
+		Endpoint:  cfg.Config["endpoint"],
+		AccessKey: cfg.Config["access_key"],
+		SecretKey: cfg.Config["secret_key"],
+	}
+
+	var cat iceberg.Catalog
+	switch catalogType := cfg.Config["catalog"]; catalogType {
+	case "rest":
+		rest, err := iceberg.NewRESTCatalog(iceberg.RESTConfig{
+			URI:        cfg.Config["catalog_uri"],
+			Warehouse:  warehouse,
+			Token:      cfg.Config["token"],
+			Credential: cfg.Config["credential"],
+			TokenURL:   cfg.Config["token_url"],
+			Scope:      cfg.Config["scope"],
+		})
+		if err != nil {
+			return nil, err
+		}
+		cat = rest
+	case "sql", "hadoop", "":
+		if warehouse == "" {
+			return nil, fmt.Errorf("iceberg warehouse is required")
+		}
+		fio, err := iceberg.NewFileIO(ctx, warehouse, s3Opts)
+		if err != nil {
+			return nil, err
+		}
+		if catalogType == "sql" {
+			sqlCat, err := iceberg.OpenSQLCatalog(ctx, cfg.Config["catalog_uri"], cfg.Config["catalog_name"], warehouse, fio)
+			if err != nil {
+				return nil, err
+			}
+			cat = sqlCat
+		} else {
+			cat = iceberg.NewHadoopCatalog(warehouse, fio)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported iceberg catalog: %s", catalogType)
+	}
+
+	targetSize, _ := strconv.ParseInt(cfg.Config["target_file_size"], 10, 64)
+	return iceberg.NewIcebergSink(iceberg.Config{
+		Catalog: cat,
+		Table: iceberg.Identifier{
+			Namespace: strings.Split(cfg.Config["namespace"], "."),
+			Name:      cfg.Config["table"],
+		},
+		KeyColumns:     splitList(cfg.Config["key_columns"]),
+		TargetFileSize: targetSize,
+		SpoolDir:       sinkSpoolDir(cfg, "iceberg"),
+		S3:             s3Opts,
+	})
+}
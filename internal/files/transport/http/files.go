@@ -1,8 +1,6 @@
 package http
 
 import (
-	"net/http"
-
 	"github.com/user/hermod/internal/api/handlers"
 )
 
@@ -14,6 +12,6 @@ func NewFileHandler(h *handlers.Handler) *FileHandler {
 	return &FileHandler{Handler: h}
 }
 
-func (h *FileHandler) RegisterFileRoutes(mux *http.ServeMux) {
+func (h *FileHandler) RegisterFileRoutes(mux handlers.Router) {
 	mux.HandleFunc("POST /api/files/upload", h.UploadFile)
 }
@@ -43,7 +43,7 @@ type FormField struct {
 }
 
 // HandleForm receives form submissions (JSON, x-www-form-urlencoded, or multipart)
-func (h *FormHandler) RegisterFormRoutes(mux *http.ServeMux) {
+func (h *FormHandler) RegisterFormRoutes(mux handlers.Router) {
 	mux.HandleFunc("POST /api/forms/{path...}", h.HandleForm)
 	mux.HandleFunc("GET /api/forms/{path...}", h.HandleForm)
 	// Public generated form page
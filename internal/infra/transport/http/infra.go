@@ -19,6 +19,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/api/openapi"
+	"github.com/user/hermod/internal/api/sdkgen"
 	"github.com/user/hermod/internal/config"
 	"github.com/user/hermod/internal/mesh"
 	"github.com/user/hermod/internal/notification"
@@ -26,6 +28,7 @@ import (
 	storagemongo "github.com/user/hermod/internal/storage/mongodb"
 	pebblestorage "github.com/user/hermod/internal/storage/pebble"
 	sqlstorage "github.com/user/hermod/internal/storage/sql"
+	"github.com/user/hermod/internal/watch"
 	"github.com/user/hermod/pkg/infra/filestorage"
 	"github.com/user/hermod/pkg/infra/state"
 	"github.com/user/hermod/pkg/security/crypto"
@@ -41,8 +44,9 @@ func (h *InfraHandler) HandleLiveness(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("OK"))
 }
 
-func (h *InfraHandler) RegisterInfrastructureRoutes(mux *http.ServeMux) {
+func (h *InfraHandler) RegisterInfrastructureRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/config/status", h.GetConfigStatus)
+	mux.Handle("GET /api/config/leader", h.AdminOnly(h.GetLeaderStatus))
 	mux.HandleFunc("GET /api/config/secrets", h.GetSecretConfig)
 	mux.HandleFunc("PUT /api/config/secrets", h.UpdateSecretConfig)
 	mux.HandleFunc("GET /api/config/state", h.GetStateStoreConfig)
@@ -74,9 +78,10 @@ func (h *InfraHandler) RegisterInfrastructureRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/infra/lineage", h.GetLineage)
 	mux.HandleFunc("POST /api/mesh/clusters", h.RegisterMeshCluster)
 	mux.HandleFunc("/api/audit-logs", h.ListAuditLogs)
+	mux.HandleFunc("POST /api/sdk", h.GenerateSDK)
 }
 
-func (h *InfraHandler) RegisterSchemaRoutes(mux *http.ServeMux) {
+func (h *InfraHandler) RegisterSchemaRoutes(mux handlers.Router) {
 	// Delegated to SchemaHandler
 }
 
@@ -139,10 +144,17 @@ func (h *InfraHandler) GetConfigStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]bool{
-		"configured": configured,
-		"user_setup": userSetup,
-	})
+	_ = json.NewEncoder(w).Encode(openapi.ConfigStatus{Configured: configured, UserSetup: userSetup})
+}
+
+// GetLeaderStatus reports which API replica leads and the jobs it runs.
+func (h *InfraHandler) GetLeaderStatus(w http.ResponseWriter, r *http.Request) {
+	if h.Elector == nil {
+		h.JsonError(w, "this replica takes no part in leader election", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(h.Elector.Status(r.Context()))
 }
 
 func (h *InfraHandler) GetDBConfig(w http.ResponseWriter, r *http.Request) {
@@ -565,6 +577,9 @@ func (h *InfraHandler) SaveDBConfig(w http.ResponseWriter, r *http.Request) {
 			h.Registry.GetLogger().Warn("Failed to initialize new logging storage", "error", err)
 		}
 	}
+	if h.Watch != nil {
+		newStore = watch.NewStorage(newStore, h.Watch)
+	}
 
 	h.StoreMu.Lock()
 	oldStore := h.Storage
@@ -1500,82 +1515,30 @@ func (h *InfraHandler) BootstrapEnterpriseScenario(w http.ResponseWriter, r *htt
 	})
 }
 
+// GenerateSDK renders a typed client for the requested language from the
+// server's own OpenAPI document, so the SDK always matches the running API.
 func (h *InfraHandler) GenerateSDK(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Language string `json:"language"` // "go", "typescript"
+		Language string `json:"language"` // "go", "python", "typescript"
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.JsonError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if h.OpenAPI == nil {
+		h.JsonError(w, "API description is not available", http.StatusServiceUnavailable)
+		return
+	}
 
-	var content string
-	var filename string
-
-	switch strings.ToLower(req.Language) {
-	case "go":
-		filename = "hermod_client.go"
-		content = `package hermod
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"net/http"
-)
-
-type Client struct {
-	BaseURL string
-	Token   string
-}
-
-func NewClient(baseURL, token string) *Client {
-	return &Client{BaseURL: baseURL, Token: token}
-}
-
-func (c *Client) Publish(path string, data any) error {
-	body, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", c.BaseURL+"/api/webhooks/"+path, bytes.NewBuffer(body))
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Content-Type", "application/json")
-	
-	resp, err := http.DefaultClient.Do(req)
+	content, err := sdkgen.NewGenerator().Generate(r.Context(), req.Language, h.OpenAPI.Document())
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("publish failed with status: %d", resp.StatusCode)
-	}
-	return nil
-}
-`
-	case "typescript":
-		filename = "hermod-client.ts"
-		content = "export class HermodClient {\n" +
-			"  constructor(private baseURL: string, private token: string) {}\n\n" +
-			"  async publish(path: string, data: any): Promise<void> {\n" +
-			"    const response = await fetch(`${this.baseURL}/api/webhooks/${path}`, {\n" +
-			"      method: 'POST',\n" +
-			"      headers: {\n" +
-			"        'Authorization': `Bearer ${this.token}`,\n" +
-			"        'Content-Type': 'application/json'\n" +
-			"      },\n" +
-			"      body: JSON.stringify(data)\n" +
-			"    });\n\n" +
-			"    if (!response.ok) {\n" +
-			"      throw new Error(`Publish failed with status: ${response.status}`);\n" +
-			"    }\n" +
-			"  }\n" +
-			"}\n"
-	default:
-		h.JsonError(w, "unsupported language: "+req.Language, http.StatusBadRequest)
+		h.JsonError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Disposition", "attachment; filename="+sdkgen.FileName(req.Language))
 	w.Header().Set("Content-Type", "application/octet-stream")
-	_, _ = w.Write([]byte(content))
+	_, _ = w.Write(content)
 }
 
 func (h *InfraHandler) ListAuditLogs(w http.ResponseWriter, r *http.Request) {
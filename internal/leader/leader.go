@@ -0,0 +1,236 @@
+// Package leader elects one of several API replicas to run the platform's
+// singleton background services, such as the autoscaler and retention purges,
+// which would otherwise run once per replica.
+package leader
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/pkg/engine/telemetry"
+)
+
+// DefaultLeaseName is the lease the platform's replicas compete for.
+const DefaultLeaseName = "platform-leader"
+
+// DefaultTTL is how long, in seconds, a leader that stops renewing keeps the
+// lease before another replica takes over.
+const DefaultTTL = 15
+
+// LeaseStore holds the named lease the election is decided on. Every
+// storage.Storage is one; so is the etcd state store.
+type LeaseStore interface {
+	AcquireLease(ctx context.Context, name, ownerID string, ttlSeconds int) (bool, error)
+	ReleaseLease(ctx context.Context, name, ownerID string) error
+	GetLease(ctx context.Context, name string) (storage.Lease, error)
+}
+
+// Status describes the election as seen by one replica.
+type Status struct {
+	Self     string `json:"self"`
+	Leader   string `json:"leader,omitempty"`
+	IsLeader bool   `json:"is_leader"`
+	// Term counts the leadership changes so far.
+	Term         int64      `json:"term,omitempty"`
+	LastHandover *time.Time `json:"last_handover,omitempty"`
+	Jobs         []string   `json:"jobs,omitempty"`
+}
+
+type job struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// Elector campaigns for a named lease and runs its registered jobs only while
+// it holds it. Losing the lease, or failing to renew it for a full TTL,
+// cancels them.
+type Elector struct {
+	store  LeaseStore
+	name   string
+	id     string
+	ttl    int
+	logger hermod.Logger
+
+	mu          sync.Mutex
+	jobs        []job
+	leading     bool
+	lastRenewed time.Time
+	jobCtx      context.Context
+	stopJobs    context.CancelFunc
+	running     sync.WaitGroup
+}
+
+// NewElector creates an elector that campaigns for the named lease as id.
+func NewElector(store LeaseStore, name, id string) *Elector {
+	return &Elector{
+		store:  store,
+		name:   name,
+		id:     id,
+		ttl:    DefaultTTL,
+		logger: telemetry.NewDefaultLogger(),
+	}
+}
+
+// SetTTL sets the lease TTL in seconds (default DefaultTTL).
+func (e *Elector) SetTTL(seconds int) {
+	if seconds <= 0 {
+		seconds = DefaultTTL
+	}
+	e.ttl = seconds
+}
+
+// SetLogger sets the logger leadership changes are reported to.
+func (e *Elector) SetLogger(l hermod.Logger) {
+	if l != nil {
+		e.logger = l
+	}
+}
+
+// ID returns the identity the elector campaigns as.
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// Register adds a singleton job. run is called when this replica becomes
+// leader and must return once its context is done. A job registered while
+// leading starts right away.
+func (e *Elector) Register(name string, run func(ctx context.Context)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	j := job{name: name, run: run}
+	e.jobs = append(e.jobs, j)
+	if e.leading {
+		e.start(j)
+	}
+}
+
+// IsLeader reports whether this replica currently leads.
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading
+}
+
+// Run campaigns until ctx is done, renewing the lease three times per TTL.
+// On return the jobs have stopped and the lease is released, so another
+// replica takes over without waiting for it to expire.
+func (e *Elector) Run(ctx context.Context) {
+	interval := time.Duration(e.ttl) * time.Second / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	e.campaign(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			e.resign()
+			return
+		case <-ticker.C:
+			e.campaign(ctx)
+		}
+	}
+}
+
+func (e *Elector) campaign(ctx context.Context) {
+	held, err := e.store.AcquireLease(ctx, e.name, e.id, e.ttl)
+	now := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch {
+	case err != nil:
+		// The lease may still be ours; others only take it once it expires.
+		if e.leading && now.Sub(e.lastRenewed) >= time.Duration(e.ttl)*time.Second {
+			e.logger.Warn("Leader: could not renew lease, stepping down", "lease", e.name, "error", err)
+			e.stepDown()
+		} else if !errors.Is(err, context.Canceled) {
+			e.logger.Warn("Leader: lease campaign failed", "lease", e.name, "error", err)
+		}
+	case held:
+		e.lastRenewed = now
+		if !e.leading {
+			e.logger.Info("Leader: elected", "lease", e.name, "id", e.id)
+			e.stepUp()
+		}
+	case e.leading:
+		e.logger.Warn("Leader: lease taken over, stepping down", "lease", e.name)
+		e.stepDown()
+	}
+}
+
+// resign stops the jobs and hands the lease back.
+func (e *Elector) resign() {
+	e.mu.Lock()
+	leading := e.leading
+	if leading {
+		e.stepDown()
+	}
+	e.mu.Unlock()
+	if leading {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := e.store.ReleaseLease(ctx, e.name, e.id); err != nil {
+			e.logger.Warn("Leader: failed to release lease", "lease", e.name, "error", err)
+		}
+	}
+}
+
+// stepUp starts every job. e.mu must be held.
+func (e *Elector) stepUp() {
+	e.leading = true
+	e.jobCtx, e.stopJobs = context.WithCancel(context.Background())
+	for _, j := range e.jobs {
+		e.start(j)
+	}
+}
+
+// stepDown cancels the jobs and waits for them to return. e.mu must be held.
+func (e *Elector) stepDown() {
+	e.leading = false
+	e.stopJobs()
+	e.running.Wait()
+}
+
+func (e *Elector) start(j job) {
+	ctx := e.jobCtx
+	e.running.Go(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				e.logger.Error("Leader: singleton job panicked", "job", j.name, "panic", r, "stack", string(debug.Stack()))
+			}
+		}()
+		j.run(ctx)
+	})
+}
+
+// Status reports the current leader and when leadership last changed hands.
+func (e *Elector) Status(ctx context.Context) Status {
+	e.mu.Lock()
+	st := Status{Self: e.id, IsLeader: e.leading}
+	for _, j := range e.jobs {
+		st.Jobs = append(st.Jobs, j.name)
+	}
+	e.mu.Unlock()
+
+	l, err := e.store.GetLease(ctx, e.name)
+	if err != nil {
+		if st.IsLeader {
+			st.Leader = e.id
+		}
+		return st
+	}
+	if l.OwnerID != "" && l.LeaseUntil.After(time.Now()) {
+		st.Leader = l.OwnerID
+	}
+	st.Term = l.Token
+	if !l.AcquiredAt.IsZero() {
+		handover := l.AcquiredAt
+		st.LastHandover = &handover
+	}
+	return st
+}
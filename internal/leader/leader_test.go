@@ -0,0 +1,133 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/user/hermod/internal/storage"
+	pebblestorage "github.com/user/hermod/internal/storage/pebble"
+)
+
+func newStore(t *testing.T) storage.Storage {
+	t.Helper()
+	s, err := pebblestorage.NewPebbleStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("open pebble: %v", err)
+	}
+	return s
+}
+
+func waitFor(d time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return cond()
+}
+
+// replica runs an elector with one job that counts how many copies of it are
+// running across all replicas.
+type replica struct {
+	e      *Elector
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startReplica(t *testing.T, store LeaseStore, id string, running *atomic.Int32) *replica {
+	t.Helper()
+	e := NewElector(store, DefaultLeaseName, id)
+	e.SetTTL(1)
+	e.Register("job", func(ctx context.Context) {
+		running.Add(1)
+		defer running.Add(-1)
+		<-ctx.Done()
+	})
+	ctx, cancel := context.WithCancel(t.Context())
+	r := &replica{e: e, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		e.Run(ctx)
+	}()
+	t.Cleanup(r.stop)
+	return r
+}
+
+func (r *replica) stop() {
+	r.cancel()
+	<-r.done
+}
+
+func TestOnlyOneReplicaRunsTheJobs(t *testing.T) {
+	store := newStore(t)
+	var running atomic.Int32
+	a := startReplica(t, store, "a", &running)
+	if !waitFor(2*time.Second, a.e.IsLeader) {
+		t.Fatal("first replica was not elected")
+	}
+	b := startReplica(t, store, "b", &running)
+
+	// Several renewals later, b is still a follower.
+	time.Sleep(time.Second)
+	if b.e.IsLeader() || running.Load() != 1 {
+		t.Fatalf("expected exactly one leader running the job, b leading=%v running=%d", b.e.IsLeader(), running.Load())
+	}
+
+	st := b.e.Status(t.Context())
+	if st.Leader != "a" || st.IsLeader || st.Term != 1 || st.LastHandover == nil {
+		t.Fatalf("unexpected status from follower: %+v", st)
+	}
+}
+
+func TestLeaderHandsOverOnShutdown(t *testing.T) {
+	store := newStore(t)
+	var running atomic.Int32
+	a := startReplica(t, store, "a", &running)
+	if !waitFor(2*time.Second, a.e.IsLeader) {
+		t.Fatal("first replica was not elected")
+	}
+	b := startReplica(t, store, "b", &running)
+
+	a.stop()
+	if running.Load() != 0 {
+		t.Fatalf("expected the old leader's job to have stopped, %d running", running.Load())
+	}
+	// The lease was released, so b need not wait out the TTL.
+	if !waitFor(time.Second, b.e.IsLeader) {
+		t.Fatal("follower did not take over after the leader released the lease")
+	}
+	if !waitFor(time.Second, func() bool { return running.Load() == 1 }) {
+		t.Fatalf("expected the new leader to run the job, %d running", running.Load())
+	}
+
+	st := b.e.Status(t.Context())
+	if st.Leader != "b" || !st.IsLeader || st.Term != 2 {
+		t.Fatalf("unexpected status after handover: %+v", st)
+	}
+}
+
+func TestLeaderStepsDownWhenLeaseIsTaken(t *testing.T) {
+	store := newStore(t)
+	var running atomic.Int32
+	a := startReplica(t, store, "a", &running)
+	if !waitFor(2*time.Second, a.e.IsLeader) {
+		t.Fatal("replica was not elected")
+	}
+
+	// Simulate a's lease lapsing (e.g. a long GC pause) and another replica
+	// taking over in the meantime.
+	if err := store.ReleaseLease(t.Context(), DefaultLeaseName, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.AcquireLease(t.Context(), DefaultLeaseName, "other", 60); err != nil || !ok {
+		t.Fatalf("takeover failed: %v %v", ok, err)
+	}
+
+	if !waitFor(2*time.Second, func() bool { return !a.e.IsLeader() && running.Load() == 0 }) {
+		t.Fatal("leader kept running its jobs after losing the lease")
+	}
+}
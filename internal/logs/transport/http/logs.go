@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/user/hermod/internal/api/handlers"
 	"github.com/user/hermod/internal/storage"
 )
 
-func (h *LogHandler) RegisterLogRoutes(mux *http.ServeMux) {
+func (h *LogHandler) RegisterLogRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/logs", h.ListLogs)
 	mux.HandleFunc("POST /api/logs", h.CreateLog)
 	mux.HandleFunc("POST /api/logs/batch", h.CreateLogs)
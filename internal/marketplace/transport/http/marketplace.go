@@ -15,7 +15,7 @@ import (
 	"github.com/user/hermod/internal/storage"
 )
 
-func (h *MarketplaceHandler) RegisterMarketplaceRoutes(mux *http.ServeMux) {
+func (h *MarketplaceHandler) RegisterMarketplaceRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/marketplace/plugins", h.HandleListPlugins)
 	mux.HandleFunc("GET /api/marketplace/plugins/{id}", h.HandleGetPlugin)
 	mux.Handle("POST /api/marketplace/install", h.EditorOnly(h.HandleInstallPlugin))
@@ -0,0 +1,451 @@
+package http
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/pkg/infra/schema"
+)
+
+// confluentPrefix is where the registry answers the REST API of the
+// Confluent Schema Registry, so that Kafka serializers and other tools built
+// for it can use Hermod's registry. Subjects cannot be deleted through it.
+const confluentPrefix = "/api/schema-registry"
+
+const confluentContentType = "application/vnd.schemaregistry.v1+json"
+
+// Error codes of the Confluent API. The HTTP status is the first three digits.
+const (
+	crSubjectNotFound      = 40401
+	crVersionNotFound      = 40402
+	crSchemaNotFound       = 40403
+	crSubjectConfigNotSet  = 40408
+	crInvalidSchema        = 42201
+	crInvalidVersion       = 42202
+	crInvalidCompatibility = 42203
+	crIncompatibleSchema   = 409
+	crStoreError           = 50001
+)
+
+// crSchemaTypes maps the schema types of the Confluent API to Hermod's.
+var crSchemaTypes = map[string]schema.SchemaType{
+	"AVRO":     schema.Avro,
+	"JSON":     schema.JSONSchema,
+	"PROTOBUF": schema.Protobuf,
+}
+
+// crSchemaRequest is the body of the endpoints taking a schema.
+type crSchemaRequest struct {
+	Schema     string            `json:"schema"`
+	SchemaType string            `json:"schemaType,omitempty"`
+	References []json.RawMessage `json:"references,omitempty"`
+}
+
+// crSchema is a registered schema version as the Confluent API shows it.
+type crSchema struct {
+	Subject    string `json:"subject,omitempty"`
+	ID         int    `json:"id,omitempty"`
+	Version    int    `json:"version,omitempty"`
+	SchemaType string `json:"schemaType,omitempty"`
+	Schema     string `json:"schema"`
+}
+
+func (h *SchemaHandler) registerConfluentRoutes(mux handlers.Router) {
+	cr := http.NewServeMux()
+	cr.HandleFunc("GET /subjects", h.crListSubjects)
+	cr.HandleFunc("GET /subjects/{subject}/versions", h.crListVersions)
+	cr.HandleFunc("POST /subjects/{subject}/versions", h.requireEditor(h.crRegister))
+	cr.HandleFunc("GET /subjects/{subject}/versions/{version}", h.crGetVersion)
+	cr.HandleFunc("GET /subjects/{subject}/versions/{version}/schema", h.crGetVersionSchema)
+	cr.HandleFunc("POST /subjects/{subject}", h.crLookup)
+	cr.HandleFunc("GET /schemas/types", h.crSchemaTypes)
+	cr.HandleFunc("GET /schemas/ids/{id}", h.crGetByID)
+	cr.HandleFunc("GET /schemas/ids/{id}/versions", h.crGetVersionsByID)
+	cr.HandleFunc("POST /compatibility/subjects/{subject}/versions", h.crTestCompatibility)
+	cr.HandleFunc("POST /compatibility/subjects/{subject}/versions/{version}", h.crTestCompatibility)
+	cr.HandleFunc("GET /config", h.crGetConfig)
+	cr.HandleFunc("PUT /config", h.requireEditor(h.crSetConfig))
+	cr.HandleFunc("GET /config/{subject}", h.crGetConfig)
+	cr.HandleFunc("PUT /config/{subject}", h.requireEditor(h.crSetConfig))
+	cr.HandleFunc("DELETE /config/{subject}", h.requireEditor(h.crDeleteConfig))
+	cr.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		crError(w, http.StatusNotFound, r.Method+" "+r.URL.Path+" is not supported")
+	})
+
+	handler := http.StripPrefix(confluentPrefix, cr)
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+		mux.Handle(method+" "+confluentPrefix+"/", handler)
+	}
+}
+
+// requireEditor is EditorOnly for a route of the inner mux.
+func (h *SchemaHandler) requireEditor(next http.HandlerFunc) http.HandlerFunc {
+	return h.EditorOnly(next).ServeHTTP
+}
+
+func (h *SchemaHandler) crListSubjects(w http.ResponseWriter, r *http.Request) {
+	latest, err := h.Storage.ListAllSchemas(r.Context())
+	if err != nil {
+		crError(w, crStoreError, err.Error())
+		return
+	}
+	subjects := make([]string, 0, len(latest))
+	for _, sc := range latest {
+		subjects = append(subjects, sc.Name)
+	}
+	slices.Sort(subjects)
+	crJSON(w, subjects)
+}
+
+func (h *SchemaHandler) crListVersions(w http.ResponseWriter, r *http.Request) {
+	versions, ok := h.crVersions(w, r, r.PathValue("subject"))
+	if !ok {
+		return
+	}
+	out := make([]int, len(versions))
+	for i, sc := range versions {
+		out[i] = sc.Version
+	}
+	crJSON(w, out)
+}
+
+func (h *SchemaHandler) crGetVersion(w http.ResponseWriter, r *http.Request) {
+	sc, ok := h.crVersion(w, r)
+	if !ok {
+		return
+	}
+	crJSON(w, crView(sc, true))
+}
+
+// crGetVersionSchema answers with the schema text alone.
+func (h *SchemaHandler) crGetVersionSchema(w http.ResponseWriter, r *http.Request) {
+	sc, ok := h.crVersion(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", confluentContentType)
+	_, _ = w.Write([]byte(sc.Content))
+}
+
+// crRegister registers a schema under a subject. A schema the subject already
+// holds is not registered again; its ID is returned.
+func (h *SchemaHandler) crRegister(w http.ResponseWriter, r *http.Request) {
+	subject := r.PathValue("subject")
+	schemaType, content, ok := crDecodeSchema(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.Storage.ListSchemas(r.Context(), subject)
+	if err != nil {
+		crError(w, crStoreError, err.Error())
+		return
+	}
+	for _, sc := range versions {
+		if sc.Type == string(schemaType) && sc.Content == content {
+			crJSON(w, map[string]int{"id": sc.SchemaID})
+			return
+		}
+	}
+
+	version, err := schema.NewStorageRegistry(h.Storage).Register(r.Context(), subject, schemaType, content)
+	var compatErr *schema.CompatibilityError
+	if errors.As(err, &compatErr) {
+		crError(w, crIncompatibleSchema, compatErr.Error())
+		return
+	}
+	if err != nil {
+		crError(w, crStoreError, err.Error())
+		return
+	}
+	h.RecordAuditLog(r, "INFO", "Registered schema "+subject+" version "+strconv.Itoa(version), "REGISTER", "", "", "",
+		map[string]any{"name": subject, "type": schemaType, "version": version})
+	sc, err := h.Storage.GetSchema(r.Context(), subject, version)
+	if err != nil {
+		crError(w, crStoreError, err.Error())
+		return
+	}
+	crJSON(w, map[string]int{"id": sc.SchemaID})
+}
+
+// crLookup finds the version of a subject holding a schema.
+func (h *SchemaHandler) crLookup(w http.ResponseWriter, r *http.Request) {
+	subject := r.PathValue("subject")
+	schemaType, content, ok := crDecodeSchema(w, r)
+	if !ok {
+		return
+	}
+	versions, ok := h.crVersions(w, r, subject)
+	if !ok {
+		return
+	}
+	for _, sc := range versions {
+		if sc.Type == string(schemaType) && sc.Content == content {
+			crJSON(w, crView(sc, true))
+			return
+		}
+	}
+	crError(w, crSchemaNotFound, "Schema not found")
+}
+
+func (h *SchemaHandler) crSchemaTypes(w http.ResponseWriter, r *http.Request) {
+	types := make([]string, 0, len(crSchemaTypes))
+	for name := range crSchemaTypes {
+		types = append(types, name)
+	}
+	slices.Sort(types)
+	crJSON(w, types)
+}
+
+func (h *SchemaHandler) crGetByID(w http.ResponseWriter, r *http.Request) {
+	matches, ok := h.crFindByID(w, r)
+	if !ok {
+		return
+	}
+	crJSON(w, crView(matches[0], false))
+}
+
+func (h *SchemaHandler) crGetVersionsByID(w http.ResponseWriter, r *http.Request) {
+	matches, ok := h.crFindByID(w, r)
+	if !ok {
+		return
+	}
+	out := make([]map[string]any, len(matches))
+	for i, sc := range matches {
+		out[i] = map[string]any{"subject": sc.Name, "version": sc.Version}
+	}
+	crJSON(w, out)
+}
+
+// crTestCompatibility checks a schema against a subject, or against one
+// version of it. With verbose=true the answer lists what is incompatible.
+func (h *SchemaHandler) crTestCompatibility(w http.ResponseWriter, r *http.Request) {
+	subject := r.PathValue("subject")
+	schemaType, content, ok := crDecodeSchema(w, r)
+	if !ok {
+		return
+	}
+
+	version := 0
+	if r.PathValue("version") != "" {
+		sc, ok := h.crVersion(w, r)
+		if !ok {
+			return
+		}
+		version = sc.Version
+	}
+	messages, err := schema.NewStorageRegistry(h.Storage).TestCompatibility(r.Context(), subject, schemaType, content, version)
+	if err != nil {
+		crError(w, crStoreError, err.Error())
+		return
+	}
+
+	resp := map[string]any{"is_compatible": len(messages) == 0}
+	if verbose, _ := strconv.ParseBool(r.URL.Query().Get("verbose")); verbose {
+		if messages == nil {
+			messages = []string{}
+		}
+		resp["messages"] = messages
+	}
+	crJSON(w, resp)
+}
+
+// crGetConfig returns the global level, or the level of a subject. A subject
+// without one answers 40408 unless defaultToGlobal=true.
+func (h *SchemaHandler) crGetConfig(w http.ResponseWriter, r *http.Request) {
+	subject := r.PathValue("subject")
+	reg := schema.NewStorageRegistry(h.Storage)
+	level, explicit, err := reg.SubjectCompatibility(r.Context(), subject)
+	if err != nil {
+		crError(w, crStoreError, err.Error())
+		return
+	}
+	if !explicit {
+		if defaultToGlobal, _ := strconv.ParseBool(r.URL.Query().Get("defaultToGlobal")); subject != "" && !defaultToGlobal {
+			crError(w, crSubjectConfigNotSet, "Subject '"+subject+"' does not have subject-level compatibility configured")
+			return
+		}
+		if level, err = reg.GetCompatibility(r.Context(), ""); err != nil {
+			crError(w, crStoreError, err.Error())
+			return
+		}
+	}
+	crJSON(w, map[string]any{"compatibilityLevel": level})
+}
+
+func (h *SchemaHandler) crSetConfig(w http.ResponseWriter, r *http.Request) {
+	subject := r.PathValue("subject")
+	var req struct {
+		Compatibility string `json:"compatibility"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		crError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	level, err := schema.ParseCompatibilityLevel(req.Compatibility)
+	if err != nil {
+		crError(w, crInvalidCompatibility, "Invalid compatibility level")
+		return
+	}
+	if err := schema.NewStorageRegistry(h.Storage).SetCompatibility(r.Context(), subject, level); err != nil {
+		crError(w, crStoreError, err.Error())
+		return
+	}
+	h.RecordAuditLog(r, "INFO", "Set compatibility level of "+crConfigTarget(subject)+" to "+string(level), "UPDATE", "", "", "", req)
+	crJSON(w, map[string]any{"compatibility": level})
+}
+
+// crDeleteConfig makes a subject follow the global level again and returns
+// the level it had.
+func (h *SchemaHandler) crDeleteConfig(w http.ResponseWriter, r *http.Request) {
+	subject := r.PathValue("subject")
+	reg := schema.NewStorageRegistry(h.Storage)
+	level, explicit, err := reg.SubjectCompatibility(r.Context(), subject)
+	if err != nil {
+		crError(w, crStoreError, err.Error())
+		return
+	}
+	if !explicit {
+		crError(w, crSubjectConfigNotSet, "Subject '"+subject+"' does not have subject-level compatibility configured")
+		return
+	}
+	if err := reg.SetCompatibility(r.Context(), subject, ""); err != nil {
+		crError(w, crStoreError, err.Error())
+		return
+	}
+	h.RecordAuditLog(r, "INFO", "Cleared compatibility level of "+crConfigTarget(subject), "DELETE", "", "", "", nil)
+	crJSON(w, map[string]any{"compatibilityLevel": level})
+}
+
+// crVersions returns the versions of a subject in ascending order, answering
+// 40401 when it has none.
+func (h *SchemaHandler) crVersions(w http.ResponseWriter, r *http.Request, subject string) ([]storage.Schema, bool) {
+	versions, err := h.Storage.ListSchemas(r.Context(), subject)
+	if err != nil {
+		crError(w, crStoreError, err.Error())
+		return nil, false
+	}
+	if len(versions) == 0 {
+		crError(w, crSubjectNotFound, "Subject '"+subject+"' not found.")
+		return nil, false
+	}
+	slices.SortFunc(versions, func(a, b storage.Schema) int { return a.Version - b.Version })
+	return versions, true
+}
+
+// crVersion returns the version the subject and version path values name.
+// "latest" and -1 name the latest version.
+func (h *SchemaHandler) crVersion(w http.ResponseWriter, r *http.Request) (storage.Schema, bool) {
+	raw := r.PathValue("version")
+	version, err := strconv.Atoi(raw)
+	if raw == "latest" {
+		version, err = -1, nil
+	}
+	if err != nil || version == 0 || version < -1 {
+		crError(w, crInvalidVersion, "The specified version '"+raw+"' is not a valid version id.")
+		return storage.Schema{}, false
+	}
+	versions, ok := h.crVersions(w, r, r.PathValue("subject"))
+	if !ok {
+		return storage.Schema{}, false
+	}
+	if version == -1 {
+		return versions[len(versions)-1], true
+	}
+	for _, sc := range versions {
+		if sc.Version == version {
+			return sc, true
+		}
+	}
+	crError(w, crVersionNotFound, "Version "+raw+" not found.")
+	return storage.Schema{}, false
+}
+
+// crFindByID returns every subject version holding the schema an ID names,
+// ordered by subject and version.
+func (h *SchemaHandler) crFindByID(w http.ResponseWriter, r *http.Request) ([]storage.Schema, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		crError(w, crSchemaNotFound, "Schema "+r.PathValue("id")+" not found")
+		return nil, false
+	}
+	matches, err := h.Storage.ListSchemasByID(r.Context(), id)
+	if err != nil {
+		crError(w, crStoreError, err.Error())
+		return nil, false
+	}
+	if len(matches) == 0 {
+		crError(w, crSchemaNotFound, "Schema "+strconv.Itoa(id)+" not found")
+		return nil, false
+	}
+	return matches, true
+}
+
+// crDecodeSchema reads a schema from the request body, answering 42201 when
+// it is not a valid schema of its type. Schema references are not supported.
+func crDecodeSchema(w http.ResponseWriter, r *http.Request) (schema.SchemaType, string, bool) {
+	var req crSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		crError(w, crInvalidSchema, "Invalid request body")
+		return "", "", false
+	}
+	if len(req.References) > 0 {
+		crError(w, crInvalidSchema, "Schema references are not supported")
+		return "", "", false
+	}
+	schemaType, ok := crSchemaTypes[strings.ToUpper(cmp.Or(req.SchemaType, "AVRO"))]
+	if !ok {
+		crError(w, crInvalidSchema, "Unknown schema type "+req.SchemaType)
+		return "", "", false
+	}
+	if _, err := schema.NewValidator(schema.SchemaConfig{Type: schemaType, Schema: req.Schema}); err != nil {
+		crError(w, crInvalidSchema, "Invalid schema: "+err.Error())
+		return "", "", false
+	}
+	return schemaType, req.Schema, true
+}
+
+// crView renders a version; withSubject adds the subject and version.
+func crView(sc storage.Schema, withSubject bool) crSchema {
+	v := crSchema{ID: sc.SchemaID, Schema: sc.Content}
+	// The Confluent API leaves the type out for Avro, its default.
+	if sc.Type != string(schema.Avro) {
+		v.SchemaType = strings.ToUpper(sc.Type)
+	}
+	if withSubject {
+		v.Subject = sc.Name
+		v.Version = sc.Version
+	}
+	return v
+}
+
+func crConfigTarget(subject string) string {
+	if subject == "" {
+		return "the schema registry"
+	}
+	return "subject " + subject
+}
+
+func crJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", confluentContentType)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// crError answers with a Confluent error body. Codes of five digits carry
+// the HTTP status in their first three.
+func crError(w http.ResponseWriter, code int, message string) {
+	status := code
+	if code >= 10000 {
+		status = code / 100
+	}
+	w.Header().Set("Content-Type", confluentContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error_code": code, "message": message})
+}
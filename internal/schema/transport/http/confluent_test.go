@@ -0,0 +1,313 @@
+package http
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/testutil"
+)
+
+// schemaStore keeps schemas and settings in memory.
+type schemaStore struct {
+	testutil.BaseMockStorage
+	mu       sync.Mutex
+	schemas  map[string][]storage.Schema
+	ids      map[string]int
+	settings map[string]string
+}
+
+func (s *schemaStore) ListSchemas(_ context.Context, name string) ([]storage.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]storage.Schema(nil), s.schemas[name]...), nil
+}
+
+func (s *schemaStore) ListAllSchemas(_ context.Context) ([]storage.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []storage.Schema
+	for _, versions := range s.schemas {
+		out = append(out, versions[len(versions)-1])
+	}
+	return out, nil
+}
+
+func (s *schemaStore) GetSchema(_ context.Context, name string, version int) (storage.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sc := range s.schemas[name] {
+		if sc.Version == version {
+			return sc, nil
+		}
+	}
+	return storage.Schema{}, storage.ErrNotFound
+}
+
+func (s *schemaStore) GetLatestSchema(_ context.Context, name string) (storage.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.schemas[name]
+	if len(versions) == 0 {
+		return storage.Schema{}, storage.ErrNotFound
+	}
+	return versions[len(versions)-1], nil
+}
+
+func (s *schemaStore) ListSchemasByID(_ context.Context, schemaID int) ([]storage.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []storage.Schema
+	for _, versions := range s.schemas {
+		for _, sc := range versions {
+			if sc.SchemaID == schemaID {
+				out = append(out, sc)
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b storage.Schema) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), a.Version-b.Version)
+	})
+	return out, nil
+}
+
+func (s *schemaStore) CreateSchema(_ context.Context, sc storage.Schema) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fingerprint := storage.SchemaFingerprint(sc.Type, sc.Content)
+	if _, ok := s.ids[fingerprint]; !ok {
+		s.ids[fingerprint] = len(s.ids) + 1
+	}
+	sc.SchemaID = s.ids[fingerprint]
+	s.schemas[sc.Name] = append(s.schemas[sc.Name], sc)
+	return nil
+}
+
+func (s *schemaStore) GetSetting(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.settings[key], nil
+}
+
+func (s *schemaStore) SaveSetting(_ context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings[key] = value
+	return nil
+}
+
+func newSchemaTestServer(t *testing.T) *httptest.Server {
+	store := &schemaStore{schemas: make(map[string][]storage.Schema), ids: make(map[string]int), settings: make(map[string]string)}
+	h := NewSchemaHandler(&handlers.Handler{Storage: store, LogStorage: store})
+	mux := http.NewServeMux()
+	h.RegisterSchemaRoutes(mux)
+	admin := &storage.User{Username: "admin", Role: storage.RoleAdministrator}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), handlers.UserContextKey, admin)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// call sends body to the Confluent API and decodes the answer into out.
+func call(t *testing.T, srv *httptest.Server, method, path, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+confluentPrefix+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", confluentContentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func schemaBody(s string) string {
+	b, _ := json.Marshal(map[string]string{"schema": s})
+	return string(b)
+}
+
+func TestConfluentFacade(t *testing.T) {
+	const (
+		v1 = `{"type":"record","name":"User","fields":[{"name":"id","type":"int"}]}`
+		v2 = `{"type":"record","name":"User","fields":[{"name":"id","type":"int"},{"name":"email","type":["null","string"],"default":null}]}`
+		v3 = `{"type":"record","name":"User","fields":[{"name":"id","type":"int"},{"name":"name","type":"string"}]}`
+	)
+	srv := newSchemaTestServer(t)
+
+	var reg struct {
+		ID int `json:"id"`
+	}
+	if code := call(t, srv, "POST", "/subjects/users-value/versions", schemaBody(v1), &reg); code != http.StatusOK || reg.ID == 0 {
+		t.Fatalf("register v1: %d %+v", code, reg)
+	}
+	firstID := reg.ID
+	// Registering the same schema again returns its ID without a new version.
+	if call(t, srv, "POST", "/subjects/users-value/versions", schemaBody(v1), &reg); reg.ID != firstID {
+		t.Fatalf("re-register returned ID %d, want %d", reg.ID, firstID)
+	}
+	if code := call(t, srv, "POST", "/subjects/users-value/versions", schemaBody(v2), &reg); code != http.StatusOK || reg.ID == firstID {
+		t.Fatalf("register v2: %d %+v", code, reg)
+	}
+
+	var versions []int
+	call(t, srv, "GET", "/subjects/users-value/versions", "", &versions)
+	if len(versions) != 2 || versions[1] != 2 {
+		t.Fatalf("versions = %v", versions)
+	}
+	var latest crSchema
+	call(t, srv, "GET", "/subjects/users-value/versions/latest", "", &latest)
+	if latest.Version != 2 || latest.Schema != v2 || latest.ID != reg.ID {
+		t.Fatalf("latest = %+v", latest)
+	}
+	var byID crSchema
+	if code := call(t, srv, "GET", "/schemas/ids/"+strconv.Itoa(firstID), "", &byID); code != http.StatusOK || byID.Schema != v1 {
+		t.Fatalf("schema by ID: %d %+v", code, byID)
+	}
+	var lookup crSchema
+	if call(t, srv, "POST", "/subjects/users-value", schemaBody(v1), &lookup); lookup.Version != 1 {
+		t.Fatalf("lookup = %+v", lookup)
+	}
+
+	var subjects []string
+	call(t, srv, "GET", "/subjects", "", &subjects)
+	if len(subjects) != 1 || subjects[0] != "users-value" {
+		t.Fatalf("subjects = %v", subjects)
+	}
+
+	// The same schema keeps its ID under another subject.
+	if call(t, srv, "POST", "/subjects/archive-value/versions", schemaBody(v1), &reg); reg.ID != firstID {
+		t.Fatalf("register under another subject returned ID %d, want %d", reg.ID, firstID)
+	}
+	var holders []struct {
+		Subject string `json:"subject"`
+		Version int    `json:"version"`
+	}
+	call(t, srv, "GET", "/schemas/ids/"+strconv.Itoa(firstID)+"/versions", "", &holders)
+	if len(holders) != 2 || holders[0].Subject != "archive-value" || holders[1].Subject != "users-value" || holders[1].Version != 1 {
+		t.Fatalf("versions by ID = %+v", holders)
+	}
+
+	var compat struct {
+		IsCompatible bool     `json:"is_compatible"`
+		Messages     []string `json:"messages"`
+	}
+	call(t, srv, "POST", "/compatibility/subjects/users-value/versions/latest?verbose=true", schemaBody(v3), &compat)
+	if compat.IsCompatible || len(compat.Messages) == 0 {
+		t.Fatalf("compatibility = %+v", compat)
+	}
+
+	var apiErr struct {
+		ErrorCode int    `json:"error_code"`
+		Message   string `json:"message"`
+	}
+	if code := call(t, srv, "POST", "/subjects/users-value/versions", schemaBody(v3), &apiErr); code != http.StatusConflict || apiErr.ErrorCode != crIncompatibleSchema {
+		t.Fatalf("incompatible register: %d %+v", code, apiErr)
+	}
+	if code := call(t, srv, "GET", "/subjects/missing/versions", "", &apiErr); code != http.StatusNotFound || apiErr.ErrorCode != crSubjectNotFound {
+		t.Fatalf("missing subject: %d %+v", code, apiErr)
+	}
+	if code := call(t, srv, "GET", "/subjects/users-value/versions/7", "", &apiErr); apiErr.ErrorCode != crVersionNotFound {
+		t.Fatalf("missing version: %d %+v", code, apiErr)
+	}
+	if code := call(t, srv, "POST", "/subjects/users-value/versions", schemaBody(`{"type":`), &apiErr); code != http.StatusUnprocessableEntity || apiErr.ErrorCode != crInvalidSchema {
+		t.Fatalf("invalid schema: %d %+v", code, apiErr)
+	}
+
+	// Levels: a subject without its own level answers 40408 unless it asks
+	// for the global one.
+	if code := call(t, srv, "GET", "/config/users-value", "", &apiErr); code != http.StatusNotFound || apiErr.ErrorCode != crSubjectConfigNotSet {
+		t.Fatalf("unset subject config: %d %+v", code, apiErr)
+	}
+	var cfg struct {
+		CompatibilityLevel string `json:"compatibilityLevel"`
+		Compatibility      string `json:"compatibility"`
+	}
+	call(t, srv, "GET", "/config/users-value?defaultToGlobal=true", "", &cfg)
+	if cfg.CompatibilityLevel != "BACKWARD" {
+		t.Fatalf("default level = %+v", cfg)
+	}
+	if code := call(t, srv, "PUT", "/config/users-value", `{"compatibility":"FULL"}`, &cfg); code != http.StatusOK || cfg.Compatibility != "FULL" {
+		t.Fatalf("set subject config: %d %+v", code, cfg)
+	}
+	if code := call(t, srv, "PUT", "/config", `{"compatibility":"SIDEWAYS"}`, &apiErr); apiErr.ErrorCode != crInvalidCompatibility {
+		t.Fatalf("invalid level: %d %+v", code, apiErr)
+	}
+	call(t, srv, "PUT", "/config", `{"compatibility":"NONE"}`, &cfg)
+	call(t, srv, "GET", "/config/users-value", "", &cfg)
+	if cfg.CompatibilityLevel != "FULL" {
+		t.Fatalf("subject level = %+v", cfg)
+	}
+	call(t, srv, "DELETE", "/config/users-value", "", &cfg)
+	if code := call(t, srv, "POST", "/subjects/users-value/versions", schemaBody(v3), &reg); code != http.StatusOK {
+		t.Fatalf("register under the global NONE level: %d", code)
+	}
+
+	if code := call(t, srv, "DELETE", "/subjects/users-value", "", &apiErr); code != http.StatusNotFound {
+		t.Fatalf("deleting a subject: %d", code)
+	}
+}
+
+func TestSchemaCompatibilityEndpoints(t *testing.T) {
+	srv := newSchemaTestServer(t)
+	do := func(method, path, body string, out any) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if out != nil {
+			_ = json.NewDecoder(resp.Body).Decode(out)
+		}
+		return resp.StatusCode
+	}
+
+	if code := do("POST", "/api/schemas", `{"name":"orders","type":"json","content":"{\"type\":\"object\",\"required\":[\"id\"]}"}`, nil); code != http.StatusOK {
+		t.Fatalf("register: %d", code)
+	}
+	candidate := `{"type":"json","content":"{\"type\":\"object\",\"required\":[\"id\",\"total\"]}"}`
+	var res struct {
+		Compatible bool     `json:"compatible"`
+		Level      string   `json:"level"`
+		Messages   []string `json:"messages"`
+	}
+	if code := do("POST", "/api/schemas/orders/compatibility", candidate, &res); code != http.StatusOK || res.Compatible || res.Level != "BACKWARD" || len(res.Messages) != 1 {
+		t.Fatalf("compatibility: %d %+v", code, res)
+	}
+	if code := do("POST", "/api/schemas", `{"name":"orders","type":"json","content":"{\"type\":\"object\",\"required\":[\"id\",\"total\"]}"}`, nil); code != http.StatusConflict {
+		t.Fatalf("incompatible register: %d, want 409", code)
+	}
+
+	var level struct {
+		Level    string `json:"level"`
+		Explicit bool   `json:"explicit"`
+	}
+	if code := do("PUT", "/api/schemas/orders/compatibility-level", `{"level":"forward"}`, &level); code != http.StatusOK || level.Level != "FORWARD" || !level.Explicit {
+		t.Fatalf("set level: %d %+v", code, level)
+	}
+	if do("POST", "/api/schemas/orders/compatibility", candidate, &res); !res.Compatible {
+		t.Fatalf("FORWARD compatibility: %+v", res)
+	}
+	if code := do("PUT", "/api/schemas/orders/compatibility-level", `{"level":"sideways"}`, nil); code != http.StatusBadRequest {
+		t.Fatalf("invalid level: %d", code)
+	}
+}
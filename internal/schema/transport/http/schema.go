@@ -1,19 +1,26 @@
 package http
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/user/hermod/internal/api/handlers"
 	"github.com/user/hermod/internal/storage"
 	"github.com/user/hermod/pkg/infra/schema"
 )
 
-func (h *SchemaHandler) RegisterSchemaRoutes(mux *http.ServeMux) {
+func (h *SchemaHandler) RegisterSchemaRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/schemas", h.ListSchemas)
 	mux.HandleFunc("POST /api/schemas", h.RegisterSchema)
 	mux.HandleFunc("GET /api/schemas/{name}", h.GetLatestSchema)
 	mux.HandleFunc("GET /api/schemas/{name}/history", h.GetSchemaHistory)
+	mux.HandleFunc("POST /api/schemas/{name}/compatibility", h.TestSchemaCompatibility)
+	mux.HandleFunc("GET /api/schemas/{name}/compatibility-level", h.GetCompatibilityLevel)
+	mux.Handle("PUT /api/schemas/{name}/compatibility-level", h.EditorOnly(h.SetCompatibilityLevel))
+	h.registerConfluentRoutes(mux)
 }
 
 func (h *SchemaHandler) ListSchemas(w http.ResponseWriter, r *http.Request) {
@@ -82,6 +89,11 @@ func (h *SchemaHandler) RegisterSchema(w http.ResponseWriter, r *http.Request) {
 
 	reg := schema.NewStorageRegistry(h.Storage)
 	version, err := reg.Register(r.Context(), req.Name, req.Type, req.Content)
+	var compatErr *schema.CompatibilityError
+	if errors.As(err, &compatErr) {
+		h.JsonError(w, "Failed to register schema: "+err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		h.JsonError(w, "Failed to register schema: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -96,3 +108,103 @@ func (h *SchemaHandler) RegisterSchema(w http.ResponseWriter, r *http.Request) {
 		"status":  "registered",
 	})
 }
+
+// TestSchemaCompatibility checks a candidate schema against the compatibility
+// level of a subject without registering it. A version in the body checks
+// that version alone.
+func (h *SchemaHandler) TestSchemaCompatibility(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var req struct {
+		Type    schema.SchemaType `json:"type"`
+		Content string            `json:"content"`
+		Version int               `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.JsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	reg := schema.NewStorageRegistry(h.Storage)
+	if _, err := schema.NewValidator(schema.SchemaConfig{Type: req.Type, Schema: req.Content}); err != nil {
+		h.JsonError(w, "Invalid schema: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	messages, err := reg.TestCompatibility(r.Context(), name, req.Type, req.Content, req.Version)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.JsonError(w, "Schema version not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.JsonError(w, "Failed to check compatibility: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	level, err := reg.GetCompatibility(r.Context(), name)
+	if err != nil {
+		h.JsonError(w, "Failed to get compatibility level: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if messages == nil {
+		messages = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"compatible": len(messages) == 0,
+		"level":      level,
+		"messages":   messages,
+	})
+}
+
+// GetCompatibilityLevel returns the level that applies to a subject and
+// whether the subject sets it itself.
+func (h *SchemaHandler) GetCompatibilityLevel(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	reg := schema.NewStorageRegistry(h.Storage)
+	_, explicit, err := reg.SubjectCompatibility(r.Context(), name)
+	if err != nil {
+		h.JsonError(w, "Failed to get compatibility level: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	level, err := reg.GetCompatibility(r.Context(), name)
+	if err != nil {
+		h.JsonError(w, "Failed to get compatibility level: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"name":     name,
+		"level":    level,
+		"explicit": explicit,
+	})
+}
+
+// SetCompatibilityLevel sets the level of a subject. An empty level makes the
+// subject follow the global level again.
+func (h *SchemaHandler) SetCompatibilityLevel(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var req struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.JsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var level schema.CompatibilityLevel
+	if req.Level != "" {
+		var err error
+		if level, err = schema.ParseCompatibilityLevel(req.Level); err != nil {
+			h.JsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	reg := schema.NewStorageRegistry(h.Storage)
+	if err := reg.SetCompatibility(r.Context(), name, level); err != nil {
+		h.JsonError(w, "Failed to set compatibility level: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.RecordAuditLog(r, "INFO", "Set compatibility level of schema "+name+" to "+cmp.Or(string(level), "the global level"), "UPDATE", "", "", "", req)
+
+	h.GetCompatibilityLevel(w, r)
+}
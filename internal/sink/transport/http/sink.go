@@ -10,12 +10,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/engine/registry"
 	"github.com/user/hermod/internal/factory"
 	"github.com/user/hermod/internal/storage"
 	"github.com/user/hermod/pkg/infra/sqlutil"
 )
 
-func (h *SinkHandler) RegisterSinkRoutes(mux *http.ServeMux) {
+func (h *SinkHandler) RegisterSinkRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/sinks", h.ListSinks)
 	mux.HandleFunc("GET /api/sinks/{id}", h.GetSink)
 	mux.Handle("POST /api/sinks", h.EditorOnly(h.CreateSink))
@@ -102,6 +104,10 @@ func (h *SinkHandler) CreateSink(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Name, Type, and VHost are mandatory", http.StatusBadRequest)
 		return
 	}
+	if err := registry.ValidateSchemaChangePolicy(snk.Type, snk.Config); err != nil {
+		h.JsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	role, vhosts := h.GetRoleAndVHosts(r)
 	if role != storage.RoleAdministrator {
@@ -138,6 +144,10 @@ func (h *SinkHandler) UpdateSink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	snk.ID = id
+	if err := registry.ValidateSchemaChangePolicy(snk.Type, snk.Config); err != nil {
+		h.JsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	role, vhosts := h.GetRoleAndVHosts(r)
 	if role != storage.RoleAdministrator {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/user/hermod"
+	"github.com/user/hermod/internal/api/handlers"
 	"github.com/user/hermod/internal/factory"
 	"github.com/user/hermod/internal/storage"
 	"github.com/user/hermod/pkg/comm/message"
@@ -18,7 +19,7 @@ import (
 	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
-func (h *SourceHandler) RegisterSourceRoutes(mux *http.ServeMux) {
+func (h *SourceHandler) RegisterSourceRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/sources", h.ListSources)
 	mux.HandleFunc("GET /api/sources/{id}", h.GetSource)
 	mux.Handle("POST /api/sources", h.EditorOnly(h.CreateSource))
@@ -6,10 +6,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/user/hermod/internal/api/handlers"
 	"github.com/user/hermod/internal/sse"
 )
 
-func (h *SSEHandler) RegisterSSERoutes(mux *http.ServeMux) {
+func (h *SSEHandler) RegisterSSERoutes(mux handlers.Router) {
 	// Data orchestration streams (SSE)
 	mux.HandleFunc("GET /streams/sse", h.HandleSSEStream)
 	// Internal API notifications (SSE)
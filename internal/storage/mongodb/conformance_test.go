@@ -0,0 +1,20 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/storage/storagetest"
+	"github.com/user/hermod/internal/testutil/mongomem"
+)
+
+func TestMongoStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		client, err := mongomem.Connect()
+		if err != nil {
+			t.Fatalf("failed to connect to in-memory mongo: %v", err)
+		}
+		t.Cleanup(func() { _ = client.Disconnect(t.Context()) })
+		return NewMongoStorage(client, "hermod")
+	})
+}
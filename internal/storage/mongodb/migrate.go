@@ -0,0 +1,176 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/user/hermod/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// legacyCollections are the collections earlier versions wrote straight from
+// storage structs, before the database used JSON struct tags. Their documents
+// carry the driver's default field names, the lowercased Go names (workflowid
+// rather than workflow_id), which no longer decode.
+var legacyCollections = map[string]reflect.Type{
+	"approvals":          reflect.TypeOf(storage.Approval{}),
+	"suspended_messages": reflect.TypeOf(storage.SuspendedMessage{}),
+	"workspaces":         reflect.TypeOf(storage.Workspace{}),
+	"form_submissions":   reflect.TypeOf(storage.FormSubmission{}),
+	"schemas":            reflect.TypeOf(storage.Schema{}),
+	"message_traces":     reflect.TypeOf(storage.MessageTrace{}),
+	"workflow_versions":  reflect.TypeOf(storage.WorkflowVersion{}),
+	"outbox":             reflect.TypeOf(storage.OutboxItem{}),
+}
+
+// migrateLegacyFields renames the default field names of documents in the
+// legacy collections to their JSON names. A document that already has a
+// field under its JSON name, set by a later update, keeps that value.
+func (s *mongoStorage) migrateLegacyFields(ctx context.Context) error {
+	for name, t := range legacyCollections {
+		var or []bson.M
+		for _, path := range legacyPaths(t, "") {
+			or = append(or, bson.M{path: bson.M{"$exists": true}})
+		}
+		if len(or) == 0 {
+			continue
+		}
+		coll := s.db.Collection(name)
+		cursor, err := coll.Find(ctx, bson.M{"$or": or})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		var docs []bson.D
+		err = cursor.All(ctx, &docs)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, doc := range docs {
+			id, _ := fieldValue(doc, "_id")
+			if _, err := coll.ReplaceOne(ctx, bson.M{"_id": id}, renameLegacyFields(doc, t)); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// legacyPaths returns the paths of the fields of t, including those of nested
+// structs, whose default name differs from their JSON name.
+func legacyPaths(t reflect.Type, prefix string) []string {
+	var paths []string
+	for _, f := range structFields(t) {
+		old, name := strings.ToLower(f.Name), jsonName(f)
+		if old != name {
+			paths = append(paths, prefix+old)
+		}
+		if nested := nestedStruct(f.Type); nested != nil {
+			paths = append(paths, legacyPaths(nested, prefix+old+".")...)
+			if old != name {
+				paths = append(paths, legacyPaths(nested, prefix+name+".")...)
+			}
+		}
+	}
+	return paths
+}
+
+// renameLegacyFields returns doc with the fields of t under their JSON names.
+func renameLegacyFields(doc bson.D, t reflect.Type) bson.D {
+	renames := make(map[string]reflect.StructField)
+	for _, f := range structFields(t) {
+		renames[strings.ToLower(f.Name)] = f
+		renames[jsonName(f)] = f
+	}
+	out := make(bson.D, 0, len(doc))
+	index := make(map[string]int)
+	for _, e := range doc {
+		f, ok := renames[e.Key]
+		if !ok {
+			out = append(out, e)
+			continue
+		}
+		name := jsonName(f)
+		if nested := nestedStruct(f.Type); nested != nil {
+			e.Value = renameNested(e.Value, nested)
+		}
+		if i, seen := index[name]; seen {
+			// Keep the value under the JSON name over the default one.
+			if e.Key == name {
+				out[i].Value = e.Value
+			}
+			continue
+		}
+		index[name] = len(out)
+		out = append(out, bson.E{Key: name, Value: e.Value})
+	}
+	return out
+}
+
+// renameNested renames the fields of a nested struct value, or of each
+// element of an array of them.
+func renameNested(v any, t reflect.Type) any {
+	switch val := v.(type) {
+	case bson.D:
+		return renameLegacyFields(val, t)
+	case bson.M:
+		d := make(bson.D, 0, len(val))
+		for k, e := range val {
+			d = append(d, bson.E{Key: k, Value: e})
+		}
+		return renameLegacyFields(d, t)
+	case bson.A:
+		out := make(bson.A, len(val))
+		for i, e := range val {
+			out[i] = renameNested(e, t)
+		}
+		return out
+	}
+	return v
+}
+
+// structFields returns the exported, stored fields of t.
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() && f.Tag.Get("json") != "-" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// jsonName is the name a field is stored under with JSON struct tags.
+func jsonName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return strings.ToLower(f.Name)
+}
+
+// nestedStruct returns the struct type stored as a subdocument, or the element
+// type of an array of them, for fields of t.
+func nestedStruct(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return nil
+	}
+	return t
+}
+
+func fieldValue(doc bson.D, key string) (any, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,74 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/testutil/mongomem"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Earlier versions inserted these structs with the driver's default codec,
+// which names fields after the lowercased Go names. Init must rename them so
+// that the documents still decode.
+func TestInitMigratesDefaultFieldNames(t *testing.T) {
+	ctx := t.Context()
+	client, err := mongomem.Connect()
+	if err != nil {
+		t.Fatalf("failed to connect to in-memory mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	old := client.Database("hermod")
+	created := time.Now().UTC().Truncate(time.Millisecond)
+	if _, err := old.Collection("approvals").InsertOne(ctx, storage.Approval{
+		ID: "a1", WorkflowID: "wf1", NodeID: "n1", MessageID: "m1", Status: "pending", CreatedAt: created,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// A later status update wrote the JSON name next to the default one.
+	if _, err := old.Collection("approvals").UpdateOne(ctx, bson.M{"id": "a1"},
+		bson.M{"$set": bson.M{"status": "approved", "processed_by": "alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Collection("message_traces").InsertOne(ctx, storage.MessageTrace{
+		ID: "t1", WorkflowID: "wf1", MessageID: "m1", CreatedAt: created,
+		Steps: []hermod.TraceStep{{NodeID: "n1", Timestamp: created}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewMongoStorage(client, "hermod")
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	a, err := s.GetApproval(ctx, "a1")
+	if err != nil {
+		t.Fatalf("GetApproval: %v", err)
+	}
+	if a.WorkflowID != "wf1" || a.NodeID != "n1" || a.MessageID != "m1" || !a.CreatedAt.Equal(created) {
+		t.Errorf("approval fields not migrated: %+v", a)
+	}
+	if a.Status != "approved" || a.ProcessedBy != "alice" {
+		t.Errorf("approval lost its later update: status %q, processed by %q", a.Status, a.ProcessedBy)
+	}
+
+	tr, err := s.GetMessageTrace(ctx, "wf1", "m1")
+	if err != nil {
+		t.Fatalf("GetMessageTrace: %v", err)
+	}
+	if len(tr.Steps) != 1 || tr.Steps[0].NodeID != "n1" {
+		t.Errorf("trace steps not migrated: %+v", tr.Steps)
+	}
+
+	// Init runs on every start; a second run leaves migrated documents alone.
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("second Init: %v", err)
+	}
+	if a, _ := s.GetApproval(ctx, "a1"); a.WorkflowID != "wf1" {
+		t.Errorf("second Init changed the approval: %+v", a)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -63,9 +65,42 @@ type mongoStorage struct {
 }
 
 func NewMongoStorage(client *mongo.Client, dbName string) storage.Storage {
+	// Documents written from storage structs use the same field names as the
+	// JSON API (and the SQL columns), and untyped values such as node configs
+	// decode as plain maps rather than bson.D. Init migrates documents that
+	// earlier versions wrote with the driver's default names.
+	bsonOpts := &options.BSONOptions{UseJSONStructTags: true, DefaultDocumentMap: true}
 	return &mongoStorage{
 		client: client,
-		db:     client.Database(dbName),
+		db:     client.Database(dbName, options.Database().SetBSONOptions(bsonOpts)),
+	}
+}
+
+// searchFilter matches search as a case-insensitive literal substring of any
+// of the given fields.
+func searchFilter(search string, fields ...string) []bson.M {
+	pattern := regexp.QuoteMeta(search)
+	or := make([]bson.M, 0, len(fields))
+	for _, f := range fields {
+		or = append(or, bson.M{f: bson.M{"$regex": pattern, "$options": "i"}})
+	}
+	return or
+}
+
+// applyCommonFilter adds the vhost, workspace, worker and active filters shared
+// by the source, sink and workflow listings.
+func applyCommonFilter(query bson.M, filter storage.CommonFilter) {
+	if filter.VHost != "" && filter.VHost != "all" {
+		query["vhost"] = filter.VHost
+	}
+	if filter.WorkspaceID != "" {
+		query["workspace_id"] = filter.WorkspaceID
+	}
+	if filter.WorkerID != "" {
+		query["worker_id"] = filter.WorkerID
+	}
+	if filter.Active != nil {
+		query["active"] = *filter.Active
 	}
 }
 
@@ -84,7 +119,10 @@ func (s *mongoStorage) ListApprovals(ctx context.Context, filter storage.Approva
 	}
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
 	if filter.Limit > 0 {
-		opts.SetLimit(int64(filter.Limit)).SetSkip(int64(filter.Page * filter.Limit))
+		opts.SetLimit(int64(filter.Limit))
+		if filter.Page > 0 {
+			opts.SetSkip(int64((filter.Page - 1) * filter.Limit))
+		}
 	}
 	cur, err := coll.Find(ctx, q, opts)
 	if err != nil {
@@ -166,6 +204,34 @@ func (s *mongoStorage) DeleteSuspendedMessage(ctx context.Context, id string) er
 	return err
 }
 
+func (s *mongoStorage) ListEventWaits(ctx context.Context, workflowID, nodeID, key string) ([]storage.SuspendedMessage, error) {
+	filter := bson.M{"workflow_id": workflowID, "node_id": nodeID, "wait_key": key}
+	cursor, err := s.db.Collection("suspended_messages").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var results []storage.SuspendedMessage
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoStorage) ResolveEventWait(ctx context.Context, m storage.SuspendedMessage) (bool, error) {
+	// $gt "" only matches non-empty strings, so a resolved wait is left alone.
+	res, err := s.db.Collection("suspended_messages").UpdateOne(ctx,
+		bson.M{"id": m.ID, "wait_key": bson.M{"$gt": ""}},
+		bson.M{
+			"$set":   bson.M{"metadata": m.Metadata, "data": m.Data, "resume_at": m.ResumeAt},
+			"$unset": bson.M{"wait_key": ""},
+		})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
 func (s *mongoStorage) DeleteApproval(ctx context.Context, id string) error {
 	res, err := s.db.Collection("approvals").DeleteOne(ctx, bson.M{"id": id})
 	if err != nil {
@@ -182,8 +248,13 @@ func (s *mongoStorage) Ping(ctx context.Context) error {
 }
 
 func (s *mongoStorage) Init(ctx context.Context) error {
+	// Rename old fields first so that indexes on the new names see them.
+	if err := s.migrateLegacyFields(ctx); err != nil {
+		return fmt.Errorf("failed to migrate legacy fields: %w", err)
+	}
+
 	// Create indexes
-	collections := []string{"sources", "sinks", "users", "vhosts", "workflows", "workers", "logs", "settings", "audit_logs", "webhook_requests", "schemas", "message_traces", "workflow_versions"}
+	collections := []string{"sources", "sinks", "users", "vhosts", "workflows", "workers", "logs", "settings", "audit_logs", "webhook_requests", "schemas", "schema_ids", "message_traces", "workflow_versions", "plugins", "dead_letters"}
 
 	for _, collName := range collections {
 		coll := s.db.Collection(collName)
@@ -199,14 +270,26 @@ func (s *mongoStorage) Init(ctx context.Context) error {
 			indexModels = append(indexModels, mongo.IndexModel{
 				Keys: bson.D{{Key: "created_at", Value: -1}},
 			})
+		case "dead_letters":
+			indexModels = append(indexModels, mongo.IndexModel{
+				Keys: bson.D{{Key: "workflow_id", Value: 1}, {Key: "created_at", Value: -1}},
+			})
 		case "workflow_versions":
 			indexModels = append(indexModels, mongo.IndexModel{
 				Keys:    bson.D{{Key: "workflow_id", Value: 1}, {Key: "version", Value: -1}},
 				Options: options.Index().SetUnique(true),
 			})
 		case "schemas":
+			indexModels = append(indexModels,
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "name", Value: 1}, {Key: "version", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				mongo.IndexModel{Keys: bson.D{{Key: "schema_id", Value: 1}}},
+			)
+		case "schema_ids":
 			indexModels = append(indexModels, mongo.IndexModel{
-				Keys:    bson.D{{Key: "name", Value: 1}, {Key: "version", Value: 1}},
+				Keys:    bson.D{{Key: "id", Value: 1}},
 				Options: options.Index().SetUnique(true),
 			})
 		case "sources", "sinks":
@@ -259,6 +342,11 @@ func (s *mongoStorage) Init(ctx context.Context) error {
 				mongo.IndexModel{Keys: bson.D{{Key: "timestamp", Value: -1}}},
 				mongo.IndexModel{Keys: bson.D{{Key: "path", Value: 1}}},
 			)
+		case "plugins":
+			indexModels = append(indexModels, mongo.IndexModel{
+				Keys:    bson.D{{Key: "id", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			})
 		}
 
 		if len(indexModels) > 0 {
@@ -269,6 +357,12 @@ func (s *mongoStorage) Init(ctx context.Context) error {
 		}
 	}
 
+	if err := s.seedPlugins(ctx); err != nil {
+		return fmt.Errorf("failed to seed plugins: %w", err)
+	}
+	if err := s.numberSchemas(ctx); err != nil {
+		return fmt.Errorf("failed to number schemas: %w", err)
+	}
 	return nil
 }
 
@@ -281,22 +375,35 @@ func (s *mongoStorage) AcquireWorkflowLease(ctx context.Context, workflowID, own
 	coll := s.db.Collection("workflows")
 	now := time.Now().UTC()
 	until := now.Add(time.Duration(ttlSeconds) * time.Second)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
+	// The current owner re-acquires in place and keeps its lease_token.
+	res := coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": workflowID, "owner_id": ownerID},
+		bson.M{"$set": bson.M{"lease_until": until}}, opts)
+	if err := res.Err(); err == nil {
+		return true, nil
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return false, err
+	}
+
+	// Anyone else may take over an unowned or expired lease, which moves
+	// lease_token on.
 	filter := bson.M{
-		"id": workflowID,
+		"_id": workflowID,
 		"$or": []bson.M{
 			{"owner_id": bson.M{"$exists": false}},
 			{"owner_id": nil},
 			{"lease_until": bson.M{"$exists": false}},
 			{"lease_until": nil},
 			{"lease_until": bson.M{"$lt": now}},
-			{"owner_id": ownerID},
 		},
 	}
-	update := bson.M{"$set": bson.M{"owner_id": ownerID, "lease_until": until}}
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-
-	res := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	update := bson.M{
+		"$set": bson.M{"owner_id": ownerID, "lease_until": until},
+		"$inc": bson.M{"lease_token": int64(1)},
+	}
+	res = coll.FindOneAndUpdate(ctx, filter, update, opts)
 	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
 		return false, nil
 	}
@@ -314,7 +421,7 @@ func (s *mongoStorage) RenewWorkflowLease(ctx context.Context, workflowID, owner
 	now := time.Now().UTC()
 	until := now.Add(time.Duration(ttlSeconds) * time.Second)
 
-	filter := bson.M{"id": workflowID, "owner_id": ownerID, "lease_until": bson.M{"$gte": now}}
+	filter := bson.M{"_id": workflowID, "owner_id": ownerID, "lease_until": bson.M{"$gte": now}}
 	update := bson.M{"$set": bson.M{"lease_until": until}}
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
@@ -330,28 +437,86 @@ func (s *mongoStorage) RenewWorkflowLease(ctx context.Context, workflowID, owner
 
 func (s *mongoStorage) ReleaseWorkflowLease(ctx context.Context, workflowID, ownerID string) error {
 	coll := s.db.Collection("workflows")
-	filter := bson.M{"id": workflowID, "owner_id": ownerID}
+	filter := bson.M{"_id": workflowID, "owner_id": ownerID}
 	update := bson.M{"$unset": bson.M{"owner_id": "", "lease_until": ""}}
 	_, err := coll.UpdateOne(ctx, filter, update)
 	return err
 }
 
+func (s *mongoStorage) AcquireLease(ctx context.Context, name, ownerID string, ttlSeconds int) (bool, error) {
+	if ttlSeconds <= 0 {
+		ttlSeconds = 30
+	}
+	coll := s.db.Collection("leases")
+	now := time.Now().UTC()
+	until := now.Add(time.Duration(ttlSeconds) * time.Second)
+
+	// The current owner extends in place.
+	res, err := coll.UpdateOne(ctx,
+		bson.M{"_id": name, "owner_id": ownerID},
+		bson.M{"$set": bson.M{"lease_until": until}})
+	if err != nil {
+		return false, err
+	}
+	if res.MatchedCount > 0 {
+		return true, nil
+	}
+
+	// Anyone else may take over a released or expired lease.
+	filter := bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"owner_id": ""},
+			{"lease_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{"owner_id": ownerID, "lease_until": until, "acquired_at": now},
+		"$inc": bson.M{"token": int64(1)},
+	}
+	res, err = coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	if res.MatchedCount > 0 {
+		return true, nil
+	}
+
+	// Nobody has taken it yet; a concurrent first owner wins on _id.
+	_, err = coll.InsertOne(ctx, bson.M{
+		"_id": name, "name": name, "owner_id": ownerID,
+		"lease_until": until, "token": int64(1), "acquired_at": now,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *mongoStorage) ReleaseLease(ctx context.Context, name, ownerID string) error {
+	_, err := s.db.Collection("leases").UpdateOne(ctx,
+		bson.M{"_id": name, "owner_id": ownerID},
+		bson.M{"$set": bson.M{"owner_id": "", "lease_until": time.Now().UTC()}})
+	return err
+}
+
+func (s *mongoStorage) GetLease(ctx context.Context, name string) (storage.Lease, error) {
+	var l storage.Lease
+	err := s.db.Collection("leases").FindOne(ctx, bson.M{"_id": name}).Decode(&l)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return l, storage.ErrNotFound
+	}
+	return l, err
+}
+
 func (s *mongoStorage) ListSources(ctx context.Context, filter storage.CommonFilter) ([]storage.Source, int, error) {
 	coll := s.db.Collection("sources")
 	query := bson.M{}
 
 	if filter.Search != "" {
-		query["$or"] = []bson.M{
-			{"_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"name": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"type": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"vhost": bson.M{"$regex": filter.Search, "$options": "i"}},
-		}
-	}
-
-	if filter.VHost != "" {
-		query["vhost"] = filter.VHost
+		query["$or"] = searchFilter(filter.Search, "_id", "name", "type", "vhost")
 	}
+	applyCommonFilter(query, filter)
 
 	total, err := coll.CountDocuments(ctx, query)
 	if err != nil {
@@ -397,16 +562,17 @@ func (s *mongoStorage) CreateSource(ctx context.Context, src storage.Source) err
 
 	coll := s.db.Collection("sources")
 	_, err := coll.InsertOne(ctx, bson.M{
-		"_id":       src.ID,
-		"name":      src.Name,
-		"type":      src.Type,
-		"vhost":     src.VHost,
-		"active":    src.Active,
-		"status":    src.Status,
-		"worker_id": src.WorkerID,
-		"config":    src.Config,
-		"sample":    src.Sample,
-		"state":     src.State,
+		"_id":          src.ID,
+		"name":         src.Name,
+		"type":         src.Type,
+		"vhost":        src.VHost,
+		"active":       src.Active,
+		"status":       src.Status,
+		"worker_id":    src.WorkerID,
+		"workspace_id": src.WorkspaceID,
+		"config":       src.Config,
+		"sample":       src.Sample,
+		"state":        src.State,
 	})
 	return err
 }
@@ -415,15 +581,16 @@ func (s *mongoStorage) UpdateSource(ctx context.Context, src storage.Source) err
 	src.Config = encryptConfig(src.Config)
 	coll := s.db.Collection("sources")
 	_, err := coll.UpdateOne(ctx, bson.M{"_id": src.ID}, bson.M{"$set": bson.M{
-		"name":      src.Name,
-		"type":      src.Type,
-		"vhost":     src.VHost,
-		"active":    src.Active,
-		"status":    src.Status,
-		"worker_id": src.WorkerID,
-		"config":    src.Config,
-		"sample":    src.Sample,
-		"state":     src.State,
+		"name":         src.Name,
+		"type":         src.Type,
+		"vhost":        src.VHost,
+		"active":       src.Active,
+		"status":       src.Status,
+		"worker_id":    src.WorkerID,
+		"workspace_id": src.WorkspaceID,
+		"config":       src.Config,
+		"sample":       src.Sample,
+		"state":        src.State,
 	}})
 	return err
 }
@@ -469,17 +636,9 @@ func (s *mongoStorage) ListSinks(ctx context.Context, filter storage.CommonFilte
 	query := bson.M{}
 
 	if filter.Search != "" {
-		query["$or"] = []bson.M{
-			{"_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"name": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"type": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"vhost": bson.M{"$regex": filter.Search, "$options": "i"}},
-		}
-	}
-
-	if filter.VHost != "" {
-		query["vhost"] = filter.VHost
+		query["$or"] = searchFilter(filter.Search, "_id", "name", "type", "vhost")
 	}
+	applyCommonFilter(query, filter)
 
 	total, err := coll.CountDocuments(ctx, query)
 	if err != nil {
@@ -525,14 +684,15 @@ func (s *mongoStorage) CreateSink(ctx context.Context, snk storage.Sink) error {
 
 	coll := s.db.Collection("sinks")
 	_, err := coll.InsertOne(ctx, bson.M{
-		"_id":       snk.ID,
-		"name":      snk.Name,
-		"type":      snk.Type,
-		"vhost":     snk.VHost,
-		"active":    snk.Active,
-		"status":    snk.Status,
-		"worker_id": snk.WorkerID,
-		"config":    snk.Config,
+		"_id":          snk.ID,
+		"name":         snk.Name,
+		"type":         snk.Type,
+		"vhost":        snk.VHost,
+		"active":       snk.Active,
+		"status":       snk.Status,
+		"worker_id":    snk.WorkerID,
+		"workspace_id": snk.WorkspaceID,
+		"config":       snk.Config,
 	})
 	return err
 }
@@ -541,13 +701,14 @@ func (s *mongoStorage) UpdateSink(ctx context.Context, snk storage.Sink) error {
 	snk.Config = encryptConfig(snk.Config)
 	coll := s.db.Collection("sinks")
 	_, err := coll.UpdateOne(ctx, bson.M{"_id": snk.ID}, bson.M{"$set": bson.M{
-		"name":      snk.Name,
-		"type":      snk.Type,
-		"vhost":     snk.VHost,
-		"active":    snk.Active,
-		"status":    snk.Status,
-		"worker_id": snk.WorkerID,
-		"config":    snk.Config,
+		"name":         snk.Name,
+		"type":         snk.Type,
+		"vhost":        snk.VHost,
+		"active":       snk.Active,
+		"status":       snk.Status,
+		"worker_id":    snk.WorkerID,
+		"workspace_id": snk.WorkspaceID,
+		"config":       snk.Config,
 	}})
 	return err
 }
@@ -587,12 +748,7 @@ func (s *mongoStorage) ListUsers(ctx context.Context, filter storage.CommonFilte
 	query := bson.M{}
 
 	if filter.Search != "" {
-		query["$or"] = []bson.M{
-			{"_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"username": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"full_name": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"email": bson.M{"$regex": filter.Search, "$options": "i"}},
-		}
+		query["$or"] = searchFilter(filter.Search, "_id", "username", "full_name", "email", "role")
 	}
 
 	total, err := coll.CountDocuments(ctx, query)
@@ -730,11 +886,7 @@ func (s *mongoStorage) ListVHosts(ctx context.Context, filter storage.CommonFilt
 	query := bson.M{}
 
 	if filter.Search != "" {
-		query["$or"] = []bson.M{
-			{"_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"name": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"description": bson.M{"$regex": filter.Search, "$options": "i"}},
-		}
+		query["$or"] = searchFilter(filter.Search, "_id", "name", "description")
 	}
 
 	total, err := coll.CountDocuments(ctx, query)
@@ -774,7 +926,7 @@ func (s *mongoStorage) ListVHosts(ctx context.Context, filter storage.CommonFilt
 
 func (s *mongoStorage) CreateVHost(ctx context.Context, vhost storage.VHost) error {
 	if vhost.ID == "" {
-		vhost.ID = uuid.New().String()
+		vhost.ID = vhost.Name
 	}
 	coll := s.db.Collection("vhosts")
 	_, err := coll.InsertOne(ctx, bson.M{
@@ -824,19 +976,11 @@ func (s *mongoStorage) ListWorkflows(ctx context.Context, filter storage.CommonF
 	query := bson.M{}
 
 	if filter.Search != "" {
-		query["$or"] = []bson.M{
-			{"_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"name": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"vhost": bson.M{"$regex": filter.Search, "$options": "i"}},
-		}
+		query["name"] = bson.M{"$regex": regexp.QuoteMeta(filter.Search), "$options": "i"}
 	}
-
-	if filter.VHost != "" {
-		query["vhost"] = filter.VHost
-	}
-
-	if filter.WorkspaceID != "" {
-		query["workspace_id"] = filter.WorkspaceID
+	applyCommonFilter(query, filter)
+	if filter.OwnerID != "" {
+		query["owner_id"] = filter.OwnerID
 	}
 
 	total, err := coll.CountDocuments(ctx, query)
@@ -951,6 +1095,9 @@ func (s *mongoStorage) CreateWorkflow(ctx context.Context, wf storage.Workflow)
 		"cpu_request":         wf.CPURequest,
 		"memory_request":      wf.MemoryRequest,
 		"throughput_request":  wf.ThroughputRequest,
+		"total_processed":     wf.TotalProcessed,
+		"total_errors":        wf.TotalErrors,
+		"total_lag":           wf.TotalLag,
 	})
 	return err
 }
@@ -986,6 +1133,9 @@ func (s *mongoStorage) UpdateWorkflow(ctx context.Context, wf storage.Workflow)
 		"cpu_request":         wf.CPURequest,
 		"memory_request":      wf.MemoryRequest,
 		"throughput_request":  wf.ThroughputRequest,
+		"total_processed":     wf.TotalProcessed,
+		"total_errors":        wf.TotalErrors,
+		"total_lag":           wf.TotalLag,
 	}})
 	return err
 }
@@ -1034,11 +1184,7 @@ func (s *mongoStorage) ListWorkers(ctx context.Context, filter storage.CommonFil
 	query := bson.M{}
 
 	if filter.Search != "" {
-		query["$or"] = []bson.M{
-			{"_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"name": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"host": bson.M{"$regex": filter.Search, "$options": "i"}},
-		}
+		query["$or"] = searchFilter(filter.Search, "_id", "name", "host", "description")
 	}
 
 	total, err := coll.CountDocuments(ctx, query)
@@ -1148,45 +1294,9 @@ func (s *mongoStorage) GetWorker(ctx context.Context, id string) (storage.Worker
 
 func (s *mongoStorage) ListLogs(ctx context.Context, filter storage.LogFilter) ([]storage.Log, int, error) {
 	coll := s.db.Collection("logs")
-	query := bson.M{}
-
-	// Time bounds (if provided)
-	if !filter.Since.IsZero() || !filter.Until.IsZero() {
-		ts := bson.M{}
-		if !filter.Since.IsZero() {
-			ts["$gte"] = filter.Since
-		}
-		if !filter.Until.IsZero() {
-			ts["$lt"] = filter.Until
-		}
-		query["timestamp"] = ts
-	}
-
-	if filter.SourceID != "" {
-		query["source_id"] = filter.SourceID
-	}
-	if filter.SinkID != "" {
-		query["sink_id"] = filter.SinkID
-	}
-	if filter.WorkflowID != "" {
-		query["workflow_id"] = filter.WorkflowID
-	}
-	if filter.Level != "" {
-		query["level"] = filter.Level
-	}
-	if filter.Action != "" {
-		query["action"] = filter.Action
-	}
+	query := logQuery(filter)
 	if filter.Search != "" {
-		query["$or"] = []bson.M{
-			{"message": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"data": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"source_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"sink_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"workflow_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"user_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"username": bson.M{"$regex": filter.Search, "$options": "i"}},
-		}
+		query["$or"] = searchFilter(filter.Search, "message", "action", "source_id", "sink_id", "workflow_id")
 	}
 
 	total, err := coll.CountDocuments(ctx, query)
@@ -1235,19 +1345,7 @@ func (s *mongoStorage) CreateLog(ctx context.Context, l storage.Log) error {
 	}
 
 	coll := s.db.Collection("logs")
-	_, err := coll.InsertOne(ctx, bson.M{
-		"_id":         l.ID,
-		"timestamp":   l.Timestamp,
-		"level":       l.Level,
-		"message":     l.Message,
-		"action":      l.Action,
-		"source_id":   l.SourceID,
-		"sink_id":     l.SinkID,
-		"workflow_id": l.WorkflowID,
-		"user_id":     l.UserID,
-		"username":    l.Username,
-		"data":        l.Data,
-	})
+	_, err := coll.InsertOne(ctx, logDocument(l))
 	return err
 }
 
@@ -1263,19 +1361,7 @@ func (s *mongoStorage) CreateLogs(ctx context.Context, logs []storage.Log) error
 		if l.Timestamp.IsZero() {
 			l.Timestamp = time.Now()
 		}
-		docs = append(docs, bson.M{
-			"_id":         l.ID,
-			"timestamp":   l.Timestamp,
-			"level":       l.Level,
-			"message":     l.Message,
-			"action":      l.Action,
-			"source_id":   l.SourceID,
-			"sink_id":     l.SinkID,
-			"workflow_id": l.WorkflowID,
-			"user_id":     l.UserID,
-			"username":    l.Username,
-			"data":        l.Data,
-		})
+		docs = append(docs, logDocument(l))
 	}
 	coll := s.db.Collection("logs")
 	_, err := coll.InsertMany(ctx, docs)
@@ -1284,8 +1370,51 @@ func (s *mongoStorage) CreateLogs(ctx context.Context, logs []storage.Log) error
 
 func (s *mongoStorage) DeleteLogs(ctx context.Context, filter storage.LogFilter) error {
 	coll := s.db.Collection("logs")
-	query := bson.M{}
+	query := logQuery(filter)
+	_, err := coll.DeleteMany(ctx, query)
+	return err
+}
+
+// logDocument maps a log to its stored form. Optional references are left
+// out when empty so that WithoutWorkflow can match on a missing workflow_id,
+// mirroring the NULL columns of the SQL backend.
+func logDocument(l storage.Log) bson.M {
+	doc := bson.M{
+		"_id":       l.ID,
+		"timestamp": l.Timestamp,
+		"level":     l.Level,
+		"message":   l.Message,
+	}
+	for k, v := range map[string]string{
+		"action":      l.Action,
+		"source_id":   l.SourceID,
+		"sink_id":     l.SinkID,
+		"workflow_id": l.WorkflowID,
+		"user_id":     l.UserID,
+		"username":    l.Username,
+		"data":        l.Data,
+	} {
+		if v != "" {
+			doc[k] = v
+		}
+	}
+	return doc
+}
 
+// logQuery builds the filter shared by ListLogs and DeleteLogs. Search is
+// handled by the caller since only listing supports it.
+func logQuery(filter storage.LogFilter) bson.M {
+	query := bson.M{}
+	if !filter.Since.IsZero() || !filter.Until.IsZero() {
+		ts := bson.M{}
+		if !filter.Since.IsZero() {
+			ts["$gte"] = filter.Since
+		}
+		if !filter.Until.IsZero() {
+			ts["$lt"] = filter.Until
+		}
+		query["timestamp"] = ts
+	}
 	if filter.SourceID != "" {
 		query["source_id"] = filter.SourceID
 	}
@@ -1295,25 +1424,16 @@ func (s *mongoStorage) DeleteLogs(ctx context.Context, filter storage.LogFilter)
 	if filter.WorkflowID != "" {
 		query["workflow_id"] = filter.WorkflowID
 	}
+	if filter.WithoutWorkflow {
+		query["workflow_id"] = nil
+	}
 	if filter.Level != "" {
 		query["level"] = filter.Level
 	}
 	if filter.Action != "" {
 		query["action"] = filter.Action
 	}
-	if !filter.Since.IsZero() || !filter.Until.IsZero() {
-		ts := bson.M{}
-		if !filter.Since.IsZero() {
-			ts["$gte"] = filter.Since
-		}
-		if !filter.Until.IsZero() {
-			ts["$lt"] = filter.Until
-		}
-		query["timestamp"] = ts
-	}
-
-	_, err := coll.DeleteMany(ctx, query)
-	return err
+	return query
 }
 
 func (s *mongoStorage) PurgeLogs(ctx context.Context, before time.Time) error {
@@ -1370,11 +1490,42 @@ func (s *mongoStorage) PurgeAuditLogs(ctx context.Context, before time.Time) err
 }
 
 func (s *mongoStorage) PurgeMessageTraces(ctx context.Context, before time.Time) error {
-	coll := s.db.Collection("trace_steps")
-	_, err := coll.DeleteMany(ctx, bson.M{"timestamp": bson.M{"$lt": before}})
-	return err
+	// Traces are stored one document per message with the steps embedded, so
+	// purge the expired steps and drop traces that have none left.
+	coll := s.db.Collection("message_traces")
+	cursor, err := coll.Find(ctx, bson.M{"steps.timestamp": bson.M{"$lt": before}})
+	if err != nil {
+		return err
+	}
+	var traces []storage.MessageTrace
+	err = cursor.All(ctx, &traces)
+	if err != nil {
+		return err
+	}
+	for _, tr := range traces {
+		kept := tr.Steps[:0]
+		for _, st := range tr.Steps {
+			if !st.Timestamp.Before(before) {
+				kept = append(kept, st)
+			}
+		}
+		filter := bson.M{"workflow_id": tr.WorkflowID, "message_id": tr.MessageID}
+		if len(kept) == 0 {
+			_, err = coll.DeleteOne(ctx, filter)
+		} else {
+			sortTraceSteps(kept)
+			_, err = coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"steps": kept, "created_at": kept[0].Timestamp}})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+func sortTraceSteps(steps []hermod.TraceStep) {
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Timestamp.Before(steps[j].Timestamp) })
+}
 func (s *mongoStorage) CreateWebhookRequest(ctx context.Context, req storage.WebhookRequest) error {
 	if req.ID == "" {
 		req.ID = uuid.NewString()
@@ -1506,7 +1657,9 @@ func (s *mongoStorage) ListFormSubmissions(ctx context.Context, filter storage.F
 	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
 	if filter.Limit > 0 {
 		opts.SetLimit(int64(filter.Limit))
-		opts.SetSkip(int64((filter.Page - 1) * filter.Limit))
+		if filter.Page > 0 {
+			opts.SetSkip(int64((filter.Page - 1) * filter.Limit))
+		}
 	}
 
 	total, err := coll.CountDocuments(ctx, query)
@@ -1531,6 +1684,9 @@ func (s *mongoStorage) GetFormSubmission(ctx context.Context, id string) (storag
 	coll := s.db.Collection("form_submissions")
 	var sub storage.FormSubmission
 	err := coll.FindOne(ctx, bson.M{"id": id}).Decode(&sub)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return storage.FormSubmission{}, storage.ErrNotFound
+	}
 	return sub, err
 }
 
@@ -1558,13 +1714,7 @@ func (s *mongoStorage) ListAuditLogs(ctx context.Context, filter storage.AuditFi
 	query := bson.M{}
 
 	if filter.Search != "" {
-		query["$or"] = []bson.M{
-			{"_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"username": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"action": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"entity_id": bson.M{"$regex": filter.Search, "$options": "i"}},
-			{"payload": bson.M{"$regex": filter.Search, "$options": "i"}},
-		}
+		query["$or"] = searchFilter(filter.Search, "_id", "username", "action", "entity_id", "payload")
 	}
 	if filter.UserID != "" {
 		query["user_id"] = filter.UserID
@@ -1701,7 +1851,7 @@ func (s *mongoStorage) GetSchema(ctx context.Context, name string, version int)
 	var sc storage.Schema
 	err := coll.FindOne(ctx, filter).Decode(&sc)
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return storage.Schema{}, fmt.Errorf("schema %s version %d not found", name, version)
+		return storage.Schema{}, fmt.Errorf("%w: schema %s version %d", storage.ErrNotFound, name, version)
 	}
 	return sc, err
 }
@@ -1713,11 +1863,27 @@ func (s *mongoStorage) GetLatestSchema(ctx context.Context, name string) (storag
 	var sc storage.Schema
 	err := coll.FindOne(ctx, filter, opts).Decode(&sc)
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return storage.Schema{}, fmt.Errorf("schema %s not found", name)
+		return storage.Schema{}, fmt.Errorf("%w: schema %s", storage.ErrNotFound, name)
 	}
 	return sc, err
 }
 
+func (s *mongoStorage) ListSchemasByID(ctx context.Context, schemaID int) ([]storage.Schema, error) {
+	coll := s.db.Collection("schemas")
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "version", Value: 1}})
+	cursor, err := coll.Find(ctx, bson.M{"schema_id": schemaID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	schemas := []storage.Schema{}
+	if err := cursor.All(ctx, &schemas); err != nil {
+		return nil, err
+	}
+	return schemas, nil
+}
+
 func (s *mongoStorage) CreateSchema(ctx context.Context, sc storage.Schema) error {
 	coll := s.db.Collection("schemas")
 	if sc.ID == "" {
@@ -1726,21 +1892,86 @@ func (s *mongoStorage) CreateSchema(ctx context.Context, sc storage.Schema) erro
 	if sc.CreatedAt.IsZero() {
 		sc.CreatedAt = time.Now()
 	}
+	if sc.SchemaID == 0 {
+		id, err := s.schemaID(ctx, sc.Type, sc.Content)
+		if err != nil {
+			return err
+		}
+		sc.SchemaID = id
+	}
 	_, err := coll.InsertOne(ctx, sc)
 	return err
 }
 
+// schemaID returns the ID of the schema with this type and content,
+// assigning the next free one on first use. Fingerprints are the _id and IDs
+// are unique, so when two registrations race for an ID the loser looks its
+// schema up again and retries.
+func (s *mongoStorage) schemaID(ctx context.Context, schemaType, content string) (int, error) {
+	coll := s.db.Collection("schema_ids")
+	fingerprint := storage.SchemaFingerprint(schemaType, content)
+	var err error
+	for range 5 {
+		var rec struct {
+			ID int `bson:"id"`
+		}
+		err = coll.FindOne(ctx, bson.M{"_id": fingerprint}).Decode(&rec)
+		if err == nil {
+			return rec.ID, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, err
+		}
+		err = coll.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})).Decode(&rec)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, err
+		}
+		id := rec.ID + 1
+		if _, err = coll.InsertOne(ctx, bson.M{"_id": fingerprint, "id": id}); err == nil {
+			return id, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("failed to assign a schema id: %w", err)
+}
+
+// numberSchemas assigns IDs to schemas stored before they had one.
+func (s *mongoStorage) numberSchemas(ctx context.Context) error {
+	coll := s.db.Collection("schemas")
+	filter := bson.M{"schema_id": bson.M{"$in": bson.A{nil, 0}}}
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	var unnumbered []storage.Schema
+	if err := cursor.All(ctx, &unnumbered); err != nil {
+		return err
+	}
+	for _, sc := range unnumbered {
+		id, err := s.schemaID(ctx, sc.Type, sc.Content)
+		if err != nil {
+			return err
+		}
+		if _, err := coll.UpdateOne(ctx, bson.M{"id": sc.ID}, bson.M{"$set": bson.M{"schema_id": id}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *mongoStorage) RecordTraceStep(ctx context.Context, workflowID, messageID string, step hermod.TraceStep) error {
 	coll := s.db.Collection("message_traces")
 	filter := bson.M{"workflow_id": workflowID, "message_id": messageID}
 
 	update := bson.M{
 		"$push": bson.M{"steps": step},
+		"$min":  bson.M{"created_at": step.Timestamp},
 		"$setOnInsert": bson.M{
 			"id":          uuid.New().String(),
 			"workflow_id": workflowID,
 			"message_id":  messageID,
-			"created_at":  time.Now(),
 		},
 	}
 	opts := options.UpdateOne().SetUpsert(true)
@@ -1765,16 +1996,25 @@ func (s *mongoStorage) GetMessageTrace(ctx context.Context, workflowID, messageI
 	filter := bson.M{"workflow_id": workflowID, "message_id": messageID}
 	var tr storage.MessageTrace
 	err := coll.FindOne(ctx, filter).Decode(&tr)
-	if errors.Is(err, mongo.ErrNoDocuments) {
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && len(tr.Steps) == 0) {
 		return storage.MessageTrace{}, storage.ErrNotFound
 	}
-	return tr, err
+	if err != nil {
+		return storage.MessageTrace{}, err
+	}
+	sortTraceSteps(tr.Steps)
+	tr.CreatedAt = tr.Steps[0].Timestamp
+	return tr, nil
 }
-
 func (s *mongoStorage) ListMessageTraces(ctx context.Context, workflowID string, limit, offset int) ([]storage.MessageTrace, error) {
 	coll := s.db.Collection("message_traces")
 	filter := bson.M{"workflow_id": workflowID}
-	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	// Listings carry only the message identity and start time, like the SQL
+	// backend; the steps are loaded with GetMessageTrace.
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"steps": 0})
 	if offset > 0 {
 		opts.SetSkip(int64(offset))
 	}
@@ -1947,21 +2187,69 @@ func (s *mongoStorage) GetLineage(ctx context.Context) ([]storage.LineageEdge, e
 }
 
 func (s *mongoStorage) ListPlugins(ctx context.Context) ([]storage.Plugin, error) {
-	return nil, nil
+	cursor, err := s.db.Collection("plugins").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	plugins := []storage.Plugin{}
+	if err := cursor.All(ctx, &plugins); err != nil {
+		return nil, err
+	}
+	return plugins, nil
 }
 
 func (s *mongoStorage) GetPlugin(ctx context.Context, id string) (storage.Plugin, error) {
-	return storage.Plugin{}, nil
+	var p storage.Plugin
+	err := s.db.Collection("plugins").FindOne(ctx, bson.M{"id": id}).Decode(&p)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return storage.Plugin{}, storage.ErrNotFound
+	}
+	return p, err
 }
 
 func (s *mongoStorage) InstallPlugin(ctx context.Context, id string) error {
-	return nil
+	return s.setPluginInstalled(ctx, id, true)
 }
 
 func (s *mongoStorage) UninstallPlugin(ctx context.Context, id string) error {
+	return s.setPluginInstalled(ctx, id, false)
+}
+
+func (s *mongoStorage) setPluginInstalled(ctx context.Context, id string, installed bool) error {
+	var installedAt *time.Time
+	if installed {
+		now := time.Now()
+		installedAt = &now
+	}
+	res, err := s.db.Collection("plugins").UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{
+		"installed":    installed,
+		"installed_at": installedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
 	return nil
 }
 
+func (s *mongoStorage) seedPlugins(ctx context.Context) error {
+	coll := s.db.Collection("plugins")
+	n, err := coll.CountDocuments(ctx, bson.M{})
+	if err != nil || n > 0 {
+		return err
+	}
+	var docs []any
+	for _, p := range storage.DefaultPlugins() {
+		docs = append(docs, p)
+	}
+	_, err = coll.InsertMany(ctx, docs)
+	return err
+}
+
 func (s *mongoStorage) GetDashboardStats(ctx context.Context, vhost string) (storage.DashboardStats, error) {
 	var stats storage.DashboardStats
 
@@ -1985,7 +2273,10 @@ func (s *mongoStorage) GetDashboardStats(ctx context.Context, vhost string) (sto
 			"totalErrors":     bson.M{"$sum": "$total_errors"},
 			"totalLag":        bson.M{"$sum": "$total_lag"},
 			"activeWorkflows": bson.M{"$sum": bson.M{"$cond": []any{bson.M{"$eq": []string{"$status", "running"}}, 1, 0}}},
-			"failedWorkflows": bson.M{"$sum": bson.M{"$cond": []any{bson.M{"$in": []any{"$status", []string{"failed", "error"}}}, 1, 0}}},
+			"failedWorkflows": bson.M{"$sum": bson.M{"$cond": []any{bson.M{"$or": []any{
+				bson.M{"$in": []any{"$status", []string{"failed", "error"}}},
+				bson.M{"$regexMatch": bson.M{"input": bson.M{"$ifNull": []any{"$status", ""}}, "regex": "^error:"}},
+			}}, 1, 0}}},
 		}},
 	}
 	wfCursor, err := wfColl.Aggregate(ctx, wfPipeline)
@@ -2036,3 +2327,129 @@ func (s *mongoStorage) GetDashboardStats(ctx context.Context, vhost string) (sto
 
 	return stats, nil
 }
+
+func deadLetterQuery(filter storage.DeadLetterFilter) bson.M {
+	q := bson.M{}
+	if filter.WorkflowID != "" {
+		q["workflow_id"] = filter.WorkflowID
+	}
+	if filter.SinkID != "" {
+		q["sink_id"] = filter.SinkID
+	}
+	if filter.ErrorClass != "" {
+		q["error_class"] = filter.ErrorClass
+	}
+	if filter.Status != "" {
+		q["status"] = filter.Status
+	}
+	if !filter.Since.IsZero() || !filter.Until.IsZero() {
+		created := bson.M{}
+		if !filter.Since.IsZero() {
+			created["$gte"] = filter.Since
+		}
+		if !filter.Until.IsZero() {
+			created["$lte"] = filter.Until
+		}
+		q["created_at"] = created
+	}
+	if filter.Search != "" {
+		re := bson.M{"$regex": regexp.QuoteMeta(filter.Search), "$options": "i"}
+		q["$or"] = bson.A{bson.M{"message_id": re}, bson.M{"error": re}}
+	}
+	return q
+}
+
+func (s *mongoStorage) ListDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) ([]storage.DeadLetter, int, error) {
+	coll := s.db.Collection("dead_letters")
+	q := deadLetterQuery(filter)
+	total64, err := coll.CountDocuments(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+		if filter.Page > 0 {
+			opts.SetSkip(int64((filter.Page - 1) * filter.Limit))
+		}
+	}
+	cur, err := coll.Find(ctx, q, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+	var letters []storage.DeadLetter
+	if err := cur.All(ctx, &letters); err != nil {
+		return nil, 0, err
+	}
+	return letters, int(total64), nil
+}
+
+func (s *mongoStorage) CreateDeadLetter(ctx context.Context, dl storage.DeadLetter) error {
+	if dl.ID == "" {
+		dl.ID = uuid.New().String()
+	}
+	if dl.CreatedAt.IsZero() {
+		dl.CreatedAt = time.Now()
+	}
+	if dl.Status == "" {
+		dl.Status = "pending"
+	}
+	_, err := s.db.Collection("dead_letters").InsertOne(ctx, dl)
+	return err
+}
+
+func (s *mongoStorage) GetDeadLetter(ctx context.Context, id string) (storage.DeadLetter, error) {
+	var dl storage.DeadLetter
+	err := s.db.Collection("dead_letters").FindOne(ctx, bson.M{"id": id}).Decode(&dl)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return dl, storage.ErrNotFound
+	}
+	return dl, err
+}
+
+func (s *mongoStorage) UpdateDeadLetter(ctx context.Context, dl storage.DeadLetter) error {
+	upd := bson.M{
+		"$set": bson.M{
+			"sink_id":           dl.SinkID,
+			"operation":         dl.Operation,
+			"table":             dl.Table,
+			"error_class":       dl.ErrorClass,
+			"error":             dl.Error,
+			"payload":           dl.Payload,
+			"metadata":          dl.Metadata,
+			"data":              dl.Data,
+			"status":            dl.Status,
+			"replay_count":      dl.ReplayCount,
+			"last_replay_error": dl.LastReplayError,
+			"replayed_at":       dl.ReplayedAt,
+		},
+	}
+	res, err := s.db.Collection("dead_letters").UpdateOne(ctx, bson.M{"id": dl.ID}, upd)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoStorage) DeleteDeadLetter(ctx context.Context, id string) error {
+	res, err := s.db.Collection("dead_letters").DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoStorage) PurgeDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) (int, error) {
+	res, err := s.db.Collection("dead_letters").DeleteMany(ctx, deadLetterQuery(filter))
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
@@ -0,0 +1,19 @@
+package pebble
+
+import (
+	"testing"
+
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/storage/storagetest"
+)
+
+func TestPebbleStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		s, err := NewPebbleStorage(t.TempDir())
+		if err != nil {
+			t.Fatalf("open pebble: %v", err)
+		}
+		t.Cleanup(func() { s.(*pebbleStorage).Close() })
+		return s
+	})
+}
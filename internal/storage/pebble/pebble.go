@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cockroachdb/pebble"
@@ -26,10 +28,160 @@ const (
 	defaultMemTableBytes uint64 = 8 << 20 // 8 MB
 	// defaultMaxOpenFiles caps the number of open SSTable file descriptors.
 	defaultMaxOpenFiles = 256
+
+	// maxNanos is subtracted from timestamps to build keys that sort newest
+	// first. It must exceed every UnixNano value for the keys to stay
+	// non-negative and fixed-width.
+	maxNanos int64 = math.MaxInt64
+	// webhookRetention is how many requests are kept per webhook path.
+	webhookRetention = 50
+	// defaultLeaseTTL applies when a lease is requested without a TTL.
+	defaultLeaseTTL = 30
 )
 
 type pebbleStorage struct {
 	db *pebble.DB
+	// mu serializes read-modify-write sequences (index maintenance, leases,
+	// trace appends) so concurrent writers cannot interleave between the read
+	// and the batch commit.
+	mu sync.Mutex
+}
+
+var (
+	sources = table[storage.Source]{
+		name: "source",
+		id:   func(v storage.Source) string { return v.ID },
+		indexes: map[string]func(storage.Source) string{
+			"vhost":     func(v storage.Source) string { return v.VHost },
+			"workspace": func(v storage.Source) string { return v.WorkspaceID },
+			"worker":    func(v storage.Source) string { return v.WorkerID },
+			"active":    func(v storage.Source) string { return boolValue(v.Active) },
+		},
+	}
+	sinks = table[storage.Sink]{
+		name: "sink",
+		id:   func(v storage.Sink) string { return v.ID },
+		indexes: map[string]func(storage.Sink) string{
+			"vhost":     func(v storage.Sink) string { return v.VHost },
+			"workspace": func(v storage.Sink) string { return v.WorkspaceID },
+			"worker":    func(v storage.Sink) string { return v.WorkerID },
+			"active":    func(v storage.Sink) string { return boolValue(v.Active) },
+		},
+	}
+	users = table[storage.User]{
+		name: "user",
+		id:   func(v storage.User) string { return v.ID },
+		indexes: map[string]func(storage.User) string{
+			"username": func(v storage.User) string { return v.Username },
+			"email":    func(v storage.User) string { return v.Email },
+		},
+	}
+	vhosts = table[storage.VHost]{
+		name: "vhost",
+		id:   func(v storage.VHost) string { return v.ID },
+	}
+	workspaces = table[storage.Workspace]{
+		name: "workspace",
+		id:   func(v storage.Workspace) string { return v.ID },
+	}
+	workflows = table[storage.Workflow]{
+		name: "workflow",
+		id:   func(v storage.Workflow) string { return v.ID },
+		indexes: map[string]func(storage.Workflow) string{
+			"vhost":     func(v storage.Workflow) string { return v.VHost },
+			"workspace": func(v storage.Workflow) string { return v.WorkspaceID },
+			"worker":    func(v storage.Workflow) string { return v.WorkerID },
+			"owner":     func(v storage.Workflow) string { return v.OwnerID },
+			"active":    func(v storage.Workflow) string { return boolValue(v.Active) },
+		},
+	}
+	workers = table[storage.Worker]{
+		name: "worker",
+		id:   func(v storage.Worker) string { return v.ID },
+	}
+	webhookRequests = table[storage.WebhookRequest]{
+		name: "webhook",
+		id:   func(v storage.WebhookRequest) string { return v.ID },
+		indexes: map[string]func(storage.WebhookRequest) string{
+			"path": func(v storage.WebhookRequest) string { return v.Path },
+		},
+	}
+	formSubmissions = table[storage.FormSubmission]{
+		name: "form",
+		id:   func(v storage.FormSubmission) string { return v.ID },
+		indexes: map[string]func(storage.FormSubmission) string{
+			"path":   func(v storage.FormSubmission) string { return v.Path },
+			"status": func(v storage.FormSubmission) string { return v.Status },
+		},
+	}
+	// Schemas and workflow versions are keyed by their parent name and a
+	// zero-padded version, so one parent's versions are a prefix in version
+	// order.
+	schemas = table[storage.Schema]{
+		name: "schema",
+		id:   func(v storage.Schema) string { return versionID(v.Name, v.Version) },
+		indexes: map[string]func(storage.Schema) string{
+			"schemaid": func(v storage.Schema) string { return strconv.Itoa(v.SchemaID) },
+		},
+	}
+	// schemaIDs holds the ID assigned to each distinct schema.
+	schemaIDs = table[schemaIDRecord]{
+		name: "schemaid",
+		id:   func(v schemaIDRecord) string { return v.Fingerprint },
+	}
+	workflowVersions = table[storage.WorkflowVersion]{
+		name: "wfversion",
+		id:   func(v storage.WorkflowVersion) string { return versionID(v.WorkflowID, v.Version) },
+	}
+	outboxItems = table[storage.OutboxItem]{
+		name: "outbox",
+		id:   func(v storage.OutboxItem) string { return v.ID },
+		indexes: map[string]func(storage.OutboxItem) string{
+			"status": func(v storage.OutboxItem) string { return v.Status },
+		},
+	}
+	plugins = table[storage.Plugin]{
+		name: "plugin",
+		id:   func(v storage.Plugin) string { return v.ID },
+	}
+	approvals = table[storage.Approval]{
+		name: "approval",
+		id:   func(v storage.Approval) string { return v.ID },
+		indexes: map[string]func(storage.Approval) string{
+			"workflow": func(v storage.Approval) string { return v.WorkflowID },
+			"status":   func(v storage.Approval) string { return v.Status },
+		},
+	}
+	suspendedMessages = table[storage.SuspendedMessage]{
+		name: "suspended",
+		id:   func(v storage.SuspendedMessage) string { return v.ID },
+		indexes: map[string]func(storage.SuspendedMessage) string{
+			"workflow": func(v storage.SuspendedMessage) string { return v.WorkflowID },
+			"wait":     func(v storage.SuspendedMessage) string { return v.WaitKey },
+		},
+	}
+	leases = table[storage.Lease]{
+		name: "lease",
+		id:   func(v storage.Lease) string { return v.Name },
+	}
+	deadLetters = table[storage.DeadLetter]{
+		name: "deadletter",
+		id:   func(v storage.DeadLetter) string { return v.ID },
+		indexes: map[string]func(storage.DeadLetter) string{
+			"workflow": func(v storage.DeadLetter) string { return v.WorkflowID },
+			"status":   func(v storage.DeadLetter) string { return v.Status },
+		},
+	}
+)
+
+// schemaIDRecord maps a schema fingerprint to the schema's ID.
+type schemaIDRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	ID          int    `json:"id"`
+}
+
+func versionID(parent string, version int) string {
+	return fmt.Sprintf("%s%s%010d", parent, indexSep, version)
 }
 
 func NewPebbleStorage(path string) (storage.Storage, error) {
@@ -79,7 +231,42 @@ func cacheBytesFromEnv() int64 {
 	return defaultCacheBytes
 }
 
+// Init seeds the plugin catalogue. Plugins that already exist are left
+// untouched so re-running Init keeps their installation state.
 func (s *pebbleStorage) Init(ctx context.Context) error {
+	for _, p := range storage.DefaultPlugins() {
+		if _, err := plugins.get(s.db, p.ID); err == nil {
+			continue
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		if err := insert(s, plugins, p); err != nil {
+			return err
+		}
+	}
+	return s.numberSchemas()
+}
+
+// numberSchemas assigns IDs to schemas stored before they had one.
+func (s *pebbleStorage) numberSchemas() error {
+	// Records stored before the index existed have no entry in it, so the
+	// whole table is scanned.
+	all, err := schemas.find(s.db)
+	if err != nil {
+		return err
+	}
+	for _, sc := range all {
+		if sc.SchemaID != 0 {
+			continue
+		}
+		id, err := s.schemaID(sc.Type, sc.Content)
+		if err != nil {
+			return err
+		}
+		if err := update(s, schemas, versionID(sc.Name, sc.Version), func(v *storage.Schema) { v.SchemaID = id }); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -94,655 +281,1446 @@ func (s *pebbleStorage) Close() error {
 	return nil
 }
 
-// Log methods
+// Source methods
 
-func (s *pebbleStorage) CreateLog(ctx context.Context, l storage.Log) error {
-	if l.ID == "" {
-		l.ID = uuid.New().String()
+func (s *pebbleStorage) ListSources(ctx context.Context, filter storage.CommonFilter) ([]storage.Source, int, error) {
+	all, err := sources.find(s.db, commonConds(filter)...)
+	if err != nil {
+		return nil, 0, err
 	}
-	if l.Timestamp.IsZero() {
-		l.Timestamp = time.Now()
+	var list []storage.Source
+	for _, src := range all {
+		if containsFold(filter.Search, src.ID, src.Name, src.Type, src.VHost) {
+			list = append(list, src)
+		}
 	}
-	data, err := json.Marshal(l)
-	if err != nil {
-		return err
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
+}
+
+func (s *pebbleStorage) CreateSource(ctx context.Context, src storage.Source) error {
+	if src.ID == "" {
+		src.ID = uuid.New().String()
 	}
-	// Key: l:<reverse_timestamp>:<uuid>
-	key := fmt.Sprintf("l:%020d:%s", 999999999999999999-l.Timestamp.UnixNano(), l.ID)
-	return s.db.Set([]byte(key), data, pebble.Sync)
+	return insert(s, sources, src)
 }
 
-func (s *pebbleStorage) CreateLogs(ctx context.Context, logs []storage.Log) error {
-	batch := s.db.NewBatch()
-	defer batch.Close()
+func (s *pebbleStorage) UpdateSource(ctx context.Context, src storage.Source) error {
+	return update(s, sources, src.ID, func(v *storage.Source) { *v = src })
+}
 
-	for _, l := range logs {
-		if l.ID == "" {
-			l.ID = uuid.New().String()
-		}
-		if l.Timestamp.IsZero() {
-			l.Timestamp = time.Now()
-		}
-		data, err := json.Marshal(l)
-		if err != nil {
-			return err
-		}
-		key := fmt.Sprintf("l:%020d:%s", 999999999999999999-l.Timestamp.UnixNano(), l.ID)
-		if err := batch.Set([]byte(key), data, pebble.Sync); err != nil {
-			return err
-		}
-	}
-	return batch.Commit(pebble.Sync)
+func (s *pebbleStorage) UpdateSourceStatus(ctx context.Context, id string, status string) error {
+	return update(s, sources, id, func(v *storage.Source) { v.Status = status })
 }
 
-func (s *pebbleStorage) ListLogs(ctx context.Context, filter storage.LogFilter) ([]storage.Log, int, error) {
-	var logs []storage.Log
-	iter, err := s.db.NewIter(&pebble.IterOptions{
-		LowerBound: []byte("l:"),
-		UpperBound: []byte("m:"),
-	})
-	if err != nil {
-		return nil, 0, err
-	}
-	defer iter.Close()
+func (s *pebbleStorage) UpdateSourceState(ctx context.Context, id string, state map[string]string) error {
+	return update(s, sources, id, func(v *storage.Source) { v.State = state })
+}
 
-	for iter.First(); iter.Valid(); iter.Next() {
-		var l storage.Log
-		if err := json.Unmarshal(iter.Value(), &l); err != nil {
-			continue
-		}
+func (s *pebbleStorage) DeleteSource(ctx context.Context, id string) error {
+	return remove(s, sources, id)
+}
 
-		// Apply filters
-		if !filter.Since.IsZero() && l.Timestamp.Before(filter.Since) {
-			continue
-		}
-		if !filter.Until.IsZero() && !l.Timestamp.Before(filter.Until) {
-			continue
-		}
-		if filter.SourceID != "" && l.SourceID != filter.SourceID {
-			continue
-		}
-		if filter.SinkID != "" && l.SinkID != filter.SinkID {
-			continue
-		}
-		if filter.WorkflowID != "" && l.WorkflowID != filter.WorkflowID {
-			continue
-		}
-		if filter.WithoutWorkflow && l.WorkflowID != "" {
-			continue
-		}
-		if filter.Level != "" && l.Level != filter.Level {
-			continue
-		}
-		if filter.Action != "" && l.Action != filter.Action {
-			continue
-		}
-		if filter.Search != "" {
-			search := strings.ToLower(filter.Search)
-			if !strings.Contains(strings.ToLower(l.Message), search) &&
-				!strings.Contains(strings.ToLower(l.Action), search) &&
-				!strings.Contains(strings.ToLower(l.SourceID), search) &&
-				!strings.Contains(strings.ToLower(l.SinkID), search) &&
-				!strings.Contains(strings.ToLower(l.WorkflowID), search) {
-				continue
-			}
-		}
+func (s *pebbleStorage) GetSource(ctx context.Context, id string) (storage.Source, error) {
+	return sources.get(s.db, id)
+}
 
-		logs = append(logs, l)
-	}
+// Sink methods
 
-	total := len(logs)
-	// Pagination
-	start := 0
-	if filter.Limit > 0 {
-		if filter.Page > 1 {
-			start = (filter.Page - 1) * filter.Limit
-		}
-		if start >= len(logs) {
-			return []storage.Log{}, total, nil
-		}
-		end := start + filter.Limit
-		if end > len(logs) {
-			end = len(logs)
+func (s *pebbleStorage) ListSinks(ctx context.Context, filter storage.CommonFilter) ([]storage.Sink, int, error) {
+	all, err := sinks.find(s.db, commonConds(filter)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	var list []storage.Sink
+	for _, snk := range all {
+		if containsFold(filter.Search, snk.ID, snk.Name, snk.Type, snk.VHost) {
+			list = append(list, snk)
 		}
-		logs = logs[start:end]
-	} else if len(logs) > 100 {
-		logs = logs[:100]
 	}
-
-	return logs, total, nil
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
 }
 
-func (s *pebbleStorage) DeleteLogs(ctx context.Context, filter storage.LogFilter) error {
-	iter, err := s.db.NewIter(&pebble.IterOptions{
-		LowerBound: []byte("l:"),
-		UpperBound: []byte("m:"),
-	})
-	if err != nil {
-		return err
+func (s *pebbleStorage) CreateSink(ctx context.Context, snk storage.Sink) error {
+	if snk.ID == "" {
+		snk.ID = uuid.New().String()
 	}
-	defer iter.Close()
+	return insert(s, sinks, snk)
+}
 
-	batch := s.db.NewBatch()
-	defer batch.Close()
-	for iter.First(); iter.Valid(); iter.Next() {
-		var l storage.Log
-		if err := json.Unmarshal(iter.Value(), &l); err != nil {
-			continue
-		}
+func (s *pebbleStorage) UpdateSink(ctx context.Context, snk storage.Sink) error {
+	return update(s, sinks, snk.ID, func(v *storage.Sink) { *v = snk })
+}
 
-		// Check if matches filter
-		matches := true
-		if !filter.Since.IsZero() && l.Timestamp.Before(filter.Since) {
-			matches = false
-		}
-		if !filter.Until.IsZero() && !l.Timestamp.Before(filter.Until) {
-			matches = false
-		}
-		if filter.SourceID != "" && l.SourceID != filter.SourceID {
-			matches = false
-		}
-		if filter.SinkID != "" && l.SinkID != filter.SinkID {
-			matches = false
-		}
-		if filter.WorkflowID != "" && l.WorkflowID != filter.WorkflowID {
-			matches = false
-		}
-		if filter.WithoutWorkflow && l.WorkflowID != "" {
-			matches = false
-		}
-		if filter.Level != "" && l.Level != filter.Level {
-			matches = false
-		}
-		if filter.Action != "" && l.Action != filter.Action {
-			matches = false
-		}
+func (s *pebbleStorage) UpdateSinkStatus(ctx context.Context, id string, status string) error {
+	return update(s, sinks, id, func(v *storage.Sink) { v.Status = status })
+}
 
-		if matches {
-			if err := batch.Delete(iter.Key(), pebble.Sync); err != nil {
-				return err
-			}
-		}
-	}
-	return batch.Commit(pebble.Sync)
+func (s *pebbleStorage) DeleteSink(ctx context.Context, id string) error {
+	return remove(s, sinks, id)
 }
 
-func (s *pebbleStorage) PurgeLogs(ctx context.Context, before time.Time) error {
-	startKey := fmt.Sprintf("l:%020d", 999999999999999999-before.UnixNano())
-	iter, err := s.db.NewIter(&pebble.IterOptions{
-		LowerBound: []byte(startKey),
-		UpperBound: []byte("m:"),
-	})
+func (s *pebbleStorage) GetSink(ctx context.Context, id string) (storage.Sink, error) {
+	return sinks.get(s.db, id)
+}
+
+// User methods
+
+func (s *pebbleStorage) ListUsers(ctx context.Context, filter storage.CommonFilter) ([]storage.User, int, error) {
+	all, err := users.find(s.db)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	defer iter.Close()
-
-	batch := s.db.NewBatch()
-	defer batch.Close()
-	for iter.First(); iter.Valid(); iter.Next() {
-		if err := batch.Delete(iter.Key(), pebble.Sync); err != nil {
-			return err
+	var list []storage.User
+	for _, u := range all {
+		if containsFold(filter.Search, u.ID, u.Username, u.FullName, u.Email, string(u.Role)) {
+			u.Password = ""
+			list = append(list, u)
 		}
 	}
-	return batch.Commit(pebble.Sync)
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
 }
 
-// Audit Log methods
-
-func (s *pebbleStorage) CreateAuditLog(ctx context.Context, a storage.AuditLog) error {
-	if a.ID == "" {
-		a.ID = uuid.New().String()
-	}
-	if a.Timestamp.IsZero() {
-		a.Timestamp = time.Now()
+func (s *pebbleStorage) CreateUser(ctx context.Context, user storage.User) error {
+	if user.ID == "" {
+		user.ID = uuid.New().String()
 	}
-	data, err := json.Marshal(a)
-	if err != nil {
+	if _, err := s.GetUserByUsername(ctx, user.Username); err == nil {
+		return fmt.Errorf("username %q already exists", user.Username)
+	} else if !errors.Is(err, storage.ErrNotFound) {
 		return err
 	}
-	// Key: a:<reverse_timestamp>:<uuid>
-	key := fmt.Sprintf("a:%020d:%s", 999999999999999999-a.Timestamp.UnixNano(), a.ID)
-	return s.db.Set([]byte(key), data, pebble.Sync)
+	return insert(s, users, user)
 }
 
-func (s *pebbleStorage) ListAuditLogs(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditLog, int, error) {
-	var logs []storage.AuditLog
-	iter, err := s.db.NewIter(&pebble.IterOptions{
-		LowerBound: []byte("a:"),
-		UpperBound: []byte("b:"),
-	})
-	if err != nil {
-		return nil, 0, err
-	}
-	defer iter.Close()
-
-	for iter.First(); iter.Valid(); iter.Next() {
-		var a storage.AuditLog
-		if err := json.Unmarshal(iter.Value(), &a); err != nil {
-			continue
+func (s *pebbleStorage) UpdateUser(ctx context.Context, user storage.User) error {
+	return update(s, users, user.ID, func(v *storage.User) {
+		password := v.Password
+		*v = user
+		if user.Password == "" {
+			v.Password = password
 		}
+	})
+}
 
-		if filter.From != nil && a.Timestamp.Before(*filter.From) {
-			continue
-		}
-		if filter.To != nil && !a.Timestamp.Before(*filter.To) {
-			continue
-		}
-		if filter.UserID != "" && a.UserID != filter.UserID {
-			continue
-		}
-		if filter.Action != "" && a.Action != filter.Action {
-			continue
-		}
-		if filter.EntityType != "" && a.EntityType != filter.EntityType {
-			continue
-		}
-		if filter.EntityID != "" && a.EntityID != filter.EntityID {
-			continue
-		}
+func (s *pebbleStorage) DeleteUser(ctx context.Context, id string) error {
+	return remove(s, users, id)
+}
 
-		logs = append(logs, a)
-	}
+func (s *pebbleStorage) GetUser(ctx context.Context, id string) (storage.User, error) {
+	return users.get(s.db, id)
+}
 
-	total := len(logs)
-	start := 0
-	if filter.Limit > 0 {
-		if filter.Page > 1 {
-			start = (filter.Page - 1) * filter.Limit
-		}
-		if start >= len(logs) {
-			return []storage.AuditLog{}, total, nil
-		}
-		end := start + filter.Limit
-		if end > len(logs) {
-			end = len(logs)
-		}
-		logs = logs[start:end]
-	}
+func (s *pebbleStorage) GetUserByUsername(ctx context.Context, username string) (storage.User, error) {
+	return s.userBy("username", username)
+}
 
-	return logs, total, nil
+func (s *pebbleStorage) GetUserByEmail(ctx context.Context, email string) (storage.User, error) {
+	return s.userBy("email", email)
 }
 
-func (s *pebbleStorage) PurgeAuditLogs(ctx context.Context, before time.Time) error {
-	startKey := fmt.Sprintf("a:%020d", 999999999999999999-before.UnixNano())
-	iter, err := s.db.NewIter(&pebble.IterOptions{
-		LowerBound: []byte(startKey),
-		UpperBound: []byte("b:"),
-	})
+func (s *pebbleStorage) userBy(index, value string) (storage.User, error) {
+	found, err := users.find(s.db, cond{index, value})
 	if err != nil {
-		return err
+		return storage.User{}, err
 	}
-	defer iter.Close()
-
-	batch := s.db.NewBatch()
-	defer batch.Close()
-	for iter.First(); iter.Valid(); iter.Next() {
-		if err := batch.Delete(iter.Key(), pebble.Sync); err != nil {
-			return err
-		}
+	if len(found) == 0 {
+		return storage.User{}, storage.ErrNotFound
 	}
-	return batch.Commit(pebble.Sync)
+	return found[0], nil
 }
 
-// Trace methods
+// VHost methods
 
-func (s *pebbleStorage) RecordTraceStep(ctx context.Context, workflowID, messageID string, step hermod.TraceStep) error {
-	key := fmt.Sprintf("t:%s:%s", workflowID, messageID)
-	val, closer, err := s.db.Get([]byte(key))
-	var trace storage.MessageTrace
-	if err == nil {
-		defer closer.Close()
-		_ = json.Unmarshal(val, &trace)
-	} else if errors.Is(err, pebble.ErrNotFound) {
-		trace = storage.MessageTrace{
-			WorkflowID: workflowID,
-			MessageID:  messageID,
-			CreatedAt:  time.Now(),
-		}
-	} else {
-		return err
+func (s *pebbleStorage) ListVHosts(ctx context.Context, filter storage.CommonFilter) ([]storage.VHost, int, error) {
+	all, err := vhosts.find(s.db)
+	if err != nil {
+		return nil, 0, err
 	}
-
-	trace.Steps = append(trace.Steps, step)
-	data, _ := json.Marshal(trace)
-	return s.db.Set([]byte(key), data, pebble.Sync)
+	var list []storage.VHost
+	for _, v := range all {
+		if containsFold(filter.Search, v.ID, v.Name, v.Description) {
+			list = append(list, v)
+		}
+	}
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
 }
 
-func (s *pebbleStorage) GetMessageTrace(ctx context.Context, workflowID, messageID string) (storage.MessageTrace, error) {
-	key := fmt.Sprintf("t:%s:%s", workflowID, messageID)
-	val, closer, err := s.db.Get([]byte(key))
-	if err != nil {
-		if errors.Is(err, pebble.ErrNotFound) {
-			return storage.MessageTrace{}, storage.ErrNotFound
-		}
-		return storage.MessageTrace{}, err
-	}
-	defer closer.Close()
-	var trace storage.MessageTrace
-	if err := json.Unmarshal(val, &trace); err != nil {
-		return storage.MessageTrace{}, err
+func (s *pebbleStorage) CreateVHost(ctx context.Context, vhost storage.VHost) error {
+	if vhost.ID == "" {
+		vhost.ID = vhost.Name
 	}
-	return trace, nil
+	return insert(s, vhosts, vhost)
 }
 
-func (s *pebbleStorage) ListMessageTraces(ctx context.Context, workflowID string, limit, offset int) ([]storage.MessageTrace, error) {
-	var traces []storage.MessageTrace
-	prefix := []byte(fmt.Sprintf("t:%s:", workflowID))
-	iter, err := s.db.NewIter(&pebble.IterOptions{
-		LowerBound: prefix,
-		UpperBound: append(prefix, 0xFF),
-	})
+func (s *pebbleStorage) UpdateVHost(ctx context.Context, vhost storage.VHost) error {
+	return update(s, vhosts, vhost.ID, func(v *storage.VHost) { *v = vhost })
+}
+
+func (s *pebbleStorage) DeleteVHost(ctx context.Context, id string) error {
+	return remove(s, vhosts, id)
+}
+
+func (s *pebbleStorage) GetVHost(ctx context.Context, id string) (storage.VHost, error) {
+	return vhosts.get(s.db, id)
+}
+
+// Workspace methods
+
+func (s *pebbleStorage) ListWorkspaces(ctx context.Context) ([]storage.Workspace, error) {
+	list, err := workspaces.find(s.db)
 	if err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-
-	for iter.First(); iter.Valid(); iter.Next() {
-		var trace storage.MessageTrace
-		if err := json.Unmarshal(iter.Value(), &trace); err != nil {
-			continue
-		}
-		traces = append(traces, trace)
-	}
-	// Sort by CreatedAt desc before applying paging so the order is stable.
-	sort.Slice(traces, func(i, j int) bool {
-		return traces[i].CreatedAt.After(traces[j].CreatedAt)
-	})
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
 
-	if offset < 0 {
-		offset = 0
-	}
-	if offset >= len(traces) {
-		return []storage.MessageTrace{}, nil
+func (s *pebbleStorage) CreateWorkspace(ctx context.Context, ws storage.Workspace) error {
+	if ws.ID == "" {
+		ws.ID = uuid.New().String()
 	}
-	traces = traces[offset:]
-	if limit > 0 && len(traces) > limit {
-		traces = traces[:limit]
+	if ws.CreatedAt.IsZero() {
+		ws.CreatedAt = time.Now()
 	}
-	return traces, nil
+	return insert(s, workspaces, ws)
 }
 
-func (s *pebbleStorage) PurgeMessageTraces(ctx context.Context, before time.Time) error {
-	iter, err := s.db.NewIter(&pebble.IterOptions{
-		LowerBound: []byte("t:"),
-		UpperBound: []byte("u:"),
-	})
+func (s *pebbleStorage) GetWorkspace(ctx context.Context, id string) (storage.Workspace, error) {
+	return workspaces.get(s.db, id)
+}
+
+func (s *pebbleStorage) DeleteWorkspace(ctx context.Context, id string) error {
+	return remove(s, workspaces, id)
+}
+
+// Workflow methods
+
+func (s *pebbleStorage) ListWorkflows(ctx context.Context, filter storage.CommonFilter) ([]storage.Workflow, int, error) {
+	conds := commonConds(filter)
+	if filter.OwnerID != "" {
+		conds = append([]cond{{"owner", filter.OwnerID}}, conds...)
+	}
+	all, err := workflows.find(s.db, conds...)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	defer iter.Close()
-
-	batch := s.db.NewBatch()
-	for iter.First(); iter.Valid(); iter.Next() {
-		var trace storage.MessageTrace
-		if err := json.Unmarshal(iter.Value(), &trace); err != nil {
-			continue
-		}
-		if trace.CreatedAt.Before(before) {
-			if err := batch.Delete(iter.Key(), pebble.Sync); err != nil {
-				return err
-			}
+	var list []storage.Workflow
+	for _, wf := range all {
+		if containsFold(filter.Search, wf.Name) {
+			list = append(list, wf)
 		}
 	}
-	return batch.Commit(pebble.Sync)
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
 }
 
-// Stubs for non-log methods
-
-func (s *pebbleStorage) ListSources(ctx context.Context, filter storage.CommonFilter) ([]storage.Source, int, error) {
-	return nil, 0, errors.New("not implemented")
-}
-func (s *pebbleStorage) CreateSource(ctx context.Context, src storage.Source) error {
-	return errors.New("not implemented")
-}
-func (s *pebbleStorage) UpdateSource(ctx context.Context, src storage.Source) error {
-	return errors.New("not implemented")
-}
-func (s *pebbleStorage) UpdateSourceStatus(ctx context.Context, id string, status string) error {
-	return errors.New("not implemented")
-}
-func (s *pebbleStorage) UpdateSourceState(ctx context.Context, id string, state map[string]string) error {
-	return errors.New("not implemented")
+func (s *pebbleStorage) CreateWorkflow(ctx context.Context, wf storage.Workflow) error {
+	if wf.ID == "" {
+		wf.ID = uuid.New().String()
+	}
+	// Ownership is only ever granted through the lease methods.
+	wf.OwnerID, wf.LeaseUntil, wf.LeaseToken = "", nil, 0
+	return insert(s, workflows, wf)
 }
-func (s *pebbleStorage) DeleteSource(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) UpdateWorkflow(ctx context.Context, wf storage.Workflow) error {
+	return update(s, workflows, wf.ID, func(v *storage.Workflow) {
+		owner, lease, token := v.OwnerID, v.LeaseUntil, v.LeaseToken
+		*v = wf
+		v.OwnerID, v.LeaseUntil, v.LeaseToken = owner, lease, token
+	})
 }
-func (s *pebbleStorage) GetSource(ctx context.Context, id string) (storage.Source, error) {
-	return storage.Source{}, errors.New("not implemented")
+
+func (s *pebbleStorage) UpdateWorkflowStatus(ctx context.Context, id string, status string) error {
+	return update(s, workflows, id, func(v *storage.Workflow) { v.Status = status })
 }
-func (s *pebbleStorage) ListSinks(ctx context.Context, filter storage.CommonFilter) ([]storage.Sink, int, error) {
-	return nil, 0, errors.New("not implemented")
+
+func (s *pebbleStorage) UpdateWorkflowStats(ctx context.Context, id string, processed, numErrors, lag uint64) error {
+	return update(s, workflows, id, func(v *storage.Workflow) {
+		v.TotalProcessed, v.TotalErrors, v.TotalLag = processed, numErrors, lag
+	})
 }
-func (s *pebbleStorage) CreateSink(ctx context.Context, snk storage.Sink) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) DeleteWorkflow(ctx context.Context, id string) error {
+	return remove(s, workflows, id)
 }
-func (s *pebbleStorage) UpdateSink(ctx context.Context, snk storage.Sink) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) GetWorkflow(ctx context.Context, id string) (storage.Workflow, error) {
+	return workflows.get(s.db, id)
 }
-func (s *pebbleStorage) UpdateSinkStatus(ctx context.Context, id string, status string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) AcquireWorkflowLease(ctx context.Context, workflowID, ownerID string, ttlSeconds int) (bool, error) {
+	now := time.Now()
+	ok, err := modify(s, workflows, workflowID, func(v *storage.Workflow) bool {
+		if v.OwnerID != "" && v.LeaseUntil != nil && !v.LeaseUntil.Before(now) && v.OwnerID != ownerID {
+			return false
+		}
+		if v.OwnerID != ownerID {
+			v.LeaseToken++
+		}
+		until := now.Add(leaseTTL(ttlSeconds))
+		v.OwnerID, v.LeaseUntil = ownerID, &until
+		return true
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return ok, err
 }
-func (s *pebbleStorage) DeleteSink(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) RenewWorkflowLease(ctx context.Context, workflowID, ownerID string, ttlSeconds int) (bool, error) {
+	now := time.Now()
+	ok, err := modify(s, workflows, workflowID, func(v *storage.Workflow) bool {
+		if v.OwnerID != ownerID || v.LeaseUntil == nil || v.LeaseUntil.Before(now) {
+			return false
+		}
+		until := now.Add(leaseTTL(ttlSeconds))
+		v.LeaseUntil = &until
+		return true
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return ok, err
 }
-func (s *pebbleStorage) GetSink(ctx context.Context, id string) (storage.Sink, error) {
-	return storage.Sink{}, errors.New("not implemented")
+
+func (s *pebbleStorage) ReleaseWorkflowLease(ctx context.Context, workflowID, ownerID string) error {
+	_, err := modify(s, workflows, workflowID, func(v *storage.Workflow) bool {
+		if v.OwnerID != ownerID {
+			return false
+		}
+		v.OwnerID, v.LeaseUntil = "", nil
+		return true
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
 }
-func (s *pebbleStorage) ListUsers(ctx context.Context, filter storage.CommonFilter) ([]storage.User, int, error) {
-	return nil, 0, errors.New("not implemented")
+
+func (s *pebbleStorage) AcquireLease(ctx context.Context, name, ownerID string, ttlSeconds int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	l, err := leases.get(s.db, name)
+	if errors.Is(err, storage.ErrNotFound) {
+		l = storage.Lease{Name: name}
+	} else if err != nil {
+		return false, err
+	}
+	old := l
+	if l.OwnerID != "" && l.OwnerID != ownerID && !l.LeaseUntil.Before(now) {
+		return false, nil
+	}
+	if l.OwnerID != ownerID {
+		l.Token++
+		l.AcquiredAt = now
+	}
+	l.OwnerID, l.LeaseUntil = ownerID, now.Add(leaseTTL(ttlSeconds))
+
+	b := s.db.NewBatch()
+	defer b.Close()
+	if err := leases.put(b, &old, l); err != nil {
+		return false, err
+	}
+	return true, b.Commit(pebble.Sync)
 }
-func (s *pebbleStorage) CreateUser(ctx context.Context, user storage.User) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) ReleaseLease(ctx context.Context, name, ownerID string) error {
+	_, err := modify(s, leases, name, func(v *storage.Lease) bool {
+		if v.OwnerID != ownerID {
+			return false
+		}
+		v.OwnerID, v.LeaseUntil = "", time.Now()
+		return true
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
 }
-func (s *pebbleStorage) UpdateUser(ctx context.Context, user storage.User) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) GetLease(ctx context.Context, name string) (storage.Lease, error) {
+	return leases.get(s.db, name)
 }
-func (s *pebbleStorage) DeleteUser(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+
+func leaseTTL(ttlSeconds int) time.Duration {
+	if ttlSeconds <= 0 {
+		ttlSeconds = defaultLeaseTTL
+	}
+	return time.Duration(ttlSeconds) * time.Second
 }
-func (s *pebbleStorage) GetUser(ctx context.Context, id string) (storage.User, error) {
-	return storage.User{}, errors.New("not implemented")
+
+// Worker methods
+
+func (s *pebbleStorage) ListWorkers(ctx context.Context, filter storage.CommonFilter) ([]storage.Worker, int, error) {
+	all, err := workers.find(s.db)
+	if err != nil {
+		return nil, 0, err
+	}
+	var list []storage.Worker
+	for _, w := range all {
+		if containsFold(filter.Search, w.ID, w.Name, w.Host, w.Description) {
+			list = append(list, w)
+		}
+	}
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
 }
-func (s *pebbleStorage) GetUserByUsername(ctx context.Context, username string) (storage.User, error) {
-	return storage.User{}, errors.New("not implemented")
+
+func (s *pebbleStorage) CreateWorker(ctx context.Context, worker storage.Worker) error {
+	// Ensure ID and token are set to sane defaults when missing to simplify setup
+	if worker.ID == "" {
+		worker.ID = uuid.New().String()
+	}
+	if worker.Token == "" {
+		worker.Token = uuid.New().String()
+	}
+	return insert(s, workers, worker)
 }
-func (s *pebbleStorage) GetUserByEmail(ctx context.Context, email string) (storage.User, error) {
-	return storage.User{}, errors.New("not implemented")
+
+func (s *pebbleStorage) UpdateWorker(ctx context.Context, worker storage.Worker) error {
+	return update(s, workers, worker.ID, func(v *storage.Worker) { *v = worker })
 }
-func (s *pebbleStorage) ListVHosts(ctx context.Context, filter storage.CommonFilter) ([]storage.VHost, int, error) {
-	return nil, 0, errors.New("not implemented")
+
+func (s *pebbleStorage) UpdateWorkerHeartbeat(ctx context.Context, id string, cpu, mem float64) error {
+	now := time.Now()
+	return update(s, workers, id, func(v *storage.Worker) {
+		v.LastSeen, v.CPUUsage, v.MemoryUsage = &now, cpu, mem
+	})
 }
-func (s *pebbleStorage) CreateVHost(ctx context.Context, vhost storage.VHost) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) DeleteWorker(ctx context.Context, id string) error {
+	return remove(s, workers, id)
 }
-func (s *pebbleStorage) UpdateVHost(ctx context.Context, vhost storage.VHost) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) GetWorker(ctx context.Context, id string) (storage.Worker, error) {
+	return workers.get(s.db, id)
 }
-func (s *pebbleStorage) DeleteVHost(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+
+// Log methods
+
+func logKey(l storage.Log) []byte {
+	// Key: l:<reverse_timestamp>:<uuid>
+	return []byte(fmt.Sprintf("l:%020d:%s", maxNanos-l.Timestamp.UnixNano(), l.ID))
 }
-func (s *pebbleStorage) GetVHost(ctx context.Context, id string) (storage.VHost, error) {
-	return storage.VHost{}, errors.New("not implemented")
+
+func (s *pebbleStorage) CreateLog(ctx context.Context, l storage.Log) error {
+	return s.CreateLogs(ctx, []storage.Log{l})
 }
-func (s *pebbleStorage) ListWorkflows(ctx context.Context, filter storage.CommonFilter) ([]storage.Workflow, int, error) {
-	return nil, 0, errors.New("not implemented")
+
+func (s *pebbleStorage) CreateLogs(ctx context.Context, logs []storage.Log) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	batch := s.db.NewBatch()
+	defer batch.Close()
+
+	for _, l := range logs {
+		if l.ID == "" {
+			l.ID = uuid.New().String()
+		}
+		if l.Timestamp.IsZero() {
+			l.Timestamp = time.Now()
+		}
+		data, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(logKey(l), data, nil); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(pebble.Sync)
 }
-func (s *pebbleStorage) ListWorkspaces(ctx context.Context) ([]storage.Workspace, error) {
-	return nil, errors.New("not implemented")
+
+func logMatches(l storage.Log, filter storage.LogFilter) bool {
+	if !filter.Since.IsZero() && l.Timestamp.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && !l.Timestamp.Before(filter.Until) {
+		return false
+	}
+	if filter.SourceID != "" && l.SourceID != filter.SourceID {
+		return false
+	}
+	if filter.SinkID != "" && l.SinkID != filter.SinkID {
+		return false
+	}
+	if filter.WorkflowID != "" && l.WorkflowID != filter.WorkflowID {
+		return false
+	}
+	if filter.WithoutWorkflow && l.WorkflowID != "" {
+		return false
+	}
+	if filter.Level != "" && l.Level != filter.Level {
+		return false
+	}
+	if filter.Action != "" && l.Action != filter.Action {
+		return false
+	}
+	return true
 }
-func (s *pebbleStorage) CreateWorkspace(ctx context.Context, ws storage.Workspace) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) ListLogs(ctx context.Context, filter storage.LogFilter) ([]storage.Log, int, error) {
+	var logs []storage.Log
+	iter, err := s.db.NewIter(prefixOptions([]byte("l:")))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var l storage.Log
+		if err := json.Unmarshal(iter.Value(), &l); err != nil {
+			continue
+		}
+		if !logMatches(l, filter) ||
+			!containsFold(filter.Search, l.Message, l.Action, l.SourceID, l.SinkID, l.WorkflowID) {
+			continue
+		}
+		logs = append(logs, l)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, 0, err
+	}
+
+	total := len(logs)
+	if filter.Limit <= 0 {
+		filter.Page, filter.Limit = 1, 100
+	}
+	return paginate(logs, filter.Page, filter.Limit), total, nil
 }
-func (s *pebbleStorage) GetWorkspace(ctx context.Context, id string) (storage.Workspace, error) {
-	return storage.Workspace{}, errors.New("not implemented")
+
+func (s *pebbleStorage) DeleteLogs(ctx context.Context, filter storage.LogFilter) error {
+	iter, err := s.db.NewIter(prefixOptions([]byte("l:")))
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for iter.First(); iter.Valid(); iter.Next() {
+		var l storage.Log
+		if err := json.Unmarshal(iter.Value(), &l); err != nil {
+			continue
+		}
+		if logMatches(l, filter) {
+			if err := batch.Delete(iter.Key(), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return batch.Commit(pebble.Sync)
 }
-func (s *pebbleStorage) DeleteWorkspace(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) PurgeLogs(ctx context.Context, before time.Time) error {
+	return s.purgeBefore("l:", before)
 }
-func (s *pebbleStorage) CreateWorkflow(ctx context.Context, wf storage.Workflow) error {
-	return errors.New("not implemented")
+
+// purgeBefore deletes the reverse-timestamp keyed entries under prefix that
+// are strictly older than before. Those sort after before's own key, so the
+// range starts one nanosecond earlier.
+func (s *pebbleStorage) purgeBefore(prefix string, before time.Time) error {
+	bounds := prefixOptions([]byte(prefix))
+	start := []byte(fmt.Sprintf("%s%020d", prefix, maxNanos-before.UnixNano()+1))
+	return s.db.DeleteRange(start, bounds.UpperBound, pebble.Sync)
 }
-func (s *pebbleStorage) UpdateWorkflow(ctx context.Context, wf storage.Workflow) error {
-	return errors.New("not implemented")
+
+// Audit Log methods
+
+func (s *pebbleStorage) CreateAuditLog(ctx context.Context, a storage.AuditLog) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	if a.Timestamp.IsZero() {
+		a.Timestamp = time.Now()
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	// Key: a:<reverse_timestamp>:<uuid>
+	key := fmt.Sprintf("a:%020d:%s", maxNanos-a.Timestamp.UnixNano(), a.ID)
+	return s.db.Set([]byte(key), data, pebble.Sync)
 }
-func (s *pebbleStorage) UpdateWorkflowStatus(ctx context.Context, id string, status string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) ListAuditLogs(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditLog, int, error) {
+	var logs []storage.AuditLog
+	iter, err := s.db.NewIter(prefixOptions([]byte("a:")))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var a storage.AuditLog
+		if err := json.Unmarshal(iter.Value(), &a); err != nil {
+			continue
+		}
+
+		if filter.From != nil && a.Timestamp.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && a.Timestamp.After(*filter.To) {
+			continue
+		}
+		if filter.UserID != "" && a.UserID != filter.UserID {
+			continue
+		}
+		if filter.Action != "" && a.Action != filter.Action {
+			continue
+		}
+		if filter.EntityType != "" && a.EntityType != filter.EntityType {
+			continue
+		}
+		if filter.EntityID != "" && a.EntityID != filter.EntityID {
+			continue
+		}
+		if !containsFold(filter.Search, a.ID, a.Username, a.Action, a.EntityID, a.Payload) {
+			continue
+		}
+
+		logs = append(logs, a)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, 0, err
+	}
+
+	return paginate(logs, filter.Page, filter.Limit), len(logs), nil
 }
-func (s *pebbleStorage) UpdateWorkflowStats(ctx context.Context, id string, processed, numErrors, lag uint64) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) PurgeAuditLogs(ctx context.Context, before time.Time) error {
+	return s.purgeBefore("a:", before)
 }
-func (s *pebbleStorage) DeleteWorkflow(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+
+// Webhook request methods
+
+func (s *pebbleStorage) ListWebhookRequests(ctx context.Context, filter storage.WebhookRequestFilter) ([]storage.WebhookRequest, int, error) {
+	list, err := s.webhookRequestsFor(filter.Path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
 }
-func (s *pebbleStorage) GetWorkflow(ctx context.Context, id string) (storage.Workflow, error) {
-	return storage.Workflow{}, errors.New("not implemented")
+
+// webhookRequestsFor returns the stored requests, newest first, optionally
+// restricted to one path.
+func (s *pebbleStorage) webhookRequestsFor(path string) ([]storage.WebhookRequest, error) {
+	var conds []cond
+	if path != "" {
+		conds = append(conds, cond{"path", path})
+	}
+	list, err := webhookRequests.find(s.db, conds...)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Timestamp.After(list[j].Timestamp) })
+	return list, nil
 }
-func (s *pebbleStorage) AcquireWorkflowLease(ctx context.Context, workflowID, ownerID string, ttlSeconds int) (bool, error) {
-	return false, errors.New("not implemented")
+
+func (s *pebbleStorage) CreateWebhookRequest(ctx context.Context, req storage.WebhookRequest) error {
+	if req.ID == "" {
+		req.ID = uuid.NewString()
+	}
+	if req.Timestamp.IsZero() {
+		req.Timestamp = time.Now()
+	}
+	if err := insert(s, webhookRequests, req); err != nil {
+		return err
+	}
+
+	// Keep only the last requests per path to satisfy the "last N" requirement.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.webhookRequestsFor(req.Path)
+	if err != nil || len(list) <= webhookRetention {
+		return err
+	}
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for _, old := range list[webhookRetention:] {
+		if err := webhookRequests.delete(batch, old); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(pebble.Sync)
 }
-func (s *pebbleStorage) RenewWorkflowLease(ctx context.Context, workflowID, ownerID string, ttlSeconds int) (bool, error) {
-	return false, errors.New("not implemented")
+
+func (s *pebbleStorage) GetWebhookRequest(ctx context.Context, id string) (storage.WebhookRequest, error) {
+	return webhookRequests.get(s.db, id)
 }
-func (s *pebbleStorage) ReleaseWorkflowLease(ctx context.Context, workflowID, ownerID string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) DeleteWebhookRequests(ctx context.Context, filter storage.WebhookRequestFilter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := s.webhookRequestsFor(filter.Path)
+	if err != nil {
+		return err
+	}
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for _, req := range list {
+		if err := webhookRequests.delete(batch, req); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(pebble.Sync)
 }
-func (s *pebbleStorage) ListWorkers(ctx context.Context, filter storage.CommonFilter) ([]storage.Worker, int, error) {
-	return nil, 0, errors.New("not implemented")
+
+// Form submission methods
+
+func formConds(filter storage.FormSubmissionFilter) []cond {
+	var conds []cond
+	if filter.Status != "" {
+		conds = append(conds, cond{"status", filter.Status})
+	}
+	if filter.Path != "" {
+		conds = append(conds, cond{"path", filter.Path})
+	}
+	return conds
 }
-func (s *pebbleStorage) CreateWorker(ctx context.Context, worker storage.Worker) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) CreateFormSubmission(ctx context.Context, sub storage.FormSubmission) error {
+	if sub.ID == "" {
+		sub.ID = uuid.NewString()
+	}
+	if sub.Timestamp.IsZero() {
+		sub.Timestamp = time.Now()
+	}
+	return insert(s, formSubmissions, sub)
 }
-func (s *pebbleStorage) UpdateWorker(ctx context.Context, worker storage.Worker) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) ListFormSubmissions(ctx context.Context, filter storage.FormSubmissionFilter) ([]storage.FormSubmission, int, error) {
+	list, err := formSubmissions.find(s.db, formConds(filter)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Timestamp.Before(list[j].Timestamp) })
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
 }
-func (s *pebbleStorage) UpdateWorkerHeartbeat(ctx context.Context, id string, cpu, mem float64) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) GetFormSubmission(ctx context.Context, id string) (storage.FormSubmission, error) {
+	return formSubmissions.get(s.db, id)
 }
-func (s *pebbleStorage) DeleteWorker(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) UpdateFormSubmissionStatus(ctx context.Context, id string, status string) error {
+	return update(s, formSubmissions, id, func(v *storage.FormSubmission) { v.Status = status })
 }
-func (s *pebbleStorage) GetWorker(ctx context.Context, id string) (storage.Worker, error) {
-	return storage.Worker{}, errors.New("not implemented")
+
+func (s *pebbleStorage) DeleteFormSubmissions(ctx context.Context, filter storage.FormSubmissionFilter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, err := formSubmissions.find(s.db, formConds(filter)...)
+	if err != nil {
+		return err
+	}
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for _, sub := range list {
+		if err := formSubmissions.delete(batch, sub); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(pebble.Sync)
 }
-func (s *pebbleStorage) ListWebhookRequests(ctx context.Context, filter storage.WebhookRequestFilter) ([]storage.WebhookRequest, int, error) {
-	return nil, 0, errors.New("not implemented")
+
+// Settings and node state methods
+
+func (s *pebbleStorage) GetSetting(ctx context.Context, key string) (string, error) {
+	val, closer, err := s.db.Get([]byte("setting:" + key))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer closer.Close()
+	return string(val), nil
 }
-func (s *pebbleStorage) CreateWebhookRequest(ctx context.Context, req storage.WebhookRequest) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) SaveSetting(ctx context.Context, key string, value string) error {
+	return s.db.Set([]byte("setting:"+key), []byte(value), pebble.Sync)
 }
-func (s *pebbleStorage) GetWebhookRequest(ctx context.Context, id string) (storage.WebhookRequest, error) {
-	return storage.WebhookRequest{}, errors.New("not implemented")
+
+func nodeStatePrefix(workflowID string) []byte {
+	return []byte("nodestate:" + workflowID + indexSep)
 }
-func (s *pebbleStorage) DeleteWebhookRequests(ctx context.Context, filter storage.WebhookRequestFilter) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) UpdateNodeState(ctx context.Context, workflowID, nodeID string, state any) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.db.Set(append(nodeStatePrefix(workflowID), nodeID...), data, pebble.Sync)
 }
-func (s *pebbleStorage) CreateFormSubmission(ctx context.Context, sub storage.FormSubmission) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) GetNodeStates(ctx context.Context, workflowID string) (map[string]any, error) {
+	prefix := nodeStatePrefix(workflowID)
+	iter, err := s.db.NewIter(prefixOptions(prefix))
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	states := make(map[string]any)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var state any
+		if err := json.Unmarshal(iter.Value(), &state); err != nil {
+			continue
+		}
+		states[string(iter.Key()[len(prefix):])] = state
+	}
+	return states, iter.Error()
 }
-func (s *pebbleStorage) ListFormSubmissions(ctx context.Context, filter storage.FormSubmissionFilter) ([]storage.FormSubmission, int, error) {
-	return nil, 0, errors.New("not implemented")
+
+// Schema registry methods
+
+func (s *pebbleStorage) ListSchemas(ctx context.Context, name string) ([]storage.Schema, error) {
+	list, err := schemas.scan(s.db, schemas.key(name+indexSep), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Keys hold versions in ascending order; the registry lists newest first.
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list, nil
 }
-func (s *pebbleStorage) GetFormSubmission(ctx context.Context, id string) (storage.FormSubmission, error) {
-	return storage.FormSubmission{}, errors.New("not implemented")
+
+func (s *pebbleStorage) ListAllSchemas(ctx context.Context) ([]storage.Schema, error) {
+	all, err := schemas.find(s.db)
+	if err != nil {
+		return nil, err
+	}
+	// Records are ordered by name, then version: keep each name's last one.
+	var latest []storage.Schema
+	for i, sc := range all {
+		if i+1 == len(all) || all[i+1].Name != sc.Name {
+			latest = append(latest, sc)
+		}
+	}
+	return latest, nil
 }
-func (s *pebbleStorage) UpdateFormSubmissionStatus(ctx context.Context, id string, status string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) GetSchema(ctx context.Context, name string, version int) (storage.Schema, error) {
+	sc, err := schemas.get(s.db, versionID(name, version))
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.Schema{}, fmt.Errorf("%w: schema %s version %d", storage.ErrNotFound, name, version)
+	}
+	return sc, err
 }
-func (s *pebbleStorage) DeleteFormSubmissions(ctx context.Context, filter storage.FormSubmissionFilter) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) GetLatestSchema(ctx context.Context, name string) (storage.Schema, error) {
+	list, err := s.ListSchemas(ctx, name)
+	if err != nil {
+		return storage.Schema{}, err
+	}
+	if len(list) == 0 {
+		return storage.Schema{}, fmt.Errorf("%w: schema %s", storage.ErrNotFound, name)
+	}
+	return list[0], nil
 }
-func (s *pebbleStorage) GetSetting(ctx context.Context, key string) (string, error) {
-	return "", errors.New("not implemented")
+
+func (s *pebbleStorage) ListSchemasByID(ctx context.Context, schemaID int) ([]storage.Schema, error) {
+	return schemas.find(s.db, cond{"schemaid", strconv.Itoa(schemaID)})
 }
-func (s *pebbleStorage) SaveSetting(ctx context.Context, key string, value string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) CreateSchema(ctx context.Context, schema storage.Schema) error {
+	if schema.ID == "" {
+		schema.ID = uuid.New().String()
+	}
+	if schema.CreatedAt.IsZero() {
+		schema.CreatedAt = time.Now()
+	}
+	if schema.SchemaID == 0 {
+		id, err := s.schemaID(schema.Type, schema.Content)
+		if err != nil {
+			return err
+		}
+		schema.SchemaID = id
+	}
+	return insert(s, schemas, schema)
 }
-func (s *pebbleStorage) UpdateNodeState(ctx context.Context, workflowID, nodeID string, state any) error {
-	return errors.New("not implemented")
+
+// schemaID returns the ID of the schema with this type and content,
+// assigning the next free one on first use.
+func (s *pebbleStorage) schemaID(schemaType, content string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fingerprint := storage.SchemaFingerprint(schemaType, content)
+	if rec, err := schemaIDs.get(s.db, fingerprint); err == nil {
+		return rec.ID, nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return 0, err
+	}
+	all, err := schemaIDs.find(s.db)
+	if err != nil {
+		return 0, err
+	}
+	rec := schemaIDRecord{Fingerprint: fingerprint, ID: 1}
+	for _, r := range all {
+		rec.ID = max(rec.ID, r.ID+1)
+	}
+	b := s.db.NewBatch()
+	defer b.Close()
+	if err := schemaIDs.put(b, nil, rec); err != nil {
+		return 0, err
+	}
+	return rec.ID, b.Commit(pebble.Sync)
 }
-func (s *pebbleStorage) GetNodeStates(ctx context.Context, workflowID string) (map[string]any, error) {
-	return nil, errors.New("not implemented")
+
+// Trace methods
+
+func traceKey(workflowID, messageID string) []byte {
+	return []byte(fmt.Sprintf("t:%s:%s", workflowID, messageID))
 }
-func (s *pebbleStorage) ListSchemas(ctx context.Context, name string) ([]storage.Schema, error) {
-	return nil, errors.New("not implemented")
+
+// sortTraceSteps orders steps chronologically and derives the trace's
+// creation time from its first step.
+func sortTraceSteps(trace *storage.MessageTrace) {
+	sort.SliceStable(trace.Steps, func(i, j int) bool {
+		return trace.Steps[i].Timestamp.Before(trace.Steps[j].Timestamp)
+	})
+	if len(trace.Steps) > 0 {
+		trace.CreatedAt = trace.Steps[0].Timestamp
+	}
 }
-func (s *pebbleStorage) ListAllSchemas(ctx context.Context) ([]storage.Schema, error) {
-	return nil, errors.New("not implemented")
+
+func (s *pebbleStorage) RecordTraceStep(ctx context.Context, workflowID, messageID string, step hermod.TraceStep) error {
+	if step.Timestamp.IsZero() {
+		step.Timestamp = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := traceKey(workflowID, messageID)
+	trace := storage.MessageTrace{
+		ID:         messageID,
+		WorkflowID: workflowID,
+		MessageID:  messageID,
+	}
+	val, closer, err := s.db.Get(key)
+	if err == nil {
+		err = json.Unmarshal(val, &trace)
+		closer.Close()
+		if err != nil {
+			return err
+		}
+	} else if !errors.Is(err, pebble.ErrNotFound) {
+		return err
+	}
+
+	trace.Steps = append(trace.Steps, step)
+	sortTraceSteps(&trace)
+	data, err := json.Marshal(trace)
+	if err != nil {
+		return err
+	}
+	return s.db.Set(key, data, pebble.Sync)
 }
-func (s *pebbleStorage) GetSchema(ctx context.Context, name string, version int) (storage.Schema, error) {
-	return storage.Schema{}, errors.New("not implemented")
+
+func (s *pebbleStorage) GetMessageTrace(ctx context.Context, workflowID, messageID string) (storage.MessageTrace, error) {
+	val, closer, err := s.db.Get(traceKey(workflowID, messageID))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return storage.MessageTrace{}, storage.ErrNotFound
+		}
+		return storage.MessageTrace{}, err
+	}
+	defer closer.Close()
+	var trace storage.MessageTrace
+	if err := json.Unmarshal(val, &trace); err != nil {
+		return storage.MessageTrace{}, err
+	}
+	return trace, nil
 }
-func (s *pebbleStorage) GetLatestSchema(ctx context.Context, name string) (storage.Schema, error) {
-	return storage.Schema{}, errors.New("not implemented")
+
+func (s *pebbleStorage) ListMessageTraces(ctx context.Context, workflowID string, limit, offset int) ([]storage.MessageTrace, error) {
+	var traces []storage.MessageTrace
+	iter, err := s.db.NewIter(prefixOptions(traceKey(workflowID, "")))
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var trace storage.MessageTrace
+		if err := json.Unmarshal(iter.Value(), &trace); err != nil {
+			continue
+		}
+		// Listings carry only the trace headers, like the SQL backends.
+		trace.Steps = nil
+		traces = append(traces, trace)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	// Sort by CreatedAt desc before applying paging so the order is stable.
+	sort.SliceStable(traces, func(i, j int) bool {
+		return traces[i].CreatedAt.After(traces[j].CreatedAt)
+	})
+
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(traces) {
+		return []storage.MessageTrace{}, nil
+	}
+	traces = traces[offset:]
+	if limit > 0 && len(traces) > limit {
+		traces = traces[:limit]
+	}
+	return traces, nil
 }
-func (s *pebbleStorage) CreateSchema(ctx context.Context, schema storage.Schema) error {
-	return errors.New("not implemented")
+
+// PurgeMessageTraces drops the steps recorded before the cutoff and removes
+// traces left without steps.
+func (s *pebbleStorage) PurgeMessageTraces(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	iter, err := s.db.NewIter(prefixOptions([]byte("t:")))
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	batch := s.db.NewBatch()
+	defer batch.Close()
+	for iter.First(); iter.Valid(); iter.Next() {
+		var trace storage.MessageTrace
+		if err := json.Unmarshal(iter.Value(), &trace); err != nil {
+			continue
+		}
+		kept := trace.Steps[:0]
+		for _, step := range trace.Steps {
+			if !step.Timestamp.Before(before) {
+				kept = append(kept, step)
+			}
+		}
+		switch {
+		case len(kept) == 0:
+			err = batch.Delete(iter.Key(), nil)
+		case len(kept) < len(trace.Steps):
+			trace.Steps = kept
+			sortTraceSteps(&trace)
+			var data []byte
+			if data, err = json.Marshal(trace); err == nil {
+				err = batch.Set(iter.Key(), data, nil)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return batch.Commit(pebble.Sync)
 }
+
+// Workflow version methods
+
 func (s *pebbleStorage) CreateWorkflowVersion(ctx context.Context, version storage.WorkflowVersion) error {
-	return errors.New("not implemented")
+	if version.ID == "" {
+		version.ID = uuid.New().String()
+	}
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = time.Now()
+	}
+	return insert(s, workflowVersions, version)
 }
+
 func (s *pebbleStorage) ListWorkflowVersions(ctx context.Context, workflowID string) ([]storage.WorkflowVersion, error) {
-	return nil, errors.New("not implemented")
+	all, err := workflowVersions.scan(s.db, workflowVersions.key(workflowID+indexSep), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Newest first, without the graph and settings payloads.
+	list := make([]storage.WorkflowVersion, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		v := all[i]
+		v.Nodes, v.Edges, v.Config = nil, nil, ""
+		list = append(list, v)
+	}
+	return list, nil
 }
+
 func (s *pebbleStorage) GetWorkflowVersion(ctx context.Context, workflowID string, version int) (storage.WorkflowVersion, error) {
-	return storage.WorkflowVersion{}, errors.New("not implemented")
+	return workflowVersions.get(s.db, versionID(workflowID, version))
 }
+
+// Outbox methods
+
 func (s *pebbleStorage) CreateOutboxItem(ctx context.Context, item storage.OutboxItem) error {
-	return errors.New("not implemented")
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = time.Now()
+	}
+	return insert(s, outboxItems, item)
 }
+
 func (s *pebbleStorage) ListOutboxItems(ctx context.Context, status string, limit int) ([]storage.OutboxItem, error) {
-	return nil, errors.New("not implemented")
+	list, err := outboxItems.find(s.db, cond{"status", status})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
 }
+
 func (s *pebbleStorage) DeleteOutboxItem(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+	return remove(s, outboxItems, id)
 }
+
 func (s *pebbleStorage) UpdateOutboxItem(ctx context.Context, item storage.OutboxItem) error {
-	return errors.New("not implemented")
+	return update(s, outboxItems, item.ID, func(v *storage.OutboxItem) {
+		v.Attempts, v.LastError, v.Status = item.Attempts, item.LastError, item.Status
+	})
 }
+
+// Lineage
+
+// GetLineage links every source node of a workflow to each of its sink nodes
+// whose source and sink records exist.
 func (s *pebbleStorage) GetLineage(ctx context.Context) ([]storage.LineageEdge, error) {
-	return nil, errors.New("not implemented")
+	all, err := workflows.find(s.db)
+	if err != nil {
+		return nil, err
+	}
+	var edges []storage.LineageEdge
+	for _, wf := range all {
+		var srcs []storage.Source
+		var snks []storage.Sink
+		for _, n := range wf.Nodes {
+			switch n.Type {
+			case "source":
+				if src, err := sources.get(s.db, n.RefID); err == nil {
+					srcs = append(srcs, src)
+				} else if !errors.Is(err, storage.ErrNotFound) {
+					return nil, err
+				}
+			case "sink":
+				if snk, err := sinks.get(s.db, n.RefID); err == nil {
+					snks = append(snks, snk)
+				} else if !errors.Is(err, storage.ErrNotFound) {
+					return nil, err
+				}
+			}
+		}
+		for _, src := range srcs {
+			for _, snk := range snks {
+				edges = append(edges, storage.LineageEdge{
+					SourceID:     src.ID,
+					SourceName:   src.Name,
+					SourceType:   src.Type,
+					SinkID:       snk.ID,
+					SinkName:     snk.Name,
+					SinkType:     snk.Type,
+					WorkflowID:   wf.ID,
+					WorkflowName: wf.Name,
+				})
+			}
+		}
+	}
+	return edges, nil
 }
+
+// Marketplace methods
+
 func (s *pebbleStorage) ListPlugins(ctx context.Context) ([]storage.Plugin, error) {
-	return nil, errors.New("not implemented")
+	return plugins.find(s.db)
 }
+
 func (s *pebbleStorage) GetPlugin(ctx context.Context, id string) (storage.Plugin, error) {
-	return storage.Plugin{}, errors.New("not implemented")
+	return plugins.get(s.db, id)
 }
+
 func (s *pebbleStorage) InstallPlugin(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+	now := time.Now()
+	_, err := modify(s, plugins, id, func(v *storage.Plugin) bool {
+		v.Installed, v.InstalledAt = true, &now
+		return true
+	})
+	return err
 }
+
 func (s *pebbleStorage) UninstallPlugin(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+	_, err := modify(s, plugins, id, func(v *storage.Plugin) bool {
+		v.Installed, v.InstalledAt = false, nil
+		return true
+	})
+	return err
 }
 
+// GetDashboardStats aggregates workflow, source and sink counters for vhost
+// (every vhost when empty or "all"). Workers are not vhost-scoped and count
+// as active when they sent a heartbeat in the last two minutes.
 func (s *pebbleStorage) GetDashboardStats(ctx context.Context, vhost string) (storage.DashboardStats, error) {
-	return storage.DashboardStats{}, errors.New("not implemented")
+	var stats storage.DashboardStats
+	conds := commonConds(storage.CommonFilter{VHost: vhost})
+
+	wfs, err := workflows.find(s.db, conds...)
+	if err != nil {
+		return stats, err
+	}
+	for _, wf := range wfs {
+		stats.TotalWorkflows++
+		switch {
+		case wf.Status == "running":
+			stats.ActiveWorkflows++
+		case wf.Status == "failed" || wf.Status == "error" || strings.HasPrefix(wf.Status, "error:"):
+			stats.FailedWorkflows++
+		}
+		stats.TotalProcessed += wf.TotalProcessed
+		stats.TotalErrors += wf.TotalErrors
+		stats.TotalLag += wf.TotalLag
+	}
+
+	srcs, err := sources.find(s.db, conds...)
+	if err != nil {
+		return stats, err
+	}
+	for _, src := range srcs {
+		stats.TotalSources++
+		if src.Status == "running" {
+			stats.ActiveSources++
+		}
+	}
+
+	snks, err := sinks.find(s.db, conds...)
+	if err != nil {
+		return stats, err
+	}
+	for _, snk := range snks {
+		stats.TotalSinks++
+		if snk.Status == "running" {
+			stats.ActiveSinks++
+		}
+	}
+
+	wks, err := workers.find(s.db)
+	if err != nil {
+		return stats, err
+	}
+	activeThreshold := time.Now().Add(-2 * time.Minute)
+	for _, w := range wks {
+		if w.LastSeen != nil && w.LastSeen.After(activeThreshold) {
+			stats.ActiveWorkers++
+		}
+	}
+	return stats, nil
 }
+
+// Approval methods
+
 func (s *pebbleStorage) ListApprovals(ctx context.Context, filter storage.ApprovalFilter) ([]storage.Approval, int, error) {
-	return nil, 0, errors.New("not implemented")
+	var conds []cond
+	if filter.WorkflowID != "" {
+		conds = append(conds, cond{"workflow", filter.WorkflowID})
+	}
+	if filter.Status != "" {
+		conds = append(conds, cond{"status", filter.Status})
+	}
+	list, err := approvals.find(s.db, conds...)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
 }
+
 func (s *pebbleStorage) CreateApproval(ctx context.Context, app storage.Approval) error {
-	return errors.New("not implemented")
+	if app.ID == "" {
+		app.ID = uuid.New().String()
+	}
+	if app.CreatedAt.IsZero() {
+		app.CreatedAt = time.Now()
+	}
+	return insert(s, approvals, app)
 }
+
 func (s *pebbleStorage) GetApproval(ctx context.Context, id string) (storage.Approval, error) {
-	return storage.Approval{}, errors.New("not implemented")
+	return approvals.get(s.db, id)
 }
+
 func (s *pebbleStorage) UpdateApprovalStatus(ctx context.Context, id string, status string, processedBy string, notes string, formData map[string]any) error {
-	return errors.New("not implemented")
+	now := time.Now()
+	_, err := modify(s, approvals, id, func(v *storage.Approval) bool {
+		v.Status, v.ProcessedAt, v.ProcessedBy, v.Notes, v.FormData = status, &now, processedBy, notes, formData
+		return true
+	})
+	return err
+}
+
+func (s *pebbleStorage) DeleteApproval(ctx context.Context, id string) error {
+	found, err := removeExisting(s, approvals, id)
+	if err == nil && !found {
+		return storage.ErrNotFound
+	}
+	return err
 }
 
+// Suspended message methods
+
 func (s *pebbleStorage) CreateSuspendedMessage(ctx context.Context, m storage.SuspendedMessage) error {
-	return errors.New("not implemented")
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	return insert(s, suspendedMessages, m)
 }
 
+// ListSuspendedMessages returns the messages due to resume at or before
+// before, optionally restricted to one workflow.
 func (s *pebbleStorage) ListSuspendedMessages(ctx context.Context, workflowID string, before time.Time) ([]storage.SuspendedMessage, error) {
-	return nil, nil
+	var conds []cond
+	if workflowID != "" {
+		conds = append(conds, cond{"workflow", workflowID})
+	}
+	all, err := suspendedMessages.find(s.db, conds...)
+	if err != nil {
+		return nil, err
+	}
+	var due []storage.SuspendedMessage
+	for _, m := range all {
+		if !m.ResumeAt.After(before) {
+			due = append(due, m)
+		}
+	}
+	return due, nil
 }
 
 func (s *pebbleStorage) DeleteSuspendedMessage(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+	return remove(s, suspendedMessages, id)
 }
-func (s *pebbleStorage) DeleteApproval(ctx context.Context, id string) error {
-	return errors.New("not implemented")
+
+func (s *pebbleStorage) ListEventWaits(ctx context.Context, workflowID, nodeID, key string) ([]storage.SuspendedMessage, error) {
+	all, err := suspendedMessages.find(s.db, cond{"wait", key}, cond{"workflow", workflowID})
+	if err != nil {
+		return nil, err
+	}
+	out := all[:0]
+	for _, m := range all {
+		if m.NodeID == nodeID {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *pebbleStorage) ResolveEventWait(ctx context.Context, m storage.SuspendedMessage) (bool, error) {
+	ok, err := modify(s, suspendedMessages, m.ID, func(v *storage.SuspendedMessage) bool {
+		if v.WaitKey == "" {
+			return false
+		}
+		v.Metadata, v.Data, v.ResumeAt, v.WaitKey = m.Metadata, m.Data, m.ResumeAt, ""
+		return true
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	return ok, err
+}
+
+// Dead letter methods
+
+func (s *pebbleStorage) findDeadLetters(filter storage.DeadLetterFilter) ([]storage.DeadLetter, error) {
+	var conds []cond
+	if filter.WorkflowID != "" {
+		conds = append(conds, cond{"workflow", filter.WorkflowID})
+	}
+	if filter.Status != "" {
+		conds = append(conds, cond{"status", filter.Status})
+	}
+	all, err := deadLetters.find(s.db, conds...)
+	if err != nil {
+		return nil, err
+	}
+	var list []storage.DeadLetter
+	for _, dl := range all {
+		switch {
+		case filter.SinkID != "" && dl.SinkID != filter.SinkID,
+			filter.ErrorClass != "" && dl.ErrorClass != filter.ErrorClass,
+			!filter.Since.IsZero() && dl.CreatedAt.Before(filter.Since),
+			!filter.Until.IsZero() && dl.CreatedAt.After(filter.Until),
+			!containsFold(filter.Search, dl.MessageID, dl.Error):
+			continue
+		}
+		list = append(list, dl)
+	}
+	return list, nil
+}
+
+func (s *pebbleStorage) ListDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) ([]storage.DeadLetter, int, error) {
+	list, err := s.findDeadLetters(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return paginate(list, filter.Page, filter.Limit), len(list), nil
+}
+
+func (s *pebbleStorage) CreateDeadLetter(ctx context.Context, dl storage.DeadLetter) error {
+	if dl.ID == "" {
+		dl.ID = uuid.New().String()
+	}
+	if dl.CreatedAt.IsZero() {
+		dl.CreatedAt = time.Now()
+	}
+	if dl.Status == "" {
+		dl.Status = "pending"
+	}
+	return insert(s, deadLetters, dl)
+}
+
+func (s *pebbleStorage) GetDeadLetter(ctx context.Context, id string) (storage.DeadLetter, error) {
+	return deadLetters.get(s.db, id)
+}
+
+func (s *pebbleStorage) UpdateDeadLetter(ctx context.Context, dl storage.DeadLetter) error {
+	_, err := modify(s, deadLetters, dl.ID, func(v *storage.DeadLetter) bool {
+		dl.WorkflowID, dl.MessageID, dl.CreatedAt = v.WorkflowID, v.MessageID, v.CreatedAt
+		*v = dl
+		return true
+	})
+	return err
+}
+
+func (s *pebbleStorage) DeleteDeadLetter(ctx context.Context, id string) error {
+	found, err := removeExisting(s, deadLetters, id)
+	if err == nil && !found {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
+func (s *pebbleStorage) PurgeDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) (int, error) {
+	list, err := s.findDeadLetters(filter)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, dl := range list {
+		found, err := removeExisting(s, deadLetters, dl.ID)
+		if err != nil {
+			return purged, err
+		}
+		if found {
+			purged++
+		}
+	}
+	return purged, nil
 }
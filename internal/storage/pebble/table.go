@@ -0,0 +1,318 @@
+package pebble
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/user/hermod/internal/storage"
+)
+
+// Entities are stored as JSON records under "<table>:<id>". Every secondary
+// index entry is an empty value under
+//
+//	i:<table>:<index>:<value>\x00<id>
+//
+// so the records sharing an indexed value form one contiguous prefix that is
+// scanned in id order. Index entries are written in the same batch as the
+// record they describe, so they never disagree with it.
+
+// indexSep terminates an indexed value. It cannot appear in the identifiers
+// and filter values Hermod stores, which keeps one value's prefix from
+// matching another value that merely starts with it.
+const indexSep = "\x00"
+
+type table[T any] struct {
+	name    string
+	id      func(T) string
+	indexes map[string]func(T) string
+}
+
+// cond restricts a query to records whose index value equals value.
+type cond struct {
+	index, value string
+}
+
+func (t table[T]) key(id string) []byte {
+	return []byte(t.name + ":" + id)
+}
+
+func (t table[T]) indexPrefix(index, value string) []byte {
+	return []byte("i:" + t.name + ":" + index + ":" + value + indexSep)
+}
+
+func (t table[T]) indexKey(index, value, id string) []byte {
+	return append(t.indexPrefix(index, value), id...)
+}
+
+func (t table[T]) get(r pebble.Reader, id string) (T, error) {
+	var v T
+	val, closer, err := r.Get(t.key(id))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return v, storage.ErrNotFound
+		}
+		return v, err
+	}
+	defer closer.Close()
+	err = json.Unmarshal(val, &v)
+	return v, err
+}
+
+// put writes v and its index entries to b. old is the currently stored
+// version of the record, if any, whose stale index entries are removed.
+func (t table[T]) put(b *pebble.Batch, old *T, v T) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	id := t.id(v)
+	if err := b.Set(t.key(id), data, nil); err != nil {
+		return err
+	}
+	for name, value := range t.indexes {
+		nv := value(v)
+		if old != nil {
+			ov := value(*old)
+			if ov == nv {
+				continue
+			}
+			if err := b.Delete(t.indexKey(name, ov, id), nil); err != nil {
+				return err
+			}
+		}
+		if err := b.Set(t.indexKey(name, nv, id), nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delete removes v and its index entries in b.
+func (t table[T]) delete(b *pebble.Batch, v T) error {
+	id := t.id(v)
+	if err := b.Delete(t.key(id), nil); err != nil {
+		return err
+	}
+	for name, value := range t.indexes {
+		if err := b.Delete(t.indexKey(name, value(v), id), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// find returns the records satisfying every condition, in id order. The first
+// condition drives an index scan and the remaining ones are checked against
+// the decoded records; without conditions the whole table is scanned.
+func (t table[T]) find(r pebble.Reader, conds ...cond) ([]T, error) {
+	var candidates []T
+	var err error
+	if len(conds) == 0 {
+		candidates, err = t.scan(r, []byte(t.name+":"), nil)
+	} else {
+		candidates, err = t.scan(r, t.indexPrefix(conds[0].index, conds[0].value), func(key []byte) (T, error) {
+			return t.get(r, string(key))
+		})
+		conds = conds[1:]
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := candidates[:0]
+	for _, v := range candidates {
+		ok := true
+		for _, c := range conds {
+			if t.indexes[c.index](v) != c.value {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// scan decodes every key under prefix. Record keys are decoded in place; for
+// index keys, load resolves the id that follows the prefix to its record.
+func (t table[T]) scan(r pebble.Reader, prefix []byte, load func(id []byte) (T, error)) ([]T, error) {
+	iter, err := r.NewIter(prefixOptions(prefix))
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var out []T
+	for iter.First(); iter.Valid(); iter.Next() {
+		var v T
+		if load == nil {
+			if err := json.Unmarshal(iter.Value(), &v); err != nil {
+				continue
+			}
+		} else {
+			v, err = load(iter.Key()[len(prefix):])
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, v)
+	}
+	return out, iter.Error()
+}
+
+// prefixOptions bounds an iterator to the keys starting with prefix.
+func prefixOptions(prefix []byte) *pebble.IterOptions {
+	upper := append([]byte(nil), prefix...)
+	for i := len(upper) - 1; i >= 0; i-- {
+		if upper[i] < 0xff {
+			upper[i]++
+			return &pebble.IterOptions{LowerBound: prefix, UpperBound: upper[:i+1]}
+		}
+	}
+	return &pebble.IterOptions{LowerBound: prefix}
+}
+
+// insert stores v, failing if a record with the same id already exists.
+func insert[T any](s *pebbleStorage, t table[T], v T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := t.get(s.db, t.id(v)); err == nil {
+		return fmt.Errorf("%s %q already exists", t.name, t.id(v))
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	b := s.db.NewBatch()
+	defer b.Close()
+	if err := t.put(b, nil, v); err != nil {
+		return err
+	}
+	return b.Commit(pebble.Sync)
+}
+
+// modify loads record id, lets fn change it and writes it back when fn
+// reports a change. It returns storage.ErrNotFound for a missing record.
+func modify[T any](s *pebbleStorage, t table[T], id string, fn func(v *T) bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := t.get(s.db, id)
+	if err != nil {
+		return false, err
+	}
+	v := old
+	if !fn(&v) {
+		return false, nil
+	}
+	b := s.db.NewBatch()
+	defer b.Close()
+	if err := t.put(b, &old, v); err != nil {
+		return false, err
+	}
+	return true, b.Commit(pebble.Sync)
+}
+
+// update is modify for unconditional changes; like the SQL backends'
+// UPDATE ... WHERE id = ?, it silently ignores a missing record.
+func update[T any](s *pebbleStorage, t table[T], id string, fn func(v *T)) error {
+	_, err := modify(s, t, id, func(v *T) bool {
+		fn(v)
+		return true
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// remove deletes record id and its index entries. A missing record is not an
+// error.
+func remove[T any](s *pebbleStorage, t table[T], id string) error {
+	_, err := removeExisting(s, t, id)
+	return err
+}
+
+// removeExisting is remove that reports whether the record existed.
+func removeExisting[T any](s *pebbleStorage, t table[T], id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := t.get(s.db, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	b := s.db.NewBatch()
+	defer b.Close()
+	if err := t.delete(b, v); err != nil {
+		return false, err
+	}
+	return true, b.Commit(pebble.Sync)
+}
+
+// paginate applies the SQL backends' LIMIT/OFFSET semantics: a positive limit
+// bounds the result and page (1-based) selects the window.
+func paginate[T any](items []T, page, limit int) []T {
+	if limit <= 0 {
+		return items
+	}
+	start := 0
+	if page > 1 {
+		start = (page - 1) * limit
+	}
+	if start >= len(items) {
+		return []T{}
+	}
+	return items[start:min(start+limit, len(items))]
+}
+
+// containsFold reports whether any field contains search, ignoring case, the
+// way the SQL backends match LIKE '%search%'. An empty search matches.
+func containsFold(search string, fields ...string) bool {
+	if search == "" {
+		return true
+	}
+	search = strings.ToLower(search)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), search) {
+			return true
+		}
+	}
+	return false
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// commonConds turns the equality filters shared by sources, sinks and
+// workflows into index conditions, most selective first.
+func commonConds(f storage.CommonFilter) []cond {
+	var conds []cond
+	if f.WorkerID != "" {
+		conds = append(conds, cond{"worker", f.WorkerID})
+	}
+	if f.WorkspaceID != "" {
+		conds = append(conds, cond{"workspace", f.WorkspaceID})
+	}
+	if f.VHost != "" && f.VHost != "all" {
+		conds = append(conds, cond{"vhost", f.VHost})
+	}
+	if f.Active != nil {
+		conds = append(conds, cond{"active", boolValue(*f.Active)})
+	}
+	return conds
+}
@@ -0,0 +1,40 @@
+package storage
+
+// DefaultPlugins returns the marketplace catalogue every backend seeds on
+// first initialization.
+func DefaultPlugins() []Plugin {
+	return []Plugin{
+		{
+			ID:          "openai-pii-filter",
+			Name:        "OpenAI PII Filter",
+			Description: "Anonymize sensitive data using OpenAI's GPT-4 before it leaves your infrastructure.",
+			Author:      "Hermod Core",
+			Stars:       128,
+			Category:    "Security",
+			Certified:   true,
+			Type:        "Transformer",
+			WasmURL:     "https://github.com/user/hermod-plugins/raw/main/openai-pii-filter.wasm",
+		},
+		{
+			ID:          "slack-connector",
+			Name:        "Slack Connector",
+			Description: "Send alerts and notifications to Slack channels with advanced formatting.",
+			Author:      "Hermod Core",
+			Stars:       89,
+			Category:    "Connectors",
+			Certified:   true,
+			Type:        "Connector",
+		},
+		{
+			ID:          "xml-to-json",
+			Name:        "XML to JSON",
+			Description: "High-performance WASM-based transformer to convert legacy XML payloads to JSON.",
+			Author:      "Community",
+			Stars:       45,
+			Category:    "Transformation",
+			Certified:   false,
+			Type:        "WASM",
+			WasmURL:     "https://github.com/user/hermod-plugins/raw/main/xml-to-json.wasm",
+		},
+	}
+}
@@ -0,0 +1,22 @@
+package sql
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/storage/storagetest"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		db, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			t.Fatalf("failed to open sqlite: %v", err)
+		}
+		db.SetMaxOpenConns(1)
+		t.Cleanup(func() { db.Close() })
+		return NewSQLStorage(db, "sqlite")
+	})
+}
@@ -34,6 +34,7 @@ const (
 	QueryInitSettingsTable           = "InitSettingsTable"
 	QueryInitAuditLogsTable          = "InitAuditLogsTable"
 	QueryInitSchemasTable            = "InitSchemasTable"
+	QueryInitSchemaIDsTable          = "InitSchemaIDsTable"
 	QueryInitMessageTraceStepsTable  = "InitMessageTraceStepsTable"
 	QueryInitWorkflowVersionsTable   = "InitWorkflowVersionsTable"
 	QueryInitOutboxTable             = "InitOutboxTable"
@@ -144,11 +145,17 @@ const (
 	QueryDeleteFormSubmissions      = "DeleteFormSubmissions"
 
 	// Schemas
-	QueryListSchemas     = "ListSchemas"
-	QueryListAllSchemas  = "ListAllSchemas"
-	QueryGetSchema       = "GetSchema"
-	QueryGetLatestSchema = "GetLatestSchema"
-	QueryCreateSchema    = "CreateSchema"
+	QueryListSchemas           = "ListSchemas"
+	QueryListAllSchemas        = "ListAllSchemas"
+	QueryGetSchema             = "GetSchema"
+	QueryGetLatestSchema       = "GetLatestSchema"
+	QueryListSchemasByID       = "ListSchemasByID"
+	QueryCreateSchema          = "CreateSchema"
+	QueryGetSchemaID           = "GetSchemaID"
+	QueryNextSchemaID          = "NextSchemaID"
+	QueryCreateSchemaID        = "CreateSchemaID"
+	QueryListUnnumberedSchemas = "ListUnnumberedSchemas"
+	QuerySetSchemaID           = "SetSchemaID"
 
 	// Tracing
 	QueryRecordTraceStep   = "RecordTraceStep"
@@ -186,6 +193,24 @@ const (
 	QueryCreateSuspendedMessage     = "CreateSuspendedMessage"
 	QueryListSuspendedMessages      = "ListSuspendedMessages"
 	QueryDeleteSuspendedMessage     = "DeleteSuspendedMessage"
+	QueryListEventWaits             = "ListEventWaits"
+	QueryResolveEventWait           = "ResolveEventWait"
+
+	// Dead Letters
+	QueryInitDeadLettersTable = "InitDeadLettersTable"
+	QueryListDeadLetters      = "ListDeadLetters"
+	QueryCountDeadLetters     = "CountDeadLetters"
+	QueryCreateDeadLetter     = "CreateDeadLetter"
+	QueryGetDeadLetter        = "GetDeadLetter"
+	QueryUpdateDeadLetter     = "UpdateDeadLetter"
+	QueryDeleteDeadLetter     = "DeleteDeadLetter"
+
+	// Named leases
+	QueryInitLeasesTable   = "InitLeasesTable"
+	QueryAcquireNamedLease = "AcquireNamedLease"
+	QueryInsertNamedLease  = "InsertNamedLease"
+	QueryReleaseNamedLease = "ReleaseNamedLease"
+	QueryGetNamedLease     = "GetNamedLease"
 )
 
 var commonQueries = map[string]string{
@@ -262,6 +287,7 @@ var commonQueries = map[string]string{
             worker_id TEXT,
             owner_id TEXT,
             lease_until TIMESTAMP,
+            lease_token BIGINT DEFAULT 0,
             nodes TEXT,
             edges TEXT,
             dead_letter_sink_id TEXT,
@@ -332,8 +358,13 @@ var commonQueries = map[string]string{
 			type TEXT NOT NULL,
 			content TEXT NOT NULL,
 			created_at TIMESTAMP NOT NULL,
+			schema_id INTEGER NOT NULL DEFAULT 0,
 			UNIQUE(name, version)
 		)`,
+	QueryInitSchemaIDsTable: `CREATE TABLE IF NOT EXISTS schema_ids (
+			fingerprint TEXT PRIMARY KEY,
+			id INTEGER NOT NULL UNIQUE
+		)`,
 	QueryInitMessageTraceStepsTable: `CREATE TABLE IF NOT EXISTS message_trace_steps (
 			id TEXT PRIMARY KEY,
 			message_id TEXT NOT NULL,
@@ -415,7 +446,33 @@ var commonQueries = map[string]string{
 			metadata TEXT,
 			data TEXT,
 			resume_at TIMESTAMP NOT NULL,
-			created_at TIMESTAMP NOT NULL
+			created_at TIMESTAMP NOT NULL,
+			wait_key TEXT NOT NULL DEFAULT ''
+		)`,
+	QueryInitLeasesTable: `CREATE TABLE IF NOT EXISTS leases (
+			name TEXT PRIMARY KEY,
+			owner_id TEXT NOT NULL DEFAULT '',
+			lease_until TIMESTAMP NOT NULL,
+			token BIGINT NOT NULL DEFAULT 0,
+			acquired_at TIMESTAMP NOT NULL
+		)`,
+	QueryInitDeadLettersTable: `CREATE TABLE IF NOT EXISTS dead_letters (
+			id TEXT PRIMARY KEY,
+			workflow_id TEXT NOT NULL,
+			sink_id TEXT,
+			message_id TEXT,
+			operation TEXT,
+			table_name TEXT,
+			error_class TEXT,
+			error TEXT,
+			payload BLOB,
+			metadata TEXT,
+			data TEXT,
+			status TEXT DEFAULT 'pending',
+			replay_count INTEGER DEFAULT 0,
+			last_replay_error TEXT,
+			created_at TIMESTAMP NOT NULL,
+			replayed_at TIMESTAMP
 		)`,
 
 	QueryUpdateNodeState: "INSERT INTO workflow_node_states (workflow_id, node_id, state) VALUES (?, ?, ?) ON CONFLICT(workflow_id, node_id) DO UPDATE SET state = excluded.state",
@@ -455,15 +512,15 @@ var commonQueries = map[string]string{
 	QueryDeleteVHost: "DELETE FROM vhosts WHERE id = ?",
 	QueryGetVHost:    "SELECT id, name, description FROM vhosts WHERE id = ?",
 
-	QueryListWorkflows:        "SELECT id, name, vhost, active, status, worker_id, owner_id, lease_until, nodes, edges, dead_letter_sink_id, prioritize_dlq, max_retries, retry_interval, reconnect_interval, dry_run, schema_type, schema, retention_days, cron, idle_timeout, tier, trace_sample_rate, dlq_threshold, tags, workspace_id, trace_retention, audit_retention, cpu_request, memory_request, throughput_request, total_processed, total_errors, total_lag FROM workflows",
+	QueryListWorkflows:        "SELECT id, name, vhost, active, status, worker_id, owner_id, lease_until, lease_token, nodes, edges, dead_letter_sink_id, prioritize_dlq, max_retries, retry_interval, reconnect_interval, dry_run, schema_type, schema, retention_days, cron, idle_timeout, tier, trace_sample_rate, dlq_threshold, tags, workspace_id, trace_retention, audit_retention, cpu_request, memory_request, throughput_request, total_processed, total_errors, total_lag FROM workflows",
 	QueryCountWorkflows:       "SELECT COUNT(*) FROM workflows",
 	QueryCreateWorkflow:       "INSERT INTO workflows (id, name, vhost, active, status, worker_id, nodes, edges, dead_letter_sink_id, prioritize_dlq, max_retries, retry_interval, reconnect_interval, dry_run, schema_type, schema, retention_days, cron, idle_timeout, tier, trace_sample_rate, dlq_threshold, tags, workspace_id, trace_retention, audit_retention, cpu_request, memory_request, throughput_request, total_processed, total_errors, total_lag) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 	QueryUpdateWorkflow:       "UPDATE workflows SET name = ?, vhost = ?, active = ?, status = ?, worker_id = ?, nodes = ?, edges = ?, dead_letter_sink_id = ?, prioritize_dlq = ?, max_retries = ?, retry_interval = ?, reconnect_interval = ?, dry_run = ?, schema_type = ?, schema = ?, retention_days = ?, cron = ?, idle_timeout = ?, tier = ?, trace_sample_rate = ?, dlq_threshold = ?, tags = ?, workspace_id = ?, trace_retention = ?, audit_retention = ?, cpu_request = ?, memory_request = ?, throughput_request = ?, total_processed = ?, total_errors = ?, total_lag = ? WHERE id = ?",
 	QueryUpdateWorkflowStatus: "UPDATE workflows SET status = ? WHERE id = ?",
 	QueryUpdateWorkflowStats:  "UPDATE workflows SET total_processed = ?, total_errors = ?, total_lag = ? WHERE id = ?",
 	QueryDeleteWorkflow:       "DELETE FROM workflows WHERE id = ?",
-	QueryGetWorkflow:          "SELECT id, name, vhost, active, status, worker_id, owner_id, lease_until, nodes, edges, dead_letter_sink_id, prioritize_dlq, max_retries, retry_interval, reconnect_interval, dry_run, schema_type, schema, retention_days, cron, idle_timeout, tier, trace_sample_rate, dlq_threshold, tags, workspace_id, trace_retention, audit_retention, cpu_request, memory_request, throughput_request, total_processed, total_errors, total_lag FROM workflows WHERE id = ?",
-	QueryAcquireLease:         "UPDATE workflows SET owner_id = ?, lease_until = ? WHERE id = ? AND (owner_id IS NULL OR lease_until IS NULL OR lease_until < ? OR owner_id = ?)",
+	QueryGetWorkflow:          "SELECT id, name, vhost, active, status, worker_id, owner_id, lease_until, lease_token, nodes, edges, dead_letter_sink_id, prioritize_dlq, max_retries, retry_interval, reconnect_interval, dry_run, schema_type, schema, retention_days, cron, idle_timeout, tier, trace_sample_rate, dlq_threshold, tags, workspace_id, trace_retention, audit_retention, cpu_request, memory_request, throughput_request, total_processed, total_errors, total_lag FROM workflows WHERE id = ?",
+	QueryAcquireLease:         "UPDATE workflows SET lease_token = CASE WHEN owner_id = ? THEN COALESCE(lease_token, 0) ELSE COALESCE(lease_token, 0) + 1 END, owner_id = ?, lease_until = ? WHERE id = ? AND (owner_id IS NULL OR lease_until IS NULL OR lease_until < ? OR owner_id = ?)",
 	QueryRenewLease:           "UPDATE workflows SET lease_until = ? WHERE id = ? AND owner_id = ? AND lease_until IS NOT NULL AND lease_until >= ?",
 	QueryReleaseLease:         "UPDATE workflows SET owner_id = NULL, lease_until = NULL WHERE id = ? AND owner_id = ?",
 
@@ -506,11 +563,17 @@ var commonQueries = map[string]string{
 	QueryUpdateFormSubmissionStatus: "UPDATE form_submissions SET status = ? WHERE id = ?",
 	QueryDeleteFormSubmissions:      "DELETE FROM form_submissions",
 
-	QueryListSchemas:     "SELECT id, name, version, type, content, created_at FROM schemas WHERE name = ? ORDER BY version DESC",
-	QueryListAllSchemas:  "SELECT id, name, version, type, content, created_at FROM schemas WHERE (name, version) IN (SELECT name, MAX(version) FROM schemas GROUP BY name) ORDER BY name ASC",
-	QueryGetSchema:       "SELECT id, name, version, type, content, created_at FROM schemas WHERE name = ? AND version = ?",
-	QueryGetLatestSchema: "SELECT id, name, version, type, content, created_at FROM schemas WHERE name = ? ORDER BY version DESC LIMIT 1",
-	QueryCreateSchema:    "INSERT INTO schemas (id, name, version, type, content, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+	QueryListSchemas:           "SELECT id, name, version, schema_id, type, content, created_at FROM schemas WHERE name = ? ORDER BY version DESC",
+	QueryListAllSchemas:        "SELECT id, name, version, schema_id, type, content, created_at FROM schemas WHERE (name, version) IN (SELECT name, MAX(version) FROM schemas GROUP BY name) ORDER BY name ASC",
+	QueryGetSchema:             "SELECT id, name, version, schema_id, type, content, created_at FROM schemas WHERE name = ? AND version = ?",
+	QueryGetLatestSchema:       "SELECT id, name, version, schema_id, type, content, created_at FROM schemas WHERE name = ? ORDER BY version DESC LIMIT 1",
+	QueryListSchemasByID:       "SELECT id, name, version, schema_id, type, content, created_at FROM schemas WHERE schema_id = ? ORDER BY name ASC, version ASC",
+	QueryCreateSchema:          "INSERT INTO schemas (id, name, version, schema_id, type, content, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+	QueryGetSchemaID:           "SELECT id FROM schema_ids WHERE fingerprint = ?",
+	QueryNextSchemaID:          "SELECT COALESCE(MAX(id), 0) + 1 FROM schema_ids",
+	QueryCreateSchemaID:        "INSERT INTO schema_ids (fingerprint, id) VALUES (?, ?)",
+	QueryListUnnumberedSchemas: "SELECT id, type, content FROM schemas WHERE schema_id = 0",
+	QuerySetSchemaID:           "UPDATE schemas SET schema_id = ? WHERE id = ?",
 
 	QueryRecordTraceStep:   "INSERT INTO message_trace_steps (id, message_id, workflow_id, node_id, timestamp, duration_ms, before_data, after_data, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
 	QueryGetMessageTrace:   "SELECT node_id, timestamp, duration_ms, before_data, after_data, error FROM message_trace_steps WHERE workflow_id = ? AND message_id = ? ORDER BY timestamp ASC",
@@ -537,10 +600,25 @@ var commonQueries = map[string]string{
 	QueryCreateApproval:       "INSERT INTO approvals (id, workflow_id, node_id, message_id, payload, metadata, data, form_definition, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 	QueryGetApproval:          "SELECT id, workflow_id, node_id, message_id, payload, metadata, data, form_definition, form_data, status, created_at, processed_at, processed_by, notes FROM approvals WHERE id = ?",
 	QueryUpdateApprovalStatus: "UPDATE approvals SET status = ?, processed_at = ?, processed_by = ?, notes = ?, form_data = ? WHERE id = ?",
+	QueryDeleteApproval:       "DELETE FROM approvals WHERE id = ?",
 	// Suspended Messages
-	QueryCreateSuspendedMessage: "INSERT INTO suspended_messages (id, workflow_id, node_id, payload, metadata, data, resume_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
-	QueryListSuspendedMessages:  "SELECT id, workflow_id, node_id, payload, metadata, data, resume_at, created_at FROM suspended_messages WHERE resume_at <= ?",
+	QueryCreateSuspendedMessage: "INSERT INTO suspended_messages (id, workflow_id, node_id, payload, metadata, data, resume_at, created_at, wait_key) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	QueryListSuspendedMessages:  "SELECT id, workflow_id, node_id, payload, metadata, data, resume_at, created_at, wait_key FROM suspended_messages WHERE resume_at <= ?",
 	QueryDeleteSuspendedMessage: "DELETE FROM suspended_messages WHERE id = ?",
+	QueryListEventWaits:         "SELECT id, workflow_id, node_id, payload, metadata, data, resume_at, created_at, wait_key FROM suspended_messages WHERE workflow_id = ? AND node_id = ? AND wait_key = ?",
+	QueryResolveEventWait:       "UPDATE suspended_messages SET metadata = ?, data = ?, resume_at = ?, wait_key = '' WHERE id = ? AND wait_key <> ''",
+	// Dead Letters
+	QueryListDeadLetters:  "SELECT id, workflow_id, sink_id, message_id, operation, table_name, error_class, error, payload, metadata, data, status, replay_count, last_replay_error, created_at, replayed_at FROM dead_letters",
+	QueryCountDeadLetters: "SELECT COUNT(*) FROM dead_letters",
+	QueryCreateDeadLetter: "INSERT INTO dead_letters (id, workflow_id, sink_id, message_id, operation, table_name, error_class, error, payload, metadata, data, status, replay_count, last_replay_error, created_at, replayed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	QueryGetDeadLetter:    "SELECT id, workflow_id, sink_id, message_id, operation, table_name, error_class, error, payload, metadata, data, status, replay_count, last_replay_error, created_at, replayed_at FROM dead_letters WHERE id = ?",
+	QueryUpdateDeadLetter: "UPDATE dead_letters SET sink_id = ?, operation = ?, table_name = ?, error_class = ?, error = ?, payload = ?, metadata = ?, data = ?, status = ?, replay_count = ?, last_replay_error = ?, replayed_at = ? WHERE id = ?",
+	QueryDeleteDeadLetter: "DELETE FROM dead_letters WHERE id = ?",
+
+	QueryAcquireNamedLease: "UPDATE leases SET token = CASE WHEN owner_id = ? THEN token ELSE token + 1 END, acquired_at = CASE WHEN owner_id = ? THEN acquired_at ELSE ? END, owner_id = ?, lease_until = ? WHERE name = ? AND (owner_id = ? OR owner_id = '' OR lease_until < ?)",
+	QueryInsertNamedLease:  "INSERT INTO leases (name, owner_id, lease_until, token, acquired_at) VALUES (?, ?, ?, 1, ?)",
+	QueryReleaseNamedLease: "UPDATE leases SET owner_id = '', lease_until = ? WHERE name = ? AND owner_id = ?",
+	QueryGetNamedLease:     "SELECT name, owner_id, lease_until, token, acquired_at FROM leases WHERE name = ?",
 }
 
 var driverOverrides = map[string]map[string]string{
@@ -175,9 +175,13 @@ func (s *sqlStorage) Init(ctx context.Context) error {
 		s.queries.get(QueryInitVHostsTable),
 		s.queries.get(QueryInitWorkersTable),
 		s.queries.get(QueryInitApprovalsTable),
+		s.queries.get(QueryInitSuspendedMessagesTable),
+		s.queries.get(QueryInitDeadLettersTable),
+		s.queries.get(QueryInitLeasesTable),
 		s.queries.get(QueryInitSettingsTable),
 		s.queries.get(QueryInitAuditLogsTable),
 		s.queries.get(QueryInitSchemasTable),
+		s.queries.get(QueryInitSchemaIDsTable),
 		s.queries.get(QueryInitMessageTraceStepsTable),
 		s.queries.get(QueryInitWorkflowVersionsTable),
 		s.queries.get(QueryInitOutboxTable),
@@ -254,8 +258,12 @@ func (s *sqlStorage) Init(ctx context.Context) error {
 	_, _ = s.db.ExecContext(ctx, s.prepareQuery("CREATE INDEX IF NOT EXISTS idx_trace_msg ON message_trace_steps(workflow_id, message_id)"))
 	_, _ = s.db.ExecContext(ctx, s.prepareQuery("CREATE INDEX IF NOT EXISTS idx_workflow_versions_id ON workflow_versions(workflow_id, version)"))
 	_, _ = s.db.ExecContext(ctx, s.prepareQuery("CREATE INDEX IF NOT EXISTS idx_outbox_status ON outbox(status, created_at)"))
+	_, _ = s.db.ExecContext(ctx, s.prepareQuery("CREATE INDEX IF NOT EXISTS idx_dead_letters_workflow ON dead_letters(workflow_id, created_at)"))
+	_, _ = s.db.ExecContext(ctx, s.prepareQuery("CREATE INDEX IF NOT EXISTS idx_suspended_wait ON suspended_messages(workflow_id, node_id, wait_key)"))
+	_, _ = s.db.ExecContext(ctx, s.prepareQuery("CREATE INDEX IF NOT EXISTS idx_schemas_schema_id ON schemas(schema_id)"))
 
 	s.seedPlugins(ctx)
+	s.numberSchemas(ctx)
 
 	return nil
 }
@@ -266,42 +274,7 @@ func (s *sqlStorage) seedPlugins(ctx context.Context) {
 		return
 	}
 
-	initialPlugins := []storage.Plugin{
-		{
-			ID:          "openai-pii-filter",
-			Name:        "OpenAI PII Filter",
-			Description: "Anonymize sensitive data using OpenAI's GPT-4 before it leaves your infrastructure.",
-			Author:      "Hermod Core",
-			Stars:       128,
-			Category:    "Security",
-			Certified:   true,
-			Type:        "Transformer",
-			WasmURL:     "https://github.com/user/hermod-plugins/raw/main/openai-pii-filter.wasm",
-		},
-		{
-			ID:          "slack-connector",
-			Name:        "Slack Connector",
-			Description: "Send alerts and notifications to Slack channels with advanced formatting.",
-			Author:      "Hermod Core",
-			Stars:       89,
-			Category:    "Connectors",
-			Certified:   true,
-			Type:        "Connector",
-		},
-		{
-			ID:          "xml-to-json",
-			Name:        "XML to JSON",
-			Description: "High-performance WASM-based transformer to convert legacy XML payloads to JSON.",
-			Author:      "Community",
-			Stars:       45,
-			Category:    "Transformation",
-			Certified:   false,
-			Type:        "WASM",
-			WasmURL:     "https://github.com/user/hermod-plugins/raw/main/xml-to-json.wasm",
-		},
-	}
-
-	for _, p := range initialPlugins {
+	for _, p := range storage.DefaultPlugins() {
 		_ = s.execWithRetry(ctx, func() error {
 			_, e := s.exec(ctx, s.queries.get(QueryCreatePlugin),
 				p.ID, p.Name, p.Description, p.Author, p.Stars, p.Category, p.Certified, p.Type, p.WasmURL, p.Installed, p.InstalledAt)
@@ -1209,6 +1182,7 @@ func (s *sqlStorage) ListWorkflows(ctx context.Context, filter storage.CommonFil
 		var wf storage.Workflow
 		var nodesJSON, edgesJSON, tagsJSON sql.NullString
 		var leaseUntil sql.NullTime
+		var leaseToken sql.NullInt64
 		var ownerID sql.NullString
 		var dlqSinkID, retryInterval, reconnectInterval sql.NullString
 		var prioritizeDLQ, dryRun sql.NullBool
@@ -1217,7 +1191,7 @@ func (s *sqlStorage) ListWorkflows(ctx context.Context, filter storage.CommonFil
 		var traceSampleRate sql.NullFloat64
 		var cpuReq, memReq sql.NullFloat64
 		var throughputReq, totalProcessed, totalErrors, totalLag sql.NullInt64
-		if err := rows.Scan(&wf.ID, &wf.Name, &wf.VHost, &wf.Active, &wf.Status, &wf.WorkerID, &ownerID, &leaseUntil, &nodesJSON, &edgesJSON, &dlqSinkID, &prioritizeDLQ, &maxRetries, &retryInterval, &reconnectInterval, &dryRun, &schemaType, &schema, &retentionDays, &cron, &idleTimeout, &tier, &traceSampleRate, &dlqThreshold, &tagsJSON, &workspaceID, &traceRetention, &auditRetention, &cpuReq, &memReq, &throughputReq, &totalProcessed, &totalErrors, &totalLag); err != nil {
+		if err := rows.Scan(&wf.ID, &wf.Name, &wf.VHost, &wf.Active, &wf.Status, &wf.WorkerID, &ownerID, &leaseUntil, &leaseToken, &nodesJSON, &edgesJSON, &dlqSinkID, &prioritizeDLQ, &maxRetries, &retryInterval, &reconnectInterval, &dryRun, &schemaType, &schema, &retentionDays, &cron, &idleTimeout, &tier, &traceSampleRate, &dlqThreshold, &tagsJSON, &workspaceID, &traceRetention, &auditRetention, &cpuReq, &memReq, &throughputReq, &totalProcessed, &totalErrors, &totalLag); err != nil {
 			return nil, 0, err
 		}
 		if cpuReq.Valid {
@@ -1260,6 +1234,7 @@ func (s *sqlStorage) ListWorkflows(ctx context.Context, filter storage.CommonFil
 			t := leaseUntil.Time
 			wf.LeaseUntil = &t
 		}
+		wf.LeaseToken = leaseToken.Int64
 		if dlqSinkID.Valid {
 			wf.DeadLetterSinkID = dlqSinkID.String
 		}
@@ -1372,6 +1347,7 @@ func (s *sqlStorage) GetWorkflow(ctx context.Context, id string) (storage.Workfl
 	var wf storage.Workflow
 	var nodesJSON, edgesJSON, tagsJSON sql.NullString
 	var leaseUntil sql.NullTime
+	var leaseToken sql.NullInt64
 	var ownerID sql.NullString
 	var dlqSinkID, retryInterval, reconnectInterval sql.NullString
 	var prioritizeDLQ, dryRun sql.NullBool
@@ -1380,7 +1356,7 @@ func (s *sqlStorage) GetWorkflow(ctx context.Context, id string) (storage.Workfl
 	var traceSampleRate sql.NullFloat64
 	var cpuReq, memReq sql.NullFloat64
 	var throughputReq, totalProcessed, totalErrors, totalLag sql.NullInt64
-	if err := row.Scan(&wf.ID, &wf.Name, &wf.VHost, &wf.Active, &wf.Status, &wf.WorkerID, &ownerID, &leaseUntil, &nodesJSON, &edgesJSON, &dlqSinkID, &prioritizeDLQ, &maxRetries, &retryInterval, &reconnectInterval, &dryRun, &schemaType, &schema, &retentionDays, &cron, &idleTimeout, &tier, &traceSampleRate, &dlqThreshold, &tagsJSON, &workspaceID, &traceRetention, &auditRetention, &cpuReq, &memReq, &throughputReq, &totalProcessed, &totalErrors, &totalLag); err != nil {
+	if err := row.Scan(&wf.ID, &wf.Name, &wf.VHost, &wf.Active, &wf.Status, &wf.WorkerID, &ownerID, &leaseUntil, &leaseToken, &nodesJSON, &edgesJSON, &dlqSinkID, &prioritizeDLQ, &maxRetries, &retryInterval, &reconnectInterval, &dryRun, &schemaType, &schema, &retentionDays, &cron, &idleTimeout, &tier, &traceSampleRate, &dlqThreshold, &tagsJSON, &workspaceID, &traceRetention, &auditRetention, &cpuReq, &memReq, &throughputReq, &totalProcessed, &totalErrors, &totalLag); err != nil {
 		if err == sql.ErrNoRows {
 			return storage.Workflow{}, storage.ErrNotFound
 		}
@@ -1426,6 +1402,7 @@ func (s *sqlStorage) GetWorkflow(ctx context.Context, id string) (storage.Workfl
 		t := leaseUntil.Time
 		wf.LeaseUntil = &t
 	}
+	wf.LeaseToken = leaseToken.Int64
 	if dlqSinkID.Valid {
 		wf.DeadLetterSinkID = dlqSinkID.String
 	}
@@ -1477,6 +1454,8 @@ func (s *sqlStorage) GetWorkflow(ctx context.Context, id string) (storage.Workfl
 
 // AcquireWorkflowLease attempts to acquire or re-acquire a workflow lease.
 // It succeeds if the workflow is unowned, expired, or already owned by this owner.
+// lease_token is assigned first: MySQL evaluates SET left to right, so it must
+// still see the previous owner_id.
 func (s *sqlStorage) AcquireWorkflowLease(ctx context.Context, workflowID, ownerID string, ttlSeconds int) (bool, error) {
 	if ttlSeconds <= 0 {
 		ttlSeconds = 30
@@ -1488,7 +1467,7 @@ func (s *sqlStorage) AcquireWorkflowLease(ctx context.Context, workflowID, owner
 		var e error
 		res, e = s.exec(ctx,
 			s.queries.get(QueryAcquireLease),
-			ownerID, until, workflowID, now, ownerID,
+			ownerID, ownerID, until, workflowID, now, ownerID,
 		)
 		return e
 	}
@@ -1534,6 +1513,69 @@ func (s *sqlStorage) ReleaseWorkflowLease(ctx context.Context, workflowID, owner
 	return s.execWithRetry(ctx, exec)
 }
 
+// AcquireLease takes or extends the named lease. The row is created by the
+// first owner; a concurrent creator loses on the primary key. token and
+// acquired_at are assigned before owner_id for the same reason as in
+// AcquireWorkflowLease.
+func (s *sqlStorage) AcquireLease(ctx context.Context, name, ownerID string, ttlSeconds int) (bool, error) {
+	if ttlSeconds <= 0 {
+		ttlSeconds = 30
+	}
+	now := time.Now().UTC()
+	until := now.Add(time.Duration(ttlSeconds) * time.Second)
+	var res sql.Result
+	exec := func() error {
+		var e error
+		res, e = s.exec(ctx,
+			s.queries.get(QueryAcquireNamedLease),
+			ownerID, ownerID, now, ownerID, until, name, ownerID, now,
+		)
+		return e
+	}
+	if err := s.execWithRetry(ctx, exec); err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return true, nil
+	}
+
+	insert := func() error {
+		_, e := s.exec(ctx, s.queries.get(QueryInsertNamedLease), name, ownerID, until, now)
+		return e
+	}
+	if err := s.execWithRetry(ctx, insert); err != nil {
+		// Held by someone else, whether it existed before or was created
+		// concurrently.
+		if _, getErr := s.GetLease(ctx, name); getErr == nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLease frees the named lease if owned by ownerID.
+func (s *sqlStorage) ReleaseLease(ctx context.Context, name, ownerID string) error {
+	exec := func() error {
+		_, e := s.exec(ctx,
+			s.queries.get(QueryReleaseNamedLease),
+			time.Now().UTC(), name, ownerID,
+		)
+		return e
+	}
+	return s.execWithRetry(ctx, exec)
+}
+
+func (s *sqlStorage) GetLease(ctx context.Context, name string) (storage.Lease, error) {
+	var l storage.Lease
+	err := s.queryRow(ctx, s.queries.get(QueryGetNamedLease), name).
+		Scan(&l.Name, &l.OwnerID, &l.LeaseUntil, &l.Token, &l.AcquiredAt)
+	if err == sql.ErrNoRows {
+		return l, storage.ErrNotFound
+	}
+	return l, err
+}
+
 func (s *sqlStorage) ListWorkers(ctx context.Context, filter storage.CommonFilter) ([]storage.Worker, int, error) {
 	baseQuery := s.queries.get(QueryListWorkers)
 	countQuery := s.queries.get(QueryCountWorkers)
@@ -2054,6 +2096,9 @@ func (s *sqlStorage) GetWebhookRequest(ctx context.Context, id string) (storage.
 	var req storage.WebhookRequest
 	var headersJSON string
 	err := s.queryRow(ctx, s.queries.get(QueryGetWebhookRequest), id).Scan(&req.ID, &req.Timestamp, &req.Path, &req.Method, &headersJSON, &req.Body)
+	if err == sql.ErrNoRows {
+		return storage.WebhookRequest{}, storage.ErrNotFound
+	}
 	if err != nil {
 		return storage.WebhookRequest{}, err
 	}
@@ -2138,6 +2183,9 @@ func (s *sqlStorage) ListFormSubmissions(ctx context.Context, filter storage.For
 func (s *sqlStorage) GetFormSubmission(ctx context.Context, id string) (storage.FormSubmission, error) {
 	var sub storage.FormSubmission
 	err := s.queryRow(ctx, s.queries.get(QueryGetFormSubmission), id).Scan(&sub.ID, &sub.Timestamp, &sub.Path, &sub.Data, &sub.Status)
+	if err == sql.ErrNoRows {
+		return storage.FormSubmission{}, storage.ErrNotFound
+	}
 	return sub, err
 }
 
@@ -2259,7 +2307,7 @@ func (s *sqlStorage) ListSchemas(ctx context.Context, name string) ([]storage.Sc
 	schemas := []storage.Schema{}
 	for rows.Next() {
 		var sc storage.Schema
-		if err := rows.Scan(&sc.ID, &sc.Name, &sc.Version, &sc.Type, &sc.Content, &sc.CreatedAt); err != nil {
+		if err := rows.Scan(&sc.ID, &sc.Name, &sc.Version, &sc.SchemaID, &sc.Type, &sc.Content, &sc.CreatedAt); err != nil {
 			return nil, err
 		}
 		schemas = append(schemas, sc)
@@ -2277,7 +2325,25 @@ func (s *sqlStorage) ListAllSchemas(ctx context.Context) ([]storage.Schema, erro
 	schemas := []storage.Schema{}
 	for rows.Next() {
 		var sc storage.Schema
-		if err := rows.Scan(&sc.ID, &sc.Name, &sc.Version, &sc.Type, &sc.Content, &sc.CreatedAt); err != nil {
+		if err := rows.Scan(&sc.ID, &sc.Name, &sc.Version, &sc.SchemaID, &sc.Type, &sc.Content, &sc.CreatedAt); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, sc)
+	}
+	return schemas, nil
+}
+
+func (s *sqlStorage) ListSchemasByID(ctx context.Context, schemaID int) ([]storage.Schema, error) {
+	rows, err := s.query(ctx, s.queries.get(QueryListSchemasByID), schemaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemas := []storage.Schema{}
+	for rows.Next() {
+		var sc storage.Schema
+		if err := rows.Scan(&sc.ID, &sc.Name, &sc.Version, &sc.SchemaID, &sc.Type, &sc.Content, &sc.CreatedAt); err != nil {
 			return nil, err
 		}
 		schemas = append(schemas, sc)
@@ -2287,18 +2353,18 @@ func (s *sqlStorage) ListAllSchemas(ctx context.Context) ([]storage.Schema, erro
 
 func (s *sqlStorage) GetSchema(ctx context.Context, name string, version int) (storage.Schema, error) {
 	var sc storage.Schema
-	err := s.queryRow(ctx, s.queries.get(QueryGetSchema), name, version).Scan(&sc.ID, &sc.Name, &sc.Version, &sc.Type, &sc.Content, &sc.CreatedAt)
+	err := s.queryRow(ctx, s.queries.get(QueryGetSchema), name, version).Scan(&sc.ID, &sc.Name, &sc.Version, &sc.SchemaID, &sc.Type, &sc.Content, &sc.CreatedAt)
 	if err == sql.ErrNoRows {
-		return storage.Schema{}, fmt.Errorf("schema %s version %d not found", name, version)
+		return storage.Schema{}, fmt.Errorf("%w: schema %s version %d", storage.ErrNotFound, name, version)
 	}
 	return sc, err
 }
 
 func (s *sqlStorage) GetLatestSchema(ctx context.Context, name string) (storage.Schema, error) {
 	var sc storage.Schema
-	err := s.queryRow(ctx, s.queries.get(QueryGetLatestSchema), name).Scan(&sc.ID, &sc.Name, &sc.Version, &sc.Type, &sc.Content, &sc.CreatedAt)
+	err := s.queryRow(ctx, s.queries.get(QueryGetLatestSchema), name).Scan(&sc.ID, &sc.Name, &sc.Version, &sc.SchemaID, &sc.Type, &sc.Content, &sc.CreatedAt)
 	if err == sql.ErrNoRows {
-		return storage.Schema{}, fmt.Errorf("schema %s not found", name)
+		return storage.Schema{}, fmt.Errorf("%w: schema %s", storage.ErrNotFound, name)
 	}
 	return sc, err
 }
@@ -2311,14 +2377,72 @@ func (s *sqlStorage) CreateSchema(ctx context.Context, sc storage.Schema) error
 		sc.CreatedAt = time.Now()
 	}
 
+	if sc.SchemaID == 0 {
+		id, err := s.schemaID(ctx, sc.Type, sc.Content)
+		if err != nil {
+			return err
+		}
+		sc.SchemaID = id
+	}
+
 	exec := func() error {
 		_, err := s.exec(ctx, s.queries.get(QueryCreateSchema),
-			sc.ID, sc.Name, sc.Version, sc.Type, sc.Content, sc.CreatedAt)
+			sc.ID, sc.Name, sc.Version, sc.SchemaID, sc.Type, sc.Content, sc.CreatedAt)
 		return err
 	}
 	return s.execWithRetry(ctx, exec)
 }
 
+// schemaID returns the ID of the schema with this type and content,
+// assigning the next free one on first use. The fingerprint and the ID are
+// both unique, so when two registrations race for an ID the loser looks its
+// schema up again and retries.
+func (s *sqlStorage) schemaID(ctx context.Context, schemaType, content string) (int, error) {
+	fingerprint := storage.SchemaFingerprint(schemaType, content)
+	var err error
+	for range 5 {
+		var id int
+		err = s.queryRow(ctx, s.queries.get(QueryGetSchemaID), fingerprint).Scan(&id)
+		if err == nil {
+			return id, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, err
+		}
+		if err = s.queryRow(ctx, s.queries.get(QueryNextSchemaID)).Scan(&id); err != nil {
+			return 0, err
+		}
+		if _, err = s.exec(ctx, s.queries.get(QueryCreateSchemaID), fingerprint, id); err == nil {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("failed to assign a schema id: %w", err)
+}
+
+// numberSchemas assigns IDs to schemas stored before they had one.
+func (s *sqlStorage) numberSchemas(ctx context.Context) {
+	rows, err := s.query(ctx, s.queries.get(QueryListUnnumberedSchemas))
+	if err != nil {
+		return
+	}
+	var unnumbered []storage.Schema
+	for rows.Next() {
+		var sc storage.Schema
+		if err := rows.Scan(&sc.ID, &sc.Type, &sc.Content); err == nil {
+			unnumbered = append(unnumbered, sc)
+		}
+	}
+	rows.Close()
+
+	for _, sc := range unnumbered {
+		id, err := s.schemaID(ctx, sc.Type, sc.Content)
+		if err != nil {
+			return
+		}
+		_, _ = s.exec(ctx, s.queries.get(QuerySetSchemaID), id, sc.ID)
+	}
+}
+
 func (s *sqlStorage) RecordTraceStep(ctx context.Context, workflowID, messageID string, step hermod.TraceStep) error {
 	id := uuid.New().String()
 
@@ -2436,6 +2560,7 @@ func parseTimeString(s string) time.Time {
 	layouts := []string{
 		time.RFC3339Nano,
 		time.RFC3339,
+		"2006-01-02 15:04:05.999999999 -0700 MST",
 		"2006-01-02 15:04:05.999999999-07:00",
 		"2006-01-02 15:04:05.999999999Z07:00",
 		"2006-01-02 15:04:05.999999999",
@@ -2646,6 +2771,9 @@ func (s *sqlStorage) GetPlugin(ctx context.Context, id string) (storage.Plugin,
 	var p storage.Plugin
 	var installedAt sql.NullTime
 	err := s.queryRow(ctx, s.queries.get(QueryGetPlugin), id).Scan(&p.ID, &p.Name, &p.Description, &p.Author, &p.Stars, &p.Category, &p.Certified, &p.Type, &p.WasmURL, &p.Installed, &installedAt)
+	if err == sql.ErrNoRows {
+		return storage.Plugin{}, storage.ErrNotFound
+	}
 	if err != nil {
 		return p, err
 	}
@@ -2709,7 +2837,11 @@ func (s *sqlStorage) ListApprovals(ctx context.Context, filter storage.ApprovalF
 
 	query += " ORDER BY created_at DESC"
 	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d OFFSET %d", filter.Limit, filter.Page*filter.Limit)
+		offset := (filter.Page - 1) * filter.Limit
+		if offset < 0 {
+			offset = 0
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", filter.Limit, offset)
 	}
 
 	var total int
@@ -2808,7 +2940,7 @@ func (s *sqlStorage) CreateSuspendedMessage(ctx context.Context, m storage.Suspe
 
 	exec := func() error {
 		_, err := s.exec(ctx, s.queries.get(QueryCreateSuspendedMessage),
-			m.ID, m.WorkflowID, m.NodeID, m.Payload, string(metadata), string(data), m.ResumeAt, m.CreatedAt)
+			m.ID, m.WorkflowID, m.NodeID, m.Payload, string(metadata), string(data), m.ResumeAt, m.CreatedAt, m.WaitKey)
 		return err
 	}
 	return s.execWithRetry(ctx, exec)
@@ -2824,7 +2956,10 @@ func (s *sqlStorage) ListSuspendedMessages(ctx context.Context, workflowID strin
 		query = s.queries.get(QueryListSuspendedMessages)
 		args = []any{before}
 	}
+	return s.listSuspendedMessages(ctx, query, args...)
+}
 
+func (s *sqlStorage) listSuspendedMessages(ctx context.Context, query string, args ...any) ([]storage.SuspendedMessage, error) {
 	rows, err := s.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
@@ -2835,7 +2970,7 @@ func (s *sqlStorage) ListSuspendedMessages(ctx context.Context, workflowID strin
 	for rows.Next() {
 		var m storage.SuspendedMessage
 		var metadata, data sql.NullString
-		err := rows.Scan(&m.ID, &m.WorkflowID, &m.NodeID, &m.Payload, &metadata, &data, &m.ResumeAt, &m.CreatedAt)
+		err := rows.Scan(&m.ID, &m.WorkflowID, &m.NodeID, &m.Payload, &metadata, &data, &m.ResumeAt, &m.CreatedAt, &m.WaitKey)
 		if err != nil {
 			return nil, err
 		}
@@ -2858,6 +2993,27 @@ func (s *sqlStorage) DeleteSuspendedMessage(ctx context.Context, id string) erro
 	return s.execWithRetry(ctx, exec)
 }
 
+func (s *sqlStorage) ListEventWaits(ctx context.Context, workflowID, nodeID, key string) ([]storage.SuspendedMessage, error) {
+	return s.listSuspendedMessages(ctx, s.queries.get(QueryListEventWaits), workflowID, nodeID, key)
+}
+
+func (s *sqlStorage) ResolveEventWait(ctx context.Context, m storage.SuspendedMessage) (bool, error) {
+	metadata, _ := json.Marshal(m.Metadata)
+	data, _ := json.Marshal(m.Data)
+
+	var res sql.Result
+	exec := func() error {
+		var e error
+		res, e = s.exec(ctx, s.queries.get(QueryResolveEventWait), string(metadata), string(data), m.ResumeAt, m.ID)
+		return e
+	}
+	if err := s.execWithRetry(ctx, exec); err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
 func (s *sqlStorage) UpdateApprovalStatus(ctx context.Context, id string, status string, processedBy string, notes string, formData map[string]any) error {
 	formDataBytes, _ := json.Marshal(formData)
 	exec := func() error {
@@ -2952,3 +3108,190 @@ func (s *sqlStorage) GetDashboardStats(ctx context.Context, vhost string) (stora
 
 	return stats, nil
 }
+
+func deadLetterWhere(filter storage.DeadLetterFilter) (string, []any) {
+	var where []string
+	var args []any
+	if filter.WorkflowID != "" {
+		where = append(where, "workflow_id = ?")
+		args = append(args, filter.WorkflowID)
+	}
+	if filter.SinkID != "" {
+		where = append(where, "sink_id = ?")
+		args = append(args, filter.SinkID)
+	}
+	if filter.ErrorClass != "" {
+		where = append(where, "error_class = ?")
+		args = append(args, filter.ErrorClass)
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at <= ?")
+		args = append(args, filter.Until)
+	}
+	if filter.Search != "" {
+		where = append(where, "(message_id LIKE ? OR error LIKE ?)")
+		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+	if len(where) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDeadLetter(row rowScanner) (storage.DeadLetter, error) {
+	var dl storage.DeadLetter
+	var sinkID, messageID, operation, table, errorClass, errText, lastReplayError sql.NullString
+	var metadata, data, status sql.NullString
+	var replayCount sql.NullInt64
+	var replayedAt sql.NullTime
+	err := row.Scan(&dl.ID, &dl.WorkflowID, &sinkID, &messageID, &operation, &table, &errorClass, &errText,
+		&dl.Payload, &metadata, &data, &status, &replayCount, &lastReplayError, &dl.CreatedAt, &replayedAt)
+	if err != nil {
+		return dl, err
+	}
+	dl.SinkID = sinkID.String
+	dl.MessageID = messageID.String
+	dl.Operation = operation.String
+	dl.Table = table.String
+	dl.ErrorClass = errorClass.String
+	dl.Error = errText.String
+	dl.Status = status.String
+	dl.ReplayCount = int(replayCount.Int64)
+	dl.LastReplayError = lastReplayError.String
+	if metadata.Valid {
+		_ = json.Unmarshal([]byte(metadata.String), &dl.Metadata)
+	}
+	if data.Valid {
+		_ = json.Unmarshal([]byte(data.String), &dl.Data)
+	}
+	if replayedAt.Valid {
+		dl.ReplayedAt = &replayedAt.Time
+	}
+	return dl, nil
+}
+
+func (s *sqlStorage) ListDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) ([]storage.DeadLetter, int, error) {
+	where, args := deadLetterWhere(filter)
+	query := s.queries.get(QueryListDeadLetters) + where + " ORDER BY created_at DESC"
+	countQuery := s.queries.get(QueryCountDeadLetters) + where
+	if filter.Limit > 0 {
+		offset := (filter.Page - 1) * filter.Limit
+		if offset < 0 {
+			offset = 0
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", filter.Limit, offset)
+	}
+
+	var total int
+	if err := s.queryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var letters []storage.DeadLetter
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		letters = append(letters, dl)
+	}
+	return letters, total, rows.Err()
+}
+
+func (s *sqlStorage) CreateDeadLetter(ctx context.Context, dl storage.DeadLetter) error {
+	if dl.ID == "" {
+		dl.ID = uuid.New().String()
+	}
+	if dl.CreatedAt.IsZero() {
+		dl.CreatedAt = time.Now()
+	}
+	if dl.Status == "" {
+		dl.Status = "pending"
+	}
+	metadata, _ := json.Marshal(dl.Metadata)
+	data, _ := json.Marshal(dl.Data)
+
+	exec := func() error {
+		_, err := s.exec(ctx, s.queries.get(QueryCreateDeadLetter),
+			dl.ID, dl.WorkflowID, dl.SinkID, dl.MessageID, dl.Operation, dl.Table, dl.ErrorClass, dl.Error,
+			dl.Payload, string(metadata), string(data), dl.Status, dl.ReplayCount, dl.LastReplayError, dl.CreatedAt, dl.ReplayedAt)
+		return err
+	}
+	return s.execWithRetry(ctx, exec)
+}
+
+func (s *sqlStorage) GetDeadLetter(ctx context.Context, id string) (storage.DeadLetter, error) {
+	dl, err := scanDeadLetter(s.queryRow(ctx, s.queries.get(QueryGetDeadLetter), id))
+	if err == sql.ErrNoRows {
+		return dl, storage.ErrNotFound
+	}
+	return dl, err
+}
+
+func (s *sqlStorage) UpdateDeadLetter(ctx context.Context, dl storage.DeadLetter) error {
+	metadata, _ := json.Marshal(dl.Metadata)
+	data, _ := json.Marshal(dl.Data)
+	exec := func() error {
+		res, err := s.exec(ctx, s.queries.get(QueryUpdateDeadLetter),
+			dl.SinkID, dl.Operation, dl.Table, dl.ErrorClass, dl.Error, dl.Payload, string(metadata), string(data),
+			dl.Status, dl.ReplayCount, dl.LastReplayError, dl.ReplayedAt, dl.ID)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		if n == 0 {
+			return storage.ErrNotFound
+		}
+		return nil
+	}
+	return s.execWithRetry(ctx, exec)
+}
+
+func (s *sqlStorage) DeleteDeadLetter(ctx context.Context, id string) error {
+	exec := func() error {
+		res, err := s.exec(ctx, s.queries.get(QueryDeleteDeadLetter), id)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		if n == 0 {
+			return storage.ErrNotFound
+		}
+		return nil
+	}
+	return s.execWithRetry(ctx, exec)
+}
+
+func (s *sqlStorage) PurgeDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) (int, error) {
+	where, args := deadLetterWhere(filter)
+	var purged int
+	exec := func() error {
+		res, err := s.exec(ctx, "DELETE FROM dead_letters"+where, args...)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		purged = int(n)
+		return nil
+	}
+	err := s.execWithRetry(ctx, exec)
+	return purged, err
+}
@@ -285,3 +285,47 @@ func TestSQLStorage_VHostEmptyIDBackfill(t *testing.T) {
 		t.Errorf("expected description 'now editable', got %q", updated.Description)
 	}
 }
+
+func TestSQLStorage_SchemaIDBackfill(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	s := NewSQLStorage(db, "sqlite")
+	ctx := t.Context()
+
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	if err := s.CreateSchema(ctx, storage.Schema{Name: "orders", Version: 1, Type: "json", Content: `{"v":1}`}); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+
+	// Simulate versions stored before schemas had an ID.
+	for _, q := range []string{
+		"INSERT INTO schemas (id, name, version, type, content, created_at) VALUES ('a', 'archive', 1, 'json', '{\"v\":1}', CURRENT_TIMESTAMP)",
+		"INSERT INTO schemas (id, name, version, type, content, created_at) VALUES ('b', 'archive', 2, 'json', '{\"v\":2}', CURRENT_TIMESTAMP)",
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatalf("failed to insert unnumbered schema: %v", err)
+		}
+	}
+
+	// Re-run Init to trigger the backfill.
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("failed to re-init storage: %v", err)
+	}
+
+	orders, _ := s.GetSchema(ctx, "orders", 1)
+	v1, _ := s.GetSchema(ctx, "archive", 1)
+	v2, _ := s.GetSchema(ctx, "archive", 2)
+	if v1.SchemaID != orders.SchemaID {
+		t.Errorf("expected archive@1 to share id %d with orders@1, got %d", orders.SchemaID, v1.SchemaID)
+	}
+	if v2.SchemaID == 0 || v2.SchemaID == orders.SchemaID {
+		t.Errorf("expected archive@2 to get its own id, got %d", v2.SchemaID)
+	}
+}
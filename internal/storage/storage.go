@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -124,6 +126,7 @@ type Workflow struct {
 	WorkerID          string         `json:"worker_id"`
 	OwnerID           string         `json:"owner_id,omitempty"`
 	LeaseUntil        *time.Time     `json:"lease_until" omitzero:"true"`
+	LeaseToken        int64          `json:"lease_token,omitempty"` // fencing token; rises whenever the lease changes owner
 	Nodes             []WorkflowNode `json:"nodes"`
 	Edges             []WorkflowEdge `json:"edges"`
 	DeadLetterSinkID  string         `json:"dead_letter_sink_id,omitempty"`
@@ -154,6 +157,27 @@ type Workflow struct {
 	TotalLag          uint64   `json:"total_lag,omitempty"`
 }
 
+// WorkflowLease is exchanged with the platform's workflow lease endpoints.
+// Remote workers send OwnerID and TTLSeconds; the platform answers with
+// whether the lease is held and, if so, its fencing token and expiry.
+type WorkflowLease struct {
+	OwnerID    string     `json:"owner_id"`
+	TTLSeconds int        `json:"ttl_seconds,omitempty"`
+	Held       bool       `json:"held"`
+	Token      int64      `json:"token,omitempty"`
+	LeaseUntil *time.Time `json:"lease_until,omitempty"`
+}
+
+// Lease is a named claim held by at most one owner at a time, such as the
+// leadership of the platform's singleton background services.
+type Lease struct {
+	Name       string    `json:"name"`
+	OwnerID    string    `json:"owner_id"`
+	LeaseUntil time.Time `json:"lease_until"`
+	Token      int64     `json:"token"`       // rises whenever the lease changes owner
+	AcquiredAt time.Time `json:"acquired_at"` // when the current owner took the lease over
+}
+
 type WorkflowHealth struct {
 	WorkflowID string        `json:"workflow_id"`
 	Status     string        `json:"status"` // "healthy", "degraded", "error"
@@ -270,14 +294,24 @@ type FormSubmissionFilter struct {
 }
 
 type Schema struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Version   int       `json:"version"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	// SchemaID is assigned by CreateSchema and shared by every version, of
+	// any subject, with the same type and content.
+	SchemaID  int       `json:"schema_id,omitempty"`
 	Type      string    `json:"type"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at" omitzero:"true"`
 }
 
+// SchemaFingerprint identifies a distinct schema by its type and content.
+// Storage keys schema IDs by it.
+func SchemaFingerprint(schemaType, content string) string {
+	sum := sha256.Sum256([]byte(schemaType + "\x00" + content))
+	return hex.EncodeToString(sum[:])
+}
+
 type Plugin struct {
 	ID          string     `json:"id"`
 	Name        string     `json:"name"`
@@ -361,6 +395,39 @@ type SuspendedMessage struct {
 	Data       map[string]any    `json:"data"`
 	ResumeAt   time.Time         `json:"resume_at"`
 	CreatedAt  time.Time         `json:"created_at"`
+	// WaitKey is the correlation key of a message waiting for an event. It
+	// is cleared once an event or the timeout resolves the wait.
+	WaitKey string `json:"wait_key,omitempty"`
+}
+
+// DeadLetter is a message a workflow could not deliver, kept so it can be
+// inspected, corrected and replayed.
+type DeadLetter struct {
+	ID              string            `json:"id"`
+	WorkflowID      string            `json:"workflow_id"`
+	SinkID          string            `json:"sink_id,omitempty"`
+	MessageID       string            `json:"message_id"`
+	Operation       string            `json:"operation,omitempty"`
+	Table           string            `json:"table,omitempty"`
+	ErrorClass      string            `json:"error_class"`
+	Error           string            `json:"error,omitempty"`
+	Payload         []byte            `json:"payload"`
+	Metadata        map[string]string `json:"metadata"`
+	Data            map[string]any    `json:"data"`
+	Status          string            `json:"status"` // pending, replayed
+	ReplayCount     int               `json:"replay_count"`
+	LastReplayError string            `json:"last_replay_error,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	ReplayedAt      *time.Time        `json:"replayed_at,omitempty"`
+}
+
+// DeadLetterFilter selects dead letters. Since and Until bound CreatedAt.
+type DeadLetterFilter struct {
+	CommonFilter
+	WorkflowID string
+	SinkID     string
+	ErrorClass string
+	Status     string
 }
 
 type DashboardStats struct {
@@ -429,13 +496,24 @@ type Storage interface {
 
 	// Lease-based ownership for workflows
 	// AcquireWorkflowLease attempts to set owner_id and extend lease_until atomically with TTL seconds.
-	// Returns true if the lease was acquired.
+	// Returns true if the lease was acquired. Taking over a lease from another owner, or one that was
+	// released, increments lease_token.
 	AcquireWorkflowLease(ctx context.Context, workflowID, ownerID string, ttlSeconds int) (bool, error)
 	// RenewWorkflowLease extends lease_until for an existing owner if not expired yet. Returns true if renewed.
 	RenewWorkflowLease(ctx context.Context, workflowID, ownerID string, ttlSeconds int) (bool, error)
 	// ReleaseWorkflowLease clears ownership if owned by ownerID.
 	ReleaseWorkflowLease(ctx context.Context, workflowID, ownerID string) error
 
+	// Named leases
+	// AcquireLease takes the named lease for ownerID, or extends it when ownerID already holds it.
+	// Returns false while another owner's lease is unexpired. A change of owner increments Token
+	// and resets AcquiredAt.
+	AcquireLease(ctx context.Context, name, ownerID string, ttlSeconds int) (bool, error)
+	// ReleaseLease frees the named lease if owned by ownerID, so another owner can take it at once.
+	ReleaseLease(ctx context.Context, name, ownerID string) error
+	// GetLease returns the named lease, or ErrNotFound if it was never taken.
+	GetLease(ctx context.Context, name string) (Lease, error)
+
 	ListWorkers(ctx context.Context, filter CommonFilter) ([]Worker, int, error)
 	CreateWorker(ctx context.Context, worker Worker) error
 	UpdateWorker(ctx context.Context, worker Worker) error
@@ -477,6 +555,9 @@ type Storage interface {
 	ListAllSchemas(ctx context.Context) ([]Schema, error)
 	GetSchema(ctx context.Context, name string, version int) (Schema, error)
 	GetLatestSchema(ctx context.Context, name string) (Schema, error)
+	// ListSchemasByID returns the versions holding a schema ID, ordered by
+	// name and version.
+	ListSchemasByID(ctx context.Context, schemaID int) ([]Schema, error)
 	CreateSchema(ctx context.Context, schema Schema) error
 
 	// Message Tracing
@@ -515,6 +596,23 @@ type Storage interface {
 	CreateSuspendedMessage(ctx context.Context, m SuspendedMessage) error
 	ListSuspendedMessages(ctx context.Context, workflowID string, before time.Time) ([]SuspendedMessage, error)
 	DeleteSuspendedMessage(ctx context.Context, id string) error
+	// ListEventWaits lists the messages a wait_for_event node suspended for
+	// key that are still waiting.
+	ListEventWaits(ctx context.Context, workflowID, nodeID, key string) ([]SuspendedMessage, error)
+	// ResolveEventWait ends the wait of m, storing its metadata, data and
+	// resume time. It reports false when the wait was already resolved, so
+	// only one of the workers racing for it resumes the message.
+	ResolveEventWait(ctx context.Context, m SuspendedMessage) (bool, error)
+
+	// Dead Letters
+	CreateDeadLetter(ctx context.Context, dl DeadLetter) error
+	ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]DeadLetter, int, error)
+	GetDeadLetter(ctx context.Context, id string) (DeadLetter, error)
+	UpdateDeadLetter(ctx context.Context, dl DeadLetter) error
+	DeleteDeadLetter(ctx context.Context, id string) error
+	// PurgeDeadLetters deletes every dead letter matching filter, ignoring
+	// pagination, and returns how many were removed.
+	PurgeDeadLetters(ctx context.Context, filter DeadLetterFilter) (int, error)
 
 	// Aggregated Dashboard Stats
 	GetDashboardStats(ctx context.Context, vhost string) (DashboardStats, error)
@@ -0,0 +1,1529 @@
+// Package storagetest provides a conformance suite that every storage.Storage
+// backend must pass. Backends call Run from their own tests so that the SQL,
+// MongoDB and Pebble implementations cannot drift apart.
+package storagetest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/storage"
+)
+
+// Run executes the conformance suite. newStore must return a fresh, empty
+// backend for every call; Run initializes it.
+func Run(t *testing.T, newStore func(t *testing.T) storage.Storage) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storage)
+	}{
+		{"Init", testInit},
+		{"Sources", testSources},
+		{"Sinks", testSinks},
+		{"Users", testUsers},
+		{"VHosts", testVHosts},
+		{"Workspaces", testWorkspaces},
+		{"Workflows", testWorkflows},
+		{"Leases", testLeases},
+		{"NamedLeases", testNamedLeases},
+		{"Workers", testWorkers},
+		{"Logs", testLogs},
+		{"AuditLogs", testAuditLogs},
+		{"WebhookRequests", testWebhookRequests},
+		{"FormSubmissions", testFormSubmissions},
+		{"Settings", testSettings},
+		{"NodeStates", testNodeStates},
+		{"Schemas", testSchemas},
+		{"MessageTraces", testMessageTraces},
+		{"WorkflowVersions", testWorkflowVersions},
+		{"Outbox", testOutbox},
+		{"Lineage", testLineage},
+		{"Plugins", testPlugins},
+		{"Approvals", testApprovals},
+		{"SuspendedMessages", testSuspendedMessages},
+		{"DeadLetters", testDeadLetters},
+		{"DashboardStats", testDashboardStats},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			if err := s.Init(t.Context()); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+			tt.fn(t, s)
+		})
+	}
+}
+
+// base is a fixed, millisecond-aligned reference time so that backends with
+// millisecond precision round-trip timestamps exactly.
+var base = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func at(offset time.Duration) time.Time { return base.Add(offset) }
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func wantNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("%s: expected storage.ErrNotFound, got %v", what, err)
+	}
+}
+
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d < time.Millisecond && d > -time.Millisecond
+}
+
+func wantTime(t *testing.T, what string, got, want time.Time) {
+	t.Helper()
+	if !sameTime(got, want) {
+		t.Errorf("%s: got %v, want %v", what, got, want)
+	}
+}
+
+// sameJSON compares values by their JSON encoding, which hides differences in
+// how backends decode numbers and nested documents into interface values.
+func sameJSON(t *testing.T, what string, got, want any) {
+	t.Helper()
+	norm := func(v any) any {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", what, err)
+		}
+		var out any
+		_ = json.Unmarshal(b, &out)
+		return out
+	}
+	if g, w := norm(got), norm(want); !reflect.DeepEqual(g, w) {
+		t.Errorf("%s: got %v, want %v", what, g, w)
+	}
+}
+
+func wantIDs[T any](t *testing.T, what string, items []T, id func(T) string, want ...string) {
+	t.Helper()
+	got := make([]string, 0, len(items))
+	for _, it := range items {
+		got = append(got, id(it))
+	}
+	sort.Strings(got)
+	want = append([]string(nil), want...)
+	sort.Strings(want)
+	if len(want) == 0 && len(got) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got ids %v, want %v", what, got, want)
+	}
+}
+
+func wantOrder[T any](t *testing.T, what string, items []T, id func(T) string, want ...string) {
+	t.Helper()
+	got := make([]string, 0, len(items))
+	for _, it := range items {
+		got = append(got, id(it))
+	}
+	if len(want) == 0 && len(got) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got order %v, want %v", what, got, want)
+	}
+}
+
+func wantTotal(t *testing.T, what string, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s: total = %d, want %d", what, got, want)
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// pages lists every page of size limit and returns the concatenated results,
+// checking that each page reports the same total.
+func pages[T any](t *testing.T, limit, total int, list func(page int) ([]T, int, error)) []T {
+	t.Helper()
+	var all []T
+	for page := 1; (page-1)*limit < total+limit; page++ {
+		items, n, err := list(page)
+		must(t, err)
+		wantTotal(t, fmt.Sprintf("page %d", page), n, total)
+		if len(items) > limit {
+			t.Fatalf("page %d returned %d items, limit %d", page, len(items), limit)
+		}
+		all = append(all, items...)
+	}
+	return all
+}
+
+func testInit(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("second Init: %v", err)
+	}
+	if err := s.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
+func sourceID(s storage.Source) string { return s.ID }
+
+func testSources(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for _, src := range []storage.Source{
+		{ID: "src-1", Name: "orders", Type: "postgres", VHost: "prod", Active: true, WorkerID: "w1", WorkspaceID: "ws1",
+			Config: hermod.StringMap{"host": "db", "password": "secret"}, Sample: `{"id":1}`, State: map[string]string{"lsn": "0/1"}},
+		{ID: "src-2", Name: "events", Type: "kafka", VHost: "prod", WorkerID: "w2"},
+		{ID: "src-3", Name: "clicks", Type: "webhook", VHost: "dev", Active: true, WorkerID: "w1"},
+	} {
+		must(t, s.CreateSource(ctx, src))
+	}
+
+	got, err := s.GetSource(ctx, "src-1")
+	must(t, err)
+	if got.Name != "orders" || got.Type != "postgres" || got.VHost != "prod" || !got.Active ||
+		got.WorkerID != "w1" || got.WorkspaceID != "ws1" || got.Sample != `{"id":1}` {
+		t.Errorf("GetSource: unexpected source %+v", got)
+	}
+	sameJSON(t, "source config", got.Config, map[string]string{"host": "db", "password": "secret"})
+	sameJSON(t, "source state", got.State, map[string]string{"lsn": "0/1"})
+	_, err = s.GetSource(ctx, "missing")
+	wantNotFound(t, "GetSource", err)
+
+	cases := []struct {
+		name   string
+		filter storage.CommonFilter
+		want   []string
+	}{
+		{"all", storage.CommonFilter{}, []string{"src-1", "src-2", "src-3"}},
+		{"vhost", storage.CommonFilter{VHost: "prod"}, []string{"src-1", "src-2"}},
+		{"vhost all", storage.CommonFilter{VHost: "all"}, []string{"src-1", "src-2", "src-3"}},
+		{"workspace", storage.CommonFilter{WorkspaceID: "ws1"}, []string{"src-1"}},
+		{"worker", storage.CommonFilter{WorkerID: "w1"}, []string{"src-1", "src-3"}},
+		{"active", storage.CommonFilter{Active: boolPtr(true)}, []string{"src-1", "src-3"}},
+		{"inactive", storage.CommonFilter{Active: boolPtr(false)}, []string{"src-2"}},
+		{"search name", storage.CommonFilter{Search: "ORD"}, []string{"src-1"}},
+		{"search type", storage.CommonFilter{Search: "kafka"}, []string{"src-2"}},
+		{"search vhost", storage.CommonFilter{Search: "dev"}, []string{"src-3"}},
+		{"search id", storage.CommonFilter{Search: "src-2"}, []string{"src-2"}},
+		{"search literal", storage.CommonFilter{Search: ".*"}, nil},
+	}
+	for _, c := range cases {
+		list, total, err := s.ListSources(ctx, c.filter)
+		must(t, err)
+		wantIDs(t, "ListSources "+c.name, list, sourceID, c.want...)
+		wantTotal(t, "ListSources "+c.name, total, len(c.want))
+	}
+	all := pages(t, 2, 3, func(page int) ([]storage.Source, int, error) {
+		return s.ListSources(ctx, storage.CommonFilter{Page: page, Limit: 2})
+	})
+	wantIDs(t, "ListSources pages", all, sourceID, "src-1", "src-2", "src-3")
+
+	got.Name = "orders-v2"
+	got.Active = false
+	got.Config = hermod.StringMap{"host": "db2"}
+	must(t, s.UpdateSource(ctx, got))
+	must(t, s.UpdateSourceStatus(ctx, "src-1", "running"))
+	must(t, s.UpdateSourceState(ctx, "src-1", map[string]string{"lsn": "0/2"}))
+	got, err = s.GetSource(ctx, "src-1")
+	must(t, err)
+	if got.Name != "orders-v2" || got.Active || got.Status != "running" {
+		t.Errorf("after update: unexpected source %+v", got)
+	}
+	sameJSON(t, "updated config", got.Config, map[string]string{"host": "db2"})
+	sameJSON(t, "updated state", got.State, map[string]string{"lsn": "0/2"})
+	list, _, err := s.ListSources(ctx, storage.CommonFilter{Search: "orders"})
+	must(t, err)
+	if len(list) != 1 || list[0].Config["host"] != "db2" {
+		t.Errorf("ListSources after update: %+v", list)
+	}
+
+	must(t, s.DeleteSource(ctx, "src-1"))
+	must(t, s.DeleteSource(ctx, "missing"))
+	_, err = s.GetSource(ctx, "src-1")
+	wantNotFound(t, "GetSource after delete", err)
+	list, total, err := s.ListSources(ctx, storage.CommonFilter{})
+	must(t, err)
+	wantIDs(t, "ListSources after delete", list, sourceID, "src-2", "src-3")
+	wantTotal(t, "ListSources after delete", total, 2)
+}
+
+func sinkID(s storage.Sink) string { return s.ID }
+
+func testSinks(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for _, snk := range []storage.Sink{
+		{ID: "snk-1", Name: "warehouse", Type: "snowflake", VHost: "prod", Active: true, WorkerID: "w1", WorkspaceID: "ws1",
+			Config: hermod.StringMap{"account": "acme", "password": "secret"}},
+		{ID: "snk-2", Name: "alerts", Type: "slack", VHost: "prod", WorkerID: "w2"},
+		{ID: "snk-3", Name: "archive", Type: "s3", VHost: "dev", Active: true, WorkerID: "w1"},
+	} {
+		must(t, s.CreateSink(ctx, snk))
+	}
+
+	got, err := s.GetSink(ctx, "snk-1")
+	must(t, err)
+	if got.Name != "warehouse" || got.Type != "snowflake" || !got.Active || got.WorkerID != "w1" || got.WorkspaceID != "ws1" {
+		t.Errorf("GetSink: unexpected sink %+v", got)
+	}
+	sameJSON(t, "sink config", got.Config, map[string]string{"account": "acme", "password": "secret"})
+	_, err = s.GetSink(ctx, "missing")
+	wantNotFound(t, "GetSink", err)
+
+	cases := []struct {
+		name   string
+		filter storage.CommonFilter
+		want   []string
+	}{
+		{"vhost", storage.CommonFilter{VHost: "dev"}, []string{"snk-3"}},
+		{"vhost all", storage.CommonFilter{VHost: "all"}, []string{"snk-1", "snk-2", "snk-3"}},
+		{"workspace", storage.CommonFilter{WorkspaceID: "ws1"}, []string{"snk-1"}},
+		{"worker", storage.CommonFilter{WorkerID: "w2"}, []string{"snk-2"}},
+		{"active", storage.CommonFilter{Active: boolPtr(true)}, []string{"snk-1", "snk-3"}},
+		{"search", storage.CommonFilter{Search: "ARCH"}, []string{"snk-3"}},
+	}
+	for _, c := range cases {
+		list, total, err := s.ListSinks(ctx, c.filter)
+		must(t, err)
+		wantIDs(t, "ListSinks "+c.name, list, sinkID, c.want...)
+		wantTotal(t, "ListSinks "+c.name, total, len(c.want))
+	}
+	all := pages(t, 2, 3, func(page int) ([]storage.Sink, int, error) {
+		return s.ListSinks(ctx, storage.CommonFilter{Page: page, Limit: 2})
+	})
+	wantIDs(t, "ListSinks pages", all, sinkID, "snk-1", "snk-2", "snk-3")
+
+	got.Name = "lake"
+	must(t, s.UpdateSink(ctx, got))
+	must(t, s.UpdateSinkStatus(ctx, "snk-1", "error"))
+	got, err = s.GetSink(ctx, "snk-1")
+	must(t, err)
+	if got.Name != "lake" || got.Status != "error" {
+		t.Errorf("after update: unexpected sink %+v", got)
+	}
+
+	must(t, s.DeleteSink(ctx, "snk-1"))
+	must(t, s.DeleteSink(ctx, "missing"))
+	_, err = s.GetSink(ctx, "snk-1")
+	wantNotFound(t, "GetSink after delete", err)
+}
+
+func userID(u storage.User) string { return u.ID }
+
+func testUsers(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for _, u := range []storage.User{
+		{ID: "u1", Username: "alice", Password: "hash-a", FullName: "Alice Doe", Email: "alice@example.com",
+			Role: storage.RoleAdministrator, VHosts: []string{"prod", "dev"}, TwoFactorEnabled: true, TwoFactorSecret: "totp"},
+		{ID: "u2", Username: "bob", Password: "hash-b", FullName: "Bob Roe", Email: "bob@example.com", Role: storage.RoleViewer},
+	} {
+		must(t, s.CreateUser(ctx, u))
+	}
+
+	got, err := s.GetUser(ctx, "u1")
+	must(t, err)
+	if got.Username != "alice" || got.Password != "hash-a" || got.FullName != "Alice Doe" || got.Email != "alice@example.com" ||
+		got.Role != storage.RoleAdministrator || !got.TwoFactorEnabled || got.TwoFactorSecret != "totp" {
+		t.Errorf("GetUser: unexpected user %+v", got)
+	}
+	sameJSON(t, "user vhosts", got.VHosts, []string{"prod", "dev"})
+	byName, err := s.GetUserByUsername(ctx, "bob")
+	must(t, err)
+	byEmail, err := s.GetUserByEmail(ctx, "bob@example.com")
+	must(t, err)
+	if byName.ID != "u2" || byEmail.ID != "u2" || byName.Password != "hash-b" {
+		t.Errorf("lookup by username/email: %+v / %+v", byName, byEmail)
+	}
+	_, err = s.GetUser(ctx, "missing")
+	wantNotFound(t, "GetUser", err)
+	_, err = s.GetUserByUsername(ctx, "missing")
+	wantNotFound(t, "GetUserByUsername", err)
+	_, err = s.GetUserByEmail(ctx, "missing@example.com")
+	wantNotFound(t, "GetUserByEmail", err)
+
+	list, total, err := s.ListUsers(ctx, storage.CommonFilter{})
+	must(t, err)
+	wantIDs(t, "ListUsers", list, userID, "u1", "u2")
+	wantTotal(t, "ListUsers", total, 2)
+	for _, u := range list {
+		if u.Password != "" {
+			t.Errorf("ListUsers leaked password for %s", u.ID)
+		}
+	}
+	for search, want := range map[string]string{"viewer": "u2", "Doe": "u1", "bob@": "u2", "ALICE": "u1"} {
+		list, _, err := s.ListUsers(ctx, storage.CommonFilter{Search: search})
+		must(t, err)
+		wantIDs(t, "ListUsers search "+search, list, userID, want)
+	}
+	all := pages(t, 1, 2, func(page int) ([]storage.User, int, error) {
+		return s.ListUsers(ctx, storage.CommonFilter{Page: page, Limit: 1})
+	})
+	wantIDs(t, "ListUsers pages", all, userID, "u1", "u2")
+
+	got.FullName = "Alice Smith"
+	got.Password = ""
+	must(t, s.UpdateUser(ctx, got))
+	got, err = s.GetUser(ctx, "u1")
+	must(t, err)
+	if got.FullName != "Alice Smith" || got.Password != "hash-a" {
+		t.Errorf("UpdateUser without password: %+v", got)
+	}
+	got.Password = "hash-a2"
+	must(t, s.UpdateUser(ctx, got))
+	got, err = s.GetUser(ctx, "u1")
+	must(t, err)
+	if got.Password != "hash-a2" {
+		t.Errorf("UpdateUser with password: got %q", got.Password)
+	}
+
+	must(t, s.DeleteUser(ctx, "u1"))
+	must(t, s.DeleteUser(ctx, "missing"))
+	_, err = s.GetUser(ctx, "u1")
+	wantNotFound(t, "GetUser after delete", err)
+}
+
+func vhostID(v storage.VHost) string { return v.ID }
+
+func testVHosts(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	must(t, s.CreateVHost(ctx, storage.VHost{Name: "prod", Description: "Production traffic"}))
+	must(t, s.CreateVHost(ctx, storage.VHost{ID: "vh-dev", Name: "dev", Description: "Developer sandbox"}))
+
+	got, err := s.GetVHost(ctx, "prod")
+	must(t, err)
+	if got.Name != "prod" || got.Description != "Production traffic" {
+		t.Errorf("GetVHost: %+v", got)
+	}
+	_, err = s.GetVHost(ctx, "missing")
+	wantNotFound(t, "GetVHost", err)
+
+	list, total, err := s.ListVHosts(ctx, storage.CommonFilter{Search: "sandbox"})
+	must(t, err)
+	wantIDs(t, "ListVHosts search", list, vhostID, "vh-dev")
+	wantTotal(t, "ListVHosts search", total, 1)
+	all := pages(t, 1, 2, func(page int) ([]storage.VHost, int, error) {
+		return s.ListVHosts(ctx, storage.CommonFilter{Page: page, Limit: 1})
+	})
+	wantIDs(t, "ListVHosts pages", all, vhostID, "prod", "vh-dev")
+
+	got.Description = "Live"
+	must(t, s.UpdateVHost(ctx, got))
+	got, err = s.GetVHost(ctx, "prod")
+	must(t, err)
+	if got.Description != "Live" {
+		t.Errorf("UpdateVHost: %+v", got)
+	}
+	must(t, s.DeleteVHost(ctx, "prod"))
+	_, err = s.GetVHost(ctx, "prod")
+	wantNotFound(t, "GetVHost after delete", err)
+}
+
+func testWorkspaces(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	must(t, s.CreateWorkspace(ctx, storage.Workspace{ID: "ws-b", Name: "beta", Description: "second", MaxWorkflows: 5,
+		MaxCPU: 1.5, MaxMemory: 512, MaxThroughput: 100, CreatedAt: at(0)}))
+	must(t, s.CreateWorkspace(ctx, storage.Workspace{ID: "ws-a", Name: "alpha", CreatedAt: at(time.Minute)}))
+
+	list, err := s.ListWorkspaces(ctx)
+	must(t, err)
+	wantOrder(t, "ListWorkspaces", list, func(w storage.Workspace) string { return w.ID }, "ws-a", "ws-b")
+
+	got, err := s.GetWorkspace(ctx, "ws-b")
+	must(t, err)
+	if got.Name != "beta" || got.Description != "second" || got.MaxWorkflows != 5 || got.MaxCPU != 1.5 ||
+		got.MaxMemory != 512 || got.MaxThroughput != 100 {
+		t.Errorf("GetWorkspace: %+v", got)
+	}
+	wantTime(t, "workspace created_at", got.CreatedAt, at(0))
+	_, err = s.GetWorkspace(ctx, "missing")
+	wantNotFound(t, "GetWorkspace", err)
+
+	must(t, s.DeleteWorkspace(ctx, "ws-b"))
+	_, err = s.GetWorkspace(ctx, "ws-b")
+	wantNotFound(t, "GetWorkspace after delete", err)
+}
+
+func workflowID(w storage.Workflow) string { return w.ID }
+
+func testWorkflows(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	retention := 7
+	wf1 := storage.Workflow{
+		ID: "wf-1", Name: "orders pipeline", VHost: "prod", Active: true, Status: "running", WorkerID: "w1", WorkspaceID: "ws1",
+		Nodes: []storage.WorkflowNode{
+			{ID: "n1", Type: "source", RefID: "src-1", Config: map[string]any{"batch": 10.0, "nested": map[string]any{"k": "v"}}, X: 1, Y: 2},
+			{ID: "n2", Type: "sink", RefID: "snk-1", X: 3, Y: 4},
+		},
+		Edges:            []storage.WorkflowEdge{{ID: "e1", SourceID: "n1", TargetID: "n2"}},
+		DeadLetterSinkID: "snk-dlq", MaxRetries: 3, RetryInterval: "5s", Cron: "*/5 * * * *", Tier: storage.WorkflowTierHot,
+		RetentionDays: &retention, TraceSampleRate: 0.5, Tags: []string{"a", "b"}, CPURequest: 0.5, ThroughputRequest: 10,
+		TotalProcessed: 42,
+	}
+	must(t, s.CreateWorkflow(ctx, wf1))
+	must(t, s.CreateWorkflow(ctx, storage.Workflow{ID: "wf-2", Name: "events", VHost: "prod", WorkerID: "w2"}))
+	must(t, s.CreateWorkflow(ctx, storage.Workflow{ID: "wf-3", Name: "clicks", VHost: "dev", Active: true, WorkerID: "w1", WorkspaceID: "ws2"}))
+
+	got, err := s.GetWorkflow(ctx, "wf-1")
+	must(t, err)
+	if got.Name != wf1.Name || got.VHost != "prod" || !got.Active || got.Status != "running" || got.WorkerID != "w1" ||
+		got.WorkspaceID != "ws1" || got.DeadLetterSinkID != "snk-dlq" || got.MaxRetries != 3 || got.RetryInterval != "5s" ||
+		got.Cron != wf1.Cron || got.Tier != storage.WorkflowTierHot || got.TraceSampleRate != 0.5 || got.CPURequest != 0.5 ||
+		got.ThroughputRequest != 10 || got.TotalProcessed != 42 {
+		t.Errorf("GetWorkflow: unexpected workflow %+v", got)
+	}
+	if got.RetentionDays == nil || *got.RetentionDays != 7 {
+		t.Errorf("GetWorkflow: retention days %v", got.RetentionDays)
+	}
+	sameJSON(t, "workflow nodes", got.Nodes, wf1.Nodes)
+	sameJSON(t, "workflow edges", got.Edges, wf1.Edges)
+	sameJSON(t, "workflow tags", got.Tags, wf1.Tags)
+	_, err = s.GetWorkflow(ctx, "missing")
+	wantNotFound(t, "GetWorkflow", err)
+
+	ok, err := s.AcquireWorkflowLease(ctx, "wf-2", "owner-a", 60)
+	must(t, err)
+	if !ok {
+		t.Fatal("AcquireWorkflowLease on unowned workflow failed")
+	}
+
+	cases := []struct {
+		name   string
+		filter storage.CommonFilter
+		want   []string
+	}{
+		{"vhost", storage.CommonFilter{VHost: "prod"}, []string{"wf-1", "wf-2"}},
+		{"vhost all", storage.CommonFilter{VHost: "all"}, []string{"wf-1", "wf-2", "wf-3"}},
+		{"workspace", storage.CommonFilter{WorkspaceID: "ws2"}, []string{"wf-3"}},
+		{"worker", storage.CommonFilter{WorkerID: "w1"}, []string{"wf-1", "wf-3"}},
+		{"owner", storage.CommonFilter{OwnerID: "owner-a"}, []string{"wf-2"}},
+		{"active", storage.CommonFilter{Active: boolPtr(true)}, []string{"wf-1", "wf-3"}},
+		{"search name", storage.CommonFilter{Search: "PIPE"}, []string{"wf-1"}},
+		{"search ignores vhost", storage.CommonFilter{Search: "dev"}, nil},
+	}
+	for _, c := range cases {
+		list, total, err := s.ListWorkflows(ctx, c.filter)
+		must(t, err)
+		wantIDs(t, "ListWorkflows "+c.name, list, workflowID, c.want...)
+		wantTotal(t, "ListWorkflows "+c.name, total, len(c.want))
+	}
+	all := pages(t, 2, 3, func(page int) ([]storage.Workflow, int, error) {
+		return s.ListWorkflows(ctx, storage.CommonFilter{Page: page, Limit: 2})
+	})
+	wantIDs(t, "ListWorkflows pages", all, workflowID, "wf-1", "wf-2", "wf-3")
+
+	wf2, err := s.GetWorkflow(ctx, "wf-2")
+	must(t, err)
+	if wf2.OwnerID != "owner-a" || wf2.LeaseUntil == nil {
+		t.Errorf("lease not visible on workflow: owner=%q lease=%v", wf2.OwnerID, wf2.LeaseUntil)
+	}
+	wf2.Name = "events v2"
+	wf2.OwnerID = ""
+	wf2.LeaseUntil = nil
+	wf2.TotalErrors = 5
+	must(t, s.UpdateWorkflow(ctx, wf2))
+	wf2, err = s.GetWorkflow(ctx, "wf-2")
+	must(t, err)
+	if wf2.Name != "events v2" || wf2.TotalErrors != 5 {
+		t.Errorf("UpdateWorkflow: %+v", wf2)
+	}
+	if wf2.OwnerID != "owner-a" {
+		t.Errorf("UpdateWorkflow must not change lease ownership, owner=%q", wf2.OwnerID)
+	}
+
+	must(t, s.UpdateWorkflowStatus(ctx, "wf-2", "stopped"))
+	must(t, s.UpdateWorkflowStats(ctx, "wf-2", 100, 7, 3))
+	wf2, err = s.GetWorkflow(ctx, "wf-2")
+	must(t, err)
+	if wf2.Status != "stopped" || wf2.TotalProcessed != 100 || wf2.TotalErrors != 7 || wf2.TotalLag != 3 {
+		t.Errorf("status/stats update: %+v", wf2)
+	}
+
+	must(t, s.DeleteWorkflow(ctx, "wf-2"))
+	must(t, s.DeleteWorkflow(ctx, "missing"))
+	_, err = s.GetWorkflow(ctx, "wf-2")
+	wantNotFound(t, "GetWorkflow after delete", err)
+}
+
+func testLeases(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	must(t, s.CreateWorkflow(ctx, storage.Workflow{ID: "wf", Name: "leased"}))
+
+	step := func(what string, got bool, err error, want bool) {
+		t.Helper()
+		must(t, err)
+		if got != want {
+			t.Fatalf("%s: got %v, want %v", what, got, want)
+		}
+	}
+	token := func(what string, want int64) {
+		t.Helper()
+		wf, err := s.GetWorkflow(ctx, "wf")
+		must(t, err)
+		if wf.LeaseToken != want {
+			t.Fatalf("%s: lease token = %d, want %d", what, wf.LeaseToken, want)
+		}
+	}
+	ok, err := s.AcquireWorkflowLease(ctx, "wf", "a", 30)
+	step("acquire unowned", ok, err, true)
+	token("acquire unowned", 1)
+	ok, err = s.AcquireWorkflowLease(ctx, "wf", "b", 30)
+	step("acquire held by other", ok, err, false)
+	ok, err = s.AcquireWorkflowLease(ctx, "wf", "a", 30)
+	step("re-acquire by owner", ok, err, true)
+	token("re-acquire by owner", 1)
+	ok, err = s.RenewWorkflowLease(ctx, "wf", "b", 30)
+	step("renew by other", ok, err, false)
+	ok, err = s.RenewWorkflowLease(ctx, "wf", "a", 30)
+	step("renew by owner", ok, err, true)
+	ok, err = s.AcquireWorkflowLease(ctx, "missing", "a", 30)
+	step("acquire missing workflow", ok, err, false)
+
+	must(t, s.ReleaseWorkflowLease(ctx, "wf", "b"))
+	ok, err = s.AcquireWorkflowLease(ctx, "wf", "b", 30)
+	step("acquire after foreign release", ok, err, false)
+	must(t, s.ReleaseWorkflowLease(ctx, "wf", "a"))
+	wf, err := s.GetWorkflow(ctx, "wf")
+	must(t, err)
+	if wf.OwnerID != "" {
+		t.Errorf("owner after release = %q", wf.OwnerID)
+	}
+	ok, err = s.RenewWorkflowLease(ctx, "wf", "a", 30)
+	step("renew after release", ok, err, false)
+
+	ok, err = s.AcquireWorkflowLease(ctx, "wf", "b", 1)
+	step("acquire short lease", ok, err, true)
+	token("acquire after release", 2)
+	time.Sleep(1100 * time.Millisecond)
+	ok, err = s.RenewWorkflowLease(ctx, "wf", "b", 30)
+	step("renew expired", ok, err, false)
+	ok, err = s.AcquireWorkflowLease(ctx, "wf", "c", 30)
+	step("acquire expired", ok, err, true)
+	token("take over expired lease", 3)
+
+	// UpdateWorkflow carries the caller's copy; it must not rewind the token.
+	wf, err = s.GetWorkflow(ctx, "wf")
+	must(t, err)
+	wf.LeaseToken = 0
+	must(t, s.UpdateWorkflow(ctx, wf))
+	token("after UpdateWorkflow", 3)
+}
+
+func testNamedLeases(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	_, err := s.GetLease(ctx, "leader")
+	wantNotFound(t, "lease never taken", err)
+
+	step := func(what string, got bool, err error, want bool) {
+		t.Helper()
+		must(t, err)
+		if got != want {
+			t.Fatalf("%s: got %v, want %v", what, got, want)
+		}
+	}
+	held := func(what, owner string, token int64) storage.Lease {
+		t.Helper()
+		l, err := s.GetLease(ctx, "leader")
+		must(t, err)
+		if l.Name != "leader" || l.OwnerID != owner || l.Token != token {
+			t.Fatalf("%s: lease = %+v, want owner %q token %d", what, l, owner, token)
+		}
+		return l
+	}
+
+	ok, err := s.AcquireLease(ctx, "leader", "a", 30)
+	step("acquire new", ok, err, true)
+	first := held("acquire new", "a", 1)
+	if first.AcquiredAt.IsZero() || !first.LeaseUntil.After(first.AcquiredAt) {
+		t.Fatalf("acquire new: lease = %+v", first)
+	}
+	ok, err = s.AcquireLease(ctx, "leader", "b", 30)
+	step("acquire held by other", ok, err, false)
+	ok, err = s.AcquireLease(ctx, "leader", "a", 30)
+	step("renew by owner", ok, err, true)
+	if l := held("renew by owner", "a", 1); !sameTime(l.AcquiredAt, first.AcquiredAt) {
+		t.Errorf("renewal moved acquired_at from %v to %v", first.AcquiredAt, l.AcquiredAt)
+	}
+
+	must(t, s.ReleaseLease(ctx, "leader", "b"))
+	ok, err = s.AcquireLease(ctx, "leader", "b", 30)
+	step("acquire after foreign release", ok, err, false)
+	must(t, s.ReleaseLease(ctx, "leader", "a"))
+	held("after release", "", 1)
+	ok, err = s.AcquireLease(ctx, "leader", "b", 1)
+	step("acquire released", ok, err, true)
+	held("acquire released", "b", 2)
+
+	time.Sleep(1100 * time.Millisecond)
+	ok, err = s.AcquireLease(ctx, "leader", "c", 30)
+	step("take over expired", ok, err, true)
+	held("take over expired", "c", 3)
+
+	ok, err = s.AcquireLease(ctx, "other", "a", 30)
+	step("leases are independent", ok, err, true)
+}
+
+func workerID(w storage.Worker) string { return w.ID }
+
+func testWorkers(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	must(t, s.CreateWorker(ctx, storage.Worker{ID: "w1", Name: "edge-1", Host: "10.0.0.1", Port: 8081, Description: "rack A", Token: "tok"}))
+	must(t, s.CreateWorker(ctx, storage.Worker{Name: "edge-2", Host: "10.0.0.2", Port: 8082}))
+
+	got, err := s.GetWorker(ctx, "w1")
+	must(t, err)
+	if got.Name != "edge-1" || got.Host != "10.0.0.1" || got.Port != 8081 || got.Description != "rack A" || got.Token != "tok" {
+		t.Errorf("GetWorker: %+v", got)
+	}
+	_, err = s.GetWorker(ctx, "missing")
+	wantNotFound(t, "GetWorker", err)
+
+	list, total, err := s.ListWorkers(ctx, storage.CommonFilter{})
+	must(t, err)
+	wantTotal(t, "ListWorkers", total, 2)
+	for _, w := range list {
+		if w.ID == "" || w.Token == "" {
+			t.Errorf("worker missing generated id/token: %+v", w)
+		}
+	}
+	list, _, err = s.ListWorkers(ctx, storage.CommonFilter{Search: "RACK"})
+	must(t, err)
+	wantIDs(t, "ListWorkers search description", list, workerID, "w1")
+	list, _, err = s.ListWorkers(ctx, storage.CommonFilter{Search: "10.0.0.2"})
+	must(t, err)
+	if len(list) != 1 || list[0].Name != "edge-2" {
+		t.Errorf("ListWorkers search host: %+v", list)
+	}
+	all := pages(t, 1, 2, func(page int) ([]storage.Worker, int, error) {
+		return s.ListWorkers(ctx, storage.CommonFilter{Page: page, Limit: 1})
+	})
+	if len(all) != 2 {
+		t.Errorf("ListWorkers pages returned %d workers", len(all))
+	}
+
+	before := time.Now().Add(-time.Second)
+	must(t, s.UpdateWorkerHeartbeat(ctx, "w1", 12.5, 256))
+	got, err = s.GetWorker(ctx, "w1")
+	must(t, err)
+	if got.LastSeen == nil || got.LastSeen.Before(before) || got.CPUUsage != 12.5 || got.MemoryUsage != 256 {
+		t.Errorf("UpdateWorkerHeartbeat: %+v", got)
+	}
+
+	got.Description = "rack B"
+	must(t, s.UpdateWorker(ctx, got))
+	got, err = s.GetWorker(ctx, "w1")
+	must(t, err)
+	if got.Description != "rack B" {
+		t.Errorf("UpdateWorker: %+v", got)
+	}
+	must(t, s.DeleteWorker(ctx, "w1"))
+	_, err = s.GetWorker(ctx, "w1")
+	wantNotFound(t, "GetWorker after delete", err)
+}
+
+func logID(l storage.Log) string { return l.ID }
+
+func testLogs(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	must(t, s.CreateLog(ctx, storage.Log{ID: "l1", Timestamp: at(1 * time.Second), Level: "INFO", Message: "source started",
+		Action: "start", SourceID: "src-1", WorkflowID: "wf-1", UserID: "u1", Username: "alice", Data: `{"k":1}`}))
+	must(t, s.CreateLogs(ctx, []storage.Log{
+		{ID: "l2", Timestamp: at(2 * time.Second), Level: "ERROR", Message: "sink failed", SinkID: "snk-1", WorkflowID: "wf-1"},
+		{ID: "l3", Timestamp: at(3 * time.Second), Level: "INFO", Message: "platform ready", Action: "boot"},
+		{ID: "l4", Timestamp: at(4 * time.Second), Level: "WARN", Message: "slow batch", WorkflowID: "wf-2"},
+	}))
+	must(t, s.CreateLogs(ctx, nil))
+
+	list, total, err := s.ListLogs(ctx, storage.LogFilter{})
+	must(t, err)
+	wantOrder(t, "ListLogs order", list, logID, "l4", "l3", "l2", "l1")
+	wantTotal(t, "ListLogs", total, 4)
+	if l := list[3]; l.Level != "INFO" || l.Message != "source started" || l.Action != "start" || l.SourceID != "src-1" ||
+		l.WorkflowID != "wf-1" || l.UserID != "u1" || l.Username != "alice" || l.Data != `{"k":1}` {
+		t.Errorf("ListLogs: unexpected log %+v", l)
+	} else {
+		wantTime(t, "log timestamp", l.Timestamp, at(time.Second))
+	}
+
+	cases := []struct {
+		name   string
+		filter storage.LogFilter
+		want   []string
+	}{
+		{"source", storage.LogFilter{SourceID: "src-1"}, []string{"l1"}},
+		{"sink", storage.LogFilter{SinkID: "snk-1"}, []string{"l2"}},
+		{"workflow", storage.LogFilter{WorkflowID: "wf-1"}, []string{"l1", "l2"}},
+		{"level", storage.LogFilter{Level: "INFO"}, []string{"l1", "l3"}},
+		{"action", storage.LogFilter{Action: "boot"}, []string{"l3"}},
+		{"without workflow", storage.LogFilter{WithoutWorkflow: true}, []string{"l3"}},
+		{"since", storage.LogFilter{CommonFilter: storage.CommonFilter{Since: at(3 * time.Second)}}, []string{"l3", "l4"}},
+		{"until", storage.LogFilter{CommonFilter: storage.CommonFilter{Until: at(2 * time.Second)}}, []string{"l1"}},
+		{"search message", storage.LogFilter{CommonFilter: storage.CommonFilter{Search: "FAILED"}}, []string{"l2"}},
+		{"search workflow", storage.LogFilter{CommonFilter: storage.CommonFilter{Search: "wf-2"}}, []string{"l4"}},
+		{"search action", storage.LogFilter{CommonFilter: storage.CommonFilter{Search: "boot"}}, []string{"l3"}},
+	}
+	for _, c := range cases {
+		list, total, err := s.ListLogs(ctx, c.filter)
+		must(t, err)
+		wantIDs(t, "ListLogs "+c.name, list, logID, c.want...)
+		wantTotal(t, "ListLogs "+c.name, total, len(c.want))
+	}
+	page, total, err := s.ListLogs(ctx, storage.LogFilter{CommonFilter: storage.CommonFilter{Page: 2, Limit: 3}})
+	must(t, err)
+	wantOrder(t, "ListLogs page 2", page, logID, "l1")
+	wantTotal(t, "ListLogs page 2", total, 4)
+
+	must(t, s.DeleteLogs(ctx, storage.LogFilter{WithoutWorkflow: true}))
+	list, _, err = s.ListLogs(ctx, storage.LogFilter{})
+	must(t, err)
+	wantIDs(t, "after DeleteLogs without workflow", list, logID, "l1", "l2", "l4")
+	must(t, s.DeleteLogs(ctx, storage.LogFilter{WorkflowID: "wf-1", Level: "ERROR"}))
+	list, _, err = s.ListLogs(ctx, storage.LogFilter{})
+	must(t, err)
+	wantIDs(t, "after DeleteLogs by workflow and level", list, logID, "l1", "l4")
+	must(t, s.PurgeLogs(ctx, at(4*time.Second)))
+	list, _, err = s.ListLogs(ctx, storage.LogFilter{})
+	must(t, err)
+	wantIDs(t, "after PurgeLogs", list, logID, "l4")
+
+	batch := make([]storage.Log, 105)
+	for i := range batch {
+		batch[i] = storage.Log{Timestamp: at(time.Minute + time.Duration(i)*time.Millisecond), Level: "DEBUG", Message: "bulk"}
+	}
+	must(t, s.CreateLogs(ctx, batch))
+	list, total, err = s.ListLogs(ctx, storage.LogFilter{Level: "DEBUG"})
+	must(t, err)
+	if len(list) != 100 || total != 105 {
+		t.Errorf("ListLogs default limit: got %d rows, total %d", len(list), total)
+	}
+}
+
+func auditID(l storage.AuditLog) string { return l.ID }
+
+func testAuditLogs(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for _, l := range []storage.AuditLog{
+		{ID: "a1", Timestamp: at(1 * time.Second), UserID: "u1", Username: "alice", Action: "CREATE", EntityType: "workflow", EntityID: "wf-1", Payload: `{"name":"x"}`, IP: "127.0.0.1"},
+		{ID: "a2", Timestamp: at(2 * time.Second), UserID: "u2", Username: "bob", Action: "DELETE", EntityType: "source", EntityID: "src-1"},
+		{ID: "a3", Timestamp: at(3 * time.Second), UserID: "u1", Username: "alice", Action: "START", EntityType: "workflow", EntityID: "wf-2"},
+	} {
+		must(t, s.CreateAuditLog(ctx, l))
+	}
+
+	list, total, err := s.ListAuditLogs(ctx, storage.AuditFilter{})
+	must(t, err)
+	wantOrder(t, "ListAuditLogs order", list, auditID, "a3", "a2", "a1")
+	wantTotal(t, "ListAuditLogs", total, 3)
+	if l := list[2]; l.UserID != "u1" || l.Username != "alice" || l.Action != "CREATE" || l.EntityType != "workflow" ||
+		l.EntityID != "wf-1" || l.Payload != `{"name":"x"}` || l.IP != "127.0.0.1" {
+		t.Errorf("ListAuditLogs: unexpected entry %+v", l)
+	}
+
+	from, to := at(2*time.Second), at(3*time.Second)
+	cases := []struct {
+		name   string
+		filter storage.AuditFilter
+		want   []string
+	}{
+		{"user", storage.AuditFilter{UserID: "u1"}, []string{"a1", "a3"}},
+		{"entity type", storage.AuditFilter{EntityType: "source"}, []string{"a2"}},
+		{"entity id", storage.AuditFilter{EntityID: "wf-2"}, []string{"a3"}},
+		{"action", storage.AuditFilter{Action: "CREATE"}, []string{"a1"}},
+		{"from", storage.AuditFilter{From: &from}, []string{"a2", "a3"}},
+		{"to", storage.AuditFilter{To: &from}, []string{"a1", "a2"}},
+		{"range", storage.AuditFilter{From: &from, To: &to}, []string{"a2", "a3"}},
+		{"search", storage.AuditFilter{CommonFilter: storage.CommonFilter{Search: "bob"}}, []string{"a2"}},
+		{"search payload", storage.AuditFilter{CommonFilter: storage.CommonFilter{Search: `"name"`}}, []string{"a1"}},
+	}
+	for _, c := range cases {
+		list, total, err := s.ListAuditLogs(ctx, c.filter)
+		must(t, err)
+		wantIDs(t, "ListAuditLogs "+c.name, list, auditID, c.want...)
+		wantTotal(t, "ListAuditLogs "+c.name, total, len(c.want))
+	}
+	page, _, err := s.ListAuditLogs(ctx, storage.AuditFilter{CommonFilter: storage.CommonFilter{Page: 2, Limit: 2}})
+	must(t, err)
+	wantOrder(t, "ListAuditLogs page 2", page, auditID, "a1")
+
+	must(t, s.PurgeAuditLogs(ctx, at(2*time.Second)))
+	list, _, err = s.ListAuditLogs(ctx, storage.AuditFilter{})
+	must(t, err)
+	wantIDs(t, "after PurgeAuditLogs", list, auditID, "a2", "a3")
+}
+
+func webhookID(r storage.WebhookRequest) string { return r.ID }
+
+func testWebhookRequests(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	must(t, s.CreateWebhookRequest(ctx, storage.WebhookRequest{ID: "r1", Timestamp: at(0), Path: "/hooks/a", Method: "POST",
+		Headers: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{"ok":true}`)}))
+	must(t, s.CreateWebhookRequest(ctx, storage.WebhookRequest{ID: "r2", Timestamp: at(time.Second), Path: "/hooks/b", Method: "PUT"}))
+
+	got, err := s.GetWebhookRequest(ctx, "r1")
+	must(t, err)
+	if got.Path != "/hooks/a" || got.Method != "POST" || string(got.Body) != `{"ok":true}` {
+		t.Errorf("GetWebhookRequest: %+v", got)
+	}
+	sameJSON(t, "webhook headers", got.Headers, map[string]string{"Content-Type": "application/json"})
+	wantTime(t, "webhook timestamp", got.Timestamp, at(0))
+	_, err = s.GetWebhookRequest(ctx, "missing")
+	wantNotFound(t, "GetWebhookRequest", err)
+
+	list, total, err := s.ListWebhookRequests(ctx, storage.WebhookRequestFilter{})
+	must(t, err)
+	wantOrder(t, "ListWebhookRequests order", list, webhookID, "r2", "r1")
+	wantTotal(t, "ListWebhookRequests", total, 2)
+	list, total, err = s.ListWebhookRequests(ctx, storage.WebhookRequestFilter{Path: "/hooks/b"})
+	must(t, err)
+	wantIDs(t, "ListWebhookRequests path", list, webhookID, "r2")
+	wantTotal(t, "ListWebhookRequests path", total, 1)
+
+	for i := range 52 {
+		must(t, s.CreateWebhookRequest(ctx, storage.WebhookRequest{ID: fmt.Sprintf("bulk-%02d", i),
+			Timestamp: at(time.Minute + time.Duration(i)*time.Second), Path: "/hooks/a", Method: "POST"}))
+	}
+	list, total, err = s.ListWebhookRequests(ctx, storage.WebhookRequestFilter{Path: "/hooks/a"})
+	must(t, err)
+	wantTotal(t, "retained per path", total, 50)
+	if len(list) != 50 || list[0].ID != "bulk-51" || list[49].ID != "bulk-02" {
+		t.Errorf("retention kept the wrong requests: first=%v last=%v", list[0].ID, list[len(list)-1].ID)
+	}
+	page, _, err := s.ListWebhookRequests(ctx, storage.WebhookRequestFilter{Path: "/hooks/a",
+		CommonFilter: storage.CommonFilter{Page: 2, Limit: 20}})
+	must(t, err)
+	if len(page) != 20 || page[0].ID != "bulk-31" {
+		t.Errorf("ListWebhookRequests page 2: %d rows starting at %v", len(page), page[0].ID)
+	}
+
+	must(t, s.DeleteWebhookRequests(ctx, storage.WebhookRequestFilter{Path: "/hooks/a"}))
+	list, total, err = s.ListWebhookRequests(ctx, storage.WebhookRequestFilter{})
+	must(t, err)
+	wantIDs(t, "after DeleteWebhookRequests", list, webhookID, "r2")
+	wantTotal(t, "after DeleteWebhookRequests", total, 1)
+}
+
+func formID(f storage.FormSubmission) string { return f.ID }
+
+func testFormSubmissions(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for _, f := range []storage.FormSubmission{
+		{ID: "f2", Timestamp: at(2 * time.Second), Path: "/forms/signup", Data: []byte(`{"email":"b"}`), Status: "pending"},
+		{ID: "f1", Timestamp: at(1 * time.Second), Path: "/forms/signup", Data: []byte(`{"email":"a"}`), Status: "completed"},
+		{ID: "f3", Timestamp: at(3 * time.Second), Path: "/forms/contact", Data: []byte(`{}`), Status: "pending"},
+	} {
+		must(t, s.CreateFormSubmission(ctx, f))
+	}
+
+	got, err := s.GetFormSubmission(ctx, "f2")
+	must(t, err)
+	if got.Path != "/forms/signup" || string(got.Data) != `{"email":"b"}` || got.Status != "pending" {
+		t.Errorf("GetFormSubmission: %+v", got)
+	}
+	wantTime(t, "form timestamp", got.Timestamp, at(2*time.Second))
+	_, err = s.GetFormSubmission(ctx, "missing")
+	wantNotFound(t, "GetFormSubmission", err)
+
+	list, total, err := s.ListFormSubmissions(ctx, storage.FormSubmissionFilter{})
+	must(t, err)
+	wantOrder(t, "ListFormSubmissions order", list, formID, "f1", "f2", "f3")
+	wantTotal(t, "ListFormSubmissions", total, 3)
+	list, total, err = s.ListFormSubmissions(ctx, storage.FormSubmissionFilter{Path: "/forms/signup", Status: "pending"})
+	must(t, err)
+	wantIDs(t, "ListFormSubmissions filter", list, formID, "f2")
+	wantTotal(t, "ListFormSubmissions filter", total, 1)
+	list, _, err = s.ListFormSubmissions(ctx, storage.FormSubmissionFilter{CommonFilter: storage.CommonFilter{Limit: 2}})
+	must(t, err)
+	wantOrder(t, "ListFormSubmissions page 0", list, formID, "f1", "f2")
+	list, _, err = s.ListFormSubmissions(ctx, storage.FormSubmissionFilter{CommonFilter: storage.CommonFilter{Page: 2, Limit: 2}})
+	must(t, err)
+	wantOrder(t, "ListFormSubmissions page 2", list, formID, "f3")
+
+	must(t, s.UpdateFormSubmissionStatus(ctx, "f2", "processing"))
+	got, err = s.GetFormSubmission(ctx, "f2")
+	must(t, err)
+	if got.Status != "processing" {
+		t.Errorf("UpdateFormSubmissionStatus: %+v", got)
+	}
+	must(t, s.DeleteFormSubmissions(ctx, storage.FormSubmissionFilter{Status: "completed"}))
+	must(t, s.DeleteFormSubmissions(ctx, storage.FormSubmissionFilter{Path: "/forms/contact"}))
+	list, _, err = s.ListFormSubmissions(ctx, storage.FormSubmissionFilter{})
+	must(t, err)
+	wantIDs(t, "after DeleteFormSubmissions", list, formID, "f2")
+}
+
+func testSettings(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	v, err := s.GetSetting(ctx, "missing")
+	must(t, err)
+	if v != "" {
+		t.Errorf("GetSetting missing = %q", v)
+	}
+	must(t, s.SaveSetting(ctx, "retention", "7d"))
+	must(t, s.SaveSetting(ctx, "retention", "30d"))
+	v, err = s.GetSetting(ctx, "retention")
+	must(t, err)
+	if v != "30d" {
+		t.Errorf("GetSetting = %q, want 30d", v)
+	}
+}
+
+func testNodeStates(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	must(t, s.UpdateNodeState(ctx, "wf-1", "n1", map[string]any{"count": 1}))
+	must(t, s.UpdateNodeState(ctx, "wf-1", "n1", map[string]any{"count": 2, "window": []any{"a", "b"}}))
+	must(t, s.UpdateNodeState(ctx, "wf-1", "n2", "plain"))
+	must(t, s.UpdateNodeState(ctx, "wf-2", "n1", 3))
+
+	states, err := s.GetNodeStates(ctx, "wf-1")
+	must(t, err)
+	sameJSON(t, "node states", states, map[string]any{
+		"n1": map[string]any{"count": 2, "window": []any{"a", "b"}},
+		"n2": "plain",
+	})
+	states, err = s.GetNodeStates(ctx, "wf-none")
+	must(t, err)
+	if len(states) != 0 {
+		t.Errorf("GetNodeStates unknown workflow: %v", states)
+	}
+}
+
+func schemaKey(sc storage.Schema) string { return fmt.Sprintf("%s@%d", sc.Name, sc.Version) }
+
+func testSchemas(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for _, sc := range []storage.Schema{
+		{Name: "orders", Version: 1, Type: "json", Content: `{"v":1}`, CreatedAt: at(0)},
+		{Name: "orders", Version: 2, Type: "json", Content: `{"v":2}`, CreatedAt: at(time.Second)},
+		{Name: "events", Version: 1, Type: "avro", Content: `{"type":"record"}`},
+		{Name: "archive", Version: 1, Type: "json", Content: `{"v":1}`},
+	} {
+		must(t, s.CreateSchema(ctx, sc))
+	}
+
+	list, err := s.ListSchemas(ctx, "orders")
+	must(t, err)
+	wantOrder(t, "ListSchemas", list, schemaKey, "orders@2", "orders@1")
+	all, err := s.ListAllSchemas(ctx)
+	must(t, err)
+	wantOrder(t, "ListAllSchemas", all, schemaKey, "archive@1", "events@1", "orders@2")
+
+	got, err := s.GetSchema(ctx, "orders", 1)
+	must(t, err)
+	if got.ID == "" || got.Type != "json" || got.Content != `{"v":1}` {
+		t.Errorf("GetSchema: %+v", got)
+	}
+	wantTime(t, "schema created_at", got.CreatedAt, at(0))
+	latest, err := s.GetLatestSchema(ctx, "orders")
+	must(t, err)
+	if latest.Version != 2 || latest.Content != `{"v":2}` {
+		t.Errorf("GetLatestSchema: %+v", latest)
+	}
+	_, err = s.GetSchema(ctx, "orders", 9)
+	wantNotFound(t, "GetSchema", err)
+
+	// The same schema gets the same ID under every subject; others get their own.
+	if got.SchemaID == 0 || latest.SchemaID == 0 || got.SchemaID == latest.SchemaID {
+		t.Errorf("schema ids: orders@1=%d orders@2=%d", got.SchemaID, latest.SchemaID)
+	}
+	shared, err := s.ListSchemasByID(ctx, got.SchemaID)
+	must(t, err)
+	wantOrder(t, "ListSchemasByID", shared, schemaKey, "archive@1", "orders@1")
+	list, err = s.ListSchemasByID(ctx, 9999)
+	must(t, err)
+	if len(list) != 0 {
+		t.Errorf("ListSchemasByID unknown id: %v", list)
+	}
+	_, err = s.GetLatestSchema(ctx, "missing")
+	wantNotFound(t, "GetLatestSchema", err)
+	list, err = s.ListSchemas(ctx, "missing")
+	must(t, err)
+	if len(list) != 0 {
+		t.Errorf("ListSchemas missing: %v", list)
+	}
+}
+
+func traceMessageID(tr storage.MessageTrace) string { return tr.MessageID }
+
+func testMessageTraces(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	steps := []struct {
+		msg  string
+		step hermod.TraceStep
+	}{
+		{"m1", hermod.TraceStep{NodeID: "n1", Timestamp: at(0), Duration: 5 * time.Millisecond, After: map[string]any{"id": 1}}},
+		{"m1", hermod.TraceStep{NodeID: "n2", Timestamp: at(2 * time.Second), Before: map[string]any{"id": 1}, Error: "boom"}},
+		{"m2", hermod.TraceStep{NodeID: "n1", Timestamp: at(time.Second)}},
+		{"m3", hermod.TraceStep{NodeID: "n1", Timestamp: at(3 * time.Second)}},
+	}
+	for _, st := range steps {
+		must(t, s.RecordTraceStep(ctx, "wf-1", st.msg, st.step))
+	}
+	must(t, s.RecordTraceStep(ctx, "wf-2", "m1", hermod.TraceStep{NodeID: "other", Timestamp: at(0)}))
+
+	tr, err := s.GetMessageTrace(ctx, "wf-1", "m1")
+	must(t, err)
+	if tr.WorkflowID != "wf-1" || tr.MessageID != "m1" || len(tr.Steps) != 2 {
+		t.Fatalf("GetMessageTrace: %+v", tr)
+	}
+	wantTime(t, "trace created_at", tr.CreatedAt, at(0))
+	if tr.Steps[0].NodeID != "n1" || tr.Steps[0].Duration != 5*time.Millisecond || tr.Steps[1].NodeID != "n2" || tr.Steps[1].Error != "boom" {
+		t.Errorf("trace steps: %+v", tr.Steps)
+	}
+	wantTime(t, "step timestamp", tr.Steps[1].Timestamp, at(2*time.Second))
+	sameJSON(t, "step after", tr.Steps[0].After, map[string]any{"id": 1})
+	sameJSON(t, "step before", tr.Steps[1].Before, map[string]any{"id": 1})
+	_, err = s.GetMessageTrace(ctx, "wf-1", "missing")
+	wantNotFound(t, "GetMessageTrace", err)
+
+	list, err := s.ListMessageTraces(ctx, "wf-1", 10, 0)
+	must(t, err)
+	wantOrder(t, "ListMessageTraces", list, traceMessageID, "m3", "m2", "m1")
+	if len(list) == 3 {
+		wantTime(t, "listed trace created_at", list[2].CreatedAt, at(0))
+	}
+	list, err = s.ListMessageTraces(ctx, "wf-1", 1, 1)
+	must(t, err)
+	wantOrder(t, "ListMessageTraces page", list, traceMessageID, "m2")
+
+	must(t, s.PurgeMessageTraces(ctx, at(1500*time.Millisecond)))
+	tr, err = s.GetMessageTrace(ctx, "wf-1", "m1")
+	must(t, err)
+	if len(tr.Steps) != 1 || tr.Steps[0].NodeID != "n2" {
+		t.Errorf("after purge: %+v", tr.Steps)
+	}
+	_, err = s.GetMessageTrace(ctx, "wf-1", "m2")
+	wantNotFound(t, "GetMessageTrace after purge", err)
+	_, err = s.GetMessageTrace(ctx, "wf-2", "m1")
+	wantNotFound(t, "GetMessageTrace other workflow after purge", err)
+	list, err = s.ListMessageTraces(ctx, "wf-1", 10, 0)
+	must(t, err)
+	wantIDs(t, "ListMessageTraces after purge", list, traceMessageID, "m1", "m3")
+}
+
+func versionNumber(v storage.WorkflowVersion) string { return fmt.Sprint(v.Version) }
+
+func testWorkflowVersions(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	nodes := []storage.WorkflowNode{{ID: "n1", Type: "source", RefID: "src-1"}}
+	edges := []storage.WorkflowEdge{{ID: "e1", SourceID: "n1", TargetID: "n2"}}
+	for i, msg := range []string{"initial", "tweak"} {
+		must(t, s.CreateWorkflowVersion(ctx, storage.WorkflowVersion{
+			ID: fmt.Sprintf("v%d", i+1), WorkflowID: "wf-1", Version: i + 1, Nodes: nodes, Edges: edges,
+			Config: `{"max_retries":3}`, CreatedAt: at(time.Duration(i) * time.Second), CreatedBy: "alice", Message: msg,
+		}))
+	}
+	must(t, s.CreateWorkflowVersion(ctx, storage.WorkflowVersion{ID: "other", WorkflowID: "wf-2", Version: 1, CreatedAt: at(0)}))
+
+	list, err := s.ListWorkflowVersions(ctx, "wf-1")
+	must(t, err)
+	wantOrder(t, "ListWorkflowVersions", list, versionNumber, "2", "1")
+	for _, v := range list {
+		if len(v.Nodes) != 0 || len(v.Edges) != 0 || v.Config != "" {
+			t.Errorf("ListWorkflowVersions must omit payloads: %+v", v)
+		}
+		if v.CreatedBy != "alice" || v.WorkflowID != "wf-1" {
+			t.Errorf("ListWorkflowVersions: %+v", v)
+		}
+	}
+
+	got, err := s.GetWorkflowVersion(ctx, "wf-1", 1)
+	must(t, err)
+	if got.ID != "v1" || got.Message != "initial" || got.Config != `{"max_retries":3}` {
+		t.Errorf("GetWorkflowVersion: %+v", got)
+	}
+	sameJSON(t, "version nodes", got.Nodes, nodes)
+	sameJSON(t, "version edges", got.Edges, edges)
+	wantTime(t, "version created_at", got.CreatedAt, at(0))
+	_, err = s.GetWorkflowVersion(ctx, "wf-1", 9)
+	wantNotFound(t, "GetWorkflowVersion", err)
+}
+
+func outboxID(o storage.OutboxItem) string { return o.ID }
+
+func testOutbox(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for i, id := range []string{"o2", "o1", "o3"} {
+		offset := map[string]time.Duration{"o1": 0, "o2": time.Second, "o3": 2 * time.Second}[id]
+		must(t, s.CreateOutboxItem(ctx, storage.OutboxItem{ID: id, WorkflowID: "wf-1", SinkID: "snk-1",
+			Payload: []byte(fmt.Sprintf(`{"i":%d}`, i)), Metadata: map[string]string{"k": id}, CreatedAt: at(offset), Status: "pending"}))
+	}
+
+	list, err := s.ListOutboxItems(ctx, "pending", 2)
+	must(t, err)
+	wantOrder(t, "ListOutboxItems", list, outboxID, "o1", "o2")
+	if len(list) > 0 {
+		o := list[0]
+		if o.WorkflowID != "wf-1" || o.SinkID != "snk-1" || string(o.Payload) != `{"i":1}` || o.Metadata["k"] != "o1" {
+			t.Errorf("ListOutboxItems: %+v", o)
+		}
+		wantTime(t, "outbox created_at", o.CreatedAt, at(0))
+	}
+
+	must(t, s.UpdateOutboxItem(ctx, storage.OutboxItem{ID: "o1", Attempts: 2, LastError: "timeout", Status: "failed"}))
+	failed, err := s.ListOutboxItems(ctx, "failed", 10)
+	must(t, err)
+	if len(failed) != 1 || failed[0].ID != "o1" || failed[0].Attempts != 2 || failed[0].LastError != "timeout" {
+		t.Errorf("UpdateOutboxItem: %+v", failed)
+	}
+	must(t, s.DeleteOutboxItem(ctx, "o2"))
+	list, err = s.ListOutboxItems(ctx, "pending", 10)
+	must(t, err)
+	wantOrder(t, "ListOutboxItems after delete", list, outboxID, "o3")
+}
+
+func testLineage(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	must(t, s.CreateSource(ctx, storage.Source{ID: "src-1", Name: "orders", Type: "postgres"}))
+	must(t, s.CreateSink(ctx, storage.Sink{ID: "snk-1", Name: "warehouse", Type: "snowflake"}))
+	must(t, s.CreateSink(ctx, storage.Sink{ID: "snk-2", Name: "alerts", Type: "slack"}))
+	must(t, s.CreateWorkflow(ctx, storage.Workflow{ID: "wf-1", Name: "fan-out", Nodes: []storage.WorkflowNode{
+		{ID: "n1", Type: "source", RefID: "src-1"},
+		{ID: "n2", Type: "transformer"},
+		{ID: "n3", Type: "sink", RefID: "snk-1"},
+		{ID: "n4", Type: "sink", RefID: "snk-2"},
+		{ID: "n5", Type: "sink", RefID: "snk-missing"},
+	}}))
+	must(t, s.CreateWorkflow(ctx, storage.Workflow{ID: "wf-2", Name: "no sinks", Nodes: []storage.WorkflowNode{
+		{ID: "n1", Type: "source", RefID: "src-1"},
+	}}))
+
+	edges, err := s.GetLineage(ctx)
+	must(t, err)
+	sort.Slice(edges, func(i, j int) bool { return edges[i].SinkID < edges[j].SinkID })
+	sameJSON(t, "lineage", edges, []storage.LineageEdge{
+		{SourceID: "src-1", SourceName: "orders", SourceType: "postgres", SinkID: "snk-1", SinkName: "warehouse", SinkType: "snowflake", WorkflowID: "wf-1", WorkflowName: "fan-out"},
+		{SourceID: "src-1", SourceName: "orders", SourceType: "postgres", SinkID: "snk-2", SinkName: "alerts", SinkType: "slack", WorkflowID: "wf-1", WorkflowName: "fan-out"},
+	})
+}
+
+func pluginID(p storage.Plugin) string { return p.ID }
+
+func testPlugins(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	list, err := s.ListPlugins(ctx)
+	must(t, err)
+	wantIDs(t, "ListPlugins", list, pluginID, "openai-pii-filter", "slack-connector", "xml-to-json")
+
+	p, err := s.GetPlugin(ctx, "slack-connector")
+	must(t, err)
+	if p.Name != "Slack Connector" || p.Type != "Connector" || !p.Certified || p.Installed || p.InstalledAt != nil {
+		t.Errorf("GetPlugin: %+v", p)
+	}
+	_, err = s.GetPlugin(ctx, "missing")
+	wantNotFound(t, "GetPlugin", err)
+
+	must(t, s.InstallPlugin(ctx, "slack-connector"))
+	p, err = s.GetPlugin(ctx, "slack-connector")
+	must(t, err)
+	if !p.Installed || p.InstalledAt == nil {
+		t.Errorf("after InstallPlugin: %+v", p)
+	}
+	must(t, s.UninstallPlugin(ctx, "slack-connector"))
+	p, err = s.GetPlugin(ctx, "slack-connector")
+	must(t, err)
+	if p.Installed || p.InstalledAt != nil {
+		t.Errorf("after UninstallPlugin: %+v", p)
+	}
+	wantNotFound(t, "InstallPlugin", s.InstallPlugin(ctx, "missing"))
+	wantNotFound(t, "UninstallPlugin", s.UninstallPlugin(ctx, "missing"))
+
+	// Re-running Init must not reset installation state.
+	must(t, s.InstallPlugin(ctx, "xml-to-json"))
+	must(t, s.Init(ctx))
+	p, err = s.GetPlugin(ctx, "xml-to-json")
+	must(t, err)
+	if !p.Installed {
+		t.Errorf("Init reset plugin installation: %+v", p)
+	}
+	list, err = s.ListPlugins(ctx)
+	must(t, err)
+	if len(list) != 3 {
+		t.Errorf("Init duplicated plugins: %d", len(list))
+	}
+}
+
+func approvalID(a storage.Approval) string { return a.ID }
+
+func testApprovals(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for i, a := range []storage.Approval{
+		{ID: "ap1", WorkflowID: "wf-1", NodeID: "n1", MessageID: "m1", Payload: []byte(`{"amount":10}`),
+			Metadata: map[string]string{"source": "api"}, Data: map[string]any{"amount": 10},
+			FormDefinition: map[string]any{"fields": []any{"reason"}}, Status: "pending"},
+		{ID: "ap2", WorkflowID: "wf-1", NodeID: "n1", MessageID: "m2", Status: "approved"},
+		{ID: "ap3", WorkflowID: "wf-2", NodeID: "n9", MessageID: "m3", Status: "pending"},
+	} {
+		a.CreatedAt = at(time.Duration(i) * time.Second)
+		must(t, s.CreateApproval(ctx, a))
+	}
+
+	got, err := s.GetApproval(ctx, "ap1")
+	must(t, err)
+	if got.WorkflowID != "wf-1" || got.NodeID != "n1" || got.MessageID != "m1" || string(got.Payload) != `{"amount":10}` ||
+		got.Status != "pending" || got.ProcessedAt != nil {
+		t.Errorf("GetApproval: %+v", got)
+	}
+	sameJSON(t, "approval metadata", got.Metadata, map[string]string{"source": "api"})
+	sameJSON(t, "approval data", got.Data, map[string]any{"amount": 10})
+	sameJSON(t, "approval form definition", got.FormDefinition, map[string]any{"fields": []any{"reason"}})
+	wantTime(t, "approval created_at", got.CreatedAt, at(0))
+	_, err = s.GetApproval(ctx, "missing")
+	wantNotFound(t, "GetApproval", err)
+
+	list, total, err := s.ListApprovals(ctx, storage.ApprovalFilter{})
+	must(t, err)
+	wantOrder(t, "ListApprovals order", list, approvalID, "ap3", "ap2", "ap1")
+	wantTotal(t, "ListApprovals", total, 3)
+	list, total, err = s.ListApprovals(ctx, storage.ApprovalFilter{WorkflowID: "wf-1", Status: "pending"})
+	must(t, err)
+	wantIDs(t, "ListApprovals filter", list, approvalID, "ap1")
+	wantTotal(t, "ListApprovals filter", total, 1)
+	list, _, err = s.ListApprovals(ctx, storage.ApprovalFilter{CommonFilter: storage.CommonFilter{Page: 1, Limit: 2}})
+	must(t, err)
+	wantOrder(t, "ListApprovals page 1", list, approvalID, "ap3", "ap2")
+	list, _, err = s.ListApprovals(ctx, storage.ApprovalFilter{CommonFilter: storage.CommonFilter{Page: 2, Limit: 2}})
+	must(t, err)
+	wantOrder(t, "ListApprovals page 2", list, approvalID, "ap1")
+
+	must(t, s.UpdateApprovalStatus(ctx, "ap1", "rejected", "alice", "too large", map[string]any{"reason": "limit"}))
+	got, err = s.GetApproval(ctx, "ap1")
+	must(t, err)
+	if got.Status != "rejected" || got.ProcessedBy != "alice" || got.Notes != "too large" || got.ProcessedAt == nil {
+		t.Errorf("UpdateApprovalStatus: %+v", got)
+	}
+	sameJSON(t, "approval form data", got.FormData, map[string]any{"reason": "limit"})
+	wantNotFound(t, "UpdateApprovalStatus", s.UpdateApprovalStatus(ctx, "missing", "approved", "", "", nil))
+
+	must(t, s.DeleteApproval(ctx, "ap1"))
+	wantNotFound(t, "DeleteApproval", s.DeleteApproval(ctx, "ap1"))
+	_, err = s.GetApproval(ctx, "ap1")
+	wantNotFound(t, "GetApproval after delete", err)
+}
+
+func suspendedID(m storage.SuspendedMessage) string { return m.ID }
+
+func testSuspendedMessages(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for _, m := range []storage.SuspendedMessage{
+		{ID: "s1", WorkflowID: "wf-1", NodeID: "wait", Payload: []byte("p1"), Metadata: map[string]string{"k": "v"},
+			Data: map[string]any{"n": 1}, ResumeAt: at(time.Minute), CreatedAt: at(0)},
+		{ID: "s2", WorkflowID: "wf-1", NodeID: "wait", ResumeAt: at(2 * time.Minute), CreatedAt: at(0)},
+		{ID: "s3", WorkflowID: "wf-2", NodeID: "wait", ResumeAt: at(time.Minute), CreatedAt: at(0)},
+	} {
+		must(t, s.CreateSuspendedMessage(ctx, m))
+	}
+
+	list, err := s.ListSuspendedMessages(ctx, "", at(time.Minute))
+	must(t, err)
+	wantIDs(t, "ListSuspendedMessages due", list, suspendedID, "s1", "s3")
+	list, err = s.ListSuspendedMessages(ctx, "wf-1", at(time.Hour))
+	must(t, err)
+	wantIDs(t, "ListSuspendedMessages workflow", list, suspendedID, "s1", "s2")
+	for _, m := range list {
+		if m.ID != "s1" {
+			continue
+		}
+		if m.NodeID != "wait" || string(m.Payload) != "p1" || m.Metadata["k"] != "v" {
+			t.Errorf("ListSuspendedMessages: %+v", m)
+		}
+		sameJSON(t, "suspended data", m.Data, map[string]any{"n": 1})
+		wantTime(t, "resume_at", m.ResumeAt, at(time.Minute))
+	}
+
+	must(t, s.DeleteSuspendedMessage(ctx, "s1"))
+	list, err = s.ListSuspendedMessages(ctx, "", at(time.Hour))
+	must(t, err)
+	wantIDs(t, "after DeleteSuspendedMessage", list, suspendedID, "s2", "s3")
+
+	for _, m := range []storage.SuspendedMessage{
+		{ID: "w1", WorkflowID: "wf-1", NodeID: "wait", WaitKey: "order-1", ResumeAt: at(time.Hour), CreatedAt: at(0)},
+		{ID: "w2", WorkflowID: "wf-1", NodeID: "wait", WaitKey: "order-1", ResumeAt: at(time.Hour), CreatedAt: at(0)},
+		{ID: "w3", WorkflowID: "wf-1", NodeID: "wait", WaitKey: "order-2", ResumeAt: at(time.Hour), CreatedAt: at(0)},
+		{ID: "w4", WorkflowID: "wf-1", NodeID: "other", WaitKey: "order-1", ResumeAt: at(time.Hour), CreatedAt: at(0)},
+		{ID: "w5", WorkflowID: "wf-2", NodeID: "wait", WaitKey: "order-1", ResumeAt: at(time.Hour), CreatedAt: at(0)},
+	} {
+		must(t, s.CreateSuspendedMessage(ctx, m))
+	}
+	waits, err := s.ListEventWaits(ctx, "wf-1", "wait", "order-1")
+	must(t, err)
+	wantIDs(t, "ListEventWaits", waits, suspendedID, "w1", "w2")
+	for _, m := range waits {
+		if m.WaitKey != "order-1" {
+			t.Errorf("ListEventWaits: wait key %q", m.WaitKey)
+		}
+	}
+
+	resolved := waits[0]
+	resolved.Metadata = map[string]string{"outcome": "matched"}
+	resolved.Data = map[string]any{"paid": true}
+	resolved.ResumeAt = at(0)
+	ok, err := s.ResolveEventWait(ctx, resolved)
+	must(t, err)
+	if !ok {
+		t.Fatal("ResolveEventWait: first resolution lost")
+	}
+	if ok, err = s.ResolveEventWait(ctx, resolved); err != nil || ok {
+		t.Fatalf("ResolveEventWait: second resolution = %v, %v; want false", ok, err)
+	}
+	if ok, err = s.ResolveEventWait(ctx, storage.SuspendedMessage{ID: "missing", ResumeAt: at(0)}); err != nil || ok {
+		t.Fatalf("ResolveEventWait missing = %v, %v; want false", ok, err)
+	}
+	still := "w1"
+	if resolved.ID == "w1" {
+		still = "w2"
+	}
+	waits, err = s.ListEventWaits(ctx, "wf-1", "wait", "order-1")
+	must(t, err)
+	wantIDs(t, "ListEventWaits after ResolveEventWait", waits, suspendedID, still)
+	list, err = s.ListSuspendedMessages(ctx, "wf-1", at(time.Minute))
+	must(t, err)
+	wantIDs(t, "resolved wait due", list, suspendedID, resolved.ID)
+	if m := list[0]; m.WaitKey != "" || m.Metadata["outcome"] != "matched" {
+		t.Errorf("resolved wait: %+v", m)
+	} else {
+		sameJSON(t, "resolved data", m.Data, map[string]any{"paid": true})
+		wantTime(t, "resolved resume_at", m.ResumeAt, at(0))
+	}
+}
+
+func testDashboardStats(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for _, wf := range []storage.Workflow{
+		{ID: "wf-1", Name: "a", VHost: "prod", Status: "running"},
+		{ID: "wf-2", Name: "b", VHost: "prod", Status: "failed"},
+		{ID: "wf-3", Name: "c", VHost: "prod", Status: "error: connection refused"},
+		{ID: "wf-4", Name: "d", VHost: "dev", Status: "running"},
+		{ID: "wf-5", Name: "e", VHost: "dev"},
+	} {
+		must(t, s.CreateWorkflow(ctx, wf))
+	}
+	must(t, s.UpdateWorkflowStats(ctx, "wf-1", 100, 2, 5))
+	must(t, s.UpdateWorkflowStats(ctx, "wf-4", 50, 1, 0))
+	for _, src := range []storage.Source{
+		{ID: "src-1", Name: "a", VHost: "prod", Status: "running"},
+		{ID: "src-2", Name: "b", VHost: "prod"},
+		{ID: "src-3", Name: "c", VHost: "dev", Status: "running"},
+	} {
+		must(t, s.CreateSource(ctx, src))
+	}
+	must(t, s.CreateSink(ctx, storage.Sink{ID: "snk-1", Name: "a", VHost: "prod", Status: "running"}))
+	must(t, s.CreateSink(ctx, storage.Sink{ID: "snk-2", Name: "b", VHost: "dev"}))
+	stale := time.Now().Add(-time.Hour)
+	must(t, s.CreateWorker(ctx, storage.Worker{ID: "w1", Name: "live"}))
+	must(t, s.CreateWorker(ctx, storage.Worker{ID: "w2", Name: "stale", LastSeen: &stale}))
+	must(t, s.UpdateWorkerHeartbeat(ctx, "w1", 1, 1))
+
+	check := func(vhost string, want storage.DashboardStats) {
+		t.Helper()
+		got, err := s.GetDashboardStats(ctx, vhost)
+		must(t, err)
+		got.Uptime, got.Throughput = 0, 0
+		if got != want {
+			t.Errorf("GetDashboardStats(%q):\n got %+v\nwant %+v", vhost, got, want)
+		}
+	}
+	check("", storage.DashboardStats{
+		TotalWorkflows: 5, ActiveWorkflows: 2, FailedWorkflows: 2, TotalProcessed: 150, TotalErrors: 3, TotalLag: 5,
+		TotalSources: 3, ActiveSources: 2, TotalSinks: 2, ActiveSinks: 1, ActiveWorkers: 1,
+	})
+	check("all", storage.DashboardStats{
+		TotalWorkflows: 5, ActiveWorkflows: 2, FailedWorkflows: 2, TotalProcessed: 150, TotalErrors: 3, TotalLag: 5,
+		TotalSources: 3, ActiveSources: 2, TotalSinks: 2, ActiveSinks: 1, ActiveWorkers: 1,
+	})
+	check("prod", storage.DashboardStats{
+		TotalWorkflows: 3, ActiveWorkflows: 1, FailedWorkflows: 2, TotalProcessed: 100, TotalErrors: 2, TotalLag: 5,
+		TotalSources: 2, ActiveSources: 1, TotalSinks: 1, ActiveSinks: 1, ActiveWorkers: 1,
+	})
+}
+
+func deadLetterID(d storage.DeadLetter) string { return d.ID }
+
+func testDeadLetters(t *testing.T, s storage.Storage) {
+	ctx := t.Context()
+	for i, d := range []storage.DeadLetter{
+		{ID: "dl1", WorkflowID: "wf-1", SinkID: "pg", MessageID: "m1", Operation: "create", Table: "orders",
+			ErrorClass: "sink_write", Error: "duplicate key value", Payload: []byte(`{"id":1}`),
+			Metadata: map[string]string{"_hermod_failed_sink": "pg"}, Data: map[string]any{"id": 1}},
+		{ID: "dl2", WorkflowID: "wf-1", SinkID: "http", MessageID: "m2", ErrorClass: "validation", Error: "missing field"},
+		{ID: "dl3", WorkflowID: "wf-2", SinkID: "pg", MessageID: "m3", ErrorClass: "sink_write", Error: "timeout"},
+	} {
+		d.CreatedAt = at(time.Duration(i) * time.Minute)
+		must(t, s.CreateDeadLetter(ctx, d))
+	}
+
+	got, err := s.GetDeadLetter(ctx, "dl1")
+	must(t, err)
+	if got.WorkflowID != "wf-1" || got.SinkID != "pg" || got.MessageID != "m1" || got.Operation != "create" ||
+		got.Table != "orders" || got.ErrorClass != "sink_write" || got.Error != "duplicate key value" ||
+		string(got.Payload) != `{"id":1}` || got.Status != "pending" || got.ReplayedAt != nil {
+		t.Errorf("GetDeadLetter: %+v", got)
+	}
+	sameJSON(t, "dead letter metadata", got.Metadata, map[string]string{"_hermod_failed_sink": "pg"})
+	sameJSON(t, "dead letter data", got.Data, map[string]any{"id": 1})
+	wantTime(t, "dead letter created_at", got.CreatedAt, at(0))
+	_, err = s.GetDeadLetter(ctx, "missing")
+	wantNotFound(t, "GetDeadLetter", err)
+
+	list, total, err := s.ListDeadLetters(ctx, storage.DeadLetterFilter{})
+	must(t, err)
+	wantOrder(t, "ListDeadLetters order", list, deadLetterID, "dl3", "dl2", "dl1")
+	wantTotal(t, "ListDeadLetters", total, 3)
+	list, total, err = s.ListDeadLetters(ctx, storage.DeadLetterFilter{WorkflowID: "wf-1", ErrorClass: "sink_write"})
+	must(t, err)
+	wantIDs(t, "ListDeadLetters workflow+class", list, deadLetterID, "dl1")
+	wantTotal(t, "ListDeadLetters workflow+class", total, 1)
+	list, _, err = s.ListDeadLetters(ctx, storage.DeadLetterFilter{SinkID: "pg"})
+	must(t, err)
+	wantIDs(t, "ListDeadLetters sink", list, deadLetterID, "dl1", "dl3")
+	list, _, err = s.ListDeadLetters(ctx, storage.DeadLetterFilter{CommonFilter: storage.CommonFilter{Since: at(30 * time.Second), Until: at(90 * time.Second)}})
+	must(t, err)
+	wantIDs(t, "ListDeadLetters time range", list, deadLetterID, "dl2")
+	list, _, err = s.ListDeadLetters(ctx, storage.DeadLetterFilter{CommonFilter: storage.CommonFilter{Search: "timeout"}})
+	must(t, err)
+	wantIDs(t, "ListDeadLetters search", list, deadLetterID, "dl3")
+	list, _, err = s.ListDeadLetters(ctx, storage.DeadLetterFilter{CommonFilter: storage.CommonFilter{Page: 2, Limit: 2}})
+	must(t, err)
+	wantOrder(t, "ListDeadLetters page 2", list, deadLetterID, "dl1")
+
+	replayed := at(time.Hour)
+	got.Payload = []byte(`{"id":2}`)
+	got.Data = map[string]any{"id": 2}
+	got.Status = "replayed"
+	got.ReplayCount = 1
+	got.ReplayedAt = &replayed
+	must(t, s.UpdateDeadLetter(ctx, got))
+	got, err = s.GetDeadLetter(ctx, "dl1")
+	must(t, err)
+	if string(got.Payload) != `{"id":2}` || got.Status != "replayed" || got.ReplayCount != 1 || got.ReplayedAt == nil {
+		t.Errorf("UpdateDeadLetter: %+v", got)
+	}
+	sameJSON(t, "updated dead letter data", got.Data, map[string]any{"id": 2})
+	wantNotFound(t, "UpdateDeadLetter", s.UpdateDeadLetter(ctx, storage.DeadLetter{ID: "missing"}))
+
+	list, _, err = s.ListDeadLetters(ctx, storage.DeadLetterFilter{Status: "pending"})
+	must(t, err)
+	wantIDs(t, "ListDeadLetters status", list, deadLetterID, "dl2", "dl3")
+
+	must(t, s.DeleteDeadLetter(ctx, "dl2"))
+	wantNotFound(t, "DeleteDeadLetter", s.DeleteDeadLetter(ctx, "dl2"))
+
+	n, err := s.PurgeDeadLetters(ctx, storage.DeadLetterFilter{SinkID: "pg", Status: "pending"})
+	must(t, err)
+	if n != 1 {
+		t.Errorf("PurgeDeadLetters removed %d, want 1", n)
+	}
+	list, _, err = s.ListDeadLetters(ctx, storage.DeadLetterFilter{})
+	must(t, err)
+	wantIDs(t, "after PurgeDeadLetters", list, deadLetterID, "dl1")
+}
@@ -104,6 +104,15 @@ func (m *BaseMockStorage) RenewWorkflowLease(ctx context.Context, workflowID, ow
 func (m *BaseMockStorage) ReleaseWorkflowLease(ctx context.Context, workflowID, ownerID string) error {
 	return nil
 }
+func (m *BaseMockStorage) AcquireLease(ctx context.Context, name, ownerID string, ttlSeconds int) (bool, error) {
+	return true, nil
+}
+func (m *BaseMockStorage) ReleaseLease(ctx context.Context, name, ownerID string) error {
+	return nil
+}
+func (m *BaseMockStorage) GetLease(ctx context.Context, name string) (storage.Lease, error) {
+	return storage.Lease{}, storage.ErrNotFound
+}
 
 func (m *BaseMockStorage) ListWorkers(ctx context.Context, filter storage.CommonFilter) ([]storage.Worker, int, error) {
 	return nil, 0, nil
@@ -190,6 +199,9 @@ func (m *BaseMockStorage) GetSchema(ctx context.Context, name string, version in
 func (m *BaseMockStorage) GetLatestSchema(ctx context.Context, name string) (storage.Schema, error) {
 	return storage.Schema{}, storage.ErrNotFound
 }
+func (m *BaseMockStorage) ListSchemasByID(ctx context.Context, schemaID int) ([]storage.Schema, error) {
+	return nil, nil
+}
 func (m *BaseMockStorage) CreateSchema(ctx context.Context, schema storage.Schema) error { return nil }
 
 func (m *BaseMockStorage) ListApprovals(ctx context.Context, filter storage.ApprovalFilter) ([]storage.Approval, int, error) {
@@ -204,6 +216,29 @@ func (m *BaseMockStorage) ListSuspendedMessages(ctx context.Context, workflowID
 	return nil, nil
 }
 func (m *BaseMockStorage) DeleteSuspendedMessage(ctx context.Context, id string) error { return nil }
+func (m *BaseMockStorage) ListEventWaits(ctx context.Context, workflowID, nodeID, key string) ([]storage.SuspendedMessage, error) {
+	return nil, nil
+}
+func (m *BaseMockStorage) ResolveEventWait(ctx context.Context, sm storage.SuspendedMessage) (bool, error) {
+	return false, nil
+}
+
+func (m *BaseMockStorage) CreateDeadLetter(ctx context.Context, dl storage.DeadLetter) error {
+	return nil
+}
+func (m *BaseMockStorage) ListDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) ([]storage.DeadLetter, int, error) {
+	return nil, 0, nil
+}
+func (m *BaseMockStorage) GetDeadLetter(ctx context.Context, id string) (storage.DeadLetter, error) {
+	return storage.DeadLetter{}, storage.ErrNotFound
+}
+func (m *BaseMockStorage) UpdateDeadLetter(ctx context.Context, dl storage.DeadLetter) error {
+	return nil
+}
+func (m *BaseMockStorage) DeleteDeadLetter(ctx context.Context, id string) error { return nil }
+func (m *BaseMockStorage) PurgeDeadLetters(ctx context.Context, filter storage.DeadLetterFilter) (int, error) {
+	return 0, nil
+}
 
 func (m *BaseMockStorage) RecordTraceStep(ctx context.Context, workflowID, messageID string, step hermod.TraceStep) error {
 	return nil
@@ -0,0 +1,432 @@
+package mongomem
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// runPipeline evaluates an aggregation pipeline over docs.
+func runPipeline(docs []bson.D, pipeline bson.A) ([]bson.D, error) {
+	for _, raw := range pipeline {
+		stage, ok := raw.(bson.D)
+		if !ok || len(stage) != 1 {
+			return nil, errorf(codeBadValue, "a pipeline stage specification object must contain exactly one field")
+		}
+		name, arg := stage[0].Key, stage[0].Value
+		var err error
+		switch name {
+		case "$match":
+			filter, _ := arg.(bson.D)
+			var out []bson.D
+			for _, d := range docs {
+				ok, err := matches(d, filter)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					out = append(out, d)
+				}
+			}
+			docs = out
+		case "$sort":
+			spec, _ := arg.(bson.D)
+			docs = append([]bson.D(nil), docs...)
+			sortDocs(docs, spec)
+		case "$skip":
+			n, _ := toFloat(arg)
+			if int(n) >= len(docs) {
+				docs = nil
+			} else {
+				docs = docs[int(n):]
+			}
+		case "$limit":
+			n, _ := toFloat(arg)
+			if int(n) < len(docs) {
+				docs = docs[:int(n)]
+			}
+		case "$project":
+			spec, _ := arg.(bson.D)
+			docs, err = projectStage(docs, spec)
+		case "$addFields", "$set":
+			spec, _ := arg.(bson.D)
+			out := make([]bson.D, len(docs))
+			for i, d := range docs {
+				nd := clone(d).(bson.D)
+				for _, f := range spec {
+					v, err := eval(d, f.Value)
+					if err != nil {
+						return nil, err
+					}
+					nd = setPath(nd, f.Key, v)
+				}
+				out[i] = nd
+			}
+			docs = out
+		case "$group":
+			spec, _ := arg.(bson.D)
+			docs, err = group(docs, spec)
+		case "$replaceRoot", "$replaceWith":
+			expr := arg
+			if name == "$replaceRoot" {
+				spec, _ := arg.(bson.D)
+				expr, _ = get(spec, "newRoot")
+			}
+			out := make([]bson.D, 0, len(docs))
+			for _, d := range docs {
+				v, err := eval(d, expr)
+				if err != nil {
+					return nil, err
+				}
+				nd, ok := v.(bson.D)
+				if !ok {
+					return nil, errorf(codeBadValue, "'newRoot' expression must evaluate to an object")
+				}
+				out = append(out, nd)
+			}
+			docs = out
+		case "$count":
+			docs = []bson.D{{{Key: fmt.Sprint(arg), Value: int32(len(docs))}}}
+		default:
+			return nil, errorf(codeBadValue, "unrecognized pipeline stage name: '%s'", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+func projectStage(docs []bson.D, spec bson.D) ([]bson.D, error) {
+	computed := false
+	for _, f := range spec {
+		switch f.Value.(type) {
+		case bool, int32, int64, float64:
+		default:
+			computed = true
+		}
+	}
+	if !computed {
+		out := make([]bson.D, len(docs))
+		for i, d := range docs {
+			out[i] = project(d, spec)
+		}
+		return out, nil
+	}
+	out := make([]bson.D, 0, len(docs))
+	for _, d := range docs {
+		nd := bson.D{}
+		if id, ok := get(d, "_id"); ok {
+			if v, listed := get(spec, "_id"); !listed || truthy(v) {
+				nd = append(nd, bson.E{Key: "_id", Value: id})
+			}
+		}
+		for _, f := range spec {
+			if f.Key == "_id" {
+				continue
+			}
+			switch f.Value.(type) {
+			case bool, int32, int64, float64:
+				if truthy(f.Value) {
+					if v, ok := lookup(d, f.Key); ok {
+						nd = setPath(nd, f.Key, v)
+					}
+				}
+				continue
+			}
+			v, err := eval(d, f.Value)
+			if err != nil {
+				return nil, err
+			}
+			nd = setPath(nd, f.Key, v)
+		}
+		out = append(out, nd)
+	}
+	return out, nil
+}
+
+type groupState struct {
+	key  any
+	out  bson.D
+	seen map[string]bool
+	avgN map[string]int
+}
+
+func group(docs []bson.D, spec bson.D) ([]bson.D, error) {
+	idExpr, ok := get(spec, "_id")
+	if !ok {
+		return nil, errorf(codeBadValue, "a group specification must include an _id")
+	}
+	var groups []*groupState
+	for _, d := range docs {
+		key, err := eval(d, idExpr)
+		if err != nil {
+			return nil, err
+		}
+		var g *groupState
+		for _, cand := range groups {
+			if compareValues(cand.key, key) == 0 && typeRank(cand.key) == typeRank(key) {
+				g = cand
+				break
+			}
+		}
+		if g == nil {
+			g = &groupState{key: key, out: bson.D{{Key: "_id", Value: key}}, seen: map[string]bool{}, avgN: map[string]int{}}
+			groups = append(groups, g)
+		}
+		for _, f := range spec {
+			if f.Key == "_id" {
+				continue
+			}
+			acc, ok := f.Value.(bson.D)
+			if !ok || len(acc) != 1 {
+				return nil, errorf(codeBadValue, "the field '%s' must be an accumulator object", f.Key)
+			}
+			v, err := eval(d, acc[0].Value)
+			if err != nil {
+				return nil, err
+			}
+			cur, _ := get(g.out, f.Key)
+			first := !g.seen[f.Key]
+			g.seen[f.Key] = true
+			switch acc[0].Key {
+			case "$sum":
+				if first {
+					cur = int32(0)
+				}
+				if _, numeric := toFloat(v); numeric {
+					cur, _ = addNumbers(cur, v)
+				}
+			case "$avg":
+				if first {
+					cur = 0.0
+				}
+				if n, numeric := toFloat(v); numeric {
+					c, _ := toFloat(cur)
+					cnt := g.avgN[f.Key]
+					cur = (c*float64(cnt) + n) / float64(cnt+1)
+					g.avgN[f.Key] = cnt + 1
+				}
+			case "$first":
+				if first {
+					cur = v
+				}
+			case "$last":
+				cur = v
+			case "$min":
+				if first || (v != nil && compareValues(v, cur) < 0) {
+					cur = v
+				}
+			case "$max":
+				if first || compareValues(v, cur) > 0 {
+					cur = v
+				}
+			case "$push":
+				cur = append(asArray(cur), v)
+			case "$addToSet":
+				arr := asArray(cur)
+				if !equalsOrContains(arr, true, v) {
+					arr = append(arr, v)
+				}
+				cur = arr
+			default:
+				return nil, errorf(codeBadValue, "unknown group operator '%s'", acc[0].Key)
+			}
+			g.out = setPath(g.out, f.Key, cur)
+		}
+	}
+	out := make([]bson.D, len(groups))
+	for i, g := range groups {
+		out[i] = clone(g.out).(bson.D)
+	}
+	return out, nil
+}
+
+// eval evaluates an aggregation expression against doc.
+func eval(doc bson.D, expr any) (any, error) {
+	switch x := expr.(type) {
+	case string:
+		switch {
+		case x == "$$ROOT" || x == "$$CURRENT":
+			return doc, nil
+		case strings.HasPrefix(x, "$$ROOT."):
+			v, _ := lookup(doc, strings.TrimPrefix(x, "$$ROOT."))
+			return v, nil
+		case strings.HasPrefix(x, "$$"):
+			return nil, errorf(codeBadValue, "unsupported variable %s", x)
+		case strings.HasPrefix(x, "$"):
+			v, _ := lookup(doc, x[1:])
+			return v, nil
+		}
+		return x, nil
+	case bson.A:
+		out := make(bson.A, len(x))
+		for i, e := range x {
+			v, err := eval(doc, e)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case bson.D:
+		if len(x) == 1 && strings.HasPrefix(x[0].Key, "$") {
+			return evalOperator(doc, x[0].Key, x[0].Value)
+		}
+		out := bson.D{}
+		for _, e := range x {
+			v, err := eval(doc, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, bson.E{Key: e.Key, Value: v})
+		}
+		return out, nil
+	}
+	return expr, nil
+}
+
+func evalArgs(doc bson.D, arg any) (bson.A, error) {
+	args, ok := arg.(bson.A)
+	if !ok {
+		args = bson.A{arg}
+	}
+	v, err := eval(doc, args)
+	if err != nil {
+		return nil, err
+	}
+	return v.(bson.A), nil
+}
+
+func evalOperator(doc bson.D, op string, arg any) (any, error) {
+	if op == "$literal" {
+		return arg, nil
+	}
+	if op == "$cond" {
+		var ifE, thenE, elseE any
+		if d, ok := arg.(bson.D); ok {
+			ifE, _ = get(d, "if")
+			thenE, _ = get(d, "then")
+			elseE, _ = get(d, "else")
+		} else if a := asArray(arg); len(a) == 3 {
+			ifE, thenE, elseE = a[0], a[1], a[2]
+		} else {
+			return nil, errorf(codeBadValue, "$cond requires if, then and else")
+		}
+		c, err := eval(doc, ifE)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(c) {
+			return eval(doc, thenE)
+		}
+		return eval(doc, elseE)
+	}
+	if op == "$regexMatch" {
+		spec, _ := arg.(bson.D)
+		inputE, _ := get(spec, "input")
+		input, err := eval(doc, inputE)
+		if err != nil {
+			return nil, err
+		}
+		pattern, _ := get(spec, "regex")
+		options, _ := get(spec, "options")
+		p, o := fmt.Sprint(pattern), ""
+		if re, ok := pattern.(bson.Regex); ok {
+			p, o = re.Pattern, re.Options
+		}
+		if s, ok := options.(string); ok {
+			o = s
+		}
+		s, ok := input.(string)
+		if !ok {
+			return false, nil
+		}
+		if o != "" {
+			p = "(?" + o + ")" + p
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, errorf(codeBadValue, "invalid regex: %v", err)
+		}
+		return re.MatchString(s), nil
+	}
+
+	args, err := evalArgs(doc, arg)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte":
+		if len(args) != 2 {
+			return nil, errorf(codeBadValue, "%s requires two arguments", op)
+		}
+		c := compareValues(args[0], args[1])
+		switch op {
+		case "$eq":
+			return c == 0, nil
+		case "$ne":
+			return c != 0, nil
+		case "$gt":
+			return c > 0, nil
+		case "$gte":
+			return c >= 0, nil
+		case "$lt":
+			return c < 0, nil
+		}
+		return c <= 0, nil
+	case "$in":
+		if len(args) != 2 {
+			return nil, errorf(codeBadValue, "$in requires two arguments")
+		}
+		for _, candidate := range asArray(args[1]) {
+			if compareValues(args[0], candidate) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$and":
+		for _, a := range args {
+			if !truthy(a) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "$or":
+		for _, a := range args {
+			if truthy(a) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$not":
+		return len(args) == 0 || !truthy(args[0]), nil
+	case "$ifNull":
+		for _, a := range args {
+			if a != nil {
+				return a, nil
+			}
+		}
+		return nil, nil
+	case "$add":
+		var sum any = int32(0)
+		for _, a := range args {
+			if _, ok := toFloat(a); ok {
+				sum, _ = addNumbers(sum, a)
+			}
+		}
+		return sum, nil
+	case "$toLower", "$toUpper":
+		if len(args) == 0 {
+			return "", nil
+		}
+		s := str(args[0])
+		if op == "$toLower" {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+	}
+	return nil, errorf(codeBadValue, "unrecognized expression '%s'", op)
+}
@@ -0,0 +1,226 @@
+// Package mongomem provides an in-memory MongoDB stand-in for tests.
+//
+// It plugs into the official Go driver as a custom deployment, so code under
+// test talks to a real *mongo.Client and exercises the driver's encoding,
+// cursor and error-mapping paths, while commands are answered by a small
+// in-process engine instead of a server. It implements the subset of the
+// query, update and aggregation languages used by Hermod's storage layer.
+package mongomem
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/address"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/x/bsonx/bsoncore"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/description"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/mnet"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/wiremessage"
+)
+
+const serverAddress = address.Address("mongomem:27017")
+
+var sessionTimeoutMinutes int64 = 30
+
+var serverDescription = description.Server{
+	Addr:                  serverAddress,
+	CanonicalAddr:         serverAddress,
+	Kind:                  description.ServerKindStandalone,
+	MaxDocumentSize:       16 << 20,
+	MaxMessageSize:        48_000_000,
+	MaxBatchCount:         100_000,
+	SessionTimeoutMinutes: &sessionTimeoutMinutes,
+	WireVersion:           &description.VersionRange{Min: 8, Max: 25},
+}
+
+// Deployment is an in-memory MongoDB deployment. The zero value is not usable;
+// create one with New.
+type Deployment struct {
+	engine  *engine
+	updates chan description.Topology
+	connID  atomic.Int64
+}
+
+var (
+	_ driver.Deployment   = (*Deployment)(nil)
+	_ driver.Server       = (*Deployment)(nil)
+	_ driver.Connector    = (*Deployment)(nil)
+	_ driver.Disconnector = (*Deployment)(nil)
+	_ driver.Subscriber   = (*Deployment)(nil)
+)
+
+// New returns an empty deployment.
+func New() *Deployment {
+	updates := make(chan description.Topology, 1)
+	updates <- description.Topology{
+		Kind:                  description.TopologyKindSingle,
+		SessionTimeoutMinutes: &sessionTimeoutMinutes,
+	}
+	return &Deployment{engine: newEngine(), updates: updates}
+}
+
+// Connect returns a client backed by a fresh, empty deployment.
+func Connect() (*mongo.Client, error) {
+	return New().Client()
+}
+
+// Client returns a new client that sends every operation to d.
+func (d *Deployment) Client() (*mongo.Client, error) {
+	opts := options.Client()
+	opts.Deployment = d
+	return mongo.Connect(opts)
+}
+
+// SelectServer implements driver.Deployment.
+func (d *Deployment) SelectServer(context.Context, description.ServerSelector) (driver.Server, error) {
+	return d, nil
+}
+
+// Kind implements driver.Deployment.
+func (d *Deployment) Kind() description.TopologyKind { return description.TopologyKindSingle }
+
+// GetServerSelectionTimeout implements driver.Deployment.
+func (d *Deployment) GetServerSelectionTimeout() time.Duration { return 0 }
+
+// Connection implements driver.Server.
+func (d *Deployment) Connection(context.Context) (*mnet.Connection, error) {
+	return mnet.NewConnection(&connection{engine: d.engine, id: d.connID.Add(1)}), nil
+}
+
+// RTTMonitor implements driver.Server.
+func (d *Deployment) RTTMonitor() driver.RTTMonitor { return rttMonitor{} }
+
+// Connect implements driver.Connector.
+func (d *Deployment) Connect() error { return nil }
+
+// Disconnect implements driver.Disconnector.
+func (d *Deployment) Disconnect(context.Context) error { return nil }
+
+// Subscribe implements driver.Subscriber.
+func (d *Deployment) Subscribe() (*driver.Subscription, error) {
+	return &driver.Subscription{Updates: d.updates}, nil
+}
+
+// Unsubscribe implements driver.Subscriber.
+func (d *Deployment) Unsubscribe(*driver.Subscription) error { return nil }
+
+type rttMonitor struct{}
+
+func (rttMonitor) EWMA() time.Duration { return 0 }
+func (rttMonitor) Min() time.Duration  { return 0 }
+func (rttMonitor) Stats() string       { return "" }
+
+// connection answers each OP_MSG written to it with the engine's reply, which
+// the driver then picks up with Read.
+type connection struct {
+	engine  *engine
+	id      int64
+	pending [][]byte
+}
+
+var (
+	_ mnet.ReadWriteCloser = (*connection)(nil)
+	_ mnet.Describer       = (*connection)(nil)
+)
+
+func (c *connection) Write(_ context.Context, wm []byte) error {
+	_, reqID, _, opcode, rem, ok := wiremessage.ReadHeader(wm)
+	if !ok {
+		return errors.New("mongomem: malformed message header")
+	}
+	if opcode != wiremessage.OpMsg {
+		return fmt.Errorf("mongomem: unsupported opcode %v", opcode)
+	}
+	flags, rem, ok := wiremessage.ReadMsgFlags(rem)
+	if !ok {
+		return errors.New("mongomem: malformed message flags")
+	}
+	if flags&wiremessage.ChecksumPresent != 0 && len(rem) >= 4 {
+		rem = rem[:len(rem)-4]
+	}
+
+	var cmd bson.D
+	for len(rem) > 0 {
+		var stype wiremessage.SectionType
+		stype, rem, ok = wiremessage.ReadMsgSectionType(rem)
+		if !ok {
+			return errors.New("mongomem: malformed section")
+		}
+		switch stype {
+		case wiremessage.SingleDocument:
+			var doc bsoncore.Document
+			doc, rem, ok = wiremessage.ReadMsgSectionSingleDocument(rem)
+			if !ok {
+				return errors.New("mongomem: malformed command document")
+			}
+			var body bson.D
+			if err := bson.Unmarshal(doc, &body); err != nil {
+				return err
+			}
+			cmd = append(body, cmd...)
+		case wiremessage.DocumentSequence:
+			var ident string
+			var docs []bsoncore.Document
+			ident, docs, rem, ok = wiremessage.ReadMsgSectionDocumentSequence(rem)
+			if !ok {
+				return errors.New("mongomem: malformed document sequence")
+			}
+			seq := make(bson.A, 0, len(docs))
+			for _, raw := range docs {
+				var d bson.D
+				if err := bson.Unmarshal(raw, &d); err != nil {
+					return err
+				}
+				seq = append(seq, d)
+			}
+			cmd = append(cmd, bson.E{Key: ident, Value: seq})
+		default:
+			return fmt.Errorf("mongomem: unsupported section type %v", stype)
+		}
+	}
+
+	reply := c.engine.run(cmd)
+	body, err := bson.Marshal(reply)
+	if err != nil {
+		return err
+	}
+	if flags&wiremessage.MoreToCome != 0 {
+		// Unacknowledged write: the driver does not read a reply.
+		return nil
+	}
+
+	idx, out := wiremessage.AppendHeaderStart(nil, wiremessage.NextRequestID(), reqID, wiremessage.OpMsg)
+	out = wiremessage.AppendMsgFlags(out, 0)
+	out = wiremessage.AppendMsgSectionType(out, wiremessage.SingleDocument)
+	out = append(out, body...)
+	binary.LittleEndian.PutUint32(out[idx:], uint32(len(out[idx:])))
+	c.pending = append(c.pending, out)
+	return nil
+}
+
+func (c *connection) Read(context.Context) ([]byte, error) {
+	if len(c.pending) == 0 {
+		return nil, errors.New("mongomem: no reply pending")
+	}
+	out := c.pending[0]
+	c.pending = c.pending[1:]
+	return out, nil
+}
+
+func (c *connection) Close() error                    { return nil }
+func (c *connection) Description() description.Server { return serverDescription }
+func (c *connection) ID() string                      { return fmt.Sprintf("mongomem[%d]", c.id) }
+func (c *connection) DriverConnectionID() int64       { return c.id }
+func (c *connection) ServerConnectionID() *int64      { return &c.id }
+func (c *connection) Address() address.Address        { return serverAddress }
+func (c *connection) Stale() bool                     { return false }
+func (c *connection) OIDCTokenGenID() uint64          { return 0 }
+func (c *connection) SetOIDCTokenGenID(uint64)        {}
@@ -0,0 +1,681 @@
+package mongomem
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Server error codes surfaced to the driver.
+const (
+	codeBadValue        = 2
+	codeNamespaceNotFnd = 26
+	codeCommandNotFound = 59
+	codeDuplicateKey    = 11000
+)
+
+type commandError struct {
+	code int32
+	msg  string
+}
+
+func (e *commandError) Error() string { return e.msg }
+
+func errorf(code int32, format string, args ...any) *commandError {
+	return &commandError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+type index struct {
+	name   string
+	keys   bson.D
+	unique bool
+}
+
+type collection struct {
+	name    string
+	docs    []bson.D
+	indexes []index
+}
+
+type engine struct {
+	mu  sync.Mutex
+	dbs map[string]map[string]*collection
+}
+
+func newEngine() *engine {
+	return &engine{dbs: map[string]map[string]*collection{}}
+}
+
+// coll returns the named collection, creating it when create is set.
+func (e *engine) coll(db, name string, create bool) *collection {
+	colls := e.dbs[db]
+	if colls == nil {
+		if !create {
+			return nil
+		}
+		colls = map[string]*collection{}
+		e.dbs[db] = colls
+	}
+	c := colls[name]
+	if c == nil && create {
+		c = &collection{name: name}
+		colls[name] = c
+	}
+	return c
+}
+
+// run executes a single command and returns its reply document.
+func (e *engine) run(cmd bson.D) bson.D {
+	if len(cmd) == 0 {
+		return errorReply(errorf(codeBadValue, "empty command"))
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	db, _ := get(cmd, "$db")
+	dbName, _ := db.(string)
+	name := cmd[0].Key
+	arg := cmd[0].Value
+
+	var (
+		reply bson.D
+		err   error
+	)
+	switch strings.ToLower(name) {
+	case "hello", "ismaster":
+		reply = bson.D{
+			{Key: "isWritablePrimary", Value: true},
+			{Key: "ismaster", Value: true},
+			{Key: "maxWireVersion", Value: int32(25)},
+			{Key: "minWireVersion", Value: int32(0)},
+			{Key: "logicalSessionTimeoutMinutes", Value: int32(sessionTimeoutMinutes)},
+		}
+	case "ping", "endsessions", "killcursors", "refreshsessions":
+	case "buildinfo":
+		reply = bson.D{{Key: "version", Value: "8.0.0"}, {Key: "versionArray", Value: bson.A{int32(8), int32(0), int32(0), int32(0)}}}
+	case "insert":
+		reply, err = e.insert(dbName, arg, cmd)
+	case "update":
+		reply, err = e.update(dbName, arg, cmd)
+	case "delete":
+		reply, err = e.delete(dbName, arg, cmd)
+	case "find":
+		reply, err = e.find(dbName, arg, cmd)
+	case "getmore":
+		coll, _ := get(cmd, "collection")
+		reply = cursorReply(dbName, fmt.Sprint(coll), "nextBatch", nil)
+	case "findandmodify":
+		reply, err = e.findAndModify(dbName, arg, cmd)
+	case "aggregate":
+		reply, err = e.aggregate(dbName, arg, cmd)
+	case "count":
+		reply, err = e.count(dbName, arg, cmd)
+	case "distinct":
+		reply, err = e.distinct(dbName, arg, cmd)
+	case "createindexes":
+		reply, err = e.createIndexes(dbName, arg, cmd)
+	case "listindexes":
+		reply, err = e.listIndexes(dbName, arg)
+	case "dropindexes":
+		reply, err = e.dropIndexes(dbName, arg, cmd)
+	case "create":
+		e.coll(dbName, fmt.Sprint(arg), true)
+	case "drop":
+		if colls := e.dbs[dbName]; colls != nil {
+			delete(colls, fmt.Sprint(arg))
+		}
+	case "dropdatabase":
+		delete(e.dbs, dbName)
+	case "listcollections":
+		reply = e.listCollections(dbName)
+	default:
+		err = errorf(codeCommandNotFound, "no such command: '%s'", name)
+	}
+	if err != nil {
+		return errorReply(err)
+	}
+	return append(reply, bson.E{Key: "ok", Value: 1.0})
+}
+
+func errorReply(err error) bson.D {
+	ce, ok := err.(*commandError)
+	if !ok {
+		ce = errorf(codeBadValue, "%s", err.Error())
+	}
+	return bson.D{
+		{Key: "ok", Value: 0.0},
+		{Key: "errmsg", Value: ce.msg},
+		{Key: "code", Value: ce.code},
+	}
+}
+
+func cursorReply(db, coll, batchKey string, docs []bson.D) bson.D {
+	batch := make(bson.A, 0, len(docs))
+	for _, d := range docs {
+		batch = append(batch, d)
+	}
+	return bson.D{{Key: "cursor", Value: bson.D{
+		{Key: "id", Value: int64(0)},
+		{Key: "ns", Value: db + "." + coll},
+		{Key: batchKey, Value: batch},
+	}}}
+}
+
+func writeError(i int, err error) bson.D {
+	ce, ok := err.(*commandError)
+	if !ok {
+		ce = errorf(codeBadValue, "%s", err.Error())
+	}
+	return bson.D{
+		{Key: "index", Value: int32(i)},
+		{Key: "code", Value: ce.code},
+		{Key: "errmsg", Value: ce.msg},
+	}
+}
+
+func (e *engine) insert(db string, arg any, cmd bson.D) (bson.D, error) {
+	c := e.coll(db, fmt.Sprint(arg), true)
+	docs, _ := get(cmd, "documents")
+	ordered := boolOr(cmd, "ordered", true)
+	var n int32
+	var werrs bson.A
+	for i, raw := range asArray(docs) {
+		doc, ok := raw.(bson.D)
+		if !ok {
+			return nil, errorf(codeBadValue, "documents must be objects")
+		}
+		doc = withID(doc)
+		if err := c.checkUnique(doc, -1); err != nil {
+			werrs = append(werrs, writeError(i, err))
+			if ordered {
+				break
+			}
+			continue
+		}
+		c.docs = append(c.docs, doc)
+		n++
+	}
+	reply := bson.D{{Key: "n", Value: n}}
+	if len(werrs) > 0 {
+		reply = append(reply, bson.E{Key: "writeErrors", Value: werrs})
+	}
+	return reply, nil
+}
+
+func (e *engine) update(db string, arg any, cmd bson.D) (bson.D, error) {
+	c := e.coll(db, fmt.Sprint(arg), true)
+	stmts, _ := get(cmd, "updates")
+	ordered := boolOr(cmd, "ordered", true)
+	var n, modified int32
+	var upserted, werrs bson.A
+	for i, raw := range asArray(stmts) {
+		stmt, _ := raw.(bson.D)
+		q, _ := get(stmt, "q")
+		u, _ := get(stmt, "u")
+		filter, _ := q.(bson.D)
+		multi := boolOr(stmt, "multi", false)
+		upsert := boolOr(stmt, "upsert", false)
+
+		matched, m, id, err := c.updateMatching(filter, u, multi, upsert)
+		if err != nil {
+			werrs = append(werrs, writeError(i, err))
+			if ordered {
+				break
+			}
+			continue
+		}
+		n += int32(matched)
+		modified += int32(m)
+		if id != nil {
+			n++
+			upserted = append(upserted, bson.D{{Key: "index", Value: int32(i)}, {Key: "_id", Value: id}})
+		}
+	}
+	reply := bson.D{{Key: "n", Value: n}, {Key: "nModified", Value: modified}}
+	if len(upserted) > 0 {
+		reply = append(reply, bson.E{Key: "upserted", Value: upserted})
+	}
+	if len(werrs) > 0 {
+		reply = append(reply, bson.E{Key: "writeErrors", Value: werrs})
+	}
+	return reply, nil
+}
+
+// updateMatching applies update u to the documents matching filter. It
+// returns the number matched and modified, and the _id of an upserted
+// document, if any.
+func (c *collection) updateMatching(filter bson.D, u any, multi, upsert bool) (matched, modified int, upsertedID any, err error) {
+	for i := range c.docs {
+		ok, err := matches(c.docs[i], filter)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if !ok {
+			continue
+		}
+		matched++
+		changed, err := c.apply(i, u)
+		if err != nil {
+			return matched - 1, modified, nil, err
+		}
+		if changed {
+			modified++
+		}
+		if !multi {
+			break
+		}
+	}
+	if matched == 0 && upsert {
+		doc, err := c.upsert(filter, u)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		id, _ := get(doc, "_id")
+		return 0, 0, id, nil
+	}
+	return matched, modified, nil, nil
+}
+
+// apply updates c.docs[i] in place, reverting if a unique index is violated.
+func (c *collection) apply(i int, u any) (bool, error) {
+	before := c.docs[i]
+	after, err := applyUpdate(clone(before).(bson.D), u, false)
+	if err != nil {
+		return false, err
+	}
+	if err := c.checkUnique(after, i); err != nil {
+		return false, err
+	}
+	c.docs[i] = after
+	return !sameDoc(before, after), nil
+}
+
+func (c *collection) upsert(filter bson.D, u any) (bson.D, error) {
+	doc, err := applyUpdate(seedFromFilter(filter), u, true)
+	if err != nil {
+		return nil, err
+	}
+	doc = withID(doc)
+	if err := c.checkUnique(doc, -1); err != nil {
+		return nil, err
+	}
+	c.docs = append(c.docs, doc)
+	return doc, nil
+}
+
+func (e *engine) delete(db string, arg any, cmd bson.D) (bson.D, error) {
+	c := e.coll(db, fmt.Sprint(arg), false)
+	stmts, _ := get(cmd, "deletes")
+	var n int32
+	if c == nil {
+		return bson.D{{Key: "n", Value: n}}, nil
+	}
+	for _, raw := range asArray(stmts) {
+		stmt, _ := raw.(bson.D)
+		q, _ := get(stmt, "q")
+		filter, _ := q.(bson.D)
+		limit := intOr(stmt, "limit", 0)
+		kept := c.docs[:0:0]
+		removed := 0
+		for _, doc := range c.docs {
+			if limit == 0 || removed < limit {
+				ok, err := matches(doc, filter)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					removed++
+					continue
+				}
+			}
+			kept = append(kept, doc)
+		}
+		c.docs = kept
+		n += int32(removed)
+	}
+	return bson.D{{Key: "n", Value: n}}, nil
+}
+
+// query runs filter, sort, skip and limit over a collection.
+func (c *collection) query(filter bson.D, sortSpec bson.D, skip, limit int) ([]bson.D, error) {
+	if c == nil {
+		return nil, nil
+	}
+	var out []bson.D
+	for _, doc := range c.docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, doc)
+		}
+	}
+	if len(sortSpec) > 0 {
+		sortDocs(out, sortSpec)
+	}
+	if skip > 0 {
+		if skip >= len(out) {
+			return nil, nil
+		}
+		out = out[skip:]
+	}
+	if limit > 0 && limit < len(out) {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (e *engine) find(db string, arg any, cmd bson.D) (bson.D, error) {
+	name := fmt.Sprint(arg)
+	filter := docOr(cmd, "filter")
+	limit := intOr(cmd, "limit", 0)
+	if limit < 0 {
+		limit = -limit
+	}
+	docs, err := e.coll(db, name, false).query(filter, docOr(cmd, "sort"), intOr(cmd, "skip", 0), limit)
+	if err != nil {
+		return nil, err
+	}
+	proj := docOr(cmd, "projection")
+	out := make([]bson.D, len(docs))
+	for i, d := range docs {
+		out[i] = project(d, proj)
+	}
+	return cursorReply(db, name, "firstBatch", out), nil
+}
+
+func (e *engine) findAndModify(db string, arg any, cmd bson.D) (bson.D, error) {
+	c := e.coll(db, fmt.Sprint(arg), true)
+	filter := docOr(cmd, "query")
+	docs, err := c.query(filter, docOr(cmd, "sort"), 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	proj := docOr(cmd, "fields")
+	returnNew := boolOr(cmd, "new", false)
+	lastErr := bson.D{}
+	var value any
+
+	switch {
+	case boolOr(cmd, "remove", false):
+		if len(docs) == 1 {
+			i := c.indexOf(docs[0])
+			value = project(c.docs[i], proj)
+			c.docs = append(c.docs[:i], c.docs[i+1:]...)
+		}
+		lastErr = append(lastErr, bson.E{Key: "n", Value: int32(len(docs))})
+	default:
+		u, _ := get(cmd, "update")
+		if len(docs) == 1 {
+			i := c.indexOf(docs[0])
+			before := c.docs[i]
+			if _, err := c.apply(i, u); err != nil {
+				return nil, err
+			}
+			if returnNew {
+				value = project(c.docs[i], proj)
+			} else {
+				value = project(before, proj)
+			}
+			lastErr = append(lastErr, bson.E{Key: "n", Value: int32(1)}, bson.E{Key: "updatedExisting", Value: true})
+		} else if boolOr(cmd, "upsert", false) {
+			doc, err := c.upsert(filter, u)
+			if err != nil {
+				return nil, err
+			}
+			id, _ := get(doc, "_id")
+			if returnNew {
+				value = project(doc, proj)
+			}
+			lastErr = append(lastErr,
+				bson.E{Key: "n", Value: int32(1)},
+				bson.E{Key: "updatedExisting", Value: false},
+				bson.E{Key: "upserted", Value: id})
+		} else {
+			lastErr = append(lastErr, bson.E{Key: "n", Value: int32(0)}, bson.E{Key: "updatedExisting", Value: false})
+		}
+	}
+	return bson.D{{Key: "lastErrorObject", Value: lastErr}, {Key: "value", Value: value}}, nil
+}
+
+func (c *collection) indexOf(doc bson.D) int {
+	for i := range c.docs {
+		if len(c.docs[i]) > 0 && len(doc) > 0 && &c.docs[i][0] == &doc[0] {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e *engine) count(db string, arg any, cmd bson.D) (bson.D, error) {
+	docs, err := e.coll(db, fmt.Sprint(arg), false).query(docOr(cmd, "query"), nil, intOr(cmd, "skip", 0), intOr(cmd, "limit", 0))
+	if err != nil {
+		return nil, err
+	}
+	return bson.D{{Key: "n", Value: int32(len(docs))}}, nil
+}
+
+func (e *engine) distinct(db string, arg any, cmd bson.D) (bson.D, error) {
+	docs, err := e.coll(db, fmt.Sprint(arg), false).query(docOr(cmd, "query"), nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	key, _ := get(cmd, "key")
+	var values bson.A
+	for _, d := range docs {
+		v, ok := lookup(d, fmt.Sprint(key))
+		if !ok {
+			continue
+		}
+		dup := false
+		for _, seen := range values {
+			if compareValues(seen, v) == 0 {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			values = append(values, v)
+		}
+	}
+	return bson.D{{Key: "values", Value: values}}, nil
+}
+
+func (e *engine) aggregate(db string, arg any, cmd bson.D) (bson.D, error) {
+	name := fmt.Sprint(arg)
+	c := e.coll(db, name, false)
+	var docs []bson.D
+	if c != nil {
+		docs = append(docs, c.docs...)
+	}
+	pipeline, _ := get(cmd, "pipeline")
+	out, err := runPipeline(docs, asArray(pipeline))
+	if err != nil {
+		return nil, err
+	}
+	return cursorReply(db, name, "firstBatch", out), nil
+}
+
+func (e *engine) createIndexes(db string, arg any, cmd bson.D) (bson.D, error) {
+	c := e.coll(db, fmt.Sprint(arg), true)
+	before := int32(len(c.indexes) + 1)
+	specs, _ := get(cmd, "indexes")
+	for _, raw := range asArray(specs) {
+		spec, _ := raw.(bson.D)
+		keys := docOr(spec, "key")
+		name, _ := get(spec, "name")
+		idx := index{name: fmt.Sprint(name), keys: keys, unique: boolOr(spec, "unique", false)}
+		if idx.name == "" || idx.name == "<nil>" {
+			idx.name = indexName(keys)
+		}
+		replaced := false
+		for i := range c.indexes {
+			if c.indexes[i].name == idx.name {
+				c.indexes[i] = idx
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.indexes = append(c.indexes, idx)
+		}
+	}
+	return bson.D{
+		{Key: "numIndexesBefore", Value: before},
+		{Key: "numIndexesAfter", Value: int32(len(c.indexes) + 1)},
+	}, nil
+}
+
+func (e *engine) listIndexes(db string, arg any) (bson.D, error) {
+	name := fmt.Sprint(arg)
+	c := e.coll(db, name, false)
+	if c == nil {
+		return nil, errorf(codeNamespaceNotFnd, "ns does not exist: %s.%s", db, name)
+	}
+	out := []bson.D{{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "_id", Value: int32(1)}}}, {Key: "name", Value: "_id_"}}}
+	for _, idx := range c.indexes {
+		d := bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: idx.keys}, {Key: "name", Value: idx.name}}
+		if idx.unique {
+			d = append(d, bson.E{Key: "unique", Value: true})
+		}
+		out = append(out, d)
+	}
+	return cursorReply(db, name, "firstBatch", out), nil
+}
+
+func (e *engine) dropIndexes(db string, arg any, cmd bson.D) (bson.D, error) {
+	c := e.coll(db, fmt.Sprint(arg), false)
+	if c == nil {
+		return nil, nil
+	}
+	target, _ := get(cmd, "index")
+	if target == "*" {
+		c.indexes = nil
+		return nil, nil
+	}
+	kept := c.indexes[:0]
+	for _, idx := range c.indexes {
+		if idx.name != fmt.Sprint(target) {
+			kept = append(kept, idx)
+		}
+	}
+	c.indexes = kept
+	return nil, nil
+}
+
+func (e *engine) listCollections(db string) bson.D {
+	var names []string
+	for name := range e.dbs[db] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]bson.D, len(names))
+	for i, n := range names {
+		out[i] = bson.D{{Key: "name", Value: n}, {Key: "type", Value: "collection"}}
+	}
+	return cursorReply(db, "$cmd.listCollections", "firstBatch", out)
+}
+
+// checkUnique reports a duplicate key error if doc collides with any document
+// other than c.docs[self] on _id or a unique index.
+func (c *collection) checkUnique(doc bson.D, self int) error {
+	id, _ := get(doc, "_id")
+	for i, other := range c.docs {
+		if i == self {
+			continue
+		}
+		if oid, _ := get(other, "_id"); compareValues(oid, id) == 0 {
+			return errorf(codeDuplicateKey, "E11000 duplicate key error collection: %s index: _id_ dup key: { _id: %v }", c.name, id)
+		}
+		for _, idx := range c.indexes {
+			if !idx.unique {
+				continue
+			}
+			same := true
+			for _, k := range idx.keys {
+				a, _ := lookup(doc, k.Key)
+				b, _ := lookup(other, k.Key)
+				if compareValues(a, b) != 0 {
+					same = false
+					break
+				}
+			}
+			if same {
+				return errorf(codeDuplicateKey, "E11000 duplicate key error collection: %s index: %s", c.name, idx.name)
+			}
+		}
+	}
+	return nil
+}
+
+func indexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s_%v", k.Key, k.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// withID ensures doc has an _id, generating an ObjectID and placing it first
+// as the server does.
+func withID(doc bson.D) bson.D {
+	if _, ok := get(doc, "_id"); ok {
+		return doc
+	}
+	return append(bson.D{{Key: "_id", Value: bson.NewObjectID()}}, doc...)
+}
+
+func sameDoc(a, b bson.D) bool {
+	x, err1 := bson.Marshal(a)
+	y, err2 := bson.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(x, y)
+}
+
+// get returns the value of a top-level key.
+func get(doc bson.D, key string) (any, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func docOr(doc bson.D, key string) bson.D {
+	v, _ := get(doc, key)
+	d, _ := v.(bson.D)
+	return d
+}
+
+func boolOr(doc bson.D, key string, def bool) bool {
+	v, ok := get(doc, key)
+	if !ok {
+		return def
+	}
+	return truthy(v)
+}
+
+func intOr(doc bson.D, key string, def int) int {
+	v, ok := get(doc, key)
+	if !ok {
+		return def
+	}
+	if f, ok := toFloat(v); ok {
+		return int(f)
+	}
+	return def
+}
+
+func asArray(v any) bson.A {
+	switch a := v.(type) {
+	case bson.A:
+		return a
+	case []any:
+		return a
+	}
+	return nil
+}
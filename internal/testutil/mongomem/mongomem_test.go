@@ -0,0 +1,87 @@
+package mongomem
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestClientRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	client, err := Connect()
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Disconnect(ctx)
+	coll := client.Database("test").Collection("items")
+
+	if _, err := coll.InsertMany(ctx, []any{
+		bson.D{{Key: "_id", Value: "a"}, {Key: "n", Value: 1}, {Key: "tag", Value: "x"}},
+		bson.D{{Key: "_id", Value: "b"}, {Key: "n", Value: 2}, {Key: "tag", Value: "y"}},
+		bson.D{{Key: "_id", Value: "c"}, {Key: "n", Value: 3}, {Key: "tag", Value: "x"}},
+	}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	_, err = coll.InsertOne(ctx, bson.D{{Key: "_id", Value: "a"}})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("expected duplicate key error, got %v", err)
+	}
+
+	cur, err := coll.Find(ctx, bson.M{"n": bson.M{"$gte": 2}}, options.Find().SetSort(bson.D{{Key: "n", Value: -1}}))
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	var found []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cur.All(ctx, &found); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(found) != 2 || found[0].ID != "c" || found[1].ID != "b" {
+		t.Fatalf("unexpected find result: %+v", found)
+	}
+
+	res, err := coll.UpdateOne(ctx, bson.M{"_id": "d"}, bson.M{"$set": bson.M{"tag": "x"}, "$inc": bson.M{"n": 4}}, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if res.UpsertedID != "d" {
+		t.Fatalf("expected upserted id d, got %v", res.UpsertedID)
+	}
+
+	n, err := coll.CountDocuments(ctx, bson.M{"tag": "x"})
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 documents tagged x, got %d", n)
+	}
+
+	agg, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$tag"}, {Key: "total", Value: bson.D{{Key: "$sum", Value: "$n"}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+	var groups []struct {
+		ID    string `bson:"_id"`
+		Total int64  `bson:"total"`
+	}
+	if err := agg.All(ctx, &groups); err != nil {
+		t.Fatalf("decode aggregate: %v", err)
+	}
+	if len(groups) != 2 || groups[0].ID != "x" || groups[0].Total != 8 || groups[1].Total != 2 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+
+	err = coll.FindOne(ctx, bson.M{"_id": "missing"}).Err()
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("expected ErrNoDocuments, got %v", err)
+	}
+}
@@ -0,0 +1,702 @@
+package mongomem
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// matches reports whether doc satisfies a query filter.
+func matches(doc bson.D, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		var ok bool
+		var err error
+		switch e.Key {
+		case "$and", "$or", "$nor":
+			ok, err = matchLogical(doc, e.Key, e.Value)
+		case "$comment":
+			ok = true
+		default:
+			if strings.HasPrefix(e.Key, "$") {
+				return false, errorf(codeBadValue, "unknown top level operator: %s", e.Key)
+			}
+			v, present := lookup(doc, e.Key)
+			ok, err = matchField(v, present, e.Value)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchLogical(doc bson.D, op string, arg any) (bool, error) {
+	clauses := asArray(arg)
+	if len(clauses) == 0 {
+		return false, errorf(codeBadValue, "%s must be a nonempty array", op)
+	}
+	for _, raw := range clauses {
+		clause, _ := raw.(bson.D)
+		ok, err := matches(doc, clause)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case op == "$and" && !ok:
+			return false, nil
+		case op == "$or" && ok:
+			return true, nil
+		case op == "$nor" && ok:
+			return false, nil
+		}
+	}
+	return op != "$or", nil
+}
+
+// matchField evaluates a single field condition, which is either a literal
+// (equality) or a document of query operators.
+func matchField(v any, present bool, cond any) (bool, error) {
+	ops, isOps := operatorDoc(cond)
+	if !isOps {
+		if re, ok := cond.(bson.Regex); ok {
+			return matchRegex(v, re.Pattern, re.Options)
+		}
+		return equalsOrContains(v, present, cond), nil
+	}
+	for _, op := range ops {
+		ok, err := matchOperator(v, present, op.Key, op.Value, ops)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// operatorDoc reports whether cond is a document whose keys are operators.
+func operatorDoc(cond any) (bson.D, bool) {
+	d, ok := cond.(bson.D)
+	if !ok || len(d) == 0 || !strings.HasPrefix(d[0].Key, "$") {
+		return nil, false
+	}
+	return d, true
+}
+
+func matchOperator(v any, present bool, op string, arg any, all bson.D) (bool, error) {
+	switch op {
+	case "$eq":
+		return equalsOrContains(v, present, arg), nil
+	case "$ne":
+		return !equalsOrContains(v, present, arg), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		if !present {
+			return false, nil
+		}
+		return anyElem(v, func(x any) bool { return compareOp(op, x, arg) }), nil
+	case "$in", "$nin":
+		in := false
+		for _, candidate := range asArray(arg) {
+			if re, ok := candidate.(bson.Regex); ok {
+				if m, _ := matchRegex(v, re.Pattern, re.Options); m {
+					in = true
+					break
+				}
+				continue
+			}
+			if equalsOrContains(v, present, candidate) {
+				in = true
+				break
+			}
+		}
+		return in == (op == "$in"), nil
+	case "$exists":
+		return present == truthy(arg), nil
+	case "$regex":
+		pattern, options := "", ""
+		switch p := arg.(type) {
+		case string:
+			pattern = p
+		case bson.Regex:
+			pattern, options = p.Pattern, p.Options
+		}
+		if o, ok := get(all, "$options"); ok {
+			options, _ = o.(string)
+		}
+		return matchRegex(v, pattern, options)
+	case "$options":
+		return true, nil
+	case "$not":
+		ok, err := matchField(v, present, arg)
+		return !ok, err
+	case "$size":
+		n, _ := toFloat(arg)
+		a, ok := v.(bson.A)
+		return ok && len(a) == int(n), nil
+	case "$all":
+		for _, want := range asArray(arg) {
+			if !equalsOrContains(v, present, want) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "$elemMatch":
+		sub, _ := arg.(bson.D)
+		for _, elem := range asArray(v) {
+			if d, ok := elem.(bson.D); ok {
+				if m, err := matches(d, sub); err != nil || m {
+					return m, err
+				}
+			} else if m, err := matchField(elem, true, sub); err != nil || m {
+				return m, err
+			}
+		}
+		return false, nil
+	}
+	return false, errorf(codeBadValue, "unknown operator: %s", op)
+}
+
+func compareOp(op string, a, b any) bool {
+	// Range operators only compare values of the same canonical type.
+	if typeRank(a) != typeRank(b) {
+		return false
+	}
+	c := compareValues(a, b)
+	switch op {
+	case "$gt":
+		return c > 0
+	case "$gte":
+		return c >= 0
+	case "$lt":
+		return c < 0
+	default:
+		return c <= 0
+	}
+}
+
+func matchRegex(v any, pattern, options string) (bool, error) {
+	flags := ""
+	for _, o := range options {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, errorf(codeBadValue, "invalid regex: %v", err)
+	}
+	return anyElem(v, func(x any) bool {
+		s, ok := x.(string)
+		return ok && re.MatchString(s)
+	}), nil
+}
+
+// equalsOrContains implements equality matching: a null condition matches
+// missing fields, and an array field matches if any element is equal.
+func equalsOrContains(v any, present bool, cond any) bool {
+	if cond == nil {
+		return !present || v == nil
+	}
+	if !present {
+		return false
+	}
+	if compareValues(v, cond) == 0 && typeRank(v) == typeRank(cond) {
+		return true
+	}
+	if a, ok := v.(bson.A); ok {
+		for _, elem := range a {
+			if compareValues(elem, cond) == 0 && typeRank(elem) == typeRank(cond) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyElem(v any, fn func(any) bool) bool {
+	if a, ok := v.(bson.A); ok {
+		for _, elem := range a {
+			if fn(elem) {
+				return true
+			}
+		}
+		return false
+	}
+	return fn(v)
+}
+
+// lookup resolves a dotted path within doc. As on the server, a path segment
+// that is not an array index descends into every document in an array and
+// yields the array of values found.
+func lookup(doc bson.D, path string) (any, bool) {
+	return lookupParts(doc, strings.Split(path, "."))
+}
+
+func lookupParts(cur any, parts []string) (any, bool) {
+	if len(parts) == 0 {
+		return cur, true
+	}
+	part, rest := parts[0], parts[1:]
+	switch c := cur.(type) {
+	case bson.D:
+		v, ok := get(c, part)
+		if !ok {
+			return nil, false
+		}
+		return lookupParts(v, rest)
+	case bson.A:
+		if i, err := strconv.Atoi(part); err == nil {
+			if i < 0 || i >= len(c) {
+				return nil, false
+			}
+			return lookupParts(c[i], rest)
+		}
+		var found bson.A
+		for _, elem := range c {
+			if d, ok := elem.(bson.D); ok {
+				if v, ok := lookupParts(d, parts); ok {
+					found = append(found, v)
+				}
+			}
+		}
+		return found, len(found) > 0
+	}
+	return nil, false
+}
+
+// typeRank orders BSON types as the server does for comparisons and sorting.
+func typeRank(v any) int {
+	switch v.(type) {
+	case nil, bson.Null, bson.Undefined:
+		return 1
+	case int32, int64, float64, int, bson.Decimal128:
+		return 2
+	case string, bson.Symbol:
+		return 3
+	case bson.D, bson.M:
+		return 4
+	case bson.A:
+		return 5
+	case bson.Binary, []byte:
+		return 6
+	case bson.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case bson.DateTime, time.Time:
+		return 9
+	case bson.Timestamp:
+		return 10
+	case bson.Regex:
+		return 11
+	}
+	return 12
+}
+
+// compareValues returns -1, 0 or 1 following BSON comparison order.
+func compareValues(a, b any) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return cmp(ra, rb)
+	}
+	switch ra {
+	case 1:
+		return 0
+	case 2:
+		ai, aInt := toInt(a)
+		bi, bInt := toInt(b)
+		if aInt && bInt {
+			return cmp(ai, bi)
+		}
+		af, _ := toFloat(a)
+		bf, _ := toFloat(b)
+		return cmp(af, bf)
+	case 3:
+		return strings.Compare(str(a), str(b))
+	case 4:
+		da, db := asDoc(a), asDoc(b)
+		for i := 0; i < len(da) && i < len(db); i++ {
+			if c := compareValues(da[i].Value, db[i].Value); c != 0 {
+				return c
+			}
+			if c := strings.Compare(da[i].Key, db[i].Key); c != 0 {
+				return c
+			}
+		}
+		return cmp(len(da), len(db))
+	case 5:
+		aa, ba := a.(bson.A), b.(bson.A)
+		for i := 0; i < len(aa) && i < len(ba); i++ {
+			if c := compareValues(aa[i], ba[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp(len(aa), len(ba))
+	case 6:
+		return bytes.Compare(binData(a), binData(b))
+	case 7:
+		x, y := a.(bson.ObjectID), b.(bson.ObjectID)
+		return bytes.Compare(x[:], y[:])
+	case 8:
+		x, y := a.(bool), b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case 9:
+		return cmp(millis(a), millis(b))
+	case 10:
+		x, y := a.(bson.Timestamp), b.(bson.Timestamp)
+		if x.T != y.T {
+			return cmp(x.T, y.T)
+		}
+		return cmp(x.I, y.I)
+	}
+	return 0
+}
+
+func cmp[T int | int64 | uint32 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func str(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case bson.Symbol:
+		return string(s)
+	}
+	return ""
+}
+
+func asDoc(v any) bson.D {
+	switch d := v.(type) {
+	case bson.D:
+		return d
+	case bson.M:
+		out := make(bson.D, 0, len(d))
+		for k, val := range d {
+			out = append(out, bson.E{Key: k, Value: val})
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+		return out
+	}
+	return nil
+}
+
+func binData(v any) []byte {
+	switch b := v.(type) {
+	case bson.Binary:
+		return b.Data
+	case []byte:
+		return b
+	}
+	return nil
+}
+
+func millis(v any) int64 {
+	switch t := v.(type) {
+	case bson.DateTime:
+		return int64(t)
+	case time.Time:
+		return t.UnixMilli()
+	}
+	return 0
+}
+
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case int32, int64, int, float64:
+		f, _ := toFloat(x)
+		return f != 0
+	}
+	return true
+}
+
+// sortDocs stably sorts docs by a sort specification such as {a: 1, b: -1}.
+func sortDocs(docs []bson.D, spec bson.D) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, k := range spec {
+			dir, _ := toFloat(k.Value)
+			a, _ := lookup(docs[i], k.Key)
+			b, _ := lookup(docs[j], k.Key)
+			c := compareValues(a, b)
+			if dir < 0 {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// project applies an inclusion or exclusion projection to doc.
+func project(doc bson.D, proj bson.D) bson.D {
+	if len(proj) == 0 {
+		return doc
+	}
+	inclusive := false
+	for _, p := range proj {
+		if p.Key != "_id" && truthy(p.Value) {
+			inclusive = true
+		}
+	}
+	keepID := true
+	if v, ok := get(proj, "_id"); ok {
+		keepID = truthy(v)
+	}
+	out := bson.D{}
+	for _, e := range doc {
+		if e.Key == "_id" {
+			if keepID {
+				out = append(out, e)
+			}
+			continue
+		}
+		v, listed := get(proj, e.Key)
+		keep := listed && truthy(v)
+		if !inclusive {
+			keep = !listed || truthy(v)
+		}
+		if keep {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// clone deep-copies documents and arrays so stored data is never aliased by
+// values handed back to callers or mutated in place by updates.
+func clone(v any) any {
+	switch x := v.(type) {
+	case bson.D:
+		out := make(bson.D, len(x))
+		for i, e := range x {
+			out[i] = bson.E{Key: e.Key, Value: clone(e.Value)}
+		}
+		return out
+	case bson.A:
+		out := make(bson.A, len(x))
+		for i, e := range x {
+			out[i] = clone(e)
+		}
+		return out
+	}
+	return v
+}
+
+// seedFromFilter builds the base document for an upsert from the equality
+// conditions in filter.
+func seedFromFilter(filter bson.D) bson.D {
+	doc := bson.D{}
+	for _, e := range filter {
+		switch {
+		case e.Key == "$and":
+			for _, raw := range asArray(e.Value) {
+				if d, ok := raw.(bson.D); ok {
+					for _, se := range seedFromFilter(d) {
+						doc = setPath(doc, se.Key, se.Value)
+					}
+				}
+			}
+		case strings.HasPrefix(e.Key, "$"):
+		default:
+			if ops, ok := operatorDoc(e.Value); ok {
+				if v, ok := get(ops, "$eq"); ok {
+					doc = setPath(doc, e.Key, clone(v))
+				}
+				continue
+			}
+			doc = setPath(doc, e.Key, clone(e.Value))
+		}
+	}
+	return doc
+}
+
+// applyUpdate applies an update document (operators or a replacement) to doc.
+func applyUpdate(doc bson.D, u any, inserting bool) (bson.D, error) {
+	upd, ok := u.(bson.D)
+	if !ok {
+		return nil, errorf(codeBadValue, "update must be a document")
+	}
+	if _, isOps := operatorDoc(upd); !isOps {
+		id, hasID := get(doc, "_id")
+		out := bson.D{}
+		if hasID {
+			out = append(out, bson.E{Key: "_id", Value: id})
+		}
+		for _, e := range upd {
+			if e.Key == "_id" && hasID {
+				continue
+			}
+			out = append(out, bson.E{Key: e.Key, Value: clone(e.Value)})
+		}
+		return out, nil
+	}
+	for _, op := range upd {
+		fields, _ := op.Value.(bson.D)
+		for _, f := range fields {
+			var err error
+			switch op.Key {
+			case "$set":
+				doc = setPath(doc, f.Key, clone(f.Value))
+			case "$setOnInsert":
+				if inserting {
+					doc = setPath(doc, f.Key, clone(f.Value))
+				}
+			case "$unset":
+				doc = unsetPath(doc, f.Key)
+			case "$inc":
+				cur, _ := lookup(doc, f.Key)
+				var sum any
+				sum, err = addNumbers(cur, f.Value)
+				doc = setPath(doc, f.Key, sum)
+			case "$min", "$max":
+				cur, present := lookup(doc, f.Key)
+				c := compareValues(f.Value, cur)
+				if !present || (op.Key == "$min" && c < 0) || (op.Key == "$max" && c > 0) {
+					doc = setPath(doc, f.Key, clone(f.Value))
+				}
+			case "$currentDate":
+				doc = setPath(doc, f.Key, bson.NewDateTimeFromTime(time.Now()))
+			case "$push", "$addToSet":
+				cur, _ := lookup(doc, f.Key)
+				arr := asArray(clone(cur))
+				items := bson.A{f.Value}
+				if d, ok := f.Value.(bson.D); ok {
+					if each, ok := get(d, "$each"); ok {
+						items = asArray(each)
+					}
+				}
+				for _, item := range items {
+					if op.Key == "$addToSet" && equalsOrContains(arr, true, item) {
+						continue
+					}
+					arr = append(arr, clone(item))
+				}
+				doc = setPath(doc, f.Key, arr)
+			default:
+				err = errorf(codeBadValue, "unknown update operator: %s", op.Key)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return doc, nil
+}
+
+func addNumbers(a, b any) (any, error) {
+	if a == nil {
+		return b, nil
+	}
+	if _, ok := toFloat(b); !ok {
+		return nil, errorf(codeBadValue, "cannot increment with non-numeric argument")
+	}
+	_, af := a.(float64)
+	_, bf := b.(float64)
+	if af || bf {
+		x, _ := toFloat(a)
+		y, _ := toFloat(b)
+		return x + y, nil
+	}
+	x, ok := toInt(a)
+	if !ok {
+		return nil, errorf(codeBadValue, "cannot apply $inc to a value of non-numeric type")
+	}
+	y, _ := toInt(b)
+	sum := x + y
+	_, a32 := a.(int32)
+	_, b32 := b.(int32)
+	if a32 && b32 && sum >= math.MinInt32 && sum <= math.MaxInt32 {
+		return int32(sum), nil
+	}
+	return sum, nil
+}
+
+// setPath sets a dotted path, creating intermediate documents as needed.
+func setPath(doc bson.D, path string, v any) bson.D {
+	head, rest, nested := strings.Cut(path, ".")
+	for i := range doc {
+		if doc[i].Key != head {
+			continue
+		}
+		if !nested {
+			doc[i].Value = v
+			return doc
+		}
+		sub, _ := doc[i].Value.(bson.D)
+		doc[i].Value = setPath(sub, rest, v)
+		return doc
+	}
+	if !nested {
+		return append(doc, bson.E{Key: head, Value: v})
+	}
+	return append(doc, bson.E{Key: head, Value: setPath(bson.D{}, rest, v)})
+}
+
+func unsetPath(doc bson.D, path string) bson.D {
+	head, rest, nested := strings.Cut(path, ".")
+	for i := range doc {
+		if doc[i].Key != head {
+			continue
+		}
+		if !nested {
+			return append(doc[:i:i], doc[i+1:]...)
+		}
+		if sub, ok := doc[i].Value.(bson.D); ok {
+			doc[i].Value = unsetPath(sub, rest)
+		}
+		return doc
+	}
+	return doc
+}
@@ -0,0 +1,164 @@
+// Package watch pushes workflow, source and sink changes and worker commands
+// from the platform to its workers, so they no longer have to poll storage to
+// notice a deploy.
+package watch
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/hermod/internal/storage"
+)
+
+// Event kinds.
+const (
+	KindWorkflow = "workflow"
+	KindSource   = "source"
+	KindSink     = "sink"
+	// KindDrain asks the Target worker to shut down gracefully.
+	KindDrain = "drain"
+	// KindRebuild asks the Target worker to rebuild workflow ID from
+	// FromOffset.
+	KindRebuild = "rebuild"
+	// KindConnected is the first event of a subscription that resumes where
+	// the subscriber left off; the events it missed follow.
+	KindConnected = "connected"
+	// KindResync is the first event of a subscription that cannot be resumed,
+	// because the subscriber is new, its version is unknown to this feed or
+	// the events it missed have been evicted. It must list everything again.
+	KindResync = "resync"
+)
+
+// Actions of workflow, source and sink events.
+const (
+	ActionUpsert = "upsert"
+	ActionDelete = "delete"
+)
+
+// Event is one change pushed to workers. Version is opaque to subscribers;
+// handing the last one seen back to Subscribe resumes the feed after it.
+type Event struct {
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	Action  string `json:"action,omitempty"`
+	ID      string `json:"id,omitempty"`
+	// Target limits a command to one worker. Changes have no target and go to
+	// every worker.
+	Target     string            `json:"target,omitempty"`
+	FromOffset int64             `json:"from_offset,omitempty"`
+	Workflow   *storage.Workflow `json:"workflow,omitempty"`
+	Source     *storage.Source   `json:"source,omitempty"`
+	Sink       *storage.Sink     `json:"sink,omitempty"`
+}
+
+// DefaultBacklog is how many recent events a feed keeps for subscribers that
+// reconnect.
+const DefaultBacklog = 1024
+
+// subscriberBuffer is how far a subscriber may fall behind before the feed
+// drops it. A dropped subscriber reconnects and resumes from the backlog.
+const subscriberBuffer = 256
+
+// Feed numbers events in publish order and fans them out to subscribers. It
+// keeps a backlog of recent events so a subscriber whose connection dropped
+// can resume without missing any.
+type Feed struct {
+	mu      sync.Mutex
+	epoch   string
+	seq     uint64
+	backlog []Event
+	size    int
+	subs    map[chan Event]struct{}
+}
+
+// NewFeed creates a feed that keeps the last backlog events for resuming
+// subscribers. Versions are prefixed with the feed's start time, so a version
+// handed out before a restart is recognised as unknown.
+func NewFeed(backlog int) *Feed {
+	if backlog <= 0 {
+		backlog = DefaultBacklog
+	}
+	return &Feed{
+		epoch: strconv.FormatInt(time.Now().UnixNano(), 36),
+		size:  backlog,
+		subs:  make(map[chan Event]struct{}),
+	}
+}
+
+func (f *Feed) version(seq uint64) string {
+	return f.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// Publish stamps ev with the next version and hands it to every subscriber.
+// A subscriber too far behind to take it is dropped instead of blocking the
+// publisher; its channel is closed and it has to resubscribe.
+func (f *Feed) Publish(ev Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	ev.Version = f.version(f.seq)
+	f.backlog = append(f.backlog, ev)
+	if len(f.backlog) > f.size {
+		f.backlog = f.backlog[len(f.backlog)-f.size:]
+	}
+	for ch := range f.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe streams events published after since. The first event is
+// KindConnected when the feed can replay everything after since, followed by
+// that replay; otherwise it is KindResync carrying the current version. The
+// channel is closed when the subscriber falls too far behind or cancel is
+// called.
+func (f *Feed) Subscribe(since string) (<-chan Event, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	replay, ok := f.replay(since)
+	ch := make(chan Event, len(replay)+subscriberBuffer)
+	if ok {
+		ch <- Event{Kind: KindConnected, Version: since}
+		for _, ev := range replay {
+			ch <- ev
+		}
+	} else {
+		ch <- Event{Kind: KindResync, Version: f.version(f.seq)}
+	}
+	f.subs[ch] = struct{}{}
+
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[ch]; ok {
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// replay returns the backlog after since, or false when since is not a
+// version of this feed or events after it have been evicted.
+func (f *Feed) replay(since string) ([]Event, bool) {
+	epoch, num, found := strings.Cut(since, "-")
+	if !found || epoch != f.epoch {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(num, 10, 64)
+	if err != nil || seq > f.seq {
+		return nil, false
+	}
+	missed := int(f.seq - seq)
+	if missed > len(f.backlog) {
+		return nil, false
+	}
+	return append([]Event(nil), f.backlog[len(f.backlog)-missed:]...), true
+}
@@ -0,0 +1,95 @@
+package watch
+
+import (
+	"testing"
+
+	"github.com/user/hermod/internal/storage"
+)
+
+func next(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return ev
+	default:
+		t.Fatal("no event")
+		return Event{}
+	}
+}
+
+func TestFeedNewSubscriberResyncs(t *testing.T) {
+	f := NewFeed(8)
+	f.Publish(Event{Kind: KindWorkflow, ID: "wf-1"})
+
+	ch, cancel := f.Subscribe("")
+	defer cancel()
+
+	ev := next(t, ch)
+	if ev.Kind != KindResync || ev.Version != f.version(1) {
+		t.Fatalf("expected resync at head, got %+v", ev)
+	}
+	f.Publish(Event{Kind: KindWorkflow, ID: "wf-2"})
+	if ev := next(t, ch); ev.ID != "wf-2" || ev.Version != f.version(2) {
+		t.Fatalf("expected wf-2 at version 2, got %+v", ev)
+	}
+}
+
+func TestFeedResumesAfterVersion(t *testing.T) {
+	f := NewFeed(8)
+	for _, id := range []string{"a", "b", "c"} {
+		f.Publish(Event{Kind: KindSource, ID: id})
+	}
+
+	ch, cancel := f.Subscribe(f.version(1))
+	defer cancel()
+
+	if ev := next(t, ch); ev.Kind != KindConnected {
+		t.Fatalf("expected connected, got %+v", ev)
+	}
+	for _, want := range []string{"b", "c"} {
+		if ev := next(t, ch); ev.ID != want {
+			t.Fatalf("expected %s, got %+v", want, ev)
+		}
+	}
+}
+
+func TestFeedResyncsWhenVersionCannotBeResumed(t *testing.T) {
+	f := NewFeed(2)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		f.Publish(Event{Kind: KindSink, ID: id})
+	}
+
+	for name, since := range map[string]string{
+		"evicted":     f.version(1),
+		"other epoch": "0-3",
+		"future":      f.version(9),
+		"garbage":     "nope",
+	} {
+		ch, cancel := f.Subscribe(since)
+		if ev := next(t, ch); ev.Kind != KindResync {
+			t.Errorf("%s: expected resync, got %+v", name, ev)
+		}
+		cancel()
+	}
+}
+
+func TestFeedDropsSlowSubscriber(t *testing.T) {
+	f := NewFeed(8)
+	ch, cancel := f.Subscribe("")
+	defer cancel()
+
+	for range subscriberBuffer + 1 {
+		f.Publish(Event{Kind: KindWorkflow, Workflow: &storage.Workflow{}})
+	}
+
+	n := 0
+	for range ch {
+		n++
+	}
+	if n == 0 || n > subscriberBuffer {
+		t.Fatalf("expected the channel to close after at most %d events, got %d", subscriberBuffer, n)
+	}
+}
@@ -0,0 +1,178 @@
+package watch
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/user/hermod/internal/storage"
+)
+
+// ErrFellBehind ends a stream whose subscriber did not keep up with the feed.
+// Resubscribing with the last version seen resumes it.
+var ErrFellBehind = errors.New("watch: subscriber fell behind")
+
+// For reports whether ev is meant for the worker with the given ID.
+func (ev Event) For(workerID string) bool {
+	return ev.Target == "" || ev.Target == workerID
+}
+
+// Stream subscribes from since and calls fn with every event meant for
+// workerID until ctx is done, fn fails or the feed drops the subscriber.
+func (f *Feed) Stream(ctx context.Context, workerID, since string, fn func(Event) error) error {
+	ch, cancel := f.Subscribe(since)
+	defer cancel()
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return ErrFellBehind
+			}
+			if !ev.For(workerID) {
+				continue
+			}
+			if err := fn(ev); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Storage publishes every workflow, source and sink written through it to a
+// feed. Only writes made through this process are seen; a change made by
+// another API replica reaches workers connected here on their next resync.
+type Storage struct {
+	storage.Storage
+	feed *Feed
+}
+
+// NewStorage wraps s so its workflow, source and sink writes are published to
+// feed.
+func NewStorage(s storage.Storage, feed *Feed) *Storage {
+	return &Storage{Storage: s, feed: feed}
+}
+
+// Feed returns the feed writes are published to.
+func (s *Storage) Feed() *Feed {
+	return s.feed
+}
+
+// Watch streams the feed to an in-process worker.
+func (s *Storage) Watch(ctx context.Context, workerID, since string, fn func(Event) error) error {
+	return s.feed.Stream(ctx, workerID, since, fn)
+}
+
+// Close closes the wrapped storage when it can be closed.
+func (s *Storage) Close() error {
+	if c, ok := s.Storage.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (s *Storage) CreateWorkflow(ctx context.Context, wf storage.Workflow) error {
+	// The ID is needed to read the stored workflow back.
+	if wf.ID == "" {
+		wf.ID = uuid.New().String()
+	}
+	if err := s.Storage.CreateWorkflow(ctx, wf); err != nil {
+		return err
+	}
+	s.publishWorkflow(ctx, wf)
+	return nil
+}
+
+func (s *Storage) UpdateWorkflow(ctx context.Context, wf storage.Workflow) error {
+	if err := s.Storage.UpdateWorkflow(ctx, wf); err != nil {
+		return err
+	}
+	s.publishWorkflow(ctx, wf)
+	return nil
+}
+
+func (s *Storage) DeleteWorkflow(ctx context.Context, id string) error {
+	if err := s.Storage.DeleteWorkflow(ctx, id); err != nil {
+		return err
+	}
+	s.feed.Publish(Event{Kind: KindWorkflow, Action: ActionDelete, ID: id})
+	return nil
+}
+
+// publishWorkflow publishes the workflow as stored, which carries the lease
+// fields the caller's copy may lack.
+func (s *Storage) publishWorkflow(ctx context.Context, wf storage.Workflow) {
+	if stored, err := s.Storage.GetWorkflow(ctx, wf.ID); err == nil {
+		wf = stored
+	}
+	s.feed.Publish(Event{Kind: KindWorkflow, Action: ActionUpsert, ID: wf.ID, Workflow: &wf})
+}
+
+func (s *Storage) CreateSource(ctx context.Context, src storage.Source) error {
+	if src.ID == "" {
+		src.ID = uuid.New().String()
+	}
+	if err := s.Storage.CreateSource(ctx, src); err != nil {
+		return err
+	}
+	s.publishSource(ctx, src)
+	return nil
+}
+
+func (s *Storage) UpdateSource(ctx context.Context, src storage.Source) error {
+	if err := s.Storage.UpdateSource(ctx, src); err != nil {
+		return err
+	}
+	s.publishSource(ctx, src)
+	return nil
+}
+
+func (s *Storage) DeleteSource(ctx context.Context, id string) error {
+	if err := s.Storage.DeleteSource(ctx, id); err != nil {
+		return err
+	}
+	s.feed.Publish(Event{Kind: KindSource, Action: ActionDelete, ID: id})
+	return nil
+}
+
+func (s *Storage) publishSource(ctx context.Context, src storage.Source) {
+	if stored, err := s.Storage.GetSource(ctx, src.ID); err == nil {
+		src = stored
+	}
+	s.feed.Publish(Event{Kind: KindSource, Action: ActionUpsert, ID: src.ID, Source: &src})
+}
+
+func (s *Storage) CreateSink(ctx context.Context, snk storage.Sink) error {
+	if snk.ID == "" {
+		snk.ID = uuid.New().String()
+	}
+	if err := s.Storage.CreateSink(ctx, snk); err != nil {
+		return err
+	}
+	s.publishSink(ctx, snk)
+	return nil
+}
+
+func (s *Storage) UpdateSink(ctx context.Context, snk storage.Sink) error {
+	if err := s.Storage.UpdateSink(ctx, snk); err != nil {
+		return err
+	}
+	s.publishSink(ctx, snk)
+	return nil
+}
+
+func (s *Storage) DeleteSink(ctx context.Context, id string) error {
+	if err := s.Storage.DeleteSink(ctx, id); err != nil {
+		return err
+	}
+	s.feed.Publish(Event{Kind: KindSink, Action: ActionDelete, ID: id})
+	return nil
+}
+
+func (s *Storage) publishSink(ctx context.Context, snk storage.Sink) {
+	if stored, err := s.Storage.GetSink(ctx, snk.ID); err == nil {
+		snk = stored
+	}
+	s.feed.Publish(Event{Kind: KindSink, Action: ActionUpsert, ID: snk.ID, Sink: &snk})
+}
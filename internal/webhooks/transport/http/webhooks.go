@@ -103,7 +103,7 @@ func collectHeaders(r *http.Request) map[string]string {
 	return headers
 }
 
-func (h *WebhookHandler) RegisterWebhookRoutes(mux *http.ServeMux) {
+func (h *WebhookHandler) RegisterWebhookRoutes(mux handlers.Router) {
 	mux.HandleFunc("POST /api/webhooks/{path...}", h.HandleWebhook)
 	mux.HandleFunc("GET /api/webhooks/{path...}", h.HandleWebhook)
 }
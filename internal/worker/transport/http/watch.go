@@ -0,0 +1,78 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/user/hermod/internal/watch"
+)
+
+// watchPingInterval keeps idle watch streams alive through proxies.
+const watchPingInterval = 30 * time.Second
+
+// WatchWorker streams workflow, source and sink changes and the worker's
+// commands as server-sent events. Each event's id is its version; a worker
+// that reconnects with it in Last-Event-ID (or ?since=) resumes after it.
+// The first event is "connected" when the stream resumes and "resync" when
+// the worker has to list everything again.
+func (h *WorkerHandler) WatchWorker(w http.ResponseWriter, r *http.Request) {
+	if h.Watch == nil {
+		h.JsonError(w, "watch is not enabled", http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.JsonError(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	id := r.PathValue("id")
+	since := r.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = r.URL.Query().Get("since")
+	}
+
+	ch, cancel := h.Watch.Subscribe(since)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ping := time.NewTicker(watchPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				// Fell behind; the worker reconnects and resumes.
+				return
+			}
+			if !ev.For(id) {
+				continue
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", ev.Version, ev.Kind, data)
+			flusher.Flush()
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// publishDrain pushes a graceful-shutdown request to a watching worker.
+func (h *WorkerHandler) publishDrain(id string) {
+	if h.Watch != nil {
+		h.Watch.Publish(watch.Event{Kind: watch.KindDrain, Target: id})
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/engine/worker"
+	"github.com/user/hermod/internal/watch"
+)
+
+var errStop = errors.New("stop")
+
+// collect reads events from the watch endpoint until n have arrived.
+func collect(t *testing.T, c *worker.WorkerAPIClient, workerID, since string, n int) []watch.Event {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	var got []watch.Event
+	err := c.Watch(ctx, workerID, since, func(ev watch.Event) error {
+		got = append(got, ev)
+		if len(got) == n {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("watch ended early with %v after %d events", err, len(got))
+	}
+	return got
+}
+
+func TestWatchWorkerStreamsAndResumes(t *testing.T) {
+	feed := watch.NewFeed(16)
+	h := &WorkerHandler{Handler: &handlers.Handler{Watch: feed}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/workers/{id}/watch", h.WatchWorker)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := worker.NewWorkerAPIClient(srv.URL, "token")
+
+	feed.Publish(watch.Event{Kind: watch.KindWorkflow, Action: watch.ActionUpsert, ID: "wf-1"})
+
+	got := collect(t, client, "w-1", "", 1)
+	if got[0].Kind != watch.KindResync {
+		t.Fatalf("expected a new watcher to resync, got %+v", got[0])
+	}
+	since := got[0].Version
+
+	// Published while the worker was disconnected.
+	feed.Publish(watch.Event{Kind: watch.KindWorkflow, Action: watch.ActionUpsert, ID: "wf-2"})
+	feed.Publish(watch.Event{Kind: watch.KindDrain, Target: "w-2"})
+	feed.Publish(watch.Event{Kind: watch.KindSink, Action: watch.ActionDelete, ID: "snk-1"})
+
+	// Resuming replays what was missed, minus the drain meant for w-2.
+	got = collect(t, client, "w-1", since, 3)
+	if got[0].Kind != watch.KindConnected || got[1].ID != "wf-2" || got[2].ID != "snk-1" {
+		t.Fatalf("expected connected, wf-2, snk-1; got %+v", got)
+	}
+}
+
+func TestWatchWorkerUnsupported(t *testing.T) {
+	h := &WorkerHandler{Handler: &handlers.Handler{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/workers/{id}/watch", h.WatchWorker)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	err := worker.NewWorkerAPIClient(srv.URL, "").Watch(t.Context(), "w-1", "", func(watch.Event) error { return nil })
+	if !errors.Is(err, worker.ErrWatchUnsupported) {
+		t.Fatalf("expected ErrWatchUnsupported, got %v", err)
+	}
+}
@@ -9,10 +9,11 @@ import (
 	"strconv"
 
 	"github.com/google/uuid"
+	"github.com/user/hermod/internal/api/handlers"
 	"github.com/user/hermod/internal/storage"
 )
 
-func (h *WorkerHandler) RegisterWorkerRoutes(mux *http.ServeMux) {
+func (h *WorkerHandler) RegisterWorkerRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/workers", h.ListWorkers)
 	mux.HandleFunc("GET /api/workers/recommend", h.RecommendWorker)
 	mux.HandleFunc("GET /api/workers/{id}", h.GetWorker)
@@ -21,6 +22,7 @@ func (h *WorkerHandler) RegisterWorkerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/workers/{id}/heartbeat", h.UpdateWorkerHeartbeat)
 	mux.HandleFunc("POST /api/workers/{id}/start", h.StartWorker)
 	mux.HandleFunc("POST /api/workers/{id}/shutdown", h.ShutdownWorker)
+	mux.HandleFunc("GET /api/workers/{id}/watch", h.WatchWorker)
 	mux.HandleFunc("DELETE /api/workers/{id}", h.DeleteWorker)
 }
 
@@ -196,6 +198,8 @@ func (h *WorkerHandler) ShutdownWorker(w http.ResponseWriter, r *http.Request) {
 	// Flag the worker as draining so it gracefully shuts down when it next polls
 	// its own record over the API.
 	h.MarkWorkerDraining(id)
+	// A worker watching for changes is told right away.
+	h.publishDrain(id)
 	// Best-effort fast path for an in-process (standalone) worker, which talks to
 	// storage directly and would not otherwise see the API-surfaced flag.
 	if h.Worker != nil {
@@ -14,8 +14,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/user/hermod"
 	"github.com/user/hermod/internal/api/handlers"
+	"github.com/user/hermod/internal/engine/registry/nodes/control"
 	"github.com/user/hermod/internal/governance"
 	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/internal/watch"
 	"github.com/user/hermod/pkg/comm/message"
 
 	"github.com/user/hermod/pkg/engine/telemetry"
@@ -33,7 +35,7 @@ func (h *WorkflowHandler) validateWorkflow(wf storage.Workflow) error {
 	return nil
 }
 
-func (h *WorkflowHandler) RegisterWorkflowRoutes(mux *http.ServeMux) {
+func (h *WorkflowHandler) RegisterWorkflowRoutes(mux handlers.Router) {
 	// Register more specific routes first to avoid potential shadowing.
 	mux.Handle("GET /api/workflows/{export_id}/export", h.EditorOnly(http.HandlerFunc(h.ExportWorkflow)))
 	mux.Handle("POST /api/workflows/import", h.EditorOnly(http.HandlerFunc(h.ImportWorkflow)))
@@ -42,8 +44,12 @@ func (h *WorkflowHandler) RegisterWorkflowRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/workflows/{id}", h.GetWorkflow)
 	mux.HandleFunc("PATCH /api/workflows/{id}/status", h.UpdateWorkflowStatus)
 	mux.HandleFunc("PATCH /api/workflows/{id}/stats", h.UpdateWorkflowStats)
+	mux.HandleFunc("POST /api/workflows/{id}/lease", h.AcquireWorkflowLease)
+	mux.HandleFunc("PUT /api/workflows/{id}/lease", h.RenewWorkflowLease)
+	mux.HandleFunc("DELETE /api/workflows/{id}/lease", h.ReleaseWorkflowLease)
 	mux.HandleFunc("GET /api/workflows/{id}/report", h.GetWorkflowComplianceReport)
 	mux.HandleFunc("GET /api/workflows/{id}/health", h.GetWorkflowHealth)
+	mux.HandleFunc("GET /api/workflows/{id}/nodes/{node_id}/state", h.GetNodeState)
 	mux.Handle("POST /api/workflows", h.EditorOnly(http.HandlerFunc(h.CreateWorkflow)))
 	mux.Handle("PUT /api/workflows/{id}", h.EditorOnly(http.HandlerFunc(h.UpdateWorkflow)))
 	mux.Handle("DELETE /api/workflows/{id}", h.EditorOnly(http.HandlerFunc(h.DeleteWorkflow)))
@@ -586,6 +592,41 @@ func (h *WorkflowHandler) GetWorkflowHealth(w http.ResponseWriter, r *http.Reque
 	_ = json.NewEncoder(w).Encode(health)
 }
 
+// GetNodeState returns the per-group values of a stateful node. The limit
+// query parameter caps the number of groups, 100 by default.
+func (h *WorkflowHandler) GetNodeState(w http.ResponseWriter, r *http.Request) {
+	id, nodeID := r.PathValue("id"), r.PathValue("node_id")
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			h.JsonError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	entries, err := control.InspectState(r.Context(), h.Registry.StateStore(), id, nodeID, limit)
+	if errors.Is(err, control.ErrNoStateStore) {
+		h.JsonError(w, "node state is only kept in memory; configure a state store to inspect it", http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		h.JsonError(w, "Failed to read node state: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []control.StateEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"workflow_id": id,
+		"node_id":     nodeID,
+		"groups":      entries,
+	})
+}
+
 func (h *WorkflowHandler) UpdateWorkflowStats(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -611,6 +652,69 @@ func (h *WorkflowHandler) UpdateWorkflowStats(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusOK)
 }
 
+// AcquireWorkflowLease takes or re-takes a workflow lease for a remote
+// worker. The response carries the lease's fencing token, which the worker
+// stamps into its writes and checkpoints.
+func (h *WorkflowHandler) AcquireWorkflowLease(w http.ResponseWriter, r *http.Request) {
+	h.handleWorkflowLease(w, r, func(ctx context.Context, id string, req storage.WorkflowLease) (bool, error) {
+		return h.Storage.AcquireWorkflowLease(ctx, id, req.OwnerID, req.TTLSeconds)
+	})
+}
+
+// RenewWorkflowLease extends a lease the worker still holds.
+func (h *WorkflowHandler) RenewWorkflowLease(w http.ResponseWriter, r *http.Request) {
+	h.handleWorkflowLease(w, r, func(ctx context.Context, id string, req storage.WorkflowLease) (bool, error) {
+		return h.Storage.RenewWorkflowLease(ctx, id, req.OwnerID, req.TTLSeconds)
+	})
+}
+
+// ReleaseWorkflowLease gives up a lease so another worker can take it over
+// without waiting for it to expire.
+func (h *WorkflowHandler) ReleaseWorkflowLease(w http.ResponseWriter, r *http.Request) {
+	h.handleWorkflowLease(w, r, func(ctx context.Context, id string, req storage.WorkflowLease) (bool, error) {
+		return false, h.Storage.ReleaseWorkflowLease(ctx, id, req.OwnerID)
+	})
+}
+
+func (h *WorkflowHandler) handleWorkflowLease(w http.ResponseWriter, r *http.Request, op func(context.Context, string, storage.WorkflowLease) (bool, error)) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.JsonError(w, "missing workflow id", http.StatusBadRequest)
+		return
+	}
+	var req storage.WorkflowLease
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.JsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.OwnerID == "" {
+		h.JsonError(w, "missing owner_id", http.StatusBadRequest)
+		return
+	}
+
+	held, err := op(r.Context(), id, req)
+	if err != nil {
+		h.JsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res := storage.WorkflowLease{OwnerID: req.OwnerID}
+	if held {
+		// The lease may have changed hands since op returned; only report a
+		// token the caller still owns.
+		wf, err := h.Storage.GetWorkflow(r.Context(), id)
+		if err != nil {
+			h.JsonError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if wf.OwnerID == req.OwnerID {
+			res.Held, res.Token, res.LeaseUntil = true, wf.LeaseToken, wf.LeaseUntil
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
 func (h *WorkflowHandler) ListWorkflows(w http.ResponseWriter, r *http.Request) {
 	filter := h.ParseCommonFilter(r)
 	filter.WorkspaceID = r.URL.Query().Get("workspace_id")
@@ -904,13 +1008,18 @@ func (h *WorkflowHandler) RebuildWorkflow(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
-		defer cancel()
-		if err := h.Registry.RebuildWorkflow(ctx, id, req.FromOffset); err != nil {
-			h.Registry.GetLogger().Error("RebuildWorkflow failed", "workflow_id", id, "error", err)
-		}
-	}()
+	// A workflow running on a watching remote worker is rebuilt there.
+	if wf, err := h.Storage.GetWorkflow(r.Context(), id); err == nil && h.Watch != nil && wf.OwnerID != "" && !h.Registry.IsEngineRunning(id) {
+		h.Watch.Publish(watch.Event{Kind: watch.KindRebuild, ID: id, Target: wf.OwnerID, FromOffset: req.FromOffset})
+	} else {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
+			defer cancel()
+			if err := h.Registry.RebuildWorkflow(ctx, id, req.FromOffset); err != nil {
+				h.Registry.GetLogger().Error("RebuildWorkflow failed", "workflow_id", id, "error", err)
+			}
+		}()
+	}
 
 	h.RecordAuditLog(r, "INFO", "Started projection rebuilding for workflow "+id, "rebuild", id, "", "", nil)
 
@@ -1,11 +1,14 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/user/hermod/internal/engine/registry"
+	"github.com/user/hermod/internal/engine/registry/nodes/control"
 	"github.com/user/hermod/internal/storage"
 )
 
@@ -91,6 +94,18 @@ func (h *WorkflowHandler) ValidateWorkflow(wf storage.Workflow) []ValidationIssu
 					NodeID:         n.ID,
 				})
 			}
+			if n.RefID != "" && h.Storage != nil {
+				if snk, err := h.Storage.GetSink(context.Background(), n.RefID); err == nil {
+					if err := registry.ValidateSchemaChangePolicy(snk.Type, snk.Config); err != nil {
+						issues = append(issues, ValidationIssue{
+							Severity:       "error",
+							Message:        fmt.Sprintf("Sink node '%s': %v.", n.ID, err),
+							Recommendation: "Only the PostgreSQL, MySQL and SQL Server sinks can alter their tables. Set the sink's schema change policy to notify or pause, or route the data to one of those sinks.",
+							NodeID:         n.ID,
+						})
+					}
+				}
+			}
 		case "foreach", "fanout":
 			ap, _ := n.Config["arrayPath"].(string)
 			if strings.TrimSpace(ap) == "" {
@@ -101,6 +116,16 @@ func (h *WorkflowHandler) ValidateWorkflow(wf storage.Workflow) []ValidationIssu
 					NodeID:         n.ID,
 				})
 			}
+		case "stateful":
+			op, _ := n.Config["operation"].(string)
+			if strings.TrimSpace(op) == "" {
+				issues = append(issues, ValidationIssue{
+					Severity:       "error",
+					Message:        fmt.Sprintf("Stateful node '%s' is missing the 'operation' configuration.", n.ID),
+					Recommendation: "Choose the running value the node keeps: count, sum, min, max, last or distinct.",
+					NodeID:         n.ID,
+				})
+			}
 		case "filter":
 			condition, _ := n.Config["condition"].(string)
 			if strings.TrimSpace(condition) == "" {
@@ -112,6 +137,15 @@ func (h *WorkflowHandler) ValidateWorkflow(wf storage.Workflow) []ValidationIssu
 				})
 			}
 		}
+
+		if err := control.CheckExpressions(&n); err != nil {
+			issues = append(issues, ValidationIssue{
+				Severity:       "error",
+				Message:        fmt.Sprintf("Node '%s' has an invalid expression: %v", n.ID, err),
+				Recommendation: "Fix the expression in the node configuration. Expressions use operators such as 'amount > 100 && status == \"paid\"', and text constants must be quoted.",
+				NodeID:         n.ID,
+			})
+		}
 	}
 
 	if !hasSource {
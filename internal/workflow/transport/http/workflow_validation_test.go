@@ -52,6 +52,40 @@ func TestValidateWorkflow(t *testing.T) {
 			expectedIssues: 0,
 			expectError:    false,
 		},
+		{
+			name: "Invalid expression",
+			wf: storage.Workflow{
+				Name: "Test",
+				Nodes: []storage.WorkflowNode{
+					{ID: "src1", Type: "source", RefID: "src-config-id"},
+					{ID: "cond1", Type: "condition", Config: map[string]any{"expression": "amount >"}},
+					{ID: "snk1", Type: "sink", RefID: "snk-config-id"},
+				},
+				Edges: []storage.WorkflowEdge{
+					{ID: "e1", SourceID: "src1", TargetID: "cond1"},
+					{ID: "e2", SourceID: "cond1", TargetID: "snk1"},
+				},
+			},
+			expectedIssues: 1,
+			expectError:    true,
+		},
+		{
+			name: "Stateful node without operation",
+			wf: storage.Workflow{
+				Name: "Test",
+				Nodes: []storage.WorkflowNode{
+					{ID: "src1", Type: "source", RefID: "src-config-id"},
+					{ID: "count1", Type: "stateful", Config: map[string]any{"outputField": "n"}},
+					{ID: "snk1", Type: "sink", RefID: "snk-config-id"},
+				},
+				Edges: []storage.WorkflowEdge{
+					{ID: "e1", SourceID: "src1", TargetID: "count1"},
+					{ID: "e2", SourceID: "count1", TargetID: "snk1"},
+				},
+			},
+			expectedIssues: 1,
+			expectError:    true,
+		},
 		{
 			name: "Dangling node",
 			wf: storage.Workflow{
@@ -93,7 +127,15 @@ func TestValidateWorkflow(t *testing.T) {
 
 type mockStorageForValidation struct {
 	storage.Storage
-	wf *storage.Workflow
+	wf    *storage.Workflow
+	sinks map[string]storage.Sink
+}
+
+func (m *mockStorageForValidation) GetSink(ctx context.Context, id string) (storage.Sink, error) {
+	if snk, ok := m.sinks[id]; ok {
+		return snk, nil
+	}
+	return storage.Sink{}, storage.ErrNotFound
 }
 
 func (m *mockStorageForValidation) GetWorkflow(ctx context.Context, id string) (storage.Workflow, error) {
@@ -139,3 +181,28 @@ func TestHandleValidateWorkflow(t *testing.T) {
 		t.Errorf("expected 0 issues, got %d", len(issues))
 	}
 }
+
+func TestValidateWorkflowSchemaChangePolicy(t *testing.T) {
+	mock := &mockStorageForValidation{sinks: map[string]storage.Sink{
+		"pg":    {ID: "pg", Type: "postgres", Config: map[string]string{"schema_change_policy": "auto"}},
+		"kafka": {ID: "kafka", Type: "kafka", Config: map[string]string{"schema_change_policy": "additive"}},
+	}}
+	h := &WorkflowHandler{Handler: &handlers.Handler{Storage: mock}}
+
+	wf := func(sinkID string) storage.Workflow {
+		return storage.Workflow{
+			Name: "Schema changes",
+			Nodes: []storage.WorkflowNode{
+				{ID: "src1", Type: "source", RefID: "src1"},
+				{ID: "snk1", Type: "sink", RefID: sinkID},
+			},
+			Edges: []storage.WorkflowEdge{{ID: "e1", SourceID: "src1", TargetID: "snk1"}},
+		}
+	}
+	if err := h.validateWorkflow(wf("pg")); err != nil {
+		t.Errorf("expected a postgres sink to accept the auto policy, got %v", err)
+	}
+	if err := h.validateWorkflow(wf("kafka")); err == nil {
+		t.Error("expected a kafka sink with the additive policy to be rejected")
+	}
+}
@@ -9,12 +9,13 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/user/hermod/internal/api/handlers"
 	"github.com/user/hermod/internal/engine/registry"
 	"github.com/user/hermod/internal/storage"
 	"github.com/user/hermod/pkg/engine/telemetry"
 )
 
-func (h *WSHandler) RegisterWSRoutes(mux *http.ServeMux) {
+func (h *WSHandler) RegisterWSRoutes(mux handlers.Router) {
 	mux.HandleFunc("GET /api/ws/in/{path...}", h.HandleWSIn)
 	mux.HandleFunc("GET /api/ws/out/{workflowID}", h.HandleWSOut)
 	mux.HandleFunc("GET /api/ws/live", h.HandleLiveMessagesWS)
@@ -370,23 +371,32 @@ func (h *WSHandler) HandleDebuggerWS(w http.ResponseWriter, r *http.Request) {
 	// Listen for commands from the UI. When the read loop exits (client
 	// disconnect, read error, or read timeout) we close done so the writer
 	// goroutine can stop instead of leaking until the next failed write.
+	// Rejected commands are answered on errs, as only the writer may write.
 	done := make(chan struct{})
+	errs := make(chan registry.DebuggerEvent, 10)
 	go func() {
 		defer close(done)
 		for {
-			var cmd struct {
-				Action string `json:"action"`
-				MsgID  string `json:"msg_id"`
-			}
+			var cmd registry.DebugCommand
 			if err := conn.ReadJSON(&cmd); err != nil {
 				return
 			}
 			// A valid command also proves the peer is alive, so extend the deadline.
 			_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
-			h.Registry.DebuggerCommand(workflowID, cmd.MsgID, cmd.Action)
+			if err := h.Registry.DebuggerCommand(workflowID, cmd); err != nil {
+				select {
+				case errs <- registry.DebuggerEvent{WorkflowID: workflowID, MsgID: cmd.MsgID, State: "error", Error: err.Error()}:
+				default:
+				}
+			}
 		}
 	}()
 
+	// Start the client with the breakpoints it may have set before reconnecting.
+	if err := wsWriteJSON(conn, registry.DebuggerEvent{WorkflowID: workflowID, State: "breakpoints", Breakpoints: h.Registry.Breakpoints(workflowID)}); err != nil {
+		return
+	}
+
 	// Heartbeat
 	ticker := time.NewTicker(wsHeartbeat)
 	defer ticker.Stop()
@@ -397,6 +407,10 @@ func (h *WSHandler) HandleDebuggerWS(w http.ResponseWriter, r *http.Request) {
 			if err := wsWriteJSON(conn, ev); err != nil {
 				return
 			}
+		case ev := <-errs:
+			if err := wsWriteJSON(conn, ev); err != nil {
+				return
+			}
 		case <-ticker.C:
 			if err := wsWritePing(conn); err != nil {
 				return
@@ -501,6 +501,18 @@ func AcquireMessage() *DefaultMessage {
 	return m
 }
 
+// AcquireSchemaChange gets a message from the pool and makes it a schema
+// change message carrying c.
+func AcquireSchemaChange(id string, c hermod.SchemaChange) *DefaultMessage {
+	m := AcquireMessage()
+	m.SetID(id)
+	m.SetOperation(hermod.OpSchemaChange)
+	m.SetSchema(c.Schema)
+	m.SetTable(c.Table)
+	hermod.SetSchemaChange(m, c)
+	return m
+}
+
 // ReleaseMessage returns a message to the pool.
 func ReleaseMessage(m hermod.Message) {
 	if dm, ok := m.(*DefaultMessage); ok {
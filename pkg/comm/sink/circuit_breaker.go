@@ -71,6 +71,19 @@ func (s *CircuitBreakerSink) WriteBatch(ctx context.Context, msgs []hermod.Messa
 	return err
 }
 
+// WriteBatchResults routes per-message batch writes through the circuit
+// breaker. The destination answered if the request as a whole succeeded, so
+// only a request failure counts against it.
+func (s *CircuitBreakerSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	if !s.allowRequest() {
+		return nil, ErrCircuitOpen
+	}
+
+	errs, err := writeBatchResults(ctx, s.Sink, msgs)
+	s.recordResult(err)
+	return errs, err
+}
+
 func (s *CircuitBreakerSink) allowRequest() bool {
 	s.mu.RLock()
 	st := s.state
@@ -92,17 +105,74 @@ func (s *CircuitBreakerSink) allowRequest() bool {
 	return true
 }
 
+// recordResult updates the breaker. A permanent error is the message's fault,
+// not the destination's, and proves the destination is reachable, so it
+// counts as a success.
 func (s *CircuitBreakerSink) recordResult(err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if err == nil {
+	if class, _ := hermod.ClassifyError(err, s); err == nil || class == hermod.ErrorPermanent {
 		s.handleSuccess()
 	} else {
 		s.handleFailure()
 	}
 }
 
+// SetFencingToken passes the lease's fencing token to the wrapped sink.
+func (s *CircuitBreakerSink) SetFencingToken(scope string, token int64) {
+	if fs, ok := s.Sink.(hermod.FencedSink); ok {
+		fs.SetFencingToken(scope, token)
+	}
+}
+
+// CommitCheckpoint passes the checkpoint on to the wrapped sink.
+func (s *CircuitBreakerSink) CommitCheckpoint(ctx context.Context) error {
+	if cc, ok := s.Sink.(hermod.CheckpointCommitter); ok {
+		return cc.CommitCheckpoint(ctx)
+	}
+	return nil
+}
+
+// SetSchemaSources passes the workflow's schema sources to the wrapped sink.
+func (s *CircuitBreakerSink) SetSchemaSources(columns hermod.ColumnDiscoverer, schemas hermod.SchemaLookup) {
+	if sa, ok := s.Sink.(hermod.SchemaAwareSink); ok {
+		sa.SetSchemaSources(columns, schemas)
+	}
+}
+
+// ApplySchemaChange passes a schema change on to the wrapped sink.
+func (s *CircuitBreakerSink) ApplySchemaChange(ctx context.Context, change hermod.SchemaChange) error {
+	if sc, ok := s.Sink.(hermod.SchemaChangeSink); ok {
+		return sc.ApplySchemaChange(ctx, change)
+	}
+	return nil
+}
+
+// SchemaState returns the schema state of the wrapped sink.
+func (s *CircuitBreakerSink) SchemaState() ([]byte, error) {
+	if ss, ok := s.Sink.(hermod.SchemaStateSink); ok {
+		return ss.SchemaState()
+	}
+	return nil, nil
+}
+
+// RestoreSchemaState passes a schema state on to the wrapped sink.
+func (s *CircuitBreakerSink) RestoreSchemaState(state []byte) error {
+	if ss, ok := s.Sink.(hermod.SchemaStateSink); ok {
+		return ss.RestoreSchemaState(state)
+	}
+	return nil
+}
+
+// ClassifyError defers to the wrapped sink when it can classify its errors.
+func (s *CircuitBreakerSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	if c, ok := s.Sink.(hermod.ErrorClassifier); ok {
+		return c.ClassifyError(err)
+	}
+	return hermod.ErrorTransient, 0
+}
+
 func (s *CircuitBreakerSink) handleSuccess() {
 	s.failureCount = 0
 	if s.state == stateHalfOpen {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/user/hermod"
@@ -73,6 +74,102 @@ func (s *TracingSink) WriteBatch(ctx context.Context, msgs []hermod.Message) err
 	return nil
 }
 
+// WriteBatchResults traces a batch write that reports per-message outcomes.
+func (s *TracingSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	ctx, span := tracer.Start(ctx, "sink.write_batch", trace.WithAttributes(
+		attribute.String("sink_id", s.sinkID),
+		attribute.Int("batch_size", len(msgs)),
+	))
+	defer span.End()
+
+	errs, err := writeBatchResults(ctx, s.Sink, msgs)
+	failed := 0
+	for _, e := range errs {
+		if e != nil {
+			failed++
+		}
+	}
+	span.SetAttributes(attribute.Int("failed", failed))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "OK")
+	}
+	return errs, err
+}
+
+// writeBatchResults writes msgs to s, with per-message outcomes when s
+// reports them and a single outcome for the whole batch otherwise.
+func writeBatchResults(ctx context.Context, s hermod.Sink, msgs []hermod.Message) ([]error, error) {
+	if rs, ok := s.(hermod.BatchResultSink); ok {
+		return rs.WriteBatchResults(ctx, msgs)
+	}
+	if bs, ok := s.(hermod.BatchSink); ok {
+		return nil, bs.WriteBatch(ctx, msgs)
+	}
+	for _, m := range msgs {
+		if err := s.Write(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// SetFencingToken passes the lease's fencing token to the wrapped sink.
+func (s *TracingSink) SetFencingToken(scope string, token int64) {
+	if fs, ok := s.Sink.(hermod.FencedSink); ok {
+		fs.SetFencingToken(scope, token)
+	}
+}
+
+// CommitCheckpoint passes the checkpoint on to the wrapped sink.
+func (s *TracingSink) CommitCheckpoint(ctx context.Context) error {
+	if cc, ok := s.Sink.(hermod.CheckpointCommitter); ok {
+		return cc.CommitCheckpoint(ctx)
+	}
+	return nil
+}
+
+// SetSchemaSources passes the workflow's schema sources to the wrapped sink.
+func (s *TracingSink) SetSchemaSources(columns hermod.ColumnDiscoverer, schemas hermod.SchemaLookup) {
+	if sa, ok := s.Sink.(hermod.SchemaAwareSink); ok {
+		sa.SetSchemaSources(columns, schemas)
+	}
+}
+
+// ApplySchemaChange passes a schema change on to the wrapped sink.
+func (s *TracingSink) ApplySchemaChange(ctx context.Context, change hermod.SchemaChange) error {
+	if sc, ok := s.Sink.(hermod.SchemaChangeSink); ok {
+		return sc.ApplySchemaChange(ctx, change)
+	}
+	return nil
+}
+
+// SchemaState returns the schema state of the wrapped sink.
+func (s *TracingSink) SchemaState() ([]byte, error) {
+	if ss, ok := s.Sink.(hermod.SchemaStateSink); ok {
+		return ss.SchemaState()
+	}
+	return nil, nil
+}
+
+// RestoreSchemaState passes a schema state on to the wrapped sink.
+func (s *TracingSink) RestoreSchemaState(state []byte) error {
+	if ss, ok := s.Sink.(hermod.SchemaStateSink); ok {
+		return ss.RestoreSchemaState(state)
+	}
+	return nil
+}
+
+// ClassifyError defers to the wrapped sink when it can classify its errors.
+func (s *TracingSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	if c, ok := s.Sink.(hermod.ErrorClassifier); ok {
+		return c.ClassifyError(err)
+	}
+	return hermod.ErrorTransient, 0
+}
+
 func (s *TracingSink) ExecuteSQL(ctx context.Context, query string) ([]map[string]any, error) {
 	if se, ok := s.Sink.(hermod.SQLExecutor); ok {
 		return se.ExecuteSQL(ctx, query)
@@ -119,44 +216,7 @@ func NewRetrySink(s hermod.Sink, maxRetries int, retryInterval time.Duration, lo
 }
 
 func (s *RetrySink) Write(ctx context.Context, msg hermod.Message) error {
-	var lastErr error
-	maxRetries := max(s.maxRetries, 1)
-
-	for i := range maxRetries {
-		err := s.Sink.Write(ctx, msg)
-		if err == nil {
-			return nil
-		}
-		lastErr = err
-		if s.logger != nil {
-			s.logger.Warn("Sink write error, retrying", "attempt", i+1, "error", err)
-		}
-
-		// Do not wait after the final attempt; we are about to return the error.
-		if i == maxRetries-1 {
-			break
-		}
-
-		if err := s.waitBeforeRetry(ctx, i); err != nil {
-			return err
-		}
-	}
-	return fmt.Errorf("sink write failed after %d retries: %w", maxRetries, lastErr)
-}
-
-// waitBeforeRetry sleeps for a jittered, linearly-increasing backoff interval
-// based on the attempt index, returning early if the context is cancelled.
-func (s *RetrySink) waitBeforeRetry(ctx context.Context, attempt int) error {
-	interval := time.Duration(attempt+1) * s.retryInterval
-	jitter := 0.8 + rand.Float64()*0.4
-	interval = time.Duration(float64(interval) * jitter)
-
-	select {
-	case <-time.After(interval):
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	}
+	return s.retry(ctx, "Sink write", func() error { return s.Sink.Write(ctx, msg) })
 }
 
 func (s *RetrySink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
@@ -169,30 +229,125 @@ func (s *RetrySink) WriteBatch(ctx context.Context, msgs []hermod.Message) error
 		}
 		return nil
 	}
+	return s.retry(ctx, "Sink batch write", func() error { return bs.WriteBatch(ctx, msgs) })
+}
 
-	var lastErr error
-	maxRetries := max(s.maxRetries, 1)
+// WriteBatchResults retries a batch write whose request as a whole failed.
+// Per-message failures are returned to the caller, which decides what to
+// retry.
+func (s *RetrySink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	var errs []error
+	err := s.retry(ctx, "Sink batch write", func() error {
+		var err error
+		errs, err = writeBatchResults(ctx, s.Sink, msgs)
+		return err
+	})
+	return errs, err
+}
 
-	for i := range maxRetries {
-		err := bs.WriteBatch(ctx, msgs)
+// retry runs write until it succeeds or its error says retrying here is
+// futile. Permanent and fatal-config errors are returned at once, and so are
+// throttled errors with the destination's hint: the engine waits them out
+// within its throttle timeout, which waiting here would defeat. The returned
+// error keeps its class so the engine can act on it too.
+func (s *RetrySink) retry(ctx context.Context, what string, write func() error) error {
+	maxRetries := max(s.maxRetries, 1)
+	for i := 0; ; {
+		err := write()
 		if err == nil {
 			return nil
 		}
-		lastErr = err
-		if s.logger != nil {
-			s.logger.Warn("Sink batch write error, retrying", "attempt", i+1, "error", err)
+		class, retryAfter := hermod.ClassifyError(err, s)
+		switch class {
+		case hermod.ErrorPermanent, hermod.ErrorFatalConfig:
+			return hermod.Classify(err, class)
+		case hermod.ErrorThrottled:
+			return hermod.Throttled(err, retryAfter)
 		}
 
+		if s.logger != nil {
+			s.logger.Warn(what+" error, retrying", "attempt", i+1, "error", err)
+		}
+		i++
 		// Do not wait after the final attempt; we are about to return the error.
-		if i == maxRetries-1 {
-			break
+		if i == maxRetries {
+			return fmt.Errorf("%s failed after %d retries: %w", strings.ToLower(what), maxRetries, err)
 		}
-
-		if err := s.waitBeforeRetry(ctx, i); err != nil {
+		if err := s.waitBeforeRetry(ctx, i-1); err != nil {
 			return err
 		}
 	}
-	return fmt.Errorf("sink batch write failed after %d retries: %w", maxRetries, lastErr)
+}
+
+// SetFencingToken passes the lease's fencing token to the wrapped sink.
+func (s *RetrySink) SetFencingToken(scope string, token int64) {
+	if fs, ok := s.Sink.(hermod.FencedSink); ok {
+		fs.SetFencingToken(scope, token)
+	}
+}
+
+// CommitCheckpoint passes the checkpoint on to the wrapped sink.
+func (s *RetrySink) CommitCheckpoint(ctx context.Context) error {
+	if cc, ok := s.Sink.(hermod.CheckpointCommitter); ok {
+		return cc.CommitCheckpoint(ctx)
+	}
+	return nil
+}
+
+// SetSchemaSources passes the workflow's schema sources to the wrapped sink.
+func (s *RetrySink) SetSchemaSources(columns hermod.ColumnDiscoverer, schemas hermod.SchemaLookup) {
+	if sa, ok := s.Sink.(hermod.SchemaAwareSink); ok {
+		sa.SetSchemaSources(columns, schemas)
+	}
+}
+
+// ApplySchemaChange passes a schema change on to the wrapped sink.
+func (s *RetrySink) ApplySchemaChange(ctx context.Context, change hermod.SchemaChange) error {
+	if sc, ok := s.Sink.(hermod.SchemaChangeSink); ok {
+		return sc.ApplySchemaChange(ctx, change)
+	}
+	return nil
+}
+
+// SchemaState returns the schema state of the wrapped sink.
+func (s *RetrySink) SchemaState() ([]byte, error) {
+	if ss, ok := s.Sink.(hermod.SchemaStateSink); ok {
+		return ss.SchemaState()
+	}
+	return nil, nil
+}
+
+// RestoreSchemaState passes a schema state on to the wrapped sink.
+func (s *RetrySink) RestoreSchemaState(state []byte) error {
+	if ss, ok := s.Sink.(hermod.SchemaStateSink); ok {
+		return ss.RestoreSchemaState(state)
+	}
+	return nil
+}
+
+// ClassifyError defers to the wrapped sink when it can classify its errors.
+func (s *RetrySink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	if c, ok := s.Sink.(hermod.ErrorClassifier); ok {
+		return c.ClassifyError(err)
+	}
+	return hermod.ErrorTransient, 0
+}
+
+// waitBeforeRetry sleeps for a jittered, linearly-increasing backoff interval
+// based on the attempt index, returning early if the context is cancelled.
+func (s *RetrySink) waitBeforeRetry(ctx context.Context, attempt int) error {
+	interval := time.Duration(attempt+1) * s.retryInterval
+	jitter := 0.8 + rand.Float64()*0.4
+	return s.wait(ctx, time.Duration(float64(interval)*jitter))
+}
+
+func (s *RetrySink) wait(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (s *RetrySink) ExecuteSQL(ctx context.Context, query string) ([]map[string]any, error) {
@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 // DiscordSink implements the hermod.Sink interface for Discord.
@@ -81,7 +82,7 @@ func (s *DiscordSink) sendWebhook(ctx context.Context, content string) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -107,7 +108,7 @@ func (s *DiscordSink) sendBotMessage(ctx context.Context, content string) error
 	req.Header.Set("Authorization", "Bot "+s.token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -142,7 +143,7 @@ func (s *DiscordSink) Ping(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := httpclient.RateLimitedClient.Do(req)
 		if err != nil {
 			return err
 		}
@@ -160,7 +161,7 @@ func (s *DiscordSink) Ping(ctx context.Context) error {
 			return err
 		}
 		req.Header.Set("Authorization", "Bot "+s.token)
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := httpclient.RateLimitedClient.Do(req)
 		if err != nil {
 			return err
 		}
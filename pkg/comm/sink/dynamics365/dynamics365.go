@@ -15,6 +15,7 @@ import (
 
 	"github.com/user/hermod"
 	"github.com/user/hermod/pkg/infra/evaluator"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 type Config struct {
@@ -40,7 +41,7 @@ func NewSink(config Config, logger hermod.Logger) *Sink {
 	return &Sink{
 		config: config,
 		logger: logger,
-		client: &http.Client{Timeout: 30 * time.Second},
+		client: httpclient.NewRateLimitedClient(30 * time.Second),
 	}
 }
 
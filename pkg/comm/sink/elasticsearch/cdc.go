@@ -0,0 +1,350 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/evaluator"
+)
+
+// Action is how the sink writes documents.
+type Action string
+
+const (
+	// ActionIndex replaces the whole document.
+	ActionIndex Action = "index"
+	// ActionCreate only adds documents, as data streams require.
+	ActionCreate Action = "create"
+	// ActionUpdate merges the message into the document, creating it when
+	// it does not exist.
+	ActionUpdate Action = "update"
+	// ActionScript runs a painless script against the document, creating it
+	// from an empty one when it does not exist.
+	ActionScript Action = "script"
+)
+
+// updateConflictRetries is how often Elasticsearch retries an update that
+// raced with another write to the same document before failing it.
+const updateConflictRetries = 3
+
+// versionSourceField holds the version of documents written by updates and
+// scripts, which Elasticsearch cannot version externally.
+const versionSourceField = "hermod_version"
+
+// WriteOptions configures how the sink applies CDC messages.
+type WriteOptions struct {
+	// Action is ActionIndex by default.
+	Action Action
+	// IDColumns builds document IDs from primary-key columns, joined with
+	// "_". Without them the message ID is used.
+	IDColumns []string
+	// VersionField names the monotonic field, such as an LSN, binlog
+	// position or updated_at, that orders writes to a document. Older
+	// writes are then dropped instead of overwriting newer ones. It may
+	// name metadata, as in "metadata.lsn".
+	VersionField string
+	// Script is the painless source run by ActionScript. The row is in
+	// params.doc and the operation in params.op.
+	Script string
+	// Pipeline is the ingest pipeline documents are indexed through.
+	Pipeline string
+	// Routing is the template of the shard routing value, in the syntax of
+	// the index template.
+	Routing string
+	// Template, when set, is installed before the first write.
+	Template *IndexTemplate
+}
+
+// IndexTemplate is a composable index template for the indices, or data
+// streams, the sink writes to.
+type IndexTemplate struct {
+	Name     string
+	Patterns []string
+	// DataStream makes indices matching the patterns data streams.
+	DataStream bool
+	// LifecyclePolicy is the ILM policy managing the indices.
+	LifecyclePolicy string
+	// RolloverAlias is the alias ILM rolls over, for indices that are not
+	// data streams.
+	RolloverAlias string
+	Shards        int
+	Replicas      int
+	// Mappings is the JSON of the template's mappings.
+	Mappings json.RawMessage
+}
+
+// SetWriteOptions sets how messages are written.
+func (s *ElasticsearchSink) SetWriteOptions(opts WriteOptions) error {
+	switch opts.Action {
+	case "":
+		opts.Action = ActionIndex
+	case ActionIndex, ActionCreate, ActionUpdate:
+	case ActionScript:
+		if opts.Script == "" {
+			return errors.New("elasticsearch script action needs a script")
+		}
+	default:
+		return fmt.Errorf("unsupported elasticsearch action %q", opts.Action)
+	}
+	if opts.Action == ActionCreate && opts.VersionField != "" {
+		return errors.New("elasticsearch create action cannot be versioned")
+	}
+	if t := opts.Template; t != nil && (t.Name == "" || len(t.Patterns) == 0) {
+		return errors.New("elasticsearch index template needs a name and patterns")
+	}
+	s.opts = opts
+	return nil
+}
+
+// body returns the JSON template of the index template.
+func (t *IndexTemplate) body() ([]byte, error) {
+	settings := map[string]any{}
+	if t.LifecyclePolicy != "" {
+		settings["index.lifecycle.name"] = t.LifecyclePolicy
+		if t.RolloverAlias != "" && !t.DataStream {
+			settings["index.lifecycle.rollover_alias"] = t.RolloverAlias
+		}
+	}
+	if t.Shards > 0 {
+		settings["index.number_of_shards"] = t.Shards
+	}
+	if t.Replicas > 0 {
+		settings["index.number_of_replicas"] = t.Replicas
+	}
+	tmpl := map[string]any{"settings": settings}
+	if len(t.Mappings) > 0 {
+		tmpl["mappings"] = t.Mappings
+	}
+	body := map[string]any{"index_patterns": t.Patterns, "template": tmpl}
+	if t.DataStream {
+		body["data_stream"] = map[string]any{}
+	}
+	return json.Marshal(body)
+}
+
+// bulkItem appends the bulk action of msg, and its source, to buf. It
+// reports whether a version conflict of the item means a stale write, which
+// holds for externally versioned index and delete actions only: a conflict
+// of an update means another write changed the document meanwhile.
+func (s *ElasticsearchSink) bulkItem(buf *bytes.Buffer, msg hermod.Message) (bool, error) {
+	index, err := s.renderIndex(msg)
+	if err != nil {
+		return false, invalid("failed to render index for message %s: %w", msg.ID(), err)
+	}
+	data := msg.Data()
+	if len(data) == 0 && len(msg.Before()) > 0 {
+		_ = json.Unmarshal(msg.Before(), &data)
+	}
+	id, err := s.documentID(msg, data)
+	if err != nil {
+		return false, err
+	}
+	meta := map[string]any{"_index": index, "_id": id}
+	if s.opts.Routing != "" {
+		routing, err := s.render("routing", s.opts.Routing, msg)
+		if err != nil {
+			return false, invalid("failed to render routing for message %s: %w", msg.ID(), err)
+		}
+		if routing != "" {
+			meta["routing"] = routing
+		}
+	}
+	var version int64
+	versioned := s.opts.VersionField != ""
+	if versioned {
+		if version, err = externalVersion(evaluator.GetMsgValByPath(msg, s.opts.VersionField)); err != nil {
+			return false, invalid("message %s: version field %q: %w", msg.ID(), s.opts.VersionField, err)
+		}
+	}
+	del := msg.Operation() == hermod.OpDelete
+	act := s.opts.Action
+	if act == "" {
+		act = ActionIndex
+	}
+
+	var action string
+	var source any
+	switch {
+	case act == ActionCreate:
+		if del {
+			return false, invalid("message %s: create-only indices cannot delete documents", msg.ID())
+		}
+		action = "create"
+	case act == ActionIndex && del:
+		action = "delete"
+	case act == ActionIndex:
+		action = "index"
+	case act == ActionUpdate && del && !versioned:
+		action = "delete"
+	case act == ActionUpdate && !versioned:
+		action = "update"
+		source = map[string]any{"doc": data, "doc_as_upsert": true}
+	default:
+		action = "update"
+		source = s.updateScript(msg, data, version, del)
+	}
+
+	stale := versioned && (action == "index" || action == "delete")
+	if stale {
+		// A delete carries the version of the row it removes, so it also
+		// applies at the version the document already has.
+		meta["version"] = version
+		meta["version_type"] = "external"
+		if del {
+			meta["version_type"] = "external_gte"
+		}
+	}
+	if action == "update" {
+		meta["retry_on_conflict"] = updateConflictRetries
+	}
+	if s.opts.Pipeline != "" && (action == "index" || action == "create") {
+		meta["pipeline"] = s.opts.Pipeline
+	}
+
+	line, err := json.Marshal(map[string]any{action: meta})
+	if err != nil {
+		return false, invalid("message %s: %w", msg.ID(), err)
+	}
+	var doc []byte
+	switch {
+	case source != nil:
+		if doc, err = json.Marshal(source); err != nil {
+			return false, invalid("message %s: %w", msg.ID(), err)
+		}
+	case action == "index" || action == "create":
+		if doc, err = s.format(msg); err != nil {
+			return false, err
+		}
+	}
+	buf.Write(line)
+	buf.WriteByte('\n')
+	if doc != nil {
+		buf.Write(doc)
+		buf.WriteByte('\n')
+	}
+	return stale, nil
+}
+
+// updateScript returns the update request of scripted and versioned
+// writes. Versioned writes record their version in the document and turn
+// into no-ops when the document has a newer one.
+func (s *ElasticsearchSink) updateScript(msg hermod.Message, data map[string]any, version int64, del bool) map[string]any {
+	var body string
+	switch {
+	case s.opts.Action == ActionScript:
+		body = s.opts.Script
+	case del:
+		body = "ctx.op = 'delete'"
+	default:
+		body = "ctx._source.putAll(params.doc)"
+	}
+	params := map[string]any{"doc": data, "op": string(msg.Operation())}
+	if s.opts.VersionField != "" {
+		params["version"] = version
+		params["delete"] = del
+		body = fmt.Sprintf(`def v = ctx._source.%s; if (v != null && (v > params.version || (v == params.version && !params.delete))) { ctx.op = 'noop' } else { ctx._source.%s = params.version; %s }`,
+			versionSourceField, versionSourceField, body)
+	}
+	req := map[string]any{"script": map[string]any{"lang": "painless", "source": body, "params": params}}
+	if !del || s.opts.Action == ActionScript {
+		req["scripted_upsert"] = true
+		req["upsert"] = map[string]any{}
+	}
+	return req
+}
+
+// documentID returns the ID of the document msg writes.
+func (s *ElasticsearchSink) documentID(msg hermod.Message, data map[string]any) (string, error) {
+	if len(s.opts.IDColumns) == 0 {
+		return msg.ID(), nil
+	}
+	parts := make([]string, len(s.opts.IDColumns))
+	for i, col := range s.opts.IDColumns {
+		v, ok := data[col]
+		if !ok || v == nil {
+			return "", invalid("message %s has no value for id column %q", msg.ID(), col)
+		}
+		switch x := v.(type) {
+		case string:
+			parts[i] = x
+		case float64:
+			parts[i] = strconv.FormatFloat(x, 'f', -1, 64)
+		default:
+			parts[i] = fmt.Sprint(x)
+		}
+	}
+	return strings.Join(parts, "_"), nil
+}
+
+func (s *ElasticsearchSink) format(msg hermod.Message) ([]byte, error) {
+	if s.formatter == nil {
+		return msg.Payload(), nil
+	}
+	data, err := s.formatter.Format(msg)
+	if err != nil {
+		return nil, invalid("failed to format message %s: %w", msg.ID(), err)
+	}
+	return data, nil
+}
+
+var (
+	// pgLSN is a PostgreSQL log sequence number, e.g. "16/B374D848".
+	pgLSN = regexp.MustCompile(`^([0-9A-Fa-f]{1,8})/([0-9A-Fa-f]{1,8})$`)
+	// binlogPos is a MySQL binlog position, e.g. "mysql-bin.000003:1234".
+	binlogPos = regexp.MustCompile(`\.(\d+):(\d+)$`)
+)
+
+// externalVersion converts the value of a version field to the positive
+// 64-bit version Elasticsearch compares. Numbers are used as they are,
+// PostgreSQL LSNs and MySQL binlog positions by their order in the log, and
+// timestamps as Unix microseconds.
+func externalVersion(v any) (int64, error) {
+	switch x := v.(type) {
+	case nil:
+		return 0, errors.New("missing")
+	case float64:
+		if x < 0 || x > math.MaxInt64 || x != math.Trunc(x) {
+			return 0, fmt.Errorf("%v is not a positive integer", x)
+		}
+		return int64(x), nil
+	case json.Number:
+		return externalVersion(x.String())
+	case time.Time:
+		return x.UnixMicro(), nil
+	case string:
+		if n, err := strconv.ParseInt(x, 10, 64); err == nil && n >= 0 {
+			return n, nil
+		}
+		if m := pgLSN.FindStringSubmatch(x); m != nil {
+			hi, _ := strconv.ParseInt(m[1], 16, 64)
+			lo, _ := strconv.ParseInt(m[2], 16, 64)
+			return hi<<32 | lo, nil
+		}
+		if m := binlogPos.FindStringSubmatch(x); m != nil {
+			file, _ := strconv.ParseInt(m[1], 10, 64)
+			pos, err := strconv.ParseInt(m[2], 10, 64)
+			if err == nil && pos < 1<<32 {
+				return file<<32 | pos, nil
+			}
+		}
+		if t, ok := evaluator.ToTime(x); ok {
+			return t.UnixMicro(), nil
+		}
+	}
+	if n, ok := evaluator.ToInt64(v); ok && n >= 0 {
+		return n, nil
+	}
+	return 0, fmt.Errorf("%v is not a version", v)
+}
+
+func invalid(format string, args ...any) error {
+	return hermod.Classify(fmt.Errorf(format, args...), hermod.ErrorPermanent)
+}
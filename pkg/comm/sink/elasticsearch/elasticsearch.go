@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/elastic/go-elasticsearch/v8"
@@ -19,6 +20,10 @@ type ElasticsearchSink struct {
 	client    *elasticsearch.Client
 	index     string // Template supported
 	formatter hermod.Formatter
+	opts      WriteOptions
+
+	mu            sync.Mutex
+	templateReady bool
 }
 
 func NewElasticsearchSink(addresses []string, username, password, apiKey, index string, formatter hermod.Formatter) (*ElasticsearchSink, error) {
@@ -37,113 +42,61 @@ func NewElasticsearchSink(addresses []string, username, password, apiKey, index
 		client:    client,
 		index:     index,
 		formatter: formatter,
+		opts:      WriteOptions{Action: ActionIndex},
 	}, nil
 }
 
 func (s *ElasticsearchSink) Write(ctx context.Context, msg hermod.Message) error {
-	index, err := s.renderIndex(msg)
-	if err != nil {
-		return fmt.Errorf("failed to render index: %w", err)
-	}
-
-	op := msg.Operation()
-	if op == "" {
-		op = hermod.OpCreate
-	}
-
-	var res *esapi.Response
-	switch op {
-	case hermod.OpDelete:
-		req := esapi.DeleteRequest{
-			Index:      index,
-			DocumentID: msg.ID(),
-			Refresh:    "true",
-		}
-		res, err = req.Do(ctx, s.client)
-	default:
-		var data []byte
-		if s.formatter != nil {
-			data, err = s.formatter.Format(msg)
-			if err != nil {
-				return fmt.Errorf("failed to format message: %w", err)
-			}
-		} else {
-			data = msg.Payload()
-		}
-
-		req := esapi.IndexRequest{
-			Index:      index,
-			DocumentID: msg.ID(),
-			Body:       bytes.NewReader(data),
-			Refresh:    "true",
-		}
-		res, err = req.Do(ctx, s.client)
-	}
-
-	if err != nil {
-		return fmt.Errorf("failed to execute elasticsearch request: %w", err)
-	}
-	defer res.Body.Close()
-
-	if res.IsError() {
-		return fmt.Errorf("elasticsearch request error: %s", res.String())
-	}
-
-	return nil
+	return hermod.BatchError(s.WriteBatchResults(ctx, []hermod.Message{msg}))
 }
 
 func (s *ElasticsearchSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults sends msgs as one _bulk request and reports the outcome
+// of each item. A message that cannot be rendered is rejected on its own
+// instead of failing the batch. Version conflicts of externally versioned
+// index and delete actions mean the document is already newer, and count
+// as written.
+func (s *ElasticsearchSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
 	if len(msgs) == 0 {
-		return nil
+		return nil, nil
+	}
+	if err := s.ensureTemplate(ctx); err != nil {
+		return nil, err
 	}
 
+	errs := make([]error, len(msgs))
+	// sent maps each bulk item back to its message.
+	sent := make([]int, 0, len(msgs))
+	stale := make([]bool, 0, len(msgs))
 	var buf bytes.Buffer
-	for _, msg := range msgs {
+	for i, msg := range msgs {
 		if msg == nil {
 			continue
 		}
-
-		index, err := s.renderIndex(msg)
+		st, err := s.bulkItem(&buf, msg)
 		if err != nil {
-			return fmt.Errorf("failed to render index for message %s: %w", msg.ID(), err)
-		}
-
-		op := msg.Operation()
-		if op == "" {
-			op = hermod.OpCreate
-		}
-
-		switch op {
-		case hermod.OpDelete:
-			fmt.Fprintf(&buf, `{ "delete" : { "_index" : "%s", "_id" : "%s" } }%s`, index, msg.ID(), "\n")
-		default:
-			var data []byte
-			if s.formatter != nil {
-				data, err = s.formatter.Format(msg)
-				if err != nil {
-					return fmt.Errorf("failed to format message %s: %w", msg.ID(), err)
-				}
-			} else {
-				data = msg.Payload()
-			}
-			fmt.Fprintf(&buf, `{ "index" : { "_index" : "%s", "_id" : "%s" } }%s`, index, msg.ID(), "\n")
-			buf.Write(data)
-			buf.WriteByte('\n')
+			errs[i] = err
+			continue
 		}
+		sent = append(sent, i)
+		stale = append(stale, st)
 	}
 
-	if buf.Len() == 0 {
-		return nil
+	if len(sent) == 0 {
+		return errs, nil
 	}
 
 	res, err := s.client.Bulk(bytes.NewReader(buf.Bytes()), s.client.Bulk.WithContext(ctx), s.client.Bulk.WithRefresh("true"))
 	if err != nil {
-		return fmt.Errorf("failed to execute bulk request: %w", err)
+		return nil, fmt.Errorf("failed to execute bulk request: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return fmt.Errorf("bulk request error: %s", res.String())
+		return nil, &ResponseError{msg: "bulk request error: " + res.String(), StatusCode: res.StatusCode}
 	}
 
 	var bulkRes struct {
@@ -158,19 +111,61 @@ func (s *ElasticsearchSink) WriteBatch(ctx context.Context, msgs []hermod.Messag
 	}
 
 	if err := json.NewDecoder(res.Body).Decode(&bulkRes); err != nil {
-		return fmt.Errorf("failed to decode bulk response: %w", err)
+		return nil, fmt.Errorf("failed to decode bulk response: %w", err)
 	}
 
 	if bulkRes.Errors {
-		for _, item := range bulkRes.Items {
+		for k, item := range bulkRes.Items {
+			if k >= len(sent) {
+				break
+			}
 			for op, details := range item {
-				if details.Status >= 300 {
-					return fmt.Errorf("bulk item error (%s): %s %s", op, details.Error.Type, details.Error.Reason)
+				switch {
+				case details.Status < 300:
+				case details.Status == http.StatusConflict && stale[k] && details.Error.Type == "version_conflict_engine_exception":
+					// The document already has a newer version.
+				case details.Status == http.StatusNotFound && (op == "delete" || details.Error.Type == "document_missing_exception"):
+					// Deleting a missing document leaves it missing.
+				default:
+					errs[sent[k]] = &ResponseError{
+						msg:        fmt.Sprintf("bulk item error (%s): %s %s", op, details.Error.Type, details.Error.Reason),
+						StatusCode: details.Status,
+						Type:       details.Error.Type,
+						Op:         op,
+					}
 				}
 			}
 		}
 	}
 
+	return errs, nil
+}
+
+// ensureTemplate installs the index template before the first write.
+func (s *ElasticsearchSink) ensureTemplate(ctx context.Context) error {
+	t := s.opts.Template
+	if t == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.templateReady {
+		return nil
+	}
+	body, err := t.body()
+	if err != nil {
+		return err
+	}
+	req := esapi.IndicesPutIndexTemplateRequest{Name: t.Name, Body: bytes.NewReader(body)}
+	res, err := req.Do(ctx, s.client)
+	if err != nil {
+		return fmt.Errorf("failed to put index template %s: %w", t.Name, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return &ResponseError{msg: fmt.Sprintf("failed to put index template %s: %s", t.Name, res.String()), StatusCode: res.StatusCode}
+	}
+	s.templateReady = true
 	return nil
 }
 
@@ -191,16 +186,26 @@ func (s *ElasticsearchSink) Close() error {
 }
 
 func (s *ElasticsearchSink) renderIndex(msg hermod.Message) (string, error) {
-	if !strings.Contains(s.index, "{{") {
-		return s.index, nil
+	return s.render("index", s.index, msg)
+}
+
+// render executes a text/template over the message's data and its system
+// fields.
+func (s *ElasticsearchSink) render(name, text string, msg hermod.Message) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
 	}
 
-	tmpl, err := template.New("index").Parse(s.index)
+	tmpl, err := template.New(name).Parse(text)
 	if err != nil {
 		return "", err
 	}
 
 	data := msg.Data()
+	if len(data) == 0 && len(msg.Before()) > 0 {
+		// Deletes are rendered from the row they remove.
+		_ = json.Unmarshal(msg.Before(), &data)
+	}
 	templateData := make(map[string]any)
 	for k, v := range data {
 		templateData[k] = v
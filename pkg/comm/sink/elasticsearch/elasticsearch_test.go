@@ -1,8 +1,15 @@
 package elasticsearch
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/user/hermod"
 	"github.com/user/hermod/pkg/comm/message"
 )
 
@@ -62,3 +69,301 @@ func TestElasticsearchSink_renderIndex_Static(t *testing.T) {
 		t.Errorf("expected static-index, got %s", index)
 	}
 }
+
+func TestElasticsearchSink_ClassifyError(t *testing.T) {
+	s := &ElasticsearchSink{}
+	tests := []struct {
+		status int
+		class  hermod.ErrorClass
+	}{
+		{429, hermod.ErrorThrottled},
+		{400, hermod.ErrorPermanent},
+		{403, hermod.ErrorFatalConfig},
+		{503, hermod.ErrorTransient},
+	}
+	for _, tt := range tests {
+		err := &ResponseError{msg: "bulk item error", StatusCode: tt.status}
+		if class, _ := s.ClassifyError(err); class != tt.class {
+			t.Errorf("%d: expected %s, got %s", tt.status, tt.class, class)
+		}
+	}
+}
+
+func TestElasticsearchSink_WriteBatchResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"errors":true,"items":[
+			{"index":{"status":201}},
+			{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [age]"}}},
+			{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}}
+		]}`)
+	}))
+	defer server.Close()
+
+	s, err := NewElasticsearchSink([]string{server.URL}, "", "", "", "idx", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make([]hermod.Message, 3)
+	for i := range msgs {
+		m := message.AcquireMessage()
+		m.SetID(string(rune('a' + i)))
+		m.SetAfter([]byte(`{}`))
+		msgs[i] = m
+	}
+
+	errs, err := s.WriteBatchResults(t.Context(), msgs)
+	if err != nil {
+		t.Fatalf("expected per-item results, got %v", err)
+	}
+	if errs[0] != nil {
+		t.Errorf("expected the first item written, got %v", errs[0])
+	}
+	if class, _ := s.ClassifyError(errs[1]); class != hermod.ErrorPermanent {
+		t.Errorf("expected the mapping failure to be permanent, got %s (%v)", class, errs[1])
+	}
+	if class, _ := s.ClassifyError(errs[2]); class != hermod.ErrorThrottled {
+		t.Errorf("expected the rejected execution to be throttled, got %s (%v)", class, errs[2])
+	}
+}
+
+// bulkServer records the bulk requests and index templates it receives and
+// answers every bulk item with the next of statuses, 200 by default.
+func bulkServer(t *testing.T, statuses ...string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		b, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.URL.Path, "/_index_template/") {
+			bodies = append(bodies, r.Method+" "+r.URL.Path+" "+string(b))
+			io.WriteString(w, `{"acknowledged":true}`)
+			return
+		}
+		bodies = append(bodies, string(b))
+		items := make([]string, 0, len(statuses))
+		for _, st := range statuses {
+			items = append(items, st)
+		}
+		io.WriteString(w, `{"errors":`+fmt.Sprint(len(items) > 0)+`,"items":[`+strings.Join(items, ",")+`]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func cdcMsg(op hermod.Operation, row string) hermod.Message {
+	m := message.AcquireMessage()
+	m.SetID("msg-1")
+	m.SetOperation(op)
+	if op == hermod.OpDelete {
+		m.SetBefore([]byte(row))
+	} else {
+		m.SetAfter([]byte(row))
+	}
+	return m
+}
+
+func TestElasticsearchSink_ExternalVersions(t *testing.T) {
+	server, bodies := bulkServer(t,
+		`{"index":{"status":201}}`,
+		`{"index":{"status":409,"error":{"type":"version_conflict_engine_exception","reason":"current version [9] is higher"}}}`,
+		`{"delete":{"status":404,"result":"not_found"}}`,
+	)
+	s, err := NewElasticsearchSink([]string{server.URL}, "", "", "", "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetWriteOptions(WriteOptions{
+		IDColumns:    []string{"tenant", "id"},
+		VersionField: "metadata.lsn",
+		Pipeline:     "enrich",
+		Routing:      "{{.tenant}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []hermod.Message{
+		cdcMsg(hermod.OpUpdate, `{"tenant":"t1","id":7,"name":"new"}`),
+		cdcMsg(hermod.OpUpdate, `{"tenant":"t1","id":7,"name":"old"}`),
+		cdcMsg(hermod.OpDelete, `{"tenant":"t1","id":8}`),
+	}
+	msgs[0].SetMetadata("lsn", "0/16B3748")
+	msgs[1].SetMetadata("lsn", "0/16B3700")
+	msgs[2].SetMetadata("lsn", "0/16B3800")
+
+	errs, err := s.WriteBatchResults(t.Context(), msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range errs {
+		if e != nil {
+			t.Errorf("message %d: %v", i, e)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace((*bodies)[0]), "\n")
+	want := []string{
+		`{"index":{"_id":"t1_7","_index":"users","pipeline":"enrich","routing":"t1","version":23803720,"version_type":"external"}}`,
+		`{"tenant":"t1","id":7,"name":"new"}`,
+		`{"index":{"_id":"t1_7","_index":"users","pipeline":"enrich","routing":"t1","version":23803648,"version_type":"external"}}`,
+		`{"tenant":"t1","id":7,"name":"old"}`,
+		`{"delete":{"_id":"t1_8","_index":"users","routing":"t1","version":23803904,"version_type":"external_gte"}}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got bulk body\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Rows without their key columns are rejected on their own.
+	errs, err = s.WriteBatchResults(t.Context(), []hermod.Message{cdcMsg(hermod.OpCreate, `{"id":1}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if class, _ := hermod.ClassifyError(errs[0], s); class != hermod.ErrorPermanent {
+		t.Fatalf("expected a permanent error, got %v", errs[0])
+	}
+}
+
+func TestElasticsearchSink_Updates(t *testing.T) {
+	server, bodies := bulkServer(t)
+	s, err := NewElasticsearchSink([]string{server.URL}, "", "", "", "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetWriteOptions(WriteOptions{Action: ActionUpdate, IDColumns: []string{"id"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteBatch(t.Context(), []hermod.Message{cdcMsg(hermod.OpUpdate, `{"id":1,"name":"ada"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"update":{"_id":"1","_index":"users","retry_on_conflict":3}}` + "\n" + `{"doc":{"id":1,"name":"ada"},"doc_as_upsert":true}` + "\n"
+	if (*bodies)[0] != want {
+		t.Fatalf("got %s, want %s", (*bodies)[0], want)
+	}
+
+	// Versioned updates and deletes guard the document with a script.
+	if err := s.SetWriteOptions(WriteOptions{Action: ActionUpdate, IDColumns: []string{"id"}, VersionField: "updated_at"}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := []hermod.Message{
+		cdcMsg(hermod.OpUpdate, `{"id":1,"updated_at":"2024-05-01T00:00:00Z"}`),
+		cdcMsg(hermod.OpDelete, `{"id":1,"updated_at":"2024-05-01T00:00:00Z"}`),
+	}
+	if err := s.WriteBatch(t.Context(), msgs); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace((*bodies)[1]), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("unexpected bulk body %s", (*bodies)[1])
+	}
+	var upsert, del struct {
+		Script struct {
+			Source string         `json:"source"`
+			Params map[string]any `json:"params"`
+		} `json:"script"`
+		ScriptedUpsert bool `json:"scripted_upsert"`
+	}
+	json.Unmarshal([]byte(lines[1]), &upsert)
+	json.Unmarshal([]byte(lines[3]), &del)
+	if !upsert.ScriptedUpsert || !strings.Contains(upsert.Script.Source, "putAll") || upsert.Script.Params["version"] != float64(1714521600000000) {
+		t.Errorf("unexpected versioned update %s", lines[1])
+	}
+	if del.ScriptedUpsert || !strings.Contains(del.Script.Source, "ctx.op = 'delete'") || del.Script.Params["delete"] != true {
+		t.Errorf("unexpected versioned delete %s", lines[3])
+	}
+}
+
+func TestElasticsearchSink_ConcurrentUpdates(t *testing.T) {
+	// The second update lost a race with another writer more often than
+	// retry_on_conflict allowed; the first is a stale external version.
+	conflict := `{"status":409,"error":{"type":"version_conflict_engine_exception","reason":"version conflict, required seqNo [4]"}}`
+	server, bodies := bulkServer(t, `{"update":{"status":200}}`, `{"update":`+conflict+`}`)
+	s, err := NewElasticsearchSink([]string{server.URL}, "", "", "", "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetWriteOptions(WriteOptions{Action: ActionUpdate, IDColumns: []string{"id"}, VersionField: "updated_at"}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := []hermod.Message{
+		cdcMsg(hermod.OpUpdate, `{"id":1,"updated_at":"2024-05-01T00:00:00Z"}`),
+		cdcMsg(hermod.OpUpdate, `{"id":1,"updated_at":"2024-05-02T00:00:00Z"}`),
+	}
+	errs, err := s.WriteBatchResults(t.Context(), msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs[0] != nil {
+		t.Errorf("first update: %v", errs[0])
+	}
+	if class, _ := hermod.ClassifyError(errs[1], s); errs[1] == nil || class != hermod.ErrorTransient {
+		t.Fatalf("expected a conflicting update to be retried, got %v", errs[1])
+	}
+	if !strings.Contains((*bodies)[0], `"retry_on_conflict":3`) {
+		t.Fatalf("updates are sent without retry_on_conflict: %s", (*bodies)[0])
+	}
+
+	// A conflicting create is not retried.
+	err = &ResponseError{msg: "exists", StatusCode: http.StatusConflict, Type: "version_conflict_engine_exception", Op: "create"}
+	if class, _ := s.ClassifyError(err); class != hermod.ErrorPermanent {
+		t.Fatalf("expected a conflicting create to be permanent, got %s", class)
+	}
+}
+
+func TestElasticsearchSink_IndexTemplate(t *testing.T) {
+	server, bodies := bulkServer(t)
+	s, err := NewElasticsearchSink([]string{server.URL}, "", "", "", "logs-app", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetWriteOptions(WriteOptions{
+		Action:   ActionCreate,
+		Template: &IndexTemplate{Name: "hermod-logs", Patterns: []string{"logs-*"}, DataStream: true, LifecyclePolicy: "logs"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := s.WriteBatch(t.Context(), []hermod.Message{cdcMsg(hermod.OpCreate, `{"msg":"hi"}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*bodies) != 3 {
+		t.Fatalf("expected the template to be installed once, got %q", *bodies)
+	}
+	want := `PUT /_index_template/hermod-logs {"data_stream":{},"index_patterns":["logs-*"],"template":{"settings":{"index.lifecycle.name":"logs"}}}`
+	if (*bodies)[0] != want {
+		t.Fatalf("got %s, want %s", (*bodies)[0], want)
+	}
+	if !strings.HasPrefix((*bodies)[1], `{"create":`) {
+		t.Fatalf("data streams need create actions, got %s", (*bodies)[1])
+	}
+	errs, _ := s.WriteBatchResults(t.Context(), []hermod.Message{cdcMsg(hermod.OpDelete, `{"msg":"hi"}`)})
+	if errs[0] == nil {
+		t.Fatal("expected deletes to be rejected by create-only indices")
+	}
+}
+
+func TestExternalVersion(t *testing.T) {
+	tests := []struct {
+		in   any
+		want int64
+	}{
+		{float64(42), 42},
+		{"42", 42},
+		{"16/B374D848", 0x16<<32 | 0xB374D848},
+		{"mysql-bin.000003:1234", 3<<32 | 1234},
+		{"2024-05-01T00:00:00Z", 1714521600000000},
+	}
+	for _, tt := range tests {
+		got, err := externalVersion(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("externalVersion(%v) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []any{nil, float64(-1), 1.5, "later"} {
+		if _, err := externalVersion(in); err == nil {
+			t.Errorf("externalVersion(%v): expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package elasticsearch
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/user/hermod"
+)
+
+// ResponseError is returned when Elasticsearch rejects a request or a bulk
+// item.
+type ResponseError struct {
+	msg        string
+	StatusCode int
+	// Type is the Elasticsearch error type, e.g. mapper_parsing_exception,
+	// when it is known.
+	Type string
+	// Op is the bulk action of a failed bulk item, e.g. "update".
+	Op string
+}
+
+func (e *ResponseError) Error() string { return e.msg }
+
+// ClassifyError implements hermod.ErrorClassifier using the response status.
+func (s *ElasticsearchSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		return hermod.ErrorTransient, 0
+	}
+	switch re.StatusCode {
+	case http.StatusTooManyRequests:
+		return hermod.ErrorThrottled, 0
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return hermod.ErrorFatalConfig, 0
+	case http.StatusConflict:
+		if re.Op == "update" {
+			// The document kept changing under retry_on_conflict; applying
+			// the update again reads its latest version.
+			return hermod.ErrorTransient, 0
+		}
+		// A create of an existing document fails the same way every time.
+		return hermod.ErrorPermanent, 0
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		// Mapping and parsing failures and oversized documents fail the
+		// same way on every attempt.
+		return hermod.ErrorPermanent, 0
+	}
+	return hermod.ErrorTransient, 0
+}
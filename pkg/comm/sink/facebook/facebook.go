@@ -8,6 +8,7 @@ import (
 	"net/url"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 // FacebookSink implements the hermod.Sink interface for Facebook.
@@ -61,7 +62,7 @@ func (s *FacebookSink) Write(ctx context.Context, msg hermod.Message) error {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -103,7 +104,7 @@ func (s *FacebookSink) Ping(ctx context.Context) error {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/errorutils"
 	"firebase.google.com/go/v4/messaging"
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 	"google.golang.org/api/option"
 )
 
@@ -80,6 +83,80 @@ func (s *FCMSink) Write(ctx context.Context, msg hermod.Message) error {
 	if err := s.ensureConnected(ctx); err != nil {
 		return err
 	}
+	fcmMsg, err := s.buildMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.Send(ctx, fcmMsg)
+	if err != nil {
+		return fmt.Errorf("failed to send fcm message: %w", err)
+	}
+
+	return nil
+}
+
+// maxSendEach is the most messages a SendEach call accepts.
+const maxSendEach = 500
+
+func (s *FCMSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults sends msgs with SendEach, which delivers each message on
+// its own, and reports the outcome of every message. A message without a
+// destination is rejected without holding back the rest.
+func (s *FCMSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	if err := s.ensureConnected(ctx); err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(msgs))
+	// sent maps each FCM message back to its message.
+	sent := make([]int, 0, len(msgs))
+	fcmMsgs := make([]*messaging.Message, 0, len(msgs))
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		fcmMsg, err := s.buildMessage(msg)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		fcmMsgs = append(fcmMsgs, fcmMsg)
+		sent = append(sent, i)
+	}
+
+	for start := 0; start < len(fcmMsgs); start += maxSendEach {
+		end := min(start+maxSendEach, len(fcmMsgs))
+		res, err := s.client.SendEach(ctx, fcmMsgs[start:end])
+		if err != nil {
+			err = fmt.Errorf("failed to send fcm messages: %w", err)
+			if len(sent) == len(msgs) && len(fcmMsgs) <= maxSendEach {
+				return nil, err
+			}
+			for _, i := range sent[start:end] {
+				errs[i] = err
+			}
+			continue
+		}
+		for k, r := range res.Responses {
+			if r == nil || r.Success || start+k >= end {
+				continue
+			}
+			errs[sent[start+k]] = fmt.Errorf("failed to send fcm message: %w", r.Error)
+		}
+	}
+	return errs, nil
+}
+
+// buildMessage turns msg into an FCM message for the destination named in
+// its fcm_* metadata or, failing that, the configured default.
+func (s *FCMSink) buildMessage(msg hermod.Message) (*messaging.Message, error) {
 	var data []byte
 	var err error
 
@@ -90,7 +167,7 @@ func (s *FCMSink) Write(ctx context.Context, msg hermod.Message) error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to format message: %w", err)
+		return nil, hermod.Classify(fmt.Errorf("failed to format message: %w", err), hermod.ErrorPermanent)
 	}
 
 	// FCM messages can be sent to a specific token, a topic, or a condition.
@@ -122,7 +199,7 @@ func (s *FCMSink) Write(ctx context.Context, msg hermod.Message) error {
 	} else {
 		// If no destination is specified, we can't send the message.
 		// Keep the error text stable for tests.
-		return errors.New("fcm destination (token, topic, or condition) not found in message metadata")
+		return nil, hermod.Classify(errors.New("fcm destination (token, topic, or condition) not found in message metadata"), hermod.ErrorPermanent)
 	}
 
 	// Optionally add notification details if present in metadata
@@ -139,12 +216,26 @@ func (s *FCMSink) Write(ctx context.Context, msg hermod.Message) error {
 		fcmMsg.Notification.Body = body
 	}
 
-	_, err = s.client.Send(ctx, fcmMsg)
-	if err != nil {
-		return fmt.Errorf("failed to send fcm message: %w", err)
-	}
+	return fcmMsg, nil
+}
 
-	return nil
+// ClassifyError implements hermod.ErrorClassifier for FCM's error codes.
+// Unregistered tokens and invalid messages fail the same way every time,
+// credential errors fail for every message.
+func (s *FCMSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	switch {
+	case messaging.IsUnregistered(err), messaging.IsInvalidArgument(err), messaging.IsSenderIDMismatch(err):
+		return hermod.ErrorPermanent, 0
+	case messaging.IsThirdPartyAuthError(err), errorutils.IsUnauthenticated(err), errorutils.IsPermissionDenied(err):
+		return hermod.ErrorFatalConfig, 0
+	case messaging.IsQuotaExceeded(err):
+		var retryAfter time.Duration
+		if resp := errorutils.HTTPResponse(err); resp != nil {
+			retryAfter, _ = httpclient.RateLimitWait(resp, time.Now())
+		}
+		return hermod.ErrorThrottled, retryAfter
+	}
+	return hermod.ErrorTransient, 0
 }
 
 func (s *FCMSink) Ping(ctx context.Context) error {
@@ -0,0 +1,40 @@
+package grpcsink
+
+import (
+	"errors"
+	"time"
+
+	"github.com/user/hermod"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClassifyError implements hermod.ErrorClassifier using the call's status code.
+func (s *GrpcSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	if errors.Is(err, ErrInvalidRequest) {
+		return hermod.ErrorPermanent, 0
+	}
+	var ce *configError
+	if errors.As(err, &ce) {
+		return hermod.ErrorFatalConfig, 0
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return hermod.ErrorTransient, 0
+	}
+	switch st.Code() {
+	case codes.ResourceExhausted:
+		return hermod.ErrorThrottled, 0
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange, codes.AlreadyExists:
+		return hermod.ErrorPermanent, 0
+	case codes.NotFound:
+		// Usually the entity the message refers to, not the method, which
+		// is Unimplemented when missing.
+		return hermod.ErrorPermanent, 0
+	case codes.Unauthenticated, codes.PermissionDenied, codes.Unimplemented:
+		return hermod.ErrorFatalConfig, 0
+	}
+	// Unavailable, DeadlineExceeded, Aborted, Internal and the rest are worth
+	// retrying.
+	return hermod.ErrorTransient, 0
+}
@@ -0,0 +1,422 @@
+package grpcsink
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/evaluator"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultTimeout is the deadline of a call when Config.Timeout is zero.
+const DefaultTimeout = 30 * time.Second
+
+// Config configures a GrpcSink.
+type Config struct {
+	// Target is the server address, in any form grpc.NewClient accepts.
+	Target string
+	// ProtoFiles are the .proto files describing the service, such as files
+	// uploaded through the API. When empty the descriptors are fetched from the
+	// server by reflection.
+	ProtoFiles []string
+	// ImportPaths are searched for ProtoFiles and their imports.
+	ImportPaths []string
+	// Method is the full name of a unary or client-streaming method,
+	// "package.Service/Method".
+	Method string
+	// RequestTemplate is a JSON rendering of the request message with
+	// {{ field }} placeholders resolved against the message's data and
+	// escaped as JSON string contents. When empty
+	// the message's data is used as the request, ignoring fields the request
+	// type does not have.
+	RequestTemplate string
+	// Metadata maps outgoing metadata keys to templates resolved against the
+	// message's data. Keys that resolve to an empty value are not sent.
+	Metadata map[string]string
+	Timeout  time.Duration
+	// TLS secures the connection; nil connects in plaintext.
+	TLS *tls.Config
+}
+
+// GrpcSink calls a gRPC method for every message, encoding requests with
+// descriptors loaded at runtime so any service can be called without
+// generated code.
+type GrpcSink struct {
+	cfg Config
+
+	mu     sync.Mutex
+	conn   *grpc.ClientConn
+	stub   grpcdynamic.Stub
+	method *desc.MethodDescriptor
+}
+
+// ErrInvalidRequest is returned when a message cannot be turned into a request.
+var ErrInvalidRequest = errors.New("invalid grpc request")
+
+// configError is returned when the configured method cannot be resolved.
+type configError struct{ err error }
+
+func (e *configError) Error() string { return e.err.Error() }
+func (e *configError) Unwrap() error { return e.err }
+
+// NewGrpcSink creates a GrpcSink. The connection is made and the method
+// resolved on first use.
+func NewGrpcSink(cfg Config) (*GrpcSink, error) {
+	if cfg.Target == "" {
+		return nil, errors.New("grpc sink: target is required")
+	}
+	if _, _, err := splitMethod(cfg.Method); err != nil {
+		return nil, err
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &GrpcSink{cfg: cfg}, nil
+}
+
+// splitMethod splits "package.Service/Method" (optionally with a leading
+// slash, or with a dot before the method) into service and method names.
+func splitMethod(full string) (string, string, error) {
+	full = strings.TrimPrefix(strings.TrimSpace(full), "/")
+	i := strings.LastIndex(full, "/")
+	if i < 0 {
+		i = strings.LastIndex(full, ".")
+	}
+	if i <= 0 || i == len(full)-1 {
+		return "", "", fmt.Errorf("grpc sink: method %q must be of the form package.Service/Method", full)
+	}
+	return full[:i], full[i+1:], nil
+}
+
+// TLSConfig builds the TLS configuration of a connection. caPEM replaces the
+// system roots, and certPEM with keyPEM presents a client certificate (mTLS).
+func TLSConfig(caPEM, certPEM, keyPEM, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: insecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if caPEM != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, errors.New("grpc sink: no certificates found in the CA PEM")
+		}
+		cfg.RootCAs = pool
+	}
+	if certPEM != "" || keyPEM != "" {
+		cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("grpc sink: invalid client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// ensureConnected connects and resolves the method on first use. It returns
+// the method and stub to call, read under the lock since Close clears them.
+func (s *GrpcSink) ensureConnected(ctx context.Context) (*desc.MethodDescriptor, grpcdynamic.Stub, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.method != nil {
+		return s.method, s.stub, nil
+	}
+
+	if s.conn == nil {
+		creds := insecure.NewCredentials()
+		if s.cfg.TLS != nil {
+			creds = credentials.NewTLS(s.cfg.TLS)
+		}
+		conn, err := grpc.NewClient(s.cfg.Target, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, grpcdynamic.Stub{}, &configError{fmt.Errorf("grpc sink: failed to create client for %s: %w", s.cfg.Target, err)}
+		}
+		s.conn = conn
+	}
+
+	md, err := s.resolveMethod(ctx)
+	if err != nil {
+		return nil, grpcdynamic.Stub{}, err
+	}
+	if md.IsServerStreaming() {
+		return nil, grpcdynamic.Stub{}, &configError{fmt.Errorf("grpc sink: method %s is server-streaming; only unary and client-streaming methods are supported", md.GetFullyQualifiedName())}
+	}
+	s.method = md
+	s.stub = grpcdynamic.NewStub(s.conn)
+	return s.method, s.stub, nil
+}
+
+func (s *GrpcSink) resolveMethod(ctx context.Context) (*desc.MethodDescriptor, error) {
+	svcName, methodName, _ := splitMethod(s.cfg.Method)
+
+	var svc *desc.ServiceDescriptor
+	if len(s.cfg.ProtoFiles) > 0 {
+		parser := protoparse.Parser{ImportPaths: s.cfg.ImportPaths}
+		fds, err := parser.ParseFiles(s.cfg.ProtoFiles...)
+		if err != nil {
+			return nil, &configError{fmt.Errorf("grpc sink: failed to parse proto files: %w", err)}
+		}
+		for _, fd := range fds {
+			if svc = fd.FindService(svcName); svc != nil {
+				break
+			}
+		}
+	} else {
+		rc := grpcreflect.NewClientAuto(ctx, s.conn)
+		defer rc.Reset()
+		var err error
+		svc, err = rc.ResolveService(svcName)
+		if err != nil {
+			if grpcreflect.IsElementNotFoundError(err) {
+				return nil, &configError{fmt.Errorf("grpc sink: service %s not found by reflection: %w", svcName, err)}
+			}
+			// The server may be unreachable for now, or not serve reflection.
+			return nil, fmt.Errorf("grpc sink: reflection failed: %w", err)
+		}
+	}
+	if svc == nil {
+		return nil, &configError{fmt.Errorf("grpc sink: service %s not found", svcName)}
+	}
+	md := svc.FindMethodByName(methodName)
+	if md == nil {
+		return nil, &configError{fmt.Errorf("grpc sink: method %s not found in service %s", methodName, svcName)}
+	}
+	return md, nil
+}
+
+// buildRequest maps msg to a request message of method.
+func (s *GrpcSink) buildRequest(method *desc.MethodDescriptor, msg hermod.Message) (*dynamic.Message, error) {
+	req := dynamic.NewMessage(method.GetInputType())
+	data := msg.Data()
+
+	var body []byte
+	um := &jsonpb.Unmarshaler{}
+	switch {
+	case s.cfg.RequestTemplate != "":
+		body = []byte(evaluator.ResolveJSONTemplate(s.cfg.RequestTemplate, data))
+	case len(data) > 0:
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		body = b
+		um.AllowUnknownFields = true
+	default:
+		body = msg.Payload()
+		um.AllowUnknownFields = true
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return req, nil
+	}
+	if err := req.UnmarshalJSONPB(um, body); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRequest, method.GetInputType().GetFullyQualifiedName(), err)
+	}
+	return req, nil
+}
+
+// outgoing returns the metadata to send with msg.
+func (s *GrpcSink) outgoing(msg hermod.Message) metadata.MD {
+	md := metadata.MD{}
+	data := msg.Data()
+	for k, v := range s.cfg.Metadata {
+		if val := evaluator.ResolveTemplate(v, data); val != "" {
+			md.Append(k, val)
+		}
+	}
+	return md
+}
+
+// Call sends msg to the configured method and returns the response as a map
+// keyed by the response's proto field names.
+func (s *GrpcSink) Call(ctx context.Context, msg hermod.Message) (map[string]any, error) {
+	method, stub, err := s.ensureConnected(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.buildRequest(method, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(ctx, s.outgoing(msg)), s.cfg.Timeout)
+	defer cancel()
+
+	var resp any
+	if method.IsClientStreaming() {
+		resp, err = stream(ctx, stub, method, []*dynamic.Message{req})
+	} else {
+		resp, err = stub.InvokeRpc(ctx, method, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("grpc call %s failed: %w", method.GetFullyQualifiedName(), err)
+	}
+	return responseMap(resp)
+}
+
+func stream(ctx context.Context, stub grpcdynamic.Stub, method *desc.MethodDescriptor, reqs []*dynamic.Message) (any, error) {
+	cs, err := stub.InvokeRpcClientStream(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	for _, req := range reqs {
+		if err := cs.SendMsg(req); err != nil {
+			// The real cause is reported by CloseAndReceive.
+			break
+		}
+	}
+	return cs.CloseAndReceive()
+}
+
+func responseMap(resp any) (map[string]any, error) {
+	dm, ok := resp.(*dynamic.Message)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response type %T", resp)
+	}
+	return fieldsToMap(dm), nil
+}
+
+// fieldsToMap returns the fields set on m keyed by their proto names, with
+// nested messages as maps.
+func fieldsToMap(m *dynamic.Message) map[string]any {
+	res := make(map[string]any)
+	for _, fd := range m.GetMessageDescriptor().GetFields() {
+		if m.HasField(fd) {
+			res[fd.GetName()] = fieldValue(m.GetField(fd))
+		}
+	}
+	return res
+}
+
+func fieldValue(val any) any {
+	switch v := val.(type) {
+	case *dynamic.Message:
+		return fieldsToMap(v)
+	case []any:
+		res := make([]any, len(v))
+		for i, item := range v {
+			res[i] = fieldValue(item)
+		}
+		return res
+	case map[any]any:
+		res := make(map[string]any, len(v))
+		for k, item := range v {
+			res[fmt.Sprint(k)] = fieldValue(item)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+func (s *GrpcSink) Write(ctx context.Context, msg hermod.Message) error {
+	if msg == nil {
+		return nil
+	}
+	_, err := s.Call(ctx, msg)
+	return err
+}
+
+func (s *GrpcSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults calls a unary method once per message and reports each
+// outcome. A client-streaming method receives the batch over one stream per
+// distinct set of metadata, and a stream fails or succeeds as a whole.
+func (s *GrpcSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	method, stub, err := s.ensureConnected(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(msgs))
+	if !method.IsClientStreaming() {
+		for i, msg := range msgs {
+			if msg != nil {
+				_, errs[i] = s.Call(ctx, msg)
+			}
+		}
+		return errs, nil
+	}
+
+	type group struct {
+		md   metadata.MD
+		idx  []int
+		reqs []*dynamic.Message
+	}
+	var groups []*group
+	byKey := map[string]*group{}
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		req, err := s.buildRequest(method, msg)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		md := s.outgoing(msg)
+		key := fmt.Sprint(md)
+		g, ok := byKey[key]
+		if !ok {
+			g = &group{md: md}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.idx = append(g.idx, i)
+		g.reqs = append(g.reqs, req)
+	}
+
+	for _, g := range groups {
+		callCtx, cancel := context.WithTimeout(metadata.NewOutgoingContext(ctx, g.md), s.cfg.Timeout)
+		_, err := stream(callCtx, stub, method, g.reqs)
+		cancel()
+		if err != nil {
+			err = fmt.Errorf("grpc call %s failed: %w", method.GetFullyQualifiedName(), err)
+			for _, i := range g.idx {
+				errs[i] = err
+			}
+		}
+	}
+	return errs, nil
+}
+
+// Ping connects and resolves the configured method, which for a reflection
+// setup is a round trip to the server.
+func (s *GrpcSink) Ping(ctx context.Context) error {
+	_, _, err := s.ensureConnected(ctx)
+	return err
+}
+
+func (s *GrpcSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.method = nil
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
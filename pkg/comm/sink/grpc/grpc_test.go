@@ -0,0 +1,267 @@
+package grpcsink
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// inventory serves testdata/inventory.proto with dynamic messages.
+type inventory struct {
+	svc *desc.ServiceDescriptor
+
+	mu      sync.Mutex
+	items   []map[string]any
+	streams int
+}
+
+func (inv *inventory) reply(ctx context.Context, stored int64) *dynamic.Message {
+	out := dynamic.NewMessage(inv.svc.FindMethodByName("Put").GetOutputType())
+	out.SetFieldByName("stored", stored)
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("x-tenant")) > 0 {
+		out.SetFieldByName("tenant", md.Get("x-tenant")[0])
+	}
+	return out
+}
+
+func (inv *inventory) record(in *dynamic.Message) error {
+	sku := in.GetFieldByName("sku").(string)
+	switch sku {
+	case "bad":
+		return status.Error(codes.InvalidArgument, "unknown sku")
+	case "busy":
+		return status.Error(codes.ResourceExhausted, "slow down")
+	case "gone":
+		return status.Error(codes.NotFound, "no such sku")
+	}
+	inv.mu.Lock()
+	inv.items = append(inv.items, map[string]any{"sku": sku, "quantity": in.GetFieldByName("quantity")})
+	inv.mu.Unlock()
+	return nil
+}
+
+func startInventory(t *testing.T) (*inventory, string) {
+	t.Helper()
+	fds, err := (&protoparse.Parser{}).ParseFiles("testdata/inventory.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv := &inventory{svc: fds[0].FindService("inventory.Inventory")}
+	itemType := inv.svc.FindMethodByName("Put").GetInputType()
+
+	gs := grpc.NewServer()
+	gs.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "inventory.Inventory",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Put",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				in := dynamic.NewMessage(itemType)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if err := inv.record(in); err != nil {
+					return nil, err
+				}
+				return inv.reply(ctx, in.GetFieldByName("quantity").(int64)), nil
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "PutMany",
+			ClientStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				inv.mu.Lock()
+				inv.streams++
+				inv.mu.Unlock()
+				var stored int64
+				for {
+					in := dynamic.NewMessage(itemType)
+					if err := stream.RecvMsg(in); err != nil {
+						break
+					}
+					if err := inv.record(in); err != nil {
+						return err
+					}
+					stored += in.GetFieldByName("quantity").(int64)
+				}
+				return stream.SendMsg(inv.reply(stream.Context(), stored))
+			},
+		}},
+	}, inv)
+
+	// Serve reflection from the parsed file, as generated code would register it.
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(fds[0].UnwrapFile()); err != nil {
+		t.Fatal(err)
+	}
+	reflectionv1.RegisterServerReflectionServer(gs, reflection.NewServerV1(reflection.ServerOptions{Services: gs, DescriptorResolver: files}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+	return inv, lis.Addr().String()
+}
+
+func newMsg(data map[string]any) hermod.Message {
+	msg := message.AcquireMessage()
+	for k, v := range data {
+		msg.SetData(k, v)
+	}
+	return msg
+}
+
+func TestCallMapsTemplateAndMetadata(t *testing.T) {
+	inv, addr := startInventory(t)
+	sink, err := NewGrpcSink(Config{
+		Target:          addr,
+		ProtoFiles:      []string{"testdata/inventory.proto"},
+		Method:          "inventory.Inventory/Put",
+		RequestTemplate: `{"sku": "{{ code }}", "quantity": {{ qty }}}`,
+		Metadata:        map[string]string{"x-tenant": "{{ tenant }}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	resp, err := sink.Call(t.Context(), newMsg(map[string]any{"code": `ab"c`, "qty": 5, "tenant": "acme"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp["stored"] != int64(5) || resp["tenant"] != "acme" {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+	if len(inv.items) != 1 || inv.items[0]["sku"] != `ab"c` {
+		t.Fatalf("unexpected request: %v", inv.items)
+	}
+}
+
+// Closing the sink while calls are in flight must not race with them; the
+// next call reconnects.
+func TestCloseDuringCalls(t *testing.T) {
+	_, addr := startInventory(t)
+	sink, err := NewGrpcSink(Config{Target: addr, ProtoFiles: []string{"testdata/inventory.proto"}, Method: "inventory.Inventory/Put"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	var calls sync.WaitGroup
+	for range 4 {
+		calls.Go(func() {
+			for range 50 {
+				_, _ = sink.Call(t.Context(), newMsg(map[string]any{"sku": "a", "quantity": 1}))
+			}
+		})
+	}
+	done := make(chan struct{})
+	go func() {
+		calls.Wait()
+		close(done)
+	}()
+	for closing := true; closing; {
+		select {
+		case <-done:
+			closing = false
+		default:
+			_ = sink.Close()
+		}
+	}
+
+	if _, err := sink.Call(t.Context(), newMsg(map[string]any{"sku": "a", "quantity": 1})); err != nil {
+		t.Fatalf("call after close: %v", err)
+	}
+}
+
+func TestClassifiesStatusCodes(t *testing.T) {
+	_, addr := startInventory(t)
+	sink, err := NewGrpcSink(Config{Target: addr, ProtoFiles: []string{"testdata/inventory.proto"}, Method: "inventory.Inventory/Put"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	tests := []struct {
+		data  map[string]any
+		class hermod.ErrorClass
+	}{
+		{map[string]any{"sku": "bad"}, hermod.ErrorPermanent},
+		{map[string]any{"sku": "busy"}, hermod.ErrorThrottled},
+		{map[string]any{"sku": "gone"}, hermod.ErrorPermanent},
+		{map[string]any{"sku": 7}, hermod.ErrorPermanent},
+	}
+	for _, tt := range tests {
+		err := sink.Write(t.Context(), newMsg(tt.data))
+		if err == nil {
+			t.Fatalf("%v: expected an error", tt.data)
+		}
+		if class, _ := hermod.ClassifyError(err, sink); class != tt.class {
+			t.Errorf("%v: expected %s, got %s (%v)", tt.data, tt.class, class, err)
+		}
+	}
+
+	missing, _ := NewGrpcSink(Config{Target: addr, ProtoFiles: []string{"testdata/inventory.proto"}, Method: "inventory.Inventory/Delete"})
+	if class, _ := hermod.ClassifyError(missing.Ping(t.Context()), missing); class != hermod.ErrorFatalConfig {
+		t.Errorf("expected an unknown method to be a config error, got %s", class)
+	}
+}
+
+func TestClientStreamingBatchUsesOneStreamPerMetadata(t *testing.T) {
+	inv, addr := startInventory(t)
+	sink, err := NewGrpcSink(Config{
+		Target:     addr,
+		ProtoFiles: []string{"testdata/inventory.proto"},
+		Method:     "inventory.Inventory/PutMany",
+		Metadata:   map[string]string{"x-tenant": "{{ tenant }}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	errs, err := sink.WriteBatchResults(t.Context(), []hermod.Message{
+		newMsg(map[string]any{"sku": "a", "quantity": 1, "tenant": "x", "ignored": true}),
+		newMsg(map[string]any{"sku": "b", "quantity": 2, "tenant": "y"}),
+		newMsg(map[string]any{"sku": "c", "quantity": 3, "tenant": "x"}),
+	})
+	if err := hermod.BatchError(errs, err); err != nil {
+		t.Fatal(err)
+	}
+	if inv.streams != 2 || len(inv.items) != 3 {
+		t.Fatalf("expected 3 items over 2 streams, got %d over %d", len(inv.items), inv.streams)
+	}
+}
+
+func TestResolvesMethodByReflection(t *testing.T) {
+	_, addr := startInventory(t)
+	sink, err := NewGrpcSink(Config{Target: addr, Method: "inventory.Inventory/Put"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	resp, err := sink.Call(t.Context(), newMsg(map[string]any{"sku": "r", "quantity": 9}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp["stored"] != int64(9) {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+}
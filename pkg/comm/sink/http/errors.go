@@ -0,0 +1,59 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
+)
+
+// StatusError is returned when the endpoint answers with a non-2xx status.
+type StatusError struct {
+	msg        string
+	StatusCode int
+	// RetryAfter is the wait the endpoint asked for, if any.
+	RetryAfter time.Duration
+	Throttled  bool
+}
+
+func (e *StatusError) Error() string { return e.msg }
+
+func newStatusError(resp *http.Response, format string) *StatusError {
+	wait, limited := httpclient.RateLimitWait(resp, time.Now())
+	return &StatusError{
+		msg:        fmt.Sprintf(format, resp.StatusCode),
+		StatusCode: resp.StatusCode,
+		RetryAfter: wait,
+		Throttled:  limited,
+	}
+}
+
+// ClassifyError implements hermod.ErrorClassifier using the response status.
+func (s *HttpSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return hermod.ErrorTransient, 0
+	}
+	if se.Throttled {
+		return hermod.ErrorThrottled, se.RetryAfter
+	}
+	switch se.StatusCode {
+	case http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return hermod.ErrorPermanent, 0
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		// With a templated URL or method the message picked the endpoint,
+		// so only that message is rejected.
+		if s.templated() {
+			return hermod.ErrorPermanent, 0
+		}
+		return hermod.ErrorFatalConfig, 0
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return hermod.ErrorFatalConfig, 0
+	}
+	// 408, 5xx and anything else are worth retrying.
+	return hermod.ErrorTransient, 0
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+)
+
+func TestHttpSink_ClassifyError(t *testing.T) {
+	tests := []struct {
+		status     int
+		retryAfter string
+		class      hermod.ErrorClass
+		wait       time.Duration
+	}{
+		{http.StatusTooManyRequests, "120", hermod.ErrorThrottled, 120 * time.Second},
+		{http.StatusUnprocessableEntity, "", hermod.ErrorPermanent, 0},
+		{http.StatusUnauthorized, "", hermod.ErrorFatalConfig, 0},
+		{http.StatusBadGateway, "", hermod.ErrorTransient, 0},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.retryAfter != "" {
+				w.Header().Set("Retry-After", tt.retryAfter)
+			}
+			w.WriteHeader(tt.status)
+		}))
+		sink := NewHttpSink(server.URL, &mockFormatter{}, nil)
+		err := sink.Write(t.Context(), &mockMessage{id: "1"})
+		server.Close()
+		if err == nil {
+			t.Fatalf("%d: expected an error", tt.status)
+		}
+		class, wait := hermod.ClassifyError(err, sink)
+		if class != tt.class || wait.Round(time.Second) != tt.wait {
+			t.Errorf("%d: expected %s after %s, got %s after %s", tt.status, tt.class, tt.wait, class, wait)
+		}
+	}
+}
+
+// A 404 or 405 is the message's fault when it picked the endpoint, and the
+// config's otherwise.
+func TestHttpSink_ClassifyErrorTemplated(t *testing.T) {
+	notFound := &StatusError{StatusCode: http.StatusNotFound}
+	notAllowed := &StatusError{StatusCode: http.StatusMethodNotAllowed}
+	fixed := NewHttpSink("http://api/customers", nil, nil)
+	byURL := NewHttpSink("http://api/customers/{{ id }}", nil, nil)
+	byMethod := NewHttpSink("http://api/customers", nil, nil)
+	byMethod.SetMethod("{{ http_method }}")
+	byOperation := NewHttpSink("http://api/customers", nil, nil)
+	byOperation.SetOperationMethods(map[hermod.Operation]string{hermod.OpDelete: http.MethodDelete})
+
+	tests := []struct {
+		name  string
+		sink  *HttpSink
+		err   error
+		class hermod.ErrorClass
+	}{
+		{"fixed 404", fixed, notFound, hermod.ErrorFatalConfig},
+		{"fixed 405", fixed, notAllowed, hermod.ErrorFatalConfig},
+		{"url 404", byURL, notFound, hermod.ErrorPermanent},
+		{"method 405", byMethod, notAllowed, hermod.ErrorPermanent},
+		{"operation 405", byOperation, notAllowed, hermod.ErrorPermanent},
+		{"url 401", byURL, &StatusError{StatusCode: http.StatusUnauthorized}, hermod.ErrorFatalConfig},
+	}
+	for _, tt := range tests {
+		if class, _ := tt.sink.ClassifyError(tt.err); class != tt.class {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.class, class)
+		}
+	}
+}
@@ -3,11 +3,17 @@ package http
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/user/hermod"
 	"github.com/user/hermod/pkg/infra/compression"
+	"github.com/user/hermod/pkg/infra/evaluator"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 type HttpSink struct {
@@ -17,15 +23,25 @@ type HttpSink struct {
 	headers    map[string]string
 	pingMethod string
 	compressor compression.Compressor
+
+	// method is the request method, possibly a template; methods maps
+	// operations to methods ahead of it.
+	method        string
+	methods       map[hermod.Operation]string
+	bodyTemplate  string
+	auth          Authenticator
+	successCodes  map[int]bool
+	responseField string
 }
 
 func NewHttpSink(url string, formatter hermod.Formatter, headers map[string]string) *HttpSink {
 	return &HttpSink{
 		url:        url,
-		client:     &http.Client{},
+		client:     httpclient.NewRateLimitedClient(0),
 		formatter:  formatter,
 		headers:    headers,
 		pingMethod: "HEAD",
+		method:     http.MethodPost,
 	}
 }
 
@@ -37,16 +53,148 @@ func (s *HttpSink) SetPingMethod(method string) {
 	s.pingMethod = method
 }
 
+// SetMethod sets the request method, POST by default. It may be a template
+// such as "{{ http_method }}".
+func (s *HttpSink) SetMethod(method string) {
+	if method != "" {
+		s.method = method
+	}
+}
+
+// SetOperationMethods picks the request method by the message's operation,
+// e.g. create→POST, update→PUT, delete→DELETE. Operations not listed use the
+// method from SetMethod.
+func (s *HttpSink) SetOperationMethods(methods map[hermod.Operation]string) {
+	s.methods = methods
+}
+
+// SetBodyTemplate renders the request body from a template over the
+// message's data instead of the formatter.
+func (s *HttpSink) SetBodyTemplate(tmpl string) {
+	s.bodyTemplate = tmpl
+}
+
+func (s *HttpSink) SetAuth(auth Authenticator) {
+	s.auth = auth
+}
+
+// SetSuccessCodes lists the status codes that count as success; by default
+// every 2xx does.
+func (s *HttpSink) SetSuccessCodes(codes []int) {
+	s.successCodes = make(map[int]bool, len(codes))
+	for _, c := range codes {
+		s.successCodes[c] = true
+	}
+}
+
+// SetResponseField captures the response body into this field of the message
+// (parsed when it is JSON), so a sequential sink node can pass the response on
+// to the nodes after it.
+func (s *HttpSink) SetResponseField(field string) {
+	s.responseField = field
+}
+
+// perMessage reports whether requests depend on the message, so a batch has
+// to be sent one request per message instead of as a JSON array.
+func (s *HttpSink) perMessage() bool {
+	return strings.Contains(s.url, "{{") || strings.Contains(s.method, "{{") || len(s.methods) > 0 ||
+		s.bodyTemplate != "" || s.responseField != "" || s.method != http.MethodPost
+}
+
+func (s *HttpSink) isSuccess(code int) bool {
+	if s.successCodes != nil {
+		return s.successCodes[code]
+	}
+	return code >= 200 && code < 300
+}
+
+func (s *HttpSink) methodFor(msg hermod.Message) string {
+	if m, ok := s.methods[msg.Operation()]; ok {
+		return m
+	}
+	if m := s.methods[hermod.OpCreate]; m != "" && msg.Operation() == hermod.OpSnapshot {
+		return m
+	}
+	if strings.Contains(s.method, "{{") {
+		return strings.ToUpper(strings.TrimSpace(evaluator.ResolveTemplate(s.method, msg.Data())))
+	}
+	return s.method
+}
+
+// templated reports whether the endpoint a request goes to depends on the
+// message, so a 404 or 405 points at that message rather than the config.
+func (s *HttpSink) templated() bool {
+	return strings.Contains(s.url, "{{") || strings.Contains(s.method, "{{") || len(s.methods) > 0
+}
+
+// renderTemplate fills the {{ field }} tokens of tmpl, passing each value to
+// escape together with the template text before the token.
+func renderTemplate(tmpl string, data map[string]any, escape func(before, val string) string) string {
+	if !strings.Contains(tmpl, "{{") {
+		return tmpl
+	}
+	var out strings.Builder
+	rest := tmpl
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			out.WriteString(rest)
+			break
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			out.WriteString(rest)
+			break
+		}
+		end += start + 2
+		out.WriteString(rest[:start])
+		val := evaluator.ResolveTemplate(rest[start:end], data)
+		out.WriteString(escape(tmpl[:len(tmpl)-len(rest)+start], val))
+		rest = rest[end:]
+	}
+	return out.String()
+}
+
+// resolveURL fills the {{ field }} tokens of the URL template, escaping each
+// value for the part of the URL it lands in.
+func resolveURL(tmpl string, data map[string]any) string {
+	return renderTemplate(tmpl, data, func(before, val string) string {
+		if strings.Contains(before, "?") {
+			return url.QueryEscape(val)
+		}
+		return url.PathEscape(val)
+	})
+}
+
+// resolveBody fills the {{ field }} tokens of the body template. Values are
+// JSON-escaped unless the request is declared as something other than JSON,
+// so a quote or newline in the data cannot break the document.
+func (s *HttpSink) resolveBody(data map[string]any) string {
+	for k, v := range s.headers {
+		if strings.EqualFold(k, "Content-Type") && v != "" && !strings.Contains(strings.ToLower(v), "json") {
+			return evaluator.ResolveTemplate(s.bodyTemplate, data)
+		}
+	}
+	return evaluator.ResolveJSONTemplate(s.bodyTemplate, data)
+}
+
 func (s *HttpSink) Write(ctx context.Context, msg hermod.Message) error {
 	if msg == nil {
 		return nil
 	}
+	method := s.methodFor(msg)
+
 	var data []byte
 	var err error
 
-	if s.formatter != nil {
+	switch {
+	case s.bodyTemplate != "":
+		data = []byte(s.resolveBody(msg.Data()))
+	case method == http.MethodDelete || method == http.MethodGet || method == http.MethodHead:
+		// Bodyless methods get no body unless a template asks for one.
+	case s.formatter != nil:
 		data, err = s.formatter.Format(msg)
-	} else {
+	default:
 		data = msg.Payload()
 	}
 
@@ -54,6 +202,36 @@ func (s *HttpSink) Write(ctx context.Context, msg hermod.Message) error {
 		return fmt.Errorf("failed to format message: %w", err)
 	}
 
+	resp, err := s.send(ctx, method, resolveURL(s.url, msg.Data()), data, "")
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if !s.isSuccess(resp.StatusCode) {
+		return newStatusError(resp, "unexpected status code: %d")
+	}
+
+	if s.responseField != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			v = string(body)
+		}
+		msg.SetData(s.responseField, v)
+	}
+
+	return nil
+}
+
+// maxResponseBytes caps the response body captured into a message.
+const maxResponseBytes = 10 << 20
+
+// send compresses, authenticates and sends one request.
+func (s *HttpSink) send(ctx context.Context, method, target string, data []byte, contentType string) (*http.Response, error) {
 	encoding := ""
 	if s.compressor != nil && len(data) > 1024 {
 		compressed, err := s.compressor.Compress(data)
@@ -63,9 +241,13 @@ func (s *HttpSink) Write(ctx context.Context, msg hermod.Message) error {
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewBuffer(data))
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	if encoding != "" {
@@ -75,21 +257,38 @@ func (s *HttpSink) Write(ctx context.Context, msg hermod.Message) error {
 	for k, v := range s.headers {
 		req.Header.Set(k, v)
 	}
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if s.auth != nil {
+		if err := s.auth.Authenticate(req, data); err != nil {
+			return nil, err
+		}
 	}
 
-	return nil
+	return s.client.Do(req)
 }
 
 func (s *HttpSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults sends the batch as one JSON array. When requests depend on
+// the message (templates, operation methods or response capture) every message
+// is sent on its own instead, and each gets its own outcome.
+func (s *HttpSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	if s.perMessage() {
+		errs := make([]error, len(msgs))
+		for i, msg := range msgs {
+			errs[i] = s.Write(ctx, msg)
+		}
+		return errs, nil
+	}
+	return nil, s.writeArray(ctx, msgs)
+}
+
+func (s *HttpSink) writeArray(ctx context.Context, msgs []hermod.Message) error {
 	// Filter nil messages
 	filtered := make([]hermod.Message, 0, len(msgs))
 	for _, m := range msgs {
@@ -148,46 +347,34 @@ func (s *HttpSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error
 		payload = buf.Bytes()
 	}
 
-	encoding := ""
-	if s.compressor != nil && len(payload) > 1024 {
-		compressed, err := s.compressor.Compress(payload)
-		if err == nil && len(compressed) < len(payload) {
-			payload = compressed
-			encoding = string(s.compressor.Algorithm())
-		}
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewBuffer(payload))
-	if err != nil {
-		return fmt.Errorf("failed to create batch request: %w", err)
-	}
-
-	if encoding != "" {
-		req.Header.Set("Content-Encoding", encoding)
-	}
-
-	for k, v := range s.headers {
-		req.Header.Set(k, v)
-	}
-	if req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	resp, err := s.client.Do(req)
+	resp, err := s.send(ctx, http.MethodPost, s.url, payload, "application/json")
 	if err != nil {
 		return fmt.Errorf("failed to send batch request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("batch request failed with status code: %d", resp.StatusCode)
+	if !s.isSuccess(resp.StatusCode) {
+		return newStatusError(resp, "batch request failed with status code: %d")
 	}
 
 	return nil
 }
 
+// Ping checks that the endpoint answers with a 2xx. A templated URL has no
+// address to check without a message, so only its server is pinged and any
+// response counts; when the server is templated too there is nothing to
+// check.
 func (s *HttpSink) Ping(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, s.pingMethod, s.url, nil)
+	target := s.url
+	templated := strings.Contains(target, "{{")
+	if templated {
+		u, err := url.Parse(target[:strings.Index(target, "{{")])
+		if err != nil || u.Host == "" {
+			return nil
+		}
+		target = u.Scheme + "://" + u.Host + "/"
+	}
+	req, err := http.NewRequestWithContext(ctx, s.pingMethod, target, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create ping request: %w", err)
 	}
@@ -195,6 +382,11 @@ func (s *HttpSink) Ping(ctx context.Context) error {
 	for k, v := range s.headers {
 		req.Header.Set(k, v)
 	}
+	if s.auth != nil {
+		if err := s.auth.Authenticate(req, nil); err != nil {
+			return err
+		}
+	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -202,7 +394,7 @@ func (s *HttpSink) Ping(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+	if !templated && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
 		return fmt.Errorf("ping failed with status code: %d", resp.StatusCode)
 	}
 
@@ -1,11 +1,18 @@
 package http
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
 )
 
 type mockMessage struct {
@@ -79,4 +86,121 @@ func TestHttpSink_Ping(t *testing.T) {
 			t.Errorf("unexpected error: %v", err)
 		}
 	})
+
+	t.Run("templated URL", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				t.Errorf("expected the server root to be pinged, got %s", r.URL.Path)
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		sink := NewHttpSink(server.URL+"/customers/{{ id }}", &mockFormatter{}, nil)
+		if err := sink.Ping(t.Context()); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		server.Close()
+		if err := sink.Ping(t.Context()); err == nil {
+			t.Error("expected an unreachable server to fail the ping")
+		}
+	})
+}
+
+func TestHttpSink_TemplatesAndResponseCapture(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	sink := NewHttpSink(server.URL+"/customers/{{ id }}?region={{ region }}", nil, nil)
+	sink.SetOperationMethods(map[hermod.Operation]string{hermod.OpCreate: "POST", hermod.OpUpdate: "PUT", hermod.OpDelete: "DELETE"})
+	sink.SetBodyTemplate(`{"name":"{{ name }}"}`)
+	sink.SetResponseField("response")
+
+	update := message.AcquireMessage()
+	update.SetOperation(hermod.OpUpdate)
+	update.SetData("id", "a/b")
+	update.SetData("region", "eu&x")
+	update.SetData("name", `Ann "Jr"`)
+	if err := sink.Write(t.Context(), update); err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != `PUT /customers/a%2Fb?region=eu%26x {"name":"Ann \"Jr\""}` {
+		t.Fatalf("unexpected request: %s", got[0])
+	}
+	if resp, _ := update.Data()["response"].(map[string]any); resp["status"] != "ok" {
+		t.Fatalf("response not captured: %v", update.Data()["response"])
+	}
+
+	del := message.AcquireMessage()
+	del.SetOperation(hermod.OpDelete)
+	del.SetData("id", "c")
+	// The message picked the missing endpoint, so only it is rejected.
+	err := sink.Write(t.Context(), del)
+	if class, _ := hermod.ClassifyError(err, sink); class != hermod.ErrorPermanent {
+		t.Fatalf("expected a permanent error for a 404 on a templated URL, got %s: %v", class, err)
+	}
+	if !strings.HasPrefix(got[1], "DELETE /customers/c?region=") {
+		t.Fatalf("unexpected request: %s", got[1])
+	}
+}
+
+func TestHttpSink_Auth(t *testing.T) {
+	var last *http.Request
+	var lastBody []byte
+	var tokens int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokens++
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"tok","token_type":"bearer","expires_in":3600}`))
+			return
+		}
+		last = r
+		lastBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	write := func(auth Authenticator) {
+		t.Helper()
+		sink := NewHttpSink(server.URL, &mockFormatter{}, nil)
+		sink.SetAuth(auth)
+		if err := sink.Write(t.Context(), &mockMessage{id: "1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(BasicAuth{Username: "u", Password: "p"})
+	if u, p, ok := last.BasicAuth(); !ok || u != "u" || p != "p" {
+		t.Errorf("basic auth not sent")
+	}
+
+	oauth := NewOAuth2Auth(server.URL+"/token", "id", "secret", nil)
+	write(oauth)
+	write(oauth)
+	if last.Header.Get("Authorization") != "Bearer tok" || tokens != 1 {
+		t.Errorf("expected a cached bearer token, got %q after %d token requests", last.Header.Get("Authorization"), tokens)
+	}
+
+	write(HMACAuth{Secret: "s", TimestampHeader: "X-Timestamp"})
+	mac := hmac.New(sha256.New, []byte("s"))
+	mac.Write([]byte(last.Header.Get("X-Timestamp") + "."))
+	mac.Write(lastBody)
+	if last.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("unexpected hmac signature %q", last.Header.Get("X-Signature"))
+	}
+
+	write(NewSigV4Auth("eu-west-1", "execute-api", credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")))
+	if auth := last.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/execute-api/aws4_request") {
+		t.Errorf("unexpected sigv4 authorization %q", auth)
+	}
 }
@@ -0,0 +1,83 @@
+package iceberg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoSuchTable is returned by Catalog.LoadTable for unknown tables.
+	ErrNoSuchTable = errors.New("iceberg: table does not exist")
+	// ErrCommitConflict is returned by Catalog.CommitTable when the table
+	// changed since it was loaded. The caller reloads and tries again.
+	ErrCommitConflict = errors.New("iceberg: commit conflict")
+)
+
+// Identifier names a table within a catalog.
+type Identifier struct {
+	Namespace []string
+	Name      string
+}
+
+func (id Identifier) String() string {
+	return strings.Join(append(append([]string(nil), id.Namespace...), id.Name), ".")
+}
+
+// Table is a loaded table.
+type Table struct {
+	Identifier       Identifier
+	MetadataLocation string
+	Metadata         *Metadata
+}
+
+// Catalog tracks the current metadata file of tables and swaps it
+// atomically on commit.
+type Catalog interface {
+	LoadTable(ctx context.Context, id Identifier) (*Table, error)
+	// CreateTable creates an empty table. It fails with ErrCommitConflict
+	// when the table was created concurrently.
+	CreateTable(ctx context.Context, id Identifier, schema *Schema) (*Table, error)
+	// CommitTable applies c to base, failing with ErrCommitConflict unless
+	// base is still the table's current version.
+	CommitTable(ctx context.Context, base *Table, c Commit) (*Table, error)
+}
+
+// tableLocation is where filesystem and SQL catalogs put a table.
+func tableLocation(warehouse string, id Identifier) string {
+	parts := append([]string{strings.TrimSuffix(warehouse, "/")}, id.Namespace...)
+	return strings.Join(append(parts, id.Name), "/")
+}
+
+// writeMetadataFile writes m under the table's metadata directory and
+// returns its location. SQL and REST style catalogs name files by version
+// and a random suffix, so concurrent writers never collide.
+func writeMetadataFile(ctx context.Context, io FileIO, m *Metadata, version int) (string, error) {
+	b, err := m.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	loc := fmt.Sprintf("%s/metadata/%05d-%s.metadata.json", m.Location, version, uuid.NewString())
+	if err := io.Write(ctx, loc, b); err != nil {
+		return "", fmt.Errorf("failed to write table metadata: %w", err)
+	}
+	return loc, nil
+}
+
+// metadataVersion parses the version of a metadata file name written by
+// writeMetadataFile or a filesystem catalog, or returns 0.
+func metadataVersion(location string) int {
+	name := location[strings.LastIndex(location, "/")+1:]
+	name = strings.TrimPrefix(name, "v")
+	n := 0
+	for _, c := range name {
+		if c < '0' || c > '9' {
+			break
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
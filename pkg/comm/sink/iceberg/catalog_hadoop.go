@@ -0,0 +1,90 @@
+package iceberg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HadoopCatalog keeps tables directly in a warehouse directory, the way
+// Iceberg's HadoopCatalog does: metadata files are named v<N>.metadata.json
+// and a commit creates the next version, which fails if another writer got
+// there first. version-hint.text points readers at the latest version.
+type HadoopCatalog struct {
+	warehouse string
+	io        FileIO
+}
+
+func NewHadoopCatalog(warehouse string, io FileIO) *HadoopCatalog {
+	return &HadoopCatalog{warehouse: strings.TrimSuffix(warehouse, "/"), io: io}
+}
+
+func (c *HadoopCatalog) metadataPath(id Identifier, version int) string {
+	return fmt.Sprintf("%s/metadata/v%d.metadata.json", tableLocation(c.warehouse, id), version)
+}
+
+func (c *HadoopCatalog) LoadTable(ctx context.Context, id Identifier) (*Table, error) {
+	version := 0
+	hint, err := c.io.Read(ctx, tableLocation(c.warehouse, id)+"/metadata/version-hint.text")
+	if err == nil {
+		version, _ = strconv.Atoi(strings.TrimSpace(string(hint)))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read version hint: %w", err)
+	}
+	if version < 1 {
+		version = 1
+	}
+
+	// The hint is written after the commit, so newer versions may exist.
+	var data []byte
+	for {
+		b, err := c.io.Read(ctx, c.metadataPath(id, version))
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table metadata: %w", err)
+		}
+		data = b
+		version++
+	}
+	if data == nil {
+		return nil, ErrNoSuchTable
+	}
+	m, err := ParseMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Table{Identifier: id, MetadataLocation: c.metadataPath(id, version-1), Metadata: m}, nil
+}
+
+func (c *HadoopCatalog) CreateTable(ctx context.Context, id Identifier, schema *Schema) (*Table, error) {
+	m := NewMetadata(tableLocation(c.warehouse, id), schema)
+	return c.write(ctx, id, m, 1)
+}
+
+func (c *HadoopCatalog) CommitTable(ctx context.Context, base *Table, commit Commit) (*Table, error) {
+	version := metadataVersion(base.MetadataLocation) + 1
+	return c.write(ctx, base.Identifier, base.Metadata.apply(commit, base.MetadataLocation), version)
+}
+
+func (c *HadoopCatalog) write(ctx context.Context, id Identifier, m *Metadata, version int) (*Table, error) {
+	b, err := m.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	loc := c.metadataPath(id, version)
+	if err := c.io.Create(ctx, loc, b); err != nil {
+		if errors.Is(err, ErrExists) {
+			return nil, ErrCommitConflict
+		}
+		return nil, fmt.Errorf("failed to write table metadata: %w", err)
+	}
+	// The hint is only an optimization; LoadTable finds newer versions
+	// without it.
+	_ = c.io.Write(ctx, tableLocation(c.warehouse, id)+"/metadata/version-hint.text", []byte(strconv.Itoa(version)))
+	return &Table{Identifier: id, MetadataLocation: loc, Metadata: m}, nil
+}
@@ -0,0 +1,264 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// RESTConfig configures a RESTCatalog.
+type RESTConfig struct {
+	URI string
+	// Warehouse is sent to the config endpoint, which some servers need to
+	// pick the catalog.
+	Warehouse string
+	// Token is a static bearer token.
+	Token string
+	// Credential, "client_id:client_secret", fetches tokens with the OAuth2
+	// client-credentials grant from TokenURL, by default the catalog's
+	// /v1/oauth/tokens endpoint.
+	Credential string
+	TokenURL   string
+	Scope      string
+}
+
+// RESTCatalog talks to an Iceberg REST catalog server, which writes the
+// metadata files itself: commits send requirements and updates rather than
+// a new metadata file.
+type RESTCatalog struct {
+	cfg    RESTConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	prefix string
+}
+
+func NewRESTCatalog(cfg RESTConfig) (*RESTCatalog, error) {
+	if cfg.URI == "" {
+		return nil, errors.New("rest catalog uri is required")
+	}
+	cfg.URI = strings.TrimSuffix(cfg.URI, "/")
+	client := &http.Client{Timeout: 60 * time.Second}
+	switch {
+	case cfg.Credential != "":
+		id, secret, _ := strings.Cut(cfg.Credential, ":")
+		tokenURL := cfg.TokenURL
+		if tokenURL == "" {
+			tokenURL = cfg.URI + "/v1/oauth/tokens"
+		}
+		scope := cfg.Scope
+		if scope == "" {
+			scope = "catalog"
+		}
+		cc := &clientcredentials.Config{ClientID: id, ClientSecret: secret, TokenURL: tokenURL, Scopes: []string{scope}}
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: 30 * time.Second})
+		client.Transport = &oauth2.Transport{Source: cc.TokenSource(ctx)}
+	case cfg.Token != "":
+		client.Transport = &oauth2.Transport{Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.Token})}
+	}
+	return &RESTCatalog{cfg: cfg, client: client}, nil
+}
+
+type restError struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+type loadTableResult struct {
+	MetadataLocation string          `json:"metadata-location"`
+	Metadata         json.RawMessage `json:"metadata"`
+}
+
+// do sends a request and decodes a 2xx JSON response into out. It returns
+// the status code of the response alongside any error.
+func (c *RESTCatalog) do(ctx context.Context, method, path string, body, out any) (int, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.URI+path, r)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("rest catalog request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e restError
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+			msg = e.Error.Type + ": " + e.Error.Message
+		}
+		return resp.StatusCode, fmt.Errorf("rest catalog returned %d: %s", resp.StatusCode, msg)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("invalid rest catalog response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// base returns the path prefix of catalog endpoints, from the server's
+// config endpoint.
+func (c *RESTCatalog) base(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.prefix != "" {
+		return c.prefix, nil
+	}
+	path := "/v1/config"
+	if c.cfg.Warehouse != "" {
+		path += "?warehouse=" + url.QueryEscape(c.cfg.Warehouse)
+	}
+	var cfg struct {
+		Defaults  map[string]string `json:"defaults"`
+		Overrides map[string]string `json:"overrides"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, path, nil, &cfg); err != nil {
+		return "", err
+	}
+	prefix := cfg.Overrides["prefix"]
+	if prefix == "" {
+		prefix = cfg.Defaults["prefix"]
+	}
+	c.prefix = "/v1"
+	if prefix != "" {
+		c.prefix += "/" + url.PathEscape(prefix)
+	}
+	return c.prefix, nil
+}
+
+func namespacePath(ns []string) string {
+	return url.PathEscape(strings.Join(ns, "\x1f"))
+}
+
+func (c *RESTCatalog) tablePath(ctx context.Context, id Identifier) (string, error) {
+	base, err := c.base(ctx)
+	if err != nil {
+		return "", err
+	}
+	return base + "/namespaces/" + namespacePath(id.Namespace) + "/tables/" + url.PathEscape(id.Name), nil
+}
+
+func (c *RESTCatalog) table(id Identifier, res loadTableResult) (*Table, error) {
+	m, err := ParseMetadata(res.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &Table{Identifier: id, MetadataLocation: res.MetadataLocation, Metadata: m}, nil
+}
+
+func (c *RESTCatalog) LoadTable(ctx context.Context, id Identifier) (*Table, error) {
+	path, err := c.tablePath(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var res loadTableResult
+	if status, err := c.do(ctx, http.MethodGet, path, nil, &res); err != nil {
+		if status == http.StatusNotFound {
+			return nil, ErrNoSuchTable
+		}
+		return nil, err
+	}
+	return c.table(id, res)
+}
+
+func (c *RESTCatalog) CreateTable(ctx context.Context, id Identifier, schema *Schema) (*Table, error) {
+	base, err := c.base(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Create the namespace first; it may well exist already.
+	if status, err := c.do(ctx, http.MethodPost, base+"/namespaces", map[string]any{"namespace": id.Namespace}, nil); err != nil && status != http.StatusConflict {
+		return nil, err
+	}
+	req := map[string]any{
+		"name":       id.Name,
+		"schema":     schema,
+		"properties": map[string]string{"format-version": "2", "write.format.default": "parquet"},
+	}
+	var res loadTableResult
+	status, err := c.do(ctx, http.MethodPost, base+"/namespaces/"+namespacePath(id.Namespace)+"/tables", req, &res)
+	if err != nil {
+		if status == http.StatusConflict {
+			return nil, ErrCommitConflict
+		}
+		return nil, err
+	}
+	return c.table(id, res)
+}
+
+func (c *RESTCatalog) CommitTable(ctx context.Context, base *Table, commit Commit) (*Table, error) {
+	path, err := c.tablePath(ctx, base.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	m := base.Metadata
+	var current any
+	if snap := m.CurrentSnapshot(); snap != nil {
+		current = snap.SnapshotID
+	}
+	requirements := []map[string]any{
+		{"type": "assert-table-uuid", "uuid": m.TableUUID},
+		{"type": "assert-ref-snapshot-id", "ref": "main", "snapshot-id": current},
+	}
+	var updates []map[string]any
+	if commit.Schema != nil {
+		lastID := m.LastColumnID
+		for _, f := range commit.Schema.Fields {
+			lastID = max(lastID, f.ID)
+		}
+		requirements = append(requirements,
+			map[string]any{"type": "assert-current-schema-id", "current-schema-id": m.CurrentSchemaID},
+			map[string]any{"type": "assert-last-assigned-field-id", "last-assigned-field-id": m.LastColumnID},
+		)
+		updates = append(updates,
+			map[string]any{"action": "add-schema", "schema": commit.Schema, "last-column-id": lastID},
+			map[string]any{"action": "set-current-schema", "schema-id": -1},
+		)
+	}
+	if s := commit.Snapshot; s != nil {
+		updates = append(updates,
+			map[string]any{"action": "add-snapshot", "snapshot": s},
+			map[string]any{"action": "set-snapshot-ref", "ref-name": "main", "type": "branch", "snapshot-id": s.SnapshotID},
+		)
+	}
+	req := map[string]any{
+		"identifier":   map[string]any{"namespace": base.Identifier.Namespace, "name": base.Identifier.Name},
+		"requirements": requirements,
+		"updates":      updates,
+	}
+	var res loadTableResult
+	if status, err := c.do(ctx, http.MethodPost, path, req, &res); err != nil {
+		if status == http.StatusConflict {
+			return nil, ErrCommitConflict
+		}
+		return nil, err
+	}
+	return c.table(base.Identifier, res)
+}
@@ -0,0 +1,123 @@
+package iceberg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// SQLCatalog keeps table pointers in the iceberg_tables table used by
+// Iceberg's JDBC catalog, so tables are visible to Spark, Trino and
+// PyIceberg configured with the same database. A commit is a compare-and-swap
+// of metadata_location. Queries use $n placeholders, which both PostgreSQL
+// and SQLite accept.
+type SQLCatalog struct {
+	db        *sql.DB
+	name      string
+	warehouse string
+	io        FileIO
+}
+
+// OpenSQLCatalog opens the database at dsn, PostgreSQL for postgres:// URLs
+// and SQLite otherwise, and returns the catalog stored in it.
+func OpenSQLCatalog(ctx context.Context, dsn, name, warehouse string, io FileIO) (*SQLCatalog, error) {
+	driver := "sqlite"
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		driver = "pgx"
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog database: %w", err)
+	}
+	cat, err := NewSQLCatalog(ctx, db, name, warehouse, io)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return cat, nil
+}
+
+// NewSQLCatalog returns the catalog called name stored in db, creating the
+// iceberg_tables table if needed. New tables are put under warehouse.
+func NewSQLCatalog(ctx context.Context, db *sql.DB, name, warehouse string, io FileIO) (*SQLCatalog, error) {
+	if name == "" {
+		name = "hermod"
+	}
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS iceberg_tables (
+		catalog_name VARCHAR(255) NOT NULL,
+		table_namespace VARCHAR(255) NOT NULL,
+		table_name VARCHAR(255) NOT NULL,
+		metadata_location VARCHAR(1000),
+		previous_metadata_location VARCHAR(1000),
+		PRIMARY KEY (catalog_name, table_namespace, table_name)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create iceberg_tables: %w", err)
+	}
+	return &SQLCatalog{db: db, name: name, warehouse: strings.TrimSuffix(warehouse, "/"), io: io}, nil
+}
+
+func (c *SQLCatalog) LoadTable(ctx context.Context, id Identifier) (*Table, error) {
+	var loc sql.NullString
+	err := c.db.QueryRowContext(ctx,
+		`SELECT metadata_location FROM iceberg_tables WHERE catalog_name = $1 AND table_namespace = $2 AND table_name = $3`,
+		c.name, strings.Join(id.Namespace, "."), id.Name).Scan(&loc)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !loc.Valid) {
+		return nil, ErrNoSuchTable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table %s: %w", id, err)
+	}
+	b, err := c.io.Read(ctx, loc.String)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table metadata: %w", err)
+	}
+	m, err := ParseMetadata(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Table{Identifier: id, MetadataLocation: loc.String, Metadata: m}, nil
+}
+
+func (c *SQLCatalog) CreateTable(ctx context.Context, id Identifier, schema *Schema) (*Table, error) {
+	m := NewMetadata(tableLocation(c.warehouse, id), schema)
+	loc, err := writeMetadataFile(ctx, c.io, m, 0)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.db.ExecContext(ctx,
+		`INSERT INTO iceberg_tables (catalog_name, table_namespace, table_name, metadata_location)
+		VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+		c.name, strings.Join(id.Namespace, "."), id.Name, loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create table %s: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrCommitConflict
+	}
+	return &Table{Identifier: id, MetadataLocation: loc, Metadata: m}, nil
+}
+
+func (c *SQLCatalog) CommitTable(ctx context.Context, base *Table, commit Commit) (*Table, error) {
+	m := base.Metadata.apply(commit, base.MetadataLocation)
+	loc, err := writeMetadataFile(ctx, c.io, m, metadataVersion(base.MetadataLocation)+1)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.db.ExecContext(ctx,
+		`UPDATE iceberg_tables SET metadata_location = $1, previous_metadata_location = $2
+		WHERE catalog_name = $3 AND table_namespace = $4 AND table_name = $5 AND metadata_location = $6`,
+		loc, base.MetadataLocation, c.name, strings.Join(base.Identifier.Namespace, "."), base.Identifier.Name, base.MetadataLocation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit table %s: %w", base.Identifier, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrCommitConflict
+	}
+	return &Table{Identifier: base.Identifier, MetadataLocation: loc, Metadata: m}, nil
+}
@@ -0,0 +1,166 @@
+package iceberg
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// rowLayout maps the columns of a schema onto a struct type parquet-go
+// can write. Every column carries its Iceberg field ID, which is how
+// readers match Parquet columns to table columns.
+type rowLayout struct {
+	typ    reflect.Type
+	fields []Field
+}
+
+func newRowLayout(fields []Field) (*rowLayout, error) {
+	sfs := make([]reflect.StructField, 0, len(fields))
+	for _, f := range fields {
+		ptype, tags, err := parquetType(f.Primitive())
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", f.Name, err)
+		}
+		rep := "OPTIONAL"
+		if f.Required {
+			rep = "REQUIRED"
+		} else {
+			ptype = reflect.PointerTo(ptype)
+		}
+		tag := fmt.Sprintf(`parquet:"inname=C%d, name=%s, %s, fieldid=%d, repetitiontype=%s"`, f.ID, parquetName(f.Name), tags, f.ID, rep)
+		sfs = append(sfs, reflect.StructField{Name: "C" + strconv.Itoa(f.ID), Type: ptype, Tag: reflect.StructTag(tag)})
+	}
+	return &rowLayout{typ: reflect.StructOf(sfs), fields: fields}, nil
+}
+
+// parquetName makes a column name safe for parquet-go struct tags. Readers
+// resolve columns by field ID, so the name is informative only.
+func parquetName(name string) string {
+	return strings.NewReplacer(",", "_", "=", "_", " ", "_").Replace(name)
+}
+
+func parquetType(typ string) (reflect.Type, string, error) {
+	switch typ {
+	case TypeBoolean:
+		return reflect.TypeFor[bool](), "type=BOOLEAN", nil
+	case TypeInt:
+		return reflect.TypeFor[int32](), "type=INT32", nil
+	case TypeLong:
+		return reflect.TypeFor[int64](), "type=INT64", nil
+	case TypeFloat:
+		return reflect.TypeFor[float32](), "type=FLOAT", nil
+	case TypeDouble:
+		return reflect.TypeFor[float64](), "type=DOUBLE", nil
+	case TypeDate:
+		return reflect.TypeFor[int32](), "type=INT32, convertedtype=DATE", nil
+	case TypeTimestamp:
+		return reflect.TypeFor[int64](), "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=false, logicaltype.unit=MICROS", nil
+	case TypeTimestampTZ:
+		return reflect.TypeFor[int64](), "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS", nil
+	case TypeString:
+		return reflect.TypeFor[string](), "type=BYTE_ARRAY, convertedtype=UTF8", nil
+	case TypeBinary:
+		return reflect.TypeFor[string](), "type=BYTE_ARRAY", nil
+	}
+	return nil, "", fmt.Errorf("type %q is not supported", typ)
+}
+
+// value builds the struct of a row whose values have been decoded with
+// decodeSpooled.
+func (l *rowLayout) value(row map[string]any) (any, error) {
+	v := reflect.New(l.typ).Elem()
+	for i, f := range l.fields {
+		x, ok := row[f.Name]
+		if !ok || x == nil {
+			if f.Required {
+				return nil, fmt.Errorf("required column %q is null", f.Name)
+			}
+			continue
+		}
+		var rv reflect.Value
+		switch x := x.(type) {
+		case int64:
+			rv = reflect.ValueOf(x)
+		case float64:
+			rv = reflect.ValueOf(x)
+		case bool:
+			rv = reflect.ValueOf(x)
+		case string:
+			rv = reflect.ValueOf(x)
+		case []byte:
+			rv = reflect.ValueOf(string(x))
+		default:
+			return nil, fmt.Errorf("column %q: unexpected %T value", f.Name, x)
+		}
+		target := l.typ.Field(i).Type
+		if !f.Required {
+			target = target.Elem()
+		}
+		if !rv.CanConvert(target) {
+			return nil, fmt.Errorf("column %q: cannot write %T as %s", f.Name, x, target)
+		}
+		rv = rv.Convert(target)
+		if f.Required {
+			v.Field(i).Set(rv)
+		} else {
+			p := reflect.New(target)
+			p.Elem().Set(rv)
+			v.Field(i).Set(p)
+		}
+	}
+	return v.Interface(), nil
+}
+
+// writeParquet encodes rows as a Parquet file with the columns of fields.
+func writeParquet(fields []Field, rows []map[string]any) ([]byte, error) {
+	layout, err := newRowLayout(fields)
+	if err != nil {
+		return nil, err
+	}
+	bf := buffer.NewBufferFile()
+	pw, err := writer.NewParquetWriter(bf, reflect.New(layout.typ).Interface(), 4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	pw.CompressionType = parquet.CompressionCodec_ZSTD
+	for _, row := range rows {
+		v, err := layout.value(row)
+		if err != nil {
+			pw.WriteStop()
+			return nil, err
+		}
+		if err := pw.Write(v); err != nil {
+			pw.WriteStop()
+			return nil, fmt.Errorf("failed to write parquet row: %w", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return nil, fmt.Errorf("failed to stop parquet writer: %w", err)
+	}
+	return bf.Bytes(), nil
+}
+
+// writeDataFile writes rows to a new Parquet file at path.
+func writeDataFile(ctx context.Context, io FileIO, path string, fields []Field, rows []map[string]any, content int32, equalityIDs []int32) (DataFile, error) {
+	b, err := writeParquet(fields, rows)
+	if err != nil {
+		return DataFile{}, err
+	}
+	if err := io.Write(ctx, path, b); err != nil {
+		return DataFile{}, fmt.Errorf("failed to write data file: %w", err)
+	}
+	return DataFile{
+		Content:         content,
+		FilePath:        path,
+		FileFormat:      "PARQUET",
+		RecordCount:     int64(len(rows)),
+		FileSizeInBytes: int64(len(b)),
+		EqualityIDs:     equalityIDs,
+	}, nil
+}
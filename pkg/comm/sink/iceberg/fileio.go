@@ -0,0 +1,177 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// ErrExists is returned by FileIO.Create when the file already exists.
+var ErrExists = errors.New("iceberg: file already exists")
+
+// FileIO reads and writes the files of a warehouse.
+type FileIO interface {
+	Read(ctx context.Context, path string) ([]byte, error)
+	Write(ctx context.Context, path string, data []byte) error
+	// Create writes a file that must not exist yet, failing with ErrExists
+	// otherwise. Filesystem catalogs commit through it.
+	Create(ctx context.Context, path string, data []byte) error
+}
+
+// S3Options configures access to an S3-compatible object store.
+type S3Options struct {
+	Region    string
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+}
+
+// NewFileIO returns the FileIO for a warehouse location: s3:// (and s3a://)
+// locations use S3, anything else the local filesystem.
+func NewFileIO(ctx context.Context, warehouse string, opts S3Options) (FileIO, error) {
+	if strings.HasPrefix(warehouse, "s3://") || strings.HasPrefix(warehouse, "s3a://") {
+		var loadOpts []func(*config.LoadOptions) error
+		if opts.Region != "" {
+			loadOpts = append(loadOpts, config.WithRegion(opts.Region))
+		}
+		if opts.AccessKey != "" {
+			loadOpts = append(loadOpts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(opts.AccessKey, opts.SecretKey, "")))
+		}
+		cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config: %w", err)
+		}
+		client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+			if opts.Endpoint != "" {
+				o.BaseEndpoint = aws.String(opts.Endpoint)
+				o.UsePathStyle = true
+			}
+		})
+		return &s3IO{client: client}, nil
+	}
+	return localIO{}, nil
+}
+
+// localIO stores files on the local filesystem. Paths may be plain or
+// file:// URLs.
+type localIO struct{}
+
+func localPath(p string) string {
+	if u, err := url.Parse(p); err == nil && u.Scheme == "file" {
+		return u.Path
+	}
+	return p
+}
+
+func (localIO) Read(_ context.Context, path string) ([]byte, error) {
+	return os.ReadFile(localPath(path))
+}
+
+func (localIO) Write(_ context.Context, path string, data []byte) error {
+	p := localPath(path)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	// Write through a temporary file so readers never see a partial file.
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (localIO) Create(_ context.Context, path string, data []byte) error {
+	p := localPath(path)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	// A hard link fails when the target exists, making creation atomic.
+	if err := os.Link(tmp, p); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return ErrExists
+		}
+		return err
+	}
+	return nil
+}
+
+// s3IO stores files in S3. Create relies on conditional writes
+// (If-None-Match), which S3 and most compatible stores support.
+type s3IO struct {
+	client *s3.Client
+}
+
+func splitS3(path string) (string, string, error) {
+	u, err := url.Parse(path)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("invalid s3 path %q", path)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+func (s *s3IO) Read(ctx context.Context, path string) ([]byte, error) {
+	bucket, key, err := splitS3(path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "NoSuchKey" || apiErr.ErrorCode() == "NotFound") {
+			return nil, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("failed to get object from s3: %w", err)
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func (s *s3IO) Write(ctx context.Context, path string, data []byte) error {
+	bucket, key, err := splitS3(path)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), Body: bytes.NewReader(data)})
+	if err != nil {
+		return fmt.Errorf("failed to put object to s3: %w", err)
+	}
+	return nil
+}
+
+func (s *s3IO) Create(ctx context.Context, path string, data []byte) error {
+	bucket, key, err := splitS3(path)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		IfNoneMatch: aws.String("*"),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "PreconditionFailed" || apiErr.ErrorCode() == "ConditionalRequestConflict") {
+			return ErrExists
+		}
+		return fmt.Errorf("failed to put object to s3: %w", err)
+	}
+	return nil
+}
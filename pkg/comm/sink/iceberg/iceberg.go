@@ -0,0 +1,551 @@
+package iceberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/hermod"
+)
+
+const (
+	// DefaultTargetFileSize is the spooled size at which the sink commits
+	// without waiting for a checkpoint.
+	DefaultTargetFileSize = 128 << 20
+
+	// summaryCommitID is the snapshot summary property recording the spool a
+	// snapshot was committed from, so a spool is never committed twice.
+	summaryCommitID = "hermod.commit-id"
+
+	// commitAttempts bounds the reload-and-retry loop on commit conflicts.
+	commitAttempts = 5
+)
+
+// ErrInvalidRow is wrapped by errors for messages the table cannot take.
+var ErrInvalidRow = errors.New("iceberg: invalid row")
+
+// Config configures an IcebergSink.
+type Config struct {
+	Catalog Catalog
+	Table   Identifier
+	// KeyColumns identify rows. With keys, every change is written as an
+	// equality delete of the key plus, unless it is a delete, the new row,
+	// so updates and deletes apply on read (merge-on-read) and replays are
+	// harmless. Without keys the table is append-only.
+	KeyColumns []string
+	// TargetFileSize is the spooled size, in bytes, that triggers a commit
+	// between checkpoints; zero means DefaultTargetFileSize.
+	TargetFileSize int64
+	// SpoolDir holds rows accepted but not yet committed. It must survive
+	// restarts for exactly-once delivery, and must not be shared with
+	// another sink.
+	SpoolDir string
+	// S3 is used for tables located in s3:// warehouses.
+	S3 S3Options
+}
+
+// IcebergSink writes messages to an Iceberg format version 2 table. Rows
+// are appended to a local spool, synced to disk before Write returns, and
+// committed as Parquet data and equality-delete files in a single snapshot
+// on each Hermod checkpoint, when the spool reaches the target file size,
+// and on Close. Spools left over by a crash are committed on start unless a
+// snapshot already carries their ID, which makes delivery exactly-once.
+// New columns in incoming rows are added to the table schema.
+type IcebergSink struct {
+	cfg Config
+
+	mu sync.Mutex
+	// ready is set once the table schema is loaded and leftover spools are
+	// committed.
+	ready   bool
+	columns map[string]Field
+	spool   *spool
+	io      FileIO
+	ioLoc   string
+}
+
+func NewIcebergSink(cfg Config) (*IcebergSink, error) {
+	if cfg.Catalog == nil {
+		return nil, errors.New("iceberg catalog is required")
+	}
+	if cfg.Table.Name == "" || len(cfg.Table.Namespace) == 0 {
+		return nil, errors.New("iceberg namespace and table are required")
+	}
+	if cfg.TargetFileSize <= 0 {
+		cfg.TargetFileSize = DefaultTargetFileSize
+	}
+	if cfg.SpoolDir == "" {
+		return nil, errors.New("iceberg spool directory is required")
+	}
+	if err := os.MkdirAll(cfg.SpoolDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	return &IcebergSink{cfg: cfg, columns: make(map[string]Field)}, nil
+}
+
+// record is a spooled change. Col records a column added by later rows.
+type record struct {
+	Op  string         `json:"op,omitempty"`
+	Row map[string]any `json:"row,omitempty"`
+	Col *column        `json:"col,omitempty"`
+}
+
+const (
+	recUpsert = "u"
+	recDelete = "d"
+)
+
+func (s *IcebergSink) Write(ctx context.Context, msg hermod.Message) error {
+	return s.WriteBatch(ctx, []hermod.Message{msg})
+}
+
+func (s *IcebergSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults spools every valid message and reports those the table
+// cannot take, such as rows without a key or with values of the wrong type.
+func (s *IcebergSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureReady(ctx); err != nil {
+		return nil, err
+	}
+
+	var (
+		recs   []record
+		errs   []error
+		failed bool
+	)
+	added := make(map[string]Field)
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		rec, cols, err := s.toRecord(msg, added)
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(msgs))
+			}
+			errs[i] = err
+			failed = true
+			continue
+		}
+		for _, c := range cols {
+			recs = append(recs, record{Col: &c})
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) > 0 {
+		if err := s.append(recs); err != nil {
+			return nil, err
+		}
+		for name, f := range added {
+			s.columns[name] = f
+		}
+	}
+	if s.spool != nil && s.spool.size >= s.cfg.TargetFileSize {
+		if err := s.commitLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if !failed {
+		return nil, nil
+	}
+	return errs, nil
+}
+
+// toRecord validates msg against the known columns and converts it to a
+// spool record. Columns it introduces are recorded in added and returned.
+func (s *IcebergSink) toRecord(msg hermod.Message, added map[string]Field) (record, []column, error) {
+	data := msg.Data()
+	if len(data) == 0 && len(msg.Before()) > 0 {
+		_ = json.Unmarshal(msg.Before(), &data)
+	}
+	if len(data) == 0 {
+		return record{}, nil, fmt.Errorf("%w: message %s has no data", ErrInvalidRow, msg.ID())
+	}
+
+	rec := record{Op: recUpsert, Row: make(map[string]any, len(data))}
+	if msg.Operation() == hermod.OpDelete {
+		if len(s.cfg.KeyColumns) == 0 {
+			return record{}, nil, fmt.Errorf("%w: deletes need key columns", ErrInvalidRow)
+		}
+		rec.Op = recDelete
+	}
+	for _, k := range s.cfg.KeyColumns {
+		if data[k] == nil {
+			return record{}, nil, fmt.Errorf("%w: key column %q is missing", ErrInvalidRow, k)
+		}
+	}
+
+	var cols []column
+	for _, name := range slices.Sorted(maps.Keys(data)) {
+		v := data[name]
+		if rec.Op == recDelete && !s.isKey(name) {
+			continue
+		}
+		f, ok := s.columns[name]
+		if !ok {
+			f, ok = added[name]
+		}
+		if !ok {
+			if v == nil {
+				// The type of a column is only known from a value.
+				continue
+			}
+			f = Field{Name: name, Type: inferType(v)}
+			added[name] = f
+			cols = append(cols, column{Name: name, Type: f.Primitive()})
+		}
+		nv, err := normalize(v, f.Primitive())
+		if err != nil {
+			return record{}, nil, fmt.Errorf("%w: column %q: %v", ErrInvalidRow, name, err)
+		}
+		rec.Row[name] = nv
+	}
+	if rec.Op == recUpsert {
+		for name, f := range s.columns {
+			if f.Required && rec.Row[name] == nil {
+				return record{}, nil, fmt.Errorf("%w: required column %q is missing", ErrInvalidRow, name)
+			}
+		}
+	}
+	return rec, cols, nil
+}
+
+func (s *IcebergSink) isKey(name string) bool {
+	for _, k := range s.cfg.KeyColumns {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureReady loads the table's columns and commits spools left over by a
+// previous run. It is retried on every call until it succeeds.
+func (s *IcebergSink) ensureReady(ctx context.Context) error {
+	if s.ready {
+		return nil
+	}
+	leftover, err := listSpools(s.cfg.SpoolDir)
+	if err != nil {
+		return err
+	}
+	for _, id := range leftover {
+		if err := s.commitSpool(ctx, id, spoolPath(s.cfg.SpoolDir, id)); err != nil {
+			return fmt.Errorf("failed to commit leftover spool %s: %w", id, err)
+		}
+		if err := os.Remove(spoolPath(s.cfg.SpoolDir, id)); err != nil {
+			return err
+		}
+	}
+	t, err := s.cfg.Catalog.LoadTable(ctx, s.cfg.Table)
+	if err != nil && !errors.Is(err, ErrNoSuchTable) {
+		return err
+	}
+	if t != nil {
+		s.learnSchema(t.Metadata.CurrentSchema())
+	}
+	s.ready = true
+	return nil
+}
+
+func (s *IcebergSink) learnSchema(schema *Schema) {
+	for _, f := range schema.Fields {
+		s.columns[f.Name] = f
+	}
+}
+
+// CommitCheckpoint implements hermod.CheckpointCommitter: everything
+// written so far becomes visible in one snapshot.
+func (s *IcebergSink) CommitCheckpoint(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ensureReady(ctx); err != nil {
+		return err
+	}
+	return s.commitLocked(ctx)
+}
+
+// commitLocked commits the current spool and starts a new one.
+func (s *IcebergSink) commitLocked(ctx context.Context) error {
+	sp := s.spool
+	if sp == nil || sp.size == 0 {
+		return nil
+	}
+	if err := s.commitSpool(ctx, sp.id, sp.path); err != nil {
+		return err
+	}
+	s.spool = nil
+	return sp.remove()
+}
+
+// commitSpool commits the spool with the given ID, reloading the table and
+// trying again when another writer commits first.
+func (s *IcebergSink) commitSpool(ctx context.Context, id, path string) error {
+	recs, err := readSpool(path)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		err := s.commitOnce(ctx, id, recs)
+		if !errors.Is(err, ErrCommitConflict) || attempt == commitAttempts {
+			return err
+		}
+	}
+}
+
+func (s *IcebergSink) commitOnce(ctx context.Context, commitID string, recs []record) error {
+	var cols []column
+	for _, r := range recs {
+		if r.Col != nil {
+			cols = append(cols, *r.Col)
+		}
+	}
+
+	t, err := s.cfg.Catalog.LoadTable(ctx, s.cfg.Table)
+	if errors.Is(err, ErrNoSuchTable) {
+		t, err = s.cfg.Catalog.CreateTable(ctx, s.cfg.Table, newSchema(cols, s.cfg.KeyColumns))
+	}
+	if err != nil {
+		return err
+	}
+	meta := t.Metadata
+	if meta.committed(commitID) {
+		return nil
+	}
+	io, err := s.fileIO(ctx, meta.Location)
+	if err != nil {
+		return err
+	}
+
+	current := meta.CurrentSchema()
+	schema, _ := evolve(current, cols, meta.LastColumnID)
+	var schemaChange *Schema
+	if schema != current {
+		schemaChange = schema
+	}
+
+	rows, deletes, err := s.collect(recs, schema)
+	if err != nil {
+		return err
+	}
+
+	snap := &Snapshot{
+		SnapshotID:     rand.Int64N(1<<62) + 1,
+		SequenceNumber: meta.LastSequenceNumber + 1,
+		TimestampMs:    time.Now().UnixMilli(),
+		Summary:        map[string]string{summaryCommitID: commitID},
+	}
+	if parent := meta.CurrentSnapshot(); parent != nil {
+		id := parent.SnapshotID
+		snap.ParentSnapshotID = &id
+	}
+
+	prefix := meta.Location + "/metadata/" + uuid.NewString()
+	var manifests []ManifestFile
+	if len(rows) > 0 {
+		df, err := writeDataFile(ctx, io, fmt.Sprintf("%s/data/%s.parquet", meta.Location, uuid.NewString()), schema.Fields, rows, contentData, nil)
+		if err != nil {
+			return err
+		}
+		mf, err := writeManifest(ctx, io, prefix+"-m0.avro", schema, snap, manifestContentData, []DataFile{df})
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, mf)
+		snap.Summary["added-data-files"] = "1"
+		snap.Summary["added-records"] = strconv.Itoa(len(rows))
+	}
+	if len(deletes) > 0 {
+		fields, ids, err := s.keyFields(schema)
+		if err != nil {
+			return err
+		}
+		df, err := writeDataFile(ctx, io, fmt.Sprintf("%s/data/%s-deletes.parquet", meta.Location, uuid.NewString()), fields, deletes, contentEqualityDeletes, ids)
+		if err != nil {
+			return err
+		}
+		mf, err := writeManifest(ctx, io, prefix+"-m1.avro", schema, snap, manifestContentDeletes, []DataFile{df})
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, mf)
+		snap.Summary["added-delete-files"] = "1"
+		snap.Summary["added-equality-delete-files"] = "1"
+		snap.Summary["added-equality-deletes"] = strconv.Itoa(len(deletes))
+	}
+	switch {
+	case len(deletes) == 0:
+		snap.Summary["operation"] = OpAppend
+	case len(rows) == 0:
+		snap.Summary["operation"] = OpDelete
+	default:
+		snap.Summary["operation"] = OpOverwrite
+	}
+
+	if snap.ParentSnapshotID != nil {
+		previous, err := readManifestList(ctx, io, meta.CurrentSnapshot().ManifestList)
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, previous...)
+	}
+	snap.ManifestList = fmt.Sprintf("%s/metadata/snap-%d-1-%s.avro", meta.Location, snap.SnapshotID, uuid.NewString())
+	if err := writeManifestList(ctx, io, snap.ManifestList, snap, manifests); err != nil {
+		return err
+	}
+
+	committed, err := s.cfg.Catalog.CommitTable(ctx, t, Commit{Schema: schemaChange, Snapshot: snap})
+	if err != nil {
+		return err
+	}
+	s.learnSchema(committed.Metadata.CurrentSchema())
+	return nil
+}
+
+// collect turns spooled records into data rows and equality-delete rows.
+// For keyed tables only the last change of each key counts, and every key
+// changed gets a delete, so the new row replaces any older version.
+func (s *IcebergSink) collect(recs []record, schema *Schema) ([]map[string]any, []map[string]any, error) {
+	decode := func(row map[string]any, only func(string) bool) (map[string]any, error) {
+		out := make(map[string]any, len(row))
+		for name, v := range row {
+			if only != nil && !only(name) {
+				continue
+			}
+			f, ok := schema.Field(name)
+			if !ok {
+				continue
+			}
+			dv, err := decodeSpooled(v, f.Primitive())
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", name, err)
+			}
+			out[name] = dv
+		}
+		return out, nil
+	}
+
+	if len(s.cfg.KeyColumns) == 0 {
+		rows := make([]map[string]any, 0, len(recs))
+		for _, r := range recs {
+			if r.Col != nil {
+				continue
+			}
+			row, err := decode(r.Row, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil, nil
+	}
+
+	latest := make(map[string]int)
+	var order []string
+	for i, r := range recs {
+		if r.Col != nil {
+			continue
+		}
+		key := make([]any, len(s.cfg.KeyColumns))
+		for j, k := range s.cfg.KeyColumns {
+			key[j] = r.Row[k]
+		}
+		b, _ := json.Marshal(key)
+		if _, ok := latest[string(b)]; !ok {
+			order = append(order, string(b))
+		}
+		latest[string(b)] = i
+	}
+	var rows, deletes []map[string]any
+	for _, k := range order {
+		r := recs[latest[k]]
+		del, err := decode(r.Row, s.isKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		deletes = append(deletes, del)
+		if r.Op == recDelete {
+			continue
+		}
+		row, err := decode(r.Row, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, deletes, nil
+}
+
+// keyFields returns the key columns of schema and their field IDs.
+func (s *IcebergSink) keyFields(schema *Schema) ([]Field, []int32, error) {
+	var fields []Field
+	var ids []int32
+	for _, k := range s.cfg.KeyColumns {
+		f, ok := schema.Field(k)
+		if !ok {
+			return nil, nil, fmt.Errorf("key column %q is not in the table schema", k)
+		}
+		fields = append(fields, f)
+		ids = append(ids, int32(f.ID))
+	}
+	return fields, ids, nil
+}
+
+// fileIO returns the FileIO for the table at location.
+func (s *IcebergSink) fileIO(ctx context.Context, location string) (FileIO, error) {
+	if s.io != nil && s.ioLoc == location {
+		return s.io, nil
+	}
+	io, err := NewFileIO(ctx, location, s.cfg.S3)
+	if err != nil {
+		return nil, err
+	}
+	s.io, s.ioLoc = io, location
+	return io, nil
+}
+
+func (s *IcebergSink) Ping(ctx context.Context) error {
+	if _, err := s.cfg.Catalog.LoadTable(ctx, s.cfg.Table); err != nil && !errors.Is(err, ErrNoSuchTable) {
+		return fmt.Errorf("failed to load iceberg table %s: %w", s.cfg.Table, err)
+	}
+	return nil
+}
+
+// Close commits what is spooled. If that fails the spool is kept and
+// committed by the next run.
+func (s *IcebergSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	var err error
+	if s.ready {
+		err = s.commitLocked(ctx)
+	}
+	if s.spool != nil {
+		s.spool.close()
+		s.spool = nil
+	}
+	return err
+}
+
+// ClassifyError implements hermod.ErrorClassifier.
+func (s *IcebergSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	if errors.Is(err, ErrInvalidRow) {
+		return hermod.ErrorPermanent, 0
+	}
+	return hermod.ErrorTransient, 0
+}
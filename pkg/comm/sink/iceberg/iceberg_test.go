@@ -0,0 +1,527 @@
+package iceberg
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+var testTable = Identifier{Namespace: []string{"db"}, Name: "users"}
+
+func newTestSink(t *testing.T, cat Catalog, spoolDir string, keys ...string) *IcebergSink {
+	t.Helper()
+	s, err := NewIcebergSink(Config{Catalog: cat, Table: testTable, KeyColumns: keys, SpoolDir: spoolDir})
+	if err != nil {
+		t.Fatalf("NewIcebergSink: %v", err)
+	}
+	return s
+}
+
+func msg(op hermod.Operation, row string) hermod.Message {
+	m := message.AcquireMessage()
+	m.SetOperation(op)
+	m.SetAfter([]byte(row))
+	return m
+}
+
+func write(t *testing.T, s *IcebergSink, msgs ...hermod.Message) {
+	t.Helper()
+	if err := s.WriteBatch(t.Context(), msgs); err != nil {
+		t.Fatalf("WriteBatch: %v", err)
+	}
+}
+
+// readManifest reads the live entries of a manifest. Entries with unset
+// sequence numbers inherit the manifest's.
+func readManifest(t *testing.T, io FileIO, m ManifestFile) []manifestEntry {
+	t.Helper()
+	b, err := io.Read(t.Context(), m.ManifestPath)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	dec, err := ocf.NewDecoder(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	var out []manifestEntry
+	for dec.HasNext() {
+		var e manifestEntry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("decode manifest entry: %v", err)
+		}
+		if e.SequenceNumber == nil {
+			seq := m.SequenceNumber
+			e.SequenceNumber = &seq
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// readParquet returns the rows of a Parquet file keyed by lower-cased
+// column name, and the field IDs of its columns.
+func readParquet(t *testing.T, io FileIO, path string) ([]map[string]any, map[string]int32) {
+	t.Helper()
+	b, err := io.Read(t.Context(), path)
+	if err != nil {
+		t.Fatalf("read data file: %v", err)
+	}
+	pr, err := reader.NewParquetReader(buffer.NewBufferFileFromBytes(b), nil, 1)
+	if err != nil {
+		t.Fatalf("open parquet: %v", err)
+	}
+	defer pr.ReadStop()
+	ids := make(map[string]int32)
+	for _, el := range pr.Footer.Schema[1:] {
+		ids[strings.ToLower(el.Name)] = el.GetFieldID()
+	}
+	res, err := pr.ReadByNumber(int(pr.GetNumRows()))
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	var rows []map[string]any
+	for _, r := range res {
+		b, _ := json.Marshal(r)
+		var row map[string]any
+		_ = json.Unmarshal(b, &row)
+		out := make(map[string]any)
+		for k, v := range row {
+			out[strings.ToLower(k)] = v
+		}
+		rows = append(rows, out)
+	}
+	return rows, ids
+}
+
+// scan reads the current state of a table keyed by "id", applying
+// equality deletes to data files with lower sequence numbers.
+func scan(t *testing.T, cat Catalog) (*Table, map[string]map[string]any) {
+	t.Helper()
+	tbl, err := cat.LoadTable(t.Context(), testTable)
+	if err != nil {
+		t.Fatalf("LoadTable: %v", err)
+	}
+	io, _ := NewFileIO(t.Context(), tbl.Metadata.Location, S3Options{})
+	snap := tbl.Metadata.CurrentSnapshot()
+	if snap == nil {
+		return tbl, nil
+	}
+	manifests, err := readManifestList(t.Context(), io, snap.ManifestList)
+	if err != nil {
+		t.Fatalf("readManifestList: %v", err)
+	}
+	type seqRow struct {
+		seq int64
+		row map[string]any
+	}
+	var data []seqRow
+	deleted := make(map[string]int64)
+	for _, m := range manifests {
+		for _, e := range readManifest(t, io, m) {
+			rows, _ := readParquet(t, io, e.DataFile.FilePath)
+			for _, r := range rows {
+				if e.DataFile.Content == contentEqualityDeletes {
+					key := fmt.Sprint(r["id"])
+					deleted[key] = max(deleted[key], *e.SequenceNumber)
+				} else {
+					data = append(data, seqRow{*e.SequenceNumber, r})
+				}
+			}
+		}
+	}
+	state := make(map[string]map[string]any)
+	for _, d := range data {
+		key := fmt.Sprint(d.row["id"])
+		if deleted[key] > d.seq {
+			continue
+		}
+		if _, dup := state[key]; dup {
+			t.Fatalf("key %s is live twice", key)
+		}
+		state[key] = d.row
+	}
+	return tbl, state
+}
+
+func TestCDCMergeOnRead(t *testing.T) {
+	warehouse := t.TempDir()
+	cat := NewHadoopCatalog(warehouse, localIO{})
+	s := newTestSink(t, cat, t.TempDir(), "id")
+
+	write(t, s,
+		msg(hermod.OpCreate, `{"id":1,"name":"ada"}`),
+		msg(hermod.OpCreate, `{"id":2,"name":"bob"}`),
+		msg(hermod.OpCreate, `{"id":3,"name":"cy"}`),
+	)
+	if err := s.CommitCheckpoint(t.Context()); err != nil {
+		t.Fatalf("CommitCheckpoint: %v", err)
+	}
+	write(t, s,
+		msg(hermod.OpUpdate, `{"id":2,"name":"bob","email":"bob@example.com"}`),
+		msg(hermod.OpDelete, `{"id":3}`),
+		msg(hermod.OpUpdate, `{"id":1,"name":"ada v2"}`),
+		msg(hermod.OpUpdate, `{"id":1,"name":"ada v3"}`),
+	)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	tbl, state := scan(t, cat)
+	if len(state) != 2 {
+		t.Fatalf("expected 2 live rows, got %v", state)
+	}
+	if state["1"]["name"] != "ada v3" {
+		t.Fatalf("expected the last update of id 1 to win, got %v", state["1"])
+	}
+	if state["2"]["email"] != "bob@example.com" {
+		t.Fatalf("expected the new email column on id 2, got %v", state["2"])
+	}
+
+	m := tbl.Metadata
+	if len(m.Snapshots) != 2 || m.LastSequenceNumber != 2 {
+		t.Fatalf("expected 2 snapshots, got %d (sequence %d)", len(m.Snapshots), m.LastSequenceNumber)
+	}
+	if op := m.CurrentSnapshot().Summary["operation"]; op != OpOverwrite {
+		t.Fatalf("expected an overwrite snapshot, got %q", op)
+	}
+	schema := m.CurrentSchema()
+	if len(m.Schemas) != 2 || schema.SchemaID != 1 {
+		t.Fatalf("expected the email column to add schema 1, got %d schemas", len(m.Schemas))
+	}
+	if f, ok := schema.Field("email"); !ok || f.ID != 3 || f.Required || f.Primitive() != TypeString {
+		t.Fatalf("unexpected email field %+v", f)
+	}
+	if f, _ := schema.Field("id"); !f.Required || f.Primitive() != TypeLong || len(schema.IdentifierFieldIDs) != 1 || schema.IdentifierFieldIDs[0] != f.ID {
+		t.Fatalf("expected id to be the required identifier field, got %+v %v", f, schema.IdentifierFieldIDs)
+	}
+	if _, err := os.Stat(filepath.Join(warehouse, "db", "users", "metadata", "v3.metadata.json")); err != nil {
+		t.Fatalf("expected the third metadata version: %v", err)
+	}
+
+	// Delete files list the key's field ID, and data files carry field IDs.
+	io := localIO{}
+	manifests, _ := readManifestList(t.Context(), io, m.CurrentSnapshot().ManifestList)
+	for _, mf := range manifests {
+		if b, _ := io.Read(t.Context(), mf.ManifestPath); !bytes.Contains(b, []byte(`"field-id":135`)) {
+			t.Fatalf("expected the manifest schema to carry field IDs")
+		}
+		for _, e := range readManifest(t, io, mf) {
+			_, ids := readParquet(t, io, e.DataFile.FilePath)
+			if ids["id"] != 1 {
+				t.Fatalf("expected field ID 1 on the id column, got %v", ids)
+			}
+			if e.DataFile.Content == contentEqualityDeletes && (len(e.DataFile.EqualityIDs) != 1 || e.DataFile.EqualityIDs[0] != 1) {
+				t.Fatalf("unexpected equality IDs %v", e.DataFile.EqualityIDs)
+			}
+		}
+	}
+}
+
+func TestRejectsInvalidRows(t *testing.T) {
+	s := newTestSink(t, NewHadoopCatalog(t.TempDir(), localIO{}), t.TempDir(), "id")
+	defer s.Close()
+
+	errs, err := s.WriteBatchResults(t.Context(), []hermod.Message{
+		msg(hermod.OpCreate, `{"id":1,"n":5}`),
+		msg(hermod.OpCreate, `{"name":"no key"}`),
+		msg(hermod.OpCreate, `{"id":2,"n":"five"}`),
+	})
+	if err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+	if len(errs) != 3 || errs[0] != nil || errs[1] == nil || errs[2] == nil {
+		t.Fatalf("expected the second and third rows to fail, got %v", errs)
+	}
+	for _, e := range errs[1:] {
+		if class, _ := s.ClassifyError(e); class != hermod.ErrorPermanent {
+			t.Fatalf("expected %v to be permanent", e)
+		}
+	}
+
+	unkeyed := newTestSink(t, NewHadoopCatalog(t.TempDir(), localIO{}), t.TempDir())
+	defer unkeyed.Close()
+	if err := unkeyed.Write(t.Context(), msg(hermod.OpDelete, `{"id":1}`)); !errors.Is(err, ErrInvalidRow) {
+		t.Fatalf("expected deletes to need key columns, got %v", err)
+	}
+}
+
+// Sources acknowledge rows once the sink has written them, so rows a crashed
+// run spooled are not sent again and must be committed by the next run.
+func TestSpoolRecoveryIsExactlyOnce(t *testing.T) {
+	cat := NewHadoopCatalog(t.TempDir(), localIO{})
+	spoolDir := t.TempDir()
+
+	// The first run crashes with rows spooled but not committed.
+	s := newTestSink(t, cat, spoolDir)
+	write(t, s, msg(hermod.OpCreate, `{"id":1}`), msg(hermod.OpCreate, `{"id":2}`))
+	s.spool.close()
+	spooled, _ := os.ReadFile(s.spool.path)
+	name := filepath.Base(s.spool.path)
+
+	// The second run commits the leftover spool before taking new rows.
+	s2 := newTestSink(t, cat, spoolDir)
+	write(t, s2, msg(hermod.OpCreate, `{"id":3}`))
+	if err := s2.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, state := scan(t, cat); len(state) != 3 {
+		t.Fatalf("expected 3 rows, got %v", state)
+	}
+
+	// Had the first run crashed after committing but before removing its
+	// spool, recovery must not commit it again.
+	if err := os.WriteFile(filepath.Join(spoolDir, name), spooled, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s3 := newTestSink(t, cat, spoolDir)
+	if err := s3.CommitCheckpoint(t.Context()); err != nil {
+		t.Fatalf("CommitCheckpoint: %v", err)
+	}
+	tbl, state := scan(t, cat)
+	if len(state) != 3 || len(tbl.Metadata.Snapshots) != 2 {
+		t.Fatalf("expected the spool to be committed once, got %d rows in %d snapshots", len(state), len(tbl.Metadata.Snapshots))
+	}
+	if left, _ := listSpools(spoolDir); len(left) != 0 {
+		t.Fatalf("expected no spools left, got %v", left)
+	}
+}
+
+func TestTargetFileSizeCommits(t *testing.T) {
+	cat := NewHadoopCatalog(t.TempDir(), localIO{})
+	s, err := NewIcebergSink(Config{Catalog: cat, Table: testTable, SpoolDir: t.TempDir(), TargetFileSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	write(t, s, msg(hermod.OpCreate, `{"id":1,"payload":"a fairly long value that fills the spool"}`))
+	if s.spool != nil {
+		t.Fatal("expected the spool to be committed once it reached the target size")
+	}
+	if _, state := scan(t, cat); len(state) != 1 {
+		t.Fatalf("expected the row to be committed, got %v", state)
+	}
+}
+
+func TestSQLCatalog(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "catalog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cat, err := NewSQLCatalog(t.Context(), db, "test", t.TempDir(), localIO{})
+	if err != nil {
+		t.Fatalf("NewSQLCatalog: %v", err)
+	}
+	if _, err := cat.LoadTable(t.Context(), testTable); !errors.Is(err, ErrNoSuchTable) {
+		t.Fatalf("expected ErrNoSuchTable, got %v", err)
+	}
+
+	s := newTestSink(t, cat, t.TempDir(), "id")
+	write(t, s, msg(hermod.OpCreate, `{"id":1,"at":"2024-05-01T10:00:00Z"}`))
+	if err := s.CommitCheckpoint(t.Context()); err != nil {
+		t.Fatalf("CommitCheckpoint: %v", err)
+	}
+	base, err := cat.LoadTable(t.Context(), testTable)
+	if err != nil {
+		t.Fatalf("LoadTable: %v", err)
+	}
+	write(t, s, msg(hermod.OpCreate, `{"id":2}`))
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// A commit based on a stale version is refused.
+	if _, err := cat.CommitTable(t.Context(), base, Commit{}); !errors.Is(err, ErrCommitConflict) {
+		t.Fatalf("expected a commit conflict, got %v", err)
+	}
+	tbl, state := scan(t, cat)
+	if len(state) != 2 || len(tbl.Metadata.MetadataLog) != 2 {
+		t.Fatalf("expected 2 rows and 2 previous metadata files, got %v and %d", state, len(tbl.Metadata.MetadataLog))
+	}
+	if f, _ := tbl.Metadata.CurrentSchema().Field("at"); f.Primitive() != TypeString {
+		t.Fatalf("expected strings to stay strings, got %+v", f)
+	}
+}
+
+func TestMetadataKeepsUnknownFields(t *testing.T) {
+	m := NewMetadata("/tmp/t", newSchema([]column{{Name: "id", Type: TypeLong}}, nil))
+	b, _ := m.MarshalJSON()
+	var raw map[string]any
+	_ = json.Unmarshal(b, &raw)
+	raw["statistics"] = []any{map[string]any{"snapshot-id": 1}}
+	b, _ = json.Marshal(raw)
+
+	parsed, err := ParseMetadata(b)
+	if err != nil {
+		t.Fatalf("ParseMetadata: %v", err)
+	}
+	out, _ := parsed.apply(Commit{}, "/tmp/t/metadata/v1.metadata.json").MarshalJSON()
+	if !strings.Contains(string(out), `"statistics"`) {
+		t.Fatalf("expected statistics to survive a commit: %s", out)
+	}
+
+	raw["partition-specs"] = []any{map[string]any{"spec-id": 0, "fields": []any{map[string]any{"name": "day"}}}}
+	b, _ = json.Marshal(raw)
+	if _, err := ParseMetadata(b); err == nil {
+		t.Fatal("expected partitioned tables to be refused")
+	}
+}
+
+func TestInferAndNormalize(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		v    any
+		typ  string
+		want any
+	}{
+		{float64(3), TypeLong, int64(3)},
+		{1.5, TypeDouble, 1.5},
+		{true, TypeBoolean, true},
+		{ts, TypeTimestampTZ, ts.UnixMicro()},
+		{[]byte("x"), TypeBinary, []byte("x")},
+		{map[string]any{"a": 1}, TypeString, `{"a":1}`},
+	}
+	for _, c := range cases {
+		if got := inferType(c.v); got != c.typ {
+			t.Errorf("inferType(%v) = %s, want %s", c.v, got, c.typ)
+		}
+		got, err := normalize(c.v, c.typ)
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("normalize(%v, %s) = %v, %v; want %v", c.v, c.typ, got, err, c.want)
+		}
+	}
+	if _, err := normalize(1.5, TypeLong); err == nil {
+		t.Error("expected a fraction to be refused by a long column")
+	}
+}
+
+// restServer is a minimal REST catalog that applies commits with the same
+// code the filesystem catalogs use and records the commit requests it got.
+type restServer struct {
+	table    *Table
+	requests []map[string]any
+}
+
+func newRESTTestServer(t *testing.T, srv *restServer, warehouse string) string {
+	reply := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	loaded := func(w http.ResponseWriter) {
+		reply(w, map[string]any{"metadata-location": srv.table.MetadataLocation, "metadata": srv.table.Metadata})
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.URL.Path == "/v1/config":
+			reply(w, map[string]any{"overrides": map[string]string{"prefix": "main"}})
+		case r.URL.Path == "/v1/main/namespaces":
+			reply(w, map[string]any{})
+		case r.URL.Path == "/v1/main/namespaces/db/tables" && r.Method == http.MethodPost:
+			var schema Schema
+			_ = json.Unmarshal(body["schema"], &schema)
+			m := NewMetadata(warehouse+"/db/users", &schema)
+			srv.table = &Table{MetadataLocation: warehouse + "/db/users/metadata/00000.metadata.json", Metadata: m}
+			loaded(w)
+		case r.URL.Path == "/v1/main/namespaces/db/tables/users" && r.Method == http.MethodGet:
+			if srv.table == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			loaded(w)
+		case r.URL.Path == "/v1/main/namespaces/db/tables/users" && r.Method == http.MethodPost:
+			var req map[string]any
+			raw, _ := json.Marshal(body)
+			_ = json.Unmarshal(raw, &req)
+			srv.requests = append(srv.requests, req)
+			var updates []struct {
+				Action   string    `json:"action"`
+				Schema   *Schema   `json:"schema"`
+				Snapshot *Snapshot `json:"snapshot"`
+			}
+			_ = json.Unmarshal(body["updates"], &updates)
+			var c Commit
+			for _, u := range updates {
+				switch u.Action {
+				case "add-schema":
+					c.Schema = u.Schema
+				case "add-snapshot":
+					c.Snapshot = u.Snapshot
+				}
+			}
+			srv.table = &Table{MetadataLocation: srv.table.MetadataLocation, Metadata: srv.table.Metadata.apply(c, srv.table.MetadataLocation)}
+			loaded(w)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestRESTCatalog(t *testing.T) {
+	srv := &restServer{}
+	warehouse := t.TempDir()
+	ts := newRESTTestServer(t, srv, warehouse)
+
+	cat, err := NewRESTCatalog(RESTConfig{URI: ts, Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestSink(t, cat, t.TempDir(), "id")
+	write(t, s, msg(hermod.OpCreate, `{"id":1}`))
+	if err := s.CommitCheckpoint(t.Context()); err != nil {
+		t.Fatalf("CommitCheckpoint: %v", err)
+	}
+	write(t, s, msg(hermod.OpUpdate, `{"id":1,"name":"x"}`))
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if len(srv.requests) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(srv.requests))
+	}
+	second := srv.requests[1]
+	var actions []string
+	for _, u := range second["updates"].([]any) {
+		actions = append(actions, u.(map[string]any)["action"].(string))
+	}
+	sort.Strings(actions)
+	if strings.Join(actions, ",") != "add-schema,add-snapshot,set-current-schema,set-snapshot-ref" {
+		t.Fatalf("unexpected updates %v", actions)
+	}
+	var snapshotReq bool
+	for _, r := range second["requirements"].([]any) {
+		r := r.(map[string]any)
+		if r["type"] == "assert-ref-snapshot-id" && r["snapshot-id"] != nil {
+			snapshotReq = true
+		}
+	}
+	if !snapshotReq {
+		t.Fatalf("expected the commit to assert the main branch, got %v", second["requirements"])
+	}
+	if _, state := scan(t, cat); state["1"]["name"] != "x" {
+		t.Fatalf("expected the update to be visible, got %v", state)
+	}
+}
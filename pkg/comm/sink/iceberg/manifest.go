@@ -0,0 +1,213 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+)
+
+// Content types of data files and manifests.
+const (
+	contentData            = 0
+	contentEqualityDeletes = 2
+
+	manifestContentData    = 0
+	manifestContentDeletes = 1
+)
+
+// Avro schemas of format version 2 manifests and manifest lists, with the
+// field IDs readers resolve columns by.
+var (
+	manifestEntrySchema = avro.MustParse(`{
+		"type": "record", "name": "manifest_entry", "fields": [
+			{"name": "status", "type": "int", "field-id": 0},
+			{"name": "snapshot_id", "type": ["null", "long"], "default": null, "field-id": 1},
+			{"name": "sequence_number", "type": ["null", "long"], "default": null, "field-id": 3},
+			{"name": "file_sequence_number", "type": ["null", "long"], "default": null, "field-id": 4},
+			{"name": "data_file", "field-id": 2, "type": {
+				"type": "record", "name": "r2", "fields": [
+					{"name": "content", "type": "int", "field-id": 134},
+					{"name": "file_path", "type": "string", "field-id": 100},
+					{"name": "file_format", "type": "string", "field-id": 101},
+					{"name": "partition", "field-id": 102, "type": {"type": "record", "name": "r102", "fields": []}},
+					{"name": "record_count", "type": "long", "field-id": 103},
+					{"name": "file_size_in_bytes", "type": "long", "field-id": 104},
+					{"name": "equality_ids", "default": null, "field-id": 135,
+						"type": ["null", {"type": "array", "items": "int", "element-id": 136}]}
+				]
+			}}
+		]
+	}`)
+
+	manifestFileSchema = avro.MustParse(`{
+		"type": "record", "name": "manifest_file", "fields": [
+			{"name": "manifest_path", "type": "string", "field-id": 500},
+			{"name": "manifest_length", "type": "long", "field-id": 501},
+			{"name": "partition_spec_id", "type": "int", "field-id": 502},
+			{"name": "content", "type": "int", "field-id": 517},
+			{"name": "sequence_number", "type": "long", "field-id": 515},
+			{"name": "min_sequence_number", "type": "long", "field-id": 516},
+			{"name": "added_snapshot_id", "type": "long", "field-id": 503},
+			{"name": "added_files_count", "type": "int", "field-id": 504},
+			{"name": "existing_files_count", "type": "int", "field-id": 505},
+			{"name": "deleted_files_count", "type": "int", "field-id": 506},
+			{"name": "added_rows_count", "type": "long", "field-id": 512},
+			{"name": "existing_rows_count", "type": "long", "field-id": 513},
+			{"name": "deleted_rows_count", "type": "long", "field-id": 514}
+		]
+	}`)
+)
+
+// entryAdded is the status of a manifest entry for a file added by the
+// manifest's snapshot.
+const entryAdded = 1
+
+type manifestEntry struct {
+	Status             int32    `avro:"status"`
+	SnapshotID         *int64   `avro:"snapshot_id"`
+	SequenceNumber     *int64   `avro:"sequence_number"`
+	FileSequenceNumber *int64   `avro:"file_sequence_number"`
+	DataFile           DataFile `avro:"data_file"`
+}
+
+// DataFile describes a data or delete file written by the sink.
+type DataFile struct {
+	Content         int32          `avro:"content"`
+	FilePath        string         `avro:"file_path"`
+	FileFormat      string         `avro:"file_format"`
+	Partition       map[string]any `avro:"partition"`
+	RecordCount     int64          `avro:"record_count"`
+	FileSizeInBytes int64          `avro:"file_size_in_bytes"`
+	EqualityIDs     []int32        `avro:"equality_ids"`
+}
+
+// ManifestFile is an entry of a manifest list.
+type ManifestFile struct {
+	ManifestPath       string `avro:"manifest_path"`
+	ManifestLength     int64  `avro:"manifest_length"`
+	PartitionSpecID    int32  `avro:"partition_spec_id"`
+	Content            int32  `avro:"content"`
+	SequenceNumber     int64  `avro:"sequence_number"`
+	MinSequenceNumber  int64  `avro:"min_sequence_number"`
+	AddedSnapshotID    int64  `avro:"added_snapshot_id"`
+	AddedFilesCount    int32  `avro:"added_files_count"`
+	ExistingFilesCount int32  `avro:"existing_files_count"`
+	DeletedFilesCount  int32  `avro:"deleted_files_count"`
+	AddedRowsCount     int64  `avro:"added_rows_count"`
+	ExistingRowsCount  int64  `avro:"existing_rows_count"`
+	DeletedRowsCount   int64  `avro:"deleted_rows_count"`
+}
+
+// writeManifest writes a manifest of files, all added by snapshot snap,
+// and returns its manifest list entry. The entries leave their sequence
+// numbers unset, so they inherit the one of the manifest.
+func writeManifest(ctx context.Context, io FileIO, path string, schema *Schema, snap *Snapshot, content int32, files []DataFile) (ManifestFile, error) {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	contentName := "data"
+	if content == manifestContentDeletes {
+		contentName = "deletes"
+	}
+	var buf bytes.Buffer
+	enc, err := ocf.NewEncoderWithSchema(manifestEntrySchema, &buf,
+		ocf.WithSchemaMarshaler(ocf.FullSchemaMarshaler),
+		ocf.WithMetadata(map[string][]byte{
+			"schema":            schemaJSON,
+			"schema-id":         []byte(strconv.Itoa(schema.SchemaID)),
+			"partition-spec":    []byte("[]"),
+			"partition-spec-id": []byte("0"),
+			"format-version":    []byte("2"),
+			"content":           []byte(contentName),
+		}),
+	)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	mf := ManifestFile{
+		ManifestPath:      path,
+		Content:           content,
+		SequenceNumber:    snap.SequenceNumber,
+		MinSequenceNumber: snap.SequenceNumber,
+		AddedSnapshotID:   snap.SnapshotID,
+	}
+	for _, f := range files {
+		id := snap.SnapshotID
+		if f.Partition == nil {
+			f.Partition = map[string]any{}
+		}
+		if err := enc.Encode(manifestEntry{Status: entryAdded, SnapshotID: &id, DataFile: f}); err != nil {
+			return ManifestFile{}, fmt.Errorf("failed to encode manifest entry: %w", err)
+		}
+		mf.AddedFilesCount++
+		mf.AddedRowsCount += f.RecordCount
+	}
+	if err := enc.Close(); err != nil {
+		return ManifestFile{}, err
+	}
+	if err := io.Write(ctx, path, buf.Bytes()); err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	mf.ManifestLength = int64(buf.Len())
+	return mf, nil
+}
+
+// writeManifestList writes the manifest list of snap.
+func writeManifestList(ctx context.Context, io FileIO, path string, snap *Snapshot, manifests []ManifestFile) error {
+	parent := "null"
+	if snap.ParentSnapshotID != nil {
+		parent = strconv.FormatInt(*snap.ParentSnapshotID, 10)
+	}
+	var buf bytes.Buffer
+	enc, err := ocf.NewEncoderWithSchema(manifestFileSchema, &buf,
+		ocf.WithSchemaMarshaler(ocf.FullSchemaMarshaler),
+		ocf.WithMetadata(map[string][]byte{
+			"snapshot-id":        []byte(strconv.FormatInt(snap.SnapshotID, 10)),
+			"parent-snapshot-id": []byte(parent),
+			"sequence-number":    []byte(strconv.FormatInt(snap.SequenceNumber, 10)),
+			"format-version":     []byte("2"),
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	for _, m := range manifests {
+		if err := enc.Encode(m); err != nil {
+			return fmt.Errorf("failed to encode manifest list entry: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if err := io.Write(ctx, path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write manifest list: %w", err)
+	}
+	return nil
+}
+
+// readManifestList reads the manifests of a snapshot.
+func readManifestList(ctx context.Context, io FileIO, path string) ([]ManifestFile, error) {
+	b, err := io.Read(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest list: %w", err)
+	}
+	dec, err := ocf.NewDecoder(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest list %s: %w", path, err)
+	}
+	var out []ManifestFile
+	for dec.HasNext() {
+		var m ManifestFile
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("invalid manifest list %s: %w", path, err)
+		}
+		out = append(out, m)
+	}
+	return out, dec.Error()
+}
@@ -0,0 +1,247 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Metadata is the table metadata file of a format version 2 table. Fields
+// the sink does not use are kept as they are, so committing never drops
+// what other engines recorded.
+type Metadata struct {
+	FormatVersion      int                `json:"format-version"`
+	TableUUID          string             `json:"table-uuid"`
+	Location           string             `json:"location"`
+	LastSequenceNumber int64              `json:"last-sequence-number"`
+	LastUpdatedMs      int64              `json:"last-updated-ms"`
+	LastColumnID       int                `json:"last-column-id"`
+	CurrentSchemaID    int                `json:"current-schema-id"`
+	Schemas            []*Schema          `json:"schemas"`
+	DefaultSpecID      int                `json:"default-spec-id"`
+	PartitionSpecs     []PartitionSpec    `json:"partition-specs"`
+	LastPartitionID    int                `json:"last-partition-id"`
+	DefaultSortOrderID int                `json:"default-sort-order-id"`
+	SortOrders         []json.RawMessage  `json:"sort-orders"`
+	Properties         map[string]string  `json:"properties,omitempty"`
+	CurrentSnapshotID  *int64             `json:"current-snapshot-id,omitempty"`
+	Snapshots          []*Snapshot        `json:"snapshots,omitempty"`
+	SnapshotLog        []SnapshotLogEntry `json:"snapshot-log,omitempty"`
+	MetadataLog        []MetadataLogEntry `json:"metadata-log,omitempty"`
+	Refs               map[string]Ref     `json:"refs,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+// PartitionSpec is a partition spec; the sink only writes to unpartitioned
+// tables, whose default spec has no fields.
+type PartitionSpec struct {
+	SpecID int               `json:"spec-id"`
+	Fields []json.RawMessage `json:"fields"`
+}
+
+// Snapshot is a table snapshot.
+type Snapshot struct {
+	SnapshotID       int64             `json:"snapshot-id"`
+	ParentSnapshotID *int64            `json:"parent-snapshot-id,omitempty"`
+	SequenceNumber   int64             `json:"sequence-number"`
+	TimestampMs      int64             `json:"timestamp-ms"`
+	ManifestList     string            `json:"manifest-list"`
+	Summary          map[string]string `json:"summary"`
+	SchemaID         *int              `json:"schema-id,omitempty"`
+}
+
+// Ref is a named branch or tag.
+type Ref struct {
+	SnapshotID int64  `json:"snapshot-id"`
+	Type       string `json:"type"`
+}
+
+type SnapshotLogEntry struct {
+	TimestampMs int64 `json:"timestamp-ms"`
+	SnapshotID  int64 `json:"snapshot-id"`
+}
+
+type MetadataLogEntry struct {
+	TimestampMs  int64  `json:"timestamp-ms"`
+	MetadataFile string `json:"metadata-file"`
+}
+
+// Snapshot operations.
+const (
+	OpAppend    = "append"
+	OpOverwrite = "overwrite"
+	OpDelete    = "delete"
+)
+
+type metadataFields Metadata
+
+var metadataKeys = func() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(metadataFields{})
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("json"); tag != "" {
+			keys[strings.Split(tag, ",")[0]] = true
+		}
+	}
+	return keys
+}()
+
+func (m *Metadata) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, (*metadataFields)(m)); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	for k := range all {
+		if metadataKeys[k] {
+			delete(all, k)
+		}
+	}
+	m.extra = all
+	// Some writers record "no current snapshot" as -1.
+	if m.CurrentSnapshotID != nil && *m.CurrentSnapshotID == -1 {
+		m.CurrentSnapshotID = nil
+	}
+	return nil
+}
+
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(metadataFields(m))
+	if err != nil || len(m.extra) == 0 {
+		return b, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	for k, v := range m.extra {
+		all[k] = v
+	}
+	return json.Marshal(all)
+}
+
+// ParseMetadata parses a table metadata file and checks that the sink can
+// write to the table.
+func ParseMetadata(b []byte) (*Metadata, error) {
+	var m Metadata
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid table metadata: %w", err)
+	}
+	if m.FormatVersion != 2 {
+		return nil, fmt.Errorf("table format version %d is not supported, only 2 is", m.FormatVersion)
+	}
+	for _, spec := range m.PartitionSpecs {
+		if spec.SpecID == m.DefaultSpecID && len(spec.Fields) > 0 {
+			return nil, errors.New("partitioned tables are not supported")
+		}
+	}
+	if m.CurrentSchema() == nil {
+		return nil, fmt.Errorf("table metadata has no schema %d", m.CurrentSchemaID)
+	}
+	return &m, nil
+}
+
+// NewMetadata returns the metadata of a new, empty table.
+func NewMetadata(location string, schema *Schema) *Metadata {
+	lastID := 0
+	for _, f := range schema.Fields {
+		lastID = max(lastID, f.ID)
+	}
+	return &Metadata{
+		FormatVersion:   2,
+		TableUUID:       uuid.NewString(),
+		Location:        strings.TrimSuffix(location, "/"),
+		LastUpdatedMs:   time.Now().UnixMilli(),
+		LastColumnID:    lastID,
+		CurrentSchemaID: schema.SchemaID,
+		Schemas:         []*Schema{schema},
+		PartitionSpecs:  []PartitionSpec{{SpecID: 0, Fields: []json.RawMessage{}}},
+		LastPartitionID: 999,
+		SortOrders:      []json.RawMessage{json.RawMessage(`{"order-id":0,"fields":[]}`)},
+		Properties:      map[string]string{"write.format.default": "parquet"},
+	}
+}
+
+// CurrentSchema returns the table's current schema.
+func (m *Metadata) CurrentSchema() *Schema {
+	for _, s := range m.Schemas {
+		if s.SchemaID == m.CurrentSchemaID {
+			return s
+		}
+	}
+	return nil
+}
+
+// CurrentSnapshot returns the snapshot the main branch points at, or nil for
+// an empty table.
+func (m *Metadata) CurrentSnapshot() *Snapshot {
+	id := m.CurrentSnapshotID
+	if ref, ok := m.Refs["main"]; ok {
+		id = &ref.SnapshotID
+	}
+	if id == nil {
+		return nil
+	}
+	for _, s := range m.Snapshots {
+		if s.SnapshotID == *id {
+			return s
+		}
+	}
+	return nil
+}
+
+// Commit is a change to a table: an optional new current schema and a new
+// snapshot of the main branch.
+type Commit struct {
+	Schema   *Schema
+	Snapshot *Snapshot
+}
+
+// apply returns a copy of m with c applied, for catalogs that write the
+// metadata file themselves. previous is the location of m's own file.
+func (m *Metadata) apply(c Commit, previous string) *Metadata {
+	out := *m
+	now := time.Now().UnixMilli()
+	if previous != "" {
+		out.MetadataLog = append(append([]MetadataLogEntry(nil), m.MetadataLog...), MetadataLogEntry{TimestampMs: m.LastUpdatedMs, MetadataFile: previous})
+	}
+	if c.Schema != nil {
+		out.Schemas = append(append([]*Schema(nil), m.Schemas...), c.Schema)
+		out.CurrentSchemaID = c.Schema.SchemaID
+		for _, f := range c.Schema.Fields {
+			out.LastColumnID = max(out.LastColumnID, f.ID)
+		}
+	}
+	if s := c.Snapshot; s != nil {
+		out.Snapshots = append(append([]*Snapshot(nil), m.Snapshots...), s)
+		out.SnapshotLog = append(append([]SnapshotLogEntry(nil), m.SnapshotLog...), SnapshotLogEntry{TimestampMs: s.TimestampMs, SnapshotID: s.SnapshotID})
+		out.LastSequenceNumber = s.SequenceNumber
+		id := s.SnapshotID
+		out.CurrentSnapshotID = &id
+		out.Refs = make(map[string]Ref, len(m.Refs)+1)
+		for k, v := range m.Refs {
+			out.Refs[k] = v
+		}
+		out.Refs["main"] = Ref{SnapshotID: id, Type: "branch"}
+	}
+	out.LastUpdatedMs = now
+	return &out
+}
+
+// committed reports whether a snapshot of the table carries commitID.
+func (m *Metadata) committed(commitID string) bool {
+	for _, s := range m.Snapshots {
+		if s.Summary[summaryCommitID] == commitID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package iceberg
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+
+	"github.com/user/hermod/pkg/infra/columnar"
+)
+
+// Primitive Iceberg types the sink writes.
+const (
+	TypeBoolean     = "boolean"
+	TypeInt         = "int"
+	TypeLong        = "long"
+	TypeFloat       = "float"
+	TypeDouble      = "double"
+	TypeDate        = "date"
+	TypeTimestamp   = "timestamp"
+	TypeTimestampTZ = "timestamptz"
+	TypeString      = "string"
+	TypeBinary      = "binary"
+)
+
+// Schema is an Iceberg table schema.
+type Schema struct {
+	Type               string  `json:"type"`
+	SchemaID           int     `json:"schema-id"`
+	IdentifierFieldIDs []int   `json:"identifier-field-ids,omitempty"`
+	Fields             []Field `json:"fields"`
+}
+
+// Field is a top-level column. Type is a primitive type name, or the JSON
+// object of a nested type for tables created by other engines.
+type Field struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	Type     any    `json:"type"`
+	Doc      string `json:"doc,omitempty"`
+}
+
+// Primitive returns the field's type name, or "" for nested types.
+func (f Field) Primitive() string {
+	s, _ := f.Type.(string)
+	return s
+}
+
+// Field returns the column called name.
+func (s *Schema) Field(name string) (Field, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// FieldByID returns the column with the given ID.
+func (s *Schema) FieldByID(id int) (Field, bool) {
+	for _, f := range s.Fields {
+		if f.ID == id {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// column is a column added by incoming rows and not yet committed.
+type column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// evolve returns a copy of s with an optional field for each column it lacks,
+// numbered from lastColumnID, and the new last column ID. It returns s
+// itself when every column exists.
+func evolve(s *Schema, cols []column, lastColumnID int) (*Schema, int) {
+	var out *Schema
+	for _, c := range cols {
+		if _, ok := s.Field(c.Name); ok {
+			continue
+		}
+		if out == nil {
+			out = &Schema{
+				Type:               "struct",
+				SchemaID:           s.SchemaID + 1,
+				IdentifierFieldIDs: s.IdentifierFieldIDs,
+				Fields:             append([]Field(nil), s.Fields...),
+			}
+			s = out
+		}
+		lastColumnID++
+		out.Fields = append(out.Fields, Field{ID: lastColumnID, Name: c.Name, Type: c.Type})
+	}
+	if out == nil {
+		return s, lastColumnID
+	}
+	return out, lastColumnID
+}
+
+// newSchema builds the schema of a new table from cols. Key columns are
+// required and become the schema's identifier fields.
+func newSchema(cols []column, keys []string) *Schema {
+	s := &Schema{Type: "struct"}
+	for i, c := range cols {
+		f := Field{ID: i + 1, Name: c.Name, Type: c.Type}
+		for _, k := range keys {
+			if k == c.Name {
+				f.Required = true
+				s.IdentifierFieldIDs = append(s.IdentifierFieldIDs, f.ID)
+			}
+		}
+		s.Fields = append(s.Fields, f)
+	}
+	return s
+}
+
+// inferType picks the Iceberg type of a column first seen with value v, as
+// columnar.Infer reads it. Text stays text, whatever it looks like, since
+// later rows need not parse the same way, and nested values are stored as
+// JSON strings.
+func inferType(v any) string {
+	if _, ok := v.(string); ok {
+		return TypeString
+	}
+	switch columnar.Infer(v) {
+	case columnar.KindBoolean:
+		return TypeBoolean
+	case columnar.KindLong:
+		return TypeLong
+	case columnar.KindDouble:
+		return TypeDouble
+	case columnar.KindTimestampTZ:
+		return TypeTimestampTZ
+	case columnar.KindBinary:
+		return TypeBinary
+	}
+	return TypeString
+}
+
+// normalize converts v to the JSON representation of a value of type typ,
+// as it is spooled and written to Parquet: integers for int, long, date
+// (days) and timestamps (microseconds), []byte for binary. It fails when v
+// cannot be stored in such a column.
+func normalize(v any, typ string) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch typ {
+	case TypeString:
+		return columnar.String(v)
+	case TypeBoolean:
+		if b, ok := columnar.Bool(v); ok {
+			return b, nil
+		}
+	case TypeInt, TypeLong:
+		n, ok := columnar.Int64(v)
+		if !ok {
+			break
+		}
+		if typ == TypeInt && (n < math.MinInt32 || n > math.MaxInt32) {
+			return nil, fmt.Errorf("value %d overflows an int column", n)
+		}
+		return n, nil
+	case TypeFloat, TypeDouble:
+		if f, ok := columnar.Float64(v); ok {
+			return f, nil
+		}
+	case TypeDate:
+		if t, ok := columnar.Date(v); ok {
+			return t.UTC().Unix() / 86400, nil
+		}
+	case TypeTimestamp, TypeTimestampTZ:
+		if t, ok := columnar.Time(v); ok {
+			return t.UnixMicro(), nil
+		}
+	case TypeBinary:
+		switch x := v.(type) {
+		case []byte:
+			return x, nil
+		case string:
+			return []byte(x), nil
+		}
+	default:
+		return nil, fmt.Errorf("columns of type %v are not supported", typ)
+	}
+	return nil, fmt.Errorf("cannot store %T value %v in a %s column", v, v, typ)
+}
+
+// decodeSpooled turns a spooled value, decoded from JSON with UseNumber,
+// back into the value normalize produced.
+func decodeSpooled(v any, typ string) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch typ {
+	case TypeInt, TypeLong, TypeDate, TypeTimestamp, TypeTimestampTZ:
+		if n, ok := columnar.Int64(v); ok {
+			return n, nil
+		}
+	case TypeFloat, TypeDouble:
+		if f, ok := columnar.Float64(v); ok {
+			return f, nil
+		}
+	case TypeBinary:
+		if s, ok := v.(string); ok {
+			return base64.StdEncoding.DecodeString(s)
+		}
+	case TypeBoolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case TypeString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("spooled value %v does not match column type %s", v, typ)
+}
@@ -0,0 +1,110 @@
+package iceberg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// spool is the local file of rows accepted since the last commit, one JSON
+// record per line. Its name is the commit ID of the snapshot it becomes;
+// IDs are time-ordered UUIDs, so leftover spools sort in write order.
+type spool struct {
+	id   string
+	path string
+	f    *os.File
+	size int64
+}
+
+const spoolExt = ".spool"
+
+func spoolPath(dir, id string) string {
+	return filepath.Join(dir, id+spoolExt)
+}
+
+// append writes recs to the current spool, opening one if needed, and syncs
+// it to disk.
+func (s *IcebergSink) append(recs []record) error {
+	if s.spool == nil {
+		id := uuid.Must(uuid.NewV7()).String()
+		path := spoolPath(s.cfg.SpoolDir, id)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to create spool: %w", err)
+		}
+		s.spool = &spool{id: id, path: path, f: f}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range recs {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode row: %w", err)
+		}
+	}
+	if _, err := s.spool.f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+	if err := s.spool.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool: %w", err)
+	}
+	s.spool.size += int64(buf.Len())
+	return nil
+}
+
+func (sp *spool) close() {
+	_ = sp.f.Close()
+}
+
+func (sp *spool) remove() error {
+	sp.close()
+	return os.Remove(sp.path)
+}
+
+// readSpool reads the records of a spool file. A torn last line, left by a
+// crash mid-write, is ignored: the write it belonged to never returned.
+func readSpool(path string) ([]record, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool: %w", err)
+	}
+	var recs []record
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), len(b)+1)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			break
+		}
+		recs = append(recs, r)
+	}
+	return recs, sc.Err()
+}
+
+// listSpools returns the IDs of the spools in dir, oldest first.
+func listSpools(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spools: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), spoolExt) {
+			ids = append(ids, strings.TrimSuffix(e.Name(), spoolExt))
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
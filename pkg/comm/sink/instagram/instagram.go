@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 // InstagramSink implements the hermod.Sink interface for Instagram.
@@ -69,7 +70,7 @@ func (s *InstagramSink) Write(ctx context.Context, msg hermod.Message) error {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -107,7 +108,7 @@ func (s *InstagramSink) Write(ctx context.Context, msg hermod.Message) error {
 	if err != nil {
 		return err
 	}
-	resp, err = http.DefaultClient.Do(req)
+	resp, err = httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -149,7 +150,7 @@ func (s *InstagramSink) Ping(ctx context.Context) error {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,71 @@
+package kafka
+
+import (
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/user/hermod"
+)
+
+// ClassifyError implements hermod.ErrorClassifier using the broker's error
+// codes. When a batch fails with per-message errors the most severe class
+// wins, so a single rejected record still sends the batch to bisection.
+func (s *KafkaSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	var werrs kafka.WriteErrors
+	if errors.As(err, &werrs) {
+		worst := hermod.ErrorTransient
+		for _, e := range werrs {
+			if e == nil {
+				continue
+			}
+			if class := classifyKafkaError(e); severity(class) > severity(worst) {
+				worst = class
+			}
+		}
+		return worst, 0
+	}
+	return classifyKafkaError(err), 0
+}
+
+func classifyKafkaError(err error) hermod.ErrorClass {
+	var kerr kafka.Error
+	if !errors.As(err, &kerr) {
+		return hermod.ErrorTransient
+	}
+	switch kerr {
+	case kafka.InvalidMessage, // corrupt message
+		kafka.MessageSizeTooLarge,
+		kafka.RecordListTooLarge,
+		kafka.InvalidRecord,
+		kafka.PolicyViolation:
+		return hermod.ErrorPermanent
+	case kafka.TopicAuthorizationFailed,
+		kafka.ClusterAuthorizationFailed,
+		kafka.TransactionalIDAuthorizationFailed,
+		kafka.SASLAuthenticationFailed,
+		kafka.UnsupportedSASLMechanism,
+		kafka.IllegalSASLState,
+		kafka.InvalidTopic,
+		kafka.InvalidRequiredAcks:
+		return hermod.ErrorFatalConfig
+	case kafka.ThrottlingQuotaExceeded:
+		return hermod.ErrorThrottled
+	}
+	// Leadership changes, network errors and timeouts are worth retrying.
+	return hermod.ErrorTransient
+}
+
+// severity orders classes by how much of the batch they condemn.
+func severity(c hermod.ErrorClass) int {
+	switch c {
+	case hermod.ErrorFatalConfig:
+		return 3
+	case hermod.ErrorPermanent:
+		return 2
+	case hermod.ErrorThrottled:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,29 @@
+package kafka
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/user/hermod"
+)
+
+func TestKafkaSink_ClassifyError(t *testing.T) {
+	s := &KafkaSink{}
+	tests := []struct {
+		name  string
+		err   error
+		class hermod.ErrorClass
+	}{
+		{"too large", fmt.Errorf("failed to write batch to kafka: %w", kafka.MessageSizeTooLarge), hermod.ErrorPermanent},
+		{"auth", kafka.SASLAuthenticationFailed, hermod.ErrorFatalConfig},
+		{"quota", kafka.ThrottlingQuotaExceeded, hermod.ErrorThrottled},
+		{"leader", kafka.NotLeaderForPartition, hermod.ErrorTransient},
+		{"one poison record", kafka.WriteErrors{nil, kafka.InvalidRecord, kafka.RequestTimedOut}, hermod.ErrorPermanent},
+	}
+	for _, tt := range tests {
+		if class, _ := s.ClassifyError(tt.err); class != tt.class {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.class, class)
+		}
+	}
+}
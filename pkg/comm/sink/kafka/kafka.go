@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -15,6 +16,9 @@ type KafkaSink struct {
 	transport       *kafka.Transport
 	formatter       hermod.Formatter
 	transactionalID string
+	// fencingToken is the lease token prepared transactions are stamped
+	// with. Kafka has no place to record it, so writes are not rejected.
+	fencingToken atomic.Int64
 }
 
 func NewKafkaSink(brokers []string, topic string, username, password string, formatter hermod.Formatter, transactionalID string) *KafkaSink {
@@ -61,10 +65,19 @@ func (s *KafkaSink) Prepare(ctx context.Context) (string, error) {
 	// 2PC Prepare for Kafka usually means finishing the transaction locally
 	// and returning a unique ID. Since Kafka transactions are atomic on commit,
 	// we use a generated txID for the 2PC manager.
+	if token := s.fencingToken.Load(); token > 0 {
+		return fmt.Sprintf("kafka-tx-%d-%d", token, time.Now().UnixNano()), nil
+	}
 	txID := fmt.Sprintf("kafka-tx-%d", time.Now().UnixNano())
 	return txID, nil
 }
 
+// SetFencingToken implements hermod.FencedSink by stamping the token into
+// the transaction IDs Prepare hands out.
+func (s *KafkaSink) SetFencingToken(scope string, token int64) {
+	s.fencingToken.Store(token)
+}
+
 func (s *KafkaSink) CommitPrepared(ctx context.Context, txID string) error {
 	return s.Commit(ctx)
 }
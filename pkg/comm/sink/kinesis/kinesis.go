@@ -2,13 +2,16 @@ package kinesis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
 	"github.com/user/hermod"
 )
 
@@ -103,6 +106,104 @@ func (s *KinesisSink) Write(ctx context.Context, msg hermod.Message) error {
 	return nil
 }
 
+// maxPutRecords is the most records a PutRecords call accepts.
+const maxPutRecords = 500
+
+func (s *KinesisSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults writes msgs with PutRecords, which accepts or rejects
+// each record on its own, and reports the outcome of every message.
+func (s *KinesisSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+	if err := s.ensureConnected(ctx); err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(msgs))
+	// sent maps each record back to its message.
+	sent := make([]int, 0, len(msgs))
+	entries := make([]types.PutRecordsRequestEntry, 0, len(msgs))
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		var data []byte
+		var err error
+		if s.formatter != nil {
+			data, err = s.formatter.Format(msg)
+		} else {
+			data = msg.Payload()
+		}
+		if err != nil {
+			errs[i] = hermod.Classify(fmt.Errorf("failed to format message: %w", err), hermod.ErrorPermanent)
+			continue
+		}
+
+		partitionKey := msg.ID()
+		if partitionKey == "" {
+			partitionKey = "default"
+		}
+		entries = append(entries, types.PutRecordsRequestEntry{Data: data, PartitionKey: aws.String(partitionKey)})
+		sent = append(sent, i)
+	}
+
+	for start := 0; start < len(entries); start += maxPutRecords {
+		end := min(start+maxPutRecords, len(entries))
+		out, err := s.client.PutRecords(ctx, &kinesis.PutRecordsInput{
+			Records:    entries[start:end],
+			StreamName: aws.String(s.streamName),
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to put records to kinesis: %w", err)
+			if len(sent) == len(msgs) && len(entries) <= maxPutRecords {
+				return nil, err
+			}
+			for _, i := range sent[start:end] {
+				errs[i] = err
+			}
+			continue
+		}
+		for k, rec := range out.Records {
+			if rec.ErrorCode == nil || start+k >= end {
+				continue
+			}
+			err := fmt.Errorf("failed to put record to kinesis: %s: %s", aws.ToString(rec.ErrorCode), aws.ToString(rec.ErrorMessage))
+			if aws.ToString(rec.ErrorCode) == "ProvisionedThroughputExceededException" {
+				err = hermod.Throttled(err, 0)
+			}
+			errs[sent[start+k]] = err
+		}
+	}
+	return errs, nil
+}
+
+// ClassifyError implements hermod.ErrorClassifier for the Kinesis API's
+// errors.
+func (s *KinesisSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	var (
+		notFound    *types.ResourceNotFoundException
+		denied      *types.AccessDeniedException
+		kmsDenied   *types.KMSAccessDeniedException
+		throughput  *types.ProvisionedThroughputExceededException
+		limit       *types.LimitExceededException
+		kmsThrottle *types.KMSThrottlingException
+		invalid     *types.InvalidArgumentException
+	)
+	switch {
+	case errors.As(err, &notFound), errors.As(err, &denied), errors.As(err, &kmsDenied):
+		return hermod.ErrorFatalConfig, 0
+	case errors.As(err, &throughput), errors.As(err, &limit), errors.As(err, &kmsThrottle):
+		return hermod.ErrorThrottled, 0
+	case errors.As(err, &invalid):
+		return hermod.ErrorPermanent, 0
+	}
+	return hermod.ErrorTransient, 0
+}
+
 func (s *KinesisSink) Ping(ctx context.Context) error {
 	if err := s.ensureConnected(ctx); err != nil {
 		return err
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 // LinkedInSink implements the hermod.Sink interface for LinkedIn.
@@ -78,7 +79,7 @@ func (s *LinkedInSink) Write(ctx context.Context, msg hermod.Message) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Restli-Protocol-Version", "2.0.0")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -116,7 +117,7 @@ func (s *LinkedInSink) Ping(ctx context.Context) error {
 	}
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
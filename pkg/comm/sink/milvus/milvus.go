@@ -3,6 +3,8 @@ package milvus
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
@@ -67,8 +69,16 @@ func (s *Sink) Write(ctx context.Context, msg hermod.Message) error {
 }
 
 func (s *Sink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults writes msgs with one insert and one delete call and
+// rejects each message whose vector is missing or malformed. Milvus does not
+// say which row a failed call choked on, so its error is returned for the
+// whole batch, which the engine retries or splits.
+func (s *Sink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
 	if len(msgs) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	s.mu.Lock()
@@ -76,7 +86,7 @@ func (s *Sink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
 	s.mu.Unlock()
 	if cl == nil {
 		if err := s.init(ctx); err != nil {
-			return err
+			return nil, err
 		}
 		s.mu.Lock()
 		cl = s.client
@@ -84,13 +94,16 @@ func (s *Sink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
 	}
 
 	// Prepare data for columnar insert
-	var ids []int64
-	var stringIDs []string
+	errs := make([]error, len(msgs))
+	var idVals []string
 	var vectors [][]float32
 
-	isStringID := false
+	var deletes []string
 
-	for _, msg := range msgs {
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
 		data := msg.Data()
 
 		// Map ID
@@ -101,42 +114,86 @@ func (s *Sink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
 			}
 		}
 
+		if msg.Operation() == hermod.OpDelete {
+			deletes = append(deletes, idVal)
+			continue
+		}
+
 		// Map Vector
 		vecVal, ok := data[s.config.VectorColumn]
 		if !ok {
-			return fmt.Errorf("vector column %s not found in message", s.config.VectorColumn)
+			errs[i] = hermod.Classify(fmt.Errorf("vector column %s not found in message", s.config.VectorColumn), hermod.ErrorPermanent)
+			continue
 		}
 
 		floatVec := toFloat32Slice(vecVal)
 		if floatVec == nil {
-			return fmt.Errorf("invalid vector format for column %s", s.config.VectorColumn)
+			errs[i] = hermod.Classify(fmt.Errorf("invalid vector format for column %s", s.config.VectorColumn), hermod.ErrorPermanent)
+			continue
+		}
+		if len(vectors) > 0 && len(floatVec) != len(vectors[0]) {
+			errs[i] = hermod.Classify(fmt.Errorf("vector in column %s has %d dimensions, expected %d", s.config.VectorColumn, len(floatVec), len(vectors[0])), hermod.ErrorPermanent)
+			continue
 		}
 
 		vectors = append(vectors, floatVec)
+		idVals = append(idVals, idVal)
+	}
 
-		// Handle ID types (Milvus supports Int64 or VarChar)
-		var i64 int64
-		if _, err := fmt.Sscanf(idVal, "%d", &i64); err == nil {
-			ids = append(ids, i64)
+	if len(vectors) > 0 {
+		// Milvus supports Int64 or VarChar primary keys; use Int64 only when
+		// every key in the batch is numeric.
+		var columns []entity.Column
+		if ids, ok := int64IDs(idVals); ok {
+			columns = append(columns, entity.NewColumnInt64(s.config.IDColumn, ids))
 		} else {
-			isStringID = true
-			stringIDs = append(stringIDs, idVal)
+			columns = append(columns, entity.NewColumnVarChar(s.config.IDColumn, idVals))
+		}
+
+		columns = append(columns, entity.NewColumnFloatVector(s.config.VectorColumn, len(vectors[0]), vectors))
+
+		if _, err := cl.Insert(ctx, s.config.CollectionName, s.config.PartitionName, columns...); err != nil {
+			return nil, err
 		}
 	}
 
-	var columns []entity.Column
-	if isStringID {
-		columns = append(columns, entity.NewColumnVarChar(s.config.IDColumn, stringIDs))
-	} else if len(ids) > 0 {
-		columns = append(columns, entity.NewColumnInt64(s.config.IDColumn, ids))
-	} else {
-		columns = append(columns, entity.NewColumnVarChar(s.config.IDColumn, stringIDs))
+	if len(deletes) > 0 {
+		if err := cl.Delete(ctx, s.config.CollectionName, s.config.PartitionName, deleteExpr(s.config.IDColumn, deletes)); err != nil {
+			return nil, err
+		}
 	}
+	return errs, nil
+}
 
-	columns = append(columns, entity.NewColumnFloatVector(s.config.VectorColumn, len(vectors[0]), vectors))
+// int64IDs parses ids as integers, reporting false if any is not one.
+func int64IDs(ids []string) ([]int64, bool) {
+	out := make([]int64, len(ids))
+	for i, id := range ids {
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
 
-	_, err := cl.Insert(ctx, s.config.CollectionName, s.config.PartitionName, columns...)
-	return err
+// deleteExpr builds the boolean expression selecting the given primary keys.
+// Keys are compared as integers when all of them parse as one, matching how
+// WriteBatch chooses the primary key column type.
+func deleteExpr(idColumn string, ids []string) string {
+	quoted := make([]string, len(ids))
+	numeric := true
+	for i, id := range ids {
+		if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+			numeric = false
+		}
+		quoted[i] = strconv.Quote(id)
+	}
+	if numeric {
+		return fmt.Sprintf("%s in [%s]", idColumn, strings.Join(ids, ","))
+	}
+	return fmt.Sprintf("%s in [%s]", idColumn, strings.Join(quoted, ","))
 }
 
 func (s *Sink) Ping(ctx context.Context) error {
@@ -0,0 +1,42 @@
+package mssql
+
+import (
+	"errors"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/user/hermod"
+)
+
+// ClassifyError implements hermod.ErrorClassifier using the server's error
+// number.
+func (s *MSSQLSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	return classifyMSSQLError(err)
+}
+
+func classifyMSSQLError(err error) (hermod.ErrorClass, time.Duration) {
+	var msErr mssql.Error
+	if !errors.As(err, &msErr) {
+		return hermod.ErrorTransient, 0
+	}
+	switch msErr.Number {
+	case 2627, 2601, // primary key and unique index violations
+		547,        // foreign key or check constraint violation
+		515,        // NULL into a NOT NULL column
+		8152, 2628, // string or binary data would be truncated
+		245: // conversion failed
+		return hermod.ErrorPermanent, 0
+	case 18456, // login failed
+		4060, // cannot open database
+		208,  // invalid object name
+		207,  // invalid column name
+		229:  // permission denied
+		return hermod.ErrorFatalConfig, 0
+	case 10928, 10929, // Azure SQL resource limits
+		40501: // Azure SQL service busy
+		// Azure asks clients to wait at least ten seconds before retrying.
+		return hermod.ErrorThrottled, 10 * time.Second
+	}
+	// Deadlock victims (1205) and anything unrecognised are worth retrying.
+	return hermod.ErrorTransient, 0
+}
@@ -28,6 +28,13 @@ type MSSQLSink struct {
 	operationMode    string
 	autoTruncate     bool
 	autoSync         bool
+
+	// mappingsMu guards mappings, which ApplySchemaChange replaces. It is
+	// separate from mu because ensureTable reads them while holding mu.
+	mappingsMu sync.RWMutex
+	// configured are the mappings the sink was created with, which schema
+	// changes evolve from.
+	configured []sqlutil.ColumnMapping
 }
 
 func NewMSSQLSink(connString string, tableName string, mappings []sqlutil.ColumnMapping, useExistingTable bool, deleteStrategy string, softDeleteColumn string, softDeleteValue string, operationMode string, autoTruncate bool, autoSync bool) *MSSQLSink {
@@ -38,6 +45,7 @@ func NewMSSQLSink(connString string, tableName string, mappings []sqlutil.Column
 		connString:       connString,
 		tableName:        tableName,
 		mappings:         mappings,
+		configured:       mappings,
 		useExistingTable: useExistingTable,
 		deleteStrategy:   deleteStrategy,
 		softDeleteColumn: softDeleteColumn,
@@ -48,6 +56,14 @@ func NewMSSQLSink(connString string, tableName string, mappings []sqlutil.Column
 	}
 }
 
+// columnMappings returns the current mappings. ApplySchemaChange replaces
+// them as the source table evolves.
+func (s *MSSQLSink) columnMappings() []sqlutil.ColumnMapping {
+	s.mappingsMu.RLock()
+	defer s.mappingsMu.RUnlock()
+	return s.mappings
+}
+
 func (s *MSSQLSink) Write(ctx context.Context, msg hermod.Message) error {
 	return s.WriteBatch(ctx, []hermod.Message{msg})
 }
@@ -137,14 +153,14 @@ func (s *MSSQLSink) resolveOperation(msg hermod.Message) hermod.Operation {
 
 func (s *MSSQLSink) executeBatch(ctx context.Context, tx *sql.Tx, table string, op hermod.Operation, msgs []hermod.Message) error {
 	// Chunk the batch to avoid parameter count limits (MSSQL limit is 2100)
-	paramsPerRow := len(s.mappings)
+	paramsPerRow := len(s.columnMappings())
 	if paramsPerRow == 0 {
 		paramsPerRow = 2 // id and data
 	}
-	if op == hermod.OpDelete && len(s.mappings) > 0 {
+	if op == hermod.OpDelete && len(s.columnMappings()) > 0 {
 		// count PKs
 		paramsPerRow = 0
-		for _, m := range s.mappings {
+		for _, m := range s.columnMappings() {
 			if m.IsPrimaryKey {
 				paramsPerRow++
 			}
@@ -171,7 +187,7 @@ func (s *MSSQLSink) executeBatch(ctx context.Context, tx *sql.Tx, table string,
 		var err error
 		switch op {
 		case hermod.OpCreate, hermod.OpSnapshot, hermod.OpUpdate:
-			if len(s.mappings) > 0 {
+			if len(s.columnMappings()) > 0 {
 				if op == hermod.OpCreate && s.operationMode == "insert" {
 					err = s.insertMappedBatch(ctx, tx, table, chunk)
 				} else if op == hermod.OpUpdate && s.operationMode == "update" {
@@ -186,7 +202,7 @@ func (s *MSSQLSink) executeBatch(ctx context.Context, tx *sql.Tx, table string,
 			if s.deleteStrategy == "ignore" {
 				continue
 			}
-			if len(s.mappings) > 0 {
+			if len(s.columnMappings()) > 0 {
 				err = s.deleteMappedBatch(ctx, tx, table, chunk)
 			} else {
 				err = s.deleteBasicBatch(ctx, tx, table, chunk)
@@ -228,16 +244,16 @@ func (s *MSSQLSink) ensureTable(ctx context.Context, tx *sql.Tx, table string) e
 				return fmt.Errorf("failed to truncate table %s: %w", table, err)
 			}
 		}
-		if s.autoSync && len(s.mappings) > 0 {
+		if s.autoSync && len(s.columnMappings()) > 0 {
 			if err := s.syncColumns(ctx, tx, table); err != nil {
 				return fmt.Errorf("failed to sync columns for table %s: %w", table, err)
 			}
 		}
 	} else {
 		var query string
-		if len(s.mappings) > 0 {
+		if len(s.columnMappings()) > 0 {
 			var cols []string
-			for _, m := range s.mappings {
+			for _, m := range s.columnMappings() {
 				dataType := m.DataType
 				if dataType == "" {
 					dataType = "NVARCHAR(MAX)"
@@ -272,7 +288,8 @@ func (s *MSSQLSink) ensureTable(ctx context.Context, tx *sql.Tx, table string) e
 	return nil
 }
 
-func (s *MSSQLSink) syncColumns(ctx context.Context, tx *sql.Tx, table string) error {
+// tableColumns returns the columns of table by name.
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]hermod.ColumnInfo, error) {
 	query := `
 		SELECT COLUMN_NAME, DATA_TYPE, IS_NULLABLE, 
 		CASE WHEN EXISTS (
@@ -286,28 +303,36 @@ func (s *MSSQLSink) syncColumns(ctx context.Context, tx *sql.Tx, table string) e
 	`
 	rows, err := tx.QueryContext(ctx, query, table, table)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rows.Close()
 
-	currentCols := make(map[string]hermod.ColumnInfo)
+	cols := make(map[string]hermod.ColumnInfo)
 	for rows.Next() {
 		var col hermod.ColumnInfo
 		var nullable string
 		var isPK, isIdentity int
 		if err := rows.Scan(&col.Name, &col.Type, &nullable, &isPK, &isIdentity); err != nil {
-			return err
+			return nil, err
 		}
 		col.IsNullable = nullable == "YES"
 		col.IsPK = isPK == 1
 		col.IsIdentity = isIdentity == 1
-		currentCols[col.Name] = col
+		cols[col.Name] = col
+	}
+	return cols, rows.Err()
+}
+
+func (s *MSSQLSink) syncColumns(ctx context.Context, tx *sql.Tx, table string) error {
+	currentCols, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return err
 	}
 
 	quotedTable, _ := sqlutil.QuoteIdent("mssql", table)
 
 	// Add or Modify columns
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		col, exists := currentCols[m.TargetColumn]
 		dataType := m.DataType
 		if dataType == "" {
@@ -347,7 +372,7 @@ func (s *MSSQLSink) syncColumns(ctx context.Context, tx *sql.Tx, table string) e
 
 	// Drop columns not in mappings
 	mappingCols := make(map[string]bool)
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		mappingCols[m.TargetColumn] = true
 	}
 	for colName := range currentCols {
@@ -408,7 +433,7 @@ func (s *MSSQLSink) insertMappedBatch(ctx context.Context, tx *sql.Tx, table str
 	}
 
 	var cols []string
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.SourceField == "" {
 			continue
 		}
@@ -431,7 +456,7 @@ func (s *MSSQLSink) insertMappedBatch(ctx context.Context, tx *sql.Tx, table str
 	pIdx := 1
 	for _, msg := range msgs {
 		var rowPlaceholders []string
-		for _, m := range s.mappings {
+		for _, m := range s.columnMappings() {
 			if m.SourceField == "" {
 				continue
 			}
@@ -466,7 +491,7 @@ func (s *MSSQLSink) upsertMappedBatch(ctx context.Context, tx *sql.Tx, table str
 	var cols []string
 	var pkCols []string
 	var updateParts []string
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.SourceField == "" {
 			continue
 		}
@@ -492,7 +517,7 @@ func (s *MSSQLSink) upsertMappedBatch(ctx context.Context, tx *sql.Tx, table str
 	pIdx := 1
 	for _, msg := range msgs {
 		var rowPlaceholders []string
-		for _, m := range s.mappings {
+		for _, m := range s.columnMappings() {
 			if m.SourceField == "" {
 				continue
 			}
@@ -543,7 +568,7 @@ func (s *MSSQLSink) deleteMappedBatch(ctx context.Context, tx *sql.Tx, table str
 	}
 
 	var pkCols []string
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.IsPrimaryKey {
 			quoted, _ := sqlutil.QuoteIdent("mssql", m.TargetColumn)
 			pkCols = append(pkCols, quoted)
@@ -564,7 +589,7 @@ func (s *MSSQLSink) deleteMappedBatch(ctx context.Context, tx *sql.Tx, table str
 			args = append(args, s.softDeleteValue)
 			for i, msg := range msgs {
 				var val any
-				for _, m := range s.mappings {
+				for _, m := range s.columnMappings() {
 					if m.IsPrimaryKey {
 						val = evaluator.GetMsgValByPath(msg, m.SourceField)
 						break
@@ -585,7 +610,7 @@ func (s *MSSQLSink) deleteMappedBatch(ctx context.Context, tx *sql.Tx, table str
 		pIdx := 2
 		for _, msg := range msgs {
 			var rowPlaceholders []string
-			for _, m := range s.mappings {
+			for _, m := range s.columnMappings() {
 				if m.IsPrimaryKey {
 					val := evaluator.GetMsgValByPath(msg, m.SourceField)
 					rowPlaceholders = append(rowPlaceholders, fmt.Sprintf("@p%d", pIdx))
@@ -619,7 +644,7 @@ func (s *MSSQLSink) deleteMappedBatch(ctx context.Context, tx *sql.Tx, table str
 		var args []any
 		for i, msg := range msgs {
 			var val any
-			for _, m := range s.mappings {
+			for _, m := range s.columnMappings() {
 				if m.IsPrimaryKey {
 					val = evaluator.GetMsgValByPath(msg, m.SourceField)
 					break
@@ -639,7 +664,7 @@ func (s *MSSQLSink) deleteMappedBatch(ctx context.Context, tx *sql.Tx, table str
 	pIdx := 1
 	for _, msg := range msgs {
 		var rowPlaceholders []string
-		for _, m := range s.mappings {
+		for _, m := range s.columnMappings() {
 			if m.IsPrimaryKey {
 				val := evaluator.GetMsgValByPath(msg, m.SourceField)
 				rowPlaceholders = append(rowPlaceholders, fmt.Sprintf("@p%d", pIdx))
@@ -0,0 +1,208 @@
+package mssql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/sqlutil"
+)
+
+// ApplySchemaChange implements hermod.SchemaChangeSink. It evolves the target
+// table and the column mappings along with the source table: added columns
+// are added as nullable columns, dropped ones are dropped and retyped ones
+// altered, all in one transaction. Without mappings rows are stored as JSON
+// text and nothing changes.
+//
+// The engine keeps the updated mappings through SchemaState, so a restarted
+// sink carries on with them.
+func (s *MSSQLSink) ApplySchemaChange(ctx context.Context, change hermod.SchemaChange) error {
+	mappings := s.columnMappings()
+	if len(mappings) == 0 {
+		return nil
+	}
+	if err := s.init(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	db := s.db
+	s.mu.Unlock()
+
+	table := s.tableName
+	if table == "" {
+		table = change.QualifiedTable()
+	}
+	quotedTable, err := sqlutil.QuoteIdent("mssql", table)
+	if err != nil {
+		return fmt.Errorf("invalid table name: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin mssql transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := s.ensureTable(ctx, tx, table); err != nil {
+		return fmt.Errorf("ensure table %s: %w", table, err)
+	}
+
+	current, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return fmt.Errorf("load columns of %s: %w", table, err)
+	}
+	next, stmts, err := evolveMappings(mappings, change, current, quotedTable)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("schema change of %s: %w", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.mappingsMu.Lock()
+	s.mappings = next
+	s.mappingsMu.Unlock()
+	return nil
+}
+
+// SchemaState implements hermod.SchemaStateSink: the column mappings as
+// schema changes evolved them.
+func (s *MSSQLSink) SchemaState() ([]byte, error) {
+	return json.Marshal(sqlutil.MappingState{Configured: s.configured, Mappings: s.columnMappings()})
+}
+
+// RestoreSchemaState implements hermod.SchemaStateSink. Mappings evolved
+// from a configuration that has changed since are ignored.
+func (s *MSSQLSink) RestoreSchemaState(state []byte) error {
+	mappings, err := sqlutil.RestoreMappings(state, s.configured)
+	if err != nil {
+		return err
+	}
+	s.mappingsMu.Lock()
+	s.mappings = mappings
+	s.mappingsMu.Unlock()
+	return nil
+}
+
+// evolveMappings returns the mappings after change and the statements that
+// bring the table, whose columns are current, in line with them. Primary key
+// columns are never dropped.
+func evolveMappings(mappings []sqlutil.ColumnMapping, change hermod.SchemaChange, current map[string]hermod.ColumnInfo, quotedTable string) ([]sqlutil.ColumnMapping, []string, error) {
+	next := slices.Clone(mappings)
+	var stmts []string
+	bySource := func(name string) int {
+		return slices.IndexFunc(next, func(m sqlutil.ColumnMapping) bool { return m.SourceField == name })
+	}
+
+	for _, c := range change.Added() {
+		if bySource(c.Name) >= 0 {
+			continue
+		}
+		// Rows already in the table have no value for the column.
+		m := sqlutil.ColumnMapping{SourceField: c.Name, TargetColumn: c.Name, DataType: mssqlDataType(c.Type), IsNullable: true}
+		if _, ok := current[m.TargetColumn]; !ok {
+			col, err := sqlutil.QuoteIdent("mssql", m.TargetColumn)
+			if err != nil {
+				return nil, nil, err
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD %s %s NULL", quotedTable, col, m.DataType))
+		}
+		next = append(next, m)
+	}
+
+	for _, c := range change.Dropped() {
+		i := bySource(c.Name)
+		if i < 0 || next[i].IsPrimaryKey {
+			continue
+		}
+		if _, ok := current[next[i].TargetColumn]; ok {
+			col, err := sqlutil.QuoteIdent("mssql", next[i].TargetColumn)
+			if err != nil {
+				return nil, nil, err
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quotedTable, col))
+		}
+		next = slices.Delete(next, i, i+1)
+	}
+
+	for _, c := range change.Retyped() {
+		i := bySource(c.Name)
+		if i < 0 {
+			continue
+		}
+		next[i].DataType = mssqlDataType(c.Type)
+		if _, ok := current[next[i].TargetColumn]; !ok {
+			continue
+		}
+		col, err := sqlutil.QuoteIdent("mssql", next[i].TargetColumn)
+		if err != nil {
+			return nil, nil, err
+		}
+		// ALTER COLUMN makes a column nullable unless told otherwise.
+		null := " NULL"
+		if next[i].IsPrimaryKey || !next[i].IsNullable {
+			null = " NOT NULL"
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s%s", quotedTable, col, next[i].DataType, null))
+	}
+	return next, stmts, nil
+}
+
+// mssqlDataType translates a column type of a source database to SQL Server.
+// Types it does not know become NVARCHAR(MAX).
+func mssqlDataType(sqlType string) string {
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	t = strings.TrimSpace(strings.ReplaceAll(t, "unsigned", ""))
+	if t == "tinyint(1)" {
+		return "BIT"
+	}
+	base, args, _ := strings.Cut(t, "(")
+	base = strings.TrimSpace(base)
+	args = strings.TrimSuffix(args, ")")
+	switch base {
+	case "bit", "bool", "boolean":
+		return "BIT"
+	case "tinyint", "smallint", "int2":
+		return "SMALLINT"
+	case "mediumint", "int", "integer", "int4", "serial":
+		return "INT"
+	case "bigint", "int8", "bigserial":
+		return "BIGINT"
+	case "datetime", "datetime2", "smalldatetime", "timestamp", "timestamp without time zone":
+		return "DATETIME2"
+	case "datetimeoffset", "timestamptz", "timestamp with time zone":
+		return "DATETIMEOFFSET"
+	case "double", "double precision", "float", "float8":
+		return "FLOAT"
+	case "real", "float4":
+		return "REAL"
+	case "decimal", "numeric":
+		if args != "" {
+			return "DECIMAL(" + args + ")"
+		}
+		return "DECIMAL(38,10)"
+	case "money", "smallmoney":
+		return strings.ToUpper(base)
+	case "char", "nchar", "bpchar", "varchar", "nvarchar", "character varying", "character":
+		if args == "" || strings.HasPrefix(args, "max") {
+			return "NVARCHAR(MAX)"
+		}
+		return "NVARCHAR(" + args + ")"
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "image", "bytea":
+		return "VARBINARY(MAX)"
+	case "xml":
+		return "XML"
+	case "uniqueidentifier", "uuid":
+		return "UNIQUEIDENTIFIER"
+	case "date", "time":
+		return strings.ToUpper(base)
+	}
+	return "NVARCHAR(MAX)"
+}
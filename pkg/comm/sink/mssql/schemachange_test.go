@@ -0,0 +1,76 @@
+package mssql
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/sqlutil"
+)
+
+func TestMSSQLDataType(t *testing.T) {
+	tests := map[string]string{
+		"tinyint(1)":       "BIT",
+		"int(11) unsigned": "INT",
+		"boolean":          "BIT",
+		"varchar(255)":     "NVARCHAR(255)",
+		"text":             "NVARCHAR(MAX)",
+		"numeric(10,2)":    "DECIMAL(10,2)",
+		"timestamp":        "DATETIME2",
+		"timestamptz":      "DATETIMEOFFSET",
+		"bytea":            "VARBINARY(MAX)",
+		"uuid":             "UNIQUEIDENTIFIER",
+		"double precision": "FLOAT",
+		"geometry":         "NVARCHAR(MAX)",
+	}
+	for in, want := range tests {
+		if got := mssqlDataType(in); got != want {
+			t.Errorf("mssqlDataType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEvolveMappings(t *testing.T) {
+	mappings := []sqlutil.ColumnMapping{
+		{SourceField: "id", TargetColumn: "id", DataType: "INT", IsPrimaryKey: true},
+		{SourceField: "name", TargetColumn: "full_name", DataType: "NVARCHAR(MAX)", IsNullable: true},
+		{SourceField: "age", TargetColumn: "age", DataType: "INT", IsNullable: true},
+	}
+	current := map[string]hermod.ColumnInfo{
+		"id":        {Name: "id", Type: "int"},
+		"full_name": {Name: "full_name", Type: "nvarchar"},
+		"age":       {Name: "age", Type: "int"},
+	}
+	change := hermod.SchemaChange{
+		Table: "users",
+		Previous: []hermod.ColumnInfo{
+			{Name: "id", Type: "int"}, {Name: "name", Type: "text"}, {Name: "age", Type: "int"},
+		},
+		Columns: []hermod.ColumnInfo{
+			{Name: "id", Type: "int"}, {Name: "name", Type: "varchar(50)"}, {Name: "email", Type: "varchar(100)"},
+		},
+	}
+
+	next, stmts, err := evolveMappings(mappings, change, current, "[users]")
+	if err != nil {
+		t.Fatalf("evolveMappings failed: %v", err)
+	}
+	wantStmts := []string{
+		"ALTER TABLE [users] ADD [email] NVARCHAR(100) NULL",
+		"ALTER TABLE [users] DROP COLUMN [age]",
+		"ALTER TABLE [users] ALTER COLUMN [full_name] NVARCHAR(50) NULL",
+	}
+	if !slices.Equal(stmts, wantStmts) {
+		t.Errorf("statements = %q, want %q", stmts, wantStmts)
+	}
+	var targets []string
+	for _, m := range next {
+		targets = append(targets, m.TargetColumn)
+	}
+	if !slices.Equal(targets, []string{"id", "full_name", "email"}) {
+		t.Errorf("mapped columns = %v", targets)
+	}
+	if len(mappings) != 3 || mappings[1].DataType != "NVARCHAR(MAX)" {
+		t.Error("evolveMappings must not modify the mappings it is given")
+	}
+}
@@ -0,0 +1,42 @@
+package mysql
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/user/hermod"
+)
+
+// ClassifyError implements hermod.ErrorClassifier using the server's error
+// number.
+func (s *MySQLSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	return classifyMySQLError(err)
+}
+
+func classifyMySQLError(err error) (hermod.ErrorClass, time.Duration) {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return hermod.ErrorTransient, 0
+	}
+	switch myErr.Number {
+	case 1062, // ER_DUP_ENTRY
+		1451, 1452, // ER_ROW_IS_REFERENCED_2, ER_NO_REFERENCED_ROW_2
+		1048, // ER_BAD_NULL_ERROR
+		1406, // ER_DATA_TOO_LONG
+		1366, // ER_TRUNCATED_WRONG_VALUE_FOR_FIELD
+		1264: // ER_WARN_DATA_OUT_OF_RANGE
+		return hermod.ErrorPermanent, 0
+	case 1044, 1045, // ER_DBACCESS_DENIED_ERROR, ER_ACCESS_DENIED_ERROR
+		1049, // ER_BAD_DB_ERROR
+		1146, // ER_NO_SUCH_TABLE
+		1054, // ER_BAD_FIELD_ERROR
+		1142: // ER_TABLEACCESS_DENIED_ERROR
+		return hermod.ErrorFatalConfig, 0
+	case 1040, 1203: // ER_CON_COUNT_ERROR, ER_TOO_MANY_USER_CONNECTIONS
+		return hermod.ErrorThrottled, 0
+	}
+	// Deadlocks (1213), lock wait timeouts (1205) and anything unrecognised
+	// are worth retrying.
+	return hermod.ErrorTransient, 0
+}
@@ -32,6 +32,13 @@ type MySQLSink struct {
 	operationMode    string
 	autoTruncate     bool
 	autoSync         bool
+
+	// mappingsMu guards mappings, which ApplySchemaChange replaces. It is
+	// separate from mu because ensureTable reads them while holding mu.
+	mappingsMu sync.RWMutex
+	// configured are the mappings the sink was created with, which schema
+	// changes evolve from.
+	configured []sqlutil.ColumnMapping
 }
 
 func NewMySQLSink(connString string, tableName string, mappings []sqlutil.ColumnMapping, useExistingTable bool, deleteStrategy string, softDeleteColumn string, softDeleteValue string, operationMode string, autoTruncate bool, autoSync bool) *MySQLSink {
@@ -42,6 +49,7 @@ func NewMySQLSink(connString string, tableName string, mappings []sqlutil.Column
 		connString:       connString,
 		tableName:        tableName,
 		mappings:         mappings,
+		configured:       mappings,
 		useExistingTable: useExistingTable,
 		deleteStrategy:   deleteStrategy,
 		softDeleteColumn: softDeleteColumn,
@@ -52,6 +60,14 @@ func NewMySQLSink(connString string, tableName string, mappings []sqlutil.Column
 	}
 }
 
+// columnMappings returns the current mappings. ApplySchemaChange replaces
+// them as the source table evolves.
+func (s *MySQLSink) columnMappings() []sqlutil.ColumnMapping {
+	s.mappingsMu.RLock()
+	defer s.mappingsMu.RUnlock()
+	return s.mappings
+}
+
 func (s *MySQLSink) Write(ctx context.Context, msg hermod.Message) error {
 	return s.WriteBatch(ctx, []hermod.Message{msg})
 }
@@ -124,7 +140,7 @@ func (s *MySQLSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error
 
 		switch op {
 		case hermod.OpCreate, hermod.OpSnapshot, hermod.OpUpdate:
-			if len(s.mappings) > 0 {
+			if len(s.columnMappings()) > 0 {
 				switch s.operationMode {
 				case "insert":
 					err = s.insertMapped(ctx, tx, table, msg)
@@ -150,7 +166,7 @@ func (s *MySQLSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error
 			if s.deleteStrategy == "ignore" {
 				continue
 			}
-			if len(s.mappings) > 0 {
+			if len(s.columnMappings()) > 0 {
 				err = s.deleteMapped(ctx, tx, table, msg)
 			} else {
 				key := "delete:" + table
@@ -413,7 +429,7 @@ func (s *MySQLSink) deleteMapped(ctx context.Context, tx *sql.Tx, table string,
 	var pks []string
 	var args []any
 
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.IsPrimaryKey {
 			val := evaluator.GetMsgValByPath(msg, m.SourceField)
 			pks = append(pks, fmt.Sprintf("`%s` = ?", m.TargetColumn))
@@ -496,16 +512,16 @@ func (s *MySQLSink) ensureTable(ctx context.Context, tx *sql.Tx, table string) e
 				return fmt.Errorf("truncate table %s: %w", table, err)
 			}
 		}
-		if s.autoSync && len(s.mappings) > 0 {
-			if err := s.syncColumns(ctx, tx, table, dbName, tableNameOnly); err != nil {
+		if s.autoSync && len(s.columnMappings()) > 0 {
+			if err := s.syncColumns(ctx, tx, table); err != nil {
 				return fmt.Errorf("sync columns %s: %w", table, err)
 			}
 		}
 	} else {
 		var tableQuery string
-		if len(s.mappings) > 0 {
+		if len(s.columnMappings()) > 0 {
 			var cols []string
-			for _, m := range s.mappings {
+			for _, m := range s.columnMappings() {
 				dataType := m.DataType
 				if dataType == "" {
 					dataType = "TEXT"
@@ -535,37 +551,47 @@ func (s *MySQLSink) ensureTable(ctx context.Context, tx *sql.Tx, table string) e
 	return nil
 }
 
-func (s *MySQLSink) syncColumns(ctx context.Context, tx *sql.Tx, table, dbName, tableNameOnly string) error {
+// tableColumns returns the columns of table by name. A name qualified with a
+// database is looked up in it, others in the current database.
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]hermod.ColumnInfo, error) {
 	query := commonQueries[QueryListColumns]
-	args := []any{tableNameOnly}
-	if dbName != "" {
+	args := []any{table}
+	if dbName, tableNameOnly, ok := strings.Cut(table, "."); ok {
 		query = strings.Replace(query, "TABLE_SCHEMA = DATABASE()", "TABLE_SCHEMA = ?", 1)
-		args = append(args, dbName)
+		args = []any{tableNameOnly, dbName}
 	}
 
 	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rows.Close()
 
-	currentCols := make(map[string]hermod.ColumnInfo)
+	cols := make(map[string]hermod.ColumnInfo)
 	for rows.Next() {
 		var col hermod.ColumnInfo
 		var def *string
 		if err := rows.Scan(&col.Name, &col.Type, &col.IsNullable, &col.IsPK, &col.IsIdentity, &def); err != nil {
-			return err
+			return nil, err
 		}
 		if def != nil {
 			col.Default = *def
 		}
-		currentCols[col.Name] = col
+		cols[col.Name] = col
+	}
+	return cols, rows.Err()
+}
+
+func (s *MySQLSink) syncColumns(ctx context.Context, tx *sql.Tx, table string) error {
+	currentCols, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return err
 	}
 
 	quotedTable, _ := sqlutil.QuoteIdent("mysql", table)
 
 	// Add or Modify columns
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		col, exists := currentCols[m.TargetColumn]
 		dataType := m.DataType
 		if dataType == "" {
@@ -599,7 +625,7 @@ func (s *MySQLSink) syncColumns(ctx context.Context, tx *sql.Tx, table, dbName,
 
 	// Drop columns not in mappings
 	mappingCols := make(map[string]bool)
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		mappingCols[m.TargetColumn] = true
 	}
 	for colName := range currentCols {
@@ -626,7 +652,7 @@ func (s *MySQLSink) upsertMapped(ctx context.Context, tx *sql.Tx, table string,
 	var updates []string
 	var pks []string
 
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.SourceField == "" {
 			continue
 		}
@@ -669,7 +695,7 @@ func (s *MySQLSink) insertMapped(ctx context.Context, tx *sql.Tx, table string,
 	var placeholders []string
 	var args []any
 
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.SourceField == "" {
 			continue
 		}
@@ -698,7 +724,7 @@ func (s *MySQLSink) updateMapped(ctx context.Context, tx *sql.Tx, table string,
 	var args []any
 	var pkArgs []any
 
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.SourceField == "" {
 			continue
 		}
@@ -0,0 +1,223 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/sqlutil"
+)
+
+// ApplySchemaChange implements hermod.SchemaChangeSink. It evolves the target
+// table and the column mappings along with the source table: added columns
+// are added as nullable columns, dropped ones are dropped and retyped ones
+// modified. Without mappings rows are stored as JSON and nothing changes.
+//
+// MySQL commits each ALTER TABLE on its own, so a change that fails halfway
+// leaves the statements before it applied; they are skipped when it is
+// retried. The engine keeps the updated mappings through SchemaState, so a
+// restarted sink carries on with them.
+func (s *MySQLSink) ApplySchemaChange(ctx context.Context, change hermod.SchemaChange) error {
+	mappings := s.columnMappings()
+	if len(mappings) == 0 {
+		return nil
+	}
+	if err := s.init(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	db := s.db
+	s.mu.Unlock()
+
+	table := s.tableName
+	if table == "" {
+		table = change.QualifiedTable()
+	}
+	quotedTable, err := sqlutil.QuoteIdent("mysql", table)
+	if err != nil {
+		return fmt.Errorf("invalid table name: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin mysql transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := s.ensureTable(ctx, tx, table); err != nil {
+		return fmt.Errorf("ensure table %s: %w", table, err)
+	}
+
+	current, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return fmt.Errorf("load columns of %s: %w", table, err)
+	}
+	next, stmts, err := evolveMappings(mappings, change, current, quotedTable)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("schema change of %s: %w", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.mappingsMu.Lock()
+	s.mappings = next
+	s.mappingsMu.Unlock()
+	return nil
+}
+
+// SchemaState implements hermod.SchemaStateSink: the column mappings as
+// schema changes evolved them.
+func (s *MySQLSink) SchemaState() ([]byte, error) {
+	return json.Marshal(sqlutil.MappingState{Configured: s.configured, Mappings: s.columnMappings()})
+}
+
+// RestoreSchemaState implements hermod.SchemaStateSink. Mappings evolved
+// from a configuration that has changed since are ignored.
+func (s *MySQLSink) RestoreSchemaState(state []byte) error {
+	mappings, err := sqlutil.RestoreMappings(state, s.configured)
+	if err != nil {
+		return err
+	}
+	s.mappingsMu.Lock()
+	s.mappings = mappings
+	s.mappingsMu.Unlock()
+	return nil
+}
+
+// evolveMappings returns the mappings after change and the statements that
+// bring the table, whose columns are current, in line with them. Primary key
+// columns are never dropped.
+func evolveMappings(mappings []sqlutil.ColumnMapping, change hermod.SchemaChange, current map[string]hermod.ColumnInfo, quotedTable string) ([]sqlutil.ColumnMapping, []string, error) {
+	next := slices.Clone(mappings)
+	var stmts []string
+	bySource := func(name string) int {
+		return slices.IndexFunc(next, func(m sqlutil.ColumnMapping) bool { return m.SourceField == name })
+	}
+
+	for _, c := range change.Added() {
+		if bySource(c.Name) >= 0 {
+			continue
+		}
+		// Rows already in the table have no value for the column.
+		m := sqlutil.ColumnMapping{SourceField: c.Name, TargetColumn: c.Name, DataType: mysqlDataType(c.Type), IsNullable: true}
+		if _, ok := current[m.TargetColumn]; !ok {
+			col, err := sqlutil.QuoteIdent("mysql", m.TargetColumn)
+			if err != nil {
+				return nil, nil, err
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s NULL", quotedTable, col, m.DataType))
+		}
+		next = append(next, m)
+	}
+
+	for _, c := range change.Dropped() {
+		i := bySource(c.Name)
+		if i < 0 || next[i].IsPrimaryKey {
+			continue
+		}
+		if _, ok := current[next[i].TargetColumn]; ok {
+			col, err := sqlutil.QuoteIdent("mysql", next[i].TargetColumn)
+			if err != nil {
+				return nil, nil, err
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quotedTable, col))
+		}
+		next = slices.Delete(next, i, i+1)
+	}
+
+	for _, c := range change.Retyped() {
+		i := bySource(c.Name)
+		if i < 0 {
+			continue
+		}
+		next[i].DataType = mysqlDataType(c.Type)
+		if _, ok := current[next[i].TargetColumn]; !ok {
+			continue
+		}
+		col, err := sqlutil.QuoteIdent("mysql", next[i].TargetColumn)
+		if err != nil {
+			return nil, nil, err
+		}
+		// MODIFY COLUMN restates the whole definition, nullability included.
+		null := " NULL"
+		if next[i].IsPrimaryKey || !next[i].IsNullable {
+			null = " NOT NULL"
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s%s", quotedTable, col, next[i].DataType, null))
+	}
+	return next, stmts, nil
+}
+
+// mysqlDataType translates a column type of a source database to MySQL.
+// Types it does not know become TEXT.
+func mysqlDataType(sqlType string) string {
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	unsigned := strings.Contains(t, "unsigned")
+	t = strings.TrimSpace(strings.ReplaceAll(t, "unsigned", ""))
+	if t == "tinyint(1)" {
+		return "TINYINT(1)"
+	}
+	base, args, _ := strings.Cut(t, "(")
+	base = strings.TrimSpace(base)
+	args = strings.TrimSuffix(args, ")")
+	sign := ""
+	if unsigned {
+		sign = " UNSIGNED"
+	}
+	switch base {
+	case "bit", "bool", "boolean":
+		return "TINYINT(1)"
+	case "tinyint":
+		return "TINYINT" + sign
+	case "smallint", "int2":
+		return "SMALLINT" + sign
+	case "mediumint":
+		return "MEDIUMINT" + sign
+	case "int", "integer", "int4", "serial":
+		return "INT" + sign
+	case "bigint", "int8", "bigserial":
+		return "BIGINT" + sign
+	case "datetime", "datetime2", "smalldatetime", "timestamp", "timestamp without time zone",
+		"datetimeoffset", "timestamptz", "timestamp with time zone":
+		return "DATETIME(6)"
+	case "double", "double precision", "float", "float8":
+		return "DOUBLE"
+	case "real", "float4":
+		return "FLOAT"
+	case "decimal", "numeric":
+		if args != "" {
+			return "DECIMAL(" + args + ")"
+		}
+		return "DECIMAL(65,30)"
+	case "money", "smallmoney":
+		return "DECIMAL(19,4)"
+	case "tinytext", "mediumtext", "longtext":
+		return strings.ToUpper(base)
+	case "text", "ntext", "xml", "enum", "set":
+		return "LONGTEXT"
+	case "char", "nchar", "bpchar", "varchar", "nvarchar", "character varying", "character":
+		if args == "" || strings.HasPrefix(args, "max") {
+			return "LONGTEXT"
+		}
+		return "VARCHAR(" + args + ")"
+	case "blob", "tinyblob", "mediumblob", "longblob":
+		return strings.ToUpper(base)
+	case "binary", "varbinary", "image", "bytea":
+		return "LONGBLOB"
+	case "json", "jsonb":
+		return "JSON"
+	case "uniqueidentifier", "uuid":
+		return "CHAR(36)"
+	case "date", "time", "year":
+		return strings.ToUpper(base)
+	}
+	return "TEXT"
+}
@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/sqlutil"
+)
+
+func TestMySQLDataType(t *testing.T) {
+	tests := map[string]string{
+		"tinyint(1)":       "TINYINT(1)",
+		"int(11) unsigned": "INT UNSIGNED",
+		"integer":          "INT",
+		"varchar(255)":     "VARCHAR(255)",
+		"nvarchar(max)":    "LONGTEXT",
+		"numeric(10,2)":    "DECIMAL(10,2)",
+		"timestamptz":      "DATETIME(6)",
+		"bytea":            "LONGBLOB",
+		"jsonb":            "JSON",
+		"uuid":             "CHAR(36)",
+		"double precision": "DOUBLE",
+		"geometry":         "TEXT",
+	}
+	for in, want := range tests {
+		if got := mysqlDataType(in); got != want {
+			t.Errorf("mysqlDataType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEvolveMappings(t *testing.T) {
+	mappings := []sqlutil.ColumnMapping{
+		{SourceField: "id", TargetColumn: "id", DataType: "INT", IsPrimaryKey: true},
+		{SourceField: "name", TargetColumn: "full_name", DataType: "TEXT", IsNullable: true},
+		{SourceField: "age", TargetColumn: "age", DataType: "INT", IsNullable: true},
+	}
+	current := map[string]hermod.ColumnInfo{
+		"id":        {Name: "id", Type: "int"},
+		"full_name": {Name: "full_name", Type: "text"},
+		"age":       {Name: "age", Type: "int"},
+	}
+	change := hermod.SchemaChange{
+		Table: "users",
+		Previous: []hermod.ColumnInfo{
+			{Name: "id", Type: "int"}, {Name: "name", Type: "text"}, {Name: "age", Type: "int"},
+		},
+		Columns: []hermod.ColumnInfo{
+			{Name: "id", Type: "int"}, {Name: "name", Type: "varchar(50)"}, {Name: "email", Type: "varchar(100)"},
+		},
+	}
+
+	next, stmts, err := evolveMappings(mappings, change, current, "`users`")
+	if err != nil {
+		t.Fatalf("evolveMappings failed: %v", err)
+	}
+	wantStmts := []string{
+		"ALTER TABLE `users` ADD COLUMN `email` VARCHAR(100) NULL",
+		"ALTER TABLE `users` DROP COLUMN `age`",
+		"ALTER TABLE `users` MODIFY COLUMN `full_name` VARCHAR(50) NULL",
+	}
+	if !slices.Equal(stmts, wantStmts) {
+		t.Errorf("statements = %q, want %q", stmts, wantStmts)
+	}
+	var targets []string
+	for _, m := range next {
+		targets = append(targets, m.TargetColumn)
+	}
+	if !slices.Equal(targets, []string{"id", "full_name", "email"}) {
+		t.Errorf("mapped columns = %v", targets)
+	}
+	if len(mappings) != 3 || mappings[1].DataType != "TEXT" {
+		t.Error("evolveMappings must not modify the mappings it is given")
+	}
+
+	// A dropped primary key stays, as rows could no longer be addressed.
+	dropPK := hermod.SchemaChange{Table: "users", Previous: change.Previous, Columns: change.Previous[1:]}
+	next, stmts, err = evolveMappings(mappings, dropPK, current, "`users`")
+	if err != nil || len(stmts) != 0 || len(next) != len(mappings) {
+		t.Errorf("dropping a primary key: %d statements, %d mappings, err %v", len(stmts), len(next), err)
+	}
+}
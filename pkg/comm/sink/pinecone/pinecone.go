@@ -67,7 +67,16 @@ func (s *Sink) Ping(ctx context.Context) error {
 
 // WriteBatch writes a batch of messages to Pinecone.
 // It expects the messages to have 'id', 'values' (float array), and optional 'metadata'.
+// Delete operations remove the vector with the message's id.
 func (s *Sink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults writes msgs with one upsert and one delete request and
+// rejects each message that carries no vector values. The API does not say
+// which vector a failed request choked on, so its error is returned for the
+// whole batch, which the engine retries or splits.
+func (s *Sink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
 	type Vector struct {
 		ID       string         `json:"id"`
 		Values   []float64      `json:"values"`
@@ -79,8 +88,13 @@ func (s *Sink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
 		Namespace string   `json:"namespace,omitempty"`
 	}
 
+	errs := make([]error, len(msgs))
 	vectors := make([]Vector, 0, len(msgs))
-	for _, msg := range msgs {
+	var deletes []string
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
 		var v Vector
 		data := msg.Data()
 
@@ -91,6 +105,11 @@ func (s *Sink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
 			v.ID = msg.ID()
 		}
 
+		if msg.Operation() == hermod.OpDelete {
+			deletes = append(deletes, v.ID)
+			continue
+		}
+
 		// Map Values (Embeddings)
 		if vals, ok := data["values"].([]any); ok {
 			v.Values = make([]float64, len(vals))
@@ -110,27 +129,45 @@ func (s *Sink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
 			v.Metadata = meta
 		}
 
-		if len(v.Values) > 0 {
-			vectors = append(vectors, v)
+		if len(v.Values) == 0 {
+			errs[i] = hermod.Classify(fmt.Errorf("message %s has no vector values", msg.ID()), hermod.ErrorPermanent)
+			continue
 		}
+		vectors = append(vectors, v)
 	}
 
-	if len(vectors) == 0 {
-		return nil
+	if len(vectors) > 0 {
+		reqBody := UpsertRequest{
+			Vectors:   vectors,
+			Namespace: s.config.Namespace,
+		}
+		if err := s.post(ctx, "upsert", reqBody); err != nil {
+			return nil, err
+		}
 	}
 
-	reqBody := UpsertRequest{
-		Vectors:   vectors,
-		Namespace: s.config.Namespace,
+	if len(deletes) > 0 {
+		reqBody := map[string]any{"ids": deletes}
+		if s.config.Namespace != "" {
+			reqBody["namespace"] = s.config.Namespace
+		}
+		if err := s.post(ctx, "delete", reqBody); err != nil {
+			return nil, err
+		}
 	}
 
-	data, err := json.Marshal(reqBody)
+	return errs, nil
+}
+
+// post sends a JSON request to the index's /vectors/<op> endpoint.
+func (s *Sink) post(ctx context.Context, op string, body any) error {
+	data, err := json.Marshal(body)
 	if err != nil {
 		return fmt.Errorf("failed to marshal pinecone request: %w", err)
 	}
 
 	// Host URL calculation (simplified for example)
-	url := fmt.Sprintf("https://%s-%s.svc.%s.pinecone.io/vectors/upsert", s.config.IndexName, s.config.Environment, s.config.Environment)
+	url := fmt.Sprintf("https://%s-%s.svc.%s.pinecone.io/vectors/%s", s.config.IndexName, s.config.Environment, s.config.Environment, op)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
 	if err != nil {
 		return err
@@ -146,7 +183,16 @@ func (s *Sink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("pinecone upsert failed with status: %d", resp.StatusCode)
+		err := fmt.Errorf("pinecone %s failed with status: %d", op, resp.StatusCode)
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests:
+			return hermod.Throttled(err, 0)
+		case http.StatusBadRequest:
+			return hermod.Classify(err, hermod.ErrorPermanent)
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return hermod.Classify(err, hermod.ErrorFatalConfig)
+		}
+		return err
 	}
 
 	return nil
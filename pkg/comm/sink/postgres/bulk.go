@@ -50,7 +50,7 @@ func (s *PostgresSink) classifyBatch(msgs []hermod.Message) bulkMode {
 	}
 	// Without mappings the column list is derived per message, so there is no
 	// stable tuple shape to COPY.
-	if len(s.mappings) == 0 {
+	if len(s.columnMappings()) == 0 {
 		return bulkModeNone
 	}
 	// Soft delete rewrites rows rather than inserting them.
@@ -137,8 +137,8 @@ func (s *PostgresSink) writeBatchCopy(ctx context.Context, tx pgx.Tx, table stri
 	// Mirror the ordered path: mappings with no source field are skipped there
 	// (see buildUpsertArgs), so they must be skipped here too or the tuple shape
 	// would not match the column list.
-	active := make([]sqlutil.ColumnMapping, 0, len(s.mappings))
-	for _, m := range s.mappings {
+	active := make([]sqlutil.ColumnMapping, 0, len(s.columnMappings()))
+	for _, m := range s.columnMappings() {
 		if m.SourceField == "" {
 			continue
 		}
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/user/hermod"
+)
+
+// ClassifyError implements hermod.ErrorClassifier using the SQLSTATE of the
+// server's error.
+func (s *PostgresSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	return classifyPgError(err)
+}
+
+func classifyPgError(err error) (hermod.ErrorClass, time.Duration) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return hermod.ErrorTransient, 0
+	}
+	code := pgErr.Code
+	switch code {
+	case "42501", // insufficient_privilege
+		"3D000", // invalid_catalog_name
+		"42P01", // undefined_table
+		"42703": // undefined_column
+		return hermod.ErrorFatalConfig, 0
+	case "53300": // too_many_connections
+		return hermod.ErrorThrottled, 0
+	}
+	switch {
+	case strings.HasPrefix(code, "28"): // invalid authorization
+		return hermod.ErrorFatalConfig, 0
+	case strings.HasPrefix(code, "23"), // integrity constraint violation
+		strings.HasPrefix(code, "22"): // data exception
+		return hermod.ErrorPermanent, 0
+	}
+	// Serialization failures, deadlocks, cancellations and connection
+	// exceptions (40xxx, 57xxx, 08xxx) are worth retrying, as is anything
+	// unrecognised.
+	return hermod.ErrorTransient, 0
+}
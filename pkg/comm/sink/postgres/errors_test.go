@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/user/hermod"
+)
+
+func TestClassifyPgError(t *testing.T) {
+	tests := []struct {
+		code  string
+		class hermod.ErrorClass
+	}{
+		{"23505", hermod.ErrorPermanent},   // unique_violation
+		{"22P02", hermod.ErrorPermanent},   // invalid_text_representation
+		{"28P01", hermod.ErrorFatalConfig}, // invalid_password
+		{"42P01", hermod.ErrorFatalConfig}, // undefined_table
+		{"53300", hermod.ErrorThrottled},   // too_many_connections
+		{"40P01", hermod.ErrorTransient},   // deadlock_detected
+		{"08006", hermod.ErrorTransient},   // connection_failure
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("failed to insert: %w", &pgconn.PgError{Code: tt.code})
+		if class, _ := classifyPgError(err); class != tt.class {
+			t.Errorf("%s: expected %s, got %s", tt.code, tt.class, class)
+		}
+	}
+	if class, _ := classifyPgError(errors.New("connection reset")); class != hermod.ErrorTransient {
+		t.Errorf("expected a non-server error to be transient, got %s", class)
+	}
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/user/hermod"
+)
+
+// fencing is the lease a sink writes under: the workflow it belongs to and
+// the token the platform issued when the lease was acquired.
+type fencing struct {
+	scope string
+	token int64
+}
+
+// SetFencingToken implements hermod.FencedSink. Every later write advances
+// the scope's row in hermod_fencing_tokens inside its own transaction, so a
+// worker still holding an older token fails instead of writing.
+func (s *PostgresSink) SetFencingToken(scope string, token int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fencing = fencing{scope: scope, token: token}
+}
+
+func (s *PostgresSink) currentFencing() fencing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fencing
+}
+
+// checkFencing advances the scope's token within the write's transaction or
+// fails with hermod.ErrFenced when a newer one has already been recorded.
+// The row lock it takes is held until the transaction ends, so writers with
+// different tokens cannot both commit.
+func (s *PostgresSink) checkFencing(ctx context.Context, executor pgExecutor) error {
+	f := s.currentFencing()
+	if f.token <= 0 {
+		return nil
+	}
+	// The table is created outside the write's transaction so a rolled back
+	// batch cannot take it with it.
+	if !s.fencesReady.Load() {
+		if _, err := s.pool.Exec(ctx, commonQueries[QueryCreateFences]); err != nil {
+			return fmt.Errorf("create fencing table: %w", err)
+		}
+		s.fencesReady.Store(true)
+	}
+	var token int64
+	err := executor.QueryRow(ctx, commonQueries[QueryAdvanceFence], f.scope, f.token).Scan(&token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %s has been written with a newer token than %d", hermod.ErrFenced, f.scope, f.token)
+	}
+	if err != nil {
+		return fmt.Errorf("check fencing token: %w", err)
+	}
+	return nil
+}
+
+// preparedTxID names a prepared transaction. Under a lease the fencing token
+// leads the name, so in-doubt transactions left by a fenced-off worker can be
+// told apart from the current holder's.
+func preparedTxID(token int64) string {
+	if token <= 0 {
+		return uuid.New().String()
+	}
+	return "hermod-" + strconv.FormatInt(token, 10) + "-" + uuid.New().String()
+}
+
+// validateTxID ensures a prepared-transaction identifier is one preparedTxID
+// could have produced before it is interpolated into a COMMIT/ROLLBACK
+// PREPARED statement, which does not accept bind parameters.
+func validateTxID(txID string) error {
+	id := txID
+	if rest, ok := strings.CutPrefix(txID, "hermod-"); ok {
+		token, tail, found := strings.Cut(rest, "-")
+		if _, err := strconv.ParseUint(token, 10, 63); !found || err != nil {
+			return fmt.Errorf("invalid prepared transaction id %q", txID)
+		}
+		id = tail
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("invalid prepared transaction id: %w", err)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -54,6 +55,12 @@ type PostgresSink struct {
 	operationMode    string
 	autoTruncate     bool
 	autoSync         bool
+	fencing          fencing
+	fencesReady      atomic.Bool
+
+	// configured are the mappings the sink was created with, which schema
+	// changes evolve from.
+	configured []sqlutil.ColumnMapping
 }
 
 func NewPostgresSink(connString string, tableName string, mappings []sqlutil.ColumnMapping, useExistingTable bool, deleteStrategy string, softDeleteColumn string, softDeleteValue string, operationMode string, autoTruncate bool, autoSync bool) *PostgresSink {
@@ -64,6 +71,7 @@ func NewPostgresSink(connString string, tableName string, mappings []sqlutil.Col
 		connString:       connString,
 		tableName:        tableName,
 		mappings:         mappings,
+		configured:       mappings,
 		useExistingTable: useExistingTable,
 		deleteStrategy:   deleteStrategy,
 		softDeleteColumn: softDeleteColumn,
@@ -149,6 +157,9 @@ func (s *PostgresSink) WriteBatch(ctx context.Context, msgs []hermod.Message) er
 	if localTx != nil {
 		defer func() { _ = localTx.Rollback(ctx) }()
 	}
+	if err := s.checkFencing(ctx, executor); err != nil {
+		return err
+	}
 
 	// An insert-only batch has no observable ordering, so it can take the COPY
 	// fast path. Everything else — and anything the classifier is unsure about —
@@ -257,7 +268,7 @@ func (s *PostgresSink) applyOperation(ctx context.Context, executor pgExecutor,
 }
 
 func (s *PostgresSink) applyUpsert(ctx context.Context, executor pgExecutor, table string, msg hermod.Message) error {
-	if len(s.mappings) > 0 {
+	if len(s.columnMappings()) > 0 {
 		switch s.operationMode {
 		case "insert":
 			return s.insertMapped(ctx, executor, table, msg)
@@ -276,7 +287,7 @@ func (s *PostgresSink) applyUpsert(ctx context.Context, executor pgExecutor, tab
 }
 
 func (s *PostgresSink) applyDelete(ctx context.Context, executor pgExecutor, table string, msg hermod.Message) error {
-	if len(s.mappings) > 0 {
+	if len(s.columnMappings()) > 0 {
 		return s.deleteMapped(ctx, executor, table, msg)
 	}
 	quoted, err := quoteTable(table)
@@ -310,6 +321,14 @@ func (s *PostgresSink) init(ctx context.Context) error {
 	return nil
 }
 
+// columnMappings returns the current mappings. ApplySchemaChange replaces
+// the slice rather than modifying it, so callers may keep what they got.
+func (s *PostgresSink) columnMappings() []sqlutil.ColumnMapping {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mappings
+}
+
 func (s *PostgresSink) currentTx() pgx.Tx {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -380,9 +399,10 @@ func (s *PostgresSink) Prepare(ctx context.Context) (string, error) {
 		return localCommitTxID, nil
 	}
 
-	txID := uuid.New().String()
+	txID := preparedTxID(s.currentFencing().token)
 	// PREPARE TRANSACTION only accepts a string literal, not a bind parameter.
-	// txID is a server-generated UUID, so it is safe to interpolate.
+	// txID is generated here from a UUID and the fencing token, so it is safe
+	// to interpolate.
 	if _, err := tx.Exec(ctx, fmt.Sprintf("PREPARE TRANSACTION '%s'", txID)); err != nil {
 		return "", err
 	}
@@ -425,16 +445,6 @@ func (s *PostgresSink) RollbackPrepared(ctx context.Context, txID string) error
 	return err
 }
 
-// validateTxID ensures a prepared-transaction identifier is a well-formed UUID
-// before it is interpolated into a COMMIT/ROLLBACK PREPARED statement, which
-// does not accept bind parameters.
-func validateTxID(txID string) error {
-	if _, err := uuid.Parse(txID); err != nil {
-		return fmt.Errorf("invalid prepared transaction id: %w", err)
-	}
-	return nil
-}
-
 func (s *PostgresSink) Ping(ctx context.Context) error {
 	if err := s.init(ctx); err != nil {
 		return err
@@ -666,7 +676,7 @@ func (s *PostgresSink) primaryKeyPredicates(msg hermod.Message, startIdx int) ([
 	var pks []string
 	var args []any
 	argIdx := startIdx
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if !m.IsPrimaryKey {
 			continue
 		}
@@ -776,7 +786,7 @@ func (s *PostgresSink) reconcileExistingTable(ctx context.Context, executor pgEx
 			return fmt.Errorf("failed to truncate table %s: %w", table, err)
 		}
 	}
-	if s.autoSync && len(s.mappings) > 0 {
+	if s.autoSync && len(s.columnMappings()) > 0 {
 		if err := s.syncColumns(ctx, executor, table); err != nil {
 			return fmt.Errorf("failed to sync columns for table %s: %w", table, err)
 		}
@@ -786,9 +796,9 @@ func (s *PostgresSink) reconcileExistingTable(ctx context.Context, executor pgEx
 
 func (s *PostgresSink) createTable(ctx context.Context, executor pgExecutor, quotedTable string) error {
 	var query string
-	if len(s.mappings) > 0 {
-		cols := make([]string, 0, len(s.mappings))
-		for _, m := range s.mappings {
+	if len(s.columnMappings()) > 0 {
+		cols := make([]string, 0, len(s.columnMappings()))
+		for _, m := range s.columnMappings() {
 			colDef, err := buildColumnDefinition(m)
 			if err != nil {
 				return err
@@ -901,7 +911,7 @@ func loadColumns(ctx context.Context, executor pgExecutor, table string) (map[st
 }
 
 func (s *PostgresSink) addOrAlterColumns(ctx context.Context, executor pgExecutor, quotedTable string, current map[string]hermod.ColumnInfo) error {
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		existing, exists := current[m.TargetColumn]
 		if !exists {
 			colDef, err := buildColumnDefinition(m)
@@ -940,8 +950,8 @@ func alterColumnType(ctx context.Context, executor pgExecutor, quotedTable strin
 // is a destructive operation gated by autoSync; failures are logged rather than
 // aborting the sync so a single protected column cannot stall ingestion.
 func (s *PostgresSink) dropUnmappedColumns(ctx context.Context, executor pgExecutor, quotedTable string, current map[string]hermod.ColumnInfo) {
-	mapped := make(map[string]bool, len(s.mappings))
-	for _, m := range s.mappings {
+	mapped := make(map[string]bool, len(s.columnMappings()))
+	for _, m := range s.columnMappings() {
 		mapped[m.TargetColumn] = true
 	}
 	for name := range current {
@@ -1058,7 +1068,7 @@ func (s *PostgresSink) upsertMapped(ctx context.Context, executor pgExecutor, ta
 	var cols, placeholders, updates, pks []string
 	var args []any
 	argIdx := 1
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.SourceField == "" {
 			continue
 		}
@@ -1114,7 +1124,7 @@ func (s *PostgresSink) insertMapped(ctx context.Context, executor pgExecutor, ta
 	var cols, placeholders []string
 	var args []any
 	argIdx := 1
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.SourceField == "" {
 			continue
 		}
@@ -1151,7 +1161,7 @@ func (s *PostgresSink) updateMapped(ctx context.Context, executor pgExecutor, ta
 	var updates []string
 	var args []any
 	argIdx := 1
-	for _, m := range s.mappings {
+	for _, m := range s.columnMappings() {
 		if m.IsPrimaryKey {
 			continue
 		}
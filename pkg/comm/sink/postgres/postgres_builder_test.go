@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/user/hermod"
@@ -208,6 +209,17 @@ func TestValidateTxID(t *testing.T) {
 	if err := validateTxID("'; ROLLBACK PREPARED 'x"); err == nil {
 		t.Error("expected error for non-uuid txID")
 	}
+	for _, token := range []int64{0, 7} {
+		if id := preparedTxID(token); validateTxID(id) != nil {
+			t.Errorf("preparedTxID(%d) = %q is rejected", token, id)
+		}
+	}
+	if id := preparedTxID(7); !strings.HasPrefix(id, "hermod-7-") {
+		t.Errorf("preparedTxID(7) = %q, want the token stamped in", id)
+	}
+	if err := validateTxID("hermod-7'-550e8400-e29b-41d4-a716-446655440000"); err == nil {
+		t.Error("expected error for a malformed token")
+	}
 }
 
 func TestResolveOperation(t *testing.T) {
@@ -10,6 +10,8 @@ const (
 	QueryCreateSchema  = "CreateSchema"
 	QueryListColumns   = "ListColumns"
 	QueryTableExists   = "TableExists"
+	QueryCreateFences  = "CreateFences"
+	QueryAdvanceFence  = "AdvanceFence"
 )
 
 var commonQueries = map[string]string{
@@ -22,4 +24,6 @@ var commonQueries = map[string]string{
 	QueryCreateSchema:  "CREATE SCHEMA IF NOT EXISTS %s",
 	QueryListColumns:   "SELECT column_name, data_type, COALESCE(is_nullable = 'YES', false), EXISTS (SELECT 1 FROM information_schema.key_column_usage kcu JOIN information_schema.table_constraints tc ON kcu.constraint_name = tc.constraint_name WHERE (kcu.table_name = $1 OR kcu.table_schema || '.' || kcu.table_name = $1) AND tc.constraint_type = 'PRIMARY KEY' AND kcu.column_name = columns.column_name), COALESCE(is_identity = 'YES' OR column_default LIKE 'nextval' || chr(37), false), column_default FROM information_schema.columns WHERE table_name = $1 OR table_schema || '.' || table_name = $1 ORDER BY ordinal_position",
 	QueryTableExists:   "SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = $1",
+	QueryCreateFences:  "CREATE TABLE IF NOT EXISTS hermod_fencing_tokens (scope TEXT PRIMARY KEY, token BIGINT NOT NULL)",
+	QueryAdvanceFence:  "INSERT INTO hermod_fencing_tokens (scope, token) VALUES ($1, $2) ON CONFLICT (scope) DO UPDATE SET token = EXCLUDED.token WHERE hermod_fencing_tokens.token <= EXCLUDED.token RETURNING token",
 }
@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/sqlutil"
+)
+
+// ApplySchemaChange implements hermod.SchemaChangeSink. It evolves the target
+// table and the column mappings along with the source table: added columns
+// are added as nullable columns, dropped ones are dropped and retyped ones
+// altered. Without mappings rows are stored as JSONB and nothing changes.
+//
+// The engine keeps the updated mappings through SchemaState, so a restarted
+// sink carries on with them.
+func (s *PostgresSink) ApplySchemaChange(ctx context.Context, change hermod.SchemaChange) error {
+	mappings := s.columnMappings()
+	if len(mappings) == 0 {
+		return nil
+	}
+	if err := s.init(ctx); err != nil {
+		return err
+	}
+
+	table := s.tableName
+	if table == "" {
+		table = change.QualifiedTable()
+	}
+	quotedTable, err := quoteTable(table)
+	if err != nil {
+		return fmt.Errorf("invalid table name: %w", err)
+	}
+
+	executor, localTx, err := s.beginExecution(ctx)
+	if err != nil {
+		return err
+	}
+	if localTx != nil {
+		defer func() { _ = localTx.Rollback(ctx) }()
+	}
+	if err := s.ensureTable(ctx, executor, table); err != nil {
+		return fmt.Errorf("ensure table %s: %w", table, err)
+	}
+
+	unlock := s.lockTable(table)
+	defer unlock()
+
+	current, err := loadColumns(ctx, executor, table)
+	if err != nil {
+		return fmt.Errorf("load columns of %s: %w", table, err)
+	}
+	next, stmts, err := evolveMappings(mappings, change, current, quotedTable)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if _, err := executor.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("schema change of %s: %w", table, err)
+		}
+	}
+	if localTx != nil {
+		if err := localTx.Commit(ctx); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.mappings = next
+	s.mu.Unlock()
+	s.verifiedTables.Delete(table)
+	return nil
+}
+
+// SchemaState implements hermod.SchemaStateSink: the column mappings as
+// schema changes evolved them.
+func (s *PostgresSink) SchemaState() ([]byte, error) {
+	return json.Marshal(sqlutil.MappingState{Configured: s.configured, Mappings: s.columnMappings()})
+}
+
+// RestoreSchemaState implements hermod.SchemaStateSink. Mappings evolved
+// from a configuration that has changed since are ignored.
+func (s *PostgresSink) RestoreSchemaState(state []byte) error {
+	mappings, err := sqlutil.RestoreMappings(state, s.configured)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.mappings = mappings
+	s.mu.Unlock()
+	return nil
+}
+
+// evolveMappings returns the mappings after change and the statements that
+// bring the table, whose columns are current, in line with them. Primary key
+// columns are never dropped.
+func evolveMappings(mappings []sqlutil.ColumnMapping, change hermod.SchemaChange, current map[string]hermod.ColumnInfo, quotedTable string) ([]sqlutil.ColumnMapping, []string, error) {
+	next := slices.Clone(mappings)
+	var stmts []string
+	bySource := func(name string) int {
+		return slices.IndexFunc(next, func(m sqlutil.ColumnMapping) bool { return m.SourceField == name })
+	}
+
+	for _, c := range change.Added() {
+		if bySource(c.Name) >= 0 {
+			continue
+		}
+		// Rows already in the table have no value for the column.
+		m := sqlutil.ColumnMapping{SourceField: c.Name, TargetColumn: c.Name, DataType: pgDataType(c.Type), IsNullable: true}
+		if _, ok := current[m.TargetColumn]; !ok {
+			def, err := buildColumnDefinition(m)
+			if err != nil {
+				return nil, nil, err
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quotedTable, def))
+		}
+		next = append(next, m)
+	}
+
+	for _, c := range change.Dropped() {
+		i := bySource(c.Name)
+		if i < 0 || next[i].IsPrimaryKey {
+			continue
+		}
+		if _, ok := current[next[i].TargetColumn]; ok {
+			col, err := quoteColumn(next[i].TargetColumn)
+			if err != nil {
+				return nil, nil, err
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quotedTable, col))
+		}
+		next = slices.Delete(next, i, i+1)
+	}
+
+	for _, c := range change.Retyped() {
+		i := bySource(c.Name)
+		if i < 0 {
+			continue
+		}
+		next[i].DataType = pgDataType(c.Type)
+		existing, ok := current[next[i].TargetColumn]
+		if !ok {
+			continue
+		}
+		dataType, err := baseDataType(next[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.EqualFold(existing.Type, dataType) {
+			continue
+		}
+		col, err := quoteColumn(next[i].TargetColumn)
+		if err != nil {
+			return nil, nil, err
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s", quotedTable, col, dataType, col, dataType))
+	}
+	return next, stmts, nil
+}
+
+// pgDataType translates a column type of a source database to PostgreSQL.
+// Types it does not know become TEXT.
+func pgDataType(sqlType string) string {
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	t = strings.TrimSpace(strings.ReplaceAll(t, "unsigned", ""))
+	if t == "tinyint(1)" {
+		return "BOOLEAN"
+	}
+	base, args, _ := strings.Cut(t, "(")
+	base = strings.TrimSpace(base)
+	switch base {
+	case "bit", "bool", "boolean":
+		return "BOOLEAN"
+	case "tinyint", "smallint", "int2":
+		return "SMALLINT"
+	case "mediumint", "int", "integer", "int4", "serial":
+		return "INTEGER"
+	case "bigint", "int8", "bigserial":
+		return "BIGINT"
+	case "datetime", "datetime2", "smalldatetime", "timestamp", "timestamp without time zone":
+		return "TIMESTAMP"
+	case "datetimeoffset", "timestamptz", "timestamp with time zone":
+		return "TIMESTAMPTZ"
+	case "double", "double precision", "float", "float8":
+		return "DOUBLE PRECISION"
+	case "real", "float4":
+		return "REAL"
+	case "decimal", "numeric", "money", "smallmoney":
+		if args != "" && base != "money" && base != "smallmoney" {
+			return "NUMERIC(" + strings.TrimSuffix(args, ")") + ")"
+		}
+		return "NUMERIC"
+	case "tinytext", "text", "mediumtext", "longtext", "ntext", "enum", "set", "xml":
+		return "TEXT"
+	case "char", "nchar", "bpchar", "varchar", "nvarchar", "character varying", "character":
+		if args == "" || strings.HasPrefix(args, "max") {
+			return "TEXT"
+		}
+		return "VARCHAR(" + strings.TrimSuffix(args, ")") + ")"
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "image", "bytea":
+		return "BYTEA"
+	case "json", "jsonb":
+		return "JSONB"
+	case "uniqueidentifier", "uuid":
+		return "UUID"
+	case "date", "time", "interval", "inet", "cidr":
+		return strings.ToUpper(base)
+	}
+	return "TEXT"
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/sqlutil"
+)
+
+func TestPgDataType(t *testing.T) {
+	tests := map[string]string{
+		"tinyint(1)":        "BOOLEAN",
+		"int(11) unsigned":  "INTEGER",
+		"bigint":            "BIGINT",
+		"varchar(255)":      "VARCHAR(255)",
+		"nvarchar(max)":     "TEXT",
+		"decimal(10,2)":     "NUMERIC(10,2)",
+		"datetime2":         "TIMESTAMP",
+		"datetimeoffset":    "TIMESTAMPTZ",
+		"longblob":          "BYTEA",
+		"json":              "JSONB",
+		"uniqueidentifier":  "UUID",
+		"enum('a','b')":     "TEXT",
+		"double precision":  "DOUBLE PRECISION",
+		"geometry":          "TEXT",
+		"character varying": "TEXT",
+	}
+	for in, want := range tests {
+		if got := pgDataType(in); got != want {
+			t.Errorf("pgDataType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEvolveMappings(t *testing.T) {
+	mappings := []sqlutil.ColumnMapping{
+		{SourceField: "id", TargetColumn: "id", DataType: "INTEGER", IsPrimaryKey: true},
+		{SourceField: "name", TargetColumn: "full_name", DataType: "TEXT", IsNullable: true},
+		{SourceField: "age", TargetColumn: "age", DataType: "INTEGER", IsNullable: true},
+	}
+	current := map[string]hermod.ColumnInfo{
+		"id":        {Name: "id", Type: "integer"},
+		"full_name": {Name: "full_name", Type: "text"},
+		"age":       {Name: "age", Type: "integer"},
+	}
+	change := hermod.SchemaChange{
+		Table: "users",
+		Previous: []hermod.ColumnInfo{
+			{Name: "id", Type: "int"}, {Name: "name", Type: "text"}, {Name: "age", Type: "int"},
+		},
+		Columns: []hermod.ColumnInfo{
+			{Name: "id", Type: "int"}, {Name: "name", Type: "varchar(50)"}, {Name: "email", Type: "varchar(100)"},
+		},
+	}
+
+	next, stmts, err := evolveMappings(mappings, change, current, `"users"`)
+	if err != nil {
+		t.Fatalf("evolveMappings failed: %v", err)
+	}
+	wantStmts := []string{
+		`ALTER TABLE "users" ADD COLUMN "email" VARCHAR(100)`,
+		`ALTER TABLE "users" DROP COLUMN "age"`,
+		`ALTER TABLE "users" ALTER COLUMN "full_name" TYPE VARCHAR(50) USING "full_name"::VARCHAR(50)`,
+	}
+	if !slices.Equal(stmts, wantStmts) {
+		t.Errorf("statements = %q, want %q", stmts, wantStmts)
+	}
+	var targets []string
+	for _, m := range next {
+		targets = append(targets, m.TargetColumn)
+	}
+	if !slices.Equal(targets, []string{"id", "full_name", "email"}) {
+		t.Errorf("mapped columns = %v", targets)
+	}
+	if len(mappings) != 3 || mappings[1].DataType != "TEXT" {
+		t.Error("evolveMappings must not modify the mappings it is given")
+	}
+
+	// A dropped primary key stays, as rows could no longer be addressed.
+	dropPK := hermod.SchemaChange{Table: "users", Previous: change.Previous, Columns: change.Previous[1:]}
+	next, stmts, err = evolveMappings(mappings, dropPK, current, `"users"`)
+	if err != nil || len(stmts) != 0 || len(next) != len(mappings) {
+		t.Errorf("dropping a primary key: %d statements, %d mappings, err %v", len(stmts), len(next), err)
+	}
+}
+
+// The mappings a schema change evolved reach the sink of the next run.
+func TestSchemaStateRestoresMappings(t *testing.T) {
+	configured := []sqlutil.ColumnMapping{{SourceField: "id", TargetColumn: "id", IsPrimaryKey: true}}
+	s := NewPostgresSink("", "users", configured, false, "", "", "", "", false, false)
+	s.mappings = append(slices.Clone(configured), sqlutil.ColumnMapping{SourceField: "email", TargetColumn: "email", DataType: "TEXT", IsNullable: true})
+	state, err := s.SchemaState()
+	if err != nil {
+		t.Fatalf("SchemaState: %v", err)
+	}
+
+	next := NewPostgresSink("", "users", configured, false, "", "", "", "", false, false)
+	if err := next.RestoreSchemaState(state); err != nil {
+		t.Fatalf("RestoreSchemaState: %v", err)
+	}
+	if got := next.columnMappings(); !slices.Equal(got, s.mappings) {
+		t.Fatalf("restored mappings = %v, want %v", got, s.mappings)
+	}
+}
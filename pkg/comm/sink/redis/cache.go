@@ -0,0 +1,469 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tidwall/gjson"
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/evaluator"
+)
+
+// Mode is the Redis data structure a sink writes.
+type Mode string
+
+const (
+	// ModeStream appends every message to a stream with XADD.
+	ModeStream Mode = "stream"
+	// ModeString sets a key to the formatted message.
+	ModeString Mode = "string"
+	// ModeHash sets the fields of a hash from the message's columns.
+	ModeHash Mode = "hash"
+	// ModeJSON sets a RedisJSON document to the message's row.
+	ModeJSON Mode = "json"
+	// ModeSortedSet adds a member to a sorted set with a computed score.
+	ModeSortedSet Mode = "zset"
+	// ModeSet adds a member to a set.
+	ModeSet Mode = "set"
+)
+
+// ErrInvalidMessage is returned for messages a cache mode cannot write, such
+// as messages without the version column or with a score that is not a
+// number.
+var ErrInvalidMessage = errors.New("invalid message")
+
+// CacheConfig configures the modes that keep Redis data structures in sync
+// with the rows of a CDC stream. Creates, updates and snapshots write the
+// row; deletes remove it.
+type CacheConfig struct {
+	Mode Mode
+	// Key is the template of the key written, such as "user:{{ id }}".
+	Key string
+	// Fields maps hash fields to the columns, or expressions, they are set
+	// from. Without it, a hash gets every top-level column of the row.
+	Fields map[string]string
+	// Path is the RedisJSON path the row is set at, "$" by default.
+	Path string
+	// Member is the template of sorted set and set members. Without it, the
+	// member is the formatted message.
+	Member string
+	// Score is the expression giving the score of sorted set members. It
+	// must evaluate to a number or a timestamp, which scores as Unix
+	// seconds.
+	Score string
+	// TTL is the expression giving a key's time to live, in seconds or as a
+	// duration such as "1h". Keys without one do not expire.
+	TTL string
+	// VersionColumn turns every write into a compare-and-set: a row is only
+	// written, or deleted, when its version is newer than the last one
+	// applied to its key, so replays never overwrite newer data. Numbers,
+	// numeric strings and timestamps compare by value, other strings
+	// byte-wise after every number.
+	VersionColumn string
+}
+
+// command is one Redis command of a message's write.
+type command []any
+
+// write is what a message turns into: the commands to run, and where its
+// version is kept when writes are compare-and-set.
+type write struct {
+	key      string
+	commands []command
+	// versionKey and versionField locate the last applied version: a plain
+	// key for per-key modes, or a field of a hash of versions per member.
+	versionKey   string
+	versionField string
+	version      string
+	delete       bool
+}
+
+// casScript applies a write when its version is newer than the recorded
+// one. KEYS[1] holds the versions and KEYS[2] is the key written. ARGV holds
+// the version, encoded by encodeVersion so that comparing the strings
+// compares the versions, the version field ("" for a plain key), "1" for
+// deletes, the number of commands, and then each command as its arity
+// followed by its arguments. A delete also applies at the recorded version,
+// since its before image carries the version of the row it removes. The
+// script returns 0 for stale writes.
+var casScript = redis.NewScript(`
+local cur
+if ARGV[2] == '' then
+	cur = redis.call('GET', KEYS[1])
+else
+	cur = redis.call('HGET', KEYS[1], ARGV[2])
+end
+if cur and (cur > ARGV[1] or (cur == ARGV[1] and ARGV[3] ~= '1')) then
+	return 0
+end
+if ARGV[2] == '' then
+	redis.call('SET', KEYS[1], ARGV[1])
+else
+	redis.call('HSET', KEYS[1], ARGV[2], ARGV[1])
+end
+local i = 5
+for _ = 1, tonumber(ARGV[4]) do
+	local n = tonumber(ARGV[i])
+	redis.call(unpack(ARGV, i + 1, i + n))
+	i = i + n + 1
+end
+return 1
+`)
+
+// SetCacheMode switches the sink from appending to a stream to keeping the
+// data structure of cfg in sync.
+func (s *RedisSink) SetCacheMode(cfg CacheConfig) error {
+	switch cfg.Mode {
+	case "", ModeStream:
+		s.cache = nil
+		return nil
+	case ModeString, ModeHash, ModeJSON, ModeSet:
+	case ModeSortedSet:
+		if cfg.Score == "" {
+			return errors.New("redis zset mode needs a score expression")
+		}
+	default:
+		return fmt.Errorf("unsupported redis sink mode %q", cfg.Mode)
+	}
+	if cfg.Key == "" {
+		return fmt.Errorf("redis %s mode needs a key template", cfg.Mode)
+	}
+	if cfg.Path == "" {
+		cfg.Path = "$"
+	}
+	s.cache = &cfg
+	return nil
+}
+
+// cacheWrite builds the write of msg in the configured cache mode.
+func (s *RedisSink) cacheWrite(msg hermod.Message) (write, error) {
+	cfg := s.cache
+	data := msg.Data()
+	if len(data) == 0 && len(msg.Before()) > 0 {
+		_ = json.Unmarshal(msg.Before(), &data)
+	}
+	if len(data) == 0 {
+		return write{}, invalid("message %s has no data", msg.ID())
+	}
+	del := msg.Operation() == hermod.OpDelete
+
+	key := evaluator.ResolveTemplate(cfg.Key, data)
+	if key == "" {
+		return write{}, invalid("key template %q is empty for message %s", cfg.Key, msg.ID())
+	}
+	var ttl time.Duration
+	if cfg.TTL != "" && !del {
+		var err error
+		if ttl, err = evalTTL(msg, cfg.TTL); err != nil {
+			return write{}, err
+		}
+	}
+
+	w := write{key: key, versionKey: sameSlot(key, "version"), delete: del}
+	switch cfg.Mode {
+	case ModeString:
+		if del {
+			w.commands = []command{{"DEL", key}}
+			break
+		}
+		val, err := s.format(msg)
+		if err != nil {
+			return write{}, err
+		}
+		set := command{"SET", key, string(val)}
+		if ttl > 0 {
+			set = append(set, "PX", ttl.Milliseconds())
+		}
+		w.commands = []command{set}
+	case ModeHash:
+		w.commands = hashCommands(msg, key, cfg.Fields, data, del)
+	case ModeJSON:
+		if del {
+			if cfg.Path == "$" || cfg.Path == "." {
+				w.commands = []command{{"DEL", key}}
+			} else {
+				w.commands = []command{{"JSON.DEL", key, cfg.Path}}
+			}
+			break
+		}
+		doc, err := json.Marshal(data)
+		if err != nil {
+			return write{}, invalid("%v", err)
+		}
+		w.commands = []command{{"JSON.SET", key, cfg.Path, string(doc)}}
+	case ModeSortedSet, ModeSet:
+		member, err := s.member(msg, data)
+		if err != nil {
+			return write{}, err
+		}
+		w.versionKey, w.versionField = sameSlot(key, "versions"), member
+		switch {
+		case del && cfg.Mode == ModeSortedSet:
+			w.commands = []command{{"ZREM", key, member}}
+		case del:
+			w.commands = []command{{"SREM", key, member}}
+		case cfg.Mode == ModeSortedSet:
+			score, err := evalScore(msg, cfg.Score)
+			if err != nil {
+				return write{}, err
+			}
+			w.commands = []command{{"ZADD", key, strconv.FormatFloat(score, 'f', -1, 64), member}}
+		default:
+			w.commands = []command{{"SADD", key, member}}
+		}
+	}
+	if ttl > 0 && cfg.Mode != ModeString {
+		w.commands = append(w.commands, command{"PEXPIRE", key, ttl.Milliseconds()})
+	}
+
+	if cfg.VersionColumn != "" {
+		raw := evaluator.GetValByPath(data, cfg.VersionColumn)
+		if f, ok := raw.(float64); ok && math.Abs(f) >= 1<<53 {
+			// The row's data rounds integers above 2^53; read the exact
+			// digits from the row itself.
+			if n, ok := exactNumber(msg, cfg.VersionColumn); ok {
+				raw = n
+			}
+		}
+		v, ok := encodeVersion(raw)
+		if !ok {
+			return write{}, invalid("message %s has no version in %q", msg.ID(), cfg.VersionColumn)
+		}
+		w.version = v
+		if ttl > 0 && w.versionField == "" {
+			// The version outlives the key no longer than the row does.
+			w.commands = append(w.commands, command{"PEXPIRE", w.versionKey, ttl.Milliseconds()})
+		}
+	}
+	return w, nil
+}
+
+// hashCommands sets the mapped fields of a hash, removing fields whose
+// value is null. Deletes remove the mapped fields, or the whole hash when
+// no fields are mapped.
+func hashCommands(msg hermod.Message, key string, fields map[string]string, data map[string]any, del bool) []command {
+	if del && len(fields) == 0 {
+		return []command{{"DEL", key}}
+	}
+	values := make(map[string]any, len(data))
+	if len(fields) == 0 {
+		values = data
+	} else {
+		for field, expr := range fields {
+			values[field] = evaluator.EvaluateField(msg, expr)
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hset, hdel := command{"HSET", key}, command{"HDEL", key}
+	for _, name := range names {
+		v := values[name]
+		if del || v == nil {
+			hdel = append(hdel, name)
+			continue
+		}
+		hset = append(hset, name, fieldValue(v))
+	}
+	var cmds []command
+	if len(hset) > 2 {
+		cmds = append(cmds, hset)
+	}
+	if len(hdel) > 2 {
+		cmds = append(cmds, hdel)
+	}
+	return cmds
+}
+
+// fieldValue is the string a hash field holds for v. Objects and arrays are
+// stored as JSON.
+func fieldValue(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case map[string]any, []any:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// member is the set member a message stands for.
+func (s *RedisSink) member(msg hermod.Message, data map[string]any) (string, error) {
+	if s.cache.Member != "" {
+		m := evaluator.ResolveTemplate(s.cache.Member, data)
+		if m == "" {
+			return "", invalid("member template %q is empty for message %s", s.cache.Member, msg.ID())
+		}
+		return m, nil
+	}
+	b, err := s.format(msg)
+	return string(b), err
+}
+
+func (s *RedisSink) format(msg hermod.Message) ([]byte, error) {
+	if s.formatter == nil {
+		return msg.Payload(), nil
+	}
+	b, err := s.formatter.Format(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format message: %w", err)
+	}
+	return b, nil
+}
+
+func evalScore(msg hermod.Message, expr string) (float64, error) {
+	v := evaluator.EvaluateField(msg, expr)
+	if f, ok := evaluator.ToFloat64(v); ok {
+		return f, nil
+	}
+	if t, ok := evaluator.ToTime(v); ok {
+		return float64(t.UnixNano()) / 1e9, nil
+	}
+	return 0, invalid("score %q is not a number: %v", expr, v)
+}
+
+// evalTTL evaluates a TTL expression to seconds or a duration. Constants
+// such as "3600" or "1h" apply to every key. Empty values mean no expiry.
+func evalTTL(msg hermod.Message, expr string) (time.Duration, error) {
+	if d, err := time.ParseDuration(expr); err == nil {
+		return d, nil
+	}
+	if f, err := strconv.ParseFloat(expr, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	v := evaluator.EvaluateField(msg, expr)
+	if s, ok := v.(string); ok {
+		if s == "" {
+			return 0, nil
+		}
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+	}
+	if v == nil {
+		return 0, nil
+	}
+	if f, ok := evaluator.ToFloat64(v); ok {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	return 0, invalid("ttl %q is not a duration: %v", expr, v)
+}
+
+// sameSlot names the key holding the versions of key, with a hash tag that
+// keeps it in key's Redis Cluster slot so the script can touch both. A key
+// with a tag of its own already shares it; a key containing "}" cannot be
+// tagged and relies on a single-node deployment.
+func sameSlot(key, suffix string) string {
+	if open := strings.IndexByte(key, '{'); open >= 0 {
+		if end := strings.IndexByte(key[open+1:], '}'); end > 0 {
+			return key + ":" + suffix
+		}
+	}
+	if strings.ContainsAny(key, "{}") {
+		return key + ":" + suffix
+	}
+	return "{" + key + "}:" + suffix
+}
+
+// exactNumber reads column from the raw row of msg without rounding it to a
+// float64.
+func exactNumber(msg hermod.Message, column string) (json.Number, bool) {
+	row := msg.After()
+	if len(row) == 0 {
+		row = msg.Before()
+	}
+	res := gjson.GetBytes(row, column)
+	if res.Type != gjson.Number {
+		return "", false
+	}
+	return json.Number(res.Raw), true
+}
+
+// decimalPattern matches the plain decimal numbers encodeVersion encodes
+// digit by digit.
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// encodeVersion encodes a version so that comparing the encodings as
+// strings compares the versions, which Lua can do without the precision
+// loss of tonumber. Numbers of any size encode as "n" (negative) or "p"
+// followed by their digits, timestamps as their Unix nanoseconds, and other
+// strings as "s" followed by the string, after every number.
+func encodeVersion(v any) (string, bool) {
+	switch x := v.(type) {
+	case nil:
+		return "", false
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return "", false
+		}
+		return encodeDecimal(strconv.FormatFloat(x, 'f', -1, 64)), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return encodeDecimal(fmt.Sprint(x)), true
+	case json.Number:
+		if decimalPattern.MatchString(x.String()) {
+			return encodeDecimal(x.String()), true
+		}
+		if f, err := x.Float64(); err == nil {
+			return encodeVersion(f)
+		}
+		return "s" + x.String(), true
+	case time.Time:
+		return encodeDecimal(strconv.FormatInt(x.UnixNano(), 10)), true
+	case string:
+		if x == "" {
+			return "", false
+		}
+		if decimalPattern.MatchString(x) {
+			return encodeDecimal(x), true
+		}
+		if t, err := time.Parse(time.RFC3339Nano, x); err == nil {
+			return encodeVersion(t)
+		}
+		return "s" + x, true
+	}
+	return encodeVersion(fmt.Sprint(v))
+}
+
+// encodeDecimal encodes a plain decimal number. A non-negative number is "p",
+// the length of its integer part in three digits, the integer part and the
+// fraction, so longer integer parts sort later and equal lengths compare
+// digit by digit. A negative number is "n" and the same with the length and
+// every digit complemented, ending in "z" so that a longer fraction, a
+// larger magnitude, sorts first.
+func encodeDecimal(d string) string {
+	neg := strings.HasPrefix(d, "-")
+	ip, fp, _ := strings.Cut(strings.TrimPrefix(d, "-"), ".")
+	ip, fp = strings.TrimLeft(ip, "0"), strings.TrimRight(fp, "0")
+	if ip == "" && fp == "" {
+		neg = false
+	}
+	if !neg {
+		return fmt.Sprintf("p%03d%s%s", len(ip), ip, fp)
+	}
+	complement := func(digits string) string {
+		b := []byte(digits)
+		for i, c := range b {
+			b[i] = '9' - c + '0'
+		}
+		return string(b)
+	}
+	return fmt.Sprintf("n%03d%s%sz", 999-len(ip), complement(ip), complement(fp))
+}
+
+func invalid(format string, args ...any) error {
+	return hermod.Classify(fmt.Errorf("%w: "+format, append([]any{ErrInvalidMessage}, args...)...), hermod.ErrorPermanent)
+}
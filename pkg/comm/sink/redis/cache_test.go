@@ -0,0 +1,223 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
+)
+
+func cacheMsg(op hermod.Operation, row string) hermod.Message {
+	m := message.AcquireMessage()
+	m.SetID("m1")
+	m.SetOperation(op)
+	if op == hermod.OpDelete {
+		m.SetBefore([]byte(row))
+	} else {
+		m.SetAfter([]byte(row))
+	}
+	return m
+}
+
+func cacheSink(t *testing.T, cfg CacheConfig) *RedisSink {
+	t.Helper()
+	s, _ := NewRedisSink("localhost:6379", "", "", nil)
+	if err := s.SetCacheMode(cfg); err != nil {
+		t.Fatalf("SetCacheMode: %v", err)
+	}
+	return s
+}
+
+func TestCacheWrites(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  CacheConfig
+		op   hermod.Operation
+		row  string
+		want string
+	}{
+		{
+			name: "string with ttl",
+			cfg:  CacheConfig{Mode: ModeString, Key: "user:{{ id }}", TTL: "ttl"},
+			op:   hermod.OpCreate,
+			row:  `{"id":1,"ttl":"1m"}`,
+			want: `[[SET user:1 {"id":1,"ttl":"1m"} PX 60000]]`,
+		},
+		{
+			name: "string delete",
+			cfg:  CacheConfig{Mode: ModeString, Key: "user:{{ id }}"},
+			op:   hermod.OpDelete,
+			row:  `{"id":1}`,
+			want: `[[DEL user:1]]`,
+		},
+		{
+			name: "hash of mapped fields",
+			cfg:  CacheConfig{Mode: ModeHash, Key: "user:{{ id }}", Fields: map[string]string{"n": "name", "e": "email", "tags": "tags"}, TTL: "30"},
+			op:   hermod.OpUpdate,
+			row:  `{"id":1,"name":"ada","email":null,"tags":["a"]}`,
+			want: `[[HSET user:1 n ada tags ["a"]] [HDEL user:1 e] [PEXPIRE user:1 30000]]`,
+		},
+		{
+			name: "hash delete of mapped fields",
+			cfg:  CacheConfig{Mode: ModeHash, Key: "user:{{ id }}", Fields: map[string]string{"n": "name"}},
+			op:   hermod.OpDelete,
+			row:  `{"id":1,"name":"ada"}`,
+			want: `[[HDEL user:1 n]]`,
+		},
+		{
+			name: "json",
+			cfg:  CacheConfig{Mode: ModeJSON, Key: "doc:{{ id }}"},
+			op:   hermod.OpSnapshot,
+			row:  `{"id":2,"a":{"b":true}}`,
+			want: `[[JSON.SET doc:2 $ {"a":{"b":true},"id":2}]]`,
+		},
+		{
+			name: "sorted set by timestamp",
+			cfg:  CacheConfig{Mode: ModeSortedSet, Key: "recent", Member: "{{ id }}", Score: "updated_at"},
+			op:   hermod.OpUpdate,
+			row:  `{"id":3,"updated_at":"2024-05-01T00:00:00Z"}`,
+			want: `[[ZADD recent 1714521600 3]]`,
+		},
+		{
+			name: "sorted set delete",
+			cfg:  CacheConfig{Mode: ModeSortedSet, Key: "recent", Member: "{{ id }}", Score: "updated_at"},
+			op:   hermod.OpDelete,
+			row:  `{"id":3}`,
+			want: `[[ZREM recent 3]]`,
+		},
+		{
+			name: "set membership",
+			cfg:  CacheConfig{Mode: ModeSet, Key: "group:{{ group }}", Member: "{{ user }}"},
+			op:   hermod.OpCreate,
+			row:  `{"group":"admins","user":"ada"}`,
+			want: `[[SADD group:admins ada]]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := cacheSink(t, tt.cfg)
+			w, err := s.cacheWrite(cacheMsg(tt.op, tt.row))
+			if err != nil {
+				t.Fatalf("cacheWrite: %v", err)
+			}
+			if got := fmt.Sprint(w.commands); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheVersions(t *testing.T) {
+	s := cacheSink(t, CacheConfig{Mode: ModeHash, Key: "user:{{ id }}", TTL: "60", VersionColumn: "version"})
+	w, err := s.cacheWrite(cacheMsg(hermod.OpUpdate, `{"id":1,"version":7}`))
+	if err != nil {
+		t.Fatalf("cacheWrite: %v", err)
+	}
+	if w.version != "p0017" || w.versionKey != "{user:1}:version" || w.versionField != "" || w.delete {
+		t.Fatalf("unexpected version of %+v", w)
+	}
+	if got := fmt.Sprint(w.commands[len(w.commands)-1]); got != "[PEXPIRE {user:1}:version 60000]" {
+		t.Fatalf("version key does not expire with the row: %s", got)
+	}
+
+	s = cacheSink(t, CacheConfig{Mode: ModeSet, Key: "members", Member: "{{ id }}", VersionColumn: "version"})
+	w, err = s.cacheWrite(cacheMsg(hermod.OpDelete, `{"id":1,"version":"2024-05-01T00:00:00Z"}`))
+	if err != nil {
+		t.Fatalf("cacheWrite: %v", err)
+	}
+	if w.versionKey != "{members}:versions" || w.versionField != "1" || !w.delete {
+		t.Fatalf("unexpected version of %+v", w)
+	}
+
+	_, err = s.cacheWrite(cacheMsg(hermod.OpCreate, `{"id":1}`))
+	if !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("got %v, want an invalid message", err)
+	}
+	if class, _ := hermod.ClassifyError(err, s); class != hermod.ErrorPermanent {
+		t.Fatalf("missing versions classified as %v", class)
+	}
+}
+
+// Encoded versions compare as strings the way the versions compare as
+// values, which is all the compare-and-set script does with them.
+func TestEncodeVersionOrder(t *testing.T) {
+	ordered := []any{
+		-1e20, "-100", -10.5, -10.25, -10, -1, -0.5, 0, "0.25", 0.5, 1, 2, 9, 10, 10.5, 99,
+		json.Number("9007199254740992"), json.Number("9007199254740993"),
+		time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), "2024-05-01T00:00:00.5Z", "2024-05-01T00:00:01+00:00",
+		uint64(1) << 63, "0/16B3748", "abc",
+	}
+	prev := ""
+	for i, v := range ordered {
+		enc, ok := encodeVersion(v)
+		if !ok {
+			t.Fatalf("%v: not a version", v)
+		}
+		if i > 0 && enc <= prev {
+			t.Errorf("%v encodes as %q, not after %v (%q)", v, enc, ordered[i-1], prev)
+		}
+		prev = enc
+	}
+	for _, same := range [][2]any{{7, "7"}, {7.0, "007"}, {"1.50", 1.5}, {0, "-0"}} {
+		a, _ := encodeVersion(same[0])
+		b, _ := encodeVersion(same[1])
+		if a != b {
+			t.Errorf("%v and %v encode differently: %q, %q", same[0], same[1], a, b)
+		}
+	}
+	if _, ok := encodeVersion(""); ok {
+		t.Error("an empty version was accepted")
+	}
+}
+
+// Integers above 2^53 keep their exact value although the row's data rounds
+// them.
+func TestCacheVersionsKeepLargeIntegers(t *testing.T) {
+	s := cacheSink(t, CacheConfig{Mode: ModeString, Key: "k:{{ id }}", VersionColumn: "lsn"})
+	older, err := s.cacheWrite(cacheMsg(hermod.OpUpdate, `{"id":1,"lsn":9007199254740992}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := s.cacheWrite(cacheMsg(hermod.OpUpdate, `{"id":1,"lsn":9007199254740993}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newer.version <= older.version {
+		t.Fatalf("versions %q and %q do not order", older.version, newer.version)
+	}
+}
+
+func TestSameSlot(t *testing.T) {
+	for key, want := range map[string]string{
+		"user:1":    "{user:1}:version",
+		"user:{42}": "user:{42}:version",
+		"a{}b":      "a{}b:version",
+	} {
+		if got := sameSlot(key, "version"); got != want {
+			t.Errorf("sameSlot(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSetCacheMode(t *testing.T) {
+	s, _ := NewRedisSink("localhost:6379", "", "", nil)
+	if err := s.SetCacheMode(CacheConfig{Mode: ModeSortedSet, Key: "k"}); err == nil {
+		t.Fatal("expected an error for a sorted set without a score")
+	}
+	if err := s.SetCacheMode(CacheConfig{Mode: "list", Key: "k"}); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+	if err := s.SetCacheMode(CacheConfig{}); err != nil || s.cache != nil {
+		t.Fatalf("the default mode is not the stream: %v", err)
+	}
+	if _, err := evalTTL(cacheMsg(hermod.OpCreate, `{"ttl":"soon"}`), "ttl"); err == nil {
+		t.Fatal("expected an error for an invalid ttl")
+	}
+	if d, _ := evalTTL(cacheMsg(hermod.OpCreate, `{"ttl":1.5}`), "ttl"); d != 1500*time.Millisecond {
+		t.Fatalf("got ttl %v", d)
+	}
+}
@@ -2,8 +2,10 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,12 +13,14 @@ import (
 	"github.com/user/hermod"
 )
 
-// RedisSink implements the hermod.Sink interface for Redis (using Streams).
+// RedisSink implements the hermod.Sink interface for Redis. It appends to a
+// stream, or keeps a data structure in sync when a cache mode is set.
 type RedisSink struct {
 	addr      string
 	password  string
 	stream    string
 	formatter hermod.Formatter
+	cache     *CacheConfig
 	client    *redis.Client
 	mu        sync.Mutex
 	// idempotency reporting (last write outcome)
@@ -61,22 +65,33 @@ func (s *RedisSink) init(ctx context.Context) error {
 	return nil
 }
 
-func (s *RedisSink) Write(ctx context.Context, msg hermod.Message) error {
-	if msg == nil {
-		return nil
-	}
-
+func (s *RedisSink) getClient(ctx context.Context) (*redis.Client, error) {
 	s.mu.Lock()
 	cl := s.client
 	s.mu.Unlock()
 	if cl == nil {
 		if err := s.init(ctx); err != nil {
-			return err
+			return nil, err
 		}
 		s.mu.Lock()
 		cl = s.client
 		s.mu.Unlock()
 	}
+	return cl, nil
+}
+
+func (s *RedisSink) Write(ctx context.Context, msg hermod.Message) error {
+	if msg == nil {
+		return nil
+	}
+	if s.cache != nil {
+		return hermod.BatchError(s.WriteBatchResults(ctx, []hermod.Message{msg}))
+	}
+
+	cl, err := s.getClient(ctx)
+	if err != nil {
+		return err
+	}
 
 	// reset last outcome
 	s.mu.Lock()
@@ -111,7 +126,6 @@ func (s *RedisSink) Write(ctx context.Context, msg hermod.Message) error {
 	}
 
 	var data []byte
-	var err error
 
 	if s.formatter != nil {
 		data, err = s.formatter.Format(msg)
@@ -135,8 +149,121 @@ func (s *RedisSink) Write(ctx context.Context, msg hermod.Message) error {
 	return nil
 }
 
+func (s *RedisSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults writes msgs, reporting an outcome per message. In a
+// cache mode the batch is sent as one pipelined transaction.
+func (s *RedisSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	errs := make([]error, len(msgs))
+	if s.cache == nil {
+		for i, msg := range msgs {
+			errs[i] = s.Write(ctx, msg)
+		}
+		return errs, nil
+	}
+
+	cl, err := s.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	writes := make([]write, len(msgs))
+	for i, msg := range msgs {
+		if msg != nil {
+			writes[i], errs[i] = s.cacheWrite(msg)
+		}
+	}
+
+	cmds, err := s.pipeline(ctx, cl, writes, errs)
+	if noScript(cmds) {
+		// The server lost its scripts, e.g. after a restart. Writes are
+		// idempotent, so the batch is simply sent again.
+		if err = casScript.Load(ctx, cl).Err(); err == nil {
+			cmds, err = s.pipeline(ctx, cl, writes, errs)
+		}
+	}
+	var rerr redis.Error
+	if err != nil && !errors.As(err, &rerr) {
+		return nil, fmt.Errorf("failed to write to redis: %w", err)
+	}
+
+	stale := false
+	for i, mc := range cmds {
+		for _, c := range mc {
+			if err := c.Err(); err != nil {
+				errs[i] = fmt.Errorf("redis %s: %w", c.Name(), err)
+				break
+			}
+			if ev, ok := c.(*redis.Cmd); ok && writes[i].version != "" {
+				if n, _ := ev.Int(); n == 0 {
+					stale = true
+				}
+			}
+		}
+	}
+	s.mu.Lock()
+	s.lastDedup = false
+	s.lastConflict = stale
+	s.mu.Unlock()
+	return errs, nil
+}
+
+// pipeline sends the writes that were built without error as one
+// transaction, returning the commands of each.
+func (s *RedisSink) pipeline(ctx context.Context, cl *redis.Client, writes []write, errs []error) ([][]redis.Cmder, error) {
+	cmds := make([][]redis.Cmder, len(writes))
+	_, err := cl.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, w := range writes {
+			if errs[i] != nil || len(w.commands) == 0 {
+				continue
+			}
+			if s.cache.VersionColumn == "" {
+				for _, c := range w.commands {
+					cmds[i] = append(cmds[i], pipe.Do(ctx, c...))
+				}
+				continue
+			}
+			del := "0"
+			if w.delete {
+				del = "1"
+			}
+			args := []any{w.version, w.versionField, del, len(w.commands)}
+			for _, c := range w.commands {
+				args = append(args, len(c))
+				args = append(args, c...)
+			}
+			cmds[i] = []redis.Cmder{casScript.EvalSha(ctx, pipe, []string{w.versionKey, w.key}, args...)}
+		}
+		return nil
+	})
+	return cmds, err
+}
+
+func noScript(cmds [][]redis.Cmder) bool {
+	for _, mc := range cmds {
+		for _, c := range mc {
+			if err := c.Err(); err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ClassifyError treats keys of the wrong type and commands the server does
+// not know, such as RedisJSON's without the module, as misconfiguration.
+func (s *RedisSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	msg := err.Error()
+	if strings.Contains(msg, "WRONGTYPE") || strings.Contains(msg, "unknown command") {
+		return hermod.ErrorFatalConfig, 0
+	}
+	return hermod.ErrorTransient, 0
+}
+
 // LastWriteIdempotent reports whether the last Write call resulted in a dedup skip
-// or a conflict. Redis sink only reports dedup (conflicts are not applicable).
+// or a conflict. Streams report dedups, and compare-and-set cache writes
+// report stale versions as conflicts.
 func (s *RedisSink) LastWriteIdempotent() (bool, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -144,16 +271,9 @@ func (s *RedisSink) LastWriteIdempotent() (bool, bool) {
 }
 
 func (s *RedisSink) Ping(ctx context.Context) error {
-	s.mu.Lock()
-	cl := s.client
-	s.mu.Unlock()
-	if cl == nil {
-		if err := s.init(ctx); err != nil {
-			return err
-		}
-		s.mu.Lock()
-		cl = s.client
-		s.mu.Unlock()
+	cl, err := s.getClient(ctx)
+	if err != nil {
+		return err
 	}
 	return cl.Ping(ctx).Err()
 }
@@ -44,3 +44,44 @@ func TestRedisSink_Write(t *testing.T) {
 		t.Errorf("failed to write to RedisSink: %v", err)
 	}
 }
+
+func TestRedisSink_CacheCompareAndSet(t *testing.T) {
+	if os.Getenv("HERMOD_INTEGRATION") != "1" {
+		t.Skip("skipping integration test; set HERMOD_INTEGRATION=1 to run")
+	}
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("integration test: set REDIS_ADDR to run")
+	}
+
+	snk, err := NewRedisSink(addr, os.Getenv("REDIS_PASSWORD"), "", nil)
+	if err != nil {
+		t.Fatalf("failed to create RedisSink: %v", err)
+	}
+	defer snk.Close()
+	if err := snk.SetCacheMode(CacheConfig{Mode: ModeHash, Key: "hermod:test:{{ id }}", VersionColumn: "version"}); err != nil {
+		t.Fatalf("SetCacheMode: %v", err)
+	}
+
+	row := func(op hermod.Operation, body string) hermod.Message {
+		m := message.AcquireMessage()
+		m.SetOperation(op)
+		m.SetAfter([]byte(body))
+		return m
+	}
+	msgs := []hermod.Message{
+		row(hermod.OpUpdate, `{"id": 1, "name": "new", "version": 2}`),
+		row(hermod.OpUpdate, `{"id": 1, "name": "old", "version": 1}`),
+	}
+	if err := snk.WriteBatch(t.Context(), msgs); err != nil {
+		t.Fatalf("WriteBatch: %v", err)
+	}
+	if _, conflict := snk.LastWriteIdempotent(); !conflict {
+		t.Error("the stale write was not reported")
+	}
+	name, err := snk.client.HGet(t.Context(), "hermod:test:1", "name").Result()
+	if err != nil || name != "new" {
+		t.Errorf("got name %q (%v), want the newer row", name, err)
+	}
+	snk.client.Del(t.Context(), "hermod:test:1", "{hermod:test:1}:version")
+}
@@ -97,3 +97,76 @@ func TestRetrySink_WriteDoesNotSleepAfterFinalAttempt(t *testing.T) {
 		t.Fatalf("retry slept after final attempt: elapsed=%v", elapsed)
 	}
 }
+
+func TestRetrySink_PermanentErrorIsNotRetried(t *testing.T) {
+	s := &fakeBatchSink{err: hermod.Classify(errors.New("duplicate key"), hermod.ErrorPermanent)}
+	rs := NewRetrySink(s, 5, time.Millisecond, nil)
+
+	err := rs.WriteBatch(t.Context(), nil)
+	if class, _ := hermod.ClassifyError(err, nil); class != hermod.ErrorPermanent {
+		t.Fatalf("expected the permanent class to survive, got %s (%v)", class, err)
+	}
+	if s.batchCalls != 1 {
+		t.Fatalf("expected a single attempt, got %d", s.batchCalls)
+	}
+}
+
+// Throttles go back to the engine, which waits them out within its throttle
+// timeout.
+func TestRetrySink_ThrottleIsReturnedWithHint(t *testing.T) {
+	s := &fakeBatchSink{err: hermod.Throttled(errors.New("429"), 30*time.Second), failTimes: 3}
+	rs := NewRetrySink(s, 5, time.Millisecond, nil)
+
+	err := rs.Write(t.Context(), nil)
+	if class, after := hermod.ClassifyError(err, nil); class != hermod.ErrorThrottled || after != 30*time.Second {
+		t.Fatalf("expected the throttle and its hint to be returned, got %s after %v (%v)", class, after, err)
+	}
+	if s.writeCalls != 1 {
+		t.Fatalf("expected a single attempt, got %d", s.writeCalls)
+	}
+}
+
+func TestCircuitBreakerSink_PermanentErrorsDoNotTrip(t *testing.T) {
+	s := &fakeBatchSink{err: hermod.Classify(errors.New("bad row"), hermod.ErrorPermanent)}
+	cb := NewCircuitBreakerSink(s, 2, time.Minute)
+
+	for range 3 {
+		_ = cb.Write(t.Context(), nil)
+	}
+	if err := cb.Write(t.Context(), nil); errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("permanent per-message errors must not open the breaker")
+	}
+}
+
+// resultSink reports a per-message failure for its second message.
+type resultSink struct {
+	fakeBatchSink
+}
+
+func (r *resultSink) WriteBatchResults(_ context.Context, msgs []hermod.Message) ([]error, error) {
+	r.batchCalls++
+	errs := make([]error, len(msgs))
+	errs[1] = errors.New("item failed")
+	return errs, nil
+}
+
+func TestDecoratorsForwardBatchResults(t *testing.T) {
+	inner := &resultSink{}
+	var s hermod.Sink = NewRetrySink(NewTracingSink(NewCircuitBreakerSink(inner, 1, time.Minute), "s1"), 3, time.Millisecond, nil)
+
+	rs, ok := s.(hermod.BatchResultSink)
+	if !ok {
+		t.Fatal("expected the decorated sink to report per-message results")
+	}
+	for range 2 {
+		errs, err := rs.WriteBatchResults(t.Context(), make([]hermod.Message, 3))
+		if err != nil || len(errs) != 3 || errs[1] == nil {
+			t.Fatalf("expected the item failure passed through, got %v, %v", errs, err)
+		}
+	}
+	// A per-message failure is for the engine to retry, and it does not
+	// count against the destination.
+	if inner.batchCalls != 2 {
+		t.Fatalf("expected one call per batch, got %d", inner.batchCalls)
+	}
+}
@@ -0,0 +1,231 @@
+package s3parquet
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// rowLayout maps columns onto a struct type parquet-go can write, with
+// the Parquet physical and logical type of each column in its tags.
+type rowLayout struct {
+	typ  reflect.Type
+	cols []Column
+}
+
+func newRowLayout(cols []Column) (*rowLayout, error) {
+	typ, err := structType(cols)
+	if err != nil {
+		return nil, err
+	}
+	return &rowLayout{typ: typ, cols: cols}, nil
+}
+
+// structType builds a struct with an optional field per column.
+func structType(cols []Column) (reflect.Type, error) {
+	sfs := make([]reflect.StructField, 0, len(cols))
+	for i, c := range cols {
+		gt, tags, err := goType(c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", c.Name, err)
+		}
+		tag := fmt.Sprintf(`parquet:"name=%s%s, repetitiontype=OPTIONAL"`, parquetName(c.Name), tags)
+		sfs = append(sfs, reflect.StructField{Name: "F" + strconv.Itoa(i), Type: reflect.PointerTo(gt), Tag: reflect.StructTag(tag)})
+	}
+	return reflect.StructOf(sfs), nil
+}
+
+// parquetName makes a column name safe for parquet-go struct tags.
+func parquetName(name string) string {
+	return strings.NewReplacer(",", "_", "=", "_").Replace(name)
+}
+
+// goType returns the Go type of the values of a column and the tags,
+// each preceded by a comma, that describe it to parquet-go.
+func goType(t Type) (reflect.Type, string, error) {
+	switch t.Kind {
+	case KindList:
+		elem := derefType(t.Elem)
+		if elem.Kind == KindList {
+			// parquet-go cannot tag lists of lists, so inner lists are
+			// written as JSON.
+			elem = Type{Kind: KindJSON}
+		}
+		et, etags, err := goType(elem)
+		if err != nil {
+			return nil, "", err
+		}
+		tags := ", type=LIST"
+		if elem.Kind != KindStruct {
+			// Element tags are the element's own, prefixed with "value".
+			for _, part := range strings.Split(strings.TrimPrefix(etags, ", "), ", ") {
+				tags += ", value" + part
+			}
+		}
+		return reflect.SliceOf(reflect.PointerTo(et)), tags, nil
+	case KindStruct:
+		st, err := structType(t.Fields)
+		return st, "", err
+	}
+	return primitiveType(t)
+}
+
+func primitiveType(t Type) (reflect.Type, string, error) {
+	switch t.Kind {
+	case KindBoolean:
+		return reflect.TypeFor[bool](), ", type=BOOLEAN", nil
+	case KindInt32:
+		return reflect.TypeFor[int32](), ", type=INT32", nil
+	case KindInt64:
+		return reflect.TypeFor[int64](), ", type=INT64", nil
+	case KindFloat:
+		return reflect.TypeFor[float32](), ", type=FLOAT", nil
+	case KindDouble:
+		return reflect.TypeFor[float64](), ", type=DOUBLE", nil
+	case KindString:
+		return reflect.TypeFor[string](), ", type=BYTE_ARRAY, convertedtype=UTF8", nil
+	case KindJSON:
+		return reflect.TypeFor[string](), ", type=BYTE_ARRAY, convertedtype=JSON", nil
+	case KindBinary:
+		return reflect.TypeFor[string](), ", type=BYTE_ARRAY", nil
+	case KindDate:
+		return reflect.TypeFor[int32](), ", type=INT32, convertedtype=DATE", nil
+	case KindTimestamp:
+		return reflect.TypeFor[int64](), fmt.Sprintf(", type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=%t, logicaltype.unit=MICROS", t.UTC), nil
+	case KindUUID:
+		return reflect.TypeFor[string](), ", type=FIXED_LEN_BYTE_ARRAY, length=16, logicaltype=UUID", nil
+	case KindDecimal:
+		dec := fmt.Sprintf(", convertedtype=DECIMAL, scale=%d, precision=%d", t.Scale, t.Precision)
+		switch {
+		case t.Precision <= 9:
+			return reflect.TypeFor[int32](), ", type=INT32" + dec, nil
+		case t.Precision <= 18:
+			return reflect.TypeFor[int64](), ", type=INT64" + dec, nil
+		}
+		return reflect.TypeFor[string](), fmt.Sprintf(", type=FIXED_LEN_BYTE_ARRAY, length=%d%s", decimalLength(t.Precision), dec), nil
+	}
+	return nil, "", fmt.Errorf("type %q is not supported", t.Kind)
+}
+
+// decimalLength is the number of bytes a two's complement decimal of the
+// given precision needs.
+func decimalLength(precision int) int {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil)
+	return max.BitLen()/8 + 1
+}
+
+// value builds the struct of a row.
+func (l *rowLayout) value(row map[string]any) (any, error) {
+	v, err := structValue(l.typ, l.cols, row)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+func structValue(typ reflect.Type, cols []Column, row map[string]any) (reflect.Value, error) {
+	v := reflect.New(typ).Elem()
+	for i, c := range cols {
+		x, err := normalize(row[c.Name], c.Type)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("column %q: %w", c.Name, err)
+		}
+		if x == nil {
+			continue
+		}
+		p, err := physical(x, c.Type, typ.Field(i).Type.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("column %q: %w", c.Name, err)
+		}
+		v.Field(i).Set(p)
+	}
+	return v, nil
+}
+
+// physical converts a normalized value to a pointer to its Parquet
+// representation of Go type gt.
+func physical(x any, t Type, gt reflect.Type) (reflect.Value, error) {
+	p := reflect.New(gt)
+	var rv reflect.Value
+	switch t.Kind {
+	case KindBoolean, KindString, KindJSON, KindFloat, KindDouble, KindInt32, KindInt64:
+		rv = reflect.ValueOf(x)
+	case KindBinary:
+		rv = reflect.ValueOf(string(x.([]byte)))
+	case KindDate:
+		d, err := time.Parse(time.DateOnly, x.(string))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		rv = reflect.ValueOf(d.Unix() / 86400)
+	case KindTimestamp:
+		rv = reflect.ValueOf(x.(time.Time).UnixMicro())
+	case KindUUID:
+		id := uuid.MustParse(x.(string))
+		rv = reflect.ValueOf(string(id[:]))
+	case KindDecimal:
+		r, _ := new(big.Rat).SetString(x.(string))
+		unscaled := new(big.Int).Mul(r.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Scale)), nil))
+		unscaled.Quo(unscaled, r.Denom())
+		if gt.Kind() == reflect.String {
+			rv = reflect.ValueOf(string(twosComplement(unscaled, decimalLength(t.Precision))))
+		} else {
+			rv = reflect.ValueOf(unscaled.Int64())
+		}
+	case KindList:
+		items := x.([]any)
+		elem := derefType(t.Elem)
+		if elem.Kind == KindList {
+			elem = Type{Kind: KindJSON}
+		}
+		s := reflect.MakeSlice(gt, len(items), len(items))
+		for i, item := range items {
+			n, err := normalize(item, elem)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			if n == nil {
+				continue
+			}
+			ep, err := physical(n, elem, gt.Elem().Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			s.Index(i).Set(ep)
+		}
+		rv = s
+	case KindStruct:
+		sv, err := structValue(gt, t.Fields, x.(map[string]any))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		rv = sv
+	default:
+		return reflect.Value{}, fmt.Errorf("type %q is not supported", t.Kind)
+	}
+	if !rv.CanConvert(gt) {
+		return reflect.Value{}, fmt.Errorf("cannot write %T as %s", x, gt)
+	}
+	p.Elem().Set(rv.Convert(gt))
+	return p, nil
+}
+
+// twosComplement encodes n big-endian in size bytes.
+func twosComplement(n *big.Int, size int) []byte {
+	b := make([]byte, size)
+	if n.Sign() >= 0 {
+		return n.FillBytes(b)
+	}
+	// -n = ^(n-1): invert the bytes of |n|-1.
+	m := new(big.Int).Sub(new(big.Int).Neg(n), big.NewInt(1))
+	m.FillBytes(b)
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	return b
+}
@@ -0,0 +1,135 @@
+package s3parquet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/hermod/pkg/infra/columnar"
+)
+
+// hiveDefaultPartition is the partition value Hive and its readers use
+// for nulls.
+const hiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// partitionField is one level of a Hive-style partition path.
+type partitionField struct {
+	name string
+	// field is the row field the value is read from. It is empty for time
+	// transforms of the time rows are written.
+	field string
+	// transform is "year", "month", "day" or "hour" for time partitions,
+	// and empty to use the field's value as is.
+	transform string
+}
+
+// parsePartitionBy parses partition specs of the form [name=]field or
+// [name=]transform(field), for example "region" or "dt=day(created_at)".
+// A transform without a field, like "day()", partitions by write time.
+func parsePartitionBy(specs []string) ([]partitionField, error) {
+	var fields []partitionField
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		var p partitionField
+		name, expr, named := strings.Cut(spec, "=")
+		if !named {
+			expr = name
+		}
+		expr = strings.TrimSpace(expr)
+		if open := strings.IndexByte(expr, '('); open >= 0 {
+			if !strings.HasSuffix(expr, ")") {
+				return nil, fmt.Errorf("invalid partition %q", spec)
+			}
+			p.transform = strings.ToLower(strings.TrimSpace(expr[:open]))
+			p.field = strings.TrimSpace(expr[open+1 : len(expr)-1])
+			switch p.transform {
+			case "year", "month", "day", "hour":
+			default:
+				return nil, fmt.Errorf("unknown partition transform %q", p.transform)
+			}
+		} else {
+			p.field = expr
+			if p.field == "" {
+				return nil, fmt.Errorf("invalid partition %q", spec)
+			}
+		}
+		p.name = strings.TrimSpace(name)
+		if !named {
+			p.name = p.field
+			if p.transform != "" {
+				p.name = strings.TrimPrefix(p.field+"_"+p.transform, "_")
+			}
+		}
+		fields = append(fields, p)
+	}
+	return fields, nil
+}
+
+// partitionPath returns the partition directories of row, such as
+// "dt=2024-05-01/region=eu".
+func partitionPath(fields []partitionField, row map[string]any, now time.Time) string {
+	parts := make([]string, 0, len(fields))
+	for _, p := range fields {
+		parts = append(parts, escapePathName(p.name)+"="+escapePathName(p.value(row, now)))
+	}
+	return strings.Join(parts, "/")
+}
+
+func (p partitionField) value(row map[string]any, now time.Time) string {
+	if p.transform == "" {
+		return partitionValue(row[p.field])
+	}
+	t := now
+	if p.field != "" {
+		var ok bool
+		if t, ok = columnar.Time(row[p.field]); !ok {
+			return hiveDefaultPartition
+		}
+	}
+	t = t.UTC()
+	switch p.transform {
+	case "year":
+		return t.Format("2006")
+	case "month":
+		return t.Format("2006-01")
+	case "day":
+		return t.Format(time.DateOnly)
+	}
+	return t.Format("2006-01-02-15")
+}
+
+func partitionValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return hiveDefaultPartition
+	case string:
+		if x == "" {
+			return hiveDefaultPartition
+		}
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+// escapePathName escapes the characters Hive escapes in partition names
+// and values.
+func escapePathName(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte("\"#%'*/:=?\\{[]^", c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
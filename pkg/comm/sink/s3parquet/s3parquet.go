@@ -3,62 +3,180 @@ package s3parquet
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
 	"github.com/user/hermod"
-	"github.com/xitongsys/parquet-go-source/local"
-	"github.com/xitongsys/parquet-go/writer"
 )
 
+// Defaults of Config.
+const (
+	DefaultTargetFileSize = 128 << 20
+	DefaultMaxFileAge     = 15 * time.Minute
+	DefaultPartSize       = 16 << 20
+
+	// minPartSize is the smallest part S3 accepts, except for the last.
+	minPartSize = 5 << 20
+)
+
+const (
+	// closeTimeout bounds how long Close waits for pending uploads.
+	closeTimeout = 2 * time.Minute
+	// retryDelay is the pause before a failed upload is tried again.
+	retryDelay = 5 * time.Second
+)
+
+// ErrInvalidRow is returned for messages that cannot be written, such as
+// messages without data or with values that do not fit their column.
+var ErrInvalidRow = errors.New("invalid row")
+
+// Config configures an S3ParquetSink.
+type Config struct {
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Endpoint  string
+	// KeyPrefix is put before the partition path of every object. A
+	// "{table}" in it is replaced by the table of the rows.
+	KeyPrefix string
+
+	// Schema is a parquet-go JSON schema. When set, every file is written
+	// with it instead of a derived schema.
+	Schema       string
+	Parallelizer int64
+	// SchemaSubject names a JSON Schema or Avro schema in the schema
+	// registry that describes the rows. Without it, the columns of each
+	// table are discovered from the workflow's source when it can describe
+	// them. Columns neither describes are inferred from the rows.
+	SchemaSubject string
+
+	// PartitionBy lists the partition columns, as [name=]field or
+	// [name=]transform(field) with the transforms year, month, day and
+	// hour, e.g. "dt=day(created_at)". A transform without a field
+	// partitions by the time rows are written.
+	PartitionBy []string
+
+	// TargetFileSize is the size of Parquet file at which a file is
+	// uploaded. Files are also uploaded once they are MaxFileAge old, and
+	// at every checkpoint.
+	TargetFileSize int64
+	MaxFileAge     time.Duration
+	// PartSize is the size of the parts of multipart uploads.
+	PartSize int64
+	// SpoolDir holds the rows of files until they are uploaded. Rows
+	// written after the last checkpoint are only kept there, so it must
+	// outlive restarts.
+	SpoolDir string
+}
+
+// S3ParquetSink writes rows to Parquet files on S3 under Hive-style
+// partition paths. Rows are spooled to local disk per table and partition
+// until their file reaches the target size or age, then converted to
+// Parquet and uploaded in the background.
 type S3ParquetSink struct {
-	region       string
-	bucket       string
-	keyPrefix    string
-	accessKey    string
-	secretKey    string
-	endpoint     string
-	schema       string
-	parallelizer int64
+	cfg        Config
+	partitions []partitionField
+	store      objectStore
+
+	metaMu  sync.Mutex
+	columns hermod.ColumnDiscoverer
+	schemas hermod.SchemaLookup
+	tables  map[string][]Column
+
+	mu     sync.Mutex
+	ready  bool
+	closed bool
+	open   map[string]*spool
+	sealed map[string]*spool
+	queue  []*spool
+	// ratio estimates the Parquet bytes per spooled byte, learned from the
+	// last file built.
+	ratio     float64
+	uploadErr error
+	// uploaded is closed and replaced whenever the uploader finishes with a
+	// spool, successfully or not.
+	uploaded chan struct{}
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
-func NewS3ParquetSink(ctx context.Context, region, bucket, keyPrefix, accessKey, secretKey, endpoint, schema string, parallelizer int64) (*S3ParquetSink, error) {
-	if parallelizer <= 0 {
-		parallelizer = 4
+// NewS3ParquetSink returns a sink writing to the bucket of cfg.
+func NewS3ParquetSink(ctx context.Context, cfg Config) (*S3ParquetSink, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3-parquet bucket is required")
+	}
+	if cfg.SpoolDir == "" {
+		return nil, errors.New("s3-parquet spool directory is required")
+	}
+	if cfg.Parallelizer <= 0 {
+		cfg.Parallelizer = 4
+	}
+	if cfg.TargetFileSize <= 0 {
+		cfg.TargetFileSize = DefaultTargetFileSize
+	}
+	if cfg.MaxFileAge <= 0 {
+		cfg.MaxFileAge = DefaultMaxFileAge
+	}
+	if cfg.PartSize <= 0 {
+		cfg.PartSize = DefaultPartSize
+	}
+	cfg.PartSize = max(cfg.PartSize, minPartSize)
+	partitions, err := parsePartitionBy(cfg.PartitionBy)
+	if err != nil {
+		return nil, err
 	}
 	return &S3ParquetSink{
-		region:       region,
-		bucket:       bucket,
-		keyPrefix:    keyPrefix,
-		accessKey:    accessKey,
-		secretKey:    secretKey,
-		endpoint:     endpoint,
-		schema:       schema,
-		parallelizer: parallelizer,
+		cfg:        cfg,
+		partitions: partitions,
+		tables:     make(map[string][]Column),
+		open:       make(map[string]*spool),
+		sealed:     make(map[string]*spool),
+		ratio:      1,
+		wake:       make(chan struct{}, 1),
+		uploaded:   make(chan struct{}),
 	}, nil
 }
 
-func (s *S3ParquetSink) getS3Client(ctx context.Context) (*s3.Client, error) {
+func (s *S3ParquetSink) getS3Client(ctx context.Context) (objectStore, error) {
+	if s.store != nil {
+		return s.store, nil
+	}
 	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(s.region),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(s.accessKey, s.secretKey, "")),
+		config.WithRegion(s.cfg.Region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(s.cfg.AccessKey, s.cfg.SecretKey, "")),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load SDK config: %w", err)
 	}
 
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		if s.endpoint != "" {
-			o.BaseEndpoint = aws.String(s.endpoint)
+	s.store = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if s.cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(s.cfg.Endpoint)
 			o.UsePathStyle = true
 		}
 	})
-	return client, nil
+	return s.store, nil
+}
+
+// SetSchemaSources implements hermod.SchemaAwareSink.
+func (s *S3ParquetSink) SetSchemaSources(columns hermod.ColumnDiscoverer, schemas hermod.SchemaLookup) {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+	s.columns, s.schemas = columns, schemas
 }
 
 func (s *S3ParquetSink) Write(ctx context.Context, msg hermod.Message) error {
@@ -66,92 +184,428 @@ func (s *S3ParquetSink) Write(ctx context.Context, msg hermod.Message) error {
 }
 
 func (s *S3ParquetSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
-	if len(msgs) == 0 {
-		return nil
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// WriteBatchResults spools msgs. Messages whose values do not fit the
+// columns known for their table fail on their own.
+func (s *S3ParquetSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errors.New("s3-parquet sink is closed")
+	}
+	if err := s.ensureReady(ctx); err != nil {
+		return nil, err
 	}
 
-	// Filter nil messages
-	filtered := make([]hermod.Message, 0, len(msgs))
-	for _, m := range msgs {
-		if m != nil {
-			filtered = append(filtered, m)
-		}
+	type group struct {
+		table, partition string
+		lines            [][]byte
 	}
-	if len(filtered) == 0 {
-		return nil
+	var (
+		errs   []error
+		failed bool
+	)
+	groups := make(map[string]*group)
+	now := time.Now()
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		line, table, partition, err := s.spoolLine(ctx, msg, now)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidRow) {
+				return nil, err
+			}
+			if errs == nil {
+				errs = make([]error, len(msgs))
+			}
+			errs[i] = err
+			failed = true
+			continue
+		}
+		key := table + "\x00" + partition
+		g, ok := groups[key]
+		if !ok {
+			g = &group{table: table, partition: partition}
+			groups[key] = g
+		}
+		g.lines = append(g.lines, line)
 	}
 
-	key := fmt.Sprintf("%s%d_%d.parquet", s.keyPrefix, time.Now().Unix(), time.Now().UnixNano())
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		g := groups[k]
+		sp, err := s.spoolFor(ctx, k, g.table, g.partition)
+		if err != nil {
+			return nil, err
+		}
+		if err := sp.append(g.lines); err != nil {
+			return nil, err
+		}
+		if float64(sp.size)*s.ratio >= float64(s.cfg.TargetFileSize) {
+			if err := s.seal(k, sp); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !failed {
+		return nil, nil
+	}
+	return errs, nil
+}
 
-	tmpFile, err := os.CreateTemp("", "hermod-*.parquet")
-	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+// spoolLine returns the spooled form of msg and where it belongs.
+func (s *S3ParquetSink) spoolLine(ctx context.Context, msg hermod.Message, now time.Time) ([]byte, string, string, error) {
+	data := msg.Data()
+	if data == nil {
+		if err := json.Unmarshal(msg.Payload(), &data); err != nil || data == nil {
+			return nil, "", "", fmt.Errorf("%w: message %s has no data", ErrInvalidRow, msg.ID())
+		}
 	}
-	tmpFileName := tmpFile.Name()
-	defer os.Remove(tmpFileName)
-	tmpFile.Close() // Close it so parquet writer can open it
+	table := msg.Table()
+	partition := partitionPath(s.partitions, data, now)
 
-	fw, err := local.NewLocalFileWriter(tmpFileName)
+	if s.cfg.Schema == "" {
+		cols, err := s.knownColumns(ctx, table)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if len(cols) > 0 {
+			row := make(map[string]any, len(data))
+			for k, v := range data {
+				row[k] = v
+			}
+			for _, c := range cols {
+				v, err := normalize(data[c.Name], c.Type)
+				if err != nil {
+					return nil, "", "", fmt.Errorf("%w: column %q: %v", ErrInvalidRow, c.Name, err)
+				}
+				if v == nil {
+					delete(row, c.Name)
+				} else {
+					row[c.Name] = v
+				}
+			}
+			data = row
+		}
+	}
+	line, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("failed to create local file writer: %w", err)
+		return nil, "", "", fmt.Errorf("%w: %v", ErrInvalidRow, err)
 	}
+	return line, table, partition, nil
+}
 
-	pw, err := writer.NewJSONWriter(s.schema, fw, s.parallelizer)
-	if err != nil {
-		fw.Close()
-		return fmt.Errorf("failed to create parquet writer: %w", err)
+// knownColumns returns the columns upstream metadata describes for table,
+// from the schema registry or else the workflow's source. Tables nothing
+// describes have no known columns, and all of theirs are inferred.
+func (s *S3ParquetSink) knownColumns(ctx context.Context, table string) ([]Column, error) {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+	if s.cfg.SchemaSubject != "" {
+		table = ""
+	}
+	if cols, ok := s.tables[table]; ok {
+		return cols, nil
 	}
 
-	for _, msg := range filtered {
-		data := msg.Data()
-		if data == nil {
-			if err := json.Unmarshal(msg.Payload(), &data); err != nil {
-				continue
-			}
+	var cols []Column
+	switch {
+	case s.cfg.SchemaSubject != "":
+		if s.schemas == nil {
+			return nil, fmt.Errorf("schema %s: the schema registry is unavailable", s.cfg.SchemaSubject)
 		}
-
-		jsonData, err := json.Marshal(data)
+		schemaType, content, err := s.schemas(ctx, s.cfg.SchemaSubject)
 		if err != nil {
-			pw.WriteStop()
-			fw.Close()
-			return fmt.Errorf("failed to marshal message data to json: %w", err)
+			return nil, err
+		}
+		if cols, err = registryColumns(schemaType, content); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", s.cfg.SchemaSubject, err)
 		}
+	case s.columns != nil && table != "":
+		// Sources that cannot describe the table leave its columns to
+		// inference.
+		if infos, err := s.columns.DiscoverColumns(ctx, table); err == nil {
+			cols = columnsFromInfo(infos)
+		}
+	}
+	s.tables[table] = cols
+	return cols, nil
+}
 
-		if err := pw.Write(string(jsonData)); err != nil {
-			pw.WriteStop()
-			fw.Close()
-			return fmt.Errorf("failed to write message to parquet: %w", err)
+// spoolFor returns the open spool of a table and partition, creating it
+// if needed.
+func (s *S3ParquetSink) spoolFor(ctx context.Context, key, table, partition string) (*spool, error) {
+	if sp, ok := s.open[key]; ok {
+		return sp, nil
+	}
+	var cols []Column
+	if s.cfg.Schema == "" {
+		var err error
+		if cols, err = s.knownColumns(ctx, table); err != nil {
+			return nil, err
 		}
 	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	sp, err := createSpool(s.cfg.SpoolDir, id.String(), spoolHeader{
+		Table:     table,
+		Partition: partition,
+		Created:   time.Now(),
+		Columns:   cols,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.open[key] = sp
+	return sp, nil
+}
 
-	if err := pw.WriteStop(); err != nil {
-		fw.Close()
-		return fmt.Errorf("failed to stop parquet writer: %w", err)
+// objectKey is where the file of a spool is uploaded.
+func (s *S3ParquetSink) objectKey(sp *spool) string {
+	key := strings.ReplaceAll(s.cfg.KeyPrefix, "{table}", sp.header.Table)
+	if key != "" && !strings.HasSuffix(key, "/") {
+		key += "/"
 	}
-	fw.Close()
+	if p := sp.header.Partition; p != "" {
+		key += p + "/"
+	}
+	return key + "part-" + sp.id + ".parquet"
+}
 
-	// Upload to S3
-	client, err := s.getS3Client(ctx)
+// seal closes an open spool to new rows and queues its upload. Callers
+// hold s.mu.
+func (s *S3ParquetSink) seal(key string, sp *spool) error {
+	delete(s.open, key)
+	if err := sp.close(); err != nil {
+		return err
+	}
+	if sp.rows == 0 {
+		return sp.remove()
+	}
+	sp.state = &uploadState{Key: s.objectKey(sp)}
+	if err := sp.saveState(); err != nil {
+		return err
+	}
+	s.sealed[sp.id] = sp
+	s.enqueue(sp)
+	return nil
+}
+
+func (s *S3ParquetSink) enqueue(sp *spool) {
+	s.queue = append(s.queue, sp)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// ensureReady recovers the spools left by an earlier run and starts the
+// uploader. Callers hold s.mu.
+func (s *S3ParquetSink) ensureReady(ctx context.Context) error {
+	if s.ready {
+		return nil
+	}
+	if err := os.MkdirAll(s.cfg.SpoolDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	store, err := s.getS3Client(ctx)
 	if err != nil {
 		return err
 	}
+	if err := s.recover(ctx, store); err != nil {
+		return err
+	}
+	uctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(uctx, store)
+	s.ready = true
+	return nil
+}
 
-	file, err := os.Open(tmpFileName)
+// recover resumes from the spools of an earlier run. Sources acknowledge
+// rows once they are spooled, so none is dropped: spools the last
+// checkpoint covers are removed, sealed ones are uploaded unless they were
+// already, and open ones take new rows again.
+func (s *S3ParquetSink) recover(ctx context.Context, store objectStore) error {
+	cp, err := readCheckpoint(s.cfg.SpoolDir)
+	if err != nil {
+		return err
+	}
+	ids, err := listSpools(s.cfg.SpoolDir)
 	if err != nil {
-		return fmt.Errorf("failed to open temp file for upload: %w", err)
+		return err
 	}
-	defer file.Close()
+	for _, id := range ids {
+		sp, err := openSpool(s.cfg.SpoolDir, id)
+		if err != nil {
+			return fmt.Errorf("spool %s: %w", id, err)
+		}
+		if err := sp.loadState(); err != nil {
+			return fmt.Errorf("spool %s: %w", id, err)
+		}
+		if cp != nil && cp.Spools[id].Uploaded {
+			// The run ended before it removed the spools of its checkpoint.
+			if err := sp.remove(); err != nil {
+				return err
+			}
+			continue
+		}
 
-	_, err = client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(key),
-		Body:   file,
-	})
+		switch key := sp.header.Table + "\x00" + sp.header.Partition; {
+		case sp.state != nil:
+			s.sealed[id] = sp
+			if !sp.state.Uploaded {
+				s.enqueue(sp)
+			}
+		case s.open[key] == nil:
+			if err := sp.reopen(); err != nil {
+				return err
+			}
+			s.open[key] = sp
+		default:
+			if err := s.seal(key, sp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to put object to s3: %w", err)
+// run uploads sealed spools, and seals open ones that reach their maximum
+// age, until ctx is cancelled or the sink is closed and nothing is left.
+func (s *S3ParquetSink) run(ctx context.Context, store objectStore) {
+	defer close(s.done)
+	tick := min(max(s.cfg.MaxFileAge/4, 10*time.Millisecond), time.Minute)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		if !s.closed {
+			s.sealExpired()
+		}
+		var sp *spool
+		if len(s.queue) > 0 {
+			sp = s.queue[0]
+			s.queue = s.queue[1:]
+		}
+		closed := s.closed
+		s.mu.Unlock()
+
+		if sp == nil {
+			if closed {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		err := s.upload(ctx, store, sp)
+		s.mu.Lock()
+		if err != nil {
+			s.uploadErr = err
+			s.queue = append(s.queue, sp)
+		}
+		close(s.uploaded)
+		s.uploaded = make(chan struct{})
+		s.mu.Unlock()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+}
+
+// sealExpired seals the open spools older than MaxFileAge. Callers hold
+// s.mu.
+func (s *S3ParquetSink) sealExpired() {
+	for key, sp := range s.open {
+		if time.Since(sp.header.Created) >= s.cfg.MaxFileAge {
+			if err := s.seal(key, sp); err != nil {
+				s.uploadErr = err
+			}
+		}
 	}
+}
 
+// CommitCheckpoint seals every open spool and waits until the spools it
+// sealed, and any sealed before, are uploaded, so that the checkpoint covers
+// no row that exists only on local disk. It fails when an upload fails.
+func (s *S3ParquetSink) CommitCheckpoint(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ready {
+		if s.closed {
+			return nil
+		}
+		if err := s.ensureReady(ctx); err != nil {
+			return err
+		}
+	}
+	for key, sp := range s.open {
+		if err := s.seal(key, sp); err != nil {
+			return err
+		}
+	}
+	// Spools sealed while waiting hold rows written after the checkpoint
+	// started; they are left to the next one.
+	covered := make([]*spool, 0, len(s.sealed))
+	for _, sp := range s.sealed {
+		covered = append(covered, sp)
+	}
+	for {
+		if err := s.uploadErr; err != nil {
+			s.uploadErr = nil
+			return err
+		}
+		if !slices.ContainsFunc(covered, func(sp *spool) bool { return !sp.state.Uploaded }) {
+			break
+		}
+		if s.closed {
+			// The uploader is gone; what it left stays spooled for the next run.
+			return errors.New("s3-parquet sink is closed with files not uploaded")
+		}
+		uploaded := s.uploaded
+		s.mu.Unlock()
+		select {
+		case <-uploaded:
+			s.mu.Lock()
+		case <-ctx.Done():
+			s.mu.Lock()
+			return ctx.Err()
+		}
+	}
+
+	cp := checkpoint{Spools: make(map[string]checkpointEntry, len(covered))}
+	for _, sp := range covered {
+		cp.Spools[sp.id] = checkpointEntry{Size: sp.size, Uploaded: true}
+	}
+	if err := writeCheckpoint(s.cfg.SpoolDir, cp); err != nil {
+		return fmt.Errorf("failed to write spool checkpoint: %w", err)
+	}
+	for _, sp := range covered {
+		if err := sp.remove(); err != nil {
+			return err
+		}
+		delete(s.sealed, sp.id)
+	}
 	return nil
 }
 
@@ -161,7 +615,7 @@ func (s *S3ParquetSink) Ping(ctx context.Context) error {
 		return err
 	}
 	_, err = client.HeadBucket(ctx, &s3.HeadBucketInput{
-		Bucket: aws.String(s.bucket),
+		Bucket: aws.String(s.cfg.Bucket),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to ping s3 bucket: %w", err)
@@ -169,6 +623,51 @@ func (s *S3ParquetSink) Ping(ctx context.Context) error {
 	return nil
 }
 
+// Close uploads every spooled row, waiting up to two minutes. What is not
+// uploaded by then stays spooled for the next run.
 func (s *S3ParquetSink) Close() error {
-	return nil
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	var errs []error
+	for key, sp := range s.open {
+		if err := s.seal(key, sp); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	ready := s.ready
+	s.mu.Unlock()
+	if !ready {
+		return errors.Join(errs...)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	select {
+	case <-s.done:
+	case <-time.After(closeTimeout):
+		errs = append(errs, errors.New("timed out uploading spooled parquet files"))
+	}
+	s.cancel()
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) > 0 && s.uploadErr != nil {
+		errs = append(errs, s.uploadErr)
+	}
+	return errors.Join(errs...)
+}
+
+// ClassifyError dead-letters invalid rows and retries everything else.
+func (s *S3ParquetSink) ClassifyError(err error) (hermod.ErrorClass, time.Duration) {
+	if errors.Is(err, ErrInvalidRow) {
+		return hermod.ErrorPermanent, 0
+	}
+	return hermod.ErrorTransient, 0
 }
@@ -1,15 +1,463 @@
 package s3parquet
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
 )
 
+// memStore is an in-memory objectStore.
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int32][]byte
+	parts   int
+	next    int
+}
+
+func newMemStore() *memStore {
+	return &memStore{objects: make(map[string][]byte), uploads: make(map[string]map[int32][]byte)}
+}
+
+func (m *memStore) HeadBucket(context.Context, *s3.HeadBucketInput, ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (m *memStore) CreateMultipartUpload(_ context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	id := fmt.Sprint(m.next)
+	m.uploads[id] = make(map[int32][]byte)
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String(id)}, nil
+}
+
+func (m *memStore) UploadPart(_ context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	b, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parts, ok := m.uploads[aws.ToString(in.UploadId)]
+	if !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+	parts[aws.ToInt32(in.PartNumber)] = b
+	m.parts++
+	return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprint(aws.ToInt32(in.PartNumber)))}, nil
+}
+
+func (m *memStore) CompleteMultipartUpload(_ context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	parts, ok := m.uploads[aws.ToString(in.UploadId)]
+	if !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+	var buf bytes.Buffer
+	for _, p := range in.MultipartUpload.Parts {
+		buf.Write(parts[aws.ToInt32(p.PartNumber)])
+	}
+	m.objects[aws.ToString(in.Key)] = buf.Bytes()
+	delete(m.uploads, aws.ToString(in.UploadId))
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (m *memStore) AbortMultipartUpload(_ context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.uploads, aws.ToString(in.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (m *memStore) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, aws.ToString(in.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *memStore) keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for k := range m.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newTestSink(t *testing.T, store objectStore, cfg Config) *S3ParquetSink {
+	t.Helper()
+	cfg.Bucket = "bucket"
+	if cfg.SpoolDir == "" {
+		cfg.SpoolDir = t.TempDir()
+	}
+	s, err := NewS3ParquetSink(t.Context(), cfg)
+	if err != nil {
+		t.Fatalf("NewS3ParquetSink: %v", err)
+	}
+	s.store = store
+	return s
+}
+
+func msg(table, row string) hermod.Message {
+	m := message.AcquireMessage()
+	m.SetTable(table)
+	m.SetAfter([]byte(row))
+	return m
+}
+
+func write(t *testing.T, s *S3ParquetSink, rows ...string) {
+	t.Helper()
+	msgs := make([]hermod.Message, len(rows))
+	for i, r := range rows {
+		msgs[i] = msg("users", r)
+	}
+	if err := s.WriteBatch(t.Context(), msgs); err != nil {
+		t.Fatalf("WriteBatch: %v", err)
+	}
+}
+
+// readObject returns the schema elements of a Parquet object by column
+// name, and its rows.
+func readObject(t *testing.T, store *memStore, key string) (map[string]*parquet.SchemaElement, []map[string]any) {
+	t.Helper()
+	store.mu.Lock()
+	b := store.objects[key]
+	store.mu.Unlock()
+	pr, err := reader.NewParquetReader(buffer.NewBufferFileFromBytes(b), nil, 1)
+	if err != nil {
+		t.Fatalf("open parquet: %v", err)
+	}
+	defer pr.ReadStop()
+	schema := make(map[string]*parquet.SchemaElement)
+	for _, el := range pr.Footer.Schema[1:] {
+		schema[strings.ToLower(el.Name)] = el
+	}
+	res, err := pr.ReadByNumber(int(pr.GetNumRows()))
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	var rows []map[string]any
+	for _, r := range res {
+		b, _ := json.Marshal(r)
+		var row map[string]any
+		_ = json.Unmarshal(b, &row)
+		out := make(map[string]any)
+		for k, v := range row {
+			out[strings.ToLower(k)] = v
+		}
+		rows = append(rows, out)
+	}
+	return schema, rows
+}
+
 func TestNewS3ParquetSink(t *testing.T) {
-	sink, err := NewS3ParquetSink(t.Context(), "us-east-1", "bucket", "prefix", "ak", "sk", "", "{}", 4)
+	if _, err := NewS3ParquetSink(t.Context(), Config{SpoolDir: t.TempDir()}); err == nil {
+		t.Fatal("expected an error without a bucket")
+	}
+	if _, err := NewS3ParquetSink(t.Context(), Config{Bucket: "b", SpoolDir: t.TempDir(), PartitionBy: []string{"week(ts)"}}); err == nil {
+		t.Fatal("expected an error for an unknown transform")
+	}
+	sink, err := NewS3ParquetSink(t.Context(), Config{Region: "us-east-1", Bucket: "bucket", SpoolDir: t.TempDir()})
 	if err != nil {
 		t.Fatalf("failed to create sink: %v", err)
 	}
-	if sink == nil {
-		t.Fatal("sink is nil")
+	if sink.cfg.PartSize != DefaultPartSize || sink.cfg.TargetFileSize != DefaultTargetFileSize {
+		t.Fatalf("defaults not applied: %+v", sink.cfg)
+	}
+}
+
+func TestInferredLogicalTypes(t *testing.T) {
+	store := newMemStore()
+	s := newTestSink(t, store, Config{KeyPrefix: "lake/{table}"})
+	write(t, s,
+		`{"id":1,"name":"ada","score":1,"active":true,"uid":"0190a4b2-6f1e-7c3a-9d2b-1a2b3c4d5e6f","born":"1815-12-10","seen":"2024-05-01T10:00:00Z","tags":["a","b"],"addr":{"city":"London"}}`,
+		`{"id":2,"name":"bob","score":2.5,"active":false,"seen":"2024-05-02T11:30:00Z","extra":"x"}`,
+	)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	keys := store.keys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], "lake/users/part-") || !strings.HasSuffix(keys[0], ".parquet") {
+		t.Fatalf("unexpected objects %v", keys)
+	}
+	schema, rows := readObject(t, store, keys[0])
+
+	types := map[string]parquet.Type{"id": parquet.Type_INT64, "score": parquet.Type_DOUBLE, "active": parquet.Type_BOOLEAN, "born": parquet.Type_INT32, "seen": parquet.Type_INT64, "uid": parquet.Type_FIXED_LEN_BYTE_ARRAY}
+	for name, want := range types {
+		el := schema[name]
+		if el == nil || el.GetType() != want {
+			t.Errorf("column %s: got %v, want %v", name, el, want)
+		}
+	}
+	if lt := schema["seen"].GetLogicalType(); lt == nil || lt.TIMESTAMP == nil || !lt.TIMESTAMP.IsAdjustedToUTC || lt.TIMESTAMP.Unit.MICROS == nil {
+		t.Errorf("seen: got logical type %v, want UTC micros timestamp", lt)
+	}
+	if lt := schema["uid"].GetLogicalType(); lt == nil || lt.UUID == nil {
+		t.Errorf("uid: got logical type %v, want uuid", lt)
+	}
+	if schema["born"].GetConvertedType() != parquet.ConvertedType_DATE {
+		t.Errorf("born: got %v, want date", schema["born"].GetConvertedType())
+	}
+	if schema["tags"].GetConvertedType() != parquet.ConvertedType_LIST {
+		t.Errorf("tags: got %v, want list", schema["tags"].GetConvertedType())
+	}
+	if schema["addr"].GetNumChildren() != 1 {
+		t.Errorf("addr: got %d children, want a struct of 1", schema["addr"].GetNumChildren())
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i]["id"].(float64) < rows[j]["id"].(float64) })
+	if rows[0]["name"] != "ada" || rows[0]["score"] != 1.0 || rows[1]["score"] != 2.5 {
+		t.Errorf("unexpected rows %v", rows)
+	}
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC).UnixMicro()
+	if rows[0]["seen"] != float64(want) {
+		t.Errorf("seen: got %v, want %d", rows[0]["seen"], want)
+	}
+	if rows[0]["born"] != float64(time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC).Unix()/86400) {
+		t.Errorf("born: got %v", rows[0]["born"])
+	}
+	if rows[0]["extra"] != nil || rows[1]["extra"] != "x" {
+		t.Errorf("extra: got %v and %v", rows[0]["extra"], rows[1]["extra"])
+	}
+}
+
+func TestPartitionPaths(t *testing.T) {
+	store := newMemStore()
+	s := newTestSink(t, store, Config{KeyPrefix: "events", PartitionBy: []string{"dt=day(created_at)", "region"}})
+	write(t, s,
+		`{"id":1,"created_at":"2024-05-01T23:30:00-02:00","region":"eu/west"}`,
+		`{"id":2,"created_at":"2024-05-01T10:00:00Z","region":"us"}`,
+		`{"id":3,"created_at":"2024-05-01T11:00:00Z","region":"us"}`,
+		`{"id":4}`,
+	)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	dirs := make(map[string]int)
+	for _, k := range store.keys() {
+		dir := k[:strings.LastIndex(k, "/")]
+		_, rows := readObject(t, store, k)
+		dirs[dir] += len(rows)
+	}
+	want := map[string]int{
+		"events/dt=2024-05-02/region=eu%2Fwest":                                  1,
+		"events/dt=2024-05-01/region=us":                                         2,
+		"events/dt=__HIVE_DEFAULT_PARTITION__/region=__HIVE_DEFAULT_PARTITION__": 1,
+	}
+	if fmt.Sprint(dirs) != fmt.Sprint(want) {
+		t.Fatalf("got partitions %v, want %v", dirs, want)
+	}
+}
+
+type staticColumns []hermod.ColumnInfo
+
+func (c staticColumns) DiscoverColumns(context.Context, string) ([]hermod.ColumnInfo, error) {
+	return c, nil
+}
+
+func TestKnownColumns(t *testing.T) {
+	store := newMemStore()
+	s := newTestSink(t, store, Config{})
+	s.SetSchemaSources(staticColumns{
+		{Name: "id", Type: "bigint"},
+		{Name: "price", Type: "numeric(10,2)"},
+		{Name: "note", Type: "text"},
+	}, nil)
+
+	msgs := []hermod.Message{
+		msg("orders", `{"id":1,"price":"12.5"}`),
+		msg("orders", `{"id":2,"price":"not a number"}`),
+		msg("orders", `{"id":3,"price":7,"note":"n"}`),
+	}
+	errs, err := s.WriteBatchResults(t.Context(), msgs)
+	if err != nil {
+		t.Fatalf("WriteBatchResults: %v", err)
+	}
+	if len(errs) != 3 || errs[0] != nil || errs[2] != nil || !errors.Is(errs[1], ErrInvalidRow) {
+		t.Fatalf("unexpected results %v", errs)
+	}
+	if class, _ := s.ClassifyError(errs[1]); class != hermod.ErrorPermanent {
+		t.Fatalf("invalid rows classified as %v", class)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	keys := store.keys()
+	if len(keys) != 1 {
+		t.Fatalf("unexpected objects %v", keys)
+	}
+	schema, rows := readObject(t, store, keys[0])
+	price := schema["price"]
+	if price.GetConvertedType() != parquet.ConvertedType_DECIMAL || price.GetPrecision() != 10 || price.GetScale() != 2 {
+		t.Fatalf("price: got %v", price)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	var prices []float64
+	for _, r := range rows {
+		prices = append(prices, r["price"].(float64))
+	}
+	sort.Float64s(prices)
+	if fmt.Sprint(prices) != "[700 1250]" {
+		t.Fatalf("got unscaled prices %v", prices)
+	}
+}
+
+func TestSealing(t *testing.T) {
+	store := newMemStore()
+	s := newTestSink(t, store, Config{TargetFileSize: 1000, MaxFileAge: 50 * time.Millisecond})
+	s.cfg.PartSize = 256
+	defer s.Close()
+
+	// The first batch fills a file, which is uploaded in 256 byte parts.
+	rows := make([]string, 20)
+	for i := range rows {
+		rows[i] = fmt.Sprintf(`{"id":%d,"payload":"%s"}`, i, strings.Repeat("x", 40))
+	}
+	write(t, s, rows...)
+	s.mu.Lock()
+	open := len(s.open)
+	s.mu.Unlock()
+	if open != 0 {
+		t.Fatalf("a full file was left open")
+	}
+	waitFor(t, func() bool { return len(store.keys()) == 1 })
+	key := store.keys()[0]
+	store.mu.Lock()
+	parts := store.parts
+	size := len(store.objects[key])
+	store.mu.Unlock()
+	if want := (size + 255) / 256; parts != want {
+		t.Fatalf("got %d parts for %d bytes, want %d", parts, size, want)
+	}
+
+	// A small file is uploaded once it is old enough.
+	write(t, s, `{"id":100}`)
+	waitFor(t, func() bool { return len(store.keys()) == 2 })
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// crash stops a sink without sealing or uploading what it spooled.
+func crash(s *S3ParquetSink) {
+	s.cancel()
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sp := range s.open {
+		sp.close()
+	}
+}
+
+// Sources acknowledge rows once they are spooled, so the rows a crashed run
+// spooled, or uploaded, after its last checkpoint must survive the restart.
+func TestRecoveryKeepsSpooledRows(t *testing.T) {
+	store := newMemStore()
+	dir := t.TempDir()
+	s := newTestSink(t, store, Config{SpoolDir: dir, MaxFileAge: time.Hour})
+	write(t, s, `{"id":1}`, `{"id":2}`)
+	if err := s.CommitCheckpoint(t.Context()); err != nil {
+		t.Fatalf("CommitCheckpoint: %v", err)
+	}
+	write(t, s, `{"id":3}`)
+	s.mu.Lock()
+	for key, sp := range s.open {
+		if err := s.seal(key, sp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.mu.Unlock()
+	waitFor(t, func() bool { return len(store.keys()) == 2 })
+	write(t, s, `{"id":4}`)
+	crash(s)
+
+	s = newTestSink(t, store, Config{SpoolDir: dir, MaxFileAge: time.Hour})
+	write(t, s, `{"id":5}`)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	var ids []float64
+	for _, k := range store.keys() {
+		_, rows := readObject(t, store, k)
+		for _, r := range rows {
+			ids = append(ids, r["id"].(float64))
+		}
+	}
+	sort.Float64s(ids)
+	if fmt.Sprint(ids) != "[1 2 3 4 5]" {
+		t.Fatalf("got ids %v, want each once", ids)
+	}
+	if err := s.CommitCheckpoint(t.Context()); err != nil {
+		t.Fatalf("CommitCheckpoint after Close: %v", err)
+	}
+}
+
+// failingStore refuses every upload.
+type failingStore struct{ *memStore }
+
+func (failingStore) UploadPart(context.Context, *s3.UploadPartInput, ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestCheckpointWaitsForUploads(t *testing.T) {
+	store := newMemStore()
+	s := newTestSink(t, store, Config{MaxFileAge: time.Hour})
+	defer s.Close()
+	write(t, s, `{"id":1}`, `{"id":2}`)
+	if err := s.CommitCheckpoint(t.Context()); err != nil {
+		t.Fatalf("CommitCheckpoint: %v", err)
+	}
+	// The rows the checkpoint covers are in the bucket, not only on disk.
+	keys := store.keys()
+	if len(keys) != 1 {
+		t.Fatalf("got objects %v after the checkpoint, want 1", keys)
+	}
+	if _, rows := readObject(t, store, keys[0]); len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+
+	failing := newTestSink(t, failingStore{newMemStore()}, Config{MaxFileAge: time.Hour})
+	defer crash(failing)
+	write(t, failing, `{"id":1}`)
+	if err := failing.CommitCheckpoint(t.Context()); err == nil {
+		t.Fatal("expected the checkpoint to fail while uploads fail")
 	}
 }
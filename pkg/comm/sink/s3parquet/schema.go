@@ -0,0 +1,575 @@
+package s3parquet
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hamba/avro/v2"
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/columnar"
+)
+
+// Kinds of column types.
+const (
+	KindBoolean   = "boolean"
+	KindInt32     = "int32"
+	KindInt64     = "int64"
+	KindFloat     = "float"
+	KindDouble    = "double"
+	KindString    = "string"
+	KindJSON      = "json"
+	KindBinary    = "binary"
+	KindDate      = "date"
+	KindTimestamp = "timestamp"
+	KindDecimal   = "decimal"
+	KindUUID      = "uuid"
+	KindList      = "list"
+	KindStruct    = "struct"
+)
+
+// maxDecimalPrecision is the largest precision written as a decimal. Wider
+// numbers are written as strings.
+const maxDecimalPrecision = 38
+
+// Type is the type of a column.
+type Type struct {
+	Kind string `json:"kind"`
+	// UTC marks timestamps that are instants rather than local date-times.
+	UTC       bool     `json:"utc,omitempty"`
+	Precision int      `json:"precision,omitempty"`
+	Scale     int      `json:"scale,omitempty"`
+	Elem      *Type    `json:"elem,omitempty"`
+	Fields    []Column `json:"fields,omitempty"`
+}
+
+// Column is a column of the files the sink writes. Every column is
+// optional, since a change event need not carry all of them.
+type Column struct {
+	Name string `json:"name"`
+	Type Type   `json:"type"`
+}
+
+func listOf(elem Type) Type {
+	return Type{Kind: KindList, Elem: &elem}
+}
+
+// columnsFromInfo maps the columns a source discovered to Parquet types.
+func columnsFromInfo(infos []hermod.ColumnInfo) []Column {
+	cols := make([]Column, 0, len(infos))
+	for _, c := range infos {
+		cols = append(cols, Column{Name: c.Name, Type: sqlType(c.Type)})
+	}
+	return cols
+}
+
+// sqlType maps a database column type, as sources report it, to a column
+// type. Unknown types are written as strings.
+func sqlType(typ string) Type {
+	t := strings.ToLower(strings.TrimSpace(typ))
+	if elem, ok := strings.CutSuffix(t, "[]"); ok {
+		return listOf(sqlType(elem))
+	}
+	if elem, ok := strings.CutPrefix(t, "_"); ok {
+		// PostgreSQL names array types after their element type.
+		return listOf(sqlType(elem))
+	}
+
+	var args []int
+	if i := strings.IndexByte(t, '('); i >= 0 {
+		if j := strings.IndexByte(t[i:], ')'); j >= 0 {
+			for _, a := range strings.Split(t[i+1:i+j], ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(a))
+				if err != nil {
+					break
+				}
+				args = append(args, n)
+			}
+			t = t[:i] + t[i+j+1:]
+		}
+	}
+	name := strings.Join(strings.Fields(t), " ")
+	unsigned := false
+	if n, ok := strings.CutSuffix(name, " unsigned"); ok {
+		name, unsigned = n, true
+	}
+
+	switch name {
+	case "bool", "boolean":
+		return Type{Kind: KindBoolean}
+	case "bit":
+		if len(args) == 0 || args[0] == 1 {
+			return Type{Kind: KindBoolean}
+		}
+		return Type{Kind: KindBinary}
+	case "tinyint":
+		if len(args) == 1 && args[0] == 1 {
+			// MySQL's boolean.
+			return Type{Kind: KindBoolean}
+		}
+		return Type{Kind: KindInt32}
+	case "smallint", "int2", "smallserial", "mediumint", "int", "integer", "int4", "serial", "year":
+		if unsigned {
+			return Type{Kind: KindInt64}
+		}
+		return Type{Kind: KindInt32}
+	case "bigint", "int8", "bigserial":
+		if unsigned {
+			return Type{Kind: KindDecimal, Precision: 20}
+		}
+		return Type{Kind: KindInt64}
+	case "real", "float4":
+		return Type{Kind: KindFloat}
+	case "float", "float8", "double", "double precision":
+		return Type{Kind: KindDouble}
+	case "numeric", "decimal", "dec", "number":
+		if len(args) == 0 || args[0] > maxDecimalPrecision {
+			// Arbitrary precision: keep every digit.
+			return Type{Kind: KindString}
+		}
+		typ := Type{Kind: KindDecimal, Precision: args[0]}
+		if len(args) > 1 {
+			typ.Scale = args[1]
+		}
+		return typ
+	case "date":
+		return Type{Kind: KindDate}
+	case "timestamp", "timestamp without time zone", "datetime", "datetime2", "smalldatetime":
+		return Type{Kind: KindTimestamp}
+	case "timestamptz", "timestamp with time zone", "timestamp with local time zone", "datetimeoffset":
+		return Type{Kind: KindTimestamp, UTC: true}
+	case "uuid", "uniqueidentifier":
+		return Type{Kind: KindUUID}
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "image", "raw", "long raw":
+		return Type{Kind: KindBinary}
+	case "json", "jsonb":
+		return Type{Kind: KindJSON}
+	}
+	return Type{Kind: KindString}
+}
+
+// registryColumns derives columns from a JSON Schema or Avro schema of
+// the schema registry.
+func registryColumns(schemaType, content string) ([]Column, error) {
+	switch schemaType {
+	case "avro":
+		s, err := avro.Parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid avro schema: %w", err)
+		}
+		rec, ok := s.(*avro.RecordSchema)
+		if !ok {
+			return nil, fmt.Errorf("avro schema is a %s, not a record", s.Type())
+		}
+		return avroFields(rec), nil
+	case "json":
+		var s map[string]any
+		if err := json.Unmarshal([]byte(content), &s); err != nil {
+			return nil, fmt.Errorf("invalid json schema: %w", err)
+		}
+		t := jsonSchemaType(s)
+		if t.Kind != KindStruct {
+			return nil, errors.New("json schema does not describe an object")
+		}
+		return t.Fields, nil
+	}
+	return nil, fmt.Errorf("%s schemas cannot describe parquet columns", schemaType)
+}
+
+func avroFields(rec *avro.RecordSchema) []Column {
+	cols := make([]Column, 0, len(rec.Fields()))
+	for _, f := range rec.Fields() {
+		cols = append(cols, Column{Name: f.Name(), Type: avroType(f.Type())})
+	}
+	return cols
+}
+
+func avroType(s avro.Schema) Type {
+	switch x := s.(type) {
+	case *avro.RefSchema:
+		return avroType(x.Schema())
+	case *avro.UnionSchema:
+		var types []avro.Schema
+		for _, t := range x.Types() {
+			if t.Type() != avro.Null {
+				types = append(types, t)
+			}
+		}
+		if len(types) == 1 {
+			return avroType(types[0])
+		}
+		return Type{Kind: KindJSON}
+	case *avro.RecordSchema:
+		return Type{Kind: KindStruct, Fields: avroFields(x)}
+	case *avro.ArraySchema:
+		return listOf(avroType(x.Items()))
+	case *avro.MapSchema:
+		return Type{Kind: KindJSON}
+	case *avro.EnumSchema:
+		return Type{Kind: KindString}
+	case *avro.FixedSchema:
+		if d, ok := x.Logical().(*avro.DecimalLogicalSchema); ok {
+			return decimalType(d.Precision(), d.Scale())
+		}
+		if l := x.Logical(); l != nil && l.Type() == avro.UUID {
+			return Type{Kind: KindUUID}
+		}
+		return Type{Kind: KindBinary}
+	case *avro.PrimitiveSchema:
+		if l := x.Logical(); l != nil {
+			switch l.Type() {
+			case avro.Decimal:
+				d := l.(*avro.DecimalLogicalSchema)
+				return decimalType(d.Precision(), d.Scale())
+			case avro.UUID:
+				return Type{Kind: KindUUID}
+			case avro.Date:
+				return Type{Kind: KindDate}
+			case avro.TimestampMillis, avro.TimestampMicros:
+				return Type{Kind: KindTimestamp, UTC: true}
+			case avro.LocalTimestampMillis, avro.LocalTimestampMicros:
+				return Type{Kind: KindTimestamp}
+			}
+		}
+		switch x.Type() {
+		case avro.Boolean:
+			return Type{Kind: KindBoolean}
+		case avro.Int:
+			return Type{Kind: KindInt32}
+		case avro.Long:
+			return Type{Kind: KindInt64}
+		case avro.Float:
+			return Type{Kind: KindFloat}
+		case avro.Double:
+			return Type{Kind: KindDouble}
+		case avro.Bytes:
+			return Type{Kind: KindBinary}
+		}
+	}
+	return Type{Kind: KindString}
+}
+
+func decimalType(precision, scale int) Type {
+	if precision > maxDecimalPrecision {
+		return Type{Kind: KindString}
+	}
+	return Type{Kind: KindDecimal, Precision: precision, Scale: scale}
+}
+
+func jsonSchemaType(s map[string]any) Type {
+	typ, _ := s["type"].(string)
+	if types, ok := s["type"].([]any); ok {
+		for _, t := range types {
+			if t, ok := t.(string); ok && t != "null" {
+				if typ != "" {
+					return Type{Kind: KindJSON}
+				}
+				typ = t
+			}
+		}
+	}
+	switch typ {
+	case "boolean":
+		return Type{Kind: KindBoolean}
+	case "integer":
+		return Type{Kind: KindInt64}
+	case "number":
+		return Type{Kind: KindDouble}
+	case "string":
+		switch s["format"] {
+		case "date-time":
+			return Type{Kind: KindTimestamp, UTC: true}
+		case "date":
+			return Type{Kind: KindDate}
+		case "uuid":
+			return Type{Kind: KindUUID}
+		}
+		if s["contentEncoding"] == "base64" {
+			return Type{Kind: KindBinary}
+		}
+		return Type{Kind: KindString}
+	case "array":
+		if items, ok := s["items"].(map[string]any); ok {
+			return listOf(jsonSchemaType(items))
+		}
+		return Type{Kind: KindJSON}
+	case "object":
+		props, ok := s["properties"].(map[string]any)
+		if !ok {
+			return Type{Kind: KindJSON}
+		}
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		t := Type{Kind: KindStruct}
+		for _, name := range names {
+			p, _ := props[name].(map[string]any)
+			t.Fields = append(t.Fields, Column{Name: name, Type: jsonSchemaType(p)})
+		}
+		return t
+	}
+	return Type{Kind: KindJSON}
+}
+
+// inferType returns the type of a value decoded from JSON with UseNumber, as
+// columnar.Infer reads it. An empty kind means the type is unknown, as for
+// nulls and empty lists.
+func inferType(v any) Type {
+	switch x := v.(type) {
+	case []any:
+		var elem Type
+		for _, e := range x {
+			elem = unify(elem, inferType(e))
+		}
+		return listOf(elem)
+	case map[string]any:
+		return Type{Kind: KindStruct, Fields: inferFields(nil, x)}
+	}
+	switch columnar.Infer(v) {
+	case columnar.KindUnknown:
+		return Type{}
+	case columnar.KindBoolean:
+		return Type{Kind: KindBoolean}
+	case columnar.KindLong:
+		return Type{Kind: KindInt64}
+	case columnar.KindDouble:
+		return Type{Kind: KindDouble}
+	case columnar.KindBinary:
+		return Type{Kind: KindBinary}
+	case columnar.KindUUID:
+		return Type{Kind: KindUUID}
+	case columnar.KindDate:
+		return Type{Kind: KindDate}
+	case columnar.KindTimestamp:
+		return Type{Kind: KindTimestamp}
+	case columnar.KindTimestampTZ:
+		return Type{Kind: KindTimestamp, UTC: true}
+	}
+	return Type{Kind: KindString}
+}
+
+// inferFields merges the fields of row into cols, keeping them sorted by
+// name.
+func inferFields(cols []Column, row map[string]any) []Column {
+	for name, v := range row {
+		i := sort.Search(len(cols), func(i int) bool { return cols[i].Name >= name })
+		if i < len(cols) && cols[i].Name == name {
+			cols[i].Type = unify(cols[i].Type, inferType(v))
+			continue
+		}
+		cols = append(cols, Column{})
+		copy(cols[i+1:], cols[i:])
+		cols[i] = Column{Name: name, Type: inferType(v)}
+	}
+	return cols
+}
+
+// unify returns a type that holds values of both a and b.
+func unify(a, b Type) Type {
+	switch {
+	case a.Kind == "":
+		return b
+	case b.Kind == "":
+		return a
+	case a.Kind == KindList && b.Kind == KindList:
+		elem := unify(derefType(a.Elem), derefType(b.Elem))
+		return listOf(elem)
+	case a.Kind == KindStruct && b.Kind == KindStruct:
+		fields := append([]Column(nil), a.Fields...)
+		for _, f := range b.Fields {
+			i := sort.Search(len(fields), func(i int) bool { return fields[i].Name >= f.Name })
+			if i < len(fields) && fields[i].Name == f.Name {
+				fields[i].Type = unify(fields[i].Type, f.Type)
+				continue
+			}
+			fields = append(fields, Column{})
+			copy(fields[i+1:], fields[i:])
+			fields[i] = f
+		}
+		return Type{Kind: KindStruct, Fields: fields}
+	case a.Kind == b.Kind:
+		a.UTC = a.UTC || b.UTC
+		return a
+	}
+	pair := map[string]bool{a.Kind: true, b.Kind: true}
+	switch {
+	case pair[KindInt64] && pair[KindDouble]:
+		return Type{Kind: KindDouble}
+	case pair[KindDate] && pair[KindTimestamp]:
+		return Type{Kind: KindTimestamp, UTC: a.UTC || b.UTC}
+	case pair[KindList] || pair[KindStruct] || pair[KindJSON]:
+		return Type{Kind: KindJSON}
+	}
+	return Type{Kind: KindString}
+}
+
+func derefType(t *Type) Type {
+	if t == nil {
+		return Type{}
+	}
+	return *t
+}
+
+// resolve replaces unknown types, of columns that only held nulls or empty
+// lists, with strings.
+func resolve(t Type) Type {
+	switch t.Kind {
+	case "":
+		return Type{Kind: KindString}
+	case KindList:
+		return listOf(resolve(derefType(t.Elem)))
+	case KindStruct:
+		fields := make([]Column, len(t.Fields))
+		for i, f := range t.Fields {
+			fields[i] = Column{Name: f.Name, Type: resolve(f.Type)}
+		}
+		t.Fields = fields
+	}
+	return t
+}
+
+// normalize converts v to the representation of a value of type t that is
+// spooled: bool, int64, float64, string, []byte, time.Time, a date string,
+// a decimal string with t.Scale digits, []any and map[string]any. It also
+// accepts what JSON decoding with UseNumber makes of those, so spooled rows
+// can be normalized again. Timestamps given as numbers are epoch
+// milliseconds and dates given as numbers are days since the epoch.
+func normalize(v any, t Type) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch t.Kind {
+	case KindBoolean:
+		if b, ok := columnar.Bool(v); ok {
+			return b, nil
+		}
+	case KindInt32, KindInt64:
+		if n, ok := columnar.Int64(v); ok {
+			if t.Kind == KindInt32 && (n < math.MinInt32 || n > math.MaxInt32) {
+				return nil, fmt.Errorf("%d does not fit in int32", n)
+			}
+			return n, nil
+		}
+	case KindFloat, KindDouble:
+		if f, ok := columnar.Float64(v); ok {
+			return f, nil
+		}
+	case KindString:
+		return columnar.String(v)
+	case KindJSON:
+		if s, ok := v.(string); ok && json.Valid([]byte(s)) {
+			return s, nil
+		}
+		b, err := json.Marshal(v)
+		return string(b), err
+	case KindBinary:
+		switch x := v.(type) {
+		case []byte:
+			return x, nil
+		case string:
+			// JSON carries bytes as base64.
+			if b, err := base64.StdEncoding.DecodeString(x); err == nil {
+				return b, nil
+			}
+			return []byte(x), nil
+		}
+	case KindDate:
+		if d, ok := columnar.Date(v); ok {
+			return d.Format(time.DateOnly), nil
+		}
+	case KindTimestamp:
+		if ts, ok := columnar.Time(v); ok {
+			return ts.UTC(), nil
+		}
+	case KindDecimal:
+		return normalizeDecimal(v, t)
+	case KindUUID:
+		switch x := v.(type) {
+		case string:
+			if id, err := uuid.Parse(x); err == nil {
+				return id.String(), nil
+			}
+		case []byte:
+			if id, err := uuid.FromBytes(x); err == nil {
+				return id.String(), nil
+			}
+		}
+	case KindList:
+		items, ok := v.([]any)
+		if !ok {
+			break
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			n, err := normalize(item, derefType(t.Elem))
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			out[i] = n
+		}
+		return out, nil
+	case KindStruct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			break
+		}
+		out := make(map[string]any, len(t.Fields))
+		for _, f := range t.Fields {
+			n, err := normalize(m[f.Name], f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", f.Name, err)
+			}
+			if n != nil {
+				out[f.Name] = n
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unknown column type %q", t.Kind)
+	}
+	return nil, fmt.Errorf("cannot store %T value %v as %s", v, v, t.Kind)
+}
+
+// normalizeDecimal formats v with exactly t.Scale fractional digits,
+// failing when that would lose digits or exceed the precision.
+func normalizeDecimal(v any, t Type) (any, error) {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = strings.TrimSpace(x)
+	case json.Number:
+		s = x.String()
+	case float64:
+		s = strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		s = strconv.FormatFloat(float64(x), 'f', -1, 32)
+	default:
+		n, ok := columnar.Int64(v)
+		if !ok {
+			return nil, fmt.Errorf("cannot store %T value %v as decimal", v, v)
+		}
+		s = strconv.FormatInt(n, 10)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a number", s)
+	}
+	unscaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Scale)), nil)))
+	if !unscaled.IsInt() {
+		return nil, fmt.Errorf("%s has more than %d decimal places", s, t.Scale)
+	}
+	if digits := len(new(big.Int).Abs(unscaled.Num()).String()); digits > t.Precision {
+		return nil, fmt.Errorf("%s does not fit in decimal(%d,%d)", s, t.Precision, t.Scale)
+	}
+	return r.FloatString(t.Scale), nil
+}
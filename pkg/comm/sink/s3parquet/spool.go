@@ -0,0 +1,307 @@
+package s3parquet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File extensions in the spool directory. A spool holds the rows of one
+// future Parquet file as JSON lines, after a header line. Once sealed, its
+// upload state is kept next to it, and the Parquet file is built from it.
+const (
+	spoolExt   = ".spool"
+	stateExt   = ".state"
+	parquetExt = ".parquet"
+
+	checkpointFile = "checkpoint.json"
+)
+
+// spoolHeader is the first line of a spool.
+type spoolHeader struct {
+	Table     string    `json:"table,omitempty"`
+	Partition string    `json:"partition,omitempty"`
+	Created   time.Time `json:"created"`
+	// Columns are the columns known from upstream metadata when the spool
+	// was created. Their values are spooled normalized.
+	Columns []Column `json:"columns,omitempty"`
+}
+
+// uploadState tracks the upload of a sealed spool, so an interrupted
+// multipart upload resumes with the parts already sent.
+type uploadState struct {
+	Key      string `json:"key"`
+	UploadID string `json:"upload_id,omitempty"`
+	Parts    []part `json:"parts,omitempty"`
+	Uploaded bool   `json:"uploaded,omitempty"`
+}
+
+type part struct {
+	Number int32  `json:"number"`
+	ETag   string `json:"etag"`
+}
+
+// checkpoint records the spools and their sizes when the workflow last
+// checkpointed. Rows appended since are replayed by the source after a
+// crash, so recovery cuts spools back to these sizes.
+type checkpoint struct {
+	Spools map[string]checkpointEntry `json:"spools"`
+}
+
+type checkpointEntry struct {
+	Size     int64 `json:"size"`
+	Uploaded bool  `json:"uploaded,omitempty"`
+}
+
+type spool struct {
+	id     string
+	dir    string
+	header spoolHeader
+	f      *os.File
+	size   int64
+	rows   int
+	// state is set once the spool is sealed.
+	state *uploadState
+}
+
+func (s *spool) path(ext string) string {
+	return filepath.Join(s.dir, s.id+ext)
+}
+
+// createSpool creates a spool and writes its header.
+func createSpool(dir, id string, h spoolHeader) (*spool, error) {
+	sp := &spool{id: id, dir: dir, header: h}
+	f, err := os.OpenFile(sp.path(spoolExt), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool: %w", err)
+	}
+	sp.f = f
+	line, err := json.Marshal(h)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := sp.append([][]byte{line}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	sp.rows = 0
+	return sp, nil
+}
+
+// openSpool reads the header of an existing spool, counts its rows and
+// cuts off a torn last line. The spool is not opened for writing.
+func openSpool(dir, id string) (*spool, error) {
+	sp := &spool{id: id, dir: dir}
+	f, err := os.Open(sp.path(spoolExt))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	first := true
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			if err := json.Unmarshal(line, &sp.header); err != nil {
+				return nil, fmt.Errorf("invalid spool header: %w", err)
+			}
+			first = false
+		} else {
+			sp.rows++
+		}
+		sp.size += int64(len(line))
+	}
+	if first {
+		return nil, errors.New("spool has no header")
+	}
+	if err := os.Truncate(sp.path(spoolExt), sp.size); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// reopen opens the spool for appending.
+func (s *spool) reopen() error {
+	f, err := os.OpenFile(s.path(spoolExt), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	s.f = f
+	return nil
+}
+
+// append writes lines and syncs them to disk.
+func (s *spool) append(lines [][]byte) error {
+	var buf bytes.Buffer
+	for _, l := range lines {
+		buf.Write(l)
+		buf.WriteByte('\n')
+	}
+	if _, err := s.f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool: %w", err)
+	}
+	s.size += int64(buf.Len())
+	s.rows += len(lines)
+	return nil
+}
+
+func (s *spool) close() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// remove deletes every file of the spool.
+func (s *spool) remove() error {
+	s.close()
+	var errs []error
+	for _, ext := range []string{spoolExt, stateExt, stateExt + ".tmp", parquetExt, parquetExt + ".tmp"} {
+		if err := os.Remove(s.path(ext)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// each calls fn with every row of the spool, decoded with UseNumber.
+func (s *spool) each(fn func(row map[string]any) error) error {
+	f, err := os.Open(s.path(spoolExt))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 1<<20)
+	first := true
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if first {
+			first = false
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		var row map[string]any
+		if err := dec.Decode(&row); err != nil {
+			return fmt.Errorf("invalid spooled row: %w", err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// saveState persists the upload state of a sealed spool.
+func (s *spool) saveState() error {
+	b, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(stateExt), b)
+}
+
+// loadState reads the upload state of a spool, which is nil when the
+// spool was not sealed.
+func (s *spool) loadState() error {
+	b, err := os.ReadFile(s.path(stateExt))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var st uploadState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return fmt.Errorf("invalid upload state: %w", err)
+	}
+	s.state = &st
+	return nil
+}
+
+// listSpools returns the IDs of the spools in dir, oldest first.
+func listSpools(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), spoolExt); ok && !e.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// readCheckpoint returns the last checkpoint, or nil if the sink never
+// checkpointed.
+func readCheckpoint(dir string) (*checkpoint, error) {
+	b, err := os.ReadFile(filepath.Join(dir, checkpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("invalid spool checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+func writeCheckpoint(dir string, cp checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, checkpointFile), b)
+}
+
+// writeFileAtomic replaces path with data, synced to disk.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
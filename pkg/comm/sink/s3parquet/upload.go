@@ -0,0 +1,245 @@
+package s3parquet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// objectStore is the part of the S3 API the sink uses.
+type objectStore interface {
+	HeadBucket(ctx context.Context, in *s3.HeadBucketInput, opts ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, opts ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, in *s3.UploadPartInput, opts ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, opts ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, opts ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, opts ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// writeParquet builds the Parquet file of a sealed spool. Columns known
+// from metadata come first, followed by the ones inferred from the rows.
+func (s *S3ParquetSink) writeParquet(sp *spool) (int64, error) {
+	tmp := sp.path(parquetExt + ".tmp")
+	fw, err := local.NewLocalFileWriter(tmp)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create parquet file: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	var pw *writer.ParquetWriter
+	var layout *rowLayout
+	if s.cfg.Schema != "" {
+		var jw *writer.JSONWriter
+		if jw, err = writer.NewJSONWriter(s.cfg.Schema, fw, s.cfg.Parallelizer); err == nil {
+			pw = &jw.ParquetWriter
+		}
+	} else {
+		layout, err = s.spoolLayout(sp)
+		if err == nil {
+			pw, err = writer.NewParquetWriter(fw, reflect.New(layout.typ).Interface(), s.cfg.Parallelizer)
+		}
+	}
+	if err != nil {
+		fw.Close()
+		return 0, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	pw.CompressionType = parquet.CompressionCodec_ZSTD
+
+	err = sp.each(func(row map[string]any) error {
+		var v any = row
+		if layout != nil {
+			var err error
+			if v, err = layout.value(row); err != nil {
+				return err
+			}
+		} else {
+			b, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			v = string(b)
+		}
+		if err := pw.Write(v); err != nil {
+			return fmt.Errorf("failed to write parquet row: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		pw.WriteStop()
+		fw.Close()
+		return 0, err
+	}
+	if err := pw.WriteStop(); err != nil {
+		fw.Close()
+		return 0, fmt.Errorf("failed to stop parquet writer: %w", err)
+	}
+	if err := fw.Close(); err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, sp.path(parquetExt)); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// spoolLayout returns the layout of the Parquet file of sp.
+func (s *S3ParquetSink) spoolLayout(sp *spool) (*rowLayout, error) {
+	known := make(map[string]bool, len(sp.header.Columns))
+	for _, c := range sp.header.Columns {
+		known[c.Name] = true
+	}
+	var inferred []Column
+	err := sp.each(func(row map[string]any) error {
+		for name := range known {
+			delete(row, name)
+		}
+		inferred = inferFields(inferred, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	cols := append([]Column(nil), sp.header.Columns...)
+	for _, c := range inferred {
+		cols = append(cols, Column{Name: c.Name, Type: resolve(c.Type)})
+	}
+	return newRowLayout(cols)
+}
+
+// upload sends the Parquet file of a sealed spool as a multipart upload,
+// recording each part so a restart resumes after the last one sent.
+func (s *S3ParquetSink) upload(ctx context.Context, store objectStore, sp *spool) error {
+	st := sp.state
+	if _, err := os.Stat(sp.path(parquetExt)); errors.Is(err, os.ErrNotExist) {
+		// Parts sent before a restart may belong to a different build of
+		// the file, so start over.
+		if st.UploadID != "" {
+			s.abortUpload(ctx, store, st)
+			st.UploadID, st.Parts = "", nil
+			if err := sp.saveState(); err != nil {
+				return err
+			}
+		}
+		spooled := sp.size
+		size, err := s.writeParquet(sp)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		if spooled > 0 {
+			s.ratio = float64(size) / float64(spooled)
+		}
+		s.mu.Unlock()
+	}
+
+	f, err := os.Open(sp.path(parquetExt))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if st.UploadID == "" {
+		out, err := store.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:      aws.String(s.cfg.Bucket),
+			Key:         aws.String(st.Key),
+			ContentType: aws.String("application/vnd.apache.parquet"),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start upload of %s: %w", st.Key, err)
+		}
+		st.UploadID = aws.ToString(out.UploadId)
+		if err := sp.saveState(); err != nil {
+			return err
+		}
+	}
+
+	partSize := s.cfg.PartSize
+	for n := int64(len(st.Parts)); n == 0 || n*partSize < info.Size(); n++ {
+		size := min(partSize, info.Size()-n*partSize)
+		out, err := store.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(s.cfg.Bucket),
+			Key:           aws.String(st.Key),
+			UploadId:      aws.String(st.UploadID),
+			PartNumber:    aws.Int32(int32(n + 1)),
+			Body:          io.NewSectionReader(f, n*partSize, size),
+			ContentLength: aws.Int64(size),
+		})
+		if err != nil {
+			if isNoSuchUpload(err) {
+				st.UploadID, st.Parts = "", nil
+				_ = sp.saveState()
+			}
+			return fmt.Errorf("failed to upload part %d of %s: %w", n+1, st.Key, err)
+		}
+		st.Parts = append(st.Parts, part{Number: int32(n + 1), ETag: aws.ToString(out.ETag)})
+		if err := sp.saveState(); err != nil {
+			return err
+		}
+	}
+
+	parts := make([]types.CompletedPart, len(st.Parts))
+	for i, p := range st.Parts {
+		parts[i] = types.CompletedPart{PartNumber: aws.Int32(p.Number), ETag: aws.String(p.ETag)}
+	}
+	_, err = store.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.cfg.Bucket),
+		Key:             aws.String(st.Key),
+		UploadId:        aws.String(st.UploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		if isNoSuchUpload(err) {
+			st.UploadID, st.Parts = "", nil
+			_ = sp.saveState()
+		}
+		return fmt.Errorf("failed to complete upload of %s: %w", st.Key, err)
+	}
+
+	s.mu.Lock()
+	st.Uploaded = true
+	s.mu.Unlock()
+	if err := sp.saveState(); err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(sp.path(parquetExt))
+}
+
+// abortUpload gives up a multipart upload, so the parts sent are not
+// billed. Failures are ignored: the bucket's lifecycle rules clean up.
+func (s *S3ParquetSink) abortUpload(ctx context.Context, store objectStore, st *uploadState) {
+	_, _ = store.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.cfg.Bucket),
+		Key:      aws.String(st.Key),
+		UploadId: aws.String(st.UploadID),
+	})
+}
+
+func isNoSuchUpload(err error) bool {
+	var nsu *types.NoSuchUpload
+	if errors.As(err, &nsu) {
+		return true
+	}
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchUpload"
+}
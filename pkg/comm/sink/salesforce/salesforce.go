@@ -4,18 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/user/hermod"
 	"github.com/user/hermod/pkg/infra/evaluator"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
-// SalesforceSink implements the hermod.Sink interface for Salesforce Bulk API 2.0.
+// SalesforceSink implements the hermod.Sink interface for Salesforce. Single
+// writes use the sObject REST API and batches the sObject Collections API.
 type SalesforceSink struct {
 	instanceURL   string
 	accessToken   string
@@ -47,7 +52,7 @@ func NewSalesforceSink(clientID, clientSecret, username, password, securityToken
 		object:        object,
 		operation:     operation,
 		externalID:    externalID,
-		client:        &http.Client{Timeout: 30 * time.Second},
+		client:        httpclient.NewRateLimitedClient(30 * time.Second),
 	}
 }
 
@@ -117,11 +122,7 @@ func (s *SalesforceSink) writeRecord(ctx context.Context, msg hermod.Message) er
 
 	switch s.operation {
 	case "update", "upsert":
-		idVal := evaluator.GetMsgValByPath(msg, "Id")
-		if idVal == nil {
-			idVal = evaluator.GetMsgValByPath(msg, "after.Id")
-		}
-		id, _ := idVal.(string)
+		id := recordID(msg, s.operation)
 
 		if s.operation == "upsert" && s.externalID != "" {
 			extVal := fmt.Sprintf("%v", evaluator.GetMsgValByPath(msg, s.externalID))
@@ -134,11 +135,7 @@ func (s *SalesforceSink) writeRecord(ctx context.Context, msg hermod.Message) er
 			method = "PATCH"
 		}
 	case "delete":
-		idVal := evaluator.GetMsgValByPath(msg, "Id")
-		if idVal == nil {
-			idVal = evaluator.GetMsgValByPath(msg, "before.Id")
-		}
-		id, _ := idVal.(string)
+		id := recordID(msg, s.operation)
 
 		if id != "" {
 			url = fmt.Sprintf("%s/services/data/v59.0/sobjects/%s/%s", instanceURL, s.object, id)
@@ -183,124 +180,197 @@ func (s *SalesforceSink) writeRecord(ctx context.Context, msg hermod.Message) er
 }
 
 func (s *SalesforceSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return hermod.BatchError(s.WriteBatchResults(ctx, msgs))
+}
+
+// maxCollectionRecords is the most records an sObject Collections request
+// accepts.
+const maxCollectionRecords = 200
+
+// collectionResult is the outcome the sObject Collections API reports for
+// one record.
+type collectionResult struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Errors  []struct {
+		StatusCode string   `json:"statusCode"`
+		Message    string   `json:"message"`
+		Fields     []string `json:"fields"`
+	} `json:"errors"`
+}
+
+// WriteBatchResults writes msgs with the sObject Collections API. Records are
+// saved independently (allOrNone=false) and the API reports a result for each
+// one, so a record failing validation does not hold back the others.
+func (s *SalesforceSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
 	if len(msgs) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	if s.accessToken == "" {
 		if err := s.authenticate(ctx); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	// 1. Create Job
-	jobID, err := s.createJob(ctx)
-	if err != nil {
-		return err
+	errs := make([]error, len(msgs))
+	// pending holds the indexes of the messages that can be sent.
+	pending := make([]int, 0, len(msgs))
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		if s.operation == "update" || s.operation == "delete" {
+			if recordID(msg, s.operation) == "" {
+				errs[i] = hermod.Classify(fmt.Errorf("salesforce %s requires an Id", s.operation), hermod.ErrorPermanent)
+				continue
+			}
+		}
+		pending = append(pending, i)
 	}
 
-	// 2. Upload Data (CSV format is best for Bulk 2.0)
-	if err := s.uploadJobData(ctx, jobID, msgs); err != nil {
-		return err
+	for start := 0; start < len(pending); start += maxCollectionRecords {
+		chunk := pending[start:min(start+maxCollectionRecords, len(pending))]
+		batch := make([]hermod.Message, len(chunk))
+		for k, i := range chunk {
+			batch[k] = msgs[i]
+		}
+		results, err := s.saveCollection(ctx, batch, true)
+		if err != nil {
+			if len(chunk) == len(msgs) {
+				return nil, err
+			}
+			for _, i := range chunk {
+				errs[i] = err
+			}
+			continue
+		}
+		for k, i := range chunk {
+			if k >= len(results) {
+				errs[i] = fmt.Errorf("salesforce returned no result for record %d of the batch", k)
+				continue
+			}
+			errs[i] = results[k].err()
+		}
 	}
-
-	// 3. Close Job
-	return s.closeJob(ctx, jobID)
+	return errs, nil
 }
 
-func (s *SalesforceSink) createJob(ctx context.Context) (string, error) {
+// saveCollection sends one sObject Collections request for msgs.
+func (s *SalesforceSink) saveCollection(ctx context.Context, msgs []hermod.Message, reauth bool) ([]collectionResult, error) {
 	s.mu.RLock()
-	url := s.instanceURL + "/services/data/v59.0/jobs/ingest"
+	base := s.instanceURL + "/services/data/v59.0/composite/sobjects"
 	accessToken := s.accessToken
 	s.mu.RUnlock()
 
-	jobSpec := map[string]string{
-		"object":      s.object,
-		"contentType": "CSV",
-		"operation":   s.operation,
-		"lineEnding":  "LF",
+	var req *http.Request
+	var err error
+	if s.operation == "delete" {
+		ids := make([]string, len(msgs))
+		for k, msg := range msgs {
+			ids[k] = recordID(msg, s.operation)
+		}
+		q := url.Values{"ids": {strings.Join(ids, ",")}, "allOrNone": {"false"}}
+		req, err = http.NewRequestWithContext(ctx, http.MethodDelete, base+"?"+q.Encode(), nil)
+	} else {
+		records := make([]map[string]any, len(msgs))
+		for k, msg := range msgs {
+			rec := maps.Clone(msg.Data())
+			if rec == nil {
+				rec = make(map[string]any)
+			}
+			rec["attributes"] = map[string]string{"type": s.object}
+			if s.operation == "update" {
+				rec["Id"] = recordID(msg, s.operation)
+			}
+			records[k] = rec
+		}
+		body, merr := json.Marshal(map[string]any{"allOrNone": false, "records": records})
+		if merr != nil {
+			return nil, hermod.Classify(fmt.Errorf("failed to marshal salesforce records: %w", merr), hermod.ErrorPermanent)
+		}
+		method, endpoint := http.MethodPost, base
+		switch s.operation {
+		case "update":
+			method = http.MethodPatch
+		case "upsert":
+			method, endpoint = http.MethodPatch, fmt.Sprintf("%s/%s/%s", base, s.object, s.externalID)
+		}
+		req, err = http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
 	}
-	if s.operation == "upsert" {
-		jobSpec["externalIdFieldName"] = s.externalID
+	if err != nil {
+		return nil, err
 	}
-
-	body, _ := json.Marshal(jobSpec)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var res struct {
-		ID string `json:"id"`
+	if resp.StatusCode == http.StatusUnauthorized && reauth {
+		// Token expired, re-auth and retry once
+		if err := s.authenticate(ctx); err != nil {
+			return nil, err
+		}
+		return s.saveCollection(ctx, msgs, false)
 	}
-	json.NewDecoder(resp.Body).Decode(&res)
-	return res.ID, nil
-}
 
-func (s *SalesforceSink) uploadJobData(ctx context.Context, jobID string, msgs []hermod.Message) error {
-	s.mu.RLock()
-	url := fmt.Sprintf("%s/services/data/v59.0/jobs/ingest/%s/batches", s.instanceURL, jobID)
-	accessToken := s.accessToken
-	s.mu.RUnlock()
-
-	// Convert messages to CSV
-	if len(msgs) == 0 {
-		return nil
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("salesforce api error (%d): %s", resp.StatusCode, string(respBody))
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return nil, hermod.Throttled(err, 0)
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return nil, hermod.Classify(err, hermod.ErrorFatalConfig)
+		}
+		return nil, err
 	}
 
-	// Get headers from first message
-	var headers []string
-	firstData := msgs[0].Data()
-	for k := range firstData {
-		headers = append(headers, k)
+	var results []collectionResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode salesforce response: %w", err)
 	}
+	return results, nil
+}
 
-	var csvBuf bytes.Buffer
-	csvBuf.WriteString(strings.Join(headers, ",") + "\n")
-	for _, msg := range msgs {
-		data := msg.Data()
-		var row []string
-		for _, h := range headers {
-			val := data[h]
-			row = append(row, fmt.Sprintf("\"%v\"", val)) // Simple quoting
-		}
-		csvBuf.WriteString(strings.Join(row, ",") + "\n")
+// err converts a failed record's result to an error. Row locks clear up and
+// request limits reset, so those are worth retrying; anything else is about
+// the record itself.
+func (r collectionResult) err() error {
+	if r.Success {
+		return nil
 	}
-
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, url, &csvBuf)
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "text/csv")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
+	if len(r.Errors) == 0 {
+		return errors.New("salesforce rejected the record without a reason")
+	}
+	e := r.Errors[0]
+	err := fmt.Errorf("salesforce rejected the record: %s: %s", e.StatusCode, e.Message)
+	switch e.StatusCode {
+	case "UNABLE_TO_LOCK_ROW":
 		return err
+	case "REQUEST_LIMIT_EXCEEDED":
+		return hermod.Throttled(err, 0)
 	}
-	defer resp.Body.Close()
-	return nil
+	return hermod.Classify(err, hermod.ErrorPermanent)
 }
 
-func (s *SalesforceSink) closeJob(ctx context.Context, jobID string) error {
-	s.mu.RLock()
-	url := fmt.Sprintf("%s/services/data/v59.0/jobs/ingest/%s", s.instanceURL, jobID)
-	accessToken := s.accessToken
-	s.mu.RUnlock()
-
-	body := []byte(`{"state":"UploadComplete"}`)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(body))
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return err
+// recordID returns the Salesforce Id a message refers to, taken from the
+// after image, or for deletes the before image, when it is not top-level.
+func recordID(msg hermod.Message, operation string) string {
+	idVal := evaluator.GetMsgValByPath(msg, "Id")
+	if idVal == nil {
+		if operation == "delete" {
+			idVal = evaluator.GetMsgValByPath(msg, "before.Id")
+		} else {
+			idVal = evaluator.GetMsgValByPath(msg, "after.Id")
+		}
 	}
-	defer resp.Body.Close()
-	return nil
+	id, _ := idVal.(string)
+	return id
 }
 
 func (s *SalesforceSink) Ping(ctx context.Context) error {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 type Config struct {
@@ -27,7 +28,7 @@ type Sink struct {
 func NewSink(config Config) *Sink {
 	return &Sink{
 		config: config,
-		client: &http.Client{Timeout: 30 * time.Second},
+		client: httpclient.NewRateLimitedClient(30 * time.Second),
 	}
 }
 
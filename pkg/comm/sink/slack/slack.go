@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 // SlackSink implements the hermod.Sink interface for Slack.
@@ -76,7 +77,7 @@ func (s *SlackSink) sendWebhook(ctx context.Context, text string) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -103,7 +104,7 @@ func (s *SlackSink) sendBotMessage(ctx context.Context, text string) error {
 	req.Header.Set("Authorization", "Bearer "+s.token)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -146,7 +147,7 @@ func (s *SlackSink) Ping(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := httpclient.RateLimitedClient.Do(req)
 		if err != nil {
 			return err
 		}
@@ -164,7 +165,7 @@ func (s *SlackSink) Ping(ctx context.Context) error {
 			return err
 		}
 		req.Header.Set("Authorization", "Bearer "+s.token)
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := httpclient.RateLimitedClient.Do(req)
 		if err != nil {
 			return err
 		}
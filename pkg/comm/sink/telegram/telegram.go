@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 // TelegramSink implements the hermod.Sink interface for Telegram.
@@ -58,7 +59,7 @@ func (s *TelegramSink) Write(ctx context.Context, msg hermod.Message) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -97,7 +98,7 @@ func (s *TelegramSink) Ping(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 // TikTokSink implements the hermod.Sink interface for TikTok.
@@ -80,7 +81,7 @@ func (s *TikTokSink) Write(ctx context.Context, msg hermod.Message) error {
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -118,7 +119,7 @@ func (s *TikTokSink) Ping(ctx context.Context) error {
 	}
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 // TwitterSink implements the hermod.Sink interface for Twitter (X).
@@ -65,7 +66,7 @@ func (s *TwitterSink) Write(ctx context.Context, msg hermod.Message) error {
 	req.Header.Set("Authorization", "Bearer "+s.token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -103,7 +104,7 @@ func (s *TwitterSink) Ping(ctx context.Context) error {
 	}
 	req.Header.Set("Authorization", "Bearer "+s.token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.RateLimitedClient.Do(req)
 	if err != nil {
 		return err
 	}
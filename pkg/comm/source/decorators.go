@@ -161,3 +161,17 @@ func (s *MetricsSource) StreamSilenceThreshold() time.Duration {
 	}
 	return 0
 }
+
+// Buffered forwards the engine's buffer acknowledgement to the wrapped source.
+func (s *MetricsSource) Buffered(ctx context.Context, msg hermod.Message) {
+	if ba, ok := s.Source.(hermod.BufferAcker); ok {
+		ba.Buffered(ctx, msg)
+	}
+}
+
+// Nack forwards the engine's negative acknowledgement to the wrapped source.
+func (s *MetricsSource) Nack(ctx context.Context, msg hermod.Message, err error) {
+	if n, ok := s.Source.(hermod.Nacker); ok {
+		n.Nack(ctx, msg, err)
+	}
+}
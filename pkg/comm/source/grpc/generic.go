@@ -83,7 +83,7 @@ func (s *GenericProtoSource) Read(ctx context.Context) (hermod.Message, error) {
 	}
 
 	// Convert dynamic message to data map
-	data := s.convertToMap(dynMsg)
+	data := dynamicToMap(dynMsg)
 	for k, v := range data {
 		msg.SetData(k, v)
 	}
@@ -91,32 +91,33 @@ func (s *GenericProtoSource) Read(ctx context.Context) (hermod.Message, error) {
 	return msg, nil
 }
 
-func (s *GenericProtoSource) convertToMap(dynMsg *dynamic.Message) map[string]any {
+// dynamicToMap converts a decoded message into the data map a message carries.
+func dynamicToMap(dynMsg *dynamic.Message) map[string]any {
 	res := make(map[string]any)
 	for _, fd := range dynMsg.GetMessageDescriptor().GetFields() {
 		if !dynMsg.HasField(fd) {
 			continue
 		}
 		val := dynMsg.GetField(fd)
-		res[fd.GetName()] = s.convertValue(val)
+		res[fd.GetName()] = dynamicValue(val)
 	}
 	return res
 }
 
-func (s *GenericProtoSource) convertValue(val any) any {
+func dynamicValue(val any) any {
 	switch v := val.(type) {
 	case *dynamic.Message:
-		return s.convertToMap(v)
+		return dynamicToMap(v)
 	case []*dynamic.Message:
 		res := make([]map[string]any, len(v))
 		for i, m := range v {
-			res[i] = s.convertToMap(m)
+			res[i] = dynamicToMap(m)
 		}
 		return res
 	case []any:
 		res := make([]any, len(v))
 		for i, item := range v {
-			res[i] = s.convertValue(item)
+			res[i] = dynamicValue(item)
 		}
 		return res
 	default:
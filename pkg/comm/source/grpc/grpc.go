@@ -14,53 +14,94 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
+// endpoint is the buffer behind a registered path. ch is never closed, so a
+// publisher blocked on a full buffer cannot panic when the source goes away;
+// done is closed instead.
+type endpoint struct {
+	ch   chan hermod.Message
+	done chan struct{}
+}
+
 var (
-	registry = make(map[string]chan hermod.Message)
+	registry = make(map[string]*endpoint)
 	mu       sync.RWMutex
 )
 
 // Register creates a new channel for a gRPC path.
 func Register(path string) chan hermod.Message {
+	return register(path).ch
+}
+
+func register(path string) *endpoint {
 	mu.Lock()
 	defer mu.Unlock()
-	if ch, ok := registry[path]; ok {
-		return ch
+	if ep, ok := registry[path]; ok {
+		return ep
+	}
+	ep := &endpoint{
+		ch:   make(chan hermod.Message, sourcebuf.DefaultSourceBuffer),
+		done: make(chan struct{}),
 	}
-	ch := make(chan hermod.Message, sourcebuf.DefaultSourceBuffer)
-	registry[path] = ch
-	return ch
+	registry[path] = ep
+	return ep
 }
 
 // Unregister closes and removes the channel for a gRPC path.
 func Unregister(path string) {
 	mu.Lock()
 	defer mu.Unlock()
-	if ch, ok := registry[path]; ok {
-		close(ch)
+	if ep, ok := registry[path]; ok {
+		close(ep.done)
 		delete(registry, path)
 	}
 }
 
-// Dispatch sends a message to the channel registered for the given path.
-func Dispatch(path string, msg hermod.Message) error {
+func lookup(path string) (*endpoint, error) {
 	mu.RLock()
-	ch, ok := registry[path]
+	ep, ok := registry[path]
 	mu.RUnlock()
 	if !ok {
-		return fmt.Errorf("no gRPC source registered for path: %s", path)
+		return nil, fmt.Errorf("no gRPC source registered for path: %s", path)
+	}
+	return ep, nil
+}
+
+// Dispatch sends a message to the channel registered for the given path.
+func Dispatch(path string, msg hermod.Message) error {
+	ep, err := lookup(path)
+	if err != nil {
+		return err
 	}
 	select {
-	case ch <- msg:
+	case ep.ch <- msg:
 		return nil
 	default:
 		return fmt.Errorf("gRPC source buffer full for path: %s", path)
 	}
 }
 
+// dispatchWait is Dispatch for a publisher that can wait: while the path's
+// buffer is full it blocks, which is how a stalled workflow pushes back on a
+// stream.
+func dispatchWait(ctx context.Context, path string, msg hermod.Message) error {
+	ep, err := lookup(path)
+	if err != nil {
+		return err
+	}
+	select {
+	case ep.ch <- msg:
+		return nil
+	case <-ep.done:
+		return fmt.Errorf("gRPC source closed for path: %s", path)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // GrpcSource implements the hermod.Source interface for receiving gRPC calls.
 type GrpcSource struct {
 	Path string
-	ch   chan hermod.Message
+	ep   *endpoint
 }
 
 // NewGrpcSource creates a new GrpcSource.
@@ -70,7 +111,7 @@ func NewGrpcSource(path string) *GrpcSource {
 	}
 	return &GrpcSource{
 		Path: path,
-		ch:   Register(path),
+		ep:   register(path),
 	}
 }
 
@@ -78,18 +119,39 @@ func (s *GrpcSource) Read(ctx context.Context) (hermod.Message, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case msg, ok := <-s.ch:
-		if !ok {
-			return nil, errors.New("gRPC source closed")
-		}
+	case <-s.ep.done:
+		return nil, errors.New("gRPC source closed")
+	case msg := <-s.ep.ch:
 		return msg, nil
 	}
 }
 
-func (s *GrpcSource) Ack(ctx context.Context, msg hermod.Message) error { return nil }
-func (s *GrpcSource) Ping(ctx context.Context) error                    { return nil }
+// Ack acknowledges a streamed message to its publisher when the stream asked
+// for acknowledgements after sink commit.
+func (s *GrpcSource) Ack(ctx context.Context, msg hermod.Message) error {
+	settleStreamed(msg, proto.AckMode_ACK_MODE_COMMITTED, "")
+	return nil
+}
+
+// Nack tells the publisher of a streamed message that it was not delivered,
+// when the stream asked for acknowledgements after sink commit.
+func (s *GrpcSource) Nack(ctx context.Context, msg hermod.Message, err error) {
+	settleStreamed(msg, proto.AckMode_ACK_MODE_COMMITTED, err.Error())
+}
+
+// Buffered acknowledges a streamed message to its publisher when the stream
+// asked for acknowledgements once the workflow has buffered it.
+func (s *GrpcSource) Buffered(ctx context.Context, msg hermod.Message) {
+	settleStreamed(msg, proto.AckMode_ACK_MODE_BUFFERED, "")
+}
+
+func (s *GrpcSource) Ping(ctx context.Context) error { return nil }
+
+// Close unregisters the path and fails whatever its streams still have in
+// flight, so their publishers can send it again once the workflow is back.
 func (s *GrpcSource) Close() error {
 	Unregister(s.Path)
+	failPending(s.Path, "gRPC source closed before the message was delivered")
 	return nil
 }
 
@@ -97,6 +159,40 @@ func (s *GrpcSource) Close() error {
 type Server struct {
 	proto.UnimplementedSourceServiceServer
 	Storage storage.Storage
+	// StreamWindow caps the messages a stream may have unacknowledged, and so
+	// the credit it is granted. Zero means DefaultStreamWindow.
+	StreamWindow int
+}
+
+// authorize checks the x-api-key metadata against the api_key of the gRPC
+// source on path, when it has one.
+func (s *Server) authorize(ctx context.Context, path string) error {
+	if s.Storage == nil {
+		return nil
+	}
+	sources, _, err := s.Storage.ListSources(ctx, storage.CommonFilter{})
+	if err != nil {
+		return nil
+	}
+	var apiKey string
+	for _, src := range sources {
+		if src.Type == "grpc" && src.Config["path"] == path {
+			apiKey = src.Config["api_key"]
+			break
+		}
+	}
+	if apiKey == "" {
+		return nil
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return errors.New("missing metadata")
+	}
+	tokens := md.Get("x-api-key")
+	if len(tokens) == 0 || tokens[0] != apiKey {
+		return errors.New("invalid api key")
+	}
+	return nil
 }
 
 func (s *Server) Publish(ctx context.Context, req *proto.PublishRequest) (*proto.PublishResponse, error) {
@@ -105,29 +201,8 @@ func (s *Server) Publish(ctx context.Context, req *proto.PublishRequest) (*proto
 		path = "/grpc/default"
 	}
 
-	// Verify API Key if storage is available
-	if s.Storage != nil {
-		sources, _, err := s.Storage.ListSources(ctx, storage.CommonFilter{})
-		if err == nil {
-			var apiKey string
-			for _, src := range sources {
-				if src.Type == "grpc" && src.Config["path"] == path {
-					apiKey = src.Config["api_key"]
-					break
-				}
-			}
-
-			if apiKey != "" {
-				md, ok := metadata.FromIncomingContext(ctx)
-				if !ok {
-					return nil, errors.New("missing metadata")
-				}
-				tokens := md.Get("x-api-key")
-				if len(tokens) == 0 || tokens[0] != apiKey {
-					return nil, errors.New("invalid api key")
-				}
-			}
-		}
+	if err := s.authorize(ctx, path); err != nil {
+		return nil, err
 	}
 
 	msg := message.AcquireMessage()
@@ -21,6 +21,56 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// AckMode chooses when a streamed message is acknowledged.
+type AckMode int32
+
+const (
+	// Once the message is in the workflow's buffer (on disk when the workflow
+	// buffers to file).
+	AckMode_ACK_MODE_BUFFERED AckMode = 0
+	// Once every sink of the workflow has committed the message.
+	AckMode_ACK_MODE_COMMITTED AckMode = 1
+)
+
+// Enum value maps for AckMode.
+var (
+	AckMode_name = map[int32]string{
+		0: "ACK_MODE_BUFFERED",
+		1: "ACK_MODE_COMMITTED",
+	}
+	AckMode_value = map[string]int32{
+		"ACK_MODE_BUFFERED":  0,
+		"ACK_MODE_COMMITTED": 1,
+	}
+)
+
+func (x AckMode) Enum() *AckMode {
+	p := new(AckMode)
+	*p = x
+	return p
+}
+
+func (x AckMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AckMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_source_grpc_proto_source_proto_enumTypes[0].Descriptor()
+}
+
+func (AckMode) Type() protoreflect.EnumType {
+	return &file_pkg_source_grpc_proto_source_proto_enumTypes[0]
+}
+
+func (x AckMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AckMode.Descriptor instead.
+func (AckMode) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_source_grpc_proto_source_proto_rawDescGZIP(), []int{0}
+}
+
 type PublishRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
@@ -181,6 +231,564 @@ func (x *PublishResponse) GetStatus() string {
 	return ""
 }
 
+type PublishStreamRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Kind:
+	//
+	//	*PublishStreamRequest_Open
+	//	*PublishStreamRequest_Message
+	Kind          isPublishStreamRequest_Kind `protobuf_oneof:"kind"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishStreamRequest) Reset() {
+	*x = PublishStreamRequest{}
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishStreamRequest) ProtoMessage() {}
+
+func (x *PublishStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishStreamRequest.ProtoReflect.Descriptor instead.
+func (*PublishStreamRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_source_grpc_proto_source_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PublishStreamRequest) GetKind() isPublishStreamRequest_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return nil
+}
+
+func (x *PublishStreamRequest) GetOpen() *OpenStream {
+	if x != nil {
+		if x, ok := x.Kind.(*PublishStreamRequest_Open); ok {
+			return x.Open
+		}
+	}
+	return nil
+}
+
+func (x *PublishStreamRequest) GetMessage() *StreamMessage {
+	if x != nil {
+		if x, ok := x.Kind.(*PublishStreamRequest_Message); ok {
+			return x.Message
+		}
+	}
+	return nil
+}
+
+type isPublishStreamRequest_Kind interface {
+	isPublishStreamRequest_Kind()
+}
+
+type PublishStreamRequest_Open struct {
+	Open *OpenStream `protobuf:"bytes,1,opt,name=open,proto3,oneof"`
+}
+
+type PublishStreamRequest_Message struct {
+	Message *StreamMessage `protobuf:"bytes,2,opt,name=message,proto3,oneof"`
+}
+
+func (*PublishStreamRequest_Open) isPublishStreamRequest_Kind() {}
+
+func (*PublishStreamRequest_Message) isPublishStreamRequest_Kind() {}
+
+type OpenStream struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Path  string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// session_id resumes an earlier session. Outcomes settled while the client
+	// was away are delivered on resume. Empty starts a new session.
+	SessionId string  `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	AckMode   AckMode `protobuf:"varint,3,opt,name=ack_mode,json=ackMode,proto3,enum=hermod.source.grpc.v1.AckMode" json:"ack_mode,omitempty"`
+	// schema_subject turns on typed mode: every payload must decode as
+	// message_type (or the schema's first message) of this Protobuf schema from
+	// the schema registry, and its fields become the message's data.
+	SchemaSubject string `protobuf:"bytes,4,opt,name=schema_subject,json=schemaSubject,proto3" json:"schema_subject,omitempty"`
+	// schema_version pins a version of schema_subject; 0 means the latest.
+	SchemaVersion int32  `protobuf:"varint,5,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	MessageType   string `protobuf:"bytes,6,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OpenStream) Reset() {
+	*x = OpenStream{}
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpenStream) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpenStream) ProtoMessage() {}
+
+func (x *OpenStream) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpenStream.ProtoReflect.Descriptor instead.
+func (*OpenStream) Descriptor() ([]byte, []int) {
+	return file_pkg_source_grpc_proto_source_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *OpenStream) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *OpenStream) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *OpenStream) GetAckMode() AckMode {
+	if x != nil {
+		return x.AckMode
+	}
+	return AckMode_ACK_MODE_BUFFERED
+}
+
+func (x *OpenStream) GetSchemaSubject() string {
+	if x != nil {
+		return x.SchemaSubject
+	}
+	return ""
+}
+
+func (x *OpenStream) GetSchemaVersion() int32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *OpenStream) GetMessageType() string {
+	if x != nil {
+		return x.MessageType
+	}
+	return ""
+}
+
+type StreamMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// seq is assigned by the client and must increase within a session. A seq
+	// the session has already seen is a retransmission and is not published
+	// again.
+	Seq           uint64            `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Id            string            `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Operation     string            `protobuf:"bytes,3,opt,name=operation,proto3" json:"operation,omitempty"`
+	Table         string            `protobuf:"bytes,4,opt,name=table,proto3" json:"table,omitempty"`
+	Schema        string            `protobuf:"bytes,5,opt,name=schema,proto3" json:"schema,omitempty"`
+	Before        []byte            `protobuf:"bytes,6,opt,name=before,proto3" json:"before,omitempty"`
+	After         []byte            `protobuf:"bytes,7,opt,name=after,proto3" json:"after,omitempty"`
+	Payload       []byte            `protobuf:"bytes,8,opt,name=payload,proto3" json:"payload,omitempty"`
+	Metadata      map[string]string `protobuf:"bytes,9,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamMessage) Reset() {
+	*x = StreamMessage{}
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMessage) ProtoMessage() {}
+
+func (x *StreamMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMessage.ProtoReflect.Descriptor instead.
+func (*StreamMessage) Descriptor() ([]byte, []int) {
+	return file_pkg_source_grpc_proto_source_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamMessage) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *StreamMessage) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *StreamMessage) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *StreamMessage) GetTable() string {
+	if x != nil {
+		return x.Table
+	}
+	return ""
+}
+
+func (x *StreamMessage) GetSchema() string {
+	if x != nil {
+		return x.Schema
+	}
+	return ""
+}
+
+func (x *StreamMessage) GetBefore() []byte {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+func (x *StreamMessage) GetAfter() []byte {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+func (x *StreamMessage) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *StreamMessage) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type PublishStreamResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Kind:
+	//
+	//	*PublishStreamResponse_Opened
+	//	*PublishStreamResponse_Ack
+	//	*PublishStreamResponse_Credit
+	Kind          isPublishStreamResponse_Kind `protobuf_oneof:"kind"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishStreamResponse) Reset() {
+	*x = PublishStreamResponse{}
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishStreamResponse) ProtoMessage() {}
+
+func (x *PublishStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishStreamResponse.ProtoReflect.Descriptor instead.
+func (*PublishStreamResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_source_grpc_proto_source_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PublishStreamResponse) GetKind() isPublishStreamResponse_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return nil
+}
+
+func (x *PublishStreamResponse) GetOpened() *StreamOpened {
+	if x != nil {
+		if x, ok := x.Kind.(*PublishStreamResponse_Opened); ok {
+			return x.Opened
+		}
+	}
+	return nil
+}
+
+func (x *PublishStreamResponse) GetAck() *StreamAck {
+	if x != nil {
+		if x, ok := x.Kind.(*PublishStreamResponse_Ack); ok {
+			return x.Ack
+		}
+	}
+	return nil
+}
+
+func (x *PublishStreamResponse) GetCredit() *StreamCredit {
+	if x != nil {
+		if x, ok := x.Kind.(*PublishStreamResponse_Credit); ok {
+			return x.Credit
+		}
+	}
+	return nil
+}
+
+type isPublishStreamResponse_Kind interface {
+	isPublishStreamResponse_Kind()
+}
+
+type PublishStreamResponse_Opened struct {
+	Opened *StreamOpened `protobuf:"bytes,1,opt,name=opened,proto3,oneof"`
+}
+
+type PublishStreamResponse_Ack struct {
+	Ack *StreamAck `protobuf:"bytes,2,opt,name=ack,proto3,oneof"`
+}
+
+type PublishStreamResponse_Credit struct {
+	Credit *StreamCredit `protobuf:"bytes,3,opt,name=credit,proto3,oneof"`
+}
+
+func (*PublishStreamResponse_Opened) isPublishStreamResponse_Kind() {}
+
+func (*PublishStreamResponse_Ack) isPublishStreamResponse_Kind() {}
+
+func (*PublishStreamResponse_Credit) isPublishStreamResponse_Kind() {}
+
+type StreamOpened struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// last_seq is the highest seq the session has received. A resuming client
+	// resends everything after it.
+	LastSeq uint64 `protobuf:"varint,2,opt,name=last_seq,json=lastSeq,proto3" json:"last_seq,omitempty"`
+	// credit is how many messages the client may send before waiting for more.
+	Credit        uint32 `protobuf:"varint,3,opt,name=credit,proto3" json:"credit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamOpened) Reset() {
+	*x = StreamOpened{}
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamOpened) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamOpened) ProtoMessage() {}
+
+func (x *StreamOpened) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamOpened.ProtoReflect.Descriptor instead.
+func (*StreamOpened) Descriptor() ([]byte, []int) {
+	return file_pkg_source_grpc_proto_source_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StreamOpened) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *StreamOpened) GetLastSeq() uint64 {
+	if x != nil {
+		return x.LastSeq
+	}
+	return 0
+}
+
+func (x *StreamOpened) GetCredit() uint32 {
+	if x != nil {
+		return x.Credit
+	}
+	return 0
+}
+
+type StreamAck struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Seq   uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Id    string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// ok is false when the message was rejected; error says why. A rejected
+	// message may be sent again under a new seq.
+	Ok            bool   `protobuf:"varint,3,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamAck) Reset() {
+	*x = StreamAck{}
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamAck) ProtoMessage() {}
+
+func (x *StreamAck) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamAck.ProtoReflect.Descriptor instead.
+func (*StreamAck) Descriptor() ([]byte, []int) {
+	return file_pkg_source_grpc_proto_source_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StreamAck) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *StreamAck) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *StreamAck) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *StreamAck) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type StreamCredit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Credit        uint32                 `protobuf:"varint,1,opt,name=credit,proto3" json:"credit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamCredit) Reset() {
+	*x = StreamCredit{}
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamCredit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamCredit) ProtoMessage() {}
+
+func (x *StreamCredit) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_source_grpc_proto_source_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamCredit.ProtoReflect.Descriptor instead.
+func (*StreamCredit) Descriptor() ([]byte, []int) {
+	return file_pkg_source_grpc_proto_source_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StreamCredit) GetCredit() uint32 {
+	if x != nil {
+		return x.Credit
+	}
+	return 0
+}
+
 var File_pkg_source_grpc_proto_source_proto protoreflect.FileDescriptor
 
 const file_pkg_source_grpc_proto_source_proto_rawDesc = "" +
@@ -201,9 +809,56 @@ const file_pkg_source_grpc_proto_source_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"9\n" +
 	"\x0fPublishResponse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
-	"\x06status\x18\x02 \x01(\tR\x06status2i\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\"\x99\x01\n" +
+	"\x14PublishStreamRequest\x127\n" +
+	"\x04open\x18\x01 \x01(\v2!.hermod.source.grpc.v1.OpenStreamH\x00R\x04open\x12@\n" +
+	"\amessage\x18\x02 \x01(\v2$.hermod.source.grpc.v1.StreamMessageH\x00R\amessageB\x06\n" +
+	"\x04kind\"\xeb\x01\n" +
+	"\n" +
+	"OpenStream\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x02 \x01(\tR\tsessionId\x129\n" +
+	"\back_mode\x18\x03 \x01(\x0e2\x1e.hermod.source.grpc.v1.AckModeR\aackMode\x12%\n" +
+	"\x0eschema_subject\x18\x04 \x01(\tR\rschemaSubject\x12%\n" +
+	"\x0eschema_version\x18\x05 \x01(\x05R\rschemaVersion\x12!\n" +
+	"\fmessage_type\x18\x06 \x01(\tR\vmessageType\"\xd2\x02\n" +
+	"\rStreamMessage\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x1c\n" +
+	"\toperation\x18\x03 \x01(\tR\toperation\x12\x14\n" +
+	"\x05table\x18\x04 \x01(\tR\x05table\x12\x16\n" +
+	"\x06schema\x18\x05 \x01(\tR\x06schema\x12\x16\n" +
+	"\x06before\x18\x06 \x01(\fR\x06before\x12\x14\n" +
+	"\x05after\x18\a \x01(\fR\x05after\x12\x18\n" +
+	"\apayload\x18\b \x01(\fR\apayload\x12N\n" +
+	"\bmetadata\x18\t \x03(\v22.hermod.source.grpc.v1.StreamMessage.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd3\x01\n" +
+	"\x15PublishStreamResponse\x12=\n" +
+	"\x06opened\x18\x01 \x01(\v2#.hermod.source.grpc.v1.StreamOpenedH\x00R\x06opened\x124\n" +
+	"\x03ack\x18\x02 \x01(\v2 .hermod.source.grpc.v1.StreamAckH\x00R\x03ack\x12=\n" +
+	"\x06credit\x18\x03 \x01(\v2#.hermod.source.grpc.v1.StreamCreditH\x00R\x06creditB\x06\n" +
+	"\x04kind\"`\n" +
+	"\fStreamOpened\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x19\n" +
+	"\blast_seq\x18\x02 \x01(\x04R\alastSeq\x12\x16\n" +
+	"\x06credit\x18\x03 \x01(\rR\x06credit\"S\n" +
+	"\tStreamAck\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x0e\n" +
+	"\x02ok\x18\x03 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"&\n" +
+	"\fStreamCredit\x12\x16\n" +
+	"\x06credit\x18\x01 \x01(\rR\x06credit*8\n" +
+	"\aAckMode\x12\x15\n" +
+	"\x11ACK_MODE_BUFFERED\x10\x00\x12\x16\n" +
+	"\x12ACK_MODE_COMMITTED\x10\x012\xd9\x01\n" +
 	"\rSourceService\x12X\n" +
-	"\aPublish\x12%.hermod.source.grpc.v1.PublishRequest\x1a&.hermod.source.grpc.v1.PublishResponseB3Z1github.com/user/hermod/pkg/comm/source/grpc/protob\x06proto3"
+	"\aPublish\x12%.hermod.source.grpc.v1.PublishRequest\x1a&.hermod.source.grpc.v1.PublishResponse\x12n\n" +
+	"\rPublishStream\x12+.hermod.source.grpc.v1.PublishStreamRequest\x1a,.hermod.source.grpc.v1.PublishStreamResponse(\x010\x01B3Z1github.com/user/hermod/pkg/comm/source/grpc/protob\x06proto3"
 
 var (
 	file_pkg_source_grpc_proto_source_proto_rawDescOnce sync.Once
@@ -217,21 +872,40 @@ func file_pkg_source_grpc_proto_source_proto_rawDescGZIP() []byte {
 	return file_pkg_source_grpc_proto_source_proto_rawDescData
 }
 
-var file_pkg_source_grpc_proto_source_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_pkg_source_grpc_proto_source_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_pkg_source_grpc_proto_source_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_pkg_source_grpc_proto_source_proto_goTypes = []any{
-	(*PublishRequest)(nil),  // 0: hermod.source.grpc.v1.PublishRequest
-	(*PublishResponse)(nil), // 1: hermod.source.grpc.v1.PublishResponse
-	nil,                     // 2: hermod.source.grpc.v1.PublishRequest.MetadataEntry
+	(AckMode)(0),                  // 0: hermod.source.grpc.v1.AckMode
+	(*PublishRequest)(nil),        // 1: hermod.source.grpc.v1.PublishRequest
+	(*PublishResponse)(nil),       // 2: hermod.source.grpc.v1.PublishResponse
+	(*PublishStreamRequest)(nil),  // 3: hermod.source.grpc.v1.PublishStreamRequest
+	(*OpenStream)(nil),            // 4: hermod.source.grpc.v1.OpenStream
+	(*StreamMessage)(nil),         // 5: hermod.source.grpc.v1.StreamMessage
+	(*PublishStreamResponse)(nil), // 6: hermod.source.grpc.v1.PublishStreamResponse
+	(*StreamOpened)(nil),          // 7: hermod.source.grpc.v1.StreamOpened
+	(*StreamAck)(nil),             // 8: hermod.source.grpc.v1.StreamAck
+	(*StreamCredit)(nil),          // 9: hermod.source.grpc.v1.StreamCredit
+	nil,                           // 10: hermod.source.grpc.v1.PublishRequest.MetadataEntry
+	nil,                           // 11: hermod.source.grpc.v1.StreamMessage.MetadataEntry
 }
 var file_pkg_source_grpc_proto_source_proto_depIdxs = []int32{
-	2, // 0: hermod.source.grpc.v1.PublishRequest.metadata:type_name -> hermod.source.grpc.v1.PublishRequest.MetadataEntry
-	0, // 1: hermod.source.grpc.v1.SourceService.Publish:input_type -> hermod.source.grpc.v1.PublishRequest
-	1, // 2: hermod.source.grpc.v1.SourceService.Publish:output_type -> hermod.source.grpc.v1.PublishResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	10, // 0: hermod.source.grpc.v1.PublishRequest.metadata:type_name -> hermod.source.grpc.v1.PublishRequest.MetadataEntry
+	4,  // 1: hermod.source.grpc.v1.PublishStreamRequest.open:type_name -> hermod.source.grpc.v1.OpenStream
+	5,  // 2: hermod.source.grpc.v1.PublishStreamRequest.message:type_name -> hermod.source.grpc.v1.StreamMessage
+	0,  // 3: hermod.source.grpc.v1.OpenStream.ack_mode:type_name -> hermod.source.grpc.v1.AckMode
+	11, // 4: hermod.source.grpc.v1.StreamMessage.metadata:type_name -> hermod.source.grpc.v1.StreamMessage.MetadataEntry
+	7,  // 5: hermod.source.grpc.v1.PublishStreamResponse.opened:type_name -> hermod.source.grpc.v1.StreamOpened
+	8,  // 6: hermod.source.grpc.v1.PublishStreamResponse.ack:type_name -> hermod.source.grpc.v1.StreamAck
+	9,  // 7: hermod.source.grpc.v1.PublishStreamResponse.credit:type_name -> hermod.source.grpc.v1.StreamCredit
+	1,  // 8: hermod.source.grpc.v1.SourceService.Publish:input_type -> hermod.source.grpc.v1.PublishRequest
+	3,  // 9: hermod.source.grpc.v1.SourceService.PublishStream:input_type -> hermod.source.grpc.v1.PublishStreamRequest
+	2,  // 10: hermod.source.grpc.v1.SourceService.Publish:output_type -> hermod.source.grpc.v1.PublishResponse
+	6,  // 11: hermod.source.grpc.v1.SourceService.PublishStream:output_type -> hermod.source.grpc.v1.PublishStreamResponse
+	10, // [10:12] is the sub-list for method output_type
+	8,  // [8:10] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_pkg_source_grpc_proto_source_proto_init() }
@@ -239,18 +913,28 @@ func file_pkg_source_grpc_proto_source_proto_init() {
 	if File_pkg_source_grpc_proto_source_proto != nil {
 		return
 	}
+	file_pkg_source_grpc_proto_source_proto_msgTypes[2].OneofWrappers = []any{
+		(*PublishStreamRequest_Open)(nil),
+		(*PublishStreamRequest_Message)(nil),
+	}
+	file_pkg_source_grpc_proto_source_proto_msgTypes[5].OneofWrappers = []any{
+		(*PublishStreamResponse_Opened)(nil),
+		(*PublishStreamResponse_Ack)(nil),
+		(*PublishStreamResponse_Credit)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_source_grpc_proto_source_proto_rawDesc), len(file_pkg_source_grpc_proto_source_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   3,
+			NumEnums:      1,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_pkg_source_grpc_proto_source_proto_goTypes,
 		DependencyIndexes: file_pkg_source_grpc_proto_source_proto_depIdxs,
+		EnumInfos:         file_pkg_source_grpc_proto_source_proto_enumTypes,
 		MessageInfos:      file_pkg_source_grpc_proto_source_proto_msgTypes,
 	}.Build()
 	File_pkg_source_grpc_proto_source_proto = out.File
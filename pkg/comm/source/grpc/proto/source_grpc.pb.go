@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	SourceService_Publish_FullMethodName = "/hermod.source.grpc.v1.SourceService/Publish"
+	SourceService_Publish_FullMethodName       = "/hermod.source.grpc.v1.SourceService/Publish"
+	SourceService_PublishStream_FullMethodName = "/hermod.source.grpc.v1.SourceService/PublishStream"
 )
 
 // SourceServiceClient is the client API for SourceService service.
@@ -27,6 +28,11 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type SourceServiceClient interface {
 	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// PublishStream publishes many messages over one stream. The first request
+	// opens (or resumes) a session; every later one carries a message. Each
+	// message is acknowledged by its sequence number, and the server grants
+	// credit as the workflow takes messages in.
+	PublishStream(ctx context.Context, opts ...grpc.CallOption) (SourceService_PublishStreamClient, error)
 }
 
 type sourceServiceClient struct {
@@ -46,11 +52,47 @@ func (c *sourceServiceClient) Publish(ctx context.Context, in *PublishRequest, o
 	return out, nil
 }
 
+func (c *sourceServiceClient) PublishStream(ctx context.Context, opts ...grpc.CallOption) (SourceService_PublishStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SourceService_ServiceDesc.Streams[0], SourceService_PublishStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sourceServicePublishStreamClient{stream}
+	return x, nil
+}
+
+type SourceService_PublishStreamClient interface {
+	Send(*PublishStreamRequest) error
+	Recv() (*PublishStreamResponse, error)
+	grpc.ClientStream
+}
+
+type sourceServicePublishStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *sourceServicePublishStreamClient) Send(m *PublishStreamRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *sourceServicePublishStreamClient) Recv() (*PublishStreamResponse, error) {
+	m := new(PublishStreamResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // SourceServiceServer is the server API for SourceService service.
 // All implementations must embed UnimplementedSourceServiceServer
 // for forward compatibility
 type SourceServiceServer interface {
 	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	// PublishStream publishes many messages over one stream. The first request
+	// opens (or resumes) a session; every later one carries a message. Each
+	// message is acknowledged by its sequence number, and the server grants
+	// credit as the workflow takes messages in.
+	PublishStream(SourceService_PublishStreamServer) error
 	mustEmbedUnimplementedSourceServiceServer()
 }
 
@@ -61,6 +103,9 @@ type UnimplementedSourceServiceServer struct {
 func (UnimplementedSourceServiceServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Publish not implemented")
 }
+func (UnimplementedSourceServiceServer) PublishStream(SourceService_PublishStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method PublishStream not implemented")
+}
 func (UnimplementedSourceServiceServer) mustEmbedUnimplementedSourceServiceServer() {}
 
 // UnsafeSourceServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +137,32 @@ func _SourceService_Publish_Handler(srv any, ctx context.Context, dec func(any)
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SourceService_PublishStream_Handler(srv any, stream grpc.ServerStream) error {
+	return srv.(SourceServiceServer).PublishStream(&sourceServicePublishStreamServer{stream})
+}
+
+type SourceService_PublishStreamServer interface {
+	Send(*PublishStreamResponse) error
+	Recv() (*PublishStreamRequest, error)
+	grpc.ServerStream
+}
+
+type sourceServicePublishStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *sourceServicePublishStreamServer) Send(m *PublishStreamResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *sourceServicePublishStreamServer) Recv() (*PublishStreamRequest, error) {
+	m := new(PublishStreamRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // SourceService_ServiceDesc is the grpc.ServiceDesc for SourceService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -104,6 +175,13 @@ var SourceService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _SourceService_Publish_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PublishStream",
+			Handler:       _SourceService_PublishStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "pkg/source/grpc/proto/source.proto",
 }
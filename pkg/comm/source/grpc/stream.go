@@ -0,0 +1,477 @@
+package grpcsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
+	"github.com/user/hermod/pkg/comm/source/grpc/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultStreamWindow is how many messages a stream may have unacknowledged
+// unless Server.StreamWindow says otherwise.
+const DefaultStreamWindow = 256
+
+// sessionTTL is how long a session outlives its stream, waiting to be resumed.
+const sessionTTL = 5 * time.Minute
+
+// Metadata that ties a streamed message back to its session so the source can
+// acknowledge it.
+const (
+	metaSession = "_grpc_session"
+	metaSeq     = "_grpc_seq"
+)
+
+var (
+	sessions   = make(map[string]*session)
+	sessionsMu sync.Mutex
+)
+
+// session is the server side of a PublishStream session. It outlives any one
+// stream so a client that reconnects can resume where it left off, and
+// collects the outcomes settled while no stream was attached.
+type session struct {
+	id    string
+	path  string
+	mode  proto.AckMode
+	typed *desc.MessageDescriptor
+
+	mu      sync.Mutex
+	lastSeq uint64
+	// pending maps the seq of every dispatched, unsettled message to its ID,
+	// and failed the seq of every message that was rejected or settled with
+	// an error to the error, until the client retransmits it.
+	pending map[uint64]string
+	failed  map[uint64]string
+	// outbox holds responses not yet sent, and credit the credit not yet
+	// granted.
+	outbox []*proto.PublishStreamResponse
+	credit uint32
+	// wake is signalled when outbox or credit grows, and detach closed when
+	// another stream takes the session over. Both are nil while detached.
+	wake      chan struct{}
+	detach    chan struct{}
+	idleSince time.Time
+}
+
+func lookupSession(id string) *session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	return sessions[id]
+}
+
+// openSession resumes the session named in open, or starts a new one.
+func (s *Server) openSession(ctx context.Context, path string, open *proto.OpenStream) (*session, error) {
+	sessionsMu.Lock()
+	now := time.Now()
+	for id, sess := range sessions {
+		sess.mu.Lock()
+		expired := sess.detach == nil && now.Sub(sess.idleSince) > sessionTTL
+		sess.mu.Unlock()
+		if expired {
+			delete(sessions, id)
+		}
+	}
+	if open.SessionId != "" {
+		sess, ok := sessions[open.SessionId]
+		sessionsMu.Unlock()
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "session %s not found or expired", open.SessionId)
+		}
+		if sess.path != path {
+			return nil, status.Errorf(codes.InvalidArgument, "session %s belongs to path %s", sess.id, sess.path)
+		}
+		return sess, nil
+	}
+	sessionsMu.Unlock()
+
+	sess := &session{
+		id:        uuid.NewString(),
+		path:      path,
+		mode:      open.AckMode,
+		pending:   make(map[uint64]string),
+		failed:    make(map[uint64]string),
+		idleSince: now,
+	}
+	if open.SchemaSubject != "" {
+		d, err := s.loadMessageType(ctx, open.SchemaSubject, int(open.SchemaVersion), open.MessageType)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		sess.typed = d
+	}
+
+	sessionsMu.Lock()
+	sessions[sess.id] = sess
+	sessionsMu.Unlock()
+	return sess, nil
+}
+
+// loadMessageType finds the message type typed mode validates against in a
+// Protobuf schema from the schema registry.
+func (s *Server) loadMessageType(ctx context.Context, subject string, version int, messageType string) (*desc.MessageDescriptor, error) {
+	if s.Storage == nil {
+		return nil, errors.New("typed mode needs the schema registry, which is unavailable")
+	}
+	sc, err := s.Storage.GetLatestSchema(ctx, subject)
+	if version > 0 {
+		sc, err = s.Storage.GetSchema(ctx, subject, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", subject, err)
+	}
+	if sc.Type != "protobuf" {
+		return nil, fmt.Errorf("schema %s is %s, not protobuf", subject, sc.Type)
+	}
+
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"schema.proto": sc.Content}),
+	}
+	fds, err := parser.ParseFiles("schema.proto")
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", subject, err)
+	}
+	if messageType == "" {
+		if msgs := fds[0].GetMessageTypes(); len(msgs) > 0 {
+			return msgs[0], nil
+		}
+		return nil, fmt.Errorf("schema %s declares no message types", subject)
+	}
+	d := fds[0].FindMessage(messageType)
+	if d == nil {
+		// Allow the name without the schema's package.
+		d = fds[0].FindMessage(fds[0].GetPackage() + "." + messageType)
+	}
+	if d == nil {
+		return nil, fmt.Errorf("message %s not found in schema %s", messageType, subject)
+	}
+	return d, nil
+}
+
+// attach makes a new stream the session's. A stream still attached is told to
+// let go. Credit restarts from the window, less what is still in flight.
+func (sess *session) attach(window int) (detach, wake chan struct{}, opened *proto.PublishStreamResponse) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.detach != nil {
+		close(sess.detach)
+	}
+	sess.detach = make(chan struct{})
+	sess.wake = make(chan struct{}, 1)
+	sess.credit = 0
+	// Anything left over is delivered as soon as the stream is up.
+	if len(sess.outbox) > 0 {
+		sess.wake <- struct{}{}
+	}
+	credit := max(window-len(sess.pending), 0)
+	return sess.detach, sess.wake, &proto.PublishStreamResponse{Kind: &proto.PublishStreamResponse_Opened{
+		Opened: &proto.StreamOpened{SessionId: sess.id, LastSeq: sess.lastSeq, Credit: uint32(credit)},
+	}}
+}
+
+// release detaches the stream that attached with detach, unless another has
+// taken over since.
+func (sess *session) release(detach chan struct{}) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.detach != detach {
+		return
+	}
+	close(sess.detach)
+	sess.detach, sess.wake = nil, nil
+	sess.idleSince = time.Now()
+}
+
+// reply queues an acknowledgement and returns the credit the message used.
+func (sess *session) reply(ack *proto.StreamAck) {
+	sess.outbox = append(sess.outbox, &proto.PublishStreamResponse{Kind: &proto.PublishStreamResponse_Ack{Ack: ack}})
+	sess.credit++
+	if sess.wake != nil {
+		select {
+		case sess.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// settle acknowledges a dispatched message, as failed when errMsg is set.
+func (sess *session) settle(seq uint64, errMsg string) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	id, ok := sess.pending[seq]
+	if !ok {
+		return
+	}
+	delete(sess.pending, seq)
+	if errMsg != "" {
+		sess.failed[seq] = errMsg
+	}
+	sess.reply(&proto.StreamAck{Seq: seq, Id: id, Ok: errMsg == "", Error: errMsg})
+}
+
+// failPending settles every message still in flight on the sessions of path
+// as failed, for when its workflow stops: they will not be delivered.
+func failPending(path, errMsg string) {
+	sessionsMu.Lock()
+	var open []*session
+	for _, sess := range sessions {
+		if sess.path == path {
+			open = append(open, sess)
+		}
+	}
+	sessionsMu.Unlock()
+	for _, sess := range open {
+		sess.mu.Lock()
+		seqs := make([]uint64, 0, len(sess.pending))
+		for seq := range sess.pending {
+			seqs = append(seqs, seq)
+		}
+		sess.mu.Unlock()
+		slices.Sort(seqs)
+		for _, seq := range seqs {
+			sess.settle(seq, errMsg)
+		}
+	}
+}
+
+// reject fails a message without publishing it. Its seq is recorded as
+// failed, so a retransmission after later messages is published rather than
+// taken for one already delivered.
+func (sess *session) reject(seq uint64, id, errMsg string) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.failed[seq] = errMsg
+	sess.lastSeq = max(sess.lastSeq, seq)
+	sess.reply(&proto.StreamAck{Seq: seq, Id: id, Error: errMsg})
+}
+
+// take empties the outbox and the credit owed, for sending.
+func (sess *session) take() ([]*proto.PublishStreamResponse, bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	out := sess.outbox
+	sess.outbox = nil
+	if sess.credit > 0 {
+		out = append(out, &proto.PublishStreamResponse{Kind: &proto.PublishStreamResponse_Credit{
+			Credit: &proto.StreamCredit{Credit: sess.credit},
+		}})
+		sess.credit = 0
+	}
+	return out, len(sess.pending) == 0
+}
+
+// requeue puts back acknowledgements a broken stream failed to send, so a
+// resumed stream delivers them. Credit is not put back: resuming recomputes it.
+func (sess *session) requeue(out []*proto.PublishStreamResponse) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	var acks []*proto.PublishStreamResponse
+	for _, r := range out {
+		if r.GetAck() != nil {
+			acks = append(acks, r)
+		}
+	}
+	sess.outbox = append(acks, sess.outbox...)
+}
+
+// settleStreamed acknowledges msg to its stream, as failed when errMsg is
+// set, if it was published over one that asked for acknowledgement at this
+// point.
+func settleStreamed(msg hermod.Message, mode proto.AckMode, errMsg string) {
+	md := msg.Metadata()
+	id := md[metaSession]
+	if id == "" {
+		return
+	}
+	seq, err := strconv.ParseUint(md[metaSeq], 10, 64)
+	if err != nil {
+		return
+	}
+	if sess := lookupSession(id); sess != nil && sess.mode == mode {
+		sess.settle(seq, errMsg)
+	}
+}
+
+// PublishStream implements the streaming publish RPC. The first request opens
+// or resumes a session; each later one publishes a message, answered with an
+// acknowledgement by seq once the session's ack mode is satisfied. Each
+// acknowledgement returns the credit its message used. When the client closes
+// its side, the stream ends once everything in flight has been acknowledged.
+func (s *Server) PublishStream(stream proto.SourceService_PublishStreamServer) error {
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	open := first.GetOpen()
+	if open == nil {
+		return status.Error(codes.InvalidArgument, "the first request must open the stream")
+	}
+	path := open.Path
+	if path == "" {
+		path = "/grpc/default"
+	}
+	if err := s.authorize(ctx, path); err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	sess, err := s.openSession(ctx, path, open)
+	if err != nil {
+		return err
+	}
+	window := s.StreamWindow
+	if window <= 0 {
+		window = DefaultStreamWindow
+	}
+	detach, wake, opened := sess.attach(window)
+	defer sess.release(detach)
+	if err := stream.Send(opened); err != nil {
+		return err
+	}
+
+	recvDone := make(chan error, 1)
+	go func() { recvDone <- s.receive(ctx, stream, sess, window) }()
+	halfClosed := make(chan struct{})
+	sendDone := make(chan error, 1)
+	go func() { sendDone <- sess.send(ctx, stream, wake, halfClosed) }()
+
+	for {
+		select {
+		case err := <-recvDone:
+			if err != nil {
+				return err
+			}
+			close(halfClosed)
+			recvDone = nil
+		case err := <-sendDone:
+			return err
+		case <-detach:
+			return status.Error(codes.Aborted, "session resumed on another stream")
+		}
+	}
+}
+
+// receive publishes the client's messages until it closes its side.
+func (s *Server) receive(ctx context.Context, stream proto.SourceService_PublishStreamServer, sess *session, window int) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		m := req.GetMessage()
+		if m == nil {
+			return status.Error(codes.InvalidArgument, "the stream is already open")
+		}
+		s.publishStreamed(ctx, sess, m, window)
+	}
+}
+
+// send delivers acknowledgements and credit as they arise. After the client
+// has half-closed, it returns once nothing is left in flight.
+func (sess *session) send(ctx context.Context, stream proto.SourceService_PublishStreamServer, wake, halfClosed <-chan struct{}) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		case <-halfClosed:
+			halfClosed = nil
+		}
+		out, idle := sess.take()
+		for i, r := range out {
+			if err := stream.Send(r); err != nil {
+				sess.requeue(out[i:])
+				return err
+			}
+		}
+		if halfClosed == nil && idle {
+			return nil
+		}
+	}
+}
+
+func (s *Server) publishStreamed(ctx context.Context, sess *session, m *proto.StreamMessage, window int) {
+	id := m.Id
+	if id == "" {
+		// Derived from the session, so a retransmission keeps its ID.
+		id = sess.id + "-" + strconv.FormatUint(m.Seq, 10)
+	}
+
+	sess.mu.Lock()
+	_, retry := sess.failed[m.Seq]
+	if m.Seq <= sess.lastSeq && !retry {
+		// A retransmission. One still in flight is acknowledged when it
+		// settles; one already delivered is acknowledged again.
+		_, inFlight := sess.pending[m.Seq]
+		if !inFlight {
+			sess.reply(&proto.StreamAck{Seq: m.Seq, Id: id, Ok: true})
+		}
+		sess.mu.Unlock()
+		return
+	}
+	// A retransmission of a message that failed publishes it again.
+	full := len(sess.pending) >= window
+	sess.mu.Unlock()
+	if full {
+		sess.reject(m.Seq, id, "no credit: wait for acknowledgements before sending more")
+		return
+	}
+
+	msg := message.AcquireMessage()
+	msg.SetID(id)
+	msg.SetOperation(hermod.Operation(m.Operation))
+	msg.SetTable(m.Table)
+	msg.SetSchema(m.Schema)
+	msg.SetBefore(m.Before)
+	msg.SetAfter(m.After)
+	msg.SetPayload(m.Payload)
+	for k, v := range m.Metadata {
+		msg.SetMetadata(k, v)
+	}
+	if sess.typed != nil {
+		dyn := dynamic.NewMessage(sess.typed)
+		if err := dyn.Unmarshal(m.Payload); err != nil {
+			message.ReleaseMessage(msg)
+			sess.reject(m.Seq, id, fmt.Sprintf("payload is not a valid %s: %v", sess.typed.GetFullyQualifiedName(), err))
+			return
+		}
+		for k, v := range dynamicToMap(dyn) {
+			msg.SetData(k, v)
+		}
+	}
+	msg.SetMetadata(metaSession, sess.id)
+	msg.SetMetadata(metaSeq, strconv.FormatUint(m.Seq, 10))
+
+	// Recorded before dispatch: the workflow may settle it straight away.
+	sess.mu.Lock()
+	delete(sess.failed, m.Seq)
+	sess.lastSeq = max(sess.lastSeq, m.Seq)
+	sess.pending[m.Seq] = id
+	sess.mu.Unlock()
+
+	if err := dispatchWait(ctx, sess.path, msg); err != nil {
+		message.ReleaseMessage(msg)
+		sess.mu.Lock()
+		delete(sess.pending, m.Seq)
+		// Never published, so the same seq may be sent again.
+		sess.failed[m.Seq] = err.Error()
+		sess.reply(&proto.StreamAck{Seq: m.Seq, Id: id, Error: err.Error()})
+		sess.mu.Unlock()
+	}
+}
@@ -0,0 +1,386 @@
+package grpcsource
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/user/hermod/internal/storage"
+	"github.com/user/hermod/pkg/comm/source/grpc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const orderSchema = `syntax = "proto3";
+package shop;
+message Order {
+  string sku = 1;
+  int64 quantity = 2;
+}`
+
+func (m *mockStorage) GetLatestSchema(ctx context.Context, name string) (storage.Schema, error) {
+	if name != "orders" {
+		return storage.Schema{}, storage.ErrNotFound
+	}
+	return storage.Schema{Name: name, Version: 1, Type: "protobuf", Content: orderSchema}, nil
+}
+
+func startStreamServer(t *testing.T, srv *Server) proto.SourceServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	proto.RegisterSourceServiceServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return proto.NewSourceServiceClient(conn)
+}
+
+func openStream(t *testing.T, ctx context.Context, c proto.SourceServiceClient, open *proto.OpenStream) (proto.SourceService_PublishStreamClient, *proto.StreamOpened) {
+	t.Helper()
+	stream, err := c.PublishStream(ctx)
+	if err != nil {
+		t.Fatalf("PublishStream: %v", err)
+	}
+	if err := stream.Send(&proto.PublishStreamRequest{Kind: &proto.PublishStreamRequest_Open{Open: open}}); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil || resp.GetOpened() == nil {
+		t.Fatalf("expected opened, got %v, %v", resp, err)
+	}
+	return stream, resp.GetOpened()
+}
+
+func sendMsg(t *testing.T, stream proto.SourceService_PublishStreamClient, m *proto.StreamMessage) {
+	t.Helper()
+	if err := stream.Send(&proto.PublishStreamRequest{Kind: &proto.PublishStreamRequest_Message{Message: m}}); err != nil {
+		t.Fatalf("send seq %d: %v", m.Seq, err)
+	}
+}
+
+// nextAck skips credit grants and returns the next acknowledgement.
+func nextAck(t *testing.T, stream proto.SourceService_PublishStreamClient) *proto.StreamAck {
+	t.Helper()
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if ack := resp.GetAck(); ack != nil {
+			return ack
+		}
+	}
+}
+
+// readOne plays the engine: it reads a message from the source and reports it
+// buffered.
+func readOne(t *testing.T, src *GrpcSource) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	msg, err := src.Read(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	src.Buffered(ctx, msg)
+	return msg.ID()
+}
+
+func TestPublishStreamAcksOnceBuffered(t *testing.T) {
+	src := NewGrpcSource("/stream/buffered")
+	defer src.Close()
+	client := startStreamServer(t, &Server{StreamWindow: 4})
+
+	stream, opened := openStream(t, t.Context(), client, &proto.OpenStream{Path: "/stream/buffered"})
+	if opened.Credit != 4 || opened.LastSeq != 0 {
+		t.Fatalf("unexpected opened: %+v", opened)
+	}
+
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 1, Id: "a", Payload: []byte("1")})
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 2, Payload: []byte("2")})
+
+	if id := readOne(t, src); id != "a" {
+		t.Fatalf("expected message a, got %s", id)
+	}
+	if ack := nextAck(t, stream); ack.Seq != 1 || !ack.Ok || ack.Id != "a" {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+	readOne(t, src)
+	if ack := nextAck(t, stream); ack.Seq != 2 || !ack.Ok || ack.Id != opened.SessionId+"-2" {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+
+	// Half-closing ends the stream once nothing is in flight.
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+}
+
+func TestPublishStreamCommittedModeWaitsForSinks(t *testing.T) {
+	src := NewGrpcSource("/stream/committed")
+	defer src.Close()
+	client := startStreamServer(t, &Server{})
+
+	stream, _ := openStream(t, t.Context(), client, &proto.OpenStream{Path: "/stream/committed", AckMode: proto.AckMode_ACK_MODE_COMMITTED})
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 1, Payload: []byte("x")})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	msg, err := src.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Buffered(ctx, msg)
+
+	acked := make(chan *proto.StreamAck, 1)
+	go func() {
+		resp, err := stream.Recv()
+		for err == nil && resp.GetAck() == nil {
+			resp, err = stream.Recv()
+		}
+		acked <- resp.GetAck()
+	}()
+	select {
+	case ack := <-acked:
+		t.Fatalf("acknowledged before the sinks committed: %+v", ack)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	_ = src.Ack(ctx, msg)
+	select {
+	case ack := <-acked:
+		if ack.Seq != 1 || !ack.Ok {
+			t.Fatalf("unexpected ack: %+v", ack)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("not acknowledged after commit")
+	}
+}
+
+func TestPublishStreamCommittedModeReportsFailures(t *testing.T) {
+	src := NewGrpcSource("/stream/failing")
+	client := startStreamServer(t, &Server{StreamWindow: 2})
+
+	stream, _ := openStream(t, t.Context(), client, &proto.OpenStream{Path: "/stream/failing", AckMode: proto.AckMode_ACK_MODE_COMMITTED})
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 1, Payload: []byte("1")})
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	msg, err := src.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sink write fails: the publisher is told, and gets its credit back.
+	src.Nack(ctx, msg, errors.New("sink unavailable"))
+	if ack := nextAck(t, stream); ack.Seq != 1 || ack.Ok || ack.Error != "sink unavailable" {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+	// A retransmission of the failed message publishes it again.
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 1, Payload: []byte("1")})
+	if msg, err = src.Read(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_ = src.Ack(ctx, msg)
+	if ack := nextAck(t, stream); ack.Seq != 1 || !ack.Ok {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+
+	// Messages in flight when the workflow stops are failed, and a
+	// half-closed stream ends instead of waiting for them.
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 2, Payload: []byte("2")})
+	if _, err := src.Read(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_ = src.Close()
+	if ack := nextAck(t, stream); ack.Seq != 2 || ack.Ok {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("stream ended with %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestPublishStreamResumesSession(t *testing.T) {
+	src := NewGrpcSource("/stream/resume")
+	defer src.Close()
+	client := startStreamServer(t, &Server{StreamWindow: 8})
+
+	ctx, drop := context.WithCancel(t.Context())
+	stream, opened := openStream(t, ctx, client, &proto.OpenStream{Path: "/stream/resume"})
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 1, Payload: []byte("1")})
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 2, Payload: []byte("2")})
+	readOne(t, src)
+	nextAck(t, stream)
+
+	// The connection drops with seq 2 received but not yet buffered.
+	drop()
+	sess := lookupSession(opened.SessionId)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sess.mu.Lock()
+		detached := sess.detach == nil
+		sess.mu.Unlock()
+		if detached {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session never detached")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	readOne(t, src)
+
+	stream, resumed := openStream(t, t.Context(), client, &proto.OpenStream{Path: "/stream/resume", SessionId: opened.SessionId})
+	if resumed.SessionId != opened.SessionId || resumed.LastSeq != 2 || resumed.Credit != 8 {
+		t.Fatalf("unexpected resume: %+v", resumed)
+	}
+	// Settled while detached, delivered on resume.
+	if ack := nextAck(t, stream); ack.Seq != 2 || !ack.Ok {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+	// Resending a settled seq does not publish it twice.
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 2, Payload: []byte("2")})
+	if ack := nextAck(t, stream); ack.Seq != 2 || !ack.Ok {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+	if n := len(src.ep.ch); n != 0 {
+		t.Fatalf("retransmission was published again: %d queued", n)
+	}
+}
+
+func TestPublishStreamRejectsWithoutCredit(t *testing.T) {
+	src := NewGrpcSource("/stream/credit")
+	defer src.Close()
+	client := startStreamServer(t, &Server{StreamWindow: 1})
+
+	stream, _ := openStream(t, t.Context(), client, &proto.OpenStream{Path: "/stream/credit"})
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 1, Payload: []byte("1")})
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 2, Payload: []byte("2")})
+	if ack := nextAck(t, stream); ack.Seq != 2 || ack.Ok {
+		t.Fatalf("expected seq 2 rejected for lack of credit, got %+v", ack)
+	}
+
+	// Once seq 1 is buffered, credit is granted and seq 2 can be sent again.
+	readOne(t, src)
+	if ack := nextAck(t, stream); ack.Seq != 1 || !ack.Ok {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 2, Payload: []byte("2")})
+	readOne(t, src)
+	if ack := nextAck(t, stream); ack.Seq != 2 || !ack.Ok {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+}
+
+// A rejected seq retransmitted after later ones were accepted is published,
+// not acknowledged as a message already delivered.
+func TestPublishStreamRetransmitsRejectedAfterLaterAccept(t *testing.T) {
+	src := NewGrpcSource("/stream/gap")
+	defer src.Close()
+	client := startStreamServer(t, &Server{StreamWindow: 1})
+
+	stream, opened := openStream(t, t.Context(), client, &proto.OpenStream{Path: "/stream/gap"})
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 1, Payload: []byte("1")})
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 2, Payload: []byte("2")})
+	if ack := nextAck(t, stream); ack.Seq != 2 || ack.Ok {
+		t.Fatalf("expected seq 2 rejected for lack of credit, got %+v", ack)
+	}
+	readOne(t, src)
+	if ack := nextAck(t, stream); ack.Seq != 1 || !ack.Ok {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 3, Payload: []byte("3")})
+	readOne(t, src)
+	if ack := nextAck(t, stream); ack.Seq != 3 || !ack.Ok {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 2, Payload: []byte("2")})
+	if id := readOne(t, src); id != opened.SessionId+"-2" {
+		t.Fatalf("expected the retransmitted seq 2 to be published, read %s", id)
+	}
+	if ack := nextAck(t, stream); ack.Seq != 2 || !ack.Ok {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+}
+
+func TestPublishStreamTypedMode(t *testing.T) {
+	src := NewGrpcSource("/stream/typed")
+	defer src.Close()
+	client := startStreamServer(t, &Server{Storage: &mockStorage{}})
+
+	if _, err := openStreamErr(t, client, &proto.OpenStream{Path: "/stream/typed", SchemaSubject: "missing"}); err == nil {
+		t.Fatal("expected an unknown schema to be refused")
+	}
+
+	stream, _ := openStream(t, t.Context(), client, &proto.OpenStream{Path: "/stream/typed", SchemaSubject: "orders", MessageType: "Order"})
+
+	fds, err := (&protoparse.Parser{Accessor: protoparse.FileContentsFromMap(map[string]string{"o.proto": orderSchema})}).ParseFiles("o.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := dynamic.NewMessage(fds[0].FindMessage("shop.Order"))
+	order.SetFieldByName("sku", "abc")
+	order.SetFieldByName("quantity", int64(3))
+	payload, err := order.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 1, Payload: []byte{0xff, 0xff, 0xff}})
+	if ack := nextAck(t, stream); ack.Seq != 1 || ack.Ok {
+		t.Fatalf("expected an invalid payload to be rejected, got %+v", ack)
+	}
+
+	sendMsg(t, stream, &proto.StreamMessage{Seq: 2, Payload: payload})
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	msg, err := src.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Data()["sku"] != "abc" || msg.Data()["quantity"] != int64(3) {
+		t.Fatalf("expected decoded fields in data, got %v", msg.Data())
+	}
+}
+
+func openStreamErr(t *testing.T, c proto.SourceServiceClient, open *proto.OpenStream) (*proto.PublishStreamResponse, error) {
+	t.Helper()
+	stream, err := c.PublishStream(t.Context())
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&proto.PublishStreamRequest{Kind: &proto.PublishStreamRequest_Open{Open: open}}); err != nil {
+		return nil, err
+	}
+	return stream.Recv()
+}
@@ -0,0 +1,71 @@
+package sourcehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// OAuth2 grants.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// OAuth2 configures OAuth2 authentication of the polled API. Tokens are
+// fetched when first needed and refreshed before they expire.
+type OAuth2 struct {
+	Grant        string
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// RefreshToken is required by the refresh_token grant.
+	RefreshToken string
+	Scopes       []string
+}
+
+// SetOAuth2 authenticates every request with a bearer token from cfg.
+func (s *HTTPSource) SetOAuth2(cfg OAuth2) error {
+	if cfg.TokenURL == "" {
+		return errors.New("oauth2: token url is required")
+	}
+	// Token requests go through a client of their own, so they are not
+	// authenticated themselves.
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: 30 * time.Second})
+
+	var ts oauth2.TokenSource
+	switch cfg.Grant {
+	case GrantClientCredentials:
+		cc := &clientcredentials.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			TokenURL:     cfg.TokenURL,
+			Scopes:       cfg.Scopes,
+		}
+		ts = cc.TokenSource(ctx)
+	case GrantRefreshToken:
+		if cfg.RefreshToken == "" {
+			return errors.New("oauth2: refresh token is required")
+		}
+		oc := &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: cfg.TokenURL},
+			Scopes:       cfg.Scopes,
+		}
+		ts = oc.TokenSource(ctx, &oauth2.Token{RefreshToken: cfg.RefreshToken})
+	default:
+		return fmt.Errorf("oauth2: unsupported grant %q", cfg.Grant)
+	}
+
+	s.client = &http.Client{
+		Timeout:   s.client.Timeout,
+		Transport: &oauth2.Transport{Source: ts, Base: s.client.Transport},
+	}
+	return nil
+}
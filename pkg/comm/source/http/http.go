@@ -2,19 +2,28 @@ package sourcehttp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/tidwall/gjson"
 	"github.com/user/hermod"
 	"github.com/user/hermod/pkg/comm/message"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
+// runKey is the metadata key that ties a message to the poll that fetched it.
+const runKey = "_http_run"
+
 // HTTPSource implements the hermod.Source interface for polling HTTP endpoints.
+// Each poll (a "run") follows the configured pagination until the last page.
+// With a watermark field the source is incremental: every run asks only for
+// records past the highest watermark seen, and the watermark is persisted via
+// hermod.Stateful once every record of a run has been acknowledged.
 type HTTPSource struct {
 	url           string
 	method        string
@@ -23,8 +32,35 @@ type HTTPSource struct {
 	dataPath      string // Path to extract array from JSON response using GJSON
 	lastTimestamp time.Time
 	client        *http.Client
-	items         []any
-	currentIndex  int
+
+	pagination  Pagination
+	incremental Incremental
+	dedup       *dedupSet
+	dedupKey    string
+
+	items []item
+	page  *page
+
+	mu        sync.Mutex
+	runs      []*run
+	nextRunID uint64
+	committed string // watermark of fully acknowledged runs, the persisted state
+	high      string // highest watermark fetched, used for the next run's query
+}
+
+// item is a fetched record waiting to be read.
+type item struct {
+	value any
+	key   string
+	run   *run
+}
+
+// run tracks one poll's records until all of them are acknowledged.
+type run struct {
+	id      uint64
+	pending int
+	done    bool
+	mark    string
 }
 
 func NewHTTPSource(url, method string, headers map[string]string, interval time.Duration, dataPath string) *HTTPSource {
@@ -40,111 +76,186 @@ func NewHTTPSource(url, method string, headers map[string]string, interval time.
 		headers:  headers,
 		interval: interval,
 		dataPath: dataPath,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:   httpclient.NewRateLimitedClient(30 * time.Second),
 	}
 }
 
-func (s *HTTPSource) Read(ctx context.Context) (hermod.Message, error) {
-	if s.currentIndex < len(s.items) {
-		item := s.items[s.currentIndex]
-		s.currentIndex++
-		return s.messageFromData(item), nil
+// SetPagination configures how each poll walks through the pages of a response.
+func (s *HTTPSource) SetPagination(p Pagination) {
+	s.pagination = p
+}
+
+// SetIncremental turns on incremental sync on a high-water mark.
+func (s *HTTPSource) SetIncremental(inc Incremental) {
+	s.incremental = inc
+	s.mu.Lock()
+	if s.high == "" {
+		s.high = inc.Initial
 	}
+	s.mu.Unlock()
+}
 
-	// Simple polling logic: wait for interval
-	if !s.lastTimestamp.IsZero() {
-		nextRun := s.lastTimestamp.Add(s.interval)
-		if time.Now().Before(nextRun) {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(time.Until(nextRun)):
-			}
+// SetDedupKey drops records whose key field (a GJSON path) was among the last
+// window keys emitted. A window of zero means 10000.
+func (s *HTTPSource) SetDedupKey(field string, window int) {
+	s.dedupKey = field
+	s.dedup = newDedupSet(window)
+}
+
+func (s *HTTPSource) Read(ctx context.Context) (hermod.Message, error) {
+	for {
+		if len(s.items) > 0 {
+			it := s.items[0]
+			s.items = s.items[1:]
+			return s.messageFromItem(it), nil
 		}
-	}
 
-	s.lastTimestamp = time.Now()
-	s.items = nil
-	s.currentIndex = 0
+		if s.page != nil && s.page.next != "" {
+			if err := s.fetchPage(ctx); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if s.page != nil {
+			s.finishRun(s.page.run)
+			s.page = nil
+		}
 
-	req, err := http.NewRequestWithContext(ctx, s.method, s.url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+		// Simple polling logic: wait for interval
+		if !s.lastTimestamp.IsZero() {
+			nextRun := s.lastTimestamp.Add(s.interval)
+			if time.Now().Before(nextRun) {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(time.Until(nextRun)):
+				}
+			}
+		}
+		s.lastTimestamp = time.Now()
 
-	for k, v := range s.headers {
-		req.Header.Set(k, v)
+		first, err := s.firstPageURL()
+		if err != nil {
+			return nil, err
+		}
+		s.page = &page{next: first, run: s.startRun()}
 	}
+}
 
-	resp, err := s.client.Do(req)
+// fetchPage requests the current page, queues its records and works out the
+// next page.
+func (s *HTTPSource) fetchPage(ctx context.Context) error {
+	pageURL := s.page.next
+	resp, body, err := s.do(ctx, pageURL)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("http request failed: status %d", resp.StatusCode)
-	}
+	records := extractRecords(body, s.dataPath)
+	s.page.fetched++
+	s.page.next = s.nextPageURL(pageURL, resp, body, len(records))
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	for _, rec := range records {
+		it := item{value: rec.value, run: s.page.run}
+		if s.dedupKey != "" {
+			it.key = rec.get(s.dedupKey)
+			if it.key != "" && !s.dedup.add(it.key) {
+				continue
+			}
+		}
+		if s.incremental.Field != "" {
+			if mark := rec.get(s.incremental.Field); mark != "" {
+				s.observe(s.page.run, mark)
+			}
+		}
+		s.items = append(s.items, it)
 	}
+	return nil
+}
 
-	if s.dataPath != "" {
-		result := gjson.GetBytes(body, s.dataPath)
-		if result.IsArray() {
-			for _, item := range result.Array() {
-				s.items = append(s.items, item.Value())
-			}
-		} else if result.Exists() {
-			s.items = append(s.items, result.Value())
+// do sends a request, sitting out rate limits until the server accepts it.
+func (s *HTTPSource) do(ctx context.Context, target string) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, s.method, target, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create request: %w", err)
 		}
-	} else {
-		var data any
-		if err := json.Unmarshal(body, &data); err != nil {
-			// If not JSON, return as raw string in a map
-			data = map[string]any{"raw": string(body)}
+		for k, v := range s.headers {
+			req.Header.Set(k, v)
 		}
 
-		if arr, ok := data.([]any); ok {
-			s.items = arr
-		} else {
-			s.items = append(s.items, data)
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("http request failed: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read response body: %w", err)
 		}
-	}
 
-	if len(s.items) == 0 {
-		// If no items found, we could either return an empty message or wait for next poll
-		// Most Hermod sources block until data is available.
-		// For now, let's recurse (it will wait for interval in the next call)
-		return s.Read(ctx)
-	}
+		if wait, limited := httpclient.RateLimitWait(resp, time.Now()); limited {
+			if wait <= 0 {
+				wait = min(time.Second<<min(attempt, 6), time.Minute)
+			}
+			select {
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
 
-	item := s.items[0]
-	s.currentIndex = 1
-	return s.messageFromData(item), nil
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, nil, fmt.Errorf("http request failed: status %d", resp.StatusCode)
+		}
+		return resp, body, nil
+	}
 }
 
-func (s *HTTPSource) messageFromData(data any) hermod.Message {
+func (s *HTTPSource) messageFromItem(it item) hermod.Message {
 	msg := message.AcquireMessage()
-	msg.SetID(uuid.New().String())
+	if it.key != "" {
+		msg.SetID(it.key)
+	} else {
+		msg.SetID(uuid.New().String())
+	}
 	msg.SetMetadata("source", "http")
 	msg.SetMetadata("url", s.url)
 
-	if m, ok := data.(map[string]any); ok {
+	s.mu.Lock()
+	it.run.pending++
+	s.mu.Unlock()
+	msg.SetMetadata(runKey, strconv.FormatUint(it.run.id, 10))
+
+	if m, ok := it.value.(map[string]any); ok {
 		for k, v := range m {
 			msg.SetData(k, v)
 		}
 	} else {
-		msg.SetData("value", data)
+		msg.SetData("value", it.value)
 	}
 	return msg
 }
 
 func (s *HTTPSource) Ack(ctx context.Context, msg hermod.Message) error {
+	if msg == nil {
+		return nil
+	}
+	id, err := strconv.ParseUint(msg.Metadata()[runKey], 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.runs {
+		if r.id == id {
+			r.pending--
+			break
+		}
+	}
+	s.commitLocked()
 	return nil
 }
 
@@ -175,3 +286,46 @@ func (s *HTTPSource) Ping(ctx context.Context) error {
 func (s *HTTPSource) Close() error {
 	return nil
 }
+
+// record is one element of a response's data.
+type record struct {
+	value any
+	raw   gjson.Result
+}
+
+// get returns the value at a GJSON path of the record, as a string.
+func (r record) get(path string) string {
+	if !r.raw.Exists() {
+		return ""
+	}
+	return r.raw.Get(path).String()
+}
+
+// extractRecords returns the records of a response body: the array (or the
+// single value) at dataPath, the top-level array or object, or the raw body
+// when it is not JSON.
+func extractRecords(body []byte, dataPath string) []record {
+	var result gjson.Result
+	if dataPath != "" {
+		result = gjson.GetBytes(body, dataPath)
+		if !result.Exists() {
+			return nil
+		}
+	} else {
+		if !gjson.ValidBytes(body) {
+			// If not JSON, return as raw string in a map
+			return []record{{value: map[string]any{"raw": string(body)}}}
+		}
+		result = gjson.ParseBytes(body)
+	}
+
+	if !result.IsArray() {
+		return []record{{value: result.Value(), raw: result}}
+	}
+	elems := result.Array()
+	records := make([]record, len(elems))
+	for i, e := range elems {
+		records[i] = record{value: e.Value(), raw: e}
+	}
+	return records
+}
@@ -1,11 +1,16 @@
 package sourcehttp
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/infra/httpclient"
 )
 
 func TestHTTPSource_Read(t *testing.T) {
@@ -87,3 +92,163 @@ func TestHTTPSource_Read(t *testing.T) {
 		}
 	})
 }
+
+func readN(t *testing.T, s *HTTPSource, n int) []hermod.Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	msgs := make([]hermod.Message, 0, n)
+	for range n {
+		msg, err := s.Read(ctx)
+		if err != nil {
+			t.Fatalf("read %d: %v", len(msgs), err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestHTTPSource_Pagination(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cursor":
+			if r.URL.Query().Get("cursor") == "" {
+				json.NewEncoder(w).Encode(map[string]any{"data": []int{1, 2}, "next": "c2"})
+			} else {
+				json.NewEncoder(w).Encode(map[string]any{"data": []int{3}, "next": ""})
+			}
+		case "/link":
+			if r.URL.Query().Get("p") == "" {
+				w.Header().Set("Link", `</link?p=2>; rel="next", </link>; rel="first"`)
+				json.NewEncoder(w).Encode([]int{1, 2})
+			} else {
+				json.NewEncoder(w).Encode([]int{3})
+			}
+		case "/offset":
+			off, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			all := []int{1, 2, 3}
+			json.NewEncoder(w).Encode(all[min(off, 3):min(off+2, 3)])
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		path string
+		data string
+		p    Pagination
+	}{
+		{"/cursor", "data", Pagination{Strategy: PaginateCursor, Path: "next"}},
+		{"/link", "", Pagination{Strategy: PaginateLink}},
+		{"/offset", "", Pagination{Strategy: PaginateOffset, LimitParam: "limit", PageSize: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			s := NewHTTPSource(ts.URL+tt.path, "GET", nil, time.Hour, tt.data)
+			s.SetPagination(tt.p)
+			for i, msg := range readN(t, s, 3) {
+				if msg.Data()["value"] != float64(i+1) {
+					t.Fatalf("record %d: got %v", i, msg.Data())
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPSource_IncrementalCommitsOnAck(t *testing.T) {
+	var since []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since = append(since, r.URL.Query().Get("since"))
+		if r.URL.Query().Get("since") == "" {
+			json.NewEncoder(w).Encode([]map[string]any{{"id": "a", "ts": 10}, {"id": "b", "ts": 20}})
+			return
+		}
+		// Inclusive filter: the boundary record comes back.
+		json.NewEncoder(w).Encode([]map[string]any{{"id": "b", "ts": 20}, {"id": "c", "ts": 30}})
+	}))
+	defer ts.Close()
+
+	s := NewHTTPSource(ts.URL, "GET", nil, 10*time.Millisecond, "")
+	s.SetIncremental(Incremental{Field: "ts", Param: "since"})
+	s.SetDedupKey("id", 0)
+
+	first := readN(t, s, 2)
+	if st := s.GetState(); st != nil {
+		t.Fatalf("state committed before the records were acknowledged: %v", st)
+	}
+
+	// Reading on starts the next poll from the watermark; b is deduplicated.
+	next := readN(t, s, 1)
+	if next[0].ID() != "c" || since[1] != "20" {
+		t.Fatalf("expected c from since=20, got %s from %v", next[0].ID(), since)
+	}
+
+	// The first poll commits only once all its records are acknowledged.
+	_ = s.Ack(t.Context(), first[0])
+	if st := s.GetState(); st != nil {
+		t.Fatalf("state committed with a record in flight: %v", st)
+	}
+	_ = s.Ack(t.Context(), first[1])
+	if st := s.GetState(); st[stateWatermark] != "20" {
+		t.Fatalf("expected watermark 20, got %v", st)
+	}
+
+	resumed := NewHTTPSource(ts.URL, "GET", nil, time.Hour, "")
+	resumed.SetIncremental(Incremental{Field: "ts", Param: "since"})
+	resumed.SetState(s.GetState())
+	readN(t, resumed, 1)
+	if since[len(since)-1] != "20" {
+		t.Fatalf("resumed source did not query from the persisted watermark: %v", since)
+	}
+}
+
+func TestHTTPSource_WaitsOutRateLimits(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 2 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": 1})
+	}))
+	defer ts.Close()
+
+	s := NewHTTPSource(ts.URL, "GET", nil, time.Hour, "")
+	// Leave the waiting to the source rather than the transport's retries.
+	s.client.Transport = &httpclient.RateLimitTransport{MaxRetries: -1}
+	if msgs := readN(t, s, 1); msgs[0].Data()["id"] != float64(1) || calls != 3 {
+		t.Fatalf("expected the record after the rate limit, got %v after %d calls", msgs[0].Data(), calls)
+	}
+}
+
+func TestHTTPSource_OAuth2ClientCredentials(t *testing.T) {
+	var tokens int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokens++
+			if r.FormValue("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "tok", "token_type": "bearer", "expires_in": 3600})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"id": 1})
+	}))
+	defer ts.Close()
+
+	s := NewHTTPSource(ts.URL+"/data", "GET", nil, 10*time.Millisecond, "")
+	if err := s.SetOAuth2(OAuth2{Grant: GrantClientCredentials, TokenURL: ts.URL + "/token", ClientID: "id", ClientSecret: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	readN(t, s, 2)
+	if tokens != 1 {
+		t.Fatalf("expected the token to be reused, fetched %d", tokens)
+	}
+}
@@ -0,0 +1,149 @@
+package sourcehttp
+
+import (
+	"strconv"
+	"time"
+)
+
+// stateWatermark is the state key of the persisted high-water mark.
+const stateWatermark = "watermark"
+
+// Incremental configures incremental sync on a high-water mark.
+type Incremental struct {
+	// Field is the GJSON path, within a record, of the timestamp or ID that
+	// grows as records change.
+	Field string
+	// Param is the query parameter the current high-water mark is sent in,
+	// e.g. "updated_since".
+	Param string
+	// Initial is the high-water mark of the first poll; empty fetches
+	// everything.
+	Initial string
+}
+
+// startRun registers a new poll.
+func (s *HTTPSource) startRun() *run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRunID++
+	r := &run{id: s.nextRunID}
+	s.runs = append(s.runs, r)
+	return r
+}
+
+// finishRun marks r as fetched in full, so its watermark commits once its
+// records are acknowledged.
+func (s *HTTPSource) finishRun(r *run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.done = true
+	s.commitLocked()
+}
+
+// observe records the watermark of a fetched record.
+func (s *HTTPSource) observe(r *run, mark string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if compareWatermarks(mark, r.mark) > 0 {
+		r.mark = mark
+	}
+	if compareWatermarks(mark, s.high) > 0 {
+		s.high = mark
+	}
+}
+
+// commitLocked advances the committed watermark past every leading run that
+// is fetched and acknowledged. Runs commit in order, so a crash never skips
+// records of an earlier run that were still in flight.
+func (s *HTTPSource) commitLocked() {
+	for len(s.runs) > 0 && s.runs[0].done && s.runs[0].pending <= 0 {
+		if compareWatermarks(s.runs[0].mark, s.committed) > 0 {
+			s.committed = s.runs[0].mark
+		}
+		s.runs = s.runs[1:]
+	}
+}
+
+func (s *HTTPSource) GetState() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.committed == "" {
+		return nil
+	}
+	return map[string]string{stateWatermark: s.committed}
+}
+
+func (s *HTTPSource) SetState(state map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if mark := state[stateWatermark]; mark != "" {
+		s.committed = mark
+		if compareWatermarks(mark, s.high) > 0 {
+			s.high = mark
+		}
+	}
+}
+
+// compareWatermarks orders watermarks numerically when both are numbers, as
+// times when both are RFC 3339 timestamps, and as strings otherwise. An empty
+// watermark is lower than any other.
+func compareWatermarks(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case b == "":
+		return 1
+	case a == "":
+		return -1
+	}
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	if x, err := time.Parse(time.RFC3339Nano, a); err == nil {
+		if y, err := time.Parse(time.RFC3339Nano, b); err == nil {
+			return x.Compare(y)
+		}
+	}
+	if a < b {
+		return -1
+	}
+	return 1
+}
+
+// dedupSet remembers the last keys emitted.
+type dedupSet struct {
+	keys  map[string]struct{}
+	order []string
+	next  int
+}
+
+func newDedupSet(window int) *dedupSet {
+	if window <= 0 {
+		window = 10000
+	}
+	return &dedupSet{keys: make(map[string]struct{}, window), order: make([]string, 0, window)}
+}
+
+// add records key and reports whether it is new.
+func (d *dedupSet) add(key string) bool {
+	if _, ok := d.keys[key]; ok {
+		return false
+	}
+	if len(d.order) < cap(d.order) {
+		d.order = append(d.order, key)
+	} else {
+		delete(d.keys, d.order[d.next])
+		d.order[d.next] = key
+		d.next = (d.next + 1) % len(d.order)
+	}
+	d.keys[key] = struct{}{}
+	return true
+}
@@ -0,0 +1,201 @@
+package sourcehttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Pagination strategies.
+const (
+	// PaginateCursor passes the cursor found at Path in each response as the
+	// Param query parameter of the next request.
+	PaginateCursor = "cursor"
+	// PaginateOffset advances the Param query parameter by the number of
+	// records received.
+	PaginateOffset = "offset"
+	// PaginatePage increments the Param query parameter, starting at StartPage.
+	PaginatePage = "page"
+	// PaginateLink follows the rel="next" URL of the Link header.
+	PaginateLink = "link"
+	// PaginateNextURL follows the URL found at Path in each response.
+	PaginateNextURL = "next_url"
+)
+
+// Pagination configures how a poll walks through the pages of a response. The
+// zero value makes a single request per poll.
+type Pagination struct {
+	Strategy string
+	// Param is the query parameter carrying the cursor, offset or page number.
+	// It defaults to "cursor", "offset" or "page".
+	Param string
+	// Path is the GJSON path of the next cursor or next URL in a response.
+	Path string
+	// LimitParam, when set, sends PageSize as this query parameter.
+	LimitParam string
+	// PageSize is the number of records a full page holds. With the offset and
+	// page strategies a shorter page is the last one.
+	PageSize int
+	// StartPage is the number of the first page; zero means 1.
+	StartPage int
+	// MaxPages bounds the pages fetched per poll; zero means no limit. The
+	// next poll starts over, or from the watermark when the source is
+	// incremental.
+	MaxPages int
+}
+
+// page is the position of the poll in progress.
+type page struct {
+	run *run
+	// next is the URL of the next page to fetch; empty when the poll is over.
+	next    string
+	fetched int
+	offset  int
+	number  int
+}
+
+func (p Pagination) param() string {
+	if p.Param != "" {
+		return p.Param
+	}
+	switch p.Strategy {
+	case PaginateCursor:
+		return "cursor"
+	case PaginateOffset:
+		return "offset"
+	case PaginatePage:
+		return "page"
+	}
+	return ""
+}
+
+func (p Pagination) startPage() int {
+	if p.StartPage > 0 {
+		return p.StartPage
+	}
+	return 1
+}
+
+// firstPageURL builds the URL of a poll's first request.
+func (s *HTTPSource) firstPageURL() (string, error) {
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	q := u.Query()
+	if s.incremental.Param != "" {
+		s.mu.Lock()
+		mark := s.high
+		s.mu.Unlock()
+		if mark != "" {
+			q.Set(s.incremental.Param, mark)
+		}
+	}
+	p := s.pagination
+	if p.LimitParam != "" && p.PageSize > 0 {
+		q.Set(p.LimitParam, strconv.Itoa(p.PageSize))
+	}
+	switch p.Strategy {
+	case PaginateOffset:
+		q.Set(p.param(), "0")
+	case PaginatePage:
+		q.Set(p.param(), strconv.Itoa(p.startPage()))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// nextPageURL works out the URL of the page after current, or returns "" when
+// current was the last page.
+func (s *HTTPSource) nextPageURL(current string, resp *http.Response, body []byte, n int) string {
+	p := s.pagination
+	pg := s.page
+	if p.Strategy == "" || n == 0 || (p.MaxPages > 0 && pg.fetched >= p.MaxPages) {
+		return ""
+	}
+
+	switch p.Strategy {
+	case PaginateCursor:
+		cursor := gjson.GetBytes(body, p.Path).String()
+		if cursor == "" {
+			return ""
+		}
+		return withParam(current, p.param(), cursor)
+	case PaginateOffset:
+		if p.PageSize > 0 && n < p.PageSize {
+			return ""
+		}
+		pg.offset += n
+		return withParam(current, p.param(), strconv.Itoa(pg.offset))
+	case PaginatePage:
+		if p.PageSize > 0 && n < p.PageSize {
+			return ""
+		}
+		if pg.number == 0 {
+			pg.number = p.startPage()
+		}
+		pg.number++
+		return withParam(current, p.param(), strconv.Itoa(pg.number))
+	case PaginateLink:
+		return resolveURL(current, linkNext(resp.Header.Values("Link")))
+	case PaginateNextURL:
+		return resolveURL(current, gjson.GetBytes(body, p.Path).String())
+	}
+	return ""
+}
+
+func withParam(rawURL, key, value string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// resolveURL resolves ref, which may be relative, against base.
+func resolveURL(base, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return b.ResolveReference(r).String()
+}
+
+// linkNext returns the rel="next" target of RFC 8288 Link headers.
+func linkNext(headers []string) string {
+	for _, h := range headers {
+		for _, link := range strings.Split(h, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, attr := range parts[1:] {
+				k, v, ok := strings.Cut(strings.TrimSpace(attr), "=")
+				if !ok || !strings.EqualFold(k, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(v, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -420,9 +420,104 @@ func (m *MSSQLSource) poll(ctx context.Context) error {
 		m.sortAndBufferMessages(allMessages)
 	}
 
+	return m.checkCaptureChanges(ctx, db)
+}
+
+// checkCaptureChanges switches tables to their newest capture instance. A
+// capture instance keeps capturing the columns it was created with, so a
+// column change upstream is only captured once a new instance is created for
+// the table. The switch is announced as a schema change, after every change
+// the old instance held up to this poll.
+func (m *MSSQLSource) checkCaptureChanges(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, queryDiscoverCaptures)
+	if err != nil {
+		return fmt.Errorf("failed to discover capture instances: %w", err)
+	}
+	newest := make(map[string]string)
+	for rows.Next() {
+		var schemaName, tableName, captureInstance string
+		var sourceObjectID int32
+		if err := rows.Scan(&schemaName, &tableName, &captureInstance, &sourceObjectID); err != nil {
+			rows.Close()
+			return err
+		}
+		newest[schemaName+"."+tableName] = captureInstance
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	type captureSwitch struct{ from, to string }
+	switches := make(map[string]captureSwitch)
+	m.mu.Lock()
+	for table, capture := range m.captures {
+		if n, ok := newest[table]; ok && n != capture {
+			switches[table] = captureSwitch{capture, n}
+		}
+	}
+	m.mu.Unlock()
+
+	for table, sw := range switches {
+		prev, err := m.capturedColumns(ctx, db, table, sw.from)
+		if err != nil {
+			prev = nil
+		}
+		cols, err := m.capturedColumns(ctx, db, table, sw.to)
+		if err != nil {
+			return fmt.Errorf("failed to read captured columns of %s: %w", sw.to, err)
+		}
+		m.log("INFO", "Capture instance changed, switching to the new one", "table", table, "from", sw.from, "to", sw.to)
+
+		schema, name := parseTableParts(table)
+		msg := message.AcquireSchemaChange(table+":schema:"+sw.to, hermod.SchemaChange{Schema: schema, Table: name, Columns: cols, Previous: prev})
+		msg.SetMetadata("source", "mssql")
+		msg.SetMetadata("capture_instance", sw.to)
+
+		m.mu.Lock()
+		m.captures[table] = sw.to
+		m.buffer = append(m.buffer, msg)
+		m.mu.Unlock()
+	}
 	return nil
 }
 
+// capturedColumns describes the columns a capture instance captures, with
+// the nullability and keys the table has now. It returns nil when the
+// instance no longer exists.
+func (m *MSSQLSource) capturedColumns(ctx context.Context, db *sql.DB, table, capture string) ([]hermod.ColumnInfo, error) {
+	rows, err := db.QueryContext(ctx, queryCapturedColumns, capture)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cols []hermod.ColumnInfo
+	for rows.Next() {
+		var col hermod.ColumnInfo
+		if err := rows.Scan(&col.Name, &col.Type); err != nil {
+			return nil, err
+		}
+		col.IsNullable = true
+		cols = append(cols, col)
+	}
+	if err := rows.Err(); err != nil || len(cols) == 0 {
+		return nil, err
+	}
+
+	current, err := m.discoverColumnsInternal(ctx, db, table)
+	if err != nil {
+		return cols, nil //nolint:nilerr // the captured columns are enough without the table's constraints
+	}
+	for i, col := range cols {
+		for _, c := range current {
+			if c.Name == col.Name {
+				cols[i].IsNullable, cols[i].IsPK, cols[i].IsIdentity, cols[i].Default = c.IsNullable, c.IsPK, c.IsIdentity, c.Default
+			}
+		}
+	}
+	return cols, nil
+}
+
 func (m *MSSQLSource) sortAndBufferMessages(msgs []hermod.Message) {
 	// Sort by LSN string (metadata) which is hex representation of binary LSN
 	sort.Slice(msgs, func(i, j int) bool {
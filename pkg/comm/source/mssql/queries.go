@@ -26,7 +26,15 @@ const (
 		SELECT s.name, t.name, ct.capture_instance, ct.source_object_id 
 		FROM cdc.change_tables ct 
 		JOIN sys.tables t ON ct.source_object_id = t.object_id 
-		JOIN sys.schemas s ON t.schema_id = s.schema_id`
+		JOIN sys.schemas s ON t.schema_id = s.schema_id
+		ORDER BY ct.create_date`
+
+	queryCapturedColumns = `
+		SELECT cc.column_name, cc.column_type 
+		FROM cdc.captured_columns cc 
+		JOIN cdc.change_tables ct ON cc.object_id = ct.object_id 
+		WHERE ct.capture_instance = @p1 
+		ORDER BY cc.column_ordinal`
 
 	queryCheckDatabaseCDC = "SELECT is_cdc_enabled FROM sys.databases WHERE name = DB_NAME()"
 
@@ -8,11 +8,15 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-mysql-org/go-mysql/canal"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/go-mysql-org/go-mysql/schema"
 	mysql_driver "github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	"github.com/user/hermod"
@@ -27,16 +31,20 @@ type MySQLSource struct {
 	useCDC     bool
 	db         *sql.DB
 	canal      *canal.Canal
-	msgChan    chan hermod.Message
-	errChan    chan error
-	mu         sync.Mutex
-	logger     hermod.Logger
+	// columns are the last known columns of each table, by schema.table,
+	// against which schema changes are compared.
+	columns map[string][]hermod.ColumnInfo
+	msgChan chan hermod.Message
+	errChan chan error
+	mu      sync.Mutex
+	logger  hermod.Logger
 }
 
 func NewMySQLSource(connString string, useCDC bool) *MySQLSource {
 	return &MySQLSource{
 		connString: connString,
 		useCDC:     useCDC,
+		columns:    make(map[string][]hermod.ColumnInfo),
 		msgChan:    make(chan hermod.Message, sourcebuf.DefaultSourceBuffer),
 		errChan:    make(chan error, 10),
 	}
@@ -144,9 +152,77 @@ func (m *MySQLSource) init(ctx context.Context) error {
 type mysqlEventHandler struct {
 	canal.DummyEventHandler
 	source *MySQLSource
+	// changed are the tables altered by the DDL statement being handled.
+	changed [][2]string
+}
+
+// OnTableChanged notes a table the DDL statement that follows altered.
+func (h *mysqlEventHandler) OnTableChanged(_ *replication.EventHeader, schema, table string) error {
+	h.changed = append(h.changed, [2]string{schema, table})
+	return nil
+}
+
+// OnDDL announces the tables the statement altered as schema changes,
+// comparing their columns with those last seen. A table never seen before
+// is announced without previous columns.
+func (h *mysqlEventHandler) OnDDL(header *replication.EventHeader, _ gomysql.Position, e *replication.QueryEvent) error {
+	changed := h.changed
+	h.changed = nil
+	for _, st := range changed {
+		t, err := h.source.canal.GetTable(st[0], st[1])
+		if err != nil {
+			// The table was dropped or is not followed.
+			continue
+		}
+		cols := tableColumns(t)
+		key := st[0] + "." + st[1]
+		h.source.mu.Lock()
+		prev, known := h.source.columns[key]
+		h.source.columns[key] = cols
+		h.source.mu.Unlock()
+		if known && slices.Equal(prev, cols) {
+			continue
+		}
+		change := hermod.SchemaChange{Schema: st[0], Table: st[1], Columns: cols, Previous: prev, DDL: string(e.Query)}
+		msg := message.AcquireSchemaChange(fmt.Sprintf("%s:schema:%d", key, header.LogPos), change)
+		msg.SetMetadata("source", "mysql")
+		// Unlike rows, a schema change is never dropped for a full buffer.
+		select {
+		case h.source.msgChan <- msg:
+		case <-h.source.canal.Ctx().Done():
+			message.ReleaseMessage(msg)
+			return nil
+		}
+	}
+	return nil
+}
+
+// tableColumns describes the columns of a table as canal reads them, which
+// does not include nullability: every column but the primary key is taken
+// to be nullable.
+func tableColumns(t *schema.Table) []hermod.ColumnInfo {
+	cols := make([]hermod.ColumnInfo, 0, len(t.Columns))
+	for i, c := range t.Columns {
+		isPK := slices.Contains(t.PKColumns, i)
+		cols = append(cols, hermod.ColumnInfo{
+			Name:       c.Name,
+			Type:       c.RawType,
+			IsNullable: !isPK,
+			IsPK:       isPK,
+			IsIdentity: c.IsAuto,
+		})
+	}
+	return cols
 }
 
 func (h *mysqlEventHandler) OnRow(e *canal.RowsEvent) error {
+	key := e.Table.Schema + "." + e.Table.Name
+	h.source.mu.Lock()
+	if _, ok := h.source.columns[key]; !ok {
+		h.source.columns[key] = tableColumns(e.Table)
+	}
+	h.source.mu.Unlock()
+
 	action := e.Action
 	var rows [][]any
 	if action == canal.UpdateAction {
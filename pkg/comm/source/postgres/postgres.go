@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -918,10 +919,7 @@ func (p *PostgresSource) handleXLogData(ctx context.Context, data []byte) error
 
 	switch lm := logicalMsg.(type) {
 	case *pglogrepl.RelationMessage:
-		p.mu.Lock()
-		p.relations[lm.RelationID] = lm
-		p.mu.Unlock()
-		return nil
+		return p.handleRelation(ctx, currentLSN, lm)
 	case *pglogrepl.InsertMessage:
 		return p.dispatch(ctx, currentLSN, p.handleInsert(currentLSN, lm))
 	case *pglogrepl.UpdateMessage:
@@ -994,6 +992,71 @@ func (p *PostgresSource) dispatch(ctx context.Context, lsn pglogrepl.LSN, msg he
 	}
 }
 
+// handleRelation records the columns of a relation. Postgres sends them
+// before the first change to a relation in each session and again after its
+// columns change, which is announced to consumers as a schema change. The
+// first relation seen is announced too, without previous columns, so the
+// engine can compare it with what it registered before a restart.
+func (p *PostgresSource) handleRelation(ctx context.Context, lsn pglogrepl.LSN, rel *pglogrepl.RelationMessage) error {
+	p.mu.Lock()
+	prev, seen := p.relations[rel.RelationID]
+	p.relations[rel.RelationID] = rel
+	p.mu.Unlock()
+
+	change := hermod.SchemaChange{Schema: rel.Namespace, Table: rel.RelationName, Columns: p.relationColumns(rel)}
+	if seen {
+		change.Previous = p.relationColumns(prev)
+		if slices.Equal(change.Previous, change.Columns) {
+			return nil
+		}
+	}
+	msg := message.AcquireSchemaChange(lsn.String()+":schema", change)
+	msg.SetMetadata("source", "postgres")
+	msg.SetMetadata("lsn", lsn.String())
+	return p.dispatch(ctx, lsn, msg)
+}
+
+// relationColumns describes the columns of a relation. Key columns are those
+// of its replica identity; pgoutput does not say whether a column is
+// nullable, so every other column is taken to be.
+func (p *PostgresSource) relationColumns(rel *pglogrepl.RelationMessage) []hermod.ColumnInfo {
+	cols := make([]hermod.ColumnInfo, 0, len(rel.Columns))
+	for _, c := range rel.Columns {
+		isKey := c.Flags == 1
+		cols = append(cols, hermod.ColumnInfo{
+			Name:       c.Name,
+			Type:       p.typeName(c.DataType, c.TypeModifier),
+			IsNullable: !isKey,
+			IsPK:       isKey,
+		})
+	}
+	return cols
+}
+
+// typeName returns the name of a column type, with the length of character
+// types and the precision of numerics.
+func (p *PostgresSource) typeName(oid uint32, typmod int32) string {
+	p.mu.Lock()
+	m := p.typeMap
+	p.mu.Unlock()
+	if m == nil {
+		m = pgtype.NewMap()
+	}
+	t, ok := m.TypeForOID(oid)
+	if !ok {
+		return "text"
+	}
+	switch {
+	case typmod < 4:
+		return t.Name
+	case t.Name == "varchar" || t.Name == "bpchar":
+		return fmt.Sprintf("%s(%d)", t.Name, typmod-4)
+	case t.Name == "numeric":
+		return fmt.Sprintf("numeric(%d,%d)", (typmod-4)>>16&0xffff, (typmod-4)&0xffff)
+	}
+	return t.Name
+}
+
 func (p *PostgresSource) handleInsert(lsn pglogrepl.LSN, lm *pglogrepl.InsertMessage) hermod.Message {
 	res := message.AcquireMessage()
 	res.SetID(lsn.String())
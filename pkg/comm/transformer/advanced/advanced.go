@@ -2,6 +2,7 @@ package advanced
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/user/hermod/pkg/comm/transformer"
@@ -25,7 +26,22 @@ type AdvancedTransformer struct {
 	evaluator *evaluator.Evaluator
 }
 
+// CheckExpressions implements transformer.ExpressionTransformer.
+func (t *AdvancedTransformer) CheckExpressions(config map[string]any) error {
+	for k, v := range config {
+		if src, ok := v.(string); ok && strings.HasPrefix(k, "column.") {
+			if err := evaluator.CheckExpression(src); err != nil {
+				return fmt.Errorf("column %s: %w", strings.TrimPrefix(k, "column."), err)
+			}
+		}
+	}
+	return nil
+}
+
 func (t *AdvancedTransformer) Prepare(config map[string]any) (map[string]any, error) {
+	if err := t.CheckExpressions(config); err != nil {
+		return nil, err
+	}
 	var columns []columnConfig
 	for k, v := range config {
 		if strings.HasPrefix(k, "column.") {
@@ -66,7 +82,10 @@ func (t *AdvancedTransformer) Transform(ctx context.Context, msg hermod.Message,
 	if transType == "advanced" {
 		results := make(map[string]any)
 		for _, col := range columns {
-			result := t.evaluator.EvaluateAdvancedExpression(msg, col.expr)
+			result, err := t.evaluator.EvaluateValue(msg, col.expr)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", col.path, err)
+			}
 			if result != nil {
 				results[col.path] = result
 			}
@@ -78,7 +97,10 @@ func (t *AdvancedTransformer) Transform(ctx context.Context, msg hermod.Message,
 		}
 	} else { // "set"
 		for _, col := range columns {
-			result := t.evaluator.EvaluateAdvancedExpression(msg, col.expr)
+			result, err := t.evaluator.EvaluateValue(msg, col.expr)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", col.path, err)
+			}
 			msg.SetData(col.path, result)
 		}
 	}
@@ -2,8 +2,10 @@ package advanced
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/user/hermod/pkg/comm/transformer"
 
@@ -70,6 +72,12 @@ func (t *RateLimitTransformer) Transform(ctx context.Context, msg hermod.Message
 		}
 	}
 
+	strategy, _ := config["strategy"].(string) // "wait" or "drop"
+
+	if scope, _ := config["scope"].(string); scope == "distributed" {
+		return t.reserveShared(ctx, msg, config, key, mps, int(burst), strategy)
+	}
+
 	actual, _ := t.limiters.LoadOrStore(key, rate.NewLimiter(rate.Limit(mps), int(burst)))
 	limiter := actual.(*rate.Limiter)
 
@@ -81,7 +89,6 @@ func (t *RateLimitTransformer) Transform(ctx context.Context, msg hermod.Message
 		limiter.SetBurst(int(burst))
 	}
 
-	strategy, _ := config["strategy"].(string) // "wait" or "drop"
 	if strategy == "drop" {
 		if !limiter.Allow() {
 			return nil, nil // Drop message
@@ -95,3 +102,42 @@ func (t *RateLimitTransformer) Transform(ctx context.Context, msg hermod.Message
 
 	return msg, nil
 }
+
+// reserveShared meters the message against a bucket in the workflow's state
+// store, so every worker running the workflow shares one budget. Buckets are
+// scoped to the workflow node unless config names a "bucket", which lets
+// several workflows share the budget of one downstream API.
+func (t *RateLimitTransformer) reserveShared(ctx context.Context, msg hermod.Message, config map[string]any, key string, mps float64, burst int, strategy string) (hermod.Message, error) {
+	store, ok := ctx.Value(hermod.StateStoreKey).(hermod.RateLimitStore)
+	if !ok {
+		return nil, errors.New("rate_limit: distributed scope requires a state store that supports rate limiting (redis, etcd or sqlite)")
+	}
+
+	bucket, _ := config["bucket"].(string)
+	if bucket == "" {
+		workflowID, _ := ctx.Value(hermod.WorkflowIDKey).(string)
+		nodeID, _ := ctx.Value(hermod.NodeIDKey).(string)
+		bucket = workflowID + ":" + nodeID
+	}
+	stateKey := fmt.Sprintf("ratelimit:%s:%s", bucket, key)
+
+	for {
+		wait, err := store.Reserve(ctx, stateKey, mps, burst)
+		if err != nil {
+			return nil, fmt.Errorf("rate_limit: %w", err)
+		}
+		if wait <= 0 {
+			return msg, nil
+		}
+		if strategy == "drop" {
+			return nil, nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package advanced
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
+	"github.com/user/hermod/pkg/infra/state"
+)
+
+func TestRateLimitTransformer_DistributedSharesBudget(t *testing.T) {
+	ss, err := state.NewSQLiteStateStore(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("failed to create state store: %v", err)
+	}
+
+	ctx := context.WithValue(t.Context(), hermod.StateStoreKey, ss)
+	ctx = context.WithValue(ctx, hermod.WorkflowIDKey, "wf1")
+	ctx = context.WithValue(ctx, hermod.NodeIDKey, "node1")
+
+	config := map[string]any{
+		"mps":      0.01,
+		"burst":    2,
+		"scope":    "distributed",
+		"strategy": "drop",
+		"keyField": "tenant",
+	}
+
+	// Two transformers stand in for two workers running the same workflow.
+	workers := []*RateLimitTransformer{{}, {}}
+	passed := 0
+	for i := 0; i < 4; i++ {
+		msg := message.AcquireMessage()
+		msg.SetData("tenant", "acme")
+		res, err := workers[i%2].Transform(ctx, msg, config)
+		if err != nil {
+			t.Fatalf("Transform failed: %v", err)
+		}
+		if res != nil {
+			passed++
+		}
+	}
+	if passed != 2 {
+		t.Errorf("expected the workers to share a burst of 2, %d messages passed", passed)
+	}
+
+	// Another tenant has its own bucket.
+	msg := message.AcquireMessage()
+	msg.SetData("tenant", "globex")
+	if res, _ := workers[0].Transform(ctx, msg, config); res == nil {
+		t.Error("expected a separate budget per key")
+	}
+}
+
+func TestRateLimitTransformer_DistributedRequiresStore(t *testing.T) {
+	msg := message.AcquireMessage()
+	_, err := (&RateLimitTransformer{}).Transform(t.Context(), msg, map[string]any{"scope": "distributed"})
+	if err == nil {
+		t.Error("expected an error without a rate-limiting state store")
+	}
+}
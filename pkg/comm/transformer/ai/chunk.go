@@ -0,0 +1,203 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// chunkText splits text into chunks of at most size runes using the named
+// strategy: "fixed" windows that overlap by overlap runes, "sentence" packs
+// whole sentences, and "markdown" packs paragraphs within heading sections,
+// repeating the section heading at the top of each of its chunks.
+func chunkText(text, strategy string, size, overlap int) ([]string, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", size)
+	}
+	if overlap < 0 || overlap >= size {
+		return nil, fmt.Errorf("chunk overlap must be between 0 and %d, got %d", size-1, overlap)
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	switch strategy {
+	case "", "fixed":
+		return chunkFixed(text, size, overlap), nil
+	case "sentence":
+		return packSegments(splitSentences(text), " ", size, overlap), nil
+	case "markdown":
+		return chunkMarkdown(text, size, overlap), nil
+	default:
+		return nil, fmt.Errorf("unsupported chunking strategy: %s", strategy)
+	}
+}
+
+func chunkFixed(text string, size, overlap int) []string {
+	runes := []rune(text)
+	var chunks []string
+	for start := 0; start < len(runes); start += size - overlap {
+		end := min(start+size, len(runes))
+		if c := strings.TrimSpace(string(runes[start:end])); c != "" {
+			chunks = append(chunks, c)
+		}
+		if end == len(runes) {
+			break
+		}
+	}
+	return chunks
+}
+
+// splitSentences breaks text after sentence-ending punctuation that is
+// followed by whitespace, and at blank lines.
+func splitSentences(text string) []string {
+	var out []string
+	var b strings.Builder
+	runes := []rune(text)
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			out = append(out, s)
+		}
+		b.Reset()
+	}
+	for i, r := range runes {
+		b.WriteRune(r)
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case (r == '.' || r == '!' || r == '?') && (next == 0 || unicode.IsSpace(next)):
+			flush()
+		case r == '\n' && next == '\n':
+			flush()
+		}
+	}
+	flush()
+	return out
+}
+
+// packSegments joins consecutive segments with sep into chunks of at most
+// size runes. Each chunk starts with as many trailing segments of the
+// previous chunk as fit in overlap runes; a segment longer than size is cut
+// into fixed windows.
+func packSegments(segments []string, sep string, size, overlap int) []string {
+	sepLen := utf8.RuneCountInString(sep)
+	var chunks []string
+	var cur []string
+	curLen, fresh := 0, 0
+
+	emit := func() {
+		if fresh == 0 {
+			return
+		}
+		chunks = append(chunks, strings.Join(cur, sep))
+		// Carry the tail of this chunk over as the overlap of the next one.
+		start, kept := len(cur), 0
+		for start > 0 {
+			l := utf8.RuneCountInString(cur[start-1])
+			if kept+l > overlap {
+				break
+			}
+			kept += l + sepLen
+			start--
+		}
+		cur = append([]string(nil), cur[start:]...)
+		curLen, fresh = kept, 0
+	}
+
+	for _, seg := range segments {
+		l := utf8.RuneCountInString(seg)
+		if l > size {
+			emit()
+			cur, curLen = nil, 0
+			chunks = append(chunks, chunkFixed(seg, size, overlap)...)
+			continue
+		}
+		if curLen+l > size {
+			emit()
+			if curLen+l > size {
+				cur, curLen = nil, 0
+			}
+		}
+		cur = append(cur, seg)
+		curLen += l + sepLen
+		fresh++
+	}
+	emit()
+	return chunks
+}
+
+// chunkMarkdown splits a markdown document into heading sections and packs
+// each section's paragraphs separately, so a chunk never spans two sections.
+func chunkMarkdown(text string, size, overlap int) []string {
+	type section struct {
+		heading string
+		body    []string
+	}
+	var sections []section
+	cur := section{}
+	var para []string
+	inFence := false
+
+	flushPara := func() {
+		if p := strings.TrimSpace(strings.Join(para, "\n")); p != "" {
+			cur.body = append(cur.body, p)
+		}
+		para = nil
+	}
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		switch {
+		case !inFence && strings.HasPrefix(trimmed, "#"):
+			flushPara()
+			if cur.heading != "" || len(cur.body) > 0 {
+				sections = append(sections, cur)
+			}
+			cur = section{heading: trimmed}
+		case !inFence && trimmed == "":
+			flushPara()
+		default:
+			para = append(para, line)
+		}
+	}
+	flushPara()
+	if cur.heading != "" || len(cur.body) > 0 {
+		sections = append(sections, cur)
+	}
+
+	var chunks []string
+	for _, s := range sections {
+		if len(s.body) == 0 {
+			chunks = append(chunks, s.heading)
+			continue
+		}
+		budget := size
+		if s.heading != "" {
+			budget -= utf8.RuneCountInString(s.heading) + 2
+		}
+		if budget <= overlap {
+			budget = size
+			s.heading = ""
+		}
+		var segments []string
+		for _, p := range s.body {
+			if utf8.RuneCountInString(p) > budget {
+				segments = append(segments, splitSentences(p)...)
+			} else {
+				segments = append(segments, p)
+			}
+		}
+		for _, c := range packSegments(segments, "\n\n", budget, overlap) {
+			if s.heading != "" {
+				c = s.heading + "\n\n" + c
+			}
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks
+}
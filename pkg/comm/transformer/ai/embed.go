@@ -0,0 +1,366 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
+	"github.com/user/hermod/pkg/comm/transformer"
+	"github.com/user/hermod/pkg/infra/evaluator"
+)
+
+func init() {
+	transformer.Register("embed", &EmbedTransformer{})
+}
+
+const (
+	defaultChunkSize      = 1000
+	defaultChunkOverlap   = 100
+	defaultEmbedBatchSize = 16
+	defaultHashDimensions = 256
+	embedCacheSize        = 10000
+)
+
+// EmbedTransformer splits a long text field into chunks and computes an
+// embedding for each one, so the vector sinks receive one record per chunk.
+//
+// Every chunk gets a stable id derived from the document key and the chunk's
+// position, so re-embedding a changed document overwrites its chunks in
+// place. The ids emitted for each document are remembered in the workflow's
+// state store; when a new version yields fewer chunks, or the document is
+// deleted, the leftover ids are emitted as deletes.
+type EmbedTransformer struct {
+	once   sync.Once
+	client *http.Client
+	cache  *embeddingCache
+
+	// docs tracks chunk ids per document when no state store is available.
+	docs sync.Map
+}
+
+func (t *EmbedTransformer) init() {
+	t.once.Do(func() {
+		if t.client == nil {
+			t.client = &http.Client{Timeout: 30 * time.Second}
+		}
+		t.cache = newEmbeddingCache(embedCacheSize)
+	})
+}
+
+// Transform chunks and embeds the configured field and records the result
+// under targetField (default "_chunks"): one entry per chunk with its id,
+// index, text and vector, followed by {"id", "op": "delete"} entries for the
+// chunk ids the document no longer has.
+func (t *EmbedTransformer) Transform(ctx context.Context, msg hermod.Message, config map[string]any) (hermod.Message, error) {
+	if msg == nil {
+		return nil, nil
+	}
+	t.init()
+
+	field, _ := config["field"].(string)
+	if field == "" {
+		return nil, errors.New("embed: field is required")
+	}
+	strategy, _ := config["strategy"].(string)
+	size := configInt(config, "chunkSize", defaultChunkSize)
+	overlap := configInt(config, "chunkOverlap", defaultChunkOverlap)
+	if _, ok := config["chunkOverlap"]; !ok && overlap >= size {
+		overlap = size / 10
+	}
+
+	docKey := msg.ID()
+	if idField, _ := config["idField"].(string); idField != "" {
+		if v := evaluator.GetMsgValByPath(msg, idField); v != nil {
+			docKey = fmt.Sprintf("%v", v)
+		}
+	}
+	if docKey == "" {
+		return nil, errors.New("embed: message has no document key; set idField")
+	}
+
+	var texts []string
+	if msg.Operation() != hermod.OpDelete {
+		raw := evaluator.GetMsgValByPath(msg, field)
+		if raw == nil {
+			return nil, fmt.Errorf("embed: field %s not found", field)
+		}
+		text, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("embed: field %s is not a string", field)
+		}
+		var err error
+		texts, err = chunkText(text, strategy, size, overlap)
+		if err != nil {
+			return nil, fmt.Errorf("embed: %w", err)
+		}
+	}
+
+	vectors, err := t.embed(ctx, config, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+
+	ids := make([]string, len(texts))
+	chunks := make([]any, 0, len(texts))
+	for i, text := range texts {
+		ids[i] = chunkID(docKey, i)
+		chunks = append(chunks, map[string]any{
+			"id":     ids[i],
+			"index":  i,
+			"text":   text,
+			"vector": vectors[i],
+		})
+	}
+
+	stale, err := t.trackChunks(ctx, docKey, ids)
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	for _, id := range stale {
+		chunks = append(chunks, map[string]any{"id": id, "op": "delete"})
+	}
+
+	targetField, _ := config["targetField"].(string)
+	if targetField == "" {
+		targetField = "_chunks"
+	}
+	msg.SetData(targetField, chunks)
+	msg.SetData("_document_id", docKey)
+	return msg, nil
+}
+
+// Split emits the chunks recorded by Transform as separate messages. Chunk
+// messages keep the source message's fields, replace the text field with the
+// chunk text and add the chunk id (as "id", or idField's name when set),
+// document_id, chunk_index, chunk_total and the vector under vectorField
+// (default "embedding"). Stale chunks become delete messages keyed by their
+// chunk id.
+func (t *EmbedTransformer) Split(msg hermod.Message, config map[string]any) []hermod.Message {
+	targetField, _ := config["targetField"].(string)
+	if targetField == "" {
+		targetField = "_chunks"
+	}
+	field, _ := config["field"].(string)
+	vectorField, _ := config["vectorField"].(string)
+	if vectorField == "" {
+		vectorField = "embedding"
+	}
+	chunkIDField, _ := config["chunkIdField"].(string)
+	if chunkIDField == "" {
+		chunkIDField = "id"
+	}
+
+	data := msg.Data()
+	entries, _ := data[targetField].([]any)
+	docKey, _ := data["_document_id"].(string)
+
+	total := 0
+	for _, e := range entries {
+		if c, ok := e.(map[string]any); ok && c["op"] != "delete" {
+			total++
+		}
+	}
+
+	op := msg.Operation()
+	if op == "" || op == hermod.OpDelete {
+		op = hermod.OpCreate
+	}
+
+	out := make([]hermod.Message, 0, len(entries))
+	for i, e := range entries {
+		c, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		id, _ := c["id"].(string)
+
+		m := message.AcquireMessage()
+		m.SetID(id)
+		m.SetTable(msg.Table())
+		m.SetSchema(msg.Schema())
+		for k, v := range msg.Metadata() {
+			m.SetMetadata(k, v)
+		}
+		m.SetMetadata("_fanout_group", msg.ID())
+		m.SetMetadata("_fanout_index", strconv.Itoa(i))
+		m.SetMetadata("_fanout_total", strconv.Itoa(len(entries)))
+
+		if c["op"] == "delete" {
+			m.SetOperation(hermod.OpDelete)
+			m.SetData(chunkIDField, id)
+			m.SetData("document_id", docKey)
+			out = append(out, m)
+			continue
+		}
+
+		m.SetOperation(op)
+		for k, v := range data {
+			if k == targetField || k == "_document_id" {
+				continue
+			}
+			m.SetData(k, v)
+		}
+		if field != "" {
+			m.SetData(field, c["text"])
+		}
+		m.SetData(chunkIDField, id)
+		m.SetData("document_id", docKey)
+		m.SetData("chunk_index", c["index"])
+		m.SetData("chunk_total", total)
+		m.SetData(vectorField, c["vector"])
+		out = append(out, m)
+	}
+	return out
+}
+
+// embed returns one vector per text, serving repeated content from the cache
+// and sending the rest to the provider in batches.
+func (t *EmbedTransformer) embed(ctx context.Context, config map[string]any, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	e, cacheKey, err := t.embedder(config)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float64, len(texts))
+	keys := make([]string, len(texts))
+	var missing []int
+	for i, text := range texts {
+		sum := sha256.Sum256([]byte(cacheKey + "\x00" + text))
+		keys[i] = hex.EncodeToString(sum[:])
+		if v, ok := t.cache.get(keys[i]); ok {
+			vectors[i] = v
+		} else {
+			missing = append(missing, i)
+		}
+	}
+
+	batchSize := configInt(config, "batchSize", defaultEmbedBatchSize)
+	if batchSize <= 0 {
+		batchSize = defaultEmbedBatchSize
+	}
+	for start := 0; start < len(missing); start += batchSize {
+		batch := missing[start:min(start+batchSize, len(missing))]
+		in := make([]string, len(batch))
+		for j, i := range batch {
+			in[j] = texts[i]
+		}
+		res, err := e.Embed(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range batch {
+			vectors[i] = res[j]
+			t.cache.put(keys[i], res[j])
+		}
+	}
+	return vectors, nil
+}
+
+// embedder builds the configured provider. The returned key identifies the
+// provider, endpoint, model and dimensions, and prefixes cache keys so that
+// vectors from different models never mix.
+func (t *EmbedTransformer) embedder(config map[string]any) (embedder, string, error) {
+	provider, _ := config["provider"].(string)
+	endpoint, _ := config["endpoint"].(string)
+	apiKey, _ := config["apiKey"].(string)
+	model, _ := config["model"].(string)
+	dimensions := configInt(config, "dimensions", 0)
+
+	var e embedder
+	switch provider {
+	case "openai", "":
+		provider = "openai"
+		if endpoint == "" {
+			endpoint = "https://api.openai.com/v1/embeddings"
+		}
+		if model == "" {
+			model = "text-embedding-3-small"
+		}
+		e = &openAIEmbedder{client: t.client, endpoint: endpoint, apiKey: apiKey, model: model, dimensions: dimensions}
+	case "ollama":
+		if endpoint == "" {
+			endpoint = "http://localhost:11434/api/embed"
+		}
+		if model == "" {
+			model = "nomic-embed-text"
+		}
+		e = &ollamaEmbedder{client: t.client, endpoint: endpoint, model: model}
+	case "hash":
+		if dimensions <= 0 {
+			dimensions = defaultHashDimensions
+		}
+		e = &hashEmbedder{dimensions: dimensions}
+	default:
+		return nil, "", fmt.Errorf("unsupported embedding provider: %s", provider)
+	}
+	return e, fmt.Sprintf("%s\x00%s\x00%s\x00%d", provider, endpoint, model, dimensions), nil
+}
+
+// trackChunks records ids as the current chunks of docKey and returns the
+// previously recorded ids that are no longer among them.
+func (t *EmbedTransformer) trackChunks(ctx context.Context, docKey string, ids []string) ([]string, error) {
+	workflowID, _ := ctx.Value(hermod.WorkflowIDKey).(string)
+	nodeID, _ := ctx.Value(hermod.NodeIDKey).(string)
+	stateKey := fmt.Sprintf("embed:%s:%s:%s", workflowID, nodeID, docKey)
+
+	var previous []string
+	store, _ := ctx.Value(hermod.StateStoreKey).(hermod.StateStore)
+	if store != nil {
+		data, err := store.Get(ctx, stateKey)
+		if err == nil && data != nil {
+			_ = json.Unmarshal(data, &previous)
+		}
+	} else if v, ok := t.docs.Load(stateKey); ok {
+		previous = v.([]string)
+	}
+
+	current := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		current[id] = true
+	}
+	var stale []string
+	for _, id := range previous {
+		if !current[id] {
+			stale = append(stale, id)
+		}
+	}
+
+	if store != nil {
+		if len(ids) == 0 {
+			return stale, store.Delete(ctx, stateKey)
+		}
+		data, _ := json.Marshal(ids)
+		return stale, store.Set(ctx, stateKey, data)
+	}
+	if len(ids) == 0 {
+		t.docs.Delete(stateKey)
+	} else {
+		t.docs.Store(stateKey, ids)
+	}
+	return stale, nil
+}
+
+// chunkID derives a chunk's id from its document key and position.
+func chunkID(docKey string, index int) string {
+	sum := sha256.Sum256([]byte(docKey + "\x00" + strconv.Itoa(index)))
+	return hex.EncodeToString(sum[:16])
+}
+
+func configInt(config map[string]any, key string, def int) int {
+	if v, ok := evaluator.ToInt64(config[key]); ok {
+		return int(v)
+	}
+	return def
+}
@@ -0,0 +1,204 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
+)
+
+func TestChunkText_Fixed(t *testing.T) {
+	chunks, err := chunkText("abcdefghij", "fixed", 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"abcd", "defg", "ghij"}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, chunks)
+	}
+
+	if _, err := chunkText("abc", "fixed", 4, 4); err == nil {
+		t.Error("expected error for overlap >= size")
+	}
+}
+
+func TestChunkText_Sentence(t *testing.T) {
+	text := "First sentence here. Second one! Third? Fourth sentence is last."
+	chunks, err := chunkText(text, "sentence", 40, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"First sentence here. Second one! Third?", "Fourth sentence is last."}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q, got %q", want, chunks)
+	}
+
+	// With overlap, the last sentence of a chunk starts the next one.
+	chunks, _ = chunkText(text, "sentence", 40, 10)
+	if len(chunks) < 2 || !strings.HasPrefix(chunks[1], "Third?") {
+		t.Errorf("expected overlap to carry %q, got %q", "Third?", chunks)
+	}
+}
+
+func TestChunkText_Markdown(t *testing.T) {
+	text := "# Intro\n\nHermod moves data.\n\n## Setup\n\nInstall it.\n\n```\n# not a heading\n```\n"
+	chunks, err := chunkText(text, "markdown", 200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("expected one chunk per section, got %q", chunks)
+	}
+	if chunks[0] != "# Intro\n\nHermod moves data." {
+		t.Errorf("unexpected first chunk %q", chunks[0])
+	}
+	if !strings.HasPrefix(chunks[1], "## Setup\n\nInstall it.") || !strings.Contains(chunks[1], "# not a heading") {
+		t.Errorf("unexpected second chunk %q", chunks[1])
+	}
+}
+
+func TestHashEmbedder_Deterministic(t *testing.T) {
+	e := &hashEmbedder{dimensions: 64}
+	a, _ := e.Embed(context.Background(), []string{"the quick brown fox", "the quick brown fox", "lorem ipsum"})
+	if len(a) != 3 || len(a[0]) != 64 {
+		t.Fatalf("unexpected shape %d x %d", len(a), len(a[0]))
+	}
+	for i := range a[0] {
+		if a[0][i] != a[1][i] {
+			t.Fatal("expected identical texts to embed identically")
+		}
+	}
+	var norm float64
+	for _, x := range a[2] {
+		norm += x * x
+	}
+	if norm < 0.999 || norm > 1.001 {
+		t.Errorf("expected unit vector, got squared norm %f", norm)
+	}
+}
+
+func TestEmbedTransformer_BatchesAndCaches(t *testing.T) {
+	var requests, inputs atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var req struct {
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		inputs.Add(int32(len(req.Input)))
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}
+		var data []item
+		for i, in := range req.Input {
+			data = append(data, item{Index: i, Embedding: []float64{float64(len(in)), 1}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer server.Close()
+
+	tf := &EmbedTransformer{}
+	config := map[string]any{
+		"provider":     "openai",
+		"endpoint":     server.URL + "/v1/embeddings",
+		"field":        "body",
+		"chunkSize":    float64(5),
+		"chunkOverlap": float64(0),
+		"batchSize":    float64(2),
+	}
+
+	msg := message.AcquireMessage()
+	msg.SetID("doc-1")
+	msg.SetData("body", "aaaaabbbbbcccccddddd")
+	if _, err := tf.Transform(context.Background(), msg, config); err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if requests.Load() != 2 || inputs.Load() != 4 {
+		t.Errorf("expected 4 chunks in 2 batches, got %d inputs in %d requests", inputs.Load(), requests.Load())
+	}
+
+	// Unchanged content is served from the cache.
+	msg2 := message.AcquireMessage()
+	msg2.SetID("doc-2")
+	msg2.SetData("body", "aaaaabbbbb")
+	if _, err := tf.Transform(context.Background(), msg2, config); err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("expected cached chunks not to be re-embedded, got %d requests", requests.Load())
+	}
+}
+
+func TestEmbedTransformer_SplitAndStaleDeletes(t *testing.T) {
+	tf := &EmbedTransformer{}
+	config := map[string]any{
+		"provider":     "hash",
+		"dimensions":   8,
+		"field":        "body",
+		"idField":      "doc_id",
+		"chunkSize":    10,
+		"chunkOverlap": 0,
+		"vectorField":  "values",
+	}
+	ctx := context.WithValue(context.Background(), hermod.NodeIDKey, "embed-1")
+
+	run := func(body string, op hermod.Operation) []hermod.Message {
+		msg := message.AcquireMessage()
+		msg.SetID("evt")
+		msg.SetOperation(op)
+		msg.SetData("doc_id", "42")
+		msg.SetData("title", "Guide")
+		msg.SetData("body", body)
+		res, err := tf.Transform(ctx, msg, config)
+		if err != nil {
+			t.Fatalf("Transform failed: %v", err)
+		}
+		return tf.Split(res, config)
+	}
+
+	first := run("one two. three four. five six.", hermod.OpCreate)
+	if len(first) != 3 {
+		t.Fatalf("expected 3 chunk messages, got %d", len(first))
+	}
+	for i, m := range first {
+		d := m.Data()
+		if m.ID() != chunkID("42", i) || d["id"] != m.ID() {
+			t.Errorf("chunk %d: unexpected id %q / %v", i, m.ID(), d["id"])
+		}
+		if d["document_id"] != "42" || d["title"] != "Guide" || d["chunk_total"] != 3 {
+			t.Errorf("chunk %d: unexpected data %v", i, d)
+		}
+		if v, ok := d["values"].([]float64); !ok || len(v) != 8 {
+			t.Errorf("chunk %d: expected an 8-dimensional vector, got %v", i, d["values"])
+		}
+		if _, ok := d["_chunks"]; ok {
+			t.Errorf("chunk %d: chunk list leaked into the chunk message", i)
+		}
+	}
+
+	// The document shrinks: the third chunk id must be deleted downstream.
+	second := run("one two. three four.", hermod.OpUpdate)
+	if len(second) != 3 {
+		t.Fatalf("expected 2 upserts and 1 delete, got %d messages", len(second))
+	}
+	del := second[2]
+	if del.Operation() != hermod.OpDelete || del.ID() != first[2].ID() {
+		t.Errorf("expected delete of %q, got %s %q", first[2].ID(), del.Operation(), del.ID())
+	}
+	if second[0].ID() != first[0].ID() {
+		t.Error("expected chunk ids to be stable across versions")
+	}
+
+	// Deleting the document deletes all of its remaining chunks.
+	third := run("", hermod.OpDelete)
+	if len(third) != 2 || third[0].Operation() != hermod.OpDelete || third[1].Operation() != hermod.OpDelete {
+		t.Fatalf("expected 2 deletes, got %d messages", len(third))
+	}
+}
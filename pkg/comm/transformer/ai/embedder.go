@@ -0,0 +1,208 @@
+package ai
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// embedder turns a batch of texts into one vector per text, in order.
+type embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// openAIEmbedder calls an OpenAI-compatible /v1/embeddings endpoint, which
+// accepts the whole batch as one input array.
+type openAIEmbedder struct {
+	client     *http.Client
+	endpoint   string
+	apiKey     string
+	model      string
+	dimensions int
+}
+
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	reqBody := map[string]any{
+		"model": e.model,
+		"input": texts,
+	}
+	if e.dimensions > 0 {
+		reqBody["dimensions"] = e.dimensions
+	}
+
+	var res struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := postJSON(ctx, e.client, e.endpoint, e.apiKey, "openai", reqBody, &res); err != nil {
+		return nil, err
+	}
+
+	out := make([][]float64, len(texts))
+	for _, d := range res.Data {
+		if d.Index < 0 || d.Index >= len(out) {
+			return nil, fmt.Errorf("openai returned embedding for unknown input %d", d.Index)
+		}
+		out[d.Index] = d.Embedding
+	}
+	for i, v := range out {
+		if v == nil {
+			return nil, fmt.Errorf("openai returned no embedding for input %d", i)
+		}
+	}
+	return out, nil
+}
+
+// ollamaEmbedder calls Ollama's /api/embed endpoint.
+type ollamaEmbedder struct {
+	client   *http.Client
+	endpoint string
+	model    string
+}
+
+func (e *ollamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	reqBody := map[string]any{
+		"model": e.model,
+		"input": texts,
+	}
+
+	var res struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := postJSON(ctx, e.client, e.endpoint, "", "ollama", reqBody, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(res.Embeddings), len(texts))
+	}
+	return res.Embeddings, nil
+}
+
+func postJSON(ctx context.Context, client *http.Client, endpoint, apiKey, provider string, body, out any) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s error (status %d): %s", provider, resp.StatusCode, string(b))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// hashEmbedder is a deterministic local embedder for tests and offline
+// pipelines. Each lowercased word is hashed into one of dimensions buckets
+// with a hash-derived sign, and the result is L2-normalized, so texts that
+// share words have a positive cosine similarity.
+type hashEmbedder struct {
+	dimensions int
+}
+
+func (e *hashEmbedder) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	out := make([][]float64, len(texts))
+	for i, text := range texts {
+		vec := make([]float64, e.dimensions)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, w := range words {
+			h := fnv.New64a()
+			_, _ = h.Write([]byte(w))
+			sum := h.Sum64()
+			if sum&(1<<63) != 0 {
+				vec[sum%uint64(e.dimensions)]--
+			} else {
+				vec[sum%uint64(e.dimensions)]++
+			}
+		}
+		var norm float64
+		for _, x := range vec {
+			norm += x * x
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j := range vec {
+				vec[j] /= norm
+			}
+		}
+		out[i] = vec
+	}
+	return out, nil
+}
+
+// embeddingCache is a fixed-size LRU of vectors keyed by content hash.
+type embeddingCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key    string
+	vector []float64
+}
+
+func newEmbeddingCache(capacity int) *embeddingCache {
+	return &embeddingCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *embeddingCache) get(key string) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		return el.Value.(*cacheEntry).vector, true
+	}
+	return nil, false
+}
+
+func (c *embeddingCache) put(key string, vector []float64) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*cacheEntry).vector = vector
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, vector: vector})
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
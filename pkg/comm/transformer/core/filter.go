@@ -19,11 +19,32 @@ func init() {
 type FilterTransformer struct{}
 
 func (t *FilterTransformer) Prepare(config map[string]any) (map[string]any, error) {
+	conditions := filterConditions(config)
+	if err := evaluator.CheckConditions(conditions); err != nil {
+		return nil, err
+	}
+	if len(conditions) > 0 {
+		config["_parsed_conditions"] = conditions
+	}
+	return config, nil
+}
+
+// CheckExpressions implements transformer.ExpressionTransformer.
+func (t *FilterTransformer) CheckExpressions(config map[string]any) error {
+	return evaluator.CheckConditions(filterConditions(config))
+}
+
+// filterConditions reads the conditions array, an expression such as
+// "amount > 10000", or the older single field, operator and value.
+func filterConditions(config map[string]any) []map[string]any {
 	conditionsStr, _ := config["conditions"].(string)
 	var conditions []map[string]any
 	if conditionsStr != "" {
 		_ = json.Unmarshal([]byte(conditionsStr), &conditions)
 	}
+	if expr, _ := config["expression"].(string); expr != "" {
+		conditions = append(conditions, map[string]any{"expression": expr})
+	}
 
 	// Fallback to old format if no conditions array
 	if len(conditions) == 0 {
@@ -38,11 +59,7 @@ func (t *FilterTransformer) Prepare(config map[string]any) (map[string]any, erro
 			})
 		}
 	}
-
-	if len(conditions) > 0 {
-		config["_parsed_conditions"] = conditions
-	}
-	return config, nil
+	return conditions
 }
 
 func (t *FilterTransformer) Transform(ctx context.Context, msg hermod.Message, config map[string]any) (hermod.Message, error) {
@@ -52,31 +69,15 @@ func (t *FilterTransformer) Transform(ctx context.Context, msg hermod.Message, c
 
 	transType, _ := config["transType"].(string)
 
-	var conditions []map[string]any
-	if cached, ok := config["_parsed_conditions"].([]map[string]any); ok {
-		conditions = cached
-	} else {
-		conditionsStr, _ := config["conditions"].(string)
-		if conditionsStr != "" {
-			_ = json.Unmarshal([]byte(conditionsStr), &conditions)
-		}
-
-		// Fallback to old format if no conditions array
-		if len(conditions) == 0 {
-			field, _ := config["field"].(string)
-			op, _ := config["operator"].(string)
-			val, _ := config["value"].(string)
-			if field != "" {
-				conditions = append(conditions, map[string]any{
-					"field":    field,
-					"operator": op,
-					"value":    val,
-				})
-			}
-		}
+	conditions, ok := config["_parsed_conditions"].([]map[string]any)
+	if !ok {
+		conditions = filterConditions(config)
 	}
 
-	isValid := evaluator.EvaluateConditions(msg, conditions)
+	isValid, err := evaluator.MatchConditions(msg, conditions)
+	if err != nil {
+		return nil, err
+	}
 	asField := evaluator.ToBool(config["asField"]) || transType == "validate"
 
 	if asField {
@@ -0,0 +1,177 @@
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/hermod"
+	grpcsink "github.com/user/hermod/pkg/comm/sink/grpc"
+	"github.com/user/hermod/pkg/comm/transformer"
+	"github.com/user/hermod/pkg/comm/transformer/core"
+	"github.com/user/hermod/pkg/infra/evaluator"
+)
+
+func init() {
+	transformer.Register("grpc_call", &GrpcCallTransformer{
+		clients: make(map[string]*grpcClient),
+	})
+}
+
+// GrpcCallTransformer runs the grpc sink as a transformer: it calls the
+// configured method with the message and puts the response back into the
+// message, so a workflow can continue with it.
+//
+// It takes the grpc sink's settings under the same keys, such as target,
+// proto_files and request_template. The camelCase keys earlier versions
+// read, such as protoFiles, are still accepted.
+type GrpcCallTransformer struct {
+	mu sync.Mutex
+	// clients holds the connection of each node, by workflow and node ID.
+	clients map[string]*grpcClient
+}
+
+// grpcClient is a node's connection and the settings it was made with.
+type grpcClient struct {
+	settings string
+	sink     *grpcsink.GrpcSink
+}
+
+// grpcSettings are the settings a connection is made with.
+var grpcSettings = []string{"target", "proto_files", "import_paths", "method", "request_template", "metadata",
+	"timeout", "tls", "ca_cert_pem", "client_cert_pem", "client_key_pem", "server_name", "insecure_skip_verify"}
+
+func (t *GrpcCallTransformer) Transform(ctx context.Context, msg hermod.Message, config map[string]any) (hermod.Message, error) {
+	if msg == nil {
+		return nil, nil
+	}
+	client, release, err := t.client(ctx, config)
+	if err != nil {
+		return msg, err
+	}
+	defer release()
+
+	resp, err := client.Call(ctx, msg)
+	if err != nil {
+		return msg, err
+	}
+
+	var result any = resp
+	if p := grpcSetting(config, "response_path"); p != "" && p != "." {
+		result = evaluator.GetValByPath(resp, p)
+	}
+
+	// Without a target field the response's fields are merged into the message.
+	targetField := grpcSetting(config, "target_field")
+	if targetField != "" {
+		msg.SetData(targetField, result)
+		return msg, nil
+	}
+	if m, ok := result.(map[string]any); ok {
+		for k, v := range m {
+			msg.SetData(k, v)
+		}
+	}
+	return msg, nil
+}
+
+// client returns the connection of the node running the transformer, shared
+// by all its messages. A node whose settings changed gets a new connection and
+// the old one is closed. Outside a workflow, such as in previews, the
+// connection lasts for one call and release closes it.
+func (t *GrpcCallTransformer) client(ctx context.Context, config map[string]any) (c *grpcsink.GrpcSink, release func(), err error) {
+	parts := make([]string, len(grpcSettings))
+	for i, k := range grpcSettings {
+		parts[i] = grpcSetting(config, k)
+	}
+	settings := strings.Join(parts, "\x00")
+
+	workflowID, _ := ctx.Value(hermod.WorkflowIDKey).(string)
+	nodeID, _ := ctx.Value(hermod.NodeIDKey).(string)
+	if nodeID == "" {
+		c, err := newGrpcClient(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		return c, func() { _ = c.Close() }, nil
+	}
+	key := workflowID + ":" + nodeID
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	old := t.clients[key]
+	if old != nil && old.settings == settings {
+		return old.sink, func() {}, nil
+	}
+	c, err = newGrpcClient(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if old != nil {
+		_ = old.sink.Close()
+	}
+	t.clients[key] = &grpcClient{settings: settings, sink: c}
+	return c, func() {}, nil
+}
+
+// newGrpcClient makes a connection with the settings in config.
+func newGrpcClient(config map[string]any) (*grpcsink.GrpcSink, error) {
+	cfg := grpcsink.Config{
+		Target:          grpcSetting(config, "target"),
+		ProtoFiles:      core.SplitComma(grpcSetting(config, "proto_files")),
+		ImportPaths:     core.SplitComma(grpcSetting(config, "import_paths")),
+		Method:          grpcSetting(config, "method"),
+		RequestTemplate: grpcSetting(config, "request_template"),
+	}
+	// metadata is name:template pairs, as the sink takes it, or a JSON object
+	// of header name to template.
+	if m := strings.TrimSpace(grpcSetting(config, "metadata")); strings.HasPrefix(m, "{") {
+		if err := json.Unmarshal([]byte(m), &cfg.Metadata); err != nil {
+			return nil, fmt.Errorf("grpc_call: invalid metadata: %w", err)
+		}
+	} else if m != "" {
+		cfg.Metadata = make(map[string]string)
+		for _, pair := range strings.Split(m, ",") {
+			if name, val, ok := strings.Cut(pair, ":"); ok {
+				cfg.Metadata[strings.TrimSpace(name)] = strings.TrimSpace(val)
+			}
+		}
+	}
+	if s := grpcSetting(config, "timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("grpc_call: invalid timeout %q: %w", s, err)
+		}
+		cfg.Timeout = d
+	}
+	if grpcSetting(config, "tls") == "true" {
+		tlsCfg, err := grpcsink.TLSConfig(
+			grpcSetting(config, "ca_cert_pem"),
+			grpcSetting(config, "client_cert_pem"),
+			grpcSetting(config, "client_key_pem"),
+			grpcSetting(config, "server_name"),
+			strings.EqualFold(grpcSetting(config, "insecure_skip_verify"), "true"),
+		)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLS = tlsCfg
+	}
+	return grpcsink.NewGrpcSink(cfg)
+}
+
+// grpcSetting returns the setting under its snake_case key, or else under the
+// camelCase one.
+func grpcSetting(config map[string]any, key string) string {
+	if v := core.GetConfigString(config, key); v != "" {
+		return v
+	}
+	words := strings.Split(key, "_")
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	return core.GetConfigString(config, strings.Join(words, ""))
+}
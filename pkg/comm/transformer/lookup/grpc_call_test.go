@@ -0,0 +1,54 @@
+package lookup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/user/hermod"
+)
+
+func TestGrpcSettingReadsBothKeyStyles(t *testing.T) {
+	if got := grpcSetting(map[string]any{"request_template": "a", "requestTemplate": "b"}, "request_template"); got != "a" {
+		t.Errorf("snake_case key: got %q, want a", got)
+	}
+	if got := grpcSetting(map[string]any{"caCertPem": "pem"}, "ca_cert_pem"); got != "pem" {
+		t.Errorf("camelCase fallback: got %q, want pem", got)
+	}
+}
+
+func TestGrpcCallReplacesClientWhenSettingsChange(t *testing.T) {
+	tr := &GrpcCallTransformer{clients: make(map[string]*grpcClient)}
+	ctx := context.WithValue(context.WithValue(context.Background(), hermod.WorkflowIDKey, "wf"), hermod.NodeIDKey, "n1")
+	config := map[string]any{"target": "localhost:1", "method": "inv.Inventory/Reserve"}
+
+	first, _, err := tr.client(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, _, _ := tr.client(ctx, config)
+	if same != first {
+		t.Fatal("unchanged settings should reuse the node's client")
+	}
+
+	config["target"] = "localhost:2"
+	second, _, err := tr.client(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first {
+		t.Fatal("changed settings should replace the node's client")
+	}
+	if len(tr.clients) != 1 {
+		t.Fatalf("got %d cached clients, want 1", len(tr.clients))
+	}
+
+	// Outside a workflow nothing is cached.
+	if _, release, err := tr.client(context.Background(), config); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+	if len(tr.clients) != 1 {
+		t.Fatalf("preview call was cached: %d clients", len(tr.clients))
+	}
+}
@@ -24,6 +24,24 @@ type PreparedTransformer interface {
 	Prepare(config map[string]any) (map[string]any, error)
 }
 
+// ExpressionTransformer is implemented by transformers whose configuration
+// holds expressions. CheckExpressions reports the first one that does not
+// compile, so that workflows are rejected when they are saved.
+type ExpressionTransformer interface {
+	Transformer
+	CheckExpressions(config map[string]any) error
+}
+
+// Splitter is implemented by transformers whose result stands for several
+// messages, such as one message per text chunk. Transform materializes the
+// parts into the result so previews and pipelines can inspect them; a
+// transformation node then calls Split on that result to emit each part as
+// its own message.
+type Splitter interface {
+	Transformer
+	Split(msg hermod.Message, config map[string]any) []hermod.Message
+}
+
 // Registry manages the available transformers.
 type Registry struct {
 	transformers map[string]Transformer
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/buffer"
+	"github.com/user/hermod/pkg/comm/message"
+)
+
+// bufferAckSource records the order in which a message is reported buffered
+// and acknowledged.
+type bufferAckSource struct {
+	slowMockSource
+	mu     sync.Mutex
+	events []string
+	acked  chan struct{}
+}
+
+func (s *bufferAckSource) Buffered(_ context.Context, msg hermod.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, "buffered:"+msg.ID())
+}
+
+func (s *bufferAckSource) Ack(_ context.Context, msg hermod.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, "acked:"+msg.ID())
+	close(s.acked)
+	return nil
+}
+
+// A streaming producer is told a message is buffered before the sinks have
+// it, and its acknowledgement still follows once they do.
+func TestEngineReportsBufferedBeforeAck(t *testing.T) {
+	msg := message.AcquireMessage()
+	msg.SetID("m-1")
+	source := &bufferAckSource{
+		slowMockSource: slowMockSource{messages: []hermod.Message{msg}},
+		acked:          make(chan struct{}),
+	}
+	sink := &mockSink{received: make(chan hermod.Message, 1)}
+	eng := NewEngine(source, []hermod.Sink{sink}, buffer.NewRingBuffer(10))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	go func() { _ = eng.Start(ctx) }()
+
+	select {
+	case <-source.acked:
+	case <-ctx.Done():
+		t.Fatal("message was never acknowledged")
+	}
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if len(source.events) != 2 || source.events[0] != "buffered:m-1" || source.events[1] != "acked:m-1" {
+		t.Fatalf("expected buffered then acked, got %v", source.events)
+	}
+}
+
+// nackSource records negative acknowledgements.
+type nackSource struct {
+	bufferAckSource
+	nacked chan error
+}
+
+func (s *nackSource) Nack(_ context.Context, msg hermod.Message, err error) {
+	s.mu.Lock()
+	s.events = append(s.events, "nacked:"+msg.ID())
+	s.mu.Unlock()
+	s.nacked <- err
+}
+
+// A producer waiting for the outcome of a message is told when its sink
+// write fails, and the message is not acknowledged.
+func TestEngineNacksFailedWrites(t *testing.T) {
+	msg := message.AcquireMessage()
+	msg.SetID("m-1")
+	source := &nackSource{
+		bufferAckSource: bufferAckSource{
+			slowMockSource: slowMockSource{messages: []hermod.Message{msg}},
+			acked:          make(chan struct{}),
+		},
+		nacked: make(chan error, 1),
+	}
+	sink := &mockSink{fail: 1 << 30}
+	eng := NewEngine(source, []hermod.Sink{sink}, buffer.NewRingBuffer(10))
+	eng.SetSinkConfigs([]SinkConfig{{MaxRetries: 1, RetryInterval: time.Millisecond}})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	go func() { _ = eng.Start(ctx) }()
+
+	select {
+	case err := <-source.nacked:
+		if err == nil {
+			t.Fatal("nacked without an error")
+		}
+	case <-source.acked:
+		t.Fatal("a failed write was acknowledged")
+	case <-ctx.Done():
+		t.Fatal("the failed write was never reported")
+	}
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if len(source.events) != 2 || source.events[1] != "nacked:m-1" {
+		t.Fatalf("expected buffered then nacked, got %v", source.events)
+	}
+}
@@ -2,6 +2,9 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"strconv"
 	"sync"
 
 	"github.com/user/hermod"
@@ -45,15 +48,33 @@ func (m *CheckpointManager) Checkpoint(ctx context.Context) error {
 	// Wait for all in-flight messages to be processed and acknowledged by sinks
 	m.engine.inFlightWg.Wait()
 
+	// Publish what sinks have staged before recording the position, so a
+	// checkpoint never covers messages that are not yet visible.
+	for _, cc := range m.committers() {
+		if err := cc.CommitCheckpoint(ctx); err != nil {
+			m.engine.logger.Error("Checkpoint failed", "workflow_id", m.engine.workflowID, "error", err)
+			return err
+		}
+	}
+
 	// Collect source state if stateful
 	var sourceState map[string]string
 	if stateful, ok := m.engine.source.(hermod.Stateful); ok {
 		sourceState = stateful.GetState()
 	}
+	// Stamp the lease's fencing token so the handler can refuse a checkpoint
+	// taken by a worker that has since been fenced off.
+	if token := m.engine.fencingToken.Load(); token > 0 && sourceState != nil {
+		sourceState = maps.Clone(sourceState)
+		sourceState[hermod.FencingTokenKey] = strconv.FormatInt(token, 10)
+	}
 
 	// Call the checkpoint handler (e.g. to save node states in Registry)
 	if err := m.handler(ctx, sourceState); err != nil {
 		m.engine.logger.Error("Checkpoint failed", "workflow_id", m.engine.workflowID, "error", err)
+		if errors.Is(err, hermod.ErrFenced) {
+			return m.engine.stopFenced(err)
+		}
 		return err
 	}
 
@@ -61,6 +82,19 @@ func (m *CheckpointManager) Checkpoint(ctx context.Context) error {
 	return nil
 }
 
+// committers returns the engine's sinks that commit on checkpoints.
+func (m *CheckpointManager) committers() []hermod.CheckpointCommitter {
+	m.engine.mu.RLock()
+	defer m.engine.mu.RUnlock()
+	var out []hermod.CheckpointCommitter
+	for _, snk := range m.engine.sinks {
+		if cc, ok := snk.(hermod.CheckpointCommitter); ok {
+			out = append(out, cc)
+		}
+	}
+	return out
+}
+
 func (m *CheckpointManager) IsInCheckpoint() bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/buffer"
+)
+
+// stagingSink stages writes and publishes them on checkpoints.
+type stagingSink struct {
+	mu        sync.Mutex
+	staged    int
+	published int
+	events    *[]string
+	commitErr error
+}
+
+func (s *stagingSink) Write(ctx context.Context, msg hermod.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.staged++
+	return nil
+}
+func (s *stagingSink) CommitCheckpoint(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*s.events = append(*s.events, "commit")
+	if s.commitErr != nil {
+		return s.commitErr
+	}
+	s.published += s.staged
+	s.staged = 0
+	return nil
+}
+func (s *stagingSink) Ping(ctx context.Context) error { return nil }
+func (s *stagingSink) Close() error                   { return nil }
+
+func TestCheckpointCommitsSinksBeforeHandler(t *testing.T) {
+	var events []string
+	snk := &stagingSink{events: &events}
+	eng := NewEngine(&feedSource{ch: make(chan hermod.Message)}, []hermod.Sink{snk}, buffer.NewRingBuffer(4))
+	eng.SetConfig(Config{MaxRetries: 1, RetryInterval: time.Millisecond, StatusInterval: time.Second})
+	eng.SetCheckpointHandler(func(ctx context.Context, state map[string]string) error {
+		events = append(events, "handler")
+		return nil
+	})
+
+	snk.staged = 2
+	if err := eng.Checkpoint(t.Context()); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	if len(events) != 2 || events[0] != "commit" || events[1] != "handler" {
+		t.Fatalf("expected the sink to commit before the handler ran, got %v", events)
+	}
+	if snk.published != 2 {
+		t.Fatalf("expected 2 published writes, got %d", snk.published)
+	}
+
+	// A failed commit fails the checkpoint, so the position is not recorded.
+	events = nil
+	snk.commitErr = errors.New("catalog unavailable")
+	if err := eng.Checkpoint(t.Context()); !errors.Is(err, snk.commitErr) {
+		t.Fatalf("expected the commit error, got %v", err)
+	}
+	if len(events) != 1 || events[0] != "commit" {
+		t.Fatalf("expected the handler to be skipped, got %v", events)
+	}
+}
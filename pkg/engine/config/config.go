@@ -25,6 +25,11 @@ type Config struct {
 	// DrainTimeout controls how long to wait for sink writers to drain on shutdown before logging a warning.
 	// Does not forcibly terminate writers; set to 0 to wait indefinitely.
 	DrainTimeout time.Duration `json:"drain_timeout"`
+	// ThrottleTimeout is how long a write keeps waiting out a destination that
+	// throttles it before it fails like one out of retries. Throttled waits
+	// do not use up retries, so this is what bounds them. Set to 0 to use the
+	// default.
+	ThrottleTimeout time.Duration `json:"throttle_timeout"`
 	// StallThreshold is how long the pipeline may hold outstanding work without
 	// completing any of it before it is reported as stalled. A wedged pipeline
 	// is otherwise indistinguishable from an idle one: it keeps reporting
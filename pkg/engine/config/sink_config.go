@@ -14,6 +14,8 @@ type SinkConfig struct {
 	BatchBytes       int  `json:"batch_bytes"`
 	AdaptiveBatching bool `json:"adaptive_batching"`
 	Concurrency      int  `json:"concurrency"`
+	// ThrottleTimeout overrides the engine's throttle timeout for this sink.
+	ThrottleTimeout time.Duration `json:"throttle_timeout"`
 
 	// Per-key sharding for ordered concurrency
 	ShardCount   int    `json:"shard_count"`
@@ -32,8 +32,11 @@ type Engine struct {
 	router         RouterFunc
 	validator      schema.Validator
 	traceRecorder  hermod.TraceRecorder
+	dlqRecorder    hermod.DeadLetterRecorder
 	outboxStore    hermod.OutboxStorage
 	dqScorer       *governance.Scorer
+	// schemaStates keeps the schema state of sinks that applied a change.
+	schemaStates hermod.SchemaStateRecorder
 
 	workflowID string
 	sourceID   string
@@ -47,6 +50,12 @@ type Engine struct {
 	// resolved — silent data loss until it is reported.
 	unroutableCount   atomic.Int64
 	unroutableLastLog atomic.Pointer[time.Time]
+	// fatalErr is the first fatal-config error a sink reported; it stops the
+	// engine and becomes its exit error.
+	fatalErr atomic.Pointer[error]
+	// fencingToken is the token of the workflow lease this engine runs
+	// under; zero when it runs unfenced. See SetFencingToken.
+	fencingToken atomic.Int64
 
 	// Internal state tracking (Facade components)
 	statusTracker *telemetry.StatusTracker
@@ -232,6 +241,20 @@ func (e *Engine) SetTraceRecorder(tr hermod.TraceRecorder) {
 	e.traceRecorder = tr
 }
 
+// SetDeadLetterRecorder registers a store that keeps a copy of every
+// dead-lettered message. Without a dead-letter sink, messages that fail are
+// kept in it alone rather than failing the write.
+func (e *Engine) SetDeadLetterRecorder(r hermod.DeadLetterRecorder) {
+	e.dlqRecorder = r
+}
+
+// SetSchemaStateRecorder registers a store that keeps the schema state of
+// every sink that applied a schema change, so the sinks of the next run can
+// carry on with it.
+func (e *Engine) SetSchemaStateRecorder(r hermod.SchemaStateRecorder) {
+	e.schemaStates = r
+}
+
 // SetOnStall registers a supervisor for this engine. The watchdog calls it once
 // per stall episode, on its own goroutine, when the pipeline is holding work it
 // has stopped completing.
@@ -281,6 +304,20 @@ func (e *Engine) SetSinkTypes(types []string) {
 	e.sinkTypes = types
 }
 
+// SetFencingToken records the fencing token of the workflow lease this engine
+// runs under and hands it to every sink that implements hermod.FencedSink,
+// scoped by workflow ID. Call it after SetIDs. Checkpoints carry the token
+// too, so a worker that lost its lease can neither write nor checkpoint over
+// the one that took it.
+func (e *Engine) SetFencingToken(token int64) {
+	e.fencingToken.Store(token)
+	for _, snk := range e.sinks {
+		if fs, ok := snk.(hermod.FencedSink); ok {
+			fs.SetFencingToken(e.workflowID, token)
+		}
+	}
+}
+
 func (e *Engine) SetDeadLetterSink(snk hermod.Sink) {
 	e.deadLetterSink = snk
 }
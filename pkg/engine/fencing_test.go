@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/buffer"
+	"github.com/user/hermod/pkg/comm/message"
+)
+
+// fenceStore is a destination shared by two workers. Like the Postgres sink's
+// fencing table, it remembers the highest token written per scope.
+type fenceStore struct {
+	mu      sync.Mutex
+	highest map[string]int64
+	written []int64 // token of every accepted write, in order
+}
+
+func (d *fenceStore) write(scope string, token int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if token < d.highest[scope] {
+		return fmt.Errorf("%w: %d < %d", hermod.ErrFenced, token, d.highest[scope])
+	}
+	d.highest[scope] = token
+	d.written = append(d.written, token)
+	return nil
+}
+
+func (d *fenceStore) count(token int64) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, t := range d.written {
+		if t == token {
+			n++
+		}
+	}
+	return n
+}
+
+// fencedSink is one worker's connection to a fenceStore.
+type fencedSink struct {
+	dest  *fenceStore
+	scope string
+	token int64
+}
+
+func (s *fencedSink) SetFencingToken(scope string, token int64) { s.scope, s.token = scope, token }
+func (s *fencedSink) Write(ctx context.Context, msg hermod.Message) error {
+	return s.dest.write(s.scope, s.token)
+}
+func (s *fencedSink) Ping(ctx context.Context) error { return nil }
+func (s *fencedSink) Close() error                   { return nil }
+
+// feedSource hands out whatever is sent on its channel and reports a
+// checkpointable position.
+type feedSource struct {
+	ch chan hermod.Message
+}
+
+func (s *feedSource) Read(ctx context.Context) (hermod.Message, error) {
+	select {
+	case msg := <-s.ch:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+func (s *feedSource) Ack(ctx context.Context, msg hermod.Message) error { return nil }
+func (s *feedSource) Ping(ctx context.Context) error                    { return nil }
+func (s *feedSource) Close() error                                      { return nil }
+func (s *feedSource) GetState() map[string]string                       { return map[string]string{"lsn": "0/1"} }
+func (s *feedSource) SetState(state map[string]string)                  {}
+
+type fencedWorker struct {
+	engine      *Engine
+	source      *feedSource
+	done        chan error
+	mu          sync.Mutex
+	checkpoints []map[string]string
+}
+
+func startFencedWorker(t *testing.T, ctx context.Context, dest *fenceStore, token int64) *fencedWorker {
+	t.Helper()
+	w := &fencedWorker{source: &feedSource{ch: make(chan hermod.Message)}, done: make(chan error, 1)}
+	w.engine = NewEngine(w.source, []hermod.Sink{&fencedSink{dest: dest}}, buffer.NewRingBuffer(4))
+	w.engine.SetIDs("wf-partition", "src1", []string{"sink1"})
+	w.engine.SetConfig(Config{MaxRetries: 3, RetryInterval: time.Millisecond, StatusInterval: time.Second})
+	w.engine.SetCheckpointHandler(func(ctx context.Context, state map[string]string) error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.checkpoints = append(w.checkpoints, state)
+		return nil
+	})
+	w.engine.SetFencingToken(token)
+	go func() { w.done <- w.engine.Start(ctx) }()
+	return w
+}
+
+func (w *fencedWorker) send(t *testing.T, id string) {
+	t.Helper()
+	msg := message.AcquireMessage()
+	msg.SetID(id)
+	select {
+	case w.source.ch <- msg:
+	case err := <-w.done:
+		t.Fatalf("engine stopped before %s was read: %v", id, err)
+	case <-time.After(3 * time.Second):
+		t.Fatalf("engine did not read %s", id)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestPartitionedWorkerIsFencedOff simulates a network partition: worker A
+// keeps streaming after its lease expired and worker B took the workflow over
+// under a newer token. A's next write must be rejected, stopping A without a
+// final checkpoint, while B carries on.
+func TestPartitionedWorkerIsFencedOff(t *testing.T) {
+	dest := &fenceStore{highest: map[string]int64{}}
+
+	a := startFencedWorker(t, t.Context(), dest, 1)
+	a.send(t, "a1")
+	waitFor(t, "A's first write", func() bool { return dest.count(1) == 1 })
+
+	ctxB, stopB := context.WithCancel(t.Context())
+	defer stopB()
+	b := startFencedWorker(t, ctxB, dest, 2)
+	b.send(t, "b1")
+	waitFor(t, "B's first write", func() bool { return dest.count(2) == 1 })
+
+	// A has not heard that it lost the lease and writes again.
+	a.send(t, "a2")
+	select {
+	case err := <-a.done:
+		if !errors.Is(err, hermod.ErrFenced) {
+			t.Fatalf("expected A to stop fenced off, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected A to stop once its token was rejected")
+	}
+	if n := dest.count(1); n != 1 {
+		t.Fatalf("expected no writes from A after the takeover, got %d", n)
+	}
+	a.mu.Lock()
+	if len(a.checkpoints) != 0 {
+		t.Fatalf("a fenced-off engine must not checkpoint, got %v", a.checkpoints)
+	}
+	a.mu.Unlock()
+
+	b.send(t, "b2")
+	waitFor(t, "B's second write", func() bool { return dest.count(2) == 2 })
+	if err := b.engine.Checkpoint(t.Context()); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	b.mu.Lock()
+	if len(b.checkpoints) != 1 || b.checkpoints[0][hermod.FencingTokenKey] != "2" {
+		t.Fatalf("expected B's checkpoint stamped with token 2, got %v", b.checkpoints)
+	}
+	b.mu.Unlock()
+
+	stopB()
+	select {
+	case err := <-b.done:
+		if errors.Is(err, hermod.ErrFenced) {
+			t.Fatalf("B must not be fenced off: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("B did not stop")
+	}
+}
@@ -3,7 +3,10 @@ package engine
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -412,3 +415,346 @@ func TestDeadLetterQueue(t *testing.T) {
 
 	cancel()
 }
+
+type recordingDLQ struct {
+	mu   sync.Mutex
+	msgs []hermod.Message
+}
+
+func (r *recordingDLQ) RecordDeadLetter(ctx context.Context, workflowID string, msg hermod.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+	return nil
+}
+
+func TestDeadLetterRecorderGetsCause(t *testing.T) {
+	e := NewEngine(nil, nil, nil)
+	e.SetDeadLetterSink(&parallelMockSink{})
+	rec := &recordingDLQ{}
+	e.SetDeadLetterRecorder(rec)
+
+	m1, m2 := message.AcquireMessage(), message.AcquireMessage()
+	m2.SetMetadata("_hermod_last_error", "field id is required")
+
+	e.writeToDLQ(t.Context(), "sink1", dlqClassSinkWrite, errors.New("connection reset"), m1)
+	e.writeToDLQ(t.Context(), "sink1", dlqClassValidation, nil, m2)
+
+	if len(rec.msgs) != 2 {
+		t.Fatalf("expected 2 recorded dead letters, got %d", len(rec.msgs))
+	}
+	md := rec.msgs[0].Metadata()
+	if md["_hermod_last_error"] != "connection reset" || md["_hermod_error_class"] != dlqClassSinkWrite || md["_hermod_failed_sink"] != "sink1" {
+		t.Fatalf("unexpected metadata: %v", md)
+	}
+	if got := rec.msgs[1].Metadata()["_hermod_last_error"]; got != "field id is required" {
+		t.Fatalf("expected the per-message validation error to be kept, got %q", got)
+	}
+}
+
+// failingRecorder cannot store dead letters.
+type failingRecorder struct{}
+
+func (failingRecorder) RecordDeadLetter(context.Context, string, hermod.Message) error {
+	return errors.New("database is down")
+}
+
+// Without a dead letter sink, a failed write is kept in the dead letter store
+// and succeeds, unless the store cannot keep it either. With neither, the write
+// fails so the message is not acknowledged.
+func TestDeadLetterStoreWithoutSink(t *testing.T) {
+	e := NewEngine(nil, nil, nil)
+	e.SetConfig(Config{MaxRetries: 1, RetryInterval: time.Millisecond})
+	rec := &recordingDLQ{}
+	e.SetDeadLetterRecorder(rec)
+	snk := &parallelMockSink{err: errors.New("connection reset")}
+
+	m := message.AcquireMessage()
+	m.SetID("m1")
+	if err := e.writeToSink(t.Context(), snk, m, "sink1", -1); err != nil {
+		t.Fatalf("expected the failed write to be dead-lettered, got %v", err)
+	}
+	if len(rec.msgs) != 1 || rec.msgs[0].Metadata()["_hermod_error_class"] != dlqClassSinkWrite {
+		t.Fatalf("expected m1 recorded as a sink write failure, got %d", len(rec.msgs))
+	}
+
+	e.SetDeadLetterRecorder(failingRecorder{})
+	if err := e.writeToSink(t.Context(), snk, m, "sink1", -1); err == nil || !strings.Contains(err.Error(), "database is down") {
+		t.Fatalf("expected the write to fail when the dead letter cannot be recorded, got %v", err)
+	}
+
+	e.SetDeadLetterRecorder(nil)
+	if err := e.writeToSink(t.Context(), snk, m, "sink1", -1); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("expected the write to fail without a dead letter sink or store, got %v", err)
+	}
+}
+
+// poisonBatchSink rejects, as a permanent error, any write containing a
+// message whose ID is listed in poison.
+type poisonBatchSink struct {
+	mu      sync.Mutex
+	poison  map[string]bool
+	fatal   bool
+	calls   int
+	written []string
+}
+
+func (s *poisonBatchSink) write(msgs []hermod.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.fatal {
+		return hermod.Classify(errors.New("password authentication failed"), hermod.ErrorFatalConfig)
+	}
+	for _, m := range msgs {
+		if s.poison[m.ID()] {
+			return hermod.Classify(errors.New("duplicate key "+m.ID()), hermod.ErrorPermanent)
+		}
+	}
+	for _, m := range msgs {
+		s.written = append(s.written, m.ID())
+	}
+	return nil
+}
+
+func (s *poisonBatchSink) Write(ctx context.Context, msg hermod.Message) error {
+	return s.write([]hermod.Message{msg})
+}
+func (s *poisonBatchSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return s.write(msgs)
+}
+func (s *poisonBatchSink) Ping(ctx context.Context) error { return nil }
+func (s *poisonBatchSink) Close() error                   { return nil }
+
+func TestBatchBisectionIsolatesPoison(t *testing.T) {
+	snk := &poisonBatchSink{poison: map[string]bool{"m5": true}}
+	e := NewEngine(nil, nil, nil)
+	e.SetConfig(Config{MaxRetries: 5, RetryInterval: time.Second})
+	rec := &recordingDLQ{}
+	e.SetDeadLetterSink(&parallelMockSink{})
+	e.SetDeadLetterRecorder(rec)
+
+	msgs := make([]hermod.Message, 16)
+	for i := range msgs {
+		m := message.AcquireMessage()
+		m.SetID(fmt.Sprintf("m%d", i))
+		msgs[i] = m
+	}
+
+	start := time.Now()
+	if err := e.writeBatchToSink(t.Context(), snk, msgs, "sink1", -1); err != nil {
+		t.Fatalf("expected the poison message to be dead-lettered, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("permanent errors must not be retried with backoff, took %v", time.Since(start))
+	}
+	if len(snk.written) != 15 {
+		t.Fatalf("expected the 15 healthy messages delivered, got %d", len(snk.written))
+	}
+	if len(rec.msgs) != 1 || rec.msgs[0].ID() != "m5" {
+		t.Fatalf("expected only m5 dead-lettered, got %d", len(rec.msgs))
+	}
+	if got := rec.msgs[0].Metadata()["_hermod_error_class"]; got != dlqClassPermanent {
+		t.Fatalf("expected class %q, got %q", dlqClassPermanent, got)
+	}
+	// 1 full batch plus 2 writes per level of the 4-level split.
+	if snk.calls > 1+2*4 {
+		t.Fatalf("expected bisection to take O(log n) writes, took %d", snk.calls)
+	}
+}
+
+func TestFatalConfigErrorStopsWorkflow(t *testing.T) {
+	snk := &poisonBatchSink{fatal: true}
+	source := &parallelMockSource{msg: &parallelMockMessage{id: "fatal"}}
+	dlq := &parallelMockSink{}
+
+	e := NewEngine(source, []hermod.Sink{snk}, buffer.NewRingBuffer(4))
+	e.SetDeadLetterSink(dlq)
+	e.SetIDs("conn-fatal", "src1", []string{"sink1"})
+	e.SetConfig(Config{MaxRetries: 5, RetryInterval: time.Second, StatusInterval: time.Second})
+
+	done := make(chan error, 1)
+	go func() { done <- e.Start(t.Context()) }()
+
+	select {
+	case err := <-done:
+		if !hermod.IsFatalConfig(err) {
+			t.Fatalf("expected a fatal-config error, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected the engine to stop on a fatal-config error")
+	}
+	if dlq.writes != 0 {
+		t.Fatalf("a configuration error must not dead-letter messages, got %d", dlq.writes)
+	}
+	if snk.calls != 1 {
+		t.Fatalf("expected no retries of a fatal-config error, got %d writes", snk.calls)
+	}
+}
+
+// throttledSink throttles every write.
+type throttledSink struct{ poisonBatchSink }
+
+func (s *throttledSink) write(msgs []hermod.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return hermod.Throttled(errors.New("429 too many requests"), 10*time.Millisecond)
+}
+
+func (s *throttledSink) Write(ctx context.Context, msg hermod.Message) error {
+	return s.write([]hermod.Message{msg})
+}
+func (s *throttledSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	return s.write(msgs)
+}
+
+func TestThrottledWritesGiveUpAfterThrottleTimeout(t *testing.T) {
+	snk := &throttledSink{}
+	e := NewEngine(nil, nil, nil)
+	e.SetConfig(Config{MaxRetries: 2, RetryInterval: time.Millisecond, ThrottleTimeout: 100 * time.Millisecond})
+	rec := &recordingDLQ{}
+	e.SetDeadLetterSink(&parallelMockSink{})
+	e.SetDeadLetterRecorder(rec)
+
+	m := message.AcquireMessage()
+	m.SetID("m1")
+	start := time.Now()
+	if err := e.writeToSink(t.Context(), snk, m, "sink1", -1); err != nil {
+		t.Fatalf("expected the throttled message to be dead-lettered, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("expected the write to give up once the throttle timeout ran out, took %v", elapsed)
+	}
+	if snk.calls <= 2 {
+		t.Fatalf("throttled writes must not use up retries, got %d writes", snk.calls)
+	}
+
+	msgs := make([]hermod.Message, 2)
+	for i := range msgs {
+		m := message.AcquireMessage()
+		m.SetID(fmt.Sprintf("b%d", i))
+		msgs[i] = m
+	}
+	start = time.Now()
+	if err := e.writeBatchToSink(t.Context(), snk, msgs, "sink1", -1); err != nil {
+		t.Fatalf("expected the throttled batch to be dead-lettered, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Fatalf("a throttled batch must not be bisected, each part waiting out the timeout again; took %v", elapsed)
+	}
+	if len(rec.msgs) != 3 {
+		t.Fatalf("expected all 3 messages dead-lettered, got %d", len(rec.msgs))
+	}
+	for _, dl := range rec.msgs {
+		if got := dl.Metadata()["_hermod_error_class"]; got != dlqClassSinkWrite {
+			t.Fatalf("expected class %q, got %q", dlqClassSinkWrite, got)
+		}
+	}
+}
+
+// downBatchSink fails every write with a transient error.
+type downBatchSink struct{ poisonBatchSink }
+
+func (s *downBatchSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return errors.New("connection refused")
+}
+
+// A batch that keeps failing transiently is not a message's fault, so it is
+// dead-lettered as a whole rather than bisected.
+func TestTransientBatchFailureIsNotBisected(t *testing.T) {
+	snk := &downBatchSink{}
+	e := NewEngine(nil, nil, nil)
+	e.SetConfig(Config{MaxRetries: 3, RetryInterval: time.Millisecond})
+	rec := &recordingDLQ{}
+	e.SetDeadLetterRecorder(rec)
+
+	msgs := make([]hermod.Message, 8)
+	for i := range msgs {
+		m := message.AcquireMessage()
+		m.SetID(fmt.Sprintf("m%d", i))
+		msgs[i] = m
+	}
+	if err := e.writeBatchToSink(t.Context(), snk, msgs, "sink1", -1); err != nil {
+		t.Fatalf("expected the batch to be dead-lettered, got %v", err)
+	}
+	if snk.calls != 3 {
+		t.Fatalf("expected the batch retried 3 times and not split, got %d writes", snk.calls)
+	}
+	if len(rec.msgs) != 8 || rec.msgs[0].Metadata()["_hermod_error_class"] != dlqClassSinkWrite {
+		t.Fatalf("expected all 8 messages dead-lettered as sink write failures, got %d", len(rec.msgs))
+	}
+
+	e.SetDeadLetterRecorder(nil)
+	if err := e.writeBatchToSink(t.Context(), snk, msgs, "sink1", -1); err == nil {
+		t.Fatal("expected the write to fail without a dead letter sink or store")
+	}
+}
+
+// resultBatchSink reports an outcome per message: poison messages are
+// rejected and flaky ones fail once with a transient error.
+type resultBatchSink struct {
+	poisonBatchSink
+	flaky   map[string]bool
+	batches [][]string
+}
+
+func (s *resultBatchSink) WriteBatchResults(ctx context.Context, msgs []hermod.Message) ([]error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	errs := make([]error, len(msgs))
+	for i, m := range msgs {
+		ids = append(ids, m.ID())
+		switch {
+		case s.poison[m.ID()]:
+			errs[i] = hermod.Classify(errors.New("mapper_parsing_exception "+m.ID()), hermod.ErrorPermanent)
+		case s.flaky[m.ID()]:
+			delete(s.flaky, m.ID())
+			errs[i] = errors.New("shard unavailable")
+		default:
+			s.written = append(s.written, m.ID())
+		}
+	}
+	s.batches = append(s.batches, ids)
+	return errs, nil
+}
+
+func TestBatchResultsRetryOnlyFailedMessages(t *testing.T) {
+	snk := &resultBatchSink{
+		poisonBatchSink: poisonBatchSink{poison: map[string]bool{"m2": true}},
+		flaky:           map[string]bool{"m5": true, "m7": true},
+	}
+	e := NewEngine(nil, nil, nil)
+	e.SetConfig(Config{MaxRetries: 3, RetryInterval: time.Millisecond})
+	rec := &recordingDLQ{}
+	e.SetDeadLetterSink(&parallelMockSink{})
+	e.SetDeadLetterRecorder(rec)
+
+	msgs := make([]hermod.Message, 10)
+	for i := range msgs {
+		m := message.AcquireMessage()
+		m.SetID(fmt.Sprintf("m%d", i))
+		msgs[i] = m
+	}
+
+	if err := e.writeBatchToSink(t.Context(), snk, msgs, "sink1", -1); err != nil {
+		t.Fatalf("expected the rejected message to be dead-lettered, got %v", err)
+	}
+	if len(snk.written) != 9 {
+		t.Fatalf("expected 9 messages delivered, got %v", snk.written)
+	}
+	if len(snk.batches) != 2 || !slices.Equal(snk.batches[1], []string{"m5", "m7"}) {
+		t.Fatalf("expected only the transient failures re-sent, got %v", snk.batches)
+	}
+	if len(rec.msgs) != 1 || rec.msgs[0].ID() != "m2" {
+		t.Fatalf("expected only m2 dead-lettered, got %d", len(rec.msgs))
+	}
+	md := rec.msgs[0].Metadata()
+	if md["_hermod_error_class"] != dlqClassPermanent || !strings.Contains(md["_hermod_last_error"], "mapper_parsing_exception m2") {
+		t.Fatalf("expected m2 dead-lettered with its own error, got %v", md)
+	}
+}
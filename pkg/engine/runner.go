@@ -324,8 +324,9 @@ func (r *Runner) Start(ctx context.Context) (err error) {
 	r.closeSinksOnShutdown()
 	close(r.errCh)
 
-	// Final checkpoint
-	if r.engine.checkpointHandler != nil {
+	// Final checkpoint, unless another worker has taken the lease over: its
+	// position is the one that counts now.
+	if r.engine.checkpointHandler != nil && !r.engine.fenced() {
 		checkpointCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		_ = r.engine.Checkpoint(checkpointCtx)
 		cancel()
@@ -337,6 +338,9 @@ func (r *Runner) Start(ctx context.Context) (err error) {
 			lastErr = err
 		}
 	}
+	if fatal := r.engine.fatalErr.Load(); fatal != nil {
+		lastErr = *fatal
+	}
 
 	if lastErr != nil {
 		r.engine.logger.Error("Hermod Engine stopped with error", "workflow_id", r.engine.workflowID, "error", lastErr)
@@ -623,9 +627,20 @@ func (r *Runner) runSourceToBuffer(ctx context.Context) {
 
 			r.engine.recordSourceActivity()
 
+			// Once produced, m belongs to the sink side and may be released at
+			// any moment, so hold a reference for the Buffered call after it.
+			ba, notify := r.engine.source.(hermod.BufferAcker)
+			if notify {
+				m.Retain()
+			}
 			if err := r.engine.buffer.Produce(ctx, m); err != nil {
 				r.engine.logger.Error("Failed to write message to buffer", "workflow_id", r.engine.workflowID, "error", err)
 				m.Release()
+			} else if notify {
+				ba.Buffered(ctx, m)
+			}
+			if notify {
+				m.Release()
 			}
 		}
 	}
@@ -692,6 +707,14 @@ func (r *Runner) runBufferToSink(ctx context.Context, sinkWg *sync.WaitGroup) {
 	}
 }
 
+// nack tells a source that waits for the outcome of every message that m
+// will not reach its sinks.
+func (r *Runner) nack(ctx context.Context, m hermod.Message, err error) {
+	if n, ok := r.engine.source.(hermod.Nacker); ok {
+		n.Nack(ctx, m, err)
+	}
+}
+
 func (r *Runner) processMessage(ctx context.Context, m hermod.Message) {
 	if m == nil {
 		return
@@ -726,20 +749,19 @@ func (r *Runner) processMessage(ctx context.Context, m hermod.Message) {
 		r.engine.RecordTraceStep(ctx, m, "workflow_start", start, nil, nil)
 	}
 
-	// Data validation
-	if r.engine.validator != nil {
+	// Data validation. Schema changes carry no row to validate.
+	if r.engine.validator != nil && m.Operation() != hermod.OpSchemaChange {
 		vstart := time.Now()
 		if err := r.engine.validator.Validate(ctx, m.Data()); err != nil {
 			r.engine.logger.Error("Message validation failed", "workflow_id", r.engine.workflowID, "message_id", m.ID(), "error", err)
 			r.engine.UpdateNodeErrorMetric("validator", 1)
 			r.engine.RecordTraceStep(ctx, m, "validator", vstart, nil, err)
 
-			if r.engine.deadLetterSink != nil {
+			if r.engine.canDeadLetter() {
 				m.SetMetadata("_hermod_validation_failed", "true")
-				m.SetMetadata("_hermod_last_error", err.Error())
-				_ = r.engine.deadLetterSink.Write(ctx, m)
-				r.engine.statusTracker.IncDeadLetter()
+				_ = r.engine.writeToDLQ(ctx, "", dlqClassValidation, err, m)
 			}
+			r.nack(ctx, m, err)
 			return
 		}
 		r.engine.UpdateNodeMetric("validator", 1)
@@ -754,6 +776,7 @@ func (r *Runner) processMessage(ctx context.Context, m hermod.Message) {
 		if err != nil {
 			r.engine.logger.Error("Routing failed", "workflow_id", r.engine.workflowID, "message_id", m.ID(), "error", err)
 			r.engine.RecordTraceStep(ctx, m, "router", rstart, nil, err)
+			r.nack(ctx, m, err)
 			return
 		}
 		targets = t
@@ -805,16 +828,18 @@ func (r *Runner) processMessage(ctx context.Context, m hermod.Message) {
 		// as a successful delivery.
 		telemetry.MessagesDroppedNoTarget.WithLabelValues(r.engine.workflowID).Inc()
 		r.engine.reportUnroutable(m)
+		r.nack(ctx, m, errUnroutable)
 
-		// Preferred: park it in the dead-letter sink, which preserves the message
-		// and lets the source advance.
-		if r.engine.deadLetterSink != nil {
-			r.engine.writeToDLQ(ctx, "", m)
-			ack()
+		// Preferred: park it in the dead-letter sink or store, which preserves
+		// the message and lets the source advance.
+		if r.engine.canDeadLetter() {
+			if err := r.engine.writeToDLQ(ctx, "", dlqClassUnroutable, errUnroutable, m); err == nil {
+				ack()
+			}
 			return
 		}
 
-		// No dead-letter sink: do NOT acknowledge. Leaving the message
+		// Nowhere to park it: do NOT acknowledge. Leaving the message
 		// un-acknowledged keeps it on the source — for a replication slot that
 		// means the WAL is retained and replays on the next run — which is the
 		// same choice the routing-error path above already makes. Retention is
@@ -868,9 +893,18 @@ func (r *Runner) processMessage(ctx context.Context, m hermod.Message) {
 	for err := range serrCh {
 		if err != nil {
 			r.engine.logger.Error("Sink write error", "workflow_id", r.engine.workflowID, "error", err)
+			r.nack(ctx, m, err)
 			return
 		}
 	}
+	// A write that ended in the dead-letter queue succeeded as far as the
+	// source is concerned, but its producer is told it was not delivered.
+	for _, target := range targets {
+		if md := target.Message.Metadata(); md["_hermod_error_class"] != "" {
+			r.nack(ctx, m, fmt.Errorf("dead-lettered (%s): %s", md["_hermod_error_class"], md["_hermod_last_error"]))
+			break
+		}
+	}
 
 	// Acknowledge the message to the source after all successful sink writes.
 	//
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/pkg/comm/message"
+)
+
+// schemaChangeSink records the rows it writes and the schema changes it
+// applies, in order.
+type schemaChangeSink struct {
+	mu       sync.Mutex
+	events   []string
+	failures int
+}
+
+func (s *schemaChangeSink) Write(ctx context.Context, msg hermod.Message) error {
+	return s.WriteBatch(ctx, []hermod.Message{msg})
+}
+func (s *schemaChangeSink) WriteBatch(ctx context.Context, msgs []hermod.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range msgs {
+		s.events = append(s.events, m.ID())
+	}
+	return nil
+}
+func (s *schemaChangeSink) ApplySchemaChange(ctx context.Context, change hermod.SchemaChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("lock timeout")
+	}
+	s.events = append(s.events, "alter:"+change.Added()[0].Name)
+	return nil
+}
+func (s *schemaChangeSink) Ping(ctx context.Context) error { return nil }
+func (s *schemaChangeSink) Close() error                   { return nil }
+
+func TestSchemaChangeAppliedInOrder(t *testing.T) {
+	snk := &schemaChangeSink{failures: 1}
+	e := NewEngine(nil, nil, nil)
+	e.SetConfig(Config{MaxRetries: 3, RetryInterval: time.Millisecond})
+
+	id := hermod.ColumnInfo{Name: "id", Type: "int"}
+	var msgs []hermod.Message
+	for i := range 4 {
+		if i == 2 {
+			msgs = append(msgs, message.AcquireSchemaChange("ddl", hermod.SchemaChange{
+				Table:    "users",
+				Previous: []hermod.ColumnInfo{id},
+				Columns:  []hermod.ColumnInfo{id, {Name: "email", Type: "text"}},
+			}))
+		}
+		m := message.AcquireMessage()
+		m.SetID(fmt.Sprintf("m%d", i))
+		msgs = append(msgs, m)
+	}
+
+	if err := e.writeBatchToSink(t.Context(), snk, msgs, "sink1", -1); err != nil {
+		t.Fatalf("writeBatchToSink failed: %v", err)
+	}
+	want := []string{"m0", "m1", "alter:email", "m2", "m3"}
+	if !slices.Equal(snk.events, want) {
+		t.Fatalf("events = %v, want %v", snk.events, want)
+	}
+
+	// A change that lets nothing through is not handed to the sink.
+	empty := message.AcquireSchemaChange("noop", hermod.SchemaChange{Table: "users", Previous: []hermod.ColumnInfo{id}, Columns: []hermod.ColumnInfo{id}})
+	if err := e.writeToSink(t.Context(), snk, empty, "sink1", -1); err != nil {
+		t.Fatalf("writeToSink failed: %v", err)
+	}
+	if len(snk.events) != len(want) {
+		t.Fatalf("an empty change reached the sink: %v", snk.events)
+	}
+}
+
+func TestSchemaChangeFailureIsReturned(t *testing.T) {
+	snk := &schemaChangeSink{failures: 10}
+	e := NewEngine(nil, nil, nil)
+	e.SetConfig(Config{MaxRetries: 2, RetryInterval: time.Millisecond})
+
+	msg := message.AcquireSchemaChange("ddl", hermod.SchemaChange{
+		Table:   "users",
+		Columns: []hermod.ColumnInfo{{Name: "email", Type: "text"}},
+	})
+	if err := e.writeToSink(t.Context(), snk, msg, "sink1", -1); err == nil {
+		t.Fatal("expected the failed schema change to be returned")
+	}
+}
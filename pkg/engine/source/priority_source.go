@@ -58,6 +58,30 @@ func (s *PrioritySource) Ack(ctx context.Context, msg hermod.Message) error {
 	return s.primary.Ack(ctx, msg)
 }
 
+// Buffered passes the buffer acknowledgement to the source the message came
+// from, found the same way Ack finds it.
+func (s *PrioritySource) Buffered(ctx context.Context, msg hermod.Message) {
+	src := s.primary
+	if s_id, ok := msg.Metadata()["_hermod_source"]; ok && s_id == "recovery" {
+		src = s.recovery
+	}
+	if ba, ok := src.(hermod.BufferAcker); ok {
+		ba.Buffered(ctx, msg)
+	}
+}
+
+// Nack passes the negative acknowledgement to the source the message came
+// from, found the same way Ack finds it.
+func (s *PrioritySource) Nack(ctx context.Context, msg hermod.Message, err error) {
+	src := s.primary
+	if s_id, ok := msg.Metadata()["_hermod_source"]; ok && s_id == "recovery" {
+		src = s.recovery
+	}
+	if n, ok := src.(hermod.Nacker); ok {
+		n.Nack(ctx, msg, err)
+	}
+}
+
 func (s *PrioritySource) Ping(ctx context.Context) error {
 	// Recovery source might be optional or might fail without affecting primary necessarily,
 	// but for priority source we usually want both healthy if recovery is enabled.
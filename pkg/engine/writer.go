@@ -166,46 +166,98 @@ var fnvPool = sync.Pool{
 	},
 }
 
-func (e *Engine) prepareDLQMessage(m hermod.Message, sinkID string, errStr string) {
+// Dead-letter error classes, recorded in _hermod_error_class so dead letters
+// can be filtered by why they failed. dlqClassSinkWrite is a write that kept
+// failing until retries ran out; dlqClassPermanent is one the sink rejected
+// outright.
+const (
+	dlqClassSinkWrite  = "sink_write"
+	dlqClassPermanent  = "permanent"
+	dlqClassValidation = "validation"
+	dlqClassSafeMode   = "safe_mode"
+	dlqClassUnroutable = "unroutable"
+)
+
+var (
+	errSafeMode   = errors.New("safe mode active: write diverted to dead letter sink")
+	errUnroutable = errors.New("no sink resolved for message")
+)
+
+// canDeadLetter reports whether failed messages can be set aside instead of
+// failing the write: in the dead letter sink or, without one, in the dead
+// letter store alone.
+func (e *Engine) canDeadLetter() bool {
+	return e.deadLetterSink != nil || e.dlqRecorder != nil
+}
+
+func (e *Engine) prepareDLQMessage(ctx context.Context, m hermod.Message, sinkID, class string, cause error) error {
 	if m == nil {
-		return
+		return nil
 	}
 	if sinkID != "" {
 		m.SetMetadata("_hermod_failed_sink", sinkID)
 	}
-	if errStr != "" {
-		m.SetMetadata("_hermod_last_error", errStr)
+	// A nil cause keeps any per-message error already recorded, as batch
+	// validation does.
+	if cause != nil {
+		m.SetMetadata("_hermod_last_error", cause.Error())
 	}
+	m.SetMetadata("_hermod_error_class", class)
 	m.SetMetadata("_hermod_failed_at", time.Now().Format(time.RFC3339))
 	e.statusTracker.IncDeadLetter()
 	telemetry.DeadLetterCount.WithLabelValues(e.workflowID, sinkID).Inc()
+	if e.dlqRecorder == nil {
+		return nil
+	}
+	// The copy must survive the shutdown that may have caused the failure.
+	err := e.dlqRecorder.RecordDeadLetter(context.WithoutCancel(ctx), e.workflowID, m)
+	if err != nil {
+		e.logger.Error("Failed to record dead letter", "workflow_id", e.workflowID, "message_id", m.ID(), "error", err)
+	}
+	return err
 }
 
-func (e *Engine) writeToDLQ(ctx context.Context, sinkID string, msgs ...hermod.Message) {
-	if e.deadLetterSink == nil || len(msgs) == 0 {
-		return
+// writeToDLQ sets msgs aside. Without a dead letter sink the dead letter
+// store is the only copy, so an error recording them is returned and the
+// caller must not acknowledge them; with one, failures are only logged.
+func (e *Engine) writeToDLQ(ctx context.Context, sinkID, class string, cause error, msgs ...hermod.Message) error {
+	if !e.canDeadLetter() || len(msgs) == 0 {
+		return nil
+	}
+
+	if e.deadLetterSink == nil {
+		var errs []error
+		for _, m := range msgs {
+			errs = append(errs, e.prepareDLQMessage(ctx, m, sinkID, class, cause))
+		}
+		if err := errors.Join(errs...); err != nil {
+			telemetry.DeadLetterErrors.WithLabelValues(e.workflowID, sinkID).Inc()
+			return fmt.Errorf("failed to record dead letter: %w", err)
+		}
+		return nil
 	}
 
 	// If the DLQ sink supports batching, use it
 	if bsnk, ok := e.deadLetterSink.(hermod.BatchSink); ok && len(msgs) > 1 {
 		for _, m := range msgs {
-			e.prepareDLQMessage(m, sinkID, "")
+			_ = e.prepareDLQMessage(ctx, m, sinkID, class, cause)
 		}
 		if err := bsnk.WriteBatch(ctx, msgs); err != nil {
 			e.logger.Error("Failed to write batch to Dead Letter Sink", "workflow_id", e.workflowID, "error", err)
 			telemetry.DeadLetterErrors.WithLabelValues(e.workflowID, sinkID).Inc()
 		}
-		return
+		return nil
 	}
 
 	// Fallback to single writes
 	for _, m := range msgs {
-		e.prepareDLQMessage(m, sinkID, "")
+		_ = e.prepareDLQMessage(ctx, m, sinkID, class, cause)
 		if err := e.deadLetterSink.Write(ctx, m); err != nil {
 			e.logger.Error("Failed to write to Dead Letter Sink", "workflow_id", e.workflowID, "error", err)
 			telemetry.DeadLetterErrors.WithLabelValues(e.workflowID, sinkID).Inc()
 		}
 	}
+	return nil
 }
 
 // writeToSink writes a single message to the sink with retry/reconnect.
@@ -217,6 +269,9 @@ func (e *Engine) writeToSink(ctx context.Context, snk hermod.Sink, msg hermod.Me
 	if msg == nil {
 		return nil
 	}
+	if msg.Operation() == hermod.OpSchemaChange {
+		return e.applySchemaChange(ctx, snk, msg, sinkID, i)
+	}
 	// Trace single write
 	var span trace.Span
 	ctx, span = tracer.Start(ctx, "sink.write", trace.WithAttributes(
@@ -234,19 +289,17 @@ func (e *Engine) writeToSink(ctx context.Context, snk hermod.Sink, msg hermod.Me
 		e.logger.Warn("Safe Mode Active: diverting message to Dead Letter Sink", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", msg.ID())
 		msg.SetMetadata("_hermod_safe_mode", "true")
 		msg.SetMetadata("_hermod_original_sink", sinkID)
-		e.writeToDLQ(ctx, sinkID, msg)
-		return nil
+		return e.writeToDLQ(ctx, sinkID, dlqClassSafeMode, errSafeMode, msg)
 	}
 
 	// Pre-write validation
 	if vs, ok := snk.(hermod.ValidatingSink); ok {
 		if err := vs.Validate(ctx, msg); err != nil {
 			e.logger.Error("Sink pre-write validation failed", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", msg.ID(), "error", err)
-			if e.deadLetterSink != nil {
-				e.logger.Info("Sending invalid message to Dead Letter Sink", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", msg.ID())
+			if e.canDeadLetter() {
+				e.logger.Info("Sending invalid message to Dead Letter Queue", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", msg.ID())
 				msg.SetMetadata("_hermod_validation_failed", "true")
-				e.writeToDLQ(ctx, sinkID, msg)
-				return nil
+				return e.writeToDLQ(ctx, sinkID, dlqClassValidation, err, msg)
 			}
 			return fmt.Errorf("validation error: %w", err)
 		}
@@ -262,25 +315,16 @@ func (e *Engine) writeToSink(ctx context.Context, snk hermod.Sink, msg hermod.Me
 		)
 		return nil
 	}
-	// Retry mechanism for Sink Write
+	// Retry mechanism for Sink Write. How a failure is retried depends on its
+	// class: permanent and fatal-config errors are not retried at all, and
+	// throttled ones wait for the destination's hint without using up retries,
+	// until the throttle timeout runs out.
 	var lastErr error
+	lastClass := hermod.ErrorTransient
+	maxRetries, retryInterval := e.retryPolicy(i)
+	var throttledSince time.Time
 
-	maxRetries := e.config.MaxRetries
-	if maxRetries <= 0 {
-		maxRetries = 1
-	}
-	retryInterval := e.config.RetryInterval
-
-	if i >= 0 && i < len(e.sinkConfigs) {
-		if e.sinkConfigs[i].MaxRetries > 0 {
-			maxRetries = e.sinkConfigs[i].MaxRetries
-		}
-		if e.sinkConfigs[i].RetryInterval > 0 {
-			retryInterval = e.sinkConfigs[i].RetryInterval
-		}
-	}
-
-	for j := range maxRetries {
+	for j := 0; j < maxRetries; {
 		start := time.Now()
 		var before map[string]any
 		if e.traceRecorder != nil && e.config.TraceSampleRate > 0 {
@@ -291,29 +335,44 @@ func (e *Engine) writeToSink(ctx context.Context, snk hermod.Sink, msg hermod.Me
 		err := snk.Write(ctx, msg)
 		if err != nil {
 			lastErr = err
+			class, retryAfter := classifySinkError(snk, err)
+			lastClass = class
+			telemetry.SinkWriteErrors.WithLabelValues(e.workflowID, sinkID).Inc()
+			if class == hermod.ErrorPermanent {
+				// The message is at fault, not the sink, so the breaker is
+				// not told.
+				e.logger.Warn("Sink rejected message, not retrying", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", msg.ID(), "error", err)
+				break
+			}
 			for _, observe := range onAttemptError {
 				if observe != nil {
 					observe()
 				}
 			}
-			telemetry.SinkWriteErrors.WithLabelValues(e.workflowID, sinkID).Inc()
+			if class == hermod.ErrorFatalConfig {
+				break
+			}
 			e.setSinkStatus(sinkID, "reconnecting")
 			e.setStatus("reconnecting:sink:" + sinkID)
-			e.logger.Warn("Sink write error, retrying", "workflow_id", e.workflowID, "attempt", j+1, "sink_id", sinkID, "error", err)
 
 			var interval time.Duration
-			if i >= 0 && i < len(e.sinkConfigs) && len(e.sinkConfigs[i].RetryIntervals) > 0 {
-				if j < len(e.sinkConfigs[i].RetryIntervals) {
-					interval = e.sinkConfigs[i].RetryIntervals[j]
-				} else {
-					interval = e.sinkConfigs[i].RetryIntervals[len(e.sinkConfigs[i].RetryIntervals)-1]
+			if class == hermod.ErrorThrottled {
+				remaining := e.throttleRemaining(i, &throttledSince)
+				if remaining <= 0 {
+					e.logger.Error("Sink still throttled after the throttle timeout, giving up", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", msg.ID(), "throttled_for", time.Since(throttledSince), "error", err)
+					break
 				}
+				interval = min(throttleWait(retryAfter, e.backoff(i, 0, retryInterval)), remaining)
+				e.logger.Warn("Sink throttled, backing off", "workflow_id", e.workflowID, "sink_id", sinkID, "delay", interval, "error", err)
 			} else {
-				interval = time.Duration(j+1) * retryInterval
+				e.logger.Warn("Sink write error, retrying", "workflow_id", e.workflowID, "attempt", j+1, "sink_id", sinkID, "error", err)
+				if j++; j == maxRetries {
+					break // no point waiting after the final attempt
+				}
+				// Add jitter (±20%) to avoid thundering herd
+				jitter := 0.8 + rand.Float64()*0.4
+				interval = time.Duration(float64(e.backoff(i, j-1, retryInterval)) * jitter)
 			}
-			// Add jitter (±20%) to avoid thundering herd
-			jitter := 0.8 + rand.Float64()*0.4
-			interval = time.Duration(float64(interval) * jitter)
 
 			select {
 			case <-time.After(interval):
@@ -356,15 +415,76 @@ func (e *Engine) writeToSink(ctx context.Context, snk hermod.Sink, msg hermod.Me
 		break
 	}
 	if lastErr != nil {
-		e.logger.Error("Sink write failed after retries", "workflow_id", e.workflowID, "sink_id", sinkID, "error", lastErr)
 		span.RecordError(lastErr)
 		span.SetStatus(codes.Error, lastErr.Error())
-		if e.deadLetterSink != nil {
-			e.logger.Info("Sending message to Dead Letter Sink", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", msg.ID())
-			e.writeToDLQ(ctx, sinkID, msg)
-			return nil // Message preserved in DLQ
+		if lastClass == hermod.ErrorFatalConfig {
+			return e.stopOnFatalConfig(sinkID, lastErr)
+		}
+		dlqClass := dlqClassPermanent
+		if lastClass != hermod.ErrorPermanent {
+			dlqClass = dlqClassSinkWrite
+			e.logger.Error("Sink write failed after retries", "workflow_id", e.workflowID, "sink_id", sinkID, "error", lastErr)
 		}
-		return fmt.Errorf("sink write error: %w", lastErr)
+		if e.canDeadLetter() {
+			e.logger.Info("Sending message to Dead Letter Queue", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", msg.ID())
+			return e.writeToDLQ(ctx, sinkID, dlqClass, lastErr, msg) // Message preserved in DLQ
+		}
+		return fmt.Errorf("sink write error: %w", hermod.Classify(lastErr, lastClass))
+	}
+	return nil
+}
+
+// applySchemaChange hands the schema change msg carries to the sink, if it
+// can evolve its tables, retrying failures like a write. A change cannot be
+// dead-lettered, so one that still fails is not acknowledged.
+func (e *Engine) applySchemaChange(ctx context.Context, snk hermod.Sink, msg hermod.Message, sinkID string, i int) error {
+	change, ok := hermod.SchemaChangeOf(msg)
+	sc, canApply := snk.(hermod.SchemaChangeSink)
+	if !ok || !canApply || change.Empty() {
+		return nil
+	}
+	if e.config.DryRun {
+		e.logger.Info("[DRY-RUN] Schema change would be applied to sink", "workflow_id", e.workflowID, "sink_id", sinkID, "table", change.QualifiedTable())
+		return nil
+	}
+
+	maxRetries, retryInterval := e.retryPolicy(i)
+	var err error
+	for j := 0; j < maxRetries; j++ {
+		if j > 0 {
+			select {
+			case <-time.After(e.backoff(i, j-1, retryInterval)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err = sc.ApplySchemaChange(ctx, change); err == nil {
+			e.logger.Info("Schema change applied to sink", "workflow_id", e.workflowID, "sink_id", sinkID, "table", change.QualifiedTable(), "version", change.Version)
+			return e.recordSchemaState(ctx, snk, sinkID)
+		}
+		if class, _ := classifySinkError(snk, err); class == hermod.ErrorPermanent || class == hermod.ErrorFatalConfig {
+			break
+		}
+		e.logger.Warn("Schema change failed, retrying", "workflow_id", e.workflowID, "attempt", j+1, "sink_id", sinkID, "error", err)
+	}
+	e.logger.Error("Schema change could not be applied to sink", "workflow_id", e.workflowID, "sink_id", sinkID, "table", change.QualifiedTable(), "error", err)
+	return fmt.Errorf("sink %s schema change: %w", sinkID, err)
+}
+
+// recordSchemaState records the schema state of a sink that applied a
+// change. If that fails the change is not acknowledged.
+func (e *Engine) recordSchemaState(ctx context.Context, snk hermod.Sink, sinkID string) error {
+	ss, ok := snk.(hermod.SchemaStateSink)
+	if !ok || e.schemaStates == nil {
+		return nil
+	}
+	state, err := ss.SchemaState()
+	if err == nil && state != nil {
+		err = e.schemaStates.RecordSchemaState(ctx, e.workflowID, sinkID, state)
+	}
+	if err != nil {
+		e.logger.Error("Schema state of sink could not be recorded", "workflow_id", e.workflowID, "sink_id", sinkID, "error", err)
+		return fmt.Errorf("sink %s schema state: %w", sinkID, err)
 	}
 	return nil
 }
@@ -377,6 +497,17 @@ func (e *Engine) writeBatchToSink(ctx context.Context, snk hermod.BatchSink, msg
 		return nil
 	}
 
+	// A schema change applies between the rows before and after it.
+	if k := slices.IndexFunc(msgs, func(m hermod.Message) bool { return m.Operation() == hermod.OpSchemaChange }); k >= 0 {
+		if err := e.writeBatchToSink(ctx, snk, msgs[:k], sinkID, i); err != nil {
+			return err
+		}
+		if err := e.applySchemaChange(ctx, snk, msgs[k], sinkID, i); err != nil {
+			return err
+		}
+		return e.writeBatchToSink(ctx, snk, msgs[k+1:], sinkID, i)
+	}
+
 	// Trace batch write
 	var span trace.Span
 	ctx, span = tracer.Start(ctx, "sink.write_batch", trace.WithAttributes(
@@ -393,7 +524,7 @@ func (e *Engine) writeBatchToSink(ctx context.Context, snk hermod.BatchSink, msg
 		for _, m := range msgs {
 			if err := vs.Validate(ctx, m); err != nil {
 				e.logger.Error("Sink pre-write validation failed for message in batch", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", m.ID(), "error", err)
-				if e.deadLetterSink != nil {
+				if e.canDeadLetter() {
 					m.SetMetadata("_hermod_validation_failed", "true")
 					m.SetMetadata("_hermod_last_error", err.Error())
 					invalidMsgs = append(invalidMsgs, m)
@@ -402,8 +533,8 @@ func (e *Engine) writeBatchToSink(ctx context.Context, snk hermod.BatchSink, msg
 			}
 			validMsgs = append(validMsgs, m)
 		}
-		if len(invalidMsgs) > 0 {
-			e.writeToDLQ(ctx, sinkID, invalidMsgs...)
+		if err := e.writeToDLQ(ctx, sinkID, dlqClassValidation, nil, invalidMsgs...); err != nil {
+			return err
 		}
 		msgs = validMsgs
 	}
@@ -420,8 +551,7 @@ func (e *Engine) writeBatchToSink(ctx context.Context, snk hermod.BatchSink, msg
 				m.SetMetadata("_hermod_original_sink", sinkID)
 			}
 		}
-		e.writeToDLQ(ctx, sinkID, msgs...)
-		return nil
+		return e.writeToDLQ(ctx, sinkID, dlqClassSafeMode, errSafeMode, msgs...)
 	}
 
 	if len(msgs) == 1 {
@@ -438,118 +568,370 @@ func (e *Engine) writeBatchToSink(ctx context.Context, snk hermod.BatchSink, msg
 		return nil
 	}
 
-	// Retry mechanism for Sink WriteBatch
-	var lastErr error
-
-	maxRetries := e.config.MaxRetries
-	if maxRetries <= 0 {
-		maxRetries = 1
+	maxRetries, _ := e.retryPolicy(i)
+	pending, class, err := e.tryWriteBatch(ctx, snk, msgs, sinkID, i, maxRetries)
+	if err == nil {
+		return nil
 	}
-	retryInterval := e.config.RetryInterval
-
-	if i >= 0 && i < len(e.sinkConfigs) {
-		if e.sinkConfigs[i].MaxRetries > 0 {
-			maxRetries = e.sinkConfigs[i].MaxRetries
-		}
-		if e.sinkConfigs[i].RetryInterval > 0 {
-			retryInterval = e.sinkConfigs[i].RetryInterval
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	if ctx.Err() != nil || len(pending) == 0 {
+		return err
+	}
+	if class == hermod.ErrorFatalConfig {
+		return e.stopOnFatalConfig(sinkID, err)
+	}
+	if class != hermod.ErrorPermanent {
+		return e.divertBatch(ctx, sinkID, err, pending)
+	}
+	msgs = pending
+
+	// One bad message fails the whole batch, so split it until the writes
+	// that still fail are down to single messages, which writeToSink retries
+	// or dead-letters on their own. Healthy neighbours are delivered on the
+	// way down instead of sharing the bad message's fate.
+	e.logger.Warn("Batch write failed, bisecting to isolate failing messages", "workflow_id", e.workflowID, "sink_id", sinkID, "batch_size", len(msgs), "error_class", class.String(), "error", err)
+	if berr := e.bisectBatch(ctx, snk, msgs, sinkID, i); berr != nil {
+		if hermod.IsFatalConfig(berr) || ctx.Err() != nil {
+			return berr
 		}
+		return fmt.Errorf("sink batch write failed and some messages could not be diverted: %w", berr)
 	}
+	return nil
+}
 
-	for j := range maxRetries {
+// tryWriteBatch writes msgs as one batch, retrying transient failures up to
+// attempts times and throttled ones for as long as the destination asks,
+// within the throttle timeout. It returns the messages whose fate is still
+// open, which is none on success, with the class of the last failure.
+//
+// Sinks that report per-message outcomes only have their failed messages
+// re-sent. Messages such a sink rejects, and those still failing once the
+// attempts run out, are dead-lettered on their own and not returned.
+func (e *Engine) tryWriteBatch(ctx context.Context, snk hermod.BatchSink, msgs []hermod.Message, sinkID string, i, attempts int) ([]hermod.Message, hermod.ErrorClass, error) {
+	_, retryInterval := e.retryPolicy(i)
+	rs, perMessage := snk.(hermod.BatchResultSink)
+	var rejected []hermod.Message
+	var throttledSince time.Time
+	for j := 0; ; {
 		start := time.Now()
-		if err := snk.WriteBatch(ctx, msgs); err != nil {
-			lastErr = err
+		var err error
+		var failedErrs []error
+		if perMessage {
+			var errs []error
+			if errs, err = rs.WriteBatchResults(ctx, msgs); err == nil {
+				var rej []hermod.Message
+				msgs, failedErrs, rej, err = e.sortBatchResults(ctx, snk, msgs, errs, sinkID, start)
+				rejected = append(rejected, rej...)
+			}
+		} else if err = snk.WriteBatch(ctx, msgs); err == nil {
+			e.recordBatchWritten(ctx, msgs, sinkID, start)
+		}
+		if err == nil {
+			if j > 0 {
+				e.logger.Info("Sink reconnected successfully", "workflow_id", e.workflowID, "sink_id", sinkID, "action", "reconnect")
+			}
+			return nil, hermod.ErrorTransient, e.divertBatchFailures(ctx, sinkID, rejected, nil, nil)
+		}
+		if failedErrs == nil {
 			telemetry.SinkWriteErrors.WithLabelValues(e.workflowID, sinkID).Add(float64(len(msgs)))
-			e.setSinkStatus(sinkID, "reconnecting")
-			e.setStatus("reconnecting:sink:" + sinkID)
-			e.logger.Warn("Sink batch write error, retrying", "workflow_id", e.workflowID, "attempt", j+1, "sink_id", sinkID, "batch_size", len(msgs), "error", err)
+		}
 
-			var interval time.Duration
-			if i >= 0 && i < len(e.sinkConfigs) && len(e.sinkConfigs[i].RetryIntervals) > 0 {
-				if j < len(e.sinkConfigs[i].RetryIntervals) {
-					interval = e.sinkConfigs[i].RetryIntervals[j]
-				} else {
-					interval = e.sinkConfigs[i].RetryIntervals[len(e.sinkConfigs[i].RetryIntervals)-1]
+		class, retryAfter := classifySinkError(snk, err)
+		var interval time.Duration
+		switch class {
+		case hermod.ErrorFatalConfig:
+			return msgs, class, err
+		case hermod.ErrorPermanent:
+			if derr := e.divertBatchFailures(ctx, sinkID, rejected, nil, nil); derr != nil {
+				return nil, class, derr
+			}
+			return msgs, class, err
+		case hermod.ErrorThrottled:
+			if remaining := e.throttleRemaining(i, &throttledSince); remaining > 0 {
+				interval = min(throttleWait(retryAfter, e.backoff(i, 0, retryInterval)), remaining)
+				e.logger.Warn("Sink throttled, backing off", "workflow_id", e.workflowID, "sink_id", sinkID, "batch_size", len(msgs), "delay", interval, "error", err)
+				break
+			}
+			e.logger.Error("Sink still throttled after the throttle timeout, giving up", "workflow_id", e.workflowID, "sink_id", sinkID, "batch_size", len(msgs), "throttled_for", time.Since(throttledSince), "error", err)
+			// Count the attempts as used up, so the batch fails as below.
+			j = attempts - 1
+			fallthrough
+		default:
+			if j++; j >= attempts {
+				if failedErrs != nil {
+					e.logger.Error("Sink write failed after retries", "workflow_id", e.workflowID, "sink_id", sinkID, "failed", len(msgs), "error", err)
+					return nil, class, e.divertBatchFailures(ctx, sinkID, rejected, msgs, failedErrs)
 				}
-			} else {
-				interval = time.Duration(j+1) * retryInterval
+				if derr := e.divertBatchFailures(ctx, sinkID, rejected, nil, nil); derr != nil {
+					return nil, class, derr
+				}
+				return msgs, class, err
 			}
+			interval = e.backoff(i, j-1, retryInterval)
+			e.logger.Warn("Sink batch write error, retrying", "workflow_id", e.workflowID, "attempt", j, "sink_id", sinkID, "batch_size", len(msgs), "error", err)
+		}
+		e.setSinkStatus(sinkID, "reconnecting")
+		e.setStatus("reconnecting:sink:" + sinkID)
 
-			select {
-			case <-time.After(interval):
-				continue
-			case <-ctx.Done():
-				span.RecordError(ctx.Err())
-				span.SetStatus(codes.Error, ctx.Err().Error())
-				return ctx.Err()
-			}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return msgs, hermod.ErrorTransient, ctx.Err()
 		}
+	}
+}
 
-		// Record trace step for each message in the batch.
-		// Sampling is handled internally by RecordTraceStep.
-		for _, m := range msgs {
-			if m != nil {
-				e.RecordTraceStep(ctx, m, sinkID, start, nil, nil)
-			}
+// recordBatchWritten accounts for messages a batch write delivered.
+func (e *Engine) recordBatchWritten(ctx context.Context, msgs []hermod.Message, sinkID string, start time.Time) {
+	if len(msgs) == 0 {
+		return
+	}
+	// Record trace step for each message in the batch.
+	// Sampling is handled internally by RecordTraceStep.
+	for _, m := range msgs {
+		if m != nil {
+			e.RecordTraceStep(ctx, m, sinkID, start, nil, nil)
 		}
+	}
+	telemetry.SinkWriteCount.WithLabelValues(e.workflowID, sinkID).Add(float64(len(msgs)))
+	e.logger.Info("Batch written to sink",
+		"workflow_id", e.workflowID,
+		"sink_id", sinkID,
+		"action", "write_batch",
+		"batch_size", len(msgs),
+	)
+}
 
-		if j > 0 {
-			e.logger.Info("Sink reconnected successfully", "workflow_id", e.workflowID, "sink_id", sinkID, "action", "reconnect")
+// sortBatchResults acknowledges the messages a per-message batch write
+// delivered and sorts the others into those worth retrying, returned with
+// their errors, and those the sink rejected for good. The returned error
+// stands for the retryable failures: fatal if any message hit a
+// configuration error, throttled with the longest hint if any was throttled,
+// and otherwise the last transient failure.
+func (e *Engine) sortBatchResults(ctx context.Context, snk hermod.Sink, msgs []hermod.Message, errs []error, sinkID string, start time.Time) (retry []hermod.Message, retryErrs []error, rejected []hermod.Message, err error) {
+	written := make([]hermod.Message, 0, len(msgs))
+	var fatal, throttled, transient error
+	var retryAfter time.Duration
+	for k, m := range msgs {
+		var merr error
+		if k < len(errs) {
+			merr = errs[k]
 		}
-		telemetry.SinkWriteCount.WithLabelValues(e.workflowID, sinkID).Add(float64(len(msgs)))
-		e.logger.Info("Batch written to sink",
-			"workflow_id", e.workflowID,
-			"sink_id", sinkID,
-			"action", "write_batch",
-			"batch_size", len(msgs),
-		)
-		lastErr = nil
-		break
+		if merr == nil {
+			written = append(written, m)
+			continue
+		}
+		class, after := classifySinkError(snk, merr)
+		switch class {
+		case hermod.ErrorPermanent:
+			e.logger.Warn("Sink rejected message, not retrying", "workflow_id", e.workflowID, "sink_id", sinkID, "message_id", m.ID(), "error", merr)
+			m.SetMetadata("_hermod_last_error", merr.Error())
+			rejected = append(rejected, m)
+			continue
+		case hermod.ErrorFatalConfig:
+			fatal = merr
+		case hermod.ErrorThrottled:
+			throttled = merr
+			retryAfter = max(retryAfter, after)
+		default:
+			transient = merr
+		}
+		retry = append(retry, m)
+		retryErrs = append(retryErrs, merr)
+	}
+	e.recordBatchWritten(ctx, written, sinkID, start)
+	if failed := len(msgs) - len(written); failed > 0 {
+		telemetry.SinkWriteErrors.WithLabelValues(e.workflowID, sinkID).Add(float64(failed))
 	}
 
-	if lastErr != nil {
-		e.logger.Error("Sink batch write failed after retries", "workflow_id", e.workflowID, "sink_id", sinkID, "error", lastErr)
-		span.RecordError(lastErr)
-		span.SetStatus(codes.Error, lastErr.Error())
+	switch {
+	case fatal != nil:
+		err = hermod.Classify(fatal, hermod.ErrorFatalConfig)
+	case throttled != nil:
+		err = hermod.Throttled(throttled, retryAfter)
+	case transient != nil:
+		err = hermod.Classify(transient, hermod.ErrorTransient)
+	}
+	return retry, retryErrs, rejected, err
+}
 
-		if e.deadLetterSink != nil || len(msgs) > 1 {
-			e.logger.Warn("Batch write failed, attempting individual writes to isolate errors", "workflow_id", e.workflowID, "sink_id", sinkID, "batch_size", len(msgs))
+// divertBatchFailures dead-letters the messages of a per-message batch write
+// that the sink rejected, which already carry their error, and those that
+// failed every attempt with failedErrs. Without a dead letter sink or store
+// they cannot be set aside, so an error is returned instead and the batch is
+// not acknowledged.
+func (e *Engine) divertBatchFailures(ctx context.Context, sinkID string, rejected, failed []hermod.Message, failedErrs []error) error {
+	if len(rejected)+len(failed) == 0 {
+		return nil
+	}
+	if !e.canDeadLetter() {
+		return fmt.Errorf("sink write error: %d messages of the batch failed and there is no dead letter sink", len(rejected)+len(failed))
+	}
+	for k, m := range failed {
+		m.SetMetadata("_hermod_last_error", failedErrs[k].Error())
+	}
+	return errors.Join(
+		e.writeToDLQ(ctx, sinkID, dlqClassPermanent, nil, rejected...),
+		e.writeToDLQ(ctx, sinkID, dlqClassSinkWrite, nil, failed...),
+	)
+}
 
-			allSucceeded := true
-			for _, m := range msgs {
-				if m == nil {
-					continue
-				}
-				// Use writeToSink for individual processing (which already handles DLQ)
-				if err := e.writeToSink(ctx, snk, m, sinkID, i); err != nil {
-					allSucceeded = false
-					// We continue with other messages in the batch instead of stopping
-					// writeToSink already logged the error and handled DLQ if available
-				}
-			}
+// divertBatch dead-letters messages still failing as a batch once retrying
+// is over: throttled past the throttle timeout, or failing transiently after
+// every attempt. That is the destination's state, not a message's fault, so
+// splitting them up would only wait it out again for every part.
+func (e *Engine) divertBatch(ctx context.Context, sinkID string, err error, msgs []hermod.Message) error {
+	if !e.canDeadLetter() {
+		return fmt.Errorf("sink write error: %w", err)
+	}
+	e.logger.Info("Sending unwritten batch to Dead Letter Queue", "workflow_id", e.workflowID, "sink_id", sinkID, "batch_size", len(msgs), "error", err)
+	return e.writeToDLQ(ctx, sinkID, dlqClassSinkWrite, err, msgs...)
+}
 
-			if allSucceeded {
-				return nil
+// bisectBatch writes each half of a batch a message rejected, splitting again
+// any half that is rejected, so n messages with one poison record cost about
+// 2·log2(n) extra writes. Halves are retried like the batch, so only a
+// permanent error splits them further; single messages go through
+// writeToSink.
+func (e *Engine) bisectBatch(ctx context.Context, snk hermod.BatchSink, msgs []hermod.Message, sinkID string, i int) error {
+	if len(msgs) == 1 {
+		return e.writeToSink(ctx, snk, msgs[0], sinkID, i)
+	}
+	maxRetries, _ := e.retryPolicy(i)
+	mid := len(msgs) / 2
+	var firstErr error
+	for _, half := range [][]hermod.Message{msgs[:mid], msgs[mid:]} {
+		var err error
+		if len(half) == 1 {
+			err = e.writeToSink(ctx, snk, half[0], sinkID, i)
+		} else if pending, class, herr := e.tryWriteBatch(ctx, snk, half, sinkID, i, maxRetries); herr != nil {
+			switch {
+			case ctx.Err() != nil:
+				return herr
+			case class == hermod.ErrorFatalConfig:
+				return e.stopOnFatalConfig(sinkID, herr)
+			case len(pending) == 0:
+				err = herr
+			case class != hermod.ErrorPermanent:
+				err = e.divertBatch(ctx, sinkID, herr, pending)
+			default:
+				err = e.bisectBatch(ctx, snk, pending, sinkID, i)
 			}
-
-			// If some failed even after individual attempts, and we don't have a DLQ
-			// for those individual failures, writeToSink would have returned an error.
-			// But here we want to return nil if we managed to process the whole batch
-			// (either by success or by DLQing the individual failures).
-			// If writeToSink returned nil for all messages (either success or DLQ),
-			// then allSucceeded will be true.
-			// If it returned error for any message (meaning no DLQ or DLQ failed),
-			// then we still have a problem.
-			if !allSucceeded {
-				return fmt.Errorf("sink batch write failed and some messages could not be diverted: %w", lastErr)
+		}
+		if err != nil {
+			if hermod.IsFatalConfig(err) || ctx.Err() != nil {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
 			}
-			return nil
 		}
+	}
+	return firstErr
+}
 
-		return fmt.Errorf("sink batch write error: %w", lastErr)
+// maxThrottleDelay caps a destination's retry hint so a bogus Retry-After
+// cannot park a sink writer for hours.
+const maxThrottleDelay = 5 * time.Minute
+
+// retryPolicy returns the retry budget and base interval for sink i.
+func (e *Engine) retryPolicy(i int) (int, time.Duration) {
+	maxRetries := e.config.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 1
+	}
+	retryInterval := e.config.RetryInterval
+	if i >= 0 && i < len(e.sinkConfigs) {
+		if e.sinkConfigs[i].MaxRetries > 0 {
+			maxRetries = e.sinkConfigs[i].MaxRetries
+		}
+		if e.sinkConfigs[i].RetryInterval > 0 {
+			retryInterval = e.sinkConfigs[i].RetryInterval
+		}
+	}
+	return maxRetries, retryInterval
+}
+
+// backoff returns the delay before retry j (0-based) of sink i: its
+// configured RetryIntervals when set, a linear backoff otherwise.
+func (e *Engine) backoff(i, j int, retryInterval time.Duration) time.Duration {
+	if i >= 0 && i < len(e.sinkConfigs) && len(e.sinkConfigs[i].RetryIntervals) > 0 {
+		intervals := e.sinkConfigs[i].RetryIntervals
+		return intervals[min(j, len(intervals)-1)]
+	}
+	return time.Duration(j+1) * retryInterval
+}
+
+// defaultThrottleTimeout bounds how long a write waits out throttling when
+// neither the engine nor the sink sets a throttle timeout.
+const defaultThrottleTimeout = 15 * time.Minute
+
+// throttleRemaining returns how much longer a write throttled since *since may
+// keep waiting for sink i, starting the clock at the first throttle. It is not
+// positive once the throttle timeout has run out.
+func (e *Engine) throttleRemaining(i int, since *time.Time) time.Duration {
+	timeout := e.config.ThrottleTimeout
+	if i >= 0 && i < len(e.sinkConfigs) && e.sinkConfigs[i].ThrottleTimeout > 0 {
+		timeout = e.sinkConfigs[i].ThrottleTimeout
+	}
+	if timeout <= 0 {
+		timeout = defaultThrottleTimeout
+	}
+	if since.IsZero() {
+		*since = time.Now()
+	}
+	return timeout - time.Since(*since)
+}
+
+// throttleWait waits at least as long as the destination asked, and never
+// less than the normal backoff.
+func throttleWait(hint, backoff time.Duration) time.Duration {
+	return min(max(hint, backoff), maxThrottleDelay)
+}
+
+func classifySinkError(snk hermod.Sink, err error) (hermod.ErrorClass, time.Duration) {
+	c, _ := snk.(hermod.ErrorClassifier)
+	return hermod.ClassifyError(err, c)
+}
+
+// stopOnFatalConfig stops the workflow after a sink reported that its
+// configuration is broken. Every further write would fail the same way, so
+// retrying would only dead-letter good data. The error ends up in the
+// workflow status; the message is not acknowledged and is delivered again
+// once the sink is fixed.
+func (e *Engine) stopOnFatalConfig(sinkID string, err error) error {
+	if errors.Is(err, hermod.ErrFenced) {
+		return e.stopFenced(fmt.Errorf("sink %s: %w", sinkID, err))
+	}
+	fatal := fmt.Errorf("sink %s configuration error: %w", sinkID, hermod.Classify(err, hermod.ErrorFatalConfig))
+	if e.fatalErr.CompareAndSwap(nil, &fatal) {
+		e.logger.Error("Sink configuration error, stopping workflow", "workflow_id", e.workflowID, "sink_id", sinkID, "error", err)
+		e.setSinkStatus(sinkID, "error")
+		e.cancelRunner()
+	}
+	return fatal
+}
+
+// stopFenced stops the workflow after its fencing token was rejected: another
+// worker holds the lease now and this one must not write anything more.
+func (e *Engine) stopFenced(err error) error {
+	if e.fatalErr.CompareAndSwap(nil, &err) {
+		e.logger.Warn("Fenced off by a newer lease holder, stopping workflow", "workflow_id", e.workflowID, "fencing_token", e.fencingToken.Load(), "error", err)
+		e.cancelRunner()
+	}
+	return err
+}
+
+func (e *Engine) fenced() bool {
+	err := e.fatalErr.Load()
+	return err != nil && errors.Is(*err, hermod.ErrFenced)
+}
+
+func (e *Engine) cancelRunner() {
+	if e.runner != nil && e.runner.cancel != nil {
+		e.runner.cancel()
 	}
-	return nil
 }
 
 func (sw *sinkWriter) checkCircuitBreaker() error {
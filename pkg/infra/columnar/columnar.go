@@ -0,0 +1,224 @@
+// Package columnar converts the loosely typed values of rows, as sources and
+// JSON decoding produce them, to the values of typed columns. The sinks that
+// write columnar files share it, so that they infer the same column types and
+// accept and refuse the same values.
+package columnar
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of column a value suggests.
+type Kind string
+
+// Kinds Infer tells apart.
+const (
+	KindUnknown   Kind = ""
+	KindBoolean   Kind = "boolean"
+	KindLong      Kind = "long"
+	KindDouble    Kind = "double"
+	KindString    Kind = "string"
+	KindBinary    Kind = "binary"
+	KindDate      Kind = "date"
+	KindUUID      Kind = "uuid"
+	KindList      Kind = "list"
+	KindMap       Kind = "map"
+	KindTimestamp Kind = "timestamp"
+	// KindTimestampTZ is a timestamp that is an instant rather than a local
+	// date-time, such as a time.Time or text with a time zone.
+	KindTimestampTZ Kind = "timestamptz"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Infer returns the kind of column a value first seen in it suggests. Whole
+// numbers are longs, even when JSON decoding made them float64, and text
+// that is a UUID, an ISO date or an ISO timestamp is of that kind. Nil is
+// KindUnknown.
+func Infer(v any) Kind {
+	switch x := v.(type) {
+	case nil:
+		return KindUnknown
+	case bool:
+		return KindBoolean
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32:
+		return KindLong
+	case uint64:
+		if x <= math.MaxInt64 {
+			return KindLong
+		}
+		return KindDouble
+	case float32:
+		return KindDouble
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return KindLong
+		}
+		return KindDouble
+	case json.Number:
+		if _, err := x.Int64(); err == nil {
+			return KindLong
+		}
+		return KindDouble
+	case time.Time:
+		return KindTimestampTZ
+	case []byte:
+		return KindBinary
+	case string:
+		if uuidPattern.MatchString(x) {
+			return KindUUID
+		}
+		if _, err := time.Parse(time.DateOnly, x); err == nil {
+			return KindDate
+		}
+		if t, zoned, ok := ParseTime(x); ok && !t.IsZero() {
+			if zoned {
+				return KindTimestampTZ
+			}
+			return KindTimestamp
+		}
+		return KindString
+	case []any:
+		return KindList
+	case map[string]any:
+		return KindMap
+	}
+	return KindString
+}
+
+// Bool reads a boolean from a bool or its text.
+func Bool(v any) (bool, bool) {
+	switch x := v.(type) {
+	case bool:
+		return x, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(x))
+		return b, err == nil
+	}
+	return false, false
+}
+
+// Int64 reads a whole number from an integer, a float without a fraction, a
+// json.Number or decimal text.
+func Int64(v any) (int64, bool) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), true
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case uint:
+		return int64(x), x <= math.MaxInt64
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		return int64(x), x <= math.MaxInt64
+	case float32:
+		return Int64(float64(x))
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<63 {
+			return int64(x), true
+		}
+	case json.Number:
+		n, err := x.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// Float64 reads a number from any numeric value or its text.
+func Float64(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	if n, ok := Int64(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// Time reads a timestamp from a time.Time, text ParseTime reads, or a
+// number of milliseconds since the epoch.
+func Time(v any) (time.Time, bool) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, true
+	case string:
+		t, _, ok := ParseTime(x)
+		return t, ok
+	}
+	if n, ok := Int64(v); ok {
+		return time.UnixMilli(n).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// Date reads a date as Time does, except that numbers count days since the
+// epoch.
+func Date(v any) (time.Time, bool) {
+	if _, isString := v.(string); !isString {
+		if n, ok := Int64(v); ok {
+			return time.Unix(n*86400, 0).UTC(), true
+		}
+	}
+	return Time(v)
+}
+
+// ParseTime parses the common textual timestamp layouts and ISO dates.
+// zoned reports whether the text carried a time zone; text without one is
+// read as UTC.
+func ParseTime(s string) (t time.Time, zoned bool, ok bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true, true
+		}
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999", time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, false, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// String renders a value for a text column: text as is, timestamps in
+// RFC 3339 and everything else as JSON.
+func String(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case time.Time:
+		return x.Format(time.RFC3339Nano), nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
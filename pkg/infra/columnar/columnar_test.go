@@ -0,0 +1,74 @@
+package columnar
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestInfer(t *testing.T) {
+	cases := []struct {
+		v    any
+		want Kind
+	}{
+		{nil, KindUnknown},
+		{true, KindBoolean},
+		{float64(3), KindLong},
+		{1.5, KindDouble},
+		{json.Number("7"), KindLong},
+		{json.Number("7.5"), KindDouble},
+		{uint64(1 << 63), KindDouble},
+		{"2024-05-01", KindDate},
+		{"2024-05-01 10:00:00", KindTimestamp},
+		{"2024-05-01T10:00:00+02:00", KindTimestampTZ},
+		{time.Now(), KindTimestampTZ},
+		{"0f8fad5b-d9cb-469f-a165-70867728950e", KindUUID},
+		{"hello", KindString},
+		{[]byte("x"), KindBinary},
+		{[]any{1}, KindList},
+		{map[string]any{"a": 1}, KindMap},
+	}
+	for _, c := range cases {
+		if got := Infer(c.v); got != c.want {
+			t.Errorf("Infer(%#v) = %q, want %q", c.v, got, c.want)
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	if n, ok := Int64(" 42 "); !ok || n != 42 {
+		t.Errorf("Int64 of padded text = %d, %v", n, ok)
+	}
+	if _, ok := Int64(1.5); ok {
+		t.Error("Int64 accepted a fraction")
+	}
+	if _, ok := Int64(uint64(1 << 63)); ok {
+		t.Error("Int64 accepted an overflowing uint64")
+	}
+	if f, ok := Float64(json.Number("2.5")); !ok || f != 2.5 {
+		t.Errorf("Float64 = %v, %v", f, ok)
+	}
+	if b, ok := Bool(" true"); !ok || !b {
+		t.Errorf("Bool = %v, %v", b, ok)
+	}
+
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, v := range []any{want, "2024-05-01T10:00:00Z", " 2024-05-01 10:00:00", want.UnixMilli(), json.Number("1714557600000")} {
+		if got, ok := Time(v); !ok || !got.Equal(want) {
+			t.Errorf("Time(%#v) = %v, %v", v, got, ok)
+		}
+	}
+	if d, ok := Date(19844); !ok || d.Format(time.DateOnly) != "2024-05-01" {
+		t.Errorf("Date of days = %v, %v", d, ok)
+	}
+	if d, ok := Date("2024-05-01"); !ok || !d.Equal(want.Truncate(24*time.Hour)) {
+		t.Errorf("Date of text = %v, %v", d, ok)
+	}
+
+	if s, _ := String(map[string]any{"a": 1}); s != `{"a":1}` {
+		t.Errorf("String of a map = %s", s)
+	}
+	if s, _ := String(want); s != "2024-05-01T10:00:00Z" {
+		t.Errorf("String of a time = %s", s)
+	}
+}
@@ -35,12 +35,53 @@ func (e *Evaluator) EvaluateAdvancedExpression(msg hermod.Message, expr any) any
 	return e.ParseAndEvaluate(msg, valStr)
 }
 
+// ParseAndEvaluate is Evaluate for callers that cannot handle an error: it
+// logs the error and yields nil.
 func (e *Evaluator) ParseAndEvaluate(msg hermod.Message, expr string) any {
+	v, err := e.Evaluate(msg, expr)
+	if err != nil {
+		LogError(nil, err)
+		return nil
+	}
+	return v
+}
+
+// EvaluateValue is Evaluate for a configuration value, which is evaluated
+// when it is a string and returned as it is otherwise.
+func (e *Evaluator) EvaluateValue(msg hermod.Message, expr any) (any, error) {
+	valStr, ok := expr.(string)
+	if !ok {
+		return expr, nil
+	}
+	return e.Evaluate(msg, valStr)
+}
+
+// Evaluate evaluates an expression from configuration. Source references,
+// literals and calls of the evaluator's functions, such as
+// upper(source.name), are evaluated as they always were. Other expressions
+// are compiled once (see Expression), and text that is not an expression is
+// returned as it is. So is text that only reads as arithmetic on numbers or
+// on names that are not fields of msg, such as 2024-01-01 or N/A. The error is that of an expression failing at run
+// time, such as a division of a string, as an *ExpressionError.
+func (e *Evaluator) Evaluate(msg hermod.Message, expr string) (any, error) {
 	expr = strings.TrimSpace(expr)
 	if expr == "" {
-		return nil
+		return nil, nil
+	}
+	if !isLegacyExpression(expr) {
+		if x, err := cachedCompile(expr, false); err == nil && !x.bare && !x.isText(msg) {
+			v, err := x.Eval(msg)
+			if err != nil {
+				return nil, &ExpressionError{Expression: expr, Err: err}
+			}
+			return outputValue(v), nil
+		}
 	}
+	return e.parseLegacy(msg, expr), nil
+}
 
+// parseLegacy evaluates an expression by scanning it.
+func (e *Evaluator) parseLegacy(msg hermod.Message, expr string) any {
 	// Check if it's a source reference: source.path
 	if strings.HasPrefix(expr, "source.") {
 		return GetMsgValByPath(msg, expr[7:])
@@ -670,6 +711,23 @@ func ToTime(val any) (time.Time, bool) {
 // loop that occurs when a resolved value itself contains a self-referential
 // {{ ... }} token (e.g. data field "a" whose value is literally "{{a}}").
 func ResolveTemplate(temp string, data map[string]any) string {
+	return resolveTemplate(temp, data, nil)
+}
+
+// ResolveJSONTemplate is ResolveTemplate for JSON documents: every resolved
+// value is escaped as the contents of a JSON string, so a quote or newline in
+// the data cannot break the document or inject fields. Numbers and booleans
+// are unchanged and can still be placed outside quotes.
+func ResolveJSONTemplate(temp string, data map[string]any) string {
+	return resolveTemplate(temp, data, func(val string) string {
+		quoted, _ := json.Marshal(val)
+		return string(quoted[1 : len(quoted)-1])
+	})
+}
+
+// resolveTemplate implements ResolveTemplate, passing every resolved value
+// through escape when it is non-nil.
+func resolveTemplate(temp string, data map[string]any, escape func(string) string) string {
 	var out strings.Builder
 	i := 0
 	for i < len(temp) {
@@ -689,7 +747,11 @@ func ResolveTemplate(temp string, data map[string]any) string {
 		}
 		end := start + 2 + closeRel
 		path := strings.TrimSpace(temp[start+2 : end])
-		out.WriteString(resolveTemplatePath(path, data))
+		val := resolveTemplatePath(path, data)
+		if escape != nil {
+			val = escape(val)
+		}
+		out.WriteString(val)
 
 		// Advance past the closing "}}" so the substituted value is not
 		// processed again, guaranteeing termination.
@@ -738,12 +800,43 @@ func EvaluateField(msg hermod.Message, field string) any {
 
 // Condition evaluator
 
+// EvaluateConditions is MatchConditions for callers that cannot handle an
+// error: it logs the error and reports the conditions as not met.
 func EvaluateConditions(msg hermod.Message, conditions []map[string]any) bool {
+	ok, err := MatchConditions(msg, conditions)
+	if err != nil {
+		LogError(nil, err)
+		return false
+	}
+	return ok
+}
+
+// MatchConditions reports whether msg meets all conditions. A condition is
+// either a field, operator and value, or an expression such as
+// {"expression": "amount > 10000 && currency == 'EUR'"}, which is compiled
+// once. An expression that fails to compile or evaluate is an
+// *ExpressionError.
+func MatchConditions(msg hermod.Message, conditions []map[string]any) (bool, error) {
 	if len(conditions) == 0 {
-		return true
+		return true, nil
 	}
 
 	for _, cond := range conditions {
+		if src, _ := cond["expression"].(string); src != "" {
+			x, err := cachedCompile(src, true)
+			if err != nil {
+				return false, &ExpressionError{Expression: src, Err: err, condition: true}
+			}
+			ok, err := x.EvalBool(msg)
+			if err != nil {
+				return false, &ExpressionError{Expression: src, Err: err, condition: true}
+			}
+			if !ok {
+				return false, nil
+			}
+			continue
+		}
+
 		field, _ := cond["field"].(string)
 		op, _ := cond["operator"].(string)
 		val := cond["value"]
@@ -840,11 +933,11 @@ func EvaluateConditions(msg hermod.Message, conditions []map[string]any) bool {
 		}
 
 		if !match {
-			return false
+			return false, nil
 		}
 	}
 
-	return true
+	return true, nil
 }
 
 type mockMessage struct {
@@ -0,0 +1,530 @@
+package evaluator
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"github.com/expr-lang/expr/vm"
+	"github.com/user/hermod"
+)
+
+// Expression is an expression compiled and type-checked once, then evaluated
+// against many messages.
+//
+// The language is expr (https://expr-lang.org): infix arithmetic, comparison
+// and logic, null-safe navigation (a?.b, a ?? b), list and map functions
+// (filter, map, all, any, len, keys, ...), time and duration arithmetic
+// (now() - duration("1h")), and regular expressions (s matches "^a").
+//
+// Bare identifiers are fields of the message: its data first, then the
+// fields GetMsgValByPath exposes, such as operation, table and after. The
+// data is also available as source, so the expressions of the older
+// evaluator, such as upper(source.name), keep working, and metadata holds
+// the message metadata. The functions of the older evaluator are all
+// defined, including and(), or(), not(), if() and contains().
+type Expression struct {
+	src     string
+	program *vm.Program
+	// fields are the bare identifiers read from the message.
+	fields []string
+	// bare reports that the expression is a lone identifier or a path from
+	// one, which the older evaluator read as a string.
+	bare bool
+	// arithmetic reports that the expression only does arithmetic on numbers
+	// and identifiers, as text such as 2024-01-01, N/A or application/json
+	// does. See isText.
+	arithmetic bool
+}
+
+// Variables of every expression.
+const (
+	sourceVar   = "source"
+	metadataVar = "metadata"
+)
+
+// legacyKeywords are functions of the older evaluator whose names are
+// operators in the language. Calls to them are renamed.
+var legacyKeywords = map[string]bool{"and": true, "or": true, "not": true, "if": true, "contains": true}
+
+// legacyFunctions are the functions of the older evaluator, implemented by
+// CallFunction, with the signatures they are type-checked against: calls
+// with the wrong number of arguments, or whose result is used as the wrong
+// type, such as upper(name) > 1, do not compile. Arguments are converted by
+// the functions, so they are not checked. now() is not among them: the
+// language's now() returns a time, for arithmetic, and is formatted as
+// before where a value is output.
+var legacyFunctions = map[string][]any{
+	"lower":       {new(func(any) string)},
+	"upper":       {new(func(any) string)},
+	"trim":        {new(func(any) string)},
+	"replace":     {new(func(any, any, any) string)},
+	"concat":      {new(func(...any) string)},
+	"substring":   {new(func(any, any) string), new(func(any, any, any) string)},
+	"date_format": {new(func(any, any) string), new(func(any, any, any) string)},
+	"coalesce":    {new(func(...any) any)},
+	"uuid":        {new(func() string)},
+	"timestamp":   {new(func() int64)},
+	"env":         {new(func(any) string), new(func(any, any) any)},
+	"secret":      {new(func(any) string), new(func(any, any) any)},
+	"add":         {new(func(any, any) float64)},
+	"sub":         {new(func(any, any) float64)},
+	"mul":         {new(func(any, any) float64)},
+	"div":         {new(func(any, any) any)}, // nil when dividing by zero
+	"round":       {new(func(any) float64), new(func(any, any) float64)},
+	"eq":          {new(func(any, any) bool)},
+	"gt":          {new(func(any, any) bool)},
+	"lt":          {new(func(any, any) bool)},
+	"toint":       {new(func(any) int64)},
+	"tofloat":     {new(func(any) float64)},
+	"tostring":    {new(func(any) string)},
+	"tobool":      {new(func(any) bool)},
+	"todate":      {new(func(any) any), new(func(any, any) any)}, // nil when unparsable
+	"and":         {new(func(...any) bool)},
+	"or":          {new(func(...any) bool)},
+	"not":         {new(func(any) bool)},
+	"if":          {new(func(any, any, any) any)},
+	"contains":    {new(func(any, any) bool)},
+}
+
+var exprOptions = func() []expr.Option {
+	opts := []expr.Option{
+		// Metadata values are strings, so metadata.x > 1 does not compile.
+		expr.Env(map[string]any{sourceVar: map[string]any{}, metadataVar: map[string]string{}}),
+		expr.AllowUndefinedVariables(),
+	}
+	e := NewEvaluator()
+	for name, types := range legacyFunctions {
+		opts = append(opts, expr.Function(exprName(name), func(args ...any) (any, error) {
+			return e.CallFunction(name, args), nil
+		}, types...))
+	}
+	return opts
+}()
+
+// exprFunctions are the functions defined by exprOptions.
+var exprFunctions = func() map[string]bool {
+	names := make(map[string]bool)
+	for name := range legacyFunctions {
+		names[exprName(name)] = true
+	}
+	return names
+}()
+
+func legacyName(name string) string {
+	return "_legacy_" + name
+}
+
+// exprName is the name a function of the older evaluator has in the
+// language.
+func exprName(name string) string {
+	if legacyKeywords[name] {
+		return legacyName(name)
+	}
+	return name
+}
+
+// Compile compiles an expression.
+func Compile(src string) (*Expression, error) {
+	return compile(src, false)
+}
+
+// CompileCondition compiles an expression that must yield a boolean.
+func CompileCondition(src string) (*Expression, error) {
+	return compile(src, true)
+}
+
+func compile(src string, condition bool) (*Expression, error) {
+	src = strings.TrimSpace(src)
+	if src == "" {
+		return nil, errors.New("empty expression")
+	}
+	opts := exprOptions
+	if condition {
+		opts = append(opts[:len(opts):len(opts)], expr.AsBool())
+	}
+	program, err := expr.Compile(renameLegacyCalls(src), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+
+	e := &Expression{src: src, program: program}
+	var idents []string
+	notFields := map[string]bool{sourceVar: true, metadataVar: true}
+	ast.Find(program.Node(), func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallNode:
+			if callee, ok := n.Callee.(*ast.IdentifierNode); ok {
+				notFields[callee.Value] = true
+			}
+		case *ast.IdentifierNode:
+			idents = append(idents, n.Value)
+		}
+		return false
+	})
+	for name := range notFields {
+		if name != sourceVar && name != metadataVar && !exprFunctions[name] {
+			return nil, fmt.Errorf("invalid expression %q: unknown function %s", src, name)
+		}
+	}
+	for _, name := range idents {
+		if !notFields[name] && !slices.Contains(e.fields, name) {
+			e.fields = append(e.fields, name)
+		}
+	}
+	root := program.Node()
+	for {
+		m, ok := root.(*ast.MemberNode)
+		if !ok {
+			break
+		}
+		root = m.Node
+	}
+	if id, ok := root.(*ast.IdentifierNode); ok && id.Value != sourceVar && id.Value != metadataVar {
+		e.bare = true
+	}
+	// Read from the tree as written: compiling folds 2024-01-01 into 2022.
+	tree, err := parser.Parse(renameLegacyCalls(src))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", src, err)
+	}
+	_, binary := tree.Node.(*ast.BinaryNode)
+	e.arithmetic = binary && ast.Find(tree.Node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IdentifierNode, *ast.IntegerNode, *ast.FloatNode:
+			return false
+		case *ast.UnaryNode:
+			return n.Operator != "-" && n.Operator != "+"
+		case *ast.BinaryNode:
+			return !arithmeticOperators[n.Operator]
+		}
+		return true
+	}) == nil
+	return e, nil
+}
+
+var arithmeticOperators = map[string]bool{"+": true, "-": true, "*": true, "/": true, "%": true, "**": true, "^": true}
+
+// isText reports whether the expression is text the older evaluator read as
+// a constant rather than arithmetic: it only does arithmetic on numbers, such
+// as 2024-01-01, or on identifiers that are not all fields of msg, such as
+// US/Eastern.
+func (e *Expression) isText(msg hermod.Message) bool {
+	if !e.arithmetic {
+		return false
+	}
+	if len(e.fields) == 0 || msg == nil {
+		return true
+	}
+	for _, f := range e.fields {
+		if _, ok := msg.DataRef()[f]; !ok && GetMsgValByPath(msg, f) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// renameLegacyCalls renames calls to legacyKeywords, such as and(a, b),
+// leaving their use as operators, such as a and (b), alone.
+func renameLegacyCalls(src string) string {
+	var out strings.Builder
+	var quote byte
+	prev := byte('(') // the last significant character outside strings
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				out.WriteByte(src[i])
+			} else if c == quote {
+				quote = 0
+				prev = c
+			}
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case isIdentStart(c):
+			j := i
+			for j < len(src) && isIdentPart(src[j]) {
+				j++
+			}
+			word := src[i:j]
+			if legacyKeywords[strings.ToLower(word)] && j < len(src) && src[j] == '(' && strings.IndexByte("(,[?:", prev) >= 0 {
+				word = legacyName(strings.ToLower(word))
+			}
+			out.WriteString(word)
+			prev = src[j-1]
+			i = j - 1
+			continue
+		}
+		out.WriteByte(c)
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			prev = c
+		}
+	}
+	return out.String()
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against msg, which may be nil.
+func (e *Expression) Eval(msg hermod.Message) (any, error) {
+	env := make(map[string]any, len(e.fields)+2)
+	if msg != nil {
+		env[sourceVar] = msg.DataRef()
+		env[metadataVar] = msg.MetadataRef()
+		for _, f := range e.fields {
+			v, ok := msg.DataRef()[f]
+			if !ok {
+				v = GetMsgValByPath(msg, f)
+			}
+			env[f] = v
+		}
+	} else {
+		env[sourceVar] = map[string]any{}
+		env[metadataVar] = map[string]string{}
+	}
+	v, err := expr.Run(e.program, env)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", e.src, err)
+	}
+	return v, nil
+}
+
+// EvalBool evaluates the expression as a condition. A nil result is false.
+func (e *Expression) EvalBool(msg hermod.Message) (bool, error) {
+	v, err := e.Eval(msg)
+	if err != nil || v == nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q yields %T, not a boolean", e.src, v)
+	}
+	return b, nil
+}
+
+// compiled is an LRU of the expressions compiled for callers that do not
+// keep them, such as conditions and mappings read from configuration.
+// maxCompiled bounds it; the least recently used expressions are evicted.
+var compiled = struct {
+	sync.Mutex
+	ll    *list.List
+	exprs map[string]*list.Element
+}{ll: list.New(), exprs: make(map[string]*list.Element)}
+
+const maxCompiled = 10000
+
+type compiledExpr struct {
+	key  string
+	expr *Expression
+	err  error
+	// lastLogged is when an error of the expression was last logged, in
+	// Unix nanoseconds.
+	lastLogged atomic.Int64
+}
+
+func compiledKey(src string, condition bool) string {
+	if condition {
+		return "?" + src
+	}
+	return src
+}
+
+// cachedCompile compiles src once.
+func cachedCompile(src string, condition bool) (*Expression, error) {
+	key := compiledKey(src, condition)
+	compiled.Lock()
+	if el, ok := compiled.exprs[key]; ok {
+		compiled.ll.MoveToFront(el)
+		c := el.Value.(*compiledExpr)
+		compiled.Unlock()
+		return c.expr, c.err
+	}
+	compiled.Unlock()
+
+	e, err := compile(src, condition)
+	compiled.Lock()
+	defer compiled.Unlock()
+	if _, ok := compiled.exprs[key]; !ok {
+		compiled.exprs[key] = compiled.ll.PushFront(&compiledExpr{key: key, expr: e, err: err})
+		for compiled.ll.Len() > maxCompiled {
+			oldest := compiled.ll.Back()
+			compiled.ll.Remove(oldest)
+			delete(compiled.exprs, oldest.Value.(*compiledExpr).key)
+		}
+	}
+	return e, err
+}
+
+// errorLogInterval is how often the errors of one expression are logged.
+const errorLogInterval = time.Minute
+
+// ExpressionError is the error of an expression from configuration that
+// fails to compile or evaluate.
+type ExpressionError struct {
+	Expression string
+	Err        error
+	// condition reports that the expression was compiled as a condition.
+	condition bool
+}
+
+func (e *ExpressionError) Error() string { return e.Err.Error() }
+
+func (e *ExpressionError) Unwrap() error { return e.Err }
+
+// LogError logs err to logger, or to the standard logger when it is nil. An
+// *ExpressionError is logged at most once per errorLogInterval for its
+// expression, so an expression failing for every message does not flood the
+// log.
+func LogError(logger hermod.Logger, err error) {
+	var exprErr *ExpressionError
+	if !errors.As(err, &exprErr) {
+		logWarn(logger, err)
+		return
+	}
+	compiled.Lock()
+	el, ok := compiled.exprs[compiledKey(strings.TrimSpace(exprErr.Expression), exprErr.condition)]
+	compiled.Unlock()
+	if ok {
+		c := el.Value.(*compiledExpr)
+		now, last := time.Now().UnixNano(), c.lastLogged.Load()
+		if now-last < int64(errorLogInterval) || !c.lastLogged.CompareAndSwap(last, now) {
+			return
+		}
+	}
+	logWarn(logger, err)
+}
+
+func logWarn(logger hermod.Logger, err error) {
+	if logger == nil {
+		log.Printf("[WARN] Expression failed: %v", err)
+		return
+	}
+	logger.Warn("Expression failed", "error", err)
+}
+
+// outputValue converts the result of an expression to the values the older
+// evaluator produced: integers as float64, times as RFC 3339 strings and
+// durations as strings.
+func outputValue(v any) any {
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case time.Time:
+		return x.Format(time.RFC3339)
+	case time.Duration:
+		return x.String()
+	}
+	return v
+}
+
+// isLegacyExpression reports whether src is a source path or a call of a
+// function of the older evaluator, which ParseAndEvaluate evaluates as it
+// always did. Their arguments may be expressions of the language.
+func isLegacyExpression(src string) bool {
+	if path, ok := strings.CutPrefix(src, "source."); ok {
+		return strings.IndexFunc(path, func(r rune) bool { return strings.ContainsRune(" +*/%<>=!&|?:()[]'\"", r) }) < 0
+	}
+	open := strings.IndexByte(src, '(')
+	if open <= 0 || !strings.HasSuffix(src, ")") || !legacyCallNames[strings.ToLower(src[:open])] {
+		return false
+	}
+	// The parenthesis opened after the name must close at the end.
+	depth := 0
+	var quote byte
+	for i := open; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i == len(src)-1
+			}
+		}
+	}
+	return false
+}
+
+// legacyCallNames are the functions CallFunction implements.
+var legacyCallNames = func() map[string]bool {
+	names := map[string]bool{"now": true}
+	for name := range legacyFunctions {
+		names[name] = true
+	}
+	return names
+}()
+
+// isLegacyConstant reports whether the older evaluator read src as a string
+// constant: it is not a source reference, a function call or an operation.
+func isLegacyConstant(src string) bool {
+	if strings.HasPrefix(src, "source.") || (strings.HasSuffix(src, ")") && strings.Contains(src, "(")) {
+		return false
+	}
+	for _, op := range []string{"==", "!=", ">=", "<=", "&&", "||", "?.", "??", " matches ", " in "} {
+		if strings.Contains(src, op) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckExpression reports the compile error of an expression from
+// configuration. Text the older evaluator read as a string constant, such as
+// "hello world", is accepted as one.
+func CheckExpression(src string) error {
+	src = strings.TrimSpace(src)
+	if src == "" {
+		return nil
+	}
+	if isLegacyExpression(src) {
+		return nil
+	}
+	if _, err := cachedCompile(src, false); err != nil && !isLegacyConstant(src) {
+		return err
+	}
+	return nil
+}
+
+// CheckConditions reports the compile error of the first condition whose
+// expression is invalid.
+func CheckConditions(conditions []map[string]any) error {
+	for _, cond := range conditions {
+		if src, _ := cond["expression"].(string); strings.TrimSpace(src) != "" {
+			if _, err := cachedCompile(src, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
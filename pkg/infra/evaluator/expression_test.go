@@ -0,0 +1,216 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func expressionMessage() *mockMessage {
+	return &mockMessage{
+		id: "msg-1",
+		data: map[string]any{
+			"amount":   12500.0,
+			"currency": "eur",
+			"email":    "ops@example.com",
+			"customer": map[string]any{"name": "Ada", "tier": "gold"},
+			"items": []any{
+				map[string]any{"sku": "a", "price": 10.0},
+				map[string]any{"sku": "b", "price": 25.0},
+			},
+			"created_at": "2024-01-01T00:00:00Z",
+		},
+		metadata: map[string]string{"region": "eu"},
+		op:       "create",
+		table:    "orders",
+	}
+}
+
+func TestExpressionEval(t *testing.T) {
+	msg := expressionMessage()
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{"amount * 2 - 5", 24995.0},
+		{"amount > 10000 && currency == 'eur'", true},
+		{"source.customer.name + ' (' + upper(customer.tier) + ')'", "Ada (GOLD)"},
+		{"customer?.address?.city ?? 'unknown'", "unknown"},
+		{"map(filter(items, .price > 15), .sku)", []any{"b"}},
+		{"sum(items, .price)", 35.0},
+		{"len(keys(customer))", 2},
+		{"email matches '^[a-z]+@example\\\\.com$'", true},
+		{"date(created_at) + duration('36h') > date('2024-01-02')", true},
+		{"metadata.region == 'eu' && operation == 'create' && table == 'orders'", true},
+		{"'gold' in map([customer], .tier)", true},
+		// The functions of the older evaluator, including those named like
+		// operators of the language.
+		{"if(and(amount > 100, not(false)), 'big', 'small')", "big"},
+		{"contains(email, 'example') or false", true},
+		{"round(div(amount, 3), 2)", 4166.67},
+	}
+	for _, tt := range tests {
+		x, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.expr, err)
+			continue
+		}
+		got, err := x.Eval(msg)
+		if err != nil {
+			t.Errorf("Eval(%q): %v", tt.expr, err)
+			continue
+		}
+		if !equalValues(got, tt.want) {
+			t.Errorf("Eval(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func equalValues(a, b any) bool {
+	as, aok := a.([]any)
+	bs, bok := b.([]any)
+	if aok && bok {
+		if len(as) != len(bs) {
+			return false
+		}
+		for i := range as {
+			if as[i] != bs[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func TestExpressionCompileErrors(t *testing.T) {
+	for _, expr := range []string{
+		"amount >", "1 + 'a'", "len(1)", "unknown_fn(amount)", "amount > 10000 &&",
+		// The older functions and metadata are typed.
+		"upper(currency) > 1", "substring(email)", "not(amount, 1)", "metadata.region * 2",
+	} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Compile(%q) succeeded", expr)
+		}
+	}
+	for _, expr := range []string{"1 + 2", "upper(currency)"} {
+		if _, err := CompileCondition(expr); err == nil {
+			t.Errorf("condition %q, not yielding a boolean, compiled", expr)
+		}
+	}
+	x, err := Compile("customer.address.city")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := x.Eval(expressionMessage()); err == nil {
+		t.Error("navigating a missing field without ?. did not fail")
+	}
+}
+
+func TestParseAndEvaluateCompatibility(t *testing.T) {
+	e := NewEvaluator()
+	msg := expressionMessage()
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{"source.currency", "eur"},
+		{"upper(source.currency)", "EUR"},
+		// Unquoted arguments of the older functions are still text.
+		{"concat(source.currency, -, x)", "eur-x"},
+		{"42", 42.0},
+		{"'quoted'", "quoted"},
+		{"hello world", "hello world"},
+		{"customer.name", "customer.name"},
+		{"source.amount / 100", 125.0},
+		{"upper(source.missing ?? 'none')", "NONE"},
+		{"amount >", "amount >"},
+		// Text that reads as arithmetic on numbers or unknown names.
+		{"2024-01-01", "2024-01-01"},
+		{"N/A", "N/A"},
+		{"application/json", "application/json"},
+		{"US/Eastern", "US/Eastern"},
+		{"user-name", "user-name"},
+		{"-5", -5.0},
+		{"amount * 2", 25000.0},
+	}
+	for _, tt := range tests {
+		if got := e.ParseAndEvaluate(msg, tt.expr); got != tt.want {
+			t.Errorf("ParseAndEvaluate(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+	now, ok := e.ParseAndEvaluate(msg, "now() - duration('1h')").(string)
+	if ts, err := time.Parse(time.RFC3339, now); !ok || err != nil || time.Since(ts) < 59*time.Minute {
+		t.Errorf("time arithmetic yielded %q", now)
+	}
+}
+
+func TestEvaluateConditionsExpression(t *testing.T) {
+	msg := expressionMessage()
+	if !EvaluateConditions(msg, []map[string]any{{"expression": "amount > 10000"}, {"field": "currency", "operator": "=", "value": "eur"}}) {
+		t.Error("met conditions were not met")
+	}
+	for _, expr := range []string{"amount < 10000", "amount >", "customer.address.city == 'x'"} {
+		if EvaluateConditions(msg, []map[string]any{{"expression": expr}}) {
+			t.Errorf("condition %q was met", expr)
+		}
+	}
+}
+
+func TestExpressionRuntimeErrors(t *testing.T) {
+	msg := expressionMessage()
+	var exprErr *ExpressionError
+	if _, err := NewEvaluator().Evaluate(msg, "amount + customer"); !errors.As(err, &exprErr) || exprErr.Expression != "amount + customer" {
+		t.Errorf("Evaluate = %v, want an *ExpressionError", err)
+	}
+	if got := NewEvaluator().ParseAndEvaluate(msg, "amount + customer"); got != nil {
+		t.Errorf("ParseAndEvaluate = %#v, want nil", got)
+	}
+	ok, err := MatchConditions(msg, []map[string]any{{"expression": "customer.address.city == 'x'"}})
+	if ok || !errors.As(err, &exprErr) {
+		t.Errorf("MatchConditions = %v, %v, want an *ExpressionError", ok, err)
+	}
+	if ok, err := MatchConditions(msg, []map[string]any{{"expression": "amount < 10000"}}); ok || err != nil {
+		t.Errorf("MatchConditions of an unmet condition = %v, %v", ok, err)
+	}
+}
+
+func TestCachedCompileEvictsLeastRecentlyUsed(t *testing.T) {
+	cached := func(src string) bool {
+		compiled.Lock()
+		defer compiled.Unlock()
+		_, ok := compiled.exprs[compiledKey(src, false)]
+		return ok
+	}
+	_, _ = cachedCompile("amount + 1", false)
+	_, _ = cachedCompile("amount + 2", false)
+	for i := range maxCompiled - 1 {
+		_, _ = cachedCompile("amount + 1", false)
+		_, _ = cachedCompile(fmt.Sprintf("amount * %d", i), false)
+	}
+	if !cached("amount + 1") || cached("amount + 2") {
+		t.Errorf("recently used cached = %v, least recently used cached = %v", cached("amount + 1"), cached("amount + 2"))
+	}
+	x, err := cachedCompile("amount - 1", false)
+	if err != nil || x == nil || !cached("amount - 1") {
+		t.Errorf("an expression compiled into a full cache was not cached: %v", err)
+	}
+}
+
+func TestCheckExpression(t *testing.T) {
+	for _, expr := range []string{"", "hello world", "upper(source.name)", "source.a * 2", "date_format(source.d, 2006-01-02)"} {
+		if err := CheckExpression(expr); err != nil {
+			t.Errorf("CheckExpression(%q): %v", expr, err)
+		}
+	}
+	for _, expr := range []string{"source.a *", "amount >= 'x' &&", "a ?? "} {
+		if err := CheckExpression(expr); err == nil || !strings.Contains(err.Error(), "invalid expression") {
+			t.Errorf("CheckExpression(%q) = %v, want a compile error", expr, err)
+		}
+	}
+	if err := CheckConditions([]map[string]any{{"expression": "amount >"}}); err == nil {
+		t.Error("an invalid condition passed the check")
+	}
+}
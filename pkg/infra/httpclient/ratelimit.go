@@ -0,0 +1,178 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitedClient is an http.Client whose transport adapts to the rate
+// limits announced by the APIs it calls. See RateLimitTransport.
+var RateLimitedClient = NewRateLimitedClient(0)
+
+// NewRateLimitedClient returns an http.Client using a RateLimitTransport over
+// the default transport.
+func NewRateLimitedClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &RateLimitTransport{},
+	}
+}
+
+const (
+	defaultRateLimitRetries = 3
+	defaultRateLimitMaxWait = 30 * time.Second
+)
+
+// RateLimitTransport is an http.RoundTripper that honours the rate-limit
+// signals of the APIs it talks to. A Retry-After header, or an exhausted
+// X-RateLimit-Remaining / RateLimit-Remaining with its reset header, pauses
+// every request to that host until the limit resets. Rate-limited responses
+// (429, or 503 with Retry-After) are retried after the pause when the request
+// body can be replayed. Pauses are shared process-wide, so all sinks calling
+// the same API back off together.
+type RateLimitTransport struct {
+	// Base performs the requests; nil means http.DefaultTransport.
+	Base http.RoundTripper
+	// MaxRetries bounds retries of rate-limited responses; zero means 3 and
+	// a negative value disables retries.
+	MaxRetries int
+	// MaxWait is the longest pause a retry will sit out; a longer one
+	// returns the rate-limited response to the caller. Zero means 30s.
+	MaxWait time.Duration
+}
+
+type hostPause struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+var hostPauses sync.Map // map[string]*hostPause
+
+func pauseFor(host string) *hostPause {
+	p, _ := hostPauses.LoadOrStore(host, &hostPause{})
+	return p.(*hostPause)
+}
+
+func (p *hostPause) extend(until time.Time) {
+	p.mu.Lock()
+	if until.After(p.until) {
+		p.until = until
+	}
+	p.mu.Unlock()
+}
+
+func (p *hostPause) remaining() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Until(p.until)
+}
+
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	retries := t.MaxRetries
+	if retries == 0 {
+		retries = defaultRateLimitRetries
+	}
+	maxWait := t.MaxWait
+	if maxWait <= 0 {
+		maxWait = defaultRateLimitMaxWait
+	}
+	pause := pauseFor(req.URL.Host)
+
+	for attempt := 0; ; attempt++ {
+		if wait := pause.remaining(); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+		}
+
+		resp, err := base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		wait, limited := RateLimitWait(resp, time.Now())
+		if limited && wait <= 0 {
+			wait = time.Second << attempt
+		}
+		if wait > 0 {
+			pause.extend(time.Now().Add(wait))
+		}
+
+		replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if !limited || attempt >= retries || wait > maxWait || !replayable {
+			return resp, nil
+		}
+
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		next := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			next.Body = body
+		}
+		req = next
+	}
+}
+
+// RateLimitWait inspects a response for rate-limit signals. It reports how
+// long the server asked clients to wait before the next request, and whether
+// the response itself was rejected for exceeding the limit.
+func RateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	limited := resp.StatusCode == http.StatusTooManyRequests
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			limited = true
+		}
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, limited
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return at.Sub(now), limited
+		}
+	}
+
+	for _, prefix := range []string{"X-Ratelimit-", "X-Rate-Limit-", "Ratelimit-"} {
+		remaining := resp.Header.Get(prefix + "Remaining")
+		if remaining == "" {
+			continue
+		}
+		if n, err := strconv.ParseFloat(remaining, 64); err != nil || n > 0 {
+			return 0, limited
+		}
+		return resetWait(resp.Header.Get(prefix+"Reset"), now), limited
+	}
+	return 0, limited
+}
+
+// resetWait interprets a rate-limit reset header, which APIs send either as
+// seconds until the reset or as the reset's Unix time in seconds or
+// milliseconds.
+func resetWait(v string, now time.Time) time.Duration {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		return 0
+	}
+	switch {
+	case f > 1e12:
+		return time.UnixMilli(int64(f)).Sub(now)
+	case f > 1e9:
+		return time.Unix(int64(f), 0).Sub(now)
+	default:
+		return time.Duration(f * float64(time.Second))
+	}
+}
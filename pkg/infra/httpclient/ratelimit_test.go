@@ -0,0 +1,100 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cases := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		wait    time.Duration
+		limited bool
+	}{
+		{"ok", 200, nil, 0, false},
+		{"retry after seconds", 429, map[string]string{"Retry-After": "7"}, 7 * time.Second, true},
+		{"retry after date", 503, map[string]string{"Retry-After": now.Add(3 * time.Second).UTC().Format(http.TimeFormat)}, 3 * time.Second, true},
+		{"remaining left", 200, map[string]string{"X-RateLimit-Remaining": "5", "X-RateLimit-Reset": "30"}, 0, false},
+		{"exhausted delta", 200, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "30"}, 30 * time.Second, false},
+		{"exhausted epoch", 200, map[string]string{"x-rate-limit-remaining": "0", "x-rate-limit-reset": strconv.FormatInt(now.Unix()+12, 10)}, 12 * time.Second, false},
+		{"ietf headers", 429, map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "2"}, 2 * time.Second, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: c.status, Header: http.Header{}}
+			for k, v := range c.headers {
+				resp.Header.Set(k, v)
+			}
+			wait, limited := RateLimitWait(resp, now)
+			if wait != c.wait || limited != c.limited {
+				t.Errorf("expected (%v, %v), got (%v, %v)", c.wait, c.limited, wait, limited)
+			}
+		})
+	}
+}
+
+func TestRateLimitTransport_RetriesAfterRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, 5)
+		n, _ := r.Body.Read(body)
+		if string(body[:n]) != "hello" {
+			t.Errorf("expected replayed body, got %q", body[:n])
+		}
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &RateLimitTransport{}}
+	start := time.Now()
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("expected success on the second call, got status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the retry to wait for Retry-After, took %v", elapsed)
+	}
+}
+
+func TestRateLimitTransport_ReturnsLongPauses(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &RateLimitTransport{MaxWait: time.Second}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Errorf("expected the 429 to be returned without retrying, got %d after %d calls", resp.StatusCode, calls.Load())
+	}
+	if pauseFor(resp.Request.URL.Host).remaining() < time.Hour-time.Minute {
+		t.Error("expected the host to be paused for the announced period")
+	}
+	hostPauses.Delete(resp.Request.URL.Host)
+}
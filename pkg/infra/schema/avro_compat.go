@@ -0,0 +1,202 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hamba/avro/v2"
+)
+
+// avroIssues returns why data written with the writer schema cannot be read
+// with the reader schema, following the Avro schema resolution rules: fields
+// the writer lacks need a default in the reader, numbers may be promoted to
+// wider types, every writer union branch needs a reader branch, and named
+// types match by name or by the reader's aliases.
+func avroIssues(reader, writer string) ([]string, error) {
+	// Each schema gets its own cache so that the named types of one version
+	// never resolve references in the other.
+	r, err := avro.ParseWithCache(reader, "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid reader schema: %w", err)
+	}
+	w, err := avro.ParseWithCache(writer, "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid writer schema: %w", err)
+	}
+	c := avroChecker{visiting: make(map[[2][32]byte]bool)}
+	return c.check("", r, w), nil
+}
+
+type avroChecker struct {
+	// visiting holds the record pairs being compared, so that recursive
+	// records end instead of looping.
+	visiting map[[2][32]byte]bool
+}
+
+func (c *avroChecker) check(path string, r, w avro.Schema) []string {
+	r, w = derefAvro(r), derefAvro(w)
+
+	if wu, ok := w.(*avro.UnionSchema); ok {
+		var issues []string
+		for _, branch := range wu.Types() {
+			if len(c.check(path, r, branch)) > 0 {
+				issues = append(issues, fmt.Sprintf("%s: the reader cannot read writer union branch %s", issuePath(path), avroTypeName(branch)))
+			}
+		}
+		return issues
+	}
+	if ru, ok := r.(*avro.UnionSchema); ok {
+		for _, branch := range ru.Types() {
+			if len(c.check(path, branch, w)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: the reader union has no branch for writer type %s", issuePath(path), avroTypeName(w))}
+	}
+
+	if r.Type() != w.Type() {
+		if avroPromotable(w.Type(), r.Type()) {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: writer type %s cannot be read as %s", issuePath(path), avroTypeName(w), avroTypeName(r))}
+	}
+
+	switch rs := r.(type) {
+	case *avro.RecordSchema:
+		return c.checkRecord(path, rs, w.(*avro.RecordSchema))
+	case *avro.EnumSchema:
+		ws := w.(*avro.EnumSchema)
+		if issue := checkAvroName(path, rs, ws); issue != "" {
+			return []string{issue}
+		}
+		if rs.HasDefault() {
+			return nil
+		}
+		var issues []string
+		for _, sym := range ws.Symbols() {
+			if !slices.Contains(rs.Symbols(), sym) {
+				issues = append(issues, fmt.Sprintf("%s: the reader enum %s lacks symbol %s and has no default", issuePath(path), rs.FullName(), sym))
+			}
+		}
+		return issues
+	case *avro.FixedSchema:
+		ws := w.(*avro.FixedSchema)
+		if issue := checkAvroName(path, rs, ws); issue != "" {
+			return []string{issue}
+		}
+		if rs.Size() != ws.Size() {
+			return []string{fmt.Sprintf("%s: fixed %s changed size from %d to %d", issuePath(path), rs.FullName(), ws.Size(), rs.Size())}
+		}
+		return checkAvroDecimal(path, rs.Logical(), ws.Logical())
+	case *avro.ArraySchema:
+		return c.check(path+"/items", rs.Items(), w.(*avro.ArraySchema).Items())
+	case *avro.MapSchema:
+		return c.check(path+"/values", rs.Values(), w.(*avro.MapSchema).Values())
+	case *avro.PrimitiveSchema:
+		return checkAvroDecimal(path, rs.Logical(), w.(*avro.PrimitiveSchema).Logical())
+	}
+	return nil
+}
+
+func (c *avroChecker) checkRecord(path string, r, w *avro.RecordSchema) []string {
+	if issue := checkAvroName(path, r, w); issue != "" {
+		return []string{issue}
+	}
+	key := [2][32]byte{r.Fingerprint(), w.Fingerprint()}
+	if c.visiting[key] {
+		return nil
+	}
+	c.visiting[key] = true
+	defer delete(c.visiting, key)
+
+	var issues []string
+	for _, rf := range r.Fields() {
+		wf := findAvroField(w.Fields(), rf)
+		if wf == nil {
+			if !rf.HasDefault() {
+				issues = append(issues, fmt.Sprintf("%s: reader field %s is missing from the writer and has no default", issuePath(path), rf.Name()))
+			}
+			continue
+		}
+		issues = append(issues, c.check(path+"/"+rf.Name(), rf.Type(), wf.Type())...)
+	}
+	return issues
+}
+
+// findAvroField returns the writer field a reader field reads: the one of
+// the same name, or else one named by an alias of the reader field.
+func findAvroField(fields []*avro.Field, rf *avro.Field) *avro.Field {
+	for _, f := range fields {
+		if f.Name() == rf.Name() {
+			return f
+		}
+	}
+	for _, f := range fields {
+		if slices.Contains(rf.Aliases(), f.Name()) {
+			return f
+		}
+	}
+	return nil
+}
+
+func checkAvroName(path string, r, w avro.NamedSchema) string {
+	if r.Name() == w.Name() || slices.Contains(r.Aliases(), w.FullName()) {
+		return ""
+	}
+	return fmt.Sprintf("%s: reader type %s does not match writer type %s", issuePath(path), r.FullName(), w.FullName())
+}
+
+// checkAvroDecimal reports a decimal whose precision or scale changed, which
+// changes the value every stored number stands for.
+func checkAvroDecimal(path string, r, w avro.LogicalSchema) []string {
+	rd, rok := r.(*avro.DecimalLogicalSchema)
+	wd, wok := w.(*avro.DecimalLogicalSchema)
+	if !rok || !wok {
+		return nil
+	}
+	if rd.Precision() != wd.Precision() || rd.Scale() != wd.Scale() {
+		return []string{fmt.Sprintf("%s: decimal changed from (%d,%d) to (%d,%d)", issuePath(path), wd.Precision(), wd.Scale(), rd.Precision(), rd.Scale())}
+	}
+	return nil
+}
+
+// issuePath renders the path of a nested type for a message.
+func issuePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func derefAvro(s avro.Schema) avro.Schema {
+	if ref, ok := s.(*avro.RefSchema); ok {
+		return ref.Schema()
+	}
+	return s
+}
+
+func avroTypeName(s avro.Schema) string {
+	s = derefAvro(s)
+	if n, ok := s.(avro.NamedSchema); ok {
+		return n.FullName()
+	}
+	return string(s.Type())
+}
+
+// avroPromotable reports whether the resolution rules let a reader of type
+// to read values written as from.
+func avroPromotable(from, to avro.Type) bool {
+	switch from {
+	case avro.Int:
+		return to == avro.Long || to == avro.Float || to == avro.Double
+	case avro.Long:
+		return to == avro.Float || to == avro.Double
+	case avro.Float:
+		return to == avro.Double
+	case avro.String:
+		return to == avro.Bytes
+	case avro.Bytes:
+		return to == avro.String
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/user/hermod"
+)
+
+// columnProp is the attribute of an Avro field holding the column it was
+// derived from, with names and types as the source database has them.
+const columnProp = "column"
+
+// ColumnsSchema renders the columns of a table as an Avro record, the form
+// in which CDC sources register table versions. Every field is nullable with
+// a null default, so that adding and dropping columns stay compatible, and
+// keeps its column under the "column" attribute.
+func ColumnsSchema(schemaName, table string, cols []hermod.ColumnInfo) (string, error) {
+	if table == "" {
+		return "", errors.New("table name is required")
+	}
+	fields := make([]map[string]any, 0, len(cols))
+	for _, c := range cols {
+		fields = append(fields, map[string]any{
+			"name":     avroName(c.Name),
+			"type":     []string{"null", avroType(c.Type)},
+			"default":  nil,
+			columnProp: c,
+		})
+	}
+	record := map[string]any{
+		"type":   "record",
+		"name":   avroName(table),
+		"fields": fields,
+	}
+	if schemaName != "" {
+		record["namespace"] = avroName(schemaName)
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// SchemaColumns returns the columns of a schema rendered by ColumnsSchema.
+func SchemaColumns(content string) ([]hermod.ColumnInfo, error) {
+	var record struct {
+		Fields []struct {
+			Column *hermod.ColumnInfo `json:"column"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(content), &record); err != nil {
+		return nil, err
+	}
+	cols := make([]hermod.ColumnInfo, 0, len(record.Fields))
+	for _, f := range record.Fields {
+		if f.Column == nil {
+			return nil, errors.New("schema was not derived from table columns")
+		}
+		cols = append(cols, *f.Column)
+	}
+	return cols, nil
+}
+
+// avroName makes name a valid Avro name.
+func avroName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// avroType maps a column type of any of the supported databases to the
+// Avro type of its values.
+func avroType(sqlType string) string {
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	if t == "tinyint(1)" {
+		return "boolean"
+	}
+	base, _, _ := strings.Cut(t, "(")
+	base, _, _ = strings.Cut(base, " ")
+	switch base {
+	case "bool", "boolean", "bit":
+		return "boolean"
+	case "int", "int2", "int4", "int8", "integer", "smallint", "bigint", "tinyint", "mediumint", "serial", "smallserial", "bigserial":
+		return "long"
+	case "float", "float4", "float8", "double", "real", "numeric", "decimal", "money", "smallmoney":
+		return "double"
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "image":
+		return "bytes"
+	}
+	return "string"
+}
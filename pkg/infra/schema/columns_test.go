@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/storage"
+)
+
+func TestColumnsSchema(t *testing.T) {
+	v1 := []hermod.ColumnInfo{
+		{Name: "id", Type: "bigint", IsPK: true},
+		{Name: "is_active", Type: "tinyint(1)", IsNullable: true},
+		{Name: "1st-name", Type: "varchar(100)", IsNullable: true},
+	}
+	content, err := ColumnsSchema("public", "users", v1)
+	if err != nil {
+		t.Fatalf("ColumnsSchema failed: %v", err)
+	}
+	if _, err := NewValidator(SchemaConfig{Type: Avro, Schema: content}); err != nil {
+		t.Fatalf("rendered schema is not valid Avro: %v\n%s", err, content)
+	}
+	got, err := SchemaColumns(content)
+	if err != nil {
+		t.Fatalf("SchemaColumns failed: %v", err)
+	}
+	if !slices.Equal(got, v1) {
+		t.Errorf("SchemaColumns() = %+v, want %+v", got, v1)
+	}
+
+	if _, err := SchemaColumns(`{"type":"record","name":"x","fields":[{"name":"a","type":"string"}]}`); err == nil {
+		t.Error("expected an error for a schema not derived from columns")
+	}
+	if _, err := ColumnsSchema("", "", v1); err == nil {
+		t.Error("expected an error without a table name")
+	}
+}
+
+func TestColumnsSchema_VersionsRegister(t *testing.T) {
+	reg := NewStorageRegistry(&mockStorage{schemas: make(map[string][]storage.Schema)})
+	versions := [][]hermod.ColumnInfo{
+		{{Name: "id", Type: "int"}, {Name: "name", Type: "text"}},
+		{{Name: "id", Type: "int"}, {Name: "name", Type: "text"}, {Name: "email", Type: "text"}},
+		{{Name: "id", Type: "int"}, {Name: "email", Type: "text"}},
+	}
+	for i, cols := range versions {
+		content, err := ColumnsSchema("", "users", cols)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := reg.Register(t.Context(), "cdc.src.users", Avro, content)
+		if err != nil {
+			t.Fatalf("version %d: %v", i+1, err)
+		}
+		if v != i+1 {
+			t.Errorf("got version %d, want %d", v, i+1)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/user/hermod/internal/storage"
+)
+
+// CompatibilityLevel decides which earlier versions of a subject a new
+// version is checked against, and in which direction.
+type CompatibilityLevel string
+
+const (
+	// CompatibilityNone accepts any new version.
+	CompatibilityNone CompatibilityLevel = "NONE"
+	// CompatibilityBackward lets readers of the new version read data written
+	// with the latest version.
+	CompatibilityBackward CompatibilityLevel = "BACKWARD"
+	// CompatibilityBackwardTransitive is CompatibilityBackward against every
+	// earlier version.
+	CompatibilityBackwardTransitive CompatibilityLevel = "BACKWARD_TRANSITIVE"
+	// CompatibilityForward lets readers of the latest version read data
+	// written with the new version.
+	CompatibilityForward CompatibilityLevel = "FORWARD"
+	// CompatibilityForwardTransitive is CompatibilityForward against every
+	// earlier version.
+	CompatibilityForwardTransitive CompatibilityLevel = "FORWARD_TRANSITIVE"
+	// CompatibilityFull is both CompatibilityBackward and CompatibilityForward.
+	CompatibilityFull CompatibilityLevel = "FULL"
+	// CompatibilityFullTransitive is CompatibilityFull against every earlier
+	// version.
+	CompatibilityFullTransitive CompatibilityLevel = "FULL_TRANSITIVE"
+)
+
+// DefaultCompatibility applies to subjects without a level of their own when
+// no global level is set.
+const DefaultCompatibility = CompatibilityBackward
+
+// ParseCompatibilityLevel parses a level name, ignoring case.
+func ParseCompatibilityLevel(s string) (CompatibilityLevel, error) {
+	level := CompatibilityLevel(strings.ToUpper(strings.TrimSpace(s)))
+	switch level {
+	case CompatibilityNone, CompatibilityBackward, CompatibilityBackwardTransitive,
+		CompatibilityForward, CompatibilityForwardTransitive, CompatibilityFull, CompatibilityFullTransitive:
+		return level, nil
+	}
+	return "", fmt.Errorf("unknown compatibility level %q", s)
+}
+
+// Transitive reports whether the level checks every earlier version rather
+// than the latest one only.
+func (l CompatibilityLevel) Transitive() bool {
+	return strings.HasSuffix(string(l), "_TRANSITIVE")
+}
+
+func (l CompatibilityLevel) backward() bool {
+	return strings.HasPrefix(string(l), "BACKWARD") || strings.HasPrefix(string(l), "FULL")
+}
+
+func (l CompatibilityLevel) forward() bool {
+	return strings.HasPrefix(string(l), "FORWARD") || strings.HasPrefix(string(l), "FULL")
+}
+
+// CompatibilityError is returned when a schema breaks the compatibility level
+// of its subject.
+type CompatibilityError struct {
+	Subject  string
+	Level    CompatibilityLevel
+	Messages []string
+}
+
+func (e *CompatibilityError) Error() string {
+	return fmt.Sprintf("schema is not %s compatible with subject %s: %s", e.Level, e.Subject, strings.Join(e.Messages, "; "))
+}
+
+// compatibilityIssues returns why candidate breaks level against the earlier
+// version previous.
+func compatibilityIssues(level CompatibilityLevel, schemaType SchemaType, candidate string, previous storage.Schema) ([]string, error) {
+	if level == CompatibilityNone {
+		return nil, nil
+	}
+	if previous.Type != string(schemaType) {
+		return []string{fmt.Sprintf("version %d: schema type changed from %s to %s", previous.Version, previous.Type, schemaType)}, nil
+	}
+
+	var issues []string
+	check := func(direction, reader, writer string) error {
+		found, err := schemaIssues(schemaType, reader, writer)
+		if err != nil {
+			return err
+		}
+		for _, issue := range found {
+			issues = append(issues, fmt.Sprintf("version %d (%s): %s", previous.Version, direction, issue))
+		}
+		return nil
+	}
+	if level.backward() {
+		if err := check("backward", candidate, previous.Content); err != nil {
+			return nil, err
+		}
+	}
+	if level.forward() {
+		if err := check("forward", previous.Content, candidate); err != nil {
+			return nil, err
+		}
+	}
+	return issues, nil
+}
+
+// schemaIssues returns why data written with the writer schema cannot be read
+// with the reader schema.
+func schemaIssues(schemaType SchemaType, reader, writer string) ([]string, error) {
+	switch schemaType {
+	case Avro:
+		return avroIssues(reader, writer)
+	case Protobuf:
+		return protoIssues(reader, writer)
+	case JSONSchema:
+		return jsonIssues(reader, writer)
+	}
+	return nil, fmt.Errorf("unsupported schema type: %s", schemaType)
+}
+
+// jsonIssues compares the top level of two JSON schemas: the reader may not
+// require a field the writer does not, and properties the writer declares
+// may neither disappear from the reader nor change their type.
+func jsonIssues(reader, writer string) ([]string, error) {
+	var r, w map[string]any
+	if err := json.Unmarshal([]byte(reader), &r); err != nil {
+		return nil, fmt.Errorf("invalid reader schema: %w", err)
+	}
+	if err := json.Unmarshal([]byte(writer), &w); err != nil {
+		return nil, fmt.Errorf("invalid writer schema: %w", err)
+	}
+
+	var issues []string
+	writerRequired := jsonRequired(w)
+	for _, name := range jsonRequired(r) {
+		if !slices.Contains(writerRequired, name) {
+			issues = append(issues, fmt.Sprintf("field '%s' is required by the reader but not by the writer", name))
+		}
+	}
+
+	rProps, _ := r["properties"].(map[string]any)
+	wProps, _ := w["properties"].(map[string]any)
+	if rProps == nil || wProps == nil {
+		return issues, nil
+	}
+	names := make([]string, 0, len(wProps))
+	for name := range wProps {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		rp, ok := rProps[name]
+		if !ok {
+			issues = append(issues, fmt.Sprintf("field '%s' of the writer is missing from the reader", name))
+			continue
+		}
+		rt := jsonPropertyType(rp)
+		wt := jsonPropertyType(wProps[name])
+		if rt != "" && wt != "" && rt != wt && !(rt == "number" && wt == "integer") {
+			issues = append(issues, fmt.Sprintf("field '%s' changed type from %s to %s", name, wt, rt))
+		}
+	}
+	return issues, nil
+}
+
+func jsonRequired(s map[string]any) []string {
+	list, _ := s["required"].([]any)
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if name, ok := v.(string); ok {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// jsonPropertyType returns the type of a property schema when it names a
+// single one.
+func jsonPropertyType(p any) string {
+	m, _ := p.(map[string]any)
+	t, _ := m["type"].(string)
+	return t
+}
@@ -0,0 +1,195 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/user/hermod"
+	"github.com/user/hermod/internal/storage"
+)
+
+func TestAvroIssues(t *testing.T) {
+	const user = `{"type":"record","name":"User","fields":[{"name":"id","type":"int"}`
+	tests := []struct {
+		name           string
+		reader, writer string
+		compatible     bool
+	}{
+		{"identical", user + `]}`, user + `]}`, true},
+		{"added field with default", user + `,{"name":"email","type":["null","string"],"default":null}]}`, user + `]}`, true},
+		{"added field without default", user + `,{"name":"email","type":"string"}]}`, user + `]}`, false},
+		{"removed field", user + `]}`, user + `,{"name":"email","type":"string"}]}`, true},
+		{"int promoted to long", `{"type":"record","name":"User","fields":[{"name":"id","type":"long"}]}`, user + `]}`, true},
+		{"long narrowed to int", user + `]}`, `{"type":"record","name":"User","fields":[{"name":"id","type":"long"}]}`, false},
+		{"renamed record", `{"type":"record","name":"Person","fields":[{"name":"id","type":"int"}]}`, user + `]}`, false},
+		{"renamed record with alias", `{"type":"record","name":"Person","aliases":["User"],"fields":[{"name":"id","type":"int"}]}`, user + `]}`, true},
+		{"renamed field with alias", `{"type":"record","name":"User","fields":[{"name":"key","aliases":["id"],"type":"int"}]}`, user + `]}`, true},
+		{"value widened to union", `{"type":"record","name":"User","fields":[{"name":"id","type":["null","int"]}]}`, user + `]}`, true},
+		{"union narrowed to value", user + `]}`, `{"type":"record","name":"User","fields":[{"name":"id","type":["null","int"]}]}`, false},
+		{"enum symbol added to writer", `{"type":"enum","name":"E","symbols":["A"]}`, `{"type":"enum","name":"E","symbols":["A","B"]}`, false},
+		{"enum with default", `{"type":"enum","name":"E","symbols":["A","U"],"default":"U"}`, `{"type":"enum","name":"E","symbols":["A","B"]}`, true},
+		{"fixed resized", `{"type":"fixed","name":"F","size":8}`, `{"type":"fixed","name":"F","size":4}`, false},
+		{"array items promoted", `{"type":"array","items":"double"}`, `{"type":"array","items":"float"}`, true},
+		{"map values changed", `{"type":"map","values":"int"}`, `{"type":"map","values":"string"}`, false},
+		{"recursive record", `{"type":"record","name":"Node","fields":[{"name":"next","type":["null","Node"]}]}`, `{"type":"record","name":"Node","fields":[{"name":"next","type":["null","Node"]}]}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := avroIssues(tt.reader, tt.writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(issues) == 0; got != tt.compatible {
+				t.Errorf("compatible = %v, want %v (issues: %v)", got, tt.compatible, issues)
+			}
+		})
+	}
+}
+
+func TestProtoIssues(t *testing.T) {
+	const header = "syntax = \"proto3\";\npackage test;\n"
+	tests := []struct {
+		name           string
+		reader, writer string
+		compatible     bool
+	}{
+		{"added field", header + `message M { int32 id = 1; string name = 2; }`, header + `message M { int32 id = 1; }`, true},
+		{"removed field", header + `message M { int32 id = 1; }`, header + `message M { int32 id = 1; string name = 2; }`, true},
+		{"renamed field", header + `message M { int32 key = 1; }`, header + `message M { int32 id = 1; }`, true},
+		{"int32 widened to int64", header + `message M { int64 id = 1; }`, header + `message M { int32 id = 1; }`, true},
+		{"int32 to string", header + `message M { string id = 1; }`, header + `message M { int32 id = 1; }`, false},
+		{"int32 to sint32", header + `message M { sint32 id = 1; }`, header + `message M { int32 id = 1; }`, false},
+		{"string to bytes", header + `message M { bytes v = 1; }`, header + `message M { string v = 1; }`, true},
+		{"message type changed", header + `message A {} message B {} message M { B v = 1; }`, header + `message A {} message B {} message M { A v = 1; }`, false},
+		{"singular to repeated", header + `message M { repeated int32 id = 1; }`, header + `message M { int32 id = 1; }`, false},
+		{"nested field retyped", header + `message M { message N { string v = 1; } N n = 1; }`, header + `message M { message N { int32 v = 1; } N n = 1; }`, false},
+		{"removed message", header + `message Other { int32 id = 1; }`, header + `message M { int32 id = 1; }`, false},
+		{"field moved into oneof", header + `message M { oneof o { int32 a = 1; int32 b = 2; } }`, header + `message M { int32 a = 1; }`, false},
+		{"reserved number reused", header + `message M { int32 id = 1; string name = 2; }`, header + `message M { int32 id = 1; reserved 2; }`, false},
+		{"reserved name reused", header + `message M { reserved "name"; int32 id = 1; }`, header + `message M { int32 id = 1; string name = 2; }`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := protoIssues(tt.reader, tt.writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(issues) == 0; got != tt.compatible {
+				t.Errorf("compatible = %v, want %v (issues: %v)", got, tt.compatible, issues)
+			}
+		})
+	}
+}
+
+func TestStorageRegistry_CompatibilityLevels(t *testing.T) {
+	const (
+		v1         = `{"type":"record","name":"User","fields":[{"name":"id","type":"int"}]}`
+		v2         = `{"type":"record","name":"User","fields":[{"name":"id","type":"int"},{"name":"email","type":"string","default":""}]}`
+		noDefault  = `{"type":"record","name":"User","fields":[{"name":"id","type":"int"},{"name":"name","type":"string"}]}`
+		dropsEmail = `{"type":"record","name":"User","fields":[{"name":"id","type":"int"},{"name":"name","type":"string","default":""}]}`
+	)
+	ctx := t.Context()
+	mock := &mockStorage{schemas: make(map[string][]storage.Schema)}
+	reg := NewStorageRegistry(mock)
+
+	if level, _ := reg.GetCompatibility(ctx, "user"); level != DefaultCompatibility {
+		t.Fatalf("default level = %s, want %s", level, DefaultCompatibility)
+	}
+	for _, content := range []string{v1, v2} {
+		if _, err := reg.Register(ctx, "user", Avro, content); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+	}
+
+	_, err := reg.Register(ctx, "user", Avro, noDefault)
+	var compatErr *CompatibilityError
+	if !errors.As(err, &compatErr) || compatErr.Level != CompatibilityBackward {
+		t.Fatalf("expected a BACKWARD CompatibilityError, got %v", err)
+	}
+
+	// Dropping email is backward compatible with the latest version only;
+	// reading it back into version 2 needs its default.
+	issues, err := reg.TestCompatibility(ctx, "user", Avro, dropsEmail, 0)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("BACKWARD: issues = %v, err = %v", issues, err)
+	}
+	if err := reg.SetCompatibility(ctx, "", CompatibilityForward); err != nil {
+		t.Fatal(err)
+	}
+	if issues, _ := reg.TestCompatibility(ctx, "user", Avro, dropsEmail, 0); len(issues) != 0 {
+		t.Errorf("FORWARD: unexpected issues %v", issues)
+	}
+	if issues, _ := reg.TestCompatibility(ctx, "user", Avro, noDefault, 0); len(issues) != 0 {
+		t.Errorf("FORWARD: unexpected issues %v", issues)
+	}
+
+	// A subject level overrides the global one, and FULL_TRANSITIVE checks
+	// version 1 as well.
+	if err := reg.SetCompatibility(ctx, "user", CompatibilityFullTransitive); err != nil {
+		t.Fatal(err)
+	}
+	if level, _ := reg.GetCompatibility(ctx, "user"); level != CompatibilityFullTransitive {
+		t.Fatalf("subject level = %s", level)
+	}
+	if level, _ := reg.GetCompatibility(ctx, "other"); level != CompatibilityForward {
+		t.Fatalf("global level = %s", level)
+	}
+	issues, _ = reg.TestCompatibility(ctx, "user", Avro, noDefault, 0)
+	if len(issues) != 2 {
+		t.Errorf("FULL_TRANSITIVE: issues = %v, want one per version", issues)
+	}
+	if issues, _ := reg.TestCompatibility(ctx, "user", Avro, noDefault, 1); len(issues) != 1 {
+		t.Errorf("against version 1: issues = %v", issues)
+	}
+
+	if err := reg.SetCompatibility(ctx, "user", CompatibilityNone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Register(ctx, "user", Avro, noDefault); err != nil {
+		t.Errorf("NONE: %v", err)
+	}
+
+	if err := reg.SetCompatibility(ctx, "user", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, explicit, _ := reg.SubjectCompatibility(ctx, "user"); explicit {
+		t.Error("cleared subject level still set")
+	}
+	if err := reg.SetCompatibility(ctx, "user", "SIDEWAYS"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if _, err := reg.Register(ctx, "user", JSONSchema, `{"type":"object"}`); err == nil {
+		t.Error("expected an error for a changed schema type")
+	}
+}
+
+func TestColumnsSchema_FullTransitive(t *testing.T) {
+	ctx := t.Context()
+	reg := NewStorageRegistry(&mockStorage{schemas: make(map[string][]storage.Schema)})
+	if err := reg.SetCompatibility(ctx, "", CompatibilityFullTransitive); err != nil {
+		t.Fatal(err)
+	}
+	register := func(cols []hermod.ColumnInfo) error {
+		content, err := ColumnsSchema("", "users", cols)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = reg.Register(ctx, "cdc.src.users", Avro, content)
+		return err
+	}
+
+	// Added and dropped columns are nullable with a null default, so either
+	// side reads the other.
+	for _, cols := range [][]hermod.ColumnInfo{
+		{{Name: "id", Type: "int"}, {Name: "name", Type: "text"}},
+		{{Name: "id", Type: "int"}, {Name: "name", Type: "text"}, {Name: "email", Type: "text"}},
+		{{Name: "id", Type: "int"}, {Name: "email", Type: "text"}},
+	} {
+		if err := register(cols); err != nil {
+			t.Fatalf("columns %v: %v", cols, err)
+		}
+	}
+	if err := register([]hermod.ColumnInfo{{Name: "id", Type: "text"}, {Name: "email", Type: "text"}}); err == nil {
+		t.Error("expected a retyped column to break compatibility")
+	}
+}
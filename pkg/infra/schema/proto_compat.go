@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// protoIssues returns why messages written with the writer schema cannot be
+// read with the reader schema on the wire: the first message type must keep
+// its name, fields are matched by number and must keep a compatible wire
+// type and cardinality, a field may not join a oneof that has other members,
+// and neither side may use a field number or name the other reserved.
+func protoIssues(reader, writer string) ([]string, error) {
+	r, err := parseProto(reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reader schema: %w", err)
+	}
+	w, err := parseProto(writer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid writer schema: %w", err)
+	}
+
+	readerMsgs := protoMessages(r.GetMessageTypes(), nil)
+	writerMsgs := protoMessages(w.GetMessageTypes(), nil)
+
+	var issues []string
+	if top := w.GetMessageTypes(); len(top) > 0 {
+		if _, ok := readerMsgs[top[0].GetFullyQualifiedName()]; !ok {
+			issues = append(issues, fmt.Sprintf("message %s was removed", top[0].GetFullyQualifiedName()))
+		}
+	}
+
+	names := make([]string, 0, len(writerMsgs))
+	for name := range writerMsgs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if rm, ok := readerMsgs[name]; ok {
+			issues = append(issues, checkProtoMessage(rm, writerMsgs[name])...)
+		}
+	}
+	return issues, nil
+}
+
+// protoMessages indexes message types and their nested types by full name.
+func protoMessages(msgs []*desc.MessageDescriptor, into map[string]*desc.MessageDescriptor) map[string]*desc.MessageDescriptor {
+	if into == nil {
+		into = make(map[string]*desc.MessageDescriptor)
+	}
+	for _, m := range msgs {
+		into[m.GetFullyQualifiedName()] = m
+		protoMessages(m.GetNestedMessageTypes(), into)
+	}
+	return into
+}
+
+func checkProtoMessage(r, w *desc.MessageDescriptor) []string {
+	var issues []string
+	name := r.GetFullyQualifiedName()
+
+	for _, wf := range w.GetFields() {
+		rf := r.FindFieldByNumber(wf.GetNumber())
+		if rf == nil {
+			continue
+		}
+		if rt, wt := protoTypeName(rf), protoTypeName(wf); protoWireClass(rf) != protoWireClass(wf) ||
+			(rf.GetMessageType() != nil && rt != wt) {
+			issues = append(issues, fmt.Sprintf("%s: field %d changed type from %s to %s", name, wf.GetNumber(), wt, rt))
+		}
+		if rf.IsRepeated() != wf.IsRepeated() && protoWireClass(rf) != "length-delimited" {
+			issues = append(issues, fmt.Sprintf("%s: field %d changed between singular and repeated", name, wf.GetNumber()))
+		}
+		if ro := rf.GetOneOf(); ro != nil && !ro.IsSynthetic() && len(ro.GetChoices()) > 1 {
+			if wo := wf.GetOneOf(); wo == nil || wo.IsSynthetic() {
+				issues = append(issues, fmt.Sprintf("%s: field %d moved into oneof %s, which has other fields", name, wf.GetNumber(), ro.GetName()))
+			}
+		}
+	}
+
+	issues = append(issues, reservedIssues(name, r, w)...)
+	issues = append(issues, reservedIssues(name, w, r)...)
+	return issues
+}
+
+// reservedIssues reports fields of a that use a number or name b reserved.
+func reservedIssues(name string, a, b *desc.MessageDescriptor) []string {
+	proto := b.AsDescriptorProto()
+	var issues []string
+	for _, f := range a.GetFields() {
+		for _, rr := range proto.GetReservedRange() {
+			// Reserved ranges are end-exclusive in descriptors.
+			if f.GetNumber() >= rr.GetStart() && f.GetNumber() < rr.GetEnd() {
+				issues = append(issues, fmt.Sprintf("%s: field %s uses reserved number %d", name, f.GetName(), f.GetNumber()))
+			}
+		}
+		if slices.Contains(proto.GetReservedName(), f.GetName()) {
+			issues = append(issues, fmt.Sprintf("%s: field %s uses a reserved name", name, f.GetName()))
+		}
+	}
+	return issues
+}
+
+// protoWireClass groups field types whose values can be read as one another.
+func protoWireClass(f *desc.FieldDescriptor) string {
+	switch f.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_BOOL, descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		return "varint"
+	case descriptorpb.FieldDescriptorProto_TYPE_SINT32, descriptorpb.FieldDescriptorProto_TYPE_SINT64:
+		return "zigzag"
+	case descriptorpb.FieldDescriptorProto_TYPE_FIXED32, descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+		descriptorpb.FieldDescriptorProto_TYPE_FLOAT:
+		return "fixed32"
+	case descriptorpb.FieldDescriptorProto_TYPE_FIXED64, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+		descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
+		return "fixed64"
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		descriptorpb.FieldDescriptorProto_TYPE_MESSAGE:
+		return "length-delimited"
+	case descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		return "group"
+	}
+	return f.GetType().String()
+}
+
+func protoTypeName(f *desc.FieldDescriptor) string {
+	if m := f.GetMessageType(); m != nil {
+		return m.GetFullyQualifiedName()
+	}
+	return f.GetType().String()
+}
@@ -2,12 +2,12 @@ package schema
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/user/hermod/internal/storage"
-	"github.com/xeipuuv/gojsonschema"
 )
 
 // Registry defines the interface for the global schema registry.
@@ -15,6 +15,7 @@ type Registry interface {
 	Register(ctx context.Context, name string, schemaType SchemaType, content string) (int, error)
 	GetValidator(ctx context.Context, name string, version int) (Validator, error)
 	GetLatestValidator(ctx context.Context, name string) (Validator, int, error)
+	GetLatestSchema(ctx context.Context, name string) (storage.Schema, error)
 	CheckCompatibility(ctx context.Context, name string, schemaType SchemaType, content string) error
 }
 
@@ -96,80 +97,128 @@ func (r *StorageRegistry) GetLatestValidator(ctx context.Context, name string) (
 	return v, sc.Version, err
 }
 
-// CheckCompatibility verifies if the new schema is compatible with previous versions.
-// For now, it implements a basic check.
-func (r *StorageRegistry) CheckCompatibility(ctx context.Context, name string, schemaType SchemaType, content string) error {
-	latest, err := r.storage.GetLatestSchema(ctx, name)
-	if err != nil {
-		// No previous version exists, so the schema is trivially compatible.
-		return nil //nolint:nilerr // absence of a prior schema means there is nothing to be incompatible with
-	}
+// GetLatestSchema retrieves the latest version of a schema.
+func (r *StorageRegistry) GetLatestSchema(ctx context.Context, name string) (storage.Schema, error) {
+	return r.storage.GetLatestSchema(ctx, name)
+}
 
-	if latest.Type != string(schemaType) {
-		return fmt.Errorf("schema type mismatch: %s vs %s", latest.Type, schemaType)
-	}
+// Settings keys holding compatibility levels: the global level, and the level
+// of a subject under the global key followed by "." and the subject.
+const compatibilitySettingKey = "schema_registry.compatibility"
 
-	if schemaType == JSONSchema {
-		return r.checkJSONCompatibility(latest.Content, content)
+func compatibilitySetting(subject string) string {
+	if subject == "" {
+		return compatibilitySettingKey
 	}
+	return compatibilitySettingKey + "." + subject
+}
 
-	// For other types, fallback to equality check for now
-	if latest.Content != content {
-		// In a real enterprise system, we would have full Avro/Proto compatibility checks here.
-		return nil // Allow for now, but in strict mode we might fail
+// SubjectCompatibility returns the level set for subject, or the global level
+// when subject is empty. explicit is false when no level is set.
+func (r *StorageRegistry) SubjectCompatibility(ctx context.Context, subject string) (level CompatibilityLevel, explicit bool, err error) {
+	val, err := r.storage.GetSetting(ctx, compatibilitySetting(subject))
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && val == "") {
+		return "", false, nil
 	}
-
-	return nil
+	if err != nil {
+		return "", false, err
+	}
+	level, err = ParseCompatibilityLevel(val)
+	if err != nil {
+		return "", false, err
+	}
+	return level, true, nil
 }
 
-func (r *StorageRegistry) checkJSONCompatibility(oldContent, newContent string) error {
-	// 1. Ensure both are valid JSON schemas
-	newLoader := gojsonschema.NewStringLoader(newContent)
+// GetCompatibility returns the level that applies to subject: its own, else
+// the global one, else DefaultCompatibility.
+func (r *StorageRegistry) GetCompatibility(ctx context.Context, subject string) (CompatibilityLevel, error) {
+	for _, key := range []string{subject, ""} {
+		level, ok, err := r.SubjectCompatibility(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return level, nil
+		}
+		if subject == "" {
+			break
+		}
+	}
+	return DefaultCompatibility, nil
+}
 
-	if _, err := gojsonschema.NewSchema(newLoader); err != nil {
-		return fmt.Errorf("new schema is invalid: %w", err)
+// SetCompatibility sets the level of subject, or the global level when
+// subject is empty. An empty level clears it.
+func (r *StorageRegistry) SetCompatibility(ctx context.Context, subject string, level CompatibilityLevel) error {
+	if level != "" {
+		if _, err := ParseCompatibilityLevel(string(level)); err != nil {
+			return err
+		}
 	}
+	return r.storage.SaveSetting(ctx, compatibilitySetting(subject), string(level))
+}
 
-	// 2. Structural compatibility check (simplified)
-	var oldSchema, newSchema map[string]any
-	if err := json.Unmarshal([]byte(oldContent), &oldSchema); err != nil {
-		// The stored schema was validated at registration time, so this should not
-		// happen; treat an unreadable old schema as compatible rather than erroring.
-		return nil //nolint:nilerr // previously-registered schema is assumed valid
+// TestCompatibility returns why content would break the compatibility level
+// of subject; nil means it would be accepted. A positive version checks that
+// version alone, in the direction the level asks for.
+func (r *StorageRegistry) TestCompatibility(ctx context.Context, subject string, schemaType SchemaType, content string, version int) ([]string, error) {
+	if _, err := NewValidator(SchemaConfig{Type: schemaType, Schema: content}); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
 	}
-	if err := json.Unmarshal([]byte(newContent), &newSchema); err != nil {
-		return fmt.Errorf("invalid new schema JSON: %w", err)
+	level, err := r.GetCompatibility(ctx, subject)
+	if err != nil {
+		return nil, err
 	}
-
-	// Simple check: new schema should not add new 'required' fields that weren't there
-	oldReq, _ := oldSchema["required"].([]any)
-	newReq, _ := newSchema["required"].([]any)
-
-	oldReqMap := make(map[string]bool)
-	for _, r := range oldReq {
-		if s, ok := r.(string); ok {
-			oldReqMap[s] = true
-		}
+	if level == CompatibilityNone {
+		return nil, nil
 	}
 
-	for _, r := range newReq {
-		if s, ok := r.(string); ok {
-			if !oldReqMap[s] {
-				return fmt.Errorf("backward compatibility broken: new required field '%s' added", s)
-			}
+	var previous []storage.Schema
+	switch {
+	case version > 0:
+		sc, err := r.storage.GetSchema(ctx, subject, version)
+		if err != nil {
+			return nil, err
 		}
+		previous = []storage.Schema{sc}
+	case level.Transitive():
+		if previous, err = r.storage.ListSchemas(ctx, subject); err != nil {
+			return nil, err
+		}
+		slices.SortFunc(previous, func(a, b storage.Schema) int { return a.Version - b.Version })
+	default:
+		latest, err := r.storage.GetLatestSchema(ctx, subject)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		previous = []storage.Schema{latest}
 	}
 
-	// Also check if any fields were removed from properties (if specified)
-	oldProps, _ := oldSchema["properties"].(map[string]any)
-	newProps, _ := newSchema["properties"].(map[string]any)
-	if oldProps != nil && newProps != nil {
-		for k := range oldProps {
-			if _, ok := newProps[k]; !ok {
-				return fmt.Errorf("backward compatibility broken: field '%s' was removed", k)
-			}
+	var issues []string
+	for _, sc := range previous {
+		found, err := compatibilityIssues(level, schemaType, content, sc)
+		if err != nil {
+			return nil, err
 		}
+		issues = append(issues, found...)
 	}
+	return issues, nil
+}
 
+// CheckCompatibility verifies that a new schema keeps the compatibility level
+// of its subject, returning a *CompatibilityError if it does not.
+func (r *StorageRegistry) CheckCompatibility(ctx context.Context, name string, schemaType SchemaType, content string) error {
+	issues, err := r.TestCompatibility(ctx, name, schemaType, content, 0)
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		level, _ := r.GetCompatibility(ctx, name)
+		return &CompatibilityError{Subject: name, Level: level, Messages: issues}
+	}
 	return nil
 }
@@ -9,7 +9,8 @@ import (
 
 type mockStorage struct {
 	storage.Storage
-	schemas map[string][]storage.Schema
+	schemas  map[string][]storage.Schema
+	settings map[string]string
 }
 
 func (m *mockStorage) CreateSchema(ctx context.Context, s storage.Schema) error {
@@ -25,6 +26,31 @@ func (m *mockStorage) GetLatestSchema(ctx context.Context, name string) (storage
 	return schemas[len(schemas)-1], nil
 }
 
+func (m *mockStorage) ListSchemas(ctx context.Context, name string) ([]storage.Schema, error) {
+	return m.schemas[name], nil
+}
+
+func (m *mockStorage) GetSchema(ctx context.Context, name string, version int) (storage.Schema, error) {
+	for _, s := range m.schemas[name] {
+		if s.Version == version {
+			return s, nil
+		}
+	}
+	return storage.Schema{}, storage.ErrNotFound
+}
+
+func (m *mockStorage) GetSetting(ctx context.Context, key string) (string, error) {
+	return m.settings[key], nil
+}
+
+func (m *mockStorage) SaveSetting(ctx context.Context, key string, value string) error {
+	if m.settings == nil {
+		m.settings = make(map[string]string)
+	}
+	m.settings[key] = value
+	return nil
+}
+
 func TestStorageRegistry_Register(t *testing.T) {
 	mock := &mockStorage{schemas: make(map[string][]storage.Schema)}
 	reg := NewStorageRegistry(mock)
@@ -83,6 +83,23 @@ type ProtobufValidator struct {
 }
 
 func NewProtobufValidator(schemaStr string) (*ProtobufValidator, error) {
+	fd, err := parseProto(schemaStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// We assume the first message in the first file is the one to validate against.
+	// In a real scenario, we might want to specify the message name.
+	msgs := fd.GetMessageTypes()
+	if len(msgs) == 0 {
+		return nil, errors.New("no message types found in protobuf schema")
+	}
+
+	return &ProtobufValidator{descriptor: msgs[0]}, nil
+}
+
+// parseProto parses the content of a .proto file.
+func parseProto(schemaStr string) (*desc.FileDescriptor, error) {
 	// For Protobuf, we assume the schemaStr is a .proto file content.
 	// This is a bit complex as protoparse usually expects files.
 	parser := protoparse.Parser{
@@ -97,15 +114,7 @@ func NewProtobufValidator(schemaStr string) (*ProtobufValidator, error) {
 	if len(fds) == 0 {
 		return nil, errors.New("no descriptors found in protobuf schema")
 	}
-
-	// We assume the first message in the first file is the one to validate against.
-	// In a real scenario, we might want to specify the message name.
-	msgs := fds[0].GetMessageTypes()
-	if len(msgs) == 0 {
-		return nil, errors.New("no message types found in protobuf schema")
-	}
-
-	return &ProtobufValidator{descriptor: msgs[0]}, nil
+	return fds[0], nil
 }
 
 func (v *ProtobufValidator) Validate(ctx context.Context, data map[string]any) error {
@@ -0,0 +1,86 @@
+package sketch
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+func TestHLL_Count(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		h := NewHLL()
+		for i := 0; i < n; i++ {
+			h.Add("user-" + strconv.Itoa(i))
+			h.Add("user-" + strconv.Itoa(i)) // duplicates must not count
+		}
+		got := float64(h.Count())
+		if math.Abs(got-float64(n))/float64(n) > 0.05 {
+			t.Errorf("n=%d: estimate %v is off by more than 5%%", n, got)
+		}
+	}
+}
+
+func TestHLL_MergeAndJSON(t *testing.T) {
+	a, b := NewHLL(), NewHLL()
+	for i := 0; i < 5000; i++ {
+		a.Add(strconv.Itoa(i))
+		b.Add(strconv.Itoa(i + 2500))
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored HLL
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	restored.Merge(b)
+	if got := float64(restored.Count()); math.Abs(got-7500)/7500 > 0.05 {
+		t.Errorf("merged estimate %v, want about 7500", got)
+	}
+}
+
+func TestTDigest_Quantiles(t *testing.T) {
+	d := NewTDigest()
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 100000; i++ {
+		d.Add(r.Float64() * 1000)
+	}
+	for _, q := range []float64{0.01, 0.5, 0.95, 0.99} {
+		got := d.Quantile(q)
+		if math.Abs(got-q*1000) > 10 {
+			t.Errorf("q=%v: got %v, want about %v", q, got, q*1000)
+		}
+	}
+	if d.Quantile(0) != d.Min || d.Quantile(1) != d.Max {
+		t.Error("expected the extreme quantiles to be the min and max")
+	}
+	if len(d.Centroids) > 500 {
+		t.Errorf("expected the digest to stay compact, got %d centroids", len(d.Centroids))
+	}
+}
+
+func TestTDigest_MergeAndJSON(t *testing.T) {
+	a, b := NewTDigest(), NewTDigest()
+	for i := 1; i <= 500; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i + 500))
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored TDigest
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	restored.Merge(b)
+	if restored.Count() != 1000 {
+		t.Errorf("expected 1000 points, got %v", restored.Count())
+	}
+	if got := restored.Quantile(0.5); math.Abs(got-500) > 10 {
+		t.Errorf("median %v, want about 500", got)
+	}
+}
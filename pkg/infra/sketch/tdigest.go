@@ -0,0 +1,165 @@
+package sketch
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// defaultCompression bounds a digest to a few hundred centroids while keeping
+// tail quantiles within a fraction of a percent.
+const defaultCompression = 100
+
+// Centroid is a cluster of Count points with mean Mean.
+type Centroid struct {
+	Mean  float64 `json:"m"`
+	Count float64 `json:"c"`
+}
+
+// TDigest is a merging t-digest for estimating quantiles of a stream.
+type TDigest struct {
+	Compression float64    `json:"compression"`
+	Centroids   []Centroid `json:"centroids"`
+	Total       float64    `json:"total"`
+	Min         float64    `json:"min"`
+	Max         float64    `json:"max"`
+
+	buffer []Centroid
+}
+
+// NewTDigest returns an empty digest.
+func NewTDigest() *TDigest {
+	return &TDigest{Compression: defaultCompression}
+}
+
+// Add records x.
+func (d *TDigest) Add(x float64) {
+	if math.IsNaN(x) {
+		return
+	}
+	if d.Total == 0 && len(d.buffer) == 0 {
+		d.Min, d.Max = x, x
+	}
+	d.Min = math.Min(d.Min, x)
+	d.Max = math.Max(d.Max, x)
+	d.buffer = append(d.buffer, Centroid{Mean: x, Count: 1})
+	if len(d.buffer) >= int(5*d.compression()) {
+		d.compress()
+	}
+}
+
+// Merge folds other into d.
+func (d *TDigest) Merge(other *TDigest) {
+	other.compress()
+	if len(other.Centroids) == 0 {
+		return
+	}
+	if d.Total == 0 && len(d.buffer) == 0 {
+		d.Min, d.Max = other.Min, other.Max
+	}
+	d.Min = math.Min(d.Min, other.Min)
+	d.Max = math.Max(d.Max, other.Max)
+	d.buffer = append(d.buffer, other.Centroids...)
+	d.compress()
+}
+
+// Count is the number of points recorded.
+func (d *TDigest) Count() float64 {
+	d.compress()
+	return d.Total
+}
+
+// Quantile estimates the value below which a fraction q of the points fall.
+func (d *TDigest) Quantile(q float64) float64 {
+	d.compress()
+	n := len(d.Centroids)
+	switch {
+	case n == 0:
+		return math.NaN()
+	case n == 1 || q <= 0:
+		if q >= 1 {
+			return d.Max
+		}
+		if n == 1 {
+			return d.Centroids[0].Mean
+		}
+		return d.Min
+	case q >= 1:
+		return d.Max
+	}
+
+	target := q * d.Total
+	// Each centroid's mass is centered on its mean; interpolate between the
+	// midpoints of neighbours, and towards Min/Max at the ends.
+	cum := 0.0
+	for i, c := range d.Centroids {
+		mid := cum + c.Count/2
+		if target < mid {
+			if i == 0 {
+				return d.Min + (c.Mean-d.Min)*target/mid
+			}
+			prev := d.Centroids[i-1]
+			prevMid := cum - prev.Count/2
+			return prev.Mean + (c.Mean-prev.Mean)*(target-prevMid)/(mid-prevMid)
+		}
+		cum += c.Count
+	}
+	last := d.Centroids[n-1]
+	lastMid := d.Total - last.Count/2
+	if d.Total == lastMid {
+		return d.Max
+	}
+	return last.Mean + (d.Max-last.Mean)*(target-lastMid)/(d.Total-lastMid)
+}
+
+// MarshalJSON flushes buffered points so that they are part of the encoding.
+func (d *TDigest) MarshalJSON() ([]byte, error) {
+	d.compress()
+	type plain TDigest
+	return json.Marshal((*plain)(d))
+}
+
+func (d *TDigest) compression() float64 {
+	if d.Compression <= 0 {
+		return defaultCompression
+	}
+	return d.Compression
+}
+
+// compress merges buffered points into the centroids, sizing clusters by the
+// k1 scale function so that clusters near the tails stay small.
+func (d *TDigest) compress() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	all := append(d.Centroids, d.buffer...)
+	d.buffer = d.buffer[:0]
+	sort.Slice(all, func(i, j int) bool { return all[i].Mean < all[j].Mean })
+
+	total := 0.0
+	for _, c := range all {
+		total += c.Count
+	}
+	delta := d.compression()
+	k := func(q float64) float64 { return delta / (2 * math.Pi) * math.Asin(2*q-1) }
+
+	merged := make([]Centroid, 0, len(all))
+	cur := all[0]
+	soFar := 0.0
+	kLow := k(0)
+	for _, c := range all[1:] {
+		q := (soFar + cur.Count + c.Count) / total
+		if k(q)-kLow <= 1 {
+			cur.Mean += (c.Mean - cur.Mean) * c.Count / (cur.Count + c.Count)
+			cur.Count += c.Count
+			continue
+		}
+		soFar += cur.Count
+		kLow = k(soFar / total)
+		merged = append(merged, cur)
+		cur = c
+	}
+	merged = append(merged, cur)
+	d.Centroids = merged
+	d.Total = total
+}
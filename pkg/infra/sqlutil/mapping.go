@@ -2,6 +2,8 @@ package sqlutil
 
 import (
 	"encoding/json"
+	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -15,6 +17,26 @@ type ColumnMapping struct {
 	IsIdentity   bool   `json:"is_identity"`    // Optional, auto-increment/sequence
 }
 
+// MappingState is the schema state of a sink whose column mappings schema
+// changes evolve: the mappings it was configured with and the evolved ones.
+type MappingState struct {
+	Configured []ColumnMapping `json:"configured"`
+	Mappings   []ColumnMapping `json:"mappings"`
+}
+
+// RestoreMappings returns the mappings saved in state if they evolved from
+// configured. A configuration changed since wins, and is returned as is.
+func RestoreMappings(state []byte, configured []ColumnMapping) ([]ColumnMapping, error) {
+	var st MappingState
+	if err := json.Unmarshal(state, &st); err != nil {
+		return nil, fmt.Errorf("invalid mapping state: %w", err)
+	}
+	if !slices.Equal(st.Configured, configured) {
+		return configured, nil
+	}
+	return st.Mappings, nil
+}
+
 // ParseColumnMappings parses a JSON string into a slice of ColumnMapping.
 //
 // A blank source_field is treated as "use the column's own name": it falls back
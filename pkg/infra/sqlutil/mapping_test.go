@@ -1,6 +1,10 @@
 package sqlutil
 
-import "testing"
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
 
 func TestParseColumnMappings(t *testing.T) {
 	tests := []struct {
@@ -70,3 +74,27 @@ func TestParseColumnMappings(t *testing.T) {
 		})
 	}
 }
+
+func TestRestoreMappings(t *testing.T) {
+	configured := []ColumnMapping{{SourceField: "id", TargetColumn: "id", IsPrimaryKey: true}}
+	evolved := append(configured, ColumnMapping{SourceField: "email", TargetColumn: "email", DataType: "TEXT", IsNullable: true})
+	state, err := json.Marshal(MappingState{Configured: configured, Mappings: evolved})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := RestoreMappings(state, configured)
+	if err != nil || !slices.Equal(got, evolved) {
+		t.Fatalf("RestoreMappings = %v, %v; want the evolved mappings", got, err)
+	}
+
+	// A configuration edited since the changes were applied wins.
+	edited := []ColumnMapping{{SourceField: "id", TargetColumn: "user_id", IsPrimaryKey: true}}
+	if got, _ := RestoreMappings(state, edited); !slices.Equal(got, edited) {
+		t.Fatalf("RestoreMappings = %v; want the edited configuration", got)
+	}
+
+	if _, err := RestoreMappings([]byte("{"), configured); err == nil {
+		t.Fatal("expected an error for invalid state")
+	}
+}
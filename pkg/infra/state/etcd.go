@@ -2,6 +2,10 @@ package state
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/user/hermod"
@@ -62,3 +66,196 @@ func (s *EtcdStateStore) Delete(ctx context.Context, key string) error {
 func (s *EtcdStateStore) Close() error {
 	return s.client.Close()
 }
+
+// Reserve implements hermod.RateLimitStore. The bucket is updated with a
+// compare-and-swap transaction on its revision, retried on contention.
+func (s *EtcdStateStore) Reserve(ctx context.Context, key string, rate float64, burst int) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	key = s.prefix + key
+	for {
+		resp, err := s.client.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		var tat, rev int64
+		if len(resp.Kvs) > 0 {
+			tat, _ = strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+			rev = resp.Kvs[0].ModRevision
+		}
+
+		next, wait := gcra(tat, time.Now().UnixNano(), rate, burst)
+		if wait > 0 || next == tat {
+			return wait, nil
+		}
+
+		txn, err := s.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", rev)).
+			Then(clientv3.OpPut(key, strconv.FormatInt(next, 10))).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if txn.Succeeded {
+			return 0, nil
+		}
+	}
+}
+
+// maxTxnOps stays within etcd's default limit of operations per transaction.
+const maxTxnOps = 128
+
+// CompareAndSwap implements hermod.KeyedStateStore with a transaction that
+// compares the key's value, or its absence.
+func (s *EtcdStateStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	key = s.prefix + key
+	cmp := clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	if old != nil {
+		cmp = clientv3.Compare(clientv3.Value(key), "=", string(old))
+	}
+	put, err := s.put(ctx, key, value, old != nil, ttl)
+	if err != nil {
+		return false, err
+	}
+	txn, err := s.client.Txn(ctx).If(cmp).Then(put).Commit()
+	if err != nil {
+		return false, err
+	}
+	return txn.Succeeded, nil
+}
+
+// Increment implements hermod.KeyedStateStore. The sum is written with a
+// compare-and-swap transaction on the key's revision, retried on contention.
+func (s *EtcdStateStore) Increment(ctx context.Context, key string, delta float64, ttl time.Duration) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	key = s.prefix + key
+	for {
+		resp, err := s.client.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		var sum float64
+		var rev int64
+		if len(resp.Kvs) > 0 {
+			if sum, err = strconv.ParseFloat(string(resp.Kvs[0].Value), 64); err != nil {
+				return 0, fmt.Errorf("state key %q does not hold a number", key)
+			}
+			rev = resp.Kvs[0].ModRevision
+		}
+		sum += delta
+
+		put, err := s.put(ctx, key, []byte(strconv.FormatFloat(sum, 'f', -1, 64)), rev != 0, ttl)
+		if err != nil {
+			return 0, err
+		}
+		txn, err := s.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", rev)).
+			Then(put).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if txn.Succeeded {
+			return sum, nil
+		}
+	}
+}
+
+// SetWithTTL implements hermod.KeyedStateStore with a lease of ttl.
+func (s *EtcdStateStore) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	put, err := s.put(ctx, s.prefix+key, value, false, ttl)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Do(ctx, put)
+	return err
+}
+
+// put returns the write of key. With a ttl the key gets a lease of its own;
+// without one an existing key keeps its lease.
+func (s *EtcdStateStore) put(ctx context.Context, key string, value []byte, exists bool, ttl time.Duration) (clientv3.Op, error) {
+	if ttl > 0 {
+		// Leases have whole seconds, so expiry is rounded up.
+		lease, err := s.client.Grant(ctx, int64((ttl+time.Second-1)/time.Second))
+		if err != nil {
+			return clientv3.Op{}, err
+		}
+		return clientv3.OpPut(key, string(value), clientv3.WithLease(lease.ID)), nil
+	}
+	if exists {
+		return clientv3.OpPut(key, string(value), clientv3.WithIgnoreLease()), nil
+	}
+	return clientv3.OpPut(key, string(value)), nil
+}
+
+// Scan implements hermod.KeyedStateStore.
+func (s *EtcdStateStore) Scan(ctx context.Context, prefix string, limit int) (map[string][]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend)}
+	if limit > 0 {
+		opts = append(opts, clientv3.WithLimit(int64(limit)))
+	}
+	resp, err := s.client.Get(ctx, s.prefix+prefix, opts...)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		entries[strings.TrimPrefix(string(kv.Key), s.prefix)] = kv.Value
+	}
+	return entries, nil
+}
+
+// GetMany implements hermod.KeyedStateStore. Keys are read in transactions
+// of at most maxTxnOps, each at a single revision.
+func (s *EtcdStateStore) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	entries := make(map[string][]byte, len(keys))
+	for chunk := range slices.Chunk(keys, maxTxnOps) {
+		ops := make([]clientv3.Op, len(chunk))
+		for i, key := range chunk {
+			ops[i] = clientv3.OpGet(s.prefix + key)
+		}
+		txn, err := s.client.Txn(ctx).Then(ops...).Commit()
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range txn.Responses {
+			if kvs := r.GetResponseRange().GetKvs(); len(kvs) > 0 {
+				entries[chunk[i]] = kvs[0].Value
+			}
+		}
+	}
+	return entries, nil
+}
+
+// SetMany implements hermod.KeyedStateStore. Entries beyond maxTxnOps are
+// written in several transactions.
+func (s *EtcdStateStore) SetMany(ctx context.Context, entries map[string][]byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	ops := make([]clientv3.Op, 0, len(entries))
+	for key, val := range entries {
+		ops = append(ops, clientv3.OpPut(s.prefix+key, string(val)))
+	}
+	for chunk := range slices.Chunk(ops, maxTxnOps) {
+		if _, err := s.client.Txn(ctx).Then(chunk...).Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
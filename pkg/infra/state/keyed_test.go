@@ -0,0 +1,152 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+)
+
+func newKeyedStore(t *testing.T) hermod.KeyedStateStore {
+	t.Helper()
+	store, err := NewSQLiteStateStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.(*SQLiteStateStore).Close() })
+	keyed, ok := store.(hermod.KeyedStateStore)
+	if !ok {
+		t.Fatal("sqlite state store does not implement hermod.KeyedStateStore")
+	}
+	return keyed
+}
+
+func TestSQLiteStateStore_CompareAndSwap(t *testing.T) {
+	store := newKeyedStore(t)
+	ctx := context.Background()
+
+	if ok, err := store.CompareAndSwap(ctx, "k", nil, []byte("a"), 0); err != nil || !ok {
+		t.Fatalf("expected to create a missing key, got %v, %v", ok, err)
+	}
+	if ok, _ := store.CompareAndSwap(ctx, "k", nil, []byte("b"), 0); ok {
+		t.Fatal("swapped an existing key expected to be missing")
+	}
+	if ok, _ := store.CompareAndSwap(ctx, "k", []byte("x"), []byte("b"), 0); ok {
+		t.Fatal("swapped a key holding another value")
+	}
+	if ok, err := store.CompareAndSwap(ctx, "k", []byte("a"), []byte("b"), 0); err != nil || !ok {
+		t.Fatalf("expected to swap, got %v, %v", ok, err)
+	}
+	if v, _ := store.Get(ctx, "k"); string(v) != "b" {
+		t.Fatalf("got %q", v)
+	}
+}
+
+func TestSQLiteStateStore_Increment(t *testing.T) {
+	store := newKeyedStore(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Increment(ctx, "n", 1.5, 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if sum, err := store.Increment(ctx, "n", 0, 0); err != nil || sum != 30 {
+		t.Fatalf("got %v, %v", sum, err)
+	}
+
+	_ = store.Set(ctx, "s", []byte("text"))
+	if _, err := store.Increment(ctx, "s", 1, 0); err == nil {
+		t.Fatal("expected an error incrementing a string")
+	}
+}
+
+func TestSQLiteStateStore_TTL(t *testing.T) {
+	store := newKeyedStore(t)
+	ctx := context.Background()
+
+	_ = store.SetWithTTL(ctx, "gone", []byte("x"), time.Millisecond)
+	_ = store.SetWithTTL(ctx, "kept", []byte("1"), time.Hour)
+	time.Sleep(5 * time.Millisecond)
+
+	if v, _ := store.Get(ctx, "gone"); v != nil {
+		t.Fatalf("expired key still reads %q", v)
+	}
+	if ok, _ := store.CompareAndSwap(ctx, "gone", nil, []byte("y"), 0); !ok {
+		t.Fatal("an expired key is not missing")
+	}
+	if v, _ := store.Get(ctx, "gone"); string(v) != "y" {
+		t.Fatalf("got %q", v)
+	}
+
+	// Increments without a ttl keep the expiry.
+	if _, err := store.Increment(ctx, "kept", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	var expires sql.NullInt64
+	db := store.(*SQLiteStateStore).db
+	_ = db.QueryRow(`SELECT expires_at FROM states WHERE key = 'kept'`).Scan(&expires)
+	if !expires.Valid {
+		t.Fatal("increment cleared the expiry")
+	}
+}
+
+func TestSQLiteStateStore_ScanAndBatch(t *testing.T) {
+	store := newKeyedStore(t)
+	ctx := context.Background()
+
+	err := store.SetMany(ctx, map[string][]byte{"a:1": []byte("1"), "a:2": []byte("2"), "a_3": []byte("3"), "b:1": []byte("4")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.Scan(ctx, "a:", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || string(entries["a:1"]) != "1" || string(entries["a:2"]) != "2" {
+		t.Fatalf("got %q", entries)
+	}
+	if entries, _ := store.Scan(ctx, "a", 1); len(entries) != 1 {
+		t.Fatalf("limit ignored: %q", entries)
+	}
+
+	got, err := store.GetMany(ctx, []string{"a:1", "b:1", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || string(got["b:1"]) != "4" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestSQLiteStateStore_MigratesExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE states (key TEXT PRIMARY KEY, value BLOB); INSERT INTO states VALUES ('old', '7')`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewSQLiteStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.(*SQLiteStateStore).Close()
+	if sum, err := store.(hermod.KeyedStateStore).Increment(context.Background(), "old", 1, time.Hour); err != nil || sum != 8 {
+		t.Fatalf("got %v, %v", sum, err)
+	}
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/user/hermod/internal/storage"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// leasePrefix namespaces named leases within the store's prefix.
+const leasePrefix = "leases/"
+
+// AcquireLease takes or extends a named lease with the same semantics as
+// storage.Storage's, so etcd can elect the platform leader instead of the
+// primary database.
+func (s *EtcdStateStore) AcquireLease(ctx context.Context, name, ownerID string, ttlSeconds int) (bool, error) {
+	if ttlSeconds <= 0 {
+		ttlSeconds = 30
+	}
+	return s.modifyLease(ctx, name, func(l *storage.Lease, now time.Time) bool {
+		if l.OwnerID != "" && l.OwnerID != ownerID && !l.LeaseUntil.Before(now) {
+			return false
+		}
+		if l.OwnerID != ownerID {
+			l.Token++
+			l.AcquiredAt = now
+		}
+		l.OwnerID, l.LeaseUntil = ownerID, now.Add(time.Duration(ttlSeconds)*time.Second)
+		return true
+	})
+}
+
+// ReleaseLease frees the named lease if owned by ownerID.
+func (s *EtcdStateStore) ReleaseLease(ctx context.Context, name, ownerID string) error {
+	_, err := s.modifyLease(ctx, name, func(l *storage.Lease, now time.Time) bool {
+		if l.Token == 0 || l.OwnerID != ownerID {
+			return false
+		}
+		l.OwnerID, l.LeaseUntil = "", now
+		return true
+	})
+	return err
+}
+
+// GetLease returns the named lease, or storage.ErrNotFound.
+func (s *EtcdStateStore) GetLease(ctx context.Context, name string) (storage.Lease, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var l storage.Lease
+	resp, err := s.client.Get(ctx, s.prefix+leasePrefix+name)
+	if err != nil {
+		return l, err
+	}
+	if len(resp.Kvs) == 0 {
+		return l, storage.ErrNotFound
+	}
+	err = json.Unmarshal(resp.Kvs[0].Value, &l)
+	return l, err
+}
+
+// modifyLease applies fn to the stored lease with a compare-and-swap on its
+// revision, retried on contention. A lease never taken is passed as its zero
+// value. It reports whether fn made a change.
+func (s *EtcdStateStore) modifyLease(ctx context.Context, name string, fn func(l *storage.Lease, now time.Time) bool) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	key := s.prefix + leasePrefix + name
+	for {
+		resp, err := s.client.Get(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		l := storage.Lease{Name: name}
+		var rev int64
+		if len(resp.Kvs) > 0 {
+			if err := json.Unmarshal(resp.Kvs[0].Value, &l); err != nil {
+				return false, err
+			}
+			rev = resp.Kvs[0].ModRevision
+		}
+
+		if !fn(&l, time.Now().UTC()) {
+			return false, nil
+		}
+		data, err := json.Marshal(l)
+		if err != nil {
+			return false, err
+		}
+
+		txn, err := s.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", rev)).
+			Then(clientv3.OpPut(key, string(data))).
+			Commit()
+		if err != nil {
+			return false, err
+		}
+		if txn.Succeeded {
+			return true, nil
+		}
+	}
+}
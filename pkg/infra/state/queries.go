@@ -1,15 +1,25 @@
 package state
 
 const (
-	QueryInitTable = "InitTable"
-	QueryGet       = "Get"
-	QuerySet       = "Set"
-	QueryDelete    = "Delete"
+	QueryInitTable    = "InitTable"
+	QueryHasExpiry    = "HasExpiry"
+	QueryAddExpiry    = "AddExpiry"
+	QueryGet          = "Get"
+	QuerySet          = "Set"
+	QueryUpdate       = "Update"
+	QueryDelete       = "Delete"
+	QueryScan         = "Scan"
+	QueryPurgeExpired = "PurgeExpired"
 )
 
 var commonQueries = map[string]string{
-	QueryInitTable: `CREATE TABLE IF NOT EXISTS states (key TEXT PRIMARY KEY, value BLOB)`,
-	QueryGet:       `SELECT value FROM states WHERE key = ?`,
-	QuerySet:       `INSERT INTO states (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value=excluded.value`,
-	QueryDelete:    `DELETE FROM states WHERE key = ?`,
+	QueryInitTable:    `CREATE TABLE IF NOT EXISTS states (key TEXT PRIMARY KEY, value BLOB, expires_at INTEGER)`,
+	QueryHasExpiry:    `SELECT COUNT(*) FROM pragma_table_info('states') WHERE name = 'expires_at'`,
+	QueryAddExpiry:    `ALTER TABLE states ADD COLUMN expires_at INTEGER`,
+	QueryGet:          `SELECT value FROM states WHERE key = ? AND (expires_at IS NULL OR expires_at > ?)`,
+	QuerySet:          `INSERT INTO states (key, value, expires_at) VALUES (?, ?, ?) ON CONFLICT(key) DO UPDATE SET value=excluded.value, expires_at=excluded.expires_at`,
+	QueryUpdate:       `UPDATE states SET value = ?, expires_at = COALESCE(?, expires_at) WHERE key = ?`,
+	QueryDelete:       `DELETE FROM states WHERE key = ?`,
+	QueryScan:         `SELECT key, value FROM states WHERE substr(key, 1, length(?)) = ? AND (expires_at IS NULL OR expires_at > ?) ORDER BY key LIMIT ?`,
+	QueryPurgeExpired: `DELETE FROM states WHERE expires_at IS NOT NULL AND expires_at <= ?`,
 }
@@ -0,0 +1,33 @@
+package state
+
+import "time"
+
+// gcra applies the generic cell rate algorithm to a bucket whose theoretical
+// arrival time is tat (Unix nanoseconds, zero for a fresh bucket). Units are
+// emitted every 1/rate seconds and up to burst of them may be taken at once.
+// It returns the bucket's new arrival time and, when no unit is available,
+// how long to wait; a denied request leaves the bucket unchanged. A
+// non-positive rate does not limit.
+func gcra(tat, now int64, rate float64, burst int) (int64, time.Duration) {
+	if rate <= 0 {
+		return tat, 0
+	}
+	emission := emissionInterval(rate)
+	if burst < 1 {
+		burst = 1
+	}
+	if tat < now {
+		tat = now
+	}
+	next := tat + emission
+	allowAt := next - emission*int64(burst)
+	if now < allowAt {
+		return tat, time.Duration(allowAt - now)
+	}
+	return next, 0
+}
+
+// emissionInterval is the nanoseconds between units at rate units per second.
+func emissionInterval(rate float64) int64 {
+	return max(int64(float64(time.Second)/rate), 1)
+}
@@ -0,0 +1,65 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/user/hermod"
+)
+
+func TestGCRA(t *testing.T) {
+	now := time.Now().UnixNano()
+	var tat int64
+
+	// A burst of 3 is available immediately, the 4th waits one interval.
+	for i := 0; i < 3; i++ {
+		var wait time.Duration
+		tat, wait = gcra(tat, now, 10, 3)
+		if wait != 0 {
+			t.Fatalf("request %d: expected no wait, got %v", i, wait)
+		}
+	}
+	next, wait := gcra(tat, now, 10, 3)
+	if wait != 100*time.Millisecond || next != tat {
+		t.Fatalf("expected a 100ms wait without consuming, got %v", wait)
+	}
+
+	// After one interval a single unit has refilled.
+	later := now + int64(100*time.Millisecond)
+	if _, wait := gcra(tat, later, 10, 3); wait != 0 {
+		t.Errorf("expected a unit after one interval, got wait %v", wait)
+	}
+}
+
+func TestSQLiteStateStore_Reserve(t *testing.T) {
+	store, err := NewSQLiteStateStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.(*SQLiteStateStore).Close()
+
+	limiter, ok := store.(hermod.RateLimitStore)
+	if !ok {
+		t.Fatal("sqlite state store does not implement hermod.RateLimitStore")
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if wait, err := limiter.Reserve(ctx, "api", 1, 2); err != nil || wait != 0 {
+			t.Fatalf("request %d: expected to pass, got %v, %v", i, wait, err)
+		}
+	}
+	wait, err := limiter.Reserve(ctx, "api", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("expected a wait of up to 1s once the burst is spent, got %v", wait)
+	}
+
+	// Buckets are independent per key.
+	if wait, _ := limiter.Reserve(ctx, "other", 1, 1); wait != 0 {
+		t.Errorf("expected a fresh bucket for another key, got wait %v", wait)
+	}
+}
@@ -3,6 +3,7 @@ package state
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -42,3 +43,149 @@ func (s *RedisStateStore) Set(ctx context.Context, key string, value []byte) err
 func (s *RedisStateStore) Delete(ctx context.Context, key string) error {
 	return s.client.Del(ctx, s.prefix+key).Err()
 }
+
+// reserveScript runs GCRA inside Redis against the server clock, so workers
+// with skewed clocks still share one budget. The bucket key expires once it
+// has fully refilled.
+var reserveScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local emission = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+local nextTat = tat + emission
+local allowAt = nextTat - emission * burst
+if now < allowAt then return allowAt - now end
+redis.call('SET', KEYS[1], nextTat, 'PX', math.ceil((nextTat - now) / 1000))
+return 0
+`)
+
+// Reserve implements hermod.RateLimitStore with an atomic GCRA script.
+func (s *RedisStateStore) Reserve(ctx context.Context, key string, rate float64, burst int) (time.Duration, error) {
+	if rate <= 0 {
+		return 0, nil
+	}
+	emission := max(emissionInterval(rate)/int64(time.Microsecond), 1)
+	waitMicros, err := reserveScript.Run(ctx, s.client, []string{s.prefix + key}, emission, max(burst, 1)).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(waitMicros) * time.Microsecond, nil
+}
+
+// casScript swaps KEYS[1] when it holds ARGV[2], or when it does not exist if
+// ARGV[1] is "1". A positive ARGV[4] sets the expiry in milliseconds; else
+// an existing key keeps its own and a new one gets ARGV[5], if positive.
+var casScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if ARGV[1] == '1' then
+	if cur then return 0 end
+elseif cur ~= ARGV[2] then
+	return 0
+end
+local ttl = tonumber(ARGV[4])
+if ttl <= 0 and not cur then ttl = tonumber(ARGV[5]) end
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[3], 'PX', ttl)
+elseif cur then
+	redis.call('SET', KEYS[1], ARGV[3], 'KEEPTTL')
+else
+	redis.call('SET', KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// CompareAndSwap implements hermod.KeyedStateStore with an atomic script.
+func (s *RedisStateStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	missing := "0"
+	if old == nil {
+		missing = "1"
+	}
+	n, err := casScript.Run(ctx, s.client, []string{s.prefix + key},
+		missing, old, value, ttl.Milliseconds(), s.ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+// Increment implements hermod.KeyedStateStore with INCRBYFLOAT, which keeps
+// the key's expiry.
+func (s *RedisStateStore) Increment(ctx context.Context, key string, delta float64, ttl time.Duration) (float64, error) {
+	if ttl <= 0 {
+		return s.client.IncrByFloat(ctx, s.prefix+key, delta).Result()
+	}
+	var incr *redis.FloatCmd
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		incr = p.IncrByFloat(ctx, s.prefix+key, delta)
+		p.PExpire(ctx, s.prefix+key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// SetWithTTL implements hermod.KeyedStateStore.
+func (s *RedisStateStore) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, value, ttl).Err()
+}
+
+// Scan implements hermod.KeyedStateStore. SCAN visits keys in no particular
+// order, so a limited scan returns an arbitrary subset.
+func (s *RedisStateStore) Scan(ctx context.Context, prefix string, limit int) (map[string][]byte, error) {
+	var keys []string
+	iter := s.client.Scan(ctx, 0, globEscape(s.prefix+prefix)+"*", 100).Iterator()
+	for iter.Next(ctx) && (limit <= 0 || len(keys) < limit) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), s.prefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return s.GetMany(ctx, keys)
+}
+
+// GetMany implements hermod.KeyedStateStore with MGET.
+func (s *RedisStateStore) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	entries := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return entries, nil
+	}
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = s.prefix + key
+	}
+	vals, err := s.client.MGet(ctx, full...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		if str, ok := v.(string); ok {
+			entries[keys[i]] = []byte(str)
+		}
+	}
+	return entries, nil
+}
+
+// SetMany implements hermod.KeyedStateStore in one transaction.
+func (s *RedisStateStore) SetMany(ctx context.Context, entries map[string][]byte) error {
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for key, val := range entries {
+			p.Set(ctx, s.prefix+key, val, s.ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// globEscape escapes the pattern characters of a SCAN MATCH glob.
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -1,18 +1,27 @@
 package state
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/user/hermod"
 	// modernc.org/sqlite registers the pure-Go "sqlite" database/sql driver via init().
 	_ "modernc.org/sqlite"
 )
 
+// purgeInterval is how often expired keys are deleted. Until then they are
+// only hidden from reads.
+const purgeInterval = time.Minute
+
 type SQLiteStateStore struct {
-	db *sql.DB
+	db        *sql.DB
+	lastPurge atomic.Int64
 }
 
 func NewSQLiteStateStore(path string) (hermod.StateStore, error) {
@@ -28,23 +37,43 @@ func NewSQLiteStateStore(path string) (hermod.StateStore, error) {
 		return nil, fmt.Errorf("failed to create states table: %w", err)
 	}
 
+	// Tables created before keys could expire lack the expiry column.
+	var hasExpiry int
+	if err := db.QueryRow(commonQueries[QueryHasExpiry]).Scan(&hasExpiry); err == nil && hasExpiry == 0 {
+		if _, err := db.Exec(commonQueries[QueryAddExpiry]); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to add expiry to states table: %w", err)
+		}
+	}
+
 	return &SQLiteStateStore{db: db}, nil
 }
 
+// rowQuerier is a *sql.DB or a *sql.Conn.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 func (s *SQLiteStateStore) Get(ctx context.Context, key string) ([]byte, error) {
+	val, _, err := s.get(ctx, s.db, key)
+	return val, err
+}
+
+// get reads the live value of key and reports whether it exists.
+func (s *SQLiteStateStore) get(ctx context.Context, q rowQuerier, key string) ([]byte, bool, error) {
 	var val []byte
-	err := s.db.QueryRowContext(ctx, commonQueries[QueryGet], key).Scan(&val)
+	err := q.QueryRowContext(ctx, commonQueries[QueryGet], key, time.Now().UnixNano()).Scan(&val)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+		return nil, false, nil
 	}
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	return val, nil
+	return val, true, nil
 }
 
 func (s *SQLiteStateStore) Set(ctx context.Context, key string, value []byte) error {
-	_, err := s.db.ExecContext(ctx, commonQueries[QuerySet], key, value)
+	_, err := s.db.ExecContext(ctx, commonQueries[QuerySet], key, value, nil)
 	return err
 }
 
@@ -56,3 +85,176 @@ func (s *SQLiteStateStore) Delete(ctx context.Context, key string) error {
 func (s *SQLiteStateStore) Close() error {
 	return s.db.Close()
 }
+
+// SetWithTTL implements hermod.KeyedStateStore.
+func (s *SQLiteStateStore) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx, commonQueries[QuerySet], key, value, expiresAt(ttl))
+	s.purge(ctx, ttl)
+	return err
+}
+
+// CompareAndSwap implements hermod.KeyedStateStore.
+func (s *SQLiteStateStore) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (swapped bool, err error) {
+	err = s.immediate(ctx, func(conn *sql.Conn) error {
+		cur, ok, err := s.get(ctx, conn, key)
+		if err != nil {
+			return err
+		}
+		if ok != (old != nil) || !bytes.Equal(cur, old) {
+			return nil
+		}
+		swapped = true
+		return s.put(ctx, conn, key, value, ok, ttl)
+	})
+	s.purge(ctx, ttl)
+	return swapped, err
+}
+
+// Increment implements hermod.KeyedStateStore.
+func (s *SQLiteStateStore) Increment(ctx context.Context, key string, delta float64, ttl time.Duration) (sum float64, err error) {
+	err = s.immediate(ctx, func(conn *sql.Conn) error {
+		cur, ok, err := s.get(ctx, conn, key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if sum, err = strconv.ParseFloat(string(cur), 64); err != nil {
+				return fmt.Errorf("state key %q does not hold a number", key)
+			}
+		}
+		sum += delta
+		return s.put(ctx, conn, key, []byte(strconv.FormatFloat(sum, 'f', -1, 64)), ok, ttl)
+	})
+	s.purge(ctx, ttl)
+	return sum, err
+}
+
+// put writes key within a read-modify-write, keeping the expiry of live keys
+// when ttl is zero.
+func (s *SQLiteStateStore) put(ctx context.Context, conn *sql.Conn, key string, value []byte, exists bool, ttl time.Duration) error {
+	var err error
+	if exists {
+		_, err = conn.ExecContext(ctx, commonQueries[QueryUpdate], value, expiresAt(ttl), key)
+	} else {
+		_, err = conn.ExecContext(ctx, commonQueries[QuerySet], key, value, expiresAt(ttl))
+	}
+	return err
+}
+
+// Scan implements hermod.KeyedStateStore.
+func (s *SQLiteStateStore) Scan(ctx context.Context, prefix string, limit int) (map[string][]byte, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.QueryContext(ctx, commonQueries[QueryScan], prefix, prefix, time.Now().UnixNano(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make(map[string][]byte)
+	for rows.Next() {
+		var key string
+		var val []byte
+		if err := rows.Scan(&key, &val); err != nil {
+			return nil, err
+		}
+		entries[key] = val
+	}
+	return entries, rows.Err()
+}
+
+// GetMany implements hermod.KeyedStateStore.
+func (s *SQLiteStateStore) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	entries := make(map[string][]byte, len(keys))
+	err := s.immediate(ctx, func(conn *sql.Conn) error {
+		for _, key := range keys {
+			val, ok, err := s.get(ctx, conn, key)
+			if err != nil {
+				return err
+			}
+			if ok {
+				entries[key] = val
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// SetMany implements hermod.KeyedStateStore.
+func (s *SQLiteStateStore) SetMany(ctx context.Context, entries map[string][]byte) error {
+	return s.immediate(ctx, func(conn *sql.Conn) error {
+		for key, val := range entries {
+			if _, err := conn.ExecContext(ctx, commonQueries[QuerySet], key, val, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// immediate runs fn in an immediate transaction, which holds the database
+// write lock against other processes sharing the file.
+func (s *SQLiteStateStore) immediate(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_, _ = conn.ExecContext(context.Background(), "ROLLBACK")
+			return
+		}
+		_, err = conn.ExecContext(ctx, "COMMIT")
+	}()
+	return fn(conn)
+}
+
+// purge deletes expired keys at most once per purgeInterval, after writes
+// that set an expiry.
+func (s *SQLiteStateStore) purge(ctx context.Context, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	last := s.lastPurge.Load()
+	if now-last < int64(purgeInterval) || !s.lastPurge.CompareAndSwap(last, now) {
+		return
+	}
+	_, _ = s.db.ExecContext(ctx, commonQueries[QueryPurgeExpired], now)
+}
+
+// expiresAt is the expiry column of a key written with ttl, NULL for none.
+func expiresAt(ttl time.Duration) any {
+	if ttl <= 0 {
+		return nil
+	}
+	return time.Now().Add(ttl).UnixNano()
+}
+
+// Reserve implements hermod.RateLimitStore for single-node deployments. The
+// bucket is read and written in one immediate transaction.
+func (s *SQLiteStateStore) Reserve(ctx context.Context, key string, rate float64, burst int) (wait time.Duration, err error) {
+	err = s.immediate(ctx, func(conn *sql.Conn) error {
+		val, _, err := s.get(ctx, conn, key)
+		if err != nil {
+			return err
+		}
+		tat, _ := strconv.ParseInt(string(val), 10, 64)
+
+		var next int64
+		next, wait = gcra(tat, time.Now().UnixNano(), rate, burst)
+		if wait > 0 || next == tat {
+			return nil
+		}
+		_, err = conn.ExecContext(ctx, commonQueries[QuerySet], key, []byte(strconv.FormatInt(next, 10)), nil)
+		return err
+	})
+	return wait, err
+}
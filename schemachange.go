@@ -0,0 +1,145 @@
+package hermod
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// OpSchemaChange marks a control message announcing that the columns of a
+// source table changed. It carries no row: the change itself is stored
+// under SchemaChangeKey in the message metadata.
+const OpSchemaChange Operation = "schema_change"
+
+// SchemaChangeKey is the metadata key holding the JSON encoded SchemaChange
+// of a schema change message.
+const SchemaChangeKey = "_hermod_schema_change"
+
+// SchemaChange describes the columns of a source table after a change, such
+// as an ALTER TABLE, and before it where the source knows them.
+type SchemaChange struct {
+	Schema string `json:"schema,omitempty"`
+	Table  string `json:"table"`
+	// Columns are the columns of the table after the change.
+	Columns []ColumnInfo `json:"columns"`
+	// Previous are the columns before the change. Nil means they are not
+	// known, in which case every column counts as added.
+	Previous []ColumnInfo `json:"previous,omitempty"`
+	// DDL is the statement that made the change, when the source has it.
+	DDL string `json:"ddl,omitempty"`
+	// Subject and Version identify the change in the schema registry once
+	// the engine has registered it.
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+}
+
+// QualifiedTable returns the table name, prefixed with its schema if any.
+func (c SchemaChange) QualifiedTable() string {
+	if c.Schema == "" {
+		return c.Table
+	}
+	return c.Schema + "." + c.Table
+}
+
+// Added returns the columns that did not exist before the change.
+func (c SchemaChange) Added() []ColumnInfo {
+	var added []ColumnInfo
+	for _, col := range c.Columns {
+		if _, ok := findColumn(c.Previous, col.Name); !ok {
+			added = append(added, col)
+		}
+	}
+	return added
+}
+
+// Dropped returns the columns that no longer exist after the change.
+func (c SchemaChange) Dropped() []ColumnInfo {
+	var dropped []ColumnInfo
+	for _, col := range c.Previous {
+		if _, ok := findColumn(c.Columns, col.Name); !ok {
+			dropped = append(dropped, col)
+		}
+	}
+	return dropped
+}
+
+// Retyped returns the columns, as they are after the change, whose type or
+// nullability changed.
+func (c SchemaChange) Retyped() []ColumnInfo {
+	var retyped []ColumnInfo
+	for _, col := range c.Columns {
+		prev, ok := findColumn(c.Previous, col.Name)
+		if ok && (!strings.EqualFold(prev.Type, col.Type) || prev.IsNullable != col.IsNullable) {
+			retyped = append(retyped, col)
+		}
+	}
+	return retyped
+}
+
+// Empty reports whether the change neither adds, drops nor retypes a column.
+func (c SchemaChange) Empty() bool {
+	return len(c.Added()) == 0 && len(c.Dropped()) == 0 && len(c.Retyped()) == 0
+}
+
+// Additive returns the part of the change that only adds columns: the
+// previous columns followed by the added ones.
+func (c SchemaChange) Additive() SchemaChange {
+	additive := c
+	if c.Previous != nil {
+		additive.Columns = append(append([]ColumnInfo{}, c.Previous...), c.Added()...)
+	}
+	return additive
+}
+
+func findColumn(cols []ColumnInfo, name string) (ColumnInfo, bool) {
+	for _, col := range cols {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return ColumnInfo{}, false
+}
+
+// SetSchemaChange makes msg a schema change message carrying c.
+func SetSchemaChange(msg Message, c SchemaChange) {
+	b, _ := json.Marshal(c)
+	msg.SetMetadata(SchemaChangeKey, string(b))
+}
+
+// SchemaChangeOf returns the change a schema change message carries, and
+// false for every other message.
+func SchemaChangeOf(msg Message) (SchemaChange, bool) {
+	var c SchemaChange
+	if msg == nil || msg.Operation() != OpSchemaChange {
+		return c, false
+	}
+	if err := json.Unmarshal([]byte(msg.Metadata()[SchemaChangeKey]), &c); err != nil {
+		return c, false
+	}
+	return c, true
+}
+
+// SchemaChangeSink is an optional interface for sinks that can evolve their
+// target tables. The engine hands them the schema changes their schema
+// change policy lets through, in order with the rows around them.
+type SchemaChangeSink interface {
+	Sink
+	ApplySchemaChange(ctx context.Context, change SchemaChange) error
+}
+
+// SchemaStateSink is implemented by schema change sinks that keep what the
+// changes they applied did, such as evolved column mappings, in memory. The
+// engine records the JSON SchemaState after every change a sink applies, and
+// the sink of the next run gets it back through RestoreSchemaState before its
+// first write, rather than starting over from its configuration. A nil state
+// means there is nothing to keep.
+type SchemaStateSink interface {
+	SchemaState() ([]byte, error)
+	RestoreSchemaState(state []byte) error
+}
+
+// SchemaStateRecorder persists the state of schema state sinks. A schema
+// change whose state is not recorded is not acknowledged.
+type SchemaStateRecorder interface {
+	RecordSchemaState(ctx context.Context, workflowID, sinkID string, state []byte) error
+}
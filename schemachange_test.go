@@ -0,0 +1,52 @@
+package hermod
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSchemaChangeDiff(t *testing.T) {
+	id := ColumnInfo{Name: "id", Type: "integer", IsPK: true}
+	name := ColumnInfo{Name: "name", Type: "text", IsNullable: true}
+	change := SchemaChange{
+		Table:    "users",
+		Previous: []ColumnInfo{id, name, {Name: "age", Type: "integer", IsNullable: true}},
+		Columns:  []ColumnInfo{id, {Name: "name", Type: "varchar(100)", IsNullable: true}, {Name: "email", Type: "text", IsNullable: true}},
+	}
+
+	names := func(cols []ColumnInfo) []string {
+		var out []string
+		for _, c := range cols {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+	if got := names(change.Added()); !slices.Equal(got, []string{"email"}) {
+		t.Errorf("Added() = %v", got)
+	}
+	if got := names(change.Dropped()); !slices.Equal(got, []string{"age"}) {
+		t.Errorf("Dropped() = %v", got)
+	}
+	if got := names(change.Retyped()); !slices.Equal(got, []string{"name"}) {
+		t.Errorf("Retyped() = %v", got)
+	}
+	if change.Empty() {
+		t.Error("Empty() = true for a change with differences")
+	}
+
+	additive := change.Additive()
+	if got := names(additive.Columns); !slices.Equal(got, []string{"id", "name", "age", "email"}) {
+		t.Errorf("Additive().Columns = %v", got)
+	}
+	if len(additive.Dropped()) != 0 || len(additive.Retyped()) != 0 {
+		t.Error("Additive() must neither drop nor retype columns")
+	}
+
+	same := SchemaChange{Table: "users", Previous: []ColumnInfo{id}, Columns: []ColumnInfo{{Name: "id", Type: "INTEGER", IsPK: true}}}
+	if !same.Empty() {
+		t.Error("a change of type case only must be empty")
+	}
+	if unknown := (SchemaChange{Table: "users", Columns: []ColumnInfo{id}}); len(unknown.Added()) != 1 {
+		t.Error("without previous columns every column must count as added")
+	}
+}
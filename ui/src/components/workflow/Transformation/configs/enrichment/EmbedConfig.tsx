@@ -0,0 +1,117 @@
+import { Group, NumberInput, PasswordInput, Select, Stack, TextInput } from '@mantine/core';
+
+interface EmbedConfigProps {
+  config: any;
+  updateNodeConfig: (id: string, config: any) => void;
+  nodeId: string;
+}
+
+export function EmbedConfig({ config, updateNodeConfig, nodeId }: EmbedConfigProps) {
+  const provider = config.provider || 'openai';
+  return (
+
+    <Stack gap="md">
+      <TextInput
+        label="Text Field"
+        placeholder="body"
+        required
+        value={config.field || ''}
+        onChange={(e: React.ChangeEvent<HTMLInputElement>) => updateNodeConfig(nodeId, { field: e.target.value })}
+        description="Field holding the text to chunk and embed."
+      />
+      <TextInput
+        label="Document Key Field"
+        placeholder="id"
+        value={config.idField || ''}
+        onChange={(e: React.ChangeEvent<HTMLInputElement>) => updateNodeConfig(nodeId, { idField: e.target.value })}
+        description="Identifies the document across versions so stale chunks can be deleted. Defaults to the message ID."
+      />
+      <Select
+        label="Chunking Strategy"
+        data={[
+          { value: 'fixed', label: 'Fixed size with overlap' },
+          { value: 'sentence', label: 'Sentences' },
+          { value: 'markdown', label: 'Markdown sections' },
+        ]}
+        value={config.strategy || 'fixed'}
+        onChange={(val: string | null) => updateNodeConfig(nodeId, { strategy: val || 'fixed' })}
+      />
+      <Group grow>
+        <NumberInput
+          label="Chunk Size"
+          description="Characters"
+          min={1}
+          value={config.chunkSize ?? 1000}
+          onChange={(val) => updateNodeConfig(nodeId, { chunkSize: Number(val) })}
+        />
+        <NumberInput
+          label="Overlap"
+          description="Characters"
+          min={0}
+          value={config.chunkOverlap ?? 100}
+          onChange={(val) => updateNodeConfig(nodeId, { chunkOverlap: Number(val) })}
+        />
+      </Group>
+      <Select
+        label="Embedding Provider"
+        data={[
+          { value: 'openai', label: 'OpenAI-compatible' },
+          { value: 'ollama', label: 'Ollama (Local)' },
+          { value: 'hash', label: 'Local hashing (testing)' },
+        ]}
+        value={provider}
+        onChange={(val: string | null) => updateNodeConfig(nodeId, { provider: val || 'openai' })}
+      />
+      {provider !== 'hash' && (
+        <TextInput
+          label="Model"
+          placeholder={provider === 'ollama' ? 'nomic-embed-text' : 'text-embedding-3-small'}
+          value={config.model || ''}
+          onChange={(e: React.ChangeEvent<HTMLInputElement>) => updateNodeConfig(nodeId, { model: e.target.value })}
+        />
+      )}
+      {provider !== 'hash' && (
+        <TextInput
+          label="Endpoint"
+          placeholder={provider === 'ollama' ? 'http://localhost:11434/api/embed' : 'https://api.openai.com/v1/embeddings'}
+          value={config.endpoint || ''}
+          onChange={(e: React.ChangeEvent<HTMLInputElement>) => updateNodeConfig(nodeId, { endpoint: e.target.value })}
+        />
+      )}
+      {provider === 'openai' && (
+        <PasswordInput
+          label="API Key"
+          placeholder="sk-..."
+          value={config.apiKey || ''}
+          onChange={(e: React.ChangeEvent<HTMLInputElement>) => updateNodeConfig(nodeId, { apiKey: e.target.value })}
+        />
+      )}
+      <Group grow>
+        {provider !== 'ollama' && (
+          <NumberInput
+            label="Dimensions"
+            min={0}
+            value={config.dimensions ?? ''}
+            onChange={(val) => updateNodeConfig(nodeId, { dimensions: Number(val) })}
+          />
+        )}
+        {provider !== 'hash' && (
+          <NumberInput
+            label="Batch Size"
+            min={1}
+            value={config.batchSize ?? 16}
+            onChange={(val) => updateNodeConfig(nodeId, { batchSize: Number(val) })}
+          />
+        )}
+      </Group>
+      <TextInput
+        label="Vector Field"
+        placeholder="embedding"
+        value={config.vectorField || ''}
+        onChange={(e: React.ChangeEvent<HTMLInputElement>) => updateNodeConfig(nodeId, { vectorField: e.target.value })}
+        description="Use 'values' for the Pinecone sink, or the vector column of pgvector and Milvus."
+      />
+    </Stack>
+
+  );
+}
@@ -1,4 +1,4 @@
-import { Stack, Group, NumberInput, Select, Autocomplete, Alert, Text } from '@mantine/core';
+import { Stack, Group, NumberInput, Select, Autocomplete, Alert, Text, TextInput } from '@mantine/core';
 import { useMemo } from 'react';
 import { IconInfoCircle } from '@tabler/icons-react';
 
@@ -50,8 +50,27 @@ export function RateLimitConfig({ config, updateNodeConfig, nodeId, availableFie
         data={fieldPaths || []}
         value={config.keyField || ''} 
         onChange={(val) => updateNodeConfig(nodeId, { keyField: val })} 
-        description="Apply limits per unique value of this field."
+        description="Apply limits per unique value of this field or expression."
       />
+      <Select
+        label="Scope"
+        data={[
+          { label: 'Per worker (in memory)', value: 'local' },
+          { label: 'Shared across workers (state store)', value: 'distributed' },
+        ]}
+        value={config.scope || 'local'}
+        onChange={(val) => updateNodeConfig(nodeId, { scope: val || 'local' })}
+        description="Shared limits need a Redis, etcd or SQLite state store."
+      />
+      {config.scope === 'distributed' && (
+        <TextInput
+          label="Bucket (Optional)"
+          placeholder="e.g. salesforce-api"
+          value={config.bucket || ''}
+          onChange={(e) => updateNodeConfig(nodeId, { bucket: e.currentTarget.value })}
+          description="Workflows using the same bucket share one budget. Defaults to this node."
+        />
+      )}
     </Stack>
   );
 }
@@ -0,0 +1,141 @@
+import { Stack, Group, Select, Autocomplete, Alert, Text, TextInput, JsonInput } from '@mantine/core';
+import { useMemo } from 'react';
+import { IconInfoCircle } from '@tabler/icons-react';
+
+interface WindowConfigProps {
+  config: any;
+  updateNodeConfig: (id: string, config: any) => void;
+  nodeId: string;
+  availableFields: any[];
+}
+
+export function WindowConfig({ config, updateNodeConfig, nodeId, availableFields }: WindowConfigProps) {
+  const fieldPaths = useMemo(() =>
+    (availableFields || []).map(f => typeof f === 'string' ? f : f.path),
+    [availableFields]
+  );
+  const windowType = config.windowType || 'tumbling';
+
+  return (
+    <Stack gap="md">
+      <Alert icon={<IconInfoCircle size="1rem" />} color="pink">
+        <Text size="sm">
+          Aggregate records into windows by their own timestamp rather than arrival time.
+          A window emits once every source's watermark passes its end. Records later than the
+          allowed lateness go to the late route; connect it with an edge labelled the same.
+        </Text>
+      </Alert>
+      <Autocomplete
+        label="Event Timestamp"
+        placeholder="e.g. created_at"
+        data={fieldPaths}
+        value={config.timestamp || ''}
+        onChange={(val) => updateNodeConfig(nodeId, { timestamp: val })}
+        description="Field or expression holding the event time (RFC3339 or Unix epoch)."
+        required
+      />
+      <Select
+        label="Window Type"
+        data={[
+          { label: 'Tumbling', value: 'tumbling' },
+          { label: 'Sliding', value: 'sliding' },
+          { label: 'Session', value: 'session' },
+        ]}
+        value={windowType}
+        onChange={(val) => updateNodeConfig(nodeId, { windowType: val || 'tumbling' })}
+      />
+      <Group grow>
+        {windowType === 'session' ? (
+          <TextInput
+            label="Gap"
+            placeholder="e.g. 30m"
+            description="Inactivity that closes a session"
+            value={config.gap || ''}
+            onChange={(e) => updateNodeConfig(nodeId, { gap: e.currentTarget.value })}
+          />
+        ) : (
+          <TextInput
+            label="Window Size"
+            placeholder="e.g. 1m"
+            description="Go duration format"
+            value={config.window || ''}
+            onChange={(e) => updateNodeConfig(nodeId, { window: e.currentTarget.value })}
+          />
+        )}
+        {windowType === 'sliding' && (
+          <TextInput
+            label="Slide"
+            placeholder="e.g. 10s"
+            description="How often a window starts"
+            value={config.slide || ''}
+            onChange={(e) => updateNodeConfig(nodeId, { slide: e.currentTarget.value })}
+          />
+        )}
+      </Group>
+      <Group grow>
+        <Autocomplete
+          label="Group By (Optional)"
+          placeholder="e.g. region"
+          data={fieldPaths}
+          value={config.groupBy || ''}
+          onChange={(val) => updateNodeConfig(nodeId, { groupBy: val })}
+        />
+        <TextInput
+          label="Group Output Field"
+          placeholder="group"
+          value={config.groupAs || ''}
+          onChange={(e) => updateNodeConfig(nodeId, { groupAs: e.currentTarget.value })}
+        />
+      </Group>
+      <JsonInput
+        label="Aggregations (JSON)"
+        placeholder='[{"type": "count"}, {"field": "amount", "type": "sum"}, {"field": "user_id", "type": "distinct"}, {"field": "latency", "type": "percentile", "percentile": 0.95, "as": "p95"}]'
+        description="Types: count, sum, avg, min, max, distinct, percentile"
+        value={typeof config.aggregations === 'string' ? config.aggregations : JSON.stringify(config.aggregations || [], null, 2)}
+        onChange={(val) => updateNodeConfig(nodeId, { aggregations: val })}
+        minRows={4}
+        autosize
+        formatOnBlur
+      />
+      <Group grow>
+        <TextInput
+          label="Max Out-of-Orderness"
+          placeholder="e.g. 5s"
+          description="Watermark delay"
+          value={config.maxOutOfOrderness || ''}
+          onChange={(e) => updateNodeConfig(nodeId, { maxOutOfOrderness: e.currentTarget.value })}
+        />
+        <TextInput
+          label="Allowed Lateness"
+          placeholder="e.g. 1m"
+          description="Late records update closed windows"
+          value={config.allowedLateness || ''}
+          onChange={(e) => updateNodeConfig(nodeId, { allowedLateness: e.currentTarget.value })}
+        />
+      </Group>
+      <Group grow>
+        <TextInput
+          label="Idle Source Timeout"
+          placeholder="e.g. 5m"
+          description="Idle sources stop holding the watermark; once all are, open windows close"
+          value={config.idleTimeout || ''}
+          onChange={(e) => updateNodeConfig(nodeId, { idleTimeout: e.currentTarget.value })}
+        />
+        <TextInput
+          label="Late Route"
+          placeholder="e.g. late"
+          description="Branch for records past lateness; empty drops them"
+          value={config.lateRoute || ''}
+          onChange={(e) => updateNodeConfig(nodeId, { lateRoute: e.currentTarget.value })}
+        />
+        <TextInput
+          label="Checkpoint Interval"
+          placeholder="10s"
+          description="How often buffered records are saved"
+          value={config.checkpointInterval || ''}
+          onChange={(e) => updateNodeConfig(nodeId, { checkpointInterval: e.currentTarget.value })}
+        />
+      </Group>
+    </Stack>
+  );
+}
@@ -25,12 +25,14 @@ import { FuzzyLookupConfig } from './enrichment/FuzzyLookupConfig'
 import { TermExtractionConfig } from './enrichment/TermExtractionConfig'
 import { APILookupConfig } from './enrichment/APILookupConfig'
 import { AIConfig } from './enrichment/AIConfig'
+import { EmbedConfig } from './enrichment/EmbedConfig'
 
 // logic
 import { ConditionConfig } from './logic/ConditionConfig'
 import { SwitchConfig } from './logic/SwitchConfig'
 import { RouterConfig } from './logic/RouterConfig'
 import { WaitConfig } from './logic/WaitConfig'
+import { WindowConfig } from './logic/WindowConfig'
 import { JoinConfig } from './logic/JoinConfig'
 import { ForeachConfig } from './logic/ForeachConfig'
 import { CollectConfig } from './logic/CollectConfig'
@@ -118,6 +120,7 @@ export const TRANSFORM_CONFIGS: Record<string, ConfigComponent> = {
   api_lookup: APILookupConfig,
   ai_enrichment: AIConfig,
   ai_mapper: AIConfig,
+  embed: EmbedConfig,
 
   // logic & flow
   condition: ConditionConfig,
@@ -155,6 +158,7 @@ export const TRANSFORM_CONFIGS: Record<string, ConfigComponent> = {
 export const NODE_TYPE_CONFIGS: Record<string, ConfigComponent> = {
   wait: WaitConfig,
   join: JoinConfig,
+  window: WindowConfig,
   circuit_breaker: CircuitBreakerConfig,
   foreach: ForeachConfig,
   collect: CollectConfig,
@@ -1,5 +1,5 @@
 import { Button, Card, Group, Paper, SimpleGrid, Stack, Text, ThemeIcon, Title } from '@mantine/core';
-import { IconBraces, IconCode } from '@tabler/icons-react';
+import { IconBraces, IconBrandPython, IconCode } from '@tabler/icons-react';
 
 import type { SettingsController } from './useSettingsController';
 
@@ -21,7 +21,7 @@ export function DeveloperTab({ ctx }: { ctx: SettingsController }) {
                 Generate lightweight client libraries to easily publish messages to Hermod from your applications.
               </Text>
               
-              <SimpleGrid cols={{ base: 1, sm: 2 }} spacing="md">
+              <SimpleGrid cols={{ base: 1, sm: 3 }} spacing="md">
                 <Card withBorder padding="lg" radius="md">
                   <Stack align="center" gap="sm">
                     <ThemeIcon size="xl" radius="md" color="blue" variant="light">
@@ -33,6 +33,17 @@ export function DeveloperTab({ ctx }: { ctx: SettingsController }) {
                   </Stack>
                 </Card>
 
+                <Card withBorder padding="lg" radius="md">
+                  <Stack align="center" gap="sm">
+                    <ThemeIcon size="xl" radius="md" color="blue" variant="light">
+                      <IconBrandPython size="1.5rem" />
+                    </ThemeIcon>
+                    <Text fw={700}>Python Client</Text>
+                    <Text size="xs" c="dimmed" ta="center">Typed dataclasses over the standard urllib.</Text>
+                    <Button variant="light" size="sm" onClick={() => handleGenerateSDK('python')}>Download .py</Button>
+                  </Stack>
+                </Card>
+
                 <Card withBorder padding="lg" radius="md">
                   <Stack align="center" gap="sm">
                     <ThemeIcon size="xl" radius="md" color="blue" variant="light">
@@ -408,7 +408,7 @@ export function useSettingsController() {
 
   const handleGenerateSDK = async (language: string) => {
     try {
-      const res = await apiFetch('/api/infra/generate-sdk', {
+      const res = await apiFetch('/api/sdk', {
         method: 'POST',
         body: JSON.stringify({ language })
       });
@@ -417,14 +417,18 @@ export function useSettingsController() {
         const url = window.URL.createObjectURL(blob);
         const a = document.createElement('a');
         a.href = url;
-        const filename = language === 'go' ? 'hermod_client.go' : 'hermod-client.ts';
-        a.download = filename;
+        // The server names the file after the language's conventions.
+        const disposition = res.headers.get('Content-Disposition') || '';
+        const match = /filename\*?=(?:UTF-8'')?"?([^";]+)"?/i.exec(disposition);
+        a.download = match ? decodeURIComponent(match[1]) : `hermod-client-${language}`;
         document.body.appendChild(a);
         a.click();
+        a.remove();
         window.URL.revokeObjectURL(url);
         notifications.show({ title: 'Success', message: `SDK for ${language} generated`, color: 'green' });
       } else {
-        throw new Error('Failed to generate SDK');
+        const body = await res.json().catch(() => ({}));
+        throw new Error(body.error || 'Failed to generate SDK');
       }
     } catch (err: any) {
       notifications.show({ title: 'Error', message: err.message, color: 'red' });
@@ -30,6 +30,7 @@ export const NODE_CATEGORIES = [
     group: 'transformations',
     items: [
       { type: 'join', refId: 'new', label: 'Stateful Join', subType: 'join', icon: IconGitMerge, color: 'indigo', description: 'Wait and join multiple events by key' },
+      { type: 'window', refId: 'new', label: 'Event-Time Window', subType: 'window', icon: IconChartBar, color: 'indigo', description: 'Aggregate by event time with watermarks and late-data routing' },
       { type: 'circuit_breaker', refId: 'new', label: 'Circuit Breaker', subType: 'cb', icon: IconShieldLock, color: 'red', description: 'Stop flow on failure threshold' },
       { type: 'condition', refId: 'new', label: 'Condition (If)', subType: 'condition', icon: IconArrowsSplit, color: 'indigo', description: 'Branch flow by boolean rule' },
       { type: 'router', refId: 'new', label: 'Content Router', subType: 'router', icon: IconArrowsSplit, color: 'indigo', description: 'Route by pattern-based rules' },
@@ -50,6 +51,7 @@ export const NODE_CATEGORIES = [
       { type: 'transformation', refId: 'new', label: 'API Lookup', subType: 'api_lookup', icon: IconCloud, color: 'teal', description: 'Fetch and merge from HTTP APIs' },
       { type: 'transformation', refId: 'new', label: 'AI Enrichment', subType: 'ai_enrichment', icon: IconSettingsAutomation, color: 'teal', description: 'Enrich data using LLMs (OpenAI, Ollama)' },
       { type: 'transformation', refId: 'new', label: 'AI Mapper', subType: 'ai_mapper', icon: IconSettingsAutomation, color: 'teal', description: 'Map unstructured data to schema using AI' },
+      { type: 'transformation', refId: 'new', label: 'Embed', subType: 'embed', icon: IconSettingsAutomation, color: 'teal', description: 'Chunk text and generate embeddings for vector sinks' },
       { type: 'transformation', refId: 'new', label: 'Pipeline', subType: 'pipeline', icon: IconPlaylist, color: 'teal', description: 'Compose multiple steps' },
       { type: 'transformation', refId: 'new', label: 'Lua Script', subType: 'lua', icon: IconCode, color: 'teal', description: 'Custom logic with Lua' },
       { type: 'transformation', refId: 'new', label: 'WASM Transform', subType: 'wasm', icon: IconTerminal2, color: 'teal', description: 'Run high-performance WebAssembly' },